@@ -2,68 +2,601 @@ package main
 
 import (
 	"context"
+	"flag"
 	"flight-simulator2/internal/api"
 	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+	"flight-simulator2/internal/mavlink"
 	"flight-simulator2/internal/sim"
+	"fmt"
 	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
 
+// version is the build reported by GET /version; release builds set it
+// with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	recordPath := flag.String("record", "", "append every published state and accepted command to this JSONL file")
+	replayPath := flag.String("replay", "", "play back a recording made with -record instead of simulating")
+	replaySpeed := flag.Float64("replay-speed", 1, "playback speed multiplier for -replay")
+	allowTeleport := flag.Bool("allow-teleport", false, "enable POST /sim/setstate")
+	allowFaults := flag.Bool("allow-faults", false, "enable /sim/fault and GET /sim/truth")
+	logLevel := flag.String("log-level", "info", "request log level: debug (adds health checks), info, warn or error")
+	sseHeartbeat := flag.Duration("sse-heartbeat", api.DefaultSSEHeartbeat, "interval of the keep-alive comment on /stream and /events; 0 = none")
+	sseWriteTimeout := flag.Duration("sse-write-timeout", api.DefaultSSEWriteTimeout, "disconnect a stream client that takes longer than this to accept a write; 0 = never")
+	missionsPath := flag.String("missions-file", "", "keep the missions of /missions in this JSON file, loaded at startup and written on every change")
+	compress := flag.Bool("gzip", true, "gzip state, history, export and stream responses for clients that accept it")
+	maxWaypoints := flag.Int("max-waypoints", api.DefaultMaxWaypoints, "most waypoints a trajectory or mission may have; 0 = no limit")
+	readHeaderTimeout := flag.Duration("read-header-timeout", 3*time.Second, "time allowed to read a request's header")
+	readTimeout := flag.Duration("read-timeout", 30*time.Second, "time allowed to read a whole request, body included; 0 = none")
+	writeTimeout := flag.Duration("write-timeout", 30*time.Second, "time allowed to write a response, the streams and /state/wait excepted; 0 = none")
+	idleTimeout := flag.Duration("idle-timeout", 2*time.Minute, "how long an idle keep-alive connection is kept; 0 = the read timeout")
+	maxHeaderBytes := flag.Int("max-header-bytes", 64<<10, "largest request header accepted (bytes)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 5*time.Second, "how long shutdown waits for requests in flight")
+	tlsCert := flag.String("tls-cert", "", "certificate file (PEM); with -tls-key, serve HTTPS")
+	tlsKey := flag.String("tls-key", "", "private key file (PEM) for -tls-cert")
+	mavlinkOut := flag.String("mavlink-out", "", "send MAVLink telemetry over UDP to these comma-separated host:port targets; empty = off")
+	mavlinkIn := flag.String("mavlink-in", "", "take MAVLink commands and mission uploads on this UDP address, e.g. :14555, and send telemetry from it; empty = off")
+	mavlinkHz := flag.Float64("mavlink-hz", mavlink.DefaultRateHz, "rate of the MAVLink position, HUD and attitude messages (Hz)")
+	mavlinkSysID := flag.Uint("mavlink-sysid", mavlink.DefaultSysID, "MAVLink system id of the aircraft (1-255)")
+	apiKeysPath := flag.String("api-keys", "", `file of "<scope> <key>" lines (scope read or control); requires a key on every request`)
 
-	// Graceful shutdown on SIGINT/SIGTERM
-	go func() {
-		sigCh := make(chan os.Signal, 2)
-		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-		<-sigCh
-		cancel()
-	}()
+	// Engine settings are bound straight into the config; newEngine adds
+	// the origin, tick rate and environment.
+	var cfg sim.Config
 
-	// Environment effects
-	wind := env.Wind{Wx: 5.0, Wy: 2.0}
-	terrain := env.Terrain{SafetyMarginM: 80.0}
+	def := sim.DefaultLimits()
+	flag.Float64Var(&cfg.PosTolM, "pos-tol", def.PosTolM, "horizontal arrival tolerance (m)")
+	flag.Float64Var(&cfg.AltTolM, "alt-tol", def.AltTolM, "vertical arrival tolerance (m)")
+	flag.Float64Var(&cfg.DefaultSpeed, "default-speed", def.DefaultSpeed, "speed used when a command gives none (m/s)")
+	flag.Float64Var(&cfg.MaxClimbRate, "max-climb", def.MaxClimbRate, "maximum climb/descent rate (m/s)")
+	flag.Float64Var(&cfg.MaxHorizAccel, "max-horiz-accel", def.MaxHorizAccel, "maximum horizontal acceleration (m/s²)")
+	flag.Float64Var(&cfg.MaxVertAccel, "max-vert-accel", def.MaxVertAccel, "maximum vertical acceleration (m/s²)")
+	flag.Float64Var(&cfg.MaxBankDeg, "max-bank", def.MaxBankDeg, "maximum bank angle in turns (degrees)")
+	integrator := flag.String("integrator", string(sim.IntegratorEuler), "position integrator: euler or midpoint")
+	flag.BoolVar(&cfg.AutoOrigin, "auto-origin", false, "move the origin to the first goto or trajectory target")
+	flag.Float64Var(&cfg.GeoidOffsetM, "geoid-offset", 0, "geoid height above the WGS84 ellipsoid in meters, for altRef wgs84")
+	projection := flag.String("projection", string(sim.ProjectionWGS84), "lat/lon conversion of the local frame: wgs84 or equirectangular")
+	flag.IntVar(&cfg.SubSteps, "substeps", 1, "physics sub-steps per tick")
+	flag.BoolVar(&cfg.Atmosphere.Enabled, "isa", false, "scale climb rate and speed with ISA air density")
+	flag.Float64Var(&cfg.Atmosphere.Exponent, "isa-exponent", 1, "density ratio exponent for -isa")
+	flag.Float64Var(&cfg.Atmosphere.TempOffsetC, "isa-temp-offset", 0, "ISA temperature deviation (°C) for -isa")
+	flag.Float64Var(&cfg.CeilingM, "ceiling", 0, "service ceiling (m); 0 = none")
+	flag.Float64Var(&cfg.MaxRangeM, "max-range", sim.DefaultMaxRangeM, "maximum operating radius around the origin for goto and trajectory targets (m)")
+	physics := flag.String("physics", string(sim.PhysicsKinematic), "motion model: kinematic or pointmass")
 
-	environment := env.Chain{
-		Effects: []env.Environment{wind, terrain},
+	flag.Float64Var(&cfg.InitialLat, "initial-lat", 0, "starting latitude (default: origin)")
+	flag.Float64Var(&cfg.InitialLon, "initial-lon", 0, "starting longitude (default: origin)")
+	flag.Float64Var(&cfg.InitialAlt, "initial-alt", 1000, "starting altitude (m)")
+	flag.Float64Var(&cfg.InitialVx, "initial-vx", 0, "starting east velocity (m/s)")
+	flag.Float64Var(&cfg.InitialVy, "initial-vy", 0, "starting north velocity (m/s)")
+	flag.Float64Var(&cfg.InitialVz, "initial-vz", 0, "starting vertical velocity (m/s)")
+
+	flag.Float64Var(&cfg.BatteryWh, "battery-wh", 0, "battery capacity (Wh); 0 disables the energy model")
+	flag.Float64Var(&cfg.CruisePowerW, "cruise-power", 0, "power draw in horizontal flight (W)")
+	flag.Float64Var(&cfg.ClimbPowerW, "climb-power", 0, "power draw while climbing (W)")
+	flag.Float64Var(&cfg.HoverPowerW, "hover-power", 0, "power draw while stationary (W)")
+	flag.StringVar(&cfg.OnEmpty, "battery-on-empty", sim.OnEmptyDescend, "behavior at zero charge: descend or freeze")
+
+	// Environment settings; newEngine builds the effect chain from them.
+	var ec envConfig
+	flag.StringVar(&ec.Scenario, "scenario", "", "JSON scenario file describing the whole environment chain")
+	flag.StringVar(&ec.NoFly, "no-fly", "", "GeoJSON FeatureCollection of no-fly zones")
+	flag.StringVar(&ec.NoFlyMode, "no-fly-mode", string(env.NoFlyAdvisory), "no-fly zone reaction: advisory or hard")
+	flag.StringVar(&ec.METAR, "metar", "", "take the wind (and gust turbulence) from a METAR report")
+	flag.StringVar(&ec.WindField, "wind-field", "", "JSON wind grid file replacing the constant wind")
+	flag.StringVar(&ec.WindProfile, "wind-profile", "", "altitude wind layers TOP:SPEED@DIR,... (m, m/s, deg) replacing the constant wind")
+	flag.Float64Var(&ec.TurbulenceMps, "turbulence", 0, "gust intensity, std deviation (m/s); 0 = none")
+	flag.Float64Var(&ec.TurbulenceTauS, "turbulence-tau", env.DefaultCorrelationTimeS, "gust correlation time (s)")
+	flag.StringVar(&ec.TurbulenceAGL, "turbulence-agl", "", "scale turbulence with height: full below FULL, residual above RESIDUAL (m AGL, FULL:RESIDUAL)")
+	flag.Float64Var(&ec.TurbulenceRes, "turbulence-residual", env.DefaultTurbulenceResidual, "share of the turbulence intensity left above the -turbulence-agl band")
+	flag.Float64Var(&ec.GlideSinkMps, "glide-sink", 0, "sink rate of an uncommanded aircraft (m/s); 0 = hover")
+	flag.Float64Var(&ec.GlideDecayS, "glide-decay", 20, "airspeed decay time constant of the glide (s); 0 = engine deceleration")
+	flag.StringVar(&ec.Icing, "icing", "", "icing band BASE:TOP (m); empty = none")
+	flag.Float64Var(&ec.IcingRate, "icing-rate", 0.01, "ice load accreted per second in the band (full load = 1)")
+	flag.Float64Var(&ec.IcingLoss, "icing-degradation", 0.5, "fraction of the climb rate lost at full ice load")
+	flag.StringVar(&ec.Terrain, "terrain", "synthetic", "terrain surface: synthetic or flat:ELEV (m)")
+	flag.Float64Var(&ec.TerrainBase, "terrain-base", 0, "base elevation of the synthetic terrain (m)")
+	flag.StringVar(&ec.TerrainWaves, "terrain-waves", "100:1000:50:500", "synthetic terrain waves AMP:SCALE:RIDGEAMP:RIDGESCALE (m, m per radian)")
+	flag.StringVar(&ec.TerrainNoise, "terrain-noise", "", "synthetic terrain noise AMP:WAVELENGTH[:OCTAVES[:PERSISTENCE]] (m); empty = none")
+	flag.Int64Var(&ec.TerrainSeed, "terrain-seed", 0, "seed of the synthetic terrain noise")
+	flag.Float64Var(&ec.TerrainRecover, "terrain-recovery-rate", env.DefaultTerrainRecoveryRateMps, "climb rate back above the terrain safety margin (m/s)")
+	flag.Float64Var(&ec.SlopeBaseline, "terrain-slope-baseline", env.DefaultSlopeBaselineM, "distance the terrain slope is measured over (m)")
+	flag.IntVar(&ec.TerrainCache, "terrain-cache", env.DefaultTerrainCacheTiles, "tiles of -dem or -heightmap answers to cache; 0 = off")
+	flag.StringVar(&ec.DEM, "dem", "", "elevation model (.hgt SRTM tile or .asc grid) replacing -terrain")
+	flag.Float64Var(&ec.DEMDefaultM, "dem-default", 0, "elevation assumed outside -dem coverage (m)")
+	flag.StringVar(&ec.Heightmap, "heightmap", "", "grayscale PNG heightmap centered on the origin, replacing -terrain")
+	flag.Float64Var(&ec.HeightmapScale, "heightmap-scale", 10, "heightmap pixel size (m)")
+	flag.StringVar(&ec.HeightmapElev, "heightmap-elev", "0:500", "heightmap elevations of black and white MIN:MAX (m)")
+	flag.BoolVar(&ec.Water, "water", false, "treat ground at or below -water-below as water, at sea level")
+	flag.Float64Var(&ec.WaterBelowM, "water-below", 0, "elevation at or below which the ground is water (m)")
+	flag.StringVar(&ec.WaterMask, "water-mask", "", "water mask grid (.asc or .hgt, 1 = water), implying -water")
+	flag.Float64Var(&ec.GroundEffectM, "ground-effect", 0, "height below which descents are slowed (m AGL); 0 = none")
+	flag.Float64Var(&ec.GroundEffectK, "ground-effect-factor", env.DefaultGroundEffectMinFactor, "share of the descent rate kept on the ground")
+	flag.Float64Var(&cfg.MaxWindMps, "max-wind", sim.DefaultMaxWindMps, "highest wind speed PUT /environment/wind accepts (m/s)")
+
+	flag.Float64Var(&cfg.TickHz, "tick-hz", 20, "physics tick rate (Hz)")
+	flag.Float64Var(&cfg.PublishHz, "publish-hz", 0, "state publish rate for /stream and /history (Hz); 0 = tick rate")
+	flag.Float64Var(&cfg.PositionNoiseSigmaM, "position-noise", 0, "std deviation of published horizontal position noise (m)")
+	flag.Float64Var(&cfg.AltNoiseSigmaM, "alt-noise", 0, "std deviation of published altitude noise (m)")
+	flag.Int64Var(&cfg.Seed, "seed", 1, "seed for sensor noise and injected faults")
+	flag.BoolVar(&cfg.ExtrapolateState, "extrapolate-state", false, "dead-reckon GET /state to the request time")
+	flag.BoolVar(&cfg.EstimateWind, "estimate-wind", false, "publish a wind estimate from the aircraft's own motion")
+	flag.Float64Var(&cfg.WindEstimateTauS, "wind-estimate-tau", sim.DefaultWindEstimateTauS, "wind estimate filter time constant (s)")
+	flag.Float64Var(&cfg.TerrainLookaheadS, "terrain-lookahead", 30, "seconds of flight path checked for terrain ahead; 0 = off")
+	flag.IntVar(&cfg.TerrainHysteresisTicks, "terrain-hysteresis", sim.DefaultTerrainHysteresisTicks, "ticks a new terrain proximity level must last before it is published")
+	flag.Float64Var(&cfg.CrosswindLimitMps, "crosswind-limit", 0, "crosswind component raising a crosswind-limit caution (m/s); 0 = none")
+	flag.Float64Var(&cfg.TailwindLimitMps, "tailwind-limit", 0, "tailwind component raising a tailwind-limit caution (m/s); 0 = none")
+	declination := flag.Float64("declination", 0, "magnetic declination (deg, east positive) for headingMagDeg")
+	declinationGrid := flag.String("declination-grid", "", "JSON declination table by lat/lon, replacing -declination")
+	flag.Parse()
+	cfg.Integrator = sim.Integrator(*integrator)
+	cfg.Projection = sim.Projection(*projection)
+	cfg.Physics = sim.Physics(*physics)
+	if ec.Scenario != "" {
+		flag.Visit(func(f *flag.Flag) {
+			if envFlags[f.Name] {
+				log.Fatalf("-%s cannot be combined with -scenario", f.Name)
+			}
+		})
+	}
+	switch {
+	case *declinationGrid != "":
+		f, err := os.Open(*declinationGrid)
+		if err != nil {
+			log.Fatalf("open declination grid: %v", err)
+		}
+		grid, err := sim.LoadDeclinationGrid(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		cfg.Declination = grid
+	case *declination != 0:
+		cfg.Declination = sim.ConstantDeclination(*declination)
 	}
 
-	eng := sim.New(sim.Config{
-		OriginLat:   32.0853, // pick any origin
-		OriginLon:   34.7818,
-		TickHz:      20,
-		Environment: &environment,
-	})
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatalf("-tls-cert and -tls-key go together")
+	}
+	if *mavlinkSysID < 1 || *mavlinkSysID > 255 {
+		log.Fatalf("-mavlink-sysid must be between 1 and 255")
+	}
 
+	// Graceful shutdown on SIGINT/SIGTERM
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	// The simulation has its own context, so that shutdown can stop it
+	// before waiting for the HTTP requests.
+	simCtx, stopSim := context.WithCancel(context.Background())
+	defer stopSim()
+
+	var eng *sim.Engine
+	if *replayPath != "" {
+		eng = newReplayEngine(*replayPath, *replaySpeed)
+	} else {
+		eng = newEngine(*recordPath, ec, cfg)
+	}
+
+	engineDone := make(chan struct{})
 	go func() {
-		if err := eng.Run(ctx); err != nil {
+		defer close(engineDone)
+		if err := eng.Run(simCtx); err != nil {
 			log.Printf("engine stopped: %v", err)
 		}
 	}()
 
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		log.Fatalf("-log-level: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	if *mavlinkOut != "" || *mavlinkIn != "" {
+		link, err := mavlink.Listen(*mavlinkIn, uint8(*mavlinkSysID), 0)
+		if err != nil {
+			log.Fatalf("-mavlink-in: %v", err)
+		}
+		defer link.Close()
+		var targets []string
+		if *mavlinkOut != "" {
+			targets = strings.Split(*mavlinkOut, ",")
+		}
+		out, err := mavlink.NewOutput(link, mavlink.OutputConfig{Targets: targets, RateHz: *mavlinkHz, Logger: logger})
+		if err != nil {
+			log.Fatalf("-mavlink-out: %v", err)
+		}
+		go out.Run(simCtx, eng)
+		if *mavlinkOut != "" {
+			log.Printf("sending MAVLink telemetry to %s", *mavlinkOut)
+		}
+		if *mavlinkIn != "" {
+			maxItems := *maxWaypoints
+			if maxItems <= 0 {
+				maxItems = -1 // no limit, as for the API
+			}
+			go mavlink.NewInput(link, eng, mavlink.InputConfig{MaxMissionItems: maxItems, Logger: logger}).Run(simCtx)
+			log.Printf("taking MAVLink commands on %s", link.LocalAddr())
+		}
+	}
+
+	apiOpts := []api.Option{api.WithTeleport(*allowTeleport), api.WithFaults(*allowFaults), api.WithBuild(version), api.WithLogger(logger),
+		api.WithSSEHeartbeat(*sseHeartbeat), api.WithSSEWriteTimeout(*sseWriteTimeout),
+		api.WithMaxWaypoints(*maxWaypoints), api.WithCompression(*compress)}
+	if *apiKeysPath != "" {
+		apiOpts = append(apiOpts, api.WithAPIKeys(loadAPIKeys(*apiKeysPath)))
+	}
+	if *missionsPath != "" {
+		missions, err := api.LoadMissionStore(*missionsPath)
+		if err != nil {
+			log.Fatalf("load missions: %v", err)
+		}
+		apiOpts = append(apiOpts, api.WithMissions(missions))
+	}
+
+	// Streams and /state/wait lift the write timeout for themselves (see
+	// the api package); every other response must be written within it.
 	httpServer := &http.Server{
 		Addr:              ":8080",
-		Handler:           api.NewServer(eng).Handler(),
-		ReadHeaderTimeout: 3 * time.Second,
+		Handler:           api.NewServer(eng, apiOpts...).Handler(),
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+		MaxHeaderBytes:    *maxHeaderBytes,
 	}
 
+	serveErr := make(chan error, 1)
 	go func() {
-		log.Printf("server listening on %s", httpServer.Addr)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("http server error: %v", err)
+		var err error
+		if *tlsCert != "" {
+			log.Printf("server listening on %s (HTTPS)", httpServer.Addr)
+			err = httpServer.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			log.Printf("server listening on %s", httpServer.Addr)
+			err = httpServer.ListenAndServe()
 		}
+		serveErr <- err
 	}()
 
-	<-ctx.Done()
+	select {
+	case <-sigCtx.Done():
+	case err := <-serveErr:
+		log.Fatalf("http server error: %v", err)
+	}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Stop the simulation first: the streams then say goodbye and end, and
+	// Shutdown is left with the short requests to wait for.
+	stopSim()
+	<-engineDone
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), *shutdownTimeout)
 	defer shutdownCancel()
-	_ = httpServer.Shutdown(shutdownCtx)
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
 
 	log.Printf("shutdown complete")
 }
+
+// envConfig holds the flags that shape the environment chain.
+type envConfig struct {
+	Scenario       string
+	NoFly          string
+	NoFlyMode      string
+	METAR          string
+	WindField      string
+	WindProfile    string
+	TurbulenceMps  float64
+	TurbulenceTauS float64
+	TurbulenceAGL  string
+	TurbulenceRes  float64
+	GlideSinkMps   float64
+	GlideDecayS    float64
+	Icing          string
+	IcingRate      float64
+	IcingLoss      float64
+	GroundEffectM  float64
+	GroundEffectK  float64
+	Terrain        string
+	TerrainRecover float64
+	TerrainBase    float64
+	TerrainWaves   string
+	TerrainNoise   string
+	TerrainSeed    int64
+	SlopeBaseline  float64
+	TerrainCache   int
+	DEM            string
+	DEMDefaultM    float64
+	Heightmap      string
+	HeightmapScale float64
+	HeightmapElev  string
+	Water          bool
+	WaterBelowM    float64
+	WaterMask      string
+}
+
+// syntheticTerrain builds the -terrain synthetic generator from its flags.
+func syntheticTerrain(ec envConfig) env.SyntheticTerrain {
+	st := env.SyntheticTerrain{BaseM: ec.TerrainBase, Seed: ec.TerrainSeed}
+	if _, err := fmt.Sscanf(ec.TerrainWaves, "%g:%g:%g:%g", &st.AmplitudeM, &st.ScaleM, &st.RidgeAmplitudeM, &st.RidgeScaleM); err != nil {
+		log.Fatalf("terrain waves %q: want AMP:SCALE:RIDGEAMP:RIDGESCALE", ec.TerrainWaves)
+	}
+	if ec.TerrainNoise != "" {
+		parts := strings.Split(ec.TerrainNoise, ":")
+		var err error
+		if len(parts) < 2 || len(parts) > 4 {
+			err = fmt.Errorf("wrong number of fields")
+		}
+		for i, dst := range []*float64{&st.NoiseAmplitudeM, &st.NoiseWavelengthM} {
+			if err == nil {
+				*dst, err = strconv.ParseFloat(parts[i], 64)
+			}
+		}
+		if err == nil && len(parts) > 2 {
+			st.Octaves, err = strconv.Atoi(parts[2])
+		}
+		if err == nil && len(parts) > 3 {
+			st.Persistence, err = strconv.ParseFloat(parts[3], 64)
+		}
+		if err != nil {
+			log.Fatalf("terrain noise %q: want AMP:WAVELENGTH[:OCTAVES[:PERSISTENCE]]", ec.TerrainNoise)
+		}
+	}
+	if err := st.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	return st
+}
+
+// envFlags are the flags a -scenario file replaces.
+var envFlags = map[string]bool{
+	"no-fly": true, "no-fly-mode": true, "metar": true, "wind-field": true, "wind-profile": true,
+	"turbulence": true, "turbulence-tau": true, "turbulence-agl": true, "turbulence-residual": true, "glide-sink": true, "glide-decay": true,
+	"icing": true, "icing-rate": true, "icing-degradation": true,
+	"ground-effect": true, "ground-effect-factor": true, "terrain": true, "terrain-recovery-rate": true, "terrain-slope-baseline": true, "terrain-cache": true,
+	"terrain-base": true, "terrain-waves": true, "terrain-noise": true, "terrain-seed": true,
+	"dem": true, "dem-default": true, "heightmap": true, "heightmap-scale": true, "heightmap-elev": true,
+	"water": true, "water-below": true, "water-mask": true,
+}
+
+func newEngine(recordPath string, ec envConfig, cfg sim.Config) *sim.Engine {
+	cfg.OriginLat = 32.0853 // pick any origin
+	cfg.OriginLon = 34.7818
+	if ec.Scenario != "" {
+		f, err := os.Open(ec.Scenario)
+		if err != nil {
+			log.Fatalf("open scenario: %v", err)
+		}
+		chain, err := env.LoadScenario(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", ec.Scenario, err)
+		}
+		cfg.Environment = chain
+	} else {
+		cfg.Environment = flagEnvironment(ec, cfg.Seed)
+	}
+
+	if recordPath != "" {
+		f, err := os.OpenFile(recordPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatalf("open recording: %v", err)
+		}
+		cfg.RecordTo = f
+		log.Printf("recording flight to %s", recordPath)
+	}
+
+	eng, err := sim.New(cfg)
+	if err != nil {
+		log.Fatalf("engine config: %v", err)
+	}
+	return eng
+}
+
+// loadAPIKeys reads the -api-keys file, exiting when it is unusable.
+func loadAPIKeys(path string) map[string]api.Scope {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open api keys: %v", err)
+	}
+	defer f.Close()
+	keys, err := api.ParseAPIKeys(f)
+	if err != nil {
+		log.Fatalf("api keys %s: %v", path, err)
+	}
+	if len(keys) == 0 {
+		log.Fatalf("api keys %s: no keys", path)
+	}
+	log.Printf("API keys required (%d loaded)", len(keys))
+	return keys
+}
+
+// flagEnvironment builds the environment chain from the individual flags.
+func flagEnvironment(ec envConfig, seed int64) env.Environment {
+	var wind env.Environment = env.Wind{Wx: 5.0, Wy: 2.0}
+	sources := 0
+	for _, s := range []string{ec.METAR, ec.WindField, ec.WindProfile} {
+		if s != "" {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		log.Fatalf("-metar, -wind-field and -wind-profile are mutually exclusive")
+	case ec.METAR != "":
+		m, err := env.ParseMETAR(ec.METAR)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		wind = m.Wind
+		// a gust is about two standard deviations above the mean
+		if m.GustMps > m.SpeedMps && ec.TurbulenceMps == 0 {
+			ec.TurbulenceMps = (m.GustMps - m.SpeedMps) / 2
+		}
+	case ec.WindField != "":
+		f, err := os.Open(ec.WindField)
+		if err != nil {
+			log.Fatalf("open wind field: %v", err)
+		}
+		field, err := env.LoadWindField(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		wind = field
+	case ec.WindProfile != "":
+		profile, err := env.ParseWindProfile(ec.WindProfile)
+		if err != nil {
+			log.Fatalf("wind profile: %v", err)
+		}
+		wind = profile
+	}
+	if !(ec.TerrainRecover > 0) {
+		log.Fatalf("-terrain-recovery-rate must be > 0")
+	}
+	if !(ec.SlopeBaseline > 0) {
+		log.Fatalf("-terrain-slope-baseline must be > 0")
+	}
+	terrain := env.Terrain{SafetyMarginM: 80.0, RecoveryRateMps: ec.TerrainRecover, SlopeBaselineM: ec.SlopeBaseline}
+	if ec.DEM != "" && ec.Heightmap != "" || (ec.DEM != "" || ec.Heightmap != "") && ec.Terrain != "synthetic" {
+		log.Fatalf("-terrain, -dem and -heightmap are mutually exclusive")
+	}
+	switch {
+	case ec.Heightmap != "":
+		var lo, hi float64
+		if _, err := fmt.Sscanf(ec.HeightmapElev, "%g:%g", &lo, &hi); err != nil {
+			log.Fatalf("heightmap elevations %q: want MIN:MAX", ec.HeightmapElev)
+		}
+		img, err := env.NewImageTerrain(ec.Heightmap, vector.Vec3{}, ec.HeightmapScale, lo, hi)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		terrain.Provider = img
+	case ec.DEM != "":
+		dem, err := env.OpenDEM(ec.DEM)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		dem.DefaultM = ec.DEMDefaultM
+		if err := dem.Validate(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Printf("loaded elevation model %s: %dx%d posts, %.1f MB", ec.DEM, dem.Cols, dem.Rows, float64(dem.MemoryBytes())/(1<<20))
+		terrain.Provider = dem
+	case ec.Terrain == "synthetic":
+		terrain.Provider = syntheticTerrain(ec)
+	case strings.HasPrefix(ec.Terrain, "flat:"):
+		elev, err := strconv.ParseFloat(strings.TrimPrefix(ec.Terrain, "flat:"), 64)
+		if err != nil || math.IsNaN(elev) || math.IsInf(elev, 0) {
+			log.Fatalf("terrain %q: want flat:ELEV", ec.Terrain)
+		}
+		terrain.Provider = env.FlatTerrain(elev)
+	default:
+		log.Fatalf("terrain %q: want synthetic or flat:ELEV", ec.Terrain)
+	}
+	if ec.TerrainCache < 0 {
+		log.Fatalf("-terrain-cache must be >= 0")
+	}
+	if ec.TerrainCache > 0 && (ec.DEM != "" || ec.Heightmap != "") {
+		terrain.Provider = &env.TerrainCache{Provider: terrain.Provider, MaxTiles: ec.TerrainCache}
+	}
+	if ec.Water || ec.WaterMask != "" {
+		water := &env.WaterSurface{Land: terrain.Provider, ThresholdM: ec.WaterBelowM}
+		if ec.WaterMask != "" {
+			mask, err := env.OpenDEM(ec.WaterMask)
+			if err != nil {
+				log.Fatalf("water mask: %v", err)
+			}
+			water.Mask = mask
+		}
+		if err := water.Validate(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		terrain.Provider = water
+	}
+
+	environment := env.Chain{
+		Effects: []env.Environment{wind},
+	}
+	if ec.TurbulenceMps > 0 {
+		turb := &env.Turbulence{
+			IntensityMps:     ec.TurbulenceMps,
+			CorrelationTimeS: ec.TurbulenceTauS,
+			Seed:             seed,
+		}
+		if ec.TurbulenceAGL != "" {
+			turb.ScaleWithAGL, turb.Residual = true, ec.TurbulenceRes
+			if _, err := fmt.Sscanf(ec.TurbulenceAGL, "%g:%g", &turb.FullBelowM, &turb.ResidualAboveM); err != nil {
+				log.Fatalf("turbulence band %q: want FULL:RESIDUAL", ec.TurbulenceAGL)
+			}
+		}
+		if err := turb.Validate(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		environment.Effects = append(environment.Effects, turb)
+	}
+	if ec.NoFly != "" {
+		f, err := os.Open(ec.NoFly)
+		if err != nil {
+			log.Fatalf("open no-fly zones: %v", err)
+		}
+		zones, err := env.LoadNoFlyZones(f, env.NoFlyMode(ec.NoFlyMode))
+		f.Close()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		environment.Effects = append(environment.Effects, zones)
+	}
+	if ec.GlideSinkMps > 0 {
+		glide := &env.Gravity{SinkRateMps: ec.GlideSinkMps, AirspeedDecayS: ec.GlideDecayS}
+		if err := glide.Validate(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		environment.Effects = append(environment.Effects, glide)
+	}
+	if ec.Icing != "" {
+		ice := &env.Icing{AccretionRate: ec.IcingRate, MaxDegradation: ec.IcingLoss}
+		if _, err := fmt.Sscanf(ec.Icing, "%g:%g", &ice.BaseAltM, &ice.TopAltM); err != nil {
+			log.Fatalf("icing band %q: want BASE:TOP", ec.Icing)
+		}
+		if err := ice.Validate(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		environment.Effects = append(environment.Effects, ice)
+	}
+	if ec.GroundEffectM > 0 {
+		ground := &env.GroundEffect{HeightM: ec.GroundEffectM, MinFactor: ec.GroundEffectK}
+		if err := ground.Validate(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		environment.Effects = append(environment.Effects, ground)
+	}
+	environment.Effects = append(environment.Effects, terrain)
+	return &environment
+}
+
+func newReplayEngine(path string, speed float64) *sim.Engine {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open replay: %v", err)
+	}
+	defer f.Close()
+
+	eng, err := sim.NewReplay(f, sim.ReplayOptions{Speed: speed, TickHz: 20})
+	if err != nil {
+		log.Fatalf("load replay: %v", err)
+	}
+	log.Printf("replaying %s at %.2gx", path, speed)
+	return eng
+}
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+// activeOut is GET /command/active's answer for a goto.
+type activeOut struct {
+	Type     sim.CommandType
+	Command  sim.GoToCommand
+	Progress *sim.CommandProgress
+}
+
+func TestActiveCommandProgress(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	// 1.5 km north and 400 m up: the distances are true lengths, not
+	// squared ones
+	const lat, lon, alt = 47.0135, 8.0, 1400.0
+	if resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.0135,"lon":8,"alt":1400,"speed":50}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(1)
+	var a activeOut
+	ts.getJSON("/v1/command/active", &a)
+	if a.Type != sim.CmdGoTo || a.Progress == nil {
+		t.Fatalf("active %+v", a)
+	}
+	want := math.Hypot(sim.HaversineM(47, 8, lat, lon), alt-1000)
+	if math.Abs(a.Progress.TotalM-want) > 5 {
+		t.Errorf("totalM %.1f, want %.1f", a.Progress.TotalM, want)
+	}
+
+	last := a.Progress.DistanceToTargetM
+	for i := 0; i < 5; i++ {
+		ts.ticks(40)
+		var st sim.AircraftState
+		ts.getJSON("/v1/state", &st)
+		a = activeOut{}
+		ts.getJSON("/v1/command/active", &a)
+		p := a.Progress
+		want := math.Hypot(sim.HaversineM(st.Lat, st.Lon, lat, lon), alt-st.Alt)
+		if math.Abs(p.DistanceToTargetM-want) > 2 {
+			t.Errorf("distanceToTargetM %.1f, want %.1f", p.DistanceToTargetM, want)
+		}
+		if p.RemainingM != p.DistanceToTargetM {
+			t.Errorf("remainingM %.1f of a goto, distanceToTargetM %.1f", p.RemainingM, p.DistanceToTargetM)
+		}
+		if !(p.DistanceToTargetM < last) || math.Abs(p.Fraction-(1-p.DistanceToTargetM/p.TotalM)) > 0.01 {
+			t.Errorf("progress %+v after %.1f m to go", p, last)
+		}
+		last = p.DistanceToTargetM
+	}
+}
+
+func TestActiveCommandErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	resp, b := ts.do(http.MethodGet, "/v1/command/active", "")
+	wantError(t, resp, b, http.StatusNotFound, "no_active_command")
+	resp, b = ts.do(http.MethodDelete, "/v1/command/active", "")
+	wantError(t, resp, b, http.StatusNotFound, "no_active_command")
+	resp, b = ts.do(http.MethodPost, "/v1/command/active", "")
+	wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+	if got := resp.Header.Get("Allow"); got != "GET, DELETE" {
+		t.Errorf("Allow %q", got)
+	}
+
+	if resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.01,"lon":8,"alt":1000}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(1)
+	var out struct {
+		Status    string
+		Cancelled sim.CommandType
+	}
+	resp, b = ts.do(http.MethodDelete, "/v1/command/active", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("cancel: %d %s", resp.StatusCode, b)
+	}
+	if err := json.Unmarshal(b, &out); err != nil || out.Status != "holding" || out.Cancelled != sim.CmdGoTo {
+		t.Errorf("cancel: %s", b)
+	}
+}
@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+	"flight-simulator2/internal/sim"
+)
+
+func TestStateQuaternion(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	// a climbing leg east, then a turn north that banks the aircraft
+	body := `{"waypoints":[{"lat":47,"lon":8.02,"alt":1150,"speed":50},{"lat":47.03,"lon":8.02,"alt":1150,"speed":50}]}`
+	if resp, b := ts.do(http.MethodPost, "/v1/command/trajectory", body); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("trajectory: %d %s", resp.StatusCode, b)
+	}
+	const rad = math.Pi / 180
+	banked, climbing := false, false
+	for i := 0; i < 120; i++ {
+		ts.ticks(10)
+		var st sim.AircraftState
+		ts.getJSON("/v1/state", &st)
+		q := st.Quaternion
+		if math.Abs(q.Length()-1) > 1e-9 {
+			t.Fatalf("step %d: |q| = %.12f", i, q.Length())
+		}
+		// q and -q are the same rotation
+		want := vector.QuatFromEuler(st.RollDeg*rad, st.PitchDeg*rad, st.YawDeg*rad)
+		if math.Abs(math.Abs(q.Dot(want))-1) > 1e-9 {
+			t.Errorf("step %d: quaternion %+v is not roll %.2f pitch %.2f yaw %.2f", i, q, st.RollDeg, st.PitchDeg, st.YawDeg)
+		}
+		// the nose points along yaw and pitch in north-east-down
+		nose := q.RotateVec(vector.Vec3{X: 1})
+		cp := math.Cos(st.PitchDeg * rad)
+		wantNose := vector.Vec3{X: math.Cos(st.YawDeg*rad) * cp, Y: math.Sin(st.YawDeg*rad) * cp, Z: -math.Sin(st.PitchDeg * rad)}
+		if nose.Sub(wantNose).Length() > 1e-9 {
+			t.Errorf("step %d: nose %v, want %v", i, nose, wantNose)
+		}
+		banked = banked || math.Abs(st.RollDeg) > 5
+		climbing = climbing || st.PitchDeg > 1
+	}
+	if !banked || !climbing {
+		t.Errorf("banked %v, climbed %v: the route did not exercise the attitude", banked, climbing)
+	}
+}
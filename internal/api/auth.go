@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Scope is what an API key may do.
+type Scope string
+
+const (
+	// ScopeRead reads state, streams and queries (terrain, environment).
+	ScopeRead Scope = "read"
+	// ScopeControl also flies the aircraft and changes the simulation:
+	// every request that is not a read.
+	ScopeControl Scope = "control"
+)
+
+// allows reports whether a key with scope s may make a request needing
+// need.
+func (s Scope) allows(need Scope) bool {
+	return need == "" || s == ScopeControl || s == need
+}
+
+// routeScopes overrides the scope a route's method implies: reads are GET
+// and HEAD, everything else is control. An empty scope is open to all.
+// Webhooks (their URLs) and snapshots (the whole simulation, to restore)
+// are control even to read.
+var routeScopes = map[string]Scope{
+	"/health":          "",
+	"/ready":           "",
+	"/live":            "",
+	"/terrain/grid":    ScopeRead,
+	"/terrain/profile": ScopeRead,
+	"/sim/truth":       ScopeControl,
+	"/sim/snapshot":    ScopeControl,
+	"/webhooks":        ScopeControl,
+	"/webhooks/":       ScopeControl,
+}
+
+// queryKeyRoutes take the key as ?access_token= too, for browsers'
+// EventSource, which cannot send headers.
+var queryKeyRoutes = map[string]bool{
+	"/stream": true,
+	"/events": true,
+}
+
+// WithAPIKeys requires one of keys, presented as "Authorization: Bearer
+// <key>" or "X-API-Key: <key>" (or ?access_token=<key> on the event
+// streams), on every route but the health probes and /version.
+// Without keys (the default) the API is open.
+func WithAPIKeys(keys map[string]Scope) Option {
+	return func(s *Server) { s.apiKeys = keys }
+}
+
+// ParseAPIKeys reads keys, one "<scope> <key>" per line; blank lines and
+// lines starting with # are skipped.
+func ParseAPIKeys(r io.Reader) (map[string]Scope, error) {
+	keys := map[string]Scope{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) != 2 {
+			return nil, fmt.Errorf("line %d: want \"<scope> <key>\"", n)
+		}
+		switch scope := Scope(f[0]); scope {
+		case ScopeRead, ScopeControl:
+			keys[f[1]] = scope
+		default:
+			return nil, fmt.Errorf("line %d: unknown scope %q (want %s or %s)", n, f[0], ScopeRead, ScopeControl)
+		}
+	}
+	return keys, sc.Err()
+}
+
+// requestScope is the scope a request to path needs.
+func requestScope(path string, r *http.Request) Scope {
+	if sc, ok := routeScopes[path]; ok {
+		return sc
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return ScopeRead
+	}
+	return ScopeControl
+}
+
+// authorize checks r's key against need, answering 401 or 403 and
+// returning false when it falls short. It is called before a handler
+// writes anything, so a stream is refused before it starts.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, need Scope) bool {
+	if len(s.apiKeys) == 0 || need == "" {
+		return true
+	}
+	key := r.Header.Get("X-API-Key")
+	if h := r.Header.Get("Authorization"); key == "" && h != "" {
+		if k, ok := strings.CutPrefix(h, "Bearer "); ok {
+			key = strings.TrimSpace(k)
+		}
+	}
+	if key == "" && r.Method == http.MethodGet && queryKeyRoutes[strings.TrimPrefix(r.URL.Path, apiPrefix)] {
+		key = r.URL.Query().Get("access_token")
+	}
+	if key == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="flight-simulator"`)
+		jsonError(w, http.StatusUnauthorized, "an API key is required (Authorization: Bearer <key> or X-API-Key)")
+		return false
+	}
+	scope, ok := s.lookupKey(key)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="flight-simulator", error="invalid_token"`)
+		jsonError(w, http.StatusUnauthorized, "unknown API key")
+		return false
+	}
+	if !scope.allows(need) {
+		jsonError(w, http.StatusForbidden, fmt.Sprintf("this API key has the %s scope; the request needs %s", scope, need))
+		return false
+	}
+	return true
+}
+
+// lookupKey finds key's scope, comparing every key in constant time.
+func (s *Server) lookupKey(key string) (Scope, bool) {
+	var found Scope
+	for k, sc := range s.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			found = sc
+		}
+	}
+	return found, found != ""
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	keys := map[string]api.Scope{"read-key": api.ScopeRead, "control-key": api.ScopeControl}
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8}, api.WithAPIKeys(keys), api.WithFaults(true))
+	ts.ticks(2)
+
+	const (
+		none = ""
+		read = "read-key"
+		ctrl = "control-key"
+		bad  = "no-such-key"
+	)
+	for _, c := range []struct {
+		method, path, body string
+		key                string
+		status             int // 0 for neither 401 nor 403
+	}{
+		{http.MethodGet, "/v1/state", "", none, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/state", "", bad, http.StatusUnauthorized},
+		{http.MethodGet, "/state", "", none, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/state", "", read, http.StatusOK},
+		{http.MethodGet, "/v1/state", "", ctrl, http.StatusOK},
+		{http.MethodGet, "/v1/history", "", read, http.StatusOK},
+		{http.MethodGet, "/v1/environment/wind", "", read, http.StatusOK},
+		{http.MethodPost, "/v1/terrain/profile", `{}`, read, 0},
+
+		{http.MethodPost, "/v1/command/hold", "", read, http.StatusForbidden},
+		{http.MethodPost, "/v1/command/hold", "", ctrl, http.StatusAccepted},
+		{http.MethodPut, "/v1/environment/wind", `{"wx":1,"wy":0}`, read, http.StatusForbidden},
+		{http.MethodGet, "/v1/state?truth=1", "", read, http.StatusForbidden},
+		{http.MethodGet, "/v1/state?truth=1", "", ctrl, http.StatusOK},
+		{http.MethodGet, "/v1/sim/truth", "", read, http.StatusForbidden},
+		{http.MethodGet, "/v1/sim/truth", "", ctrl, http.StatusOK},
+		{http.MethodGet, "/v1/webhooks", "", read, http.StatusForbidden},
+		{http.MethodGet, "/v1/webhooks", "", ctrl, http.StatusOK},
+		{http.MethodGet, "/v1/webhooks/wh-1", "", read, http.StatusForbidden},
+		{http.MethodGet, "/webhooks", "", read, http.StatusForbidden},
+		{http.MethodGet, "/v1/sim/snapshot", "", read, http.StatusForbidden},
+		{http.MethodGet, "/v1/sim/snapshot", "", ctrl, http.StatusOK},
+
+		{http.MethodGet, "/v1/health", "", none, http.StatusOK},
+		{http.MethodGet, "/v1/live", "", none, http.StatusOK},
+		{http.MethodGet, "/version", "", none, http.StatusOK},
+		{http.MethodGet, "/ui/", "", none, http.StatusOK},
+
+		// a query key counts on the event streams only
+		{http.MethodGet, "/v1/state?access_token=read-key", "", none, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/stream?access_token=no-such-key", "", none, http.StatusUnauthorized},
+		{http.MethodGet, "/v1/events?access_token=no-such-key", "", none, http.StatusUnauthorized},
+	} {
+		var hdr []string
+		if c.key != "" {
+			hdr = []string{"X-API-Key", c.key}
+		}
+		resp, b := ts.do(c.method, c.path, c.body, hdr...)
+		name := c.method + " " + c.path + " with " + c.key
+		switch c.status {
+		case 0:
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				t.Errorf("%s: %d %s", name, resp.StatusCode, b)
+			}
+		case http.StatusUnauthorized:
+			e := wantError(t, resp, b, http.StatusUnauthorized, "unauthorized")
+			www := resp.Header.Get("WWW-Authenticate")
+			if !strings.HasPrefix(www, "Bearer ") {
+				t.Errorf("%s: WWW-Authenticate %q", name, www)
+			}
+			if invalidToken := strings.Contains(www, "invalid_token"); invalidToken != (c.key == bad || strings.Contains(c.path, "no-such-key")) {
+				t.Errorf("%s: WWW-Authenticate %q (%s)", name, www, e.Message)
+			}
+		case http.StatusForbidden:
+			e := wantError(t, resp, b, http.StatusForbidden, "forbidden")
+			if !strings.Contains(e.Message, "read scope") {
+				t.Errorf("%s: %q", name, e.Message)
+			}
+		default:
+			if resp.StatusCode != c.status {
+				t.Errorf("%s: %d, want %d (%s)", name, resp.StatusCode, c.status, b)
+			}
+		}
+	}
+
+	// the bearer form, and the query key on the event streams
+	resp, b := ts.do(http.MethodPost, "/v1/command/stop", "", "Authorization", "Bearer control-key")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("bearer: %d %s", resp.StatusCode, b)
+	}
+	for _, path := range []string{"/v1/stream?access_token=read-key", "/v1/events?access_token=read-key"} {
+		resp, _ := ts.stream(path)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Errorf("%s: %d %s", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := api.ParseAPIKeys(strings.NewReader("# keys\n\nread  r1\ncontrol c1\n"))
+	if err != nil || len(keys) != 2 || keys["r1"] != api.ScopeRead || keys["c1"] != api.ScopeControl {
+		t.Errorf("ParseAPIKeys = %v, %v", keys, err)
+	}
+	for _, in := range []string{"read\n", "read a b\n", "admin k\n"} {
+		if _, err := api.ParseAPIKeys(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseAPIKeys(%q) accepted", in)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// commandRecord is a record of GET /commands/history without its command.
+type commandRecord struct {
+	Seq          uint64
+	Type         sim.CommandType
+	Source       string
+	Client       string
+	TakenAt      *time.Time
+	Outcome      sim.CommandOutcome
+	EndedAt      *time.Time
+	SupersededBy string
+}
+
+func TestCommandHistory(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	for _, c := range []struct{ path, body, id string }{
+		{"/v1/command/goto", `{"lat":47.01,"lon":8,"alt":1000}`, "req-goto"},
+		{"/v1/command/hold", "", "req-hold"},
+		{"/v1/command/stop", "", "req-stop"},
+	} {
+		if resp, b := ts.do(http.MethodPost, c.path, c.body, "X-Request-ID", c.id); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("%s: %d %s", c.path, resp.StatusCode, b)
+		}
+		ts.ticks(1)
+	}
+
+	var recs []commandRecord
+	ts.getJSON("/v1/commands/history", &recs)
+	want := []struct {
+		typ          sim.CommandType
+		source       string
+		outcome      sim.CommandOutcome
+		supersededBy string
+	}{
+		{sim.CmdGoTo, "req-goto", sim.OutcomeSuperseded, "hold"},
+		{sim.CmdHold, "req-hold", sim.OutcomeSuperseded, "stop"},
+		{sim.CmdStop, "req-stop", sim.OutcomeCompleted, ""},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("%d records, want %d: %+v", len(recs), len(want), recs)
+	}
+	for i, w := range want {
+		r := recs[i]
+		if r.Seq != uint64(i+1) || r.Type != w.typ || r.Source != w.source || r.Outcome != w.outcome || r.SupersededBy != w.supersededBy {
+			t.Errorf("record %d: %+v, want %+v", i, r, w)
+		}
+		if r.Client == "" || r.TakenAt == nil || r.EndedAt == nil {
+			t.Errorf("record %d: client %q, taken %v, ended %v", i, r.Client, r.TakenAt, r.EndedAt)
+		}
+	}
+
+	for _, c := range []struct {
+		query string
+		seqs  []uint64
+	}{
+		{"type=hold", []uint64{2}},
+		{"outcome=superseded", []uint64{1, 2}},
+		{"outcome=superseded&limit=1", []uint64{2}},
+		{"type=trajectory", nil},
+	} {
+		recs = nil
+		ts.getJSON("/v1/commands/history?"+c.query, &recs)
+		var seqs []uint64
+		for _, r := range recs {
+			seqs = append(seqs, r.Seq)
+		}
+		if !slices.Equal(seqs, c.seqs) {
+			t.Errorf("%s: seqs %v, want %v", c.query, seqs, c.seqs)
+		}
+	}
+}
+
+func TestCommandHistoryErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct{ query, code, field string }{
+		{"type=loop", "invalid_type", "type"},
+		{"outcome=expired", "invalid_outcome", "outcome"},
+		{"limit=0", "invalid_limit", "limit"},
+		{"limit=x", "invalid_limit", "limit"},
+	} {
+		resp, b := ts.do(http.MethodGet, "/v1/commands/history?"+c.query, "")
+		e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+		if e.Details["field"] != c.field {
+			t.Errorf("%s: details %v", c.query, e.Details)
+		}
+	}
+	resp, b := ts.do(http.MethodPost, "/v1/commands/history", "")
+	wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+}
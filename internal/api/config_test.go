@@ -0,0 +1,71 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+func TestConfigPatch(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, TickHz: 20})
+	var before sim.RuntimeConfig
+	ts.getJSON("/v1/config", &before)
+	if before.TickHz != 20 || before.Origin.Lat != 47 || len(before.ReadOnly) == 0 {
+		t.Fatalf("config %+v", before)
+	}
+
+	resp, b := ts.do(http.MethodPatch, "/v1/config", `{"publishHz":5,"limits":{"defaultSpeed":12},"wind":{"wx":3,"wy":-4}}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("patch: %d %s", resp.StatusCode, b)
+	}
+	var after sim.RuntimeConfig
+	ts.getJSON("/v1/config", &after)
+	if after.PublishHz != 5 || after.Limits.DefaultSpeed != 12 || after.Wind.Wx != 3 || after.Wind.Wy != -4 {
+		t.Errorf("patched config %+v", after)
+	}
+	if after.Limits.MaxClimbRate != before.Limits.MaxClimbRate {
+		t.Errorf("maxClimbRate %v changed to %v", before.Limits.MaxClimbRate, after.Limits.MaxClimbRate)
+	}
+
+	// the publish rate is clamped at the tick rate
+	ts.do(http.MethodPatch, "/v1/config", `{"publishHz":100}`)
+	ts.getJSON("/v1/config", &after)
+	if after.PublishHz != 20 {
+		t.Errorf("publishHz %v, want the tick rate", after.PublishHz)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	var before sim.RuntimeConfig
+	ts.getJSON("/v1/config", &before)
+	for _, c := range []struct {
+		body, code, field, want string
+	}{
+		{`{"tickHz":50}`, "read_only", "tickHz", "restart"},
+		{`{"origin":{"lat":1,"lon":2}}`, "read_only", "origin", "/sim/origin"},
+		{`{"nope":1}`, "unknown_field", "nope", "nope"},
+		{`{"publishHz":0}`, "bad_request", "", "publishHz"},
+		{`{"crosswindLimitMps":-1}`, "bad_request", "", "crosswindLimitMps"},
+		// all or nothing: the good publish rate is not applied either
+		{`{"publishHz":2,"limits":{"maxClimbRate":-1}}`, "bad_request", "", ""},
+	} {
+		resp, b := ts.do(http.MethodPatch, "/v1/config", c.body)
+		e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+		if !strings.Contains(e.Message, c.want) {
+			t.Errorf("%s: %q, want it to mention %q", c.body, e.Message, c.want)
+		}
+		if field, _ := e.Details["field"].(string); field != c.field {
+			t.Errorf("%s: field %q, want %q", c.body, field, c.field)
+		}
+	}
+	var after sim.RuntimeConfig
+	ts.getJSON("/v1/config", &after)
+	if after.PublishHz != before.PublishHz || after.Limits != before.Limits {
+		t.Errorf("rejected patches changed the config: %+v, then %+v", before, after)
+	}
+	resp, b := ts.do(http.MethodPost, "/v1/config", `{}`)
+	wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/sim"
+)
+
+// Every error response is an envelope:
+//
+//	{"error": {"code": "invalid_latitude", "message": "...", "details": {"field": "waypoints[2].lat"}}}
+//
+// code is stable and meant for programs; message is for people and may
+// change. details is left out when there are none.
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// apiError is an error that knows its code, and for a validation failure
+// the path of the offending field.
+type apiError struct {
+	status  int // overrides the handler's status when set
+	code    string
+	field   string
+	msg     string
+	details map[string]any // added to the field, when there is more to say
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+// invalid returns a validation error for field.
+func invalid(code, field, format string, args ...any) error {
+	return &apiError{code: code, field: field, msg: fmt.Sprintf(format, args...)}
+}
+
+// atField puts err under path: a validation error's field becomes
+// path.field, and the message names path.
+func atField(path string, err error) error {
+	e := &apiError{field: path, msg: fmt.Sprintf("%s: %s", path, err)}
+	var ae *apiError
+	if errors.As(err, &ae) {
+		e.status, e.code, e.details = ae.status, ae.code, ae.details
+		if ae.field != "" {
+			e.field = path + "." + ae.field
+		}
+	}
+	return e
+}
+
+// sentinelCodes are the codes of the engine's errors that callers tell
+// apart.
+var sentinelCodes = []struct {
+	err  error
+	code string
+}{
+	{sim.ErrReplay, "replaying"},
+	{sim.ErrOverloaded, "overloaded"},
+	{sim.ErrCommandActive, "command_active"},
+	{sim.ErrNoActiveCommand, "no_active_command"},
+	{sim.ErrNoTerrain, "no_terrain"},
+	{sim.ErrNoBattery, "no_battery"},
+	{sim.ErrFaultActive, "fault_active"},
+	{sim.ErrNoWeatherCell, "weather_cell_not_found"},
+	{sim.ErrWindTooStrong, "wind_too_strong"},
+	{sim.ErrTooManySamples, "too_many_samples"},
+	{sim.ErrOutOfRange, "out_of_range"},
+	{sim.ErrBelowFloor, "below_floor"},
+	{env.ErrWeatherCellExists, "weather_cell_exists"},
+	{env.ErrMicroburstExists, "microburst_exists"},
+	{context.DeadlineExceeded, "timeout"},
+	{context.Canceled, "canceled"},
+}
+
+// statusCodes are the codes of errors that have nothing more specific.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusRequestTimeout:        "timeout",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "body_too_large",
+	http.StatusInternalServerError:   "internal",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+func statusCode(status int) string {
+	if c, ok := statusCodes[status]; ok {
+		return c
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// writeError writes the error envelope.
+func writeError(w http.ResponseWriter, status int, code, msg string, details map[string]any) {
+	writeJSON(w, status, errorEnvelope{Error: errorBody{Code: code, Message: msg, Details: details}})
+}
+
+// jsonError answers with msg and the code that goes with status.
+func jsonError(w http.ResponseWriter, status int, msg string) {
+	writeError(w, status, statusCode(status), msg, nil)
+}
+
+// errorJSON answers with err, coded by what it is: an apiError's own code
+// (and status, when it has one), an engine sentinel's, or else status's.
+func errorJSON(w http.ResponseWriter, status int, err error) {
+	code := ""
+	var details map[string]any
+	var ae *apiError
+	if errors.As(err, &ae) {
+		if ae.status != 0 {
+			status = ae.status
+		}
+		code = ae.code
+		if ae.field != "" || len(ae.details) > 0 {
+			details = map[string]any{}
+			for k, v := range ae.details {
+				details[k] = v
+			}
+			if ae.field != "" {
+				details["field"] = ae.field
+			}
+		}
+	}
+	for _, s := range sentinelCodes {
+		if code == "" && errors.Is(err, s.err) {
+			code = s.code
+		}
+	}
+	if code == "" {
+		code = statusCode(status)
+	}
+	writeError(w, status, code, err.Error(), details)
+}
+
+// methodNotAllowed answers 405 with the methods the route takes, in the
+// Allow header and the details.
+func methodNotAllowed(w http.ResponseWriter, allow ...string) {
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	msg := allow[len(allow)-1] + " only"
+	if n := len(allow); n > 1 {
+		msg = strings.Join(allow[:n-1], ", ") + " or " + msg
+	}
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", msg, map[string]any{"allow": allow})
+}
+
+// notFound answers requests for paths no route serves.
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "route_not_found", fmt.Sprintf("no route for %s", r.URL.Path),
+		map[string]any{"path": r.URL.Path})
+}
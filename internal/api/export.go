@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// exportPage is how many history states the exports copy at a time; what
+// they keep of each is a trackPoint, a few dozen bytes.
+const exportPage = 2000
+
+type trackPoint struct {
+	ts            time.Time
+	lat, lon, alt float64
+}
+
+// eachHistory calls fn with the history newer than since, oldest first, a
+// page at a time, until fn fails or the history runs out. A zero since
+// starts from the oldest state held.
+func (s *Server) eachHistory(ctx context.Context, since time.Time, fn func([]sim.AircraftState) error) error {
+	if since.IsZero() {
+		// History takes a zero since as "the latest states"; just before
+		// the zero time comes ahead of every state, Step's included
+		since = since.Add(-time.Nanosecond)
+	}
+	for {
+		page, err := s.eng.History(ctx, since, exportPage)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		since = page[len(page)-1].TS
+	}
+}
+
+// exportTrack writes the history newer than ?since with write, as a file
+// to download.
+func (s *Server) exportTrack(w http.ResponseWriter, r *http.Request, contentType, name string, write func(io.Writer, []trackPoint)) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	since, _, ok := historyQuery(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var track []trackPoint
+	err := s.eachHistory(ctx, since, func(page []sim.AircraftState) error {
+		for _, st := range page {
+			track = append(track, trackPoint{ts: st.TS.UTC(), lat: st.Lat, lon: st.Lon, alt: st.Alt})
+		}
+		return nil
+	})
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	bw := bufio.NewWriter(w)
+	write(bw, track)
+	bw.Flush()
+}
+
+func (s *Server) exportTrackKML(w http.ResponseWriter, r *http.Request) {
+	s.exportTrack(w, r, "application/vnd.google-earth.kml+xml", "track.kml", func(w io.Writer, track []trackPoint) {
+		io.WriteString(w, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document>
+<name>flight-simulator2 track</name>
+<Placemark>
+<name>Track</name>
+<gx:Track>
+<altitudeMode>absolute</altitudeMode>
+`)
+		// the schema wants every when ahead of the coords
+		for _, p := range track {
+			fmt.Fprintf(w, "<when>%s</when>\n", p.ts.Format(time.RFC3339Nano))
+		}
+		for _, p := range track {
+			fmt.Fprintf(w, "<gx:coord>%s %s %s</gx:coord>\n", coord(p.lon), coord(p.lat), coord(p.alt))
+		}
+		io.WriteString(w, "</gx:Track>\n</Placemark>\n</Document>\n</kml>\n")
+	})
+}
+
+func (s *Server) exportTrackGPX(w http.ResponseWriter, r *http.Request) {
+	s.exportTrack(w, r, "application/gpx+xml", "track.gpx", func(w io.Writer, track []trackPoint) {
+		io.WriteString(w, xml.Header+`<gpx version="1.1" creator="flight-simulator2" xmlns="http://www.topografix.com/GPX/1/1">
+<trk>
+<name>flight-simulator2 track</name>
+<trkseg>
+`)
+		for _, p := range track {
+			fmt.Fprintf(w, "<trkpt lat=\"%s\" lon=\"%s\"><ele>%s</ele><time>%s</time></trkpt>\n",
+				coord(p.lat), coord(p.lon), coord(p.alt), p.ts.Format(time.RFC3339Nano))
+		}
+		io.WriteString(w, "</trkseg>\n</trk>\n</gpx>\n")
+	})
+}
+
+// exportPlanKML writes the loaded trajectory as a placemark per waypoint
+// and a path through them.
+func (s *Server) exportPlanKML(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	snap, err := s.eng.Snapshot(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	if len(snap.Trajectory) == 0 {
+		writeError(w, http.StatusNotFound, "trajectory_not_found", "no trajectory loaded", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="plan.kml"`)
+	bw := bufio.NewWriter(w)
+	io.WriteString(bw, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+<name>flight-simulator2 plan</name>
+`)
+	for i, wp := range snap.Trajectory {
+		// a terrain-following waypoint stands at its height above the ground
+		mode, alt := "absolute", wp.Alt
+		if wp.AGL > 0 {
+			mode, alt = "relativeToGround", wp.AGL
+		}
+		fmt.Fprintf(bw, "<Placemark><name>WP %d</name><description>", i)
+		xml.EscapeText(bw, []byte(waypointDescription(i, snap.TrajIdx, wp)))
+		fmt.Fprintf(bw, "</description><Point><altitudeMode>%s</altitudeMode><coordinates>%s,%s,%s</coordinates></Point></Placemark>\n",
+			mode, coord(wp.Lon), coord(wp.Lat), coord(alt))
+	}
+	io.WriteString(bw, "<Placemark><name>Path</name><LineString><altitudeMode>absolute</altitudeMode><coordinates>\n")
+	path := snap.Trajectory
+	if snap.TrajLoop {
+		path = append(path[:len(path):len(path)], path[0])
+	}
+	for _, wp := range path {
+		fmt.Fprintf(bw, "%s,%s,%s\n", coord(wp.Lon), coord(wp.Lat), coord(wp.Alt))
+	}
+	io.WriteString(bw, "</coordinates></LineString></Placemark>\n</Document>\n</kml>\n")
+	bw.Flush()
+}
+
+func waypointDescription(i, current int, wp sim.Waypoint) string {
+	d := fmt.Sprintf("alt %.1f m", wp.Alt)
+	if wp.AGL > 0 {
+		d = fmt.Sprintf("%.1f m above the terrain", wp.AGL)
+	}
+	if wp.Speed > 0 {
+		d += fmt.Sprintf(", %.1f m/s", wp.Speed)
+	}
+	switch {
+	case i < current:
+		d += ", reached"
+	case i == current:
+		d += ", next"
+	}
+	return d
+}
+
+// coord formats a coordinate or altitude with no more digits than it needs.
+func coord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
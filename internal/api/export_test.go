@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// longFloat matches a number with more decimals than golden files keep.
+var longFloat = regexp.MustCompile(`-?\d+\.\d{7,}`)
+
+// golden compares got with testdata/name, or with -update writes it there.
+// Numbers are rounded to six decimals first, a tenth of a metre in latitude,
+// so the files hold on platforms that fuse floating point differently.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	got = longFloat.ReplaceAllFunc(got, func(b []byte) []byte {
+		v, _ := strconv.ParseFloat(string(b), 64)
+		return strconv.AppendFloat(nil, math.Round(v*1e6)/1e6, 'f', -1, 64)
+	})
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test -update to create it", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gl, wl := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(gl) || i < len(wl); i++ {
+		var g, w string
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if g != w {
+			t.Errorf("%s differs at line %d (go test -update rewrites it):\ngot:  %s\nwant: %s", path, i+1, g, w)
+			return
+		}
+	}
+}
+
+func TestExportGolden(t *testing.T) {
+	// one published state a second keeps the files short
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000, PublishHz: 1})
+	body := `{"waypoints":[{"lat":47.003,"lon":8,"alt":1050,"speed":40},{"lat":47.003,"lon":8.004,"alt":1050,"speed":40}]}`
+	if resp, b := ts.do(http.MethodPost, "/v1/command/trajectory", body); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("trajectory: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(200)
+
+	for _, c := range []struct {
+		path, golden, contentType, filename string
+	}{
+		{"/v1/state.geojson", "state.geojson.golden", "application/geo+json", ""},
+		{"/v1/state.geojson?units=aviation", "state-aviation.geojson.golden", "application/geo+json", ""},
+		{"/v1/history.geojson?waypoints=1", "history.geojson.golden", "application/geo+json", ""},
+		{"/v1/export/track.kml", "track.kml.golden", "application/vnd.google-earth.kml+xml", "track.kml"},
+		{"/v1/export/track.gpx", "track.gpx.golden", "application/gpx+xml", "track.gpx"},
+		{"/v1/export/plan.kml", "plan.kml.golden", "application/vnd.google-earth.kml+xml", "plan.kml"},
+	} {
+		resp, b := ts.do(http.MethodGet, c.path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: %d %s", c.path, resp.StatusCode, b)
+			continue
+		}
+		if got := resp.Header.Get("Content-Type"); got != c.contentType {
+			t.Errorf("%s: Content-Type %q, want %q", c.path, got, c.contentType)
+		}
+		if want := `attachment; filename="` + c.filename + `"`; c.filename != "" && resp.Header.Get("Content-Disposition") != want {
+			t.Errorf("%s: Content-Disposition %q, want %q", c.path, resp.Header.Get("Content-Disposition"), want)
+		}
+		golden(t, c.golden, b)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/v1/export/plan.kml", http.StatusNotFound, "trajectory_not_found"},
+		{http.MethodGet, "/v1/export/track.gpx?since=yesterday", http.StatusBadRequest, "invalid_since"},
+		{http.MethodGet, "/v1/history.geojson?waypoints=2", http.StatusBadRequest, "invalid_waypoints"},
+		{http.MethodGet, "/v1/state.geojson?fields=bogus", http.StatusBadRequest, "unknown_field"},
+		{http.MethodPost, "/v1/export/track.kml", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodDelete, "/v1/state.geojson", http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		resp, b := ts.do(c.method, c.path, "")
+		wantError(t, resp, b, c.status, c.code)
+	}
+}
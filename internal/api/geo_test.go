@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+type localOut struct {
+	X, Y, Z    float64
+	Projection sim.Projection
+}
+
+type geoOut struct {
+	Lat, Lon, Alt float64
+	Projection    sim.Projection
+}
+
+func TestGeoConversionRoundTrip(t *testing.T) {
+	// 100 km out from an origin at 60°N, where the fixed 111 320 m per
+	// degree of the equirectangular conversion is off by hundreds of metres
+	for _, proj := range []sim.Projection{"", sim.ProjectionWGS84, sim.ProjectionEquirectangular} {
+		ts := newTestServer(t, sim.Config{OriginLat: 60, OriginLon: 10, Projection: proj})
+		want := proj
+		if want == "" {
+			want = sim.ProjectionWGS84
+		}
+		for _, p := range []struct{ lat, lon float64 }{
+			{60.9, 10}, {60, 11.8}, {59.4, 8.9}, {60, 10},
+		} {
+			var l localOut
+			ts.getJSON(fmt.Sprintf("/v1/geo/tolocal?lat=%g&lon=%g&alt=250", p.lat, p.lon), &l)
+			if l.Projection != want {
+				t.Errorf("%q: projection %q, want %q", proj, l.Projection, want)
+			}
+			var g geoOut
+			ts.getJSON(fmt.Sprintf("/v1/geo/togeo?x=%.6f&y=%.6f&z=%.6f", l.X, l.Y, l.Z), &g)
+			if d := sim.HaversineM(p.lat, p.lon, g.Lat, g.Lon); d > 0.5 || math.Abs(g.Alt-250) > 0.5 {
+				t.Errorf("%q: %g,%g → %v → %g,%g,%g, %.2f m off", proj, p.lat, p.lon, l, g.Lat, g.Lon, g.Alt, d)
+			}
+		}
+	}
+
+	// A degree of latitude is 111.4 km at 60°N on the ellipsoid.
+	wgs := newTestServer(t, sim.Config{OriginLat: 60, OriginLon: 10})
+	var l localOut
+	wgs.getJSON("/v1/geo/tolocal?lat=60.9&lon=10&alt=0", &l)
+	if want := 0.9 * 111_415.0; math.Abs(l.Y-want) > 150 {
+		t.Errorf("0.9° north of 60°N is %.0f m on the WGS84 plane, want %.0f", l.Y, want)
+	}
+}
+
+func TestGeoConversionErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/v1/geo/tolocal?lat=47&lon=8", http.StatusBadRequest, "bad_request"},
+		{http.MethodGet, "/v1/geo/tolocal?lat=x&lon=8&alt=0", http.StatusBadRequest, "bad_request"},
+		{http.MethodGet, "/v1/geo/tolocal?lat=91&lon=8&alt=0", http.StatusBadRequest, "invalid_latitude"},
+		{http.MethodGet, "/v1/geo/tolocal?lat=47&lon=-181&alt=0", http.StatusBadRequest, "invalid_longitude"},
+		{http.MethodGet, "/v1/geo/togeo?x=1&y=NaN&z=0", http.StatusBadRequest, "bad_request"},
+		{http.MethodGet, "/v1/geo/togeo?x=6e6&y=0&z=0", http.StatusBadRequest, "out_of_range"},
+		{http.MethodPost, "/v1/geo/tolocal?lat=47&lon=8&alt=0", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodPost, "/v1/geo/togeo?x=0&y=0&z=0", http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		resp, b := ts.do(c.method, c.path, "")
+		wantError(t, resp, b, c.status, c.code)
+	}
+}
+
+func TestGoToArrivesNearTheOrigin(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.003,"lon":8.004,"alt":1050,"speed":40}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	var st sim.AircraftState
+	for i := 0; i < 120; i++ {
+		ts.ticks(20)
+		st = sim.AircraftState{}
+		ts.getJSON("/v1/state", &st)
+		if st.ActiveCommand == "" {
+			break
+		}
+	}
+	if st.ActiveCommand != "" {
+		t.Fatalf("still flying %q after two minutes", st.ActiveCommand)
+	}
+	lim := sim.DefaultLimits()
+	if d := sim.HaversineM(st.Lat, st.Lon, 47.003, 8.004); d > lim.PosTolM {
+		t.Errorf("arrived %.1f m from the target, beyond the %g m tolerance", d, lim.PosTolM)
+	}
+	if math.Abs(st.Alt-1050) > lim.AltTolM {
+		t.Errorf("arrived at %.1f m, want 1050 ± %g", st.Alt, lim.AltTolM)
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// geoJSONFields are the state fields a GeoJSON feature carries as
+// properties unless ?fields= names others.
+var geoJSONFields = []string{
+	"alt", "altRef", "headingDeg", "trackDeg", "groundSpeedMps", "verticalSpeedMps",
+	"activeCommand", "warning", "ts", "seq",
+}
+
+// feature is a GeoJSON Feature (RFC 7946).
+type feature struct {
+	Type       string    `json:"type"`
+	Geometry   *geometry `json:"geometry"`
+	Properties any       `json:"properties"`
+}
+
+type geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+// position is a GeoJSON position: longitude first, then latitude and the
+// altitude in metres.
+func position(st sim.AircraftState) [3]float64 {
+	return [3]float64{st.Lon, st.Lat, st.Alt}
+}
+
+func writeGeoJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// parseGeoView is parseStateView with the GeoJSON properties as the default
+// fields.
+func (s *Server) parseGeoView(ctx context.Context, r *http.Request) (stateView, error) {
+	v, err := s.parseStateView(ctx, r)
+	if err != nil || v.fields != nil {
+		return v, err
+	}
+	for _, name := range geoJSONFields {
+		if c, ok := aviationUnits[name]; ok && v.aviation {
+			name = c.name
+		}
+		v.fields = append(v.fields, name)
+	}
+	return v, nil
+}
+
+// stateFeature returns st as a Point feature. The altitude of the point is
+// in the view's datum, always in metres.
+func (s *Server) stateFeature(v stateView, st sim.AircraftState) (feature, error) {
+	props, err := s.render(v, st)
+	if err != nil {
+		return feature{}, err
+	}
+	st, err = s.eng.InDatum(st, v.altRef)
+	if err != nil {
+		return feature{}, err
+	}
+	return feature{
+		Type:       "Feature",
+		Geometry:   &geometry{Type: "Point", Coordinates: position(st)},
+		Properties: props,
+	}, nil
+}
+
+func (s *Server) stateGeoJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	v, err := s.parseGeoView(ctx, r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	st, err := s.eng.GetState(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	f, err := s.stateFeature(v, st)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	writeGeoJSON(w, f)
+}
+
+// historyGeoJSON returns the recent track as a LineString feature, and
+// with ?waypoints=1 the waypoint arrivals found in it as a MultiPoint.
+func (s *Server) historyGeoJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	since, limit, ok := historyQuery(w, r)
+	if !ok {
+		return
+	}
+	waypoints := false
+	switch r.URL.Query().Get("waypoints") {
+	case "", "0", "false":
+	case "1", "true":
+		waypoints = true
+	default:
+		errorJSON(w, http.StatusBadRequest, invalid("invalid_waypoints", "waypoints", "waypoints must be 0 or 1"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	v, err := s.parseStateView(ctx, r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	states, err := s.eng.History(ctx, since, limit)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	coords := make([][3]float64, len(states))
+	for i, st := range states {
+		if st, err = s.eng.InDatum(st, v.altRef); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		states[i] = st
+		coords[i] = position(st)
+	}
+
+	track := feature{Type: "Feature", Properties: map[string]any{"count": len(states), "altRef": v.altRef}}
+	if len(states) >= 2 {
+		// a LineString needs two positions; a shorter track has no geometry
+		track.Geometry = &geometry{Type: "LineString", Coordinates: coords}
+	}
+	if n := len(states); n > 0 {
+		props := track.Properties.(map[string]any)
+		props["from"], props["to"] = states[0].TS, states[n-1].TS
+		props["fromSeq"], props["toSeq"] = states[0].Seq, states[n-1].Seq
+	}
+	fc := featureCollection{Type: "FeatureCollection", Features: []feature{track}}
+	if waypoints {
+		fc.Features = append(fc.Features, arrivals(states))
+	}
+	writeGeoJSON(w, fc)
+}
+
+// arrivals finds where a trajectory reached its waypoints in states: where
+// the target index moves on, or the trajectory ends with no command after
+// it (a stop looks the same). The feature's properties list each arrival's
+// waypoint index and time, in order.
+func arrivals(states []sim.AircraftState) feature {
+	coords := [][3]float64{}
+	index := []int{}
+	ts := []time.Time{}
+	for i := 1; i < len(states); i++ {
+		prev, st := states[i-1], states[i]
+		if prev.ActiveCommand != string(sim.CmdTrajectory) {
+			continue
+		}
+		moved := st.ActiveCommand == prev.ActiveCommand && st.TargetIndex != prev.TargetIndex
+		if !moved && st.ActiveCommand != "" {
+			continue
+		}
+		coords = append(coords, position(st))
+		index = append(index, prev.TargetIndex)
+		ts = append(ts, st.TS)
+	}
+	f := feature{Type: "Feature", Properties: map[string]any{"kind": "waypoint_reached", "waypoints": index, "ts": ts}}
+	if len(coords) > 0 {
+		f.Geometry = &geometry{Type: "MultiPoint", Coordinates: coords}
+	}
+	return f
+}
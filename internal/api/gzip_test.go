@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+func TestStreamThroughGzip(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8}, api.WithSSEHeartbeat(50*time.Millisecond))
+	resp, events := ts.stream("/v1/stream", "Accept-Encoding", "gzip")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("%d, Content-Encoding %q", resp.StatusCode, resp.Header.Get("Content-Encoding"))
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" || resp.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Content-Type %q, Vary %q", resp.Header.Get("Content-Type"), resp.Header.Get("Vary"))
+	}
+
+	// each state comes out of the compressor as it is sent, not when a
+	// block fills or the stream ends
+	last := seqOf(t, next(t, events, "state"))
+	for i := 0; i < 10; i++ {
+		ts.ticks(1)
+		if seq := seqOf(t, next(t, events, "state")); seq != last+1 {
+			t.Fatalf("seq %d after %d", seq, last)
+		}
+		last++
+	}
+	// and so does the heartbeat between them
+	timeout := time.After(2 * time.Second)
+	for ev := (sseEvent{}); ev.comment != "ping"; {
+		select {
+		case ev = <-events:
+		case <-timeout:
+			t.Fatal("no heartbeat through gzip in two seconds")
+		}
+	}
+}
+
+func TestGzipNegotiation(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(5)
+	for _, c := range []struct {
+		accept string
+		gzip   bool
+	}{
+		{"gzip", true},
+		{"br, gzip;q=0.5", true},
+		{"GZIP", true},
+		{"gzip;q=0", false},
+		{"identity", false},
+		{"br", false},
+	} {
+		resp, b := ts.do(http.MethodGet, "/v1/history?limit=5", "", "Accept-Encoding", c.accept)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: %d", c.accept, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Encoding") == "gzip"; got != c.gzip {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q", c.accept, resp.Header.Get("Content-Encoding"))
+			continue
+		}
+		if c.gzip {
+			gz, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("%s: %v", c.accept, err)
+			}
+			if b, err = io.ReadAll(gz); err != nil {
+				t.Fatalf("%s: %v", c.accept, err)
+			}
+		}
+		var states []sim.AircraftState
+		if err := json.Unmarshal(b, &states); err != nil || len(states) != 5 {
+			t.Errorf("%s: %d states (%v)", c.accept, len(states), err)
+		}
+	}
+
+	// a response without a body is left alone
+	resp, _ := ts.do(http.MethodGet, "/v1/state/wait?afterSeq=1000&timeoutS=0", "", "Accept-Encoding", "gzip")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("wait: %d, Content-Encoding %q", resp.StatusCode, resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// BenchmarkHistory serves the 1000 latest states, with and without gzip,
+// and reports the size of a response.
+func BenchmarkHistory(b *testing.B) {
+	ts := newTestServer(b, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	if resp, body := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.2,"lon":8.1,"alt":1500,"speed":60}`); resp.StatusCode != http.StatusAccepted {
+		b.Fatalf("goto: %d %s", resp.StatusCode, body)
+	}
+	ts.ticks(1000)
+	for _, encoding := range []string{"identity", "gzip"} {
+		b.Run(encoding, func(b *testing.B) {
+			var size int
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				resp, body := ts.do(http.MethodGet, "/v1/history?limit=1000", "", "Accept-Encoding", encoding)
+				if resp.StatusCode != http.StatusOK {
+					b.Fatalf("%d %s", resp.StatusCode, body)
+				}
+				size = len(body)
+			}
+			b.ReportMetric(float64(size), "B/response")
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+// probe is the body of a health answer.
+type probe struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	Ready  bool   `json:"ready"`
+	Ticks  uint64 `json:"ticks"`
+}
+
+func TestHealthProbes(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	get := func(path string, status int) probe {
+		t.Helper()
+		resp, b := ts.do(http.MethodGet, path, "")
+		if resp.StatusCode != status {
+			t.Fatalf("%s: %d, want %d: %s", path, resp.StatusCode, status, b)
+		}
+		if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("%s: Cache-Control %q", path, cc)
+		}
+		var p probe
+		if err := json.Unmarshal(b, &p); err != nil {
+			t.Fatalf("%s: %v in %s", path, err, b)
+		}
+		return p
+	}
+
+	// running but not yet ticked: alive, not ready
+	for _, path := range []string{"/v1/health", "/v1/ready"} {
+		if p := get(path, http.StatusServiceUnavailable); p.Status != "unavailable" || p.Reason != "no tick processed yet" {
+			t.Errorf("%s before a tick: %+v", path, p)
+		}
+	}
+	if p := get("/v1/live", http.StatusOK); p.Status != "ok" {
+		t.Errorf("/v1/live before a tick: %+v", p)
+	}
+
+	ts.ticks(3)
+	for _, path := range []string{"/v1/health", "/v1/ready", "/v1/live"} {
+		if p := get(path, http.StatusOK); p.Status != "ok" || !p.Ready || p.Ticks != 3 {
+			t.Errorf("%s after three ticks: %+v", path, p)
+		}
+	}
+	resp, b := ts.do(http.MethodGet, "/v1/health?verbose=0", "")
+	var terse map[string]any
+	if err := json.Unmarshal(b, &terse); err != nil || resp.StatusCode != http.StatusOK || len(terse) != 1 || terse["status"] != "ok" {
+		t.Errorf("verbose=0: %d %s", resp.StatusCode, b)
+	}
+}
@@ -1,190 +1,1644 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
 	"flight-simulator2/internal/sim"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// APIVersion is the version of the HTTP API, reported in the X-API-Version
+// header. Its routes are mounted under apiPrefix; the unprefixed paths are
+// deprecated aliases.
+const APIVersion = "1"
+
+const apiPrefix = "/v" + APIVersion
+
 const (
 	maxJSONBodyBytes = 1 << 20 // 1MB
+
+	// submitTimeout bounds how long a command handler waits for room in the
+	// engine's command queue before answering 503.
+	submitTimeout = 500 * time.Millisecond
+
+	defaultHistoryLimit = 1000
+	maxHistoryLimit     = 20000
+
+	defaultCommandLimit = 100
 )
 
+// DefaultMaxWaypoints is the most waypoints a trajectory or mission may
+// have, unless WithMaxWaypoints says otherwise.
+const DefaultMaxWaypoints = 2000
+
 type Server struct {
 	eng *sim.Engine
 	mux *http.ServeMux
+
+	allowTeleport bool
+	allowFaults   bool
+	build         string
+	apiKeys       map[string]Scope
+	log           *slog.Logger
+	missions      *MissionStore
+	webhooks      *webhookHub
+	maxWaypoints  int
+	compress      bool
+
+	sseHeartbeat    time.Duration
+	sseWriteTimeout time.Duration
+	hookBackoff     time.Duration
+	hookTimeout     time.Duration
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithTeleport enables POST /sim/setstate, which places the aircraft
+// anywhere without flying there. It is off by default.
+func WithTeleport(allow bool) Option {
+	return func(s *Server) { s.allowTeleport = allow }
+}
+
+// WithFaults enables /sim/fault and GET /sim/truth, which inject sensor
+// faults and expose the unfaulted state. It is off by default.
+func WithFaults(allow bool) Option {
+	return func(s *Server) { s.allowFaults = allow }
+}
+
+// WithMaxWaypoints sets the most waypoints a trajectory or mission may
+// have. Zero or negative means no limit but the size of the body.
+func WithMaxWaypoints(n int) Option {
+	return func(s *Server) { s.maxWaypoints = n }
+}
+
+// WithBuild sets the server build reported by GET /version.
+func WithBuild(build string) Option {
+	return func(s *Server) { s.build = build }
+}
+
+func NewServer(eng *sim.Engine, opts ...Option) *Server {
+	s := &Server{eng: eng, mux: http.NewServeMux(), build: "dev", log: slog.Default(),
+		maxWaypoints: DefaultMaxWaypoints, compress: true, sseHeartbeat: DefaultSSEHeartbeat, sseWriteTimeout: DefaultSSEWriteTimeout}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.missions == nil {
+		s.missions = NewMissionStore()
+	}
+	s.webhooks = newWebhookHub(eng, s.log, s.build, s.hookBackoff, s.hookTimeout)
+	s.routes()
+	return s
+}
+
+// Handler returns the API, with every response carrying X-API-Version and
+// X-Request-ID, and every request logged.
+func (s *Server) Handler() http.Handler {
+	return s.logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Version", APIVersion)
+		s.mux.ServeHTTP(w, r)
+	}))
+}
+
+// handle mounts h at apiPrefix+path, its canonical form, and at path as a
+// deprecated alias that points clients to the canonical one.
+// Requests are checked against the API keys first (see WithAPIKeys).
+func (s *Server) handle(path string, h http.HandlerFunc) {
+	authed := func(w http.ResponseWriter, r *http.Request) {
+		if s.authorize(w, r, requestScope(path, r)) {
+			h(w, r)
+		}
+	}
+	s.mux.HandleFunc(apiPrefix+path, authed)
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", apiPrefix, path))
+		authed(w, r)
+	})
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	v := map[string]string{
+		"apiVersion": APIVersion,
+		"build":      s.build,
+		"go":         runtime.Version(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, st := range info.Settings {
+			if st.Key == "vcs.revision" {
+				v["revision"] = st.Value
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("/", notFound)
+	s.mux.Handle("/ui/", s.ui())
+	s.mux.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+	s.mux.HandleFunc("/version", s.version)
+	s.mux.HandleFunc(apiPrefix+"/version", s.version)
+
+	s.handle("/health", s.health)
+	s.handle("/ready", s.health)
+	s.handle("/live", s.live)
+	s.handle("/state", s.gzip(s.state))
+	s.handle("/state/wait", s.gzip(s.stateWait))
+	s.handle("/state.geojson", s.gzip(s.stateGeoJSON))
+	s.handle("/stats", s.stats)
+
+	s.handle("/command/goto", s.gotoCmd)
+	s.handle("/command/trajectory", s.trajectoryCmd)
+
+	s.handle("/command/stop", s.stopCmd)
+	s.handle("/command/hold", s.holdCmd)
+	s.handle("/command/active", s.activeCmd)
+	s.handle("/commands/history", s.gzip(s.commandHistory))
+	s.handle("/missions", s.missionList)
+	s.handle("/missions/", s.mission)
+	s.handle("/webhooks", s.webhookList)
+	s.handle("/webhooks/", s.webhook)
+
+	s.handle("/stream", s.gzip(s.streamSSE))
+	s.handle("/events", s.gzip(s.eventsSSE))
+	s.handle("/history", s.gzip(s.history))
+	s.handle("/history.geojson", s.gzip(s.historyGeoJSON))
+	s.handle("/export/track.kml", s.gzip(s.exportTrackKML))
+	s.handle("/export/track.gpx", s.gzip(s.exportTrackGPX))
+	s.handle("/export/plan.kml", s.gzip(s.exportPlanKML))
+	s.handle("/geofence", s.geofence)
+	s.handle("/environment/wind", s.wind)
+	s.handle("/environment/weather", s.weather)
+	s.handle("/environment/microburst", s.microburst)
+	s.handle("/environment/at", s.environmentAt)
+	s.handle("/environment/obstacles", s.obstacles)
+	s.handle("/environment/terrain", s.terrainParams)
+	s.handle("/environment/scenario", s.scenario)
+	s.handle("/terrain", s.terrain)
+	s.handle("/terrain/grid", s.terrainGrid)
+	s.handle("/terrain/profile", s.terrainProfile)
+
+	s.handle("/config", s.config)
+	s.handle("/sim/params", s.params)
+	s.handle("/sim/battery", s.battery)
+	s.handle("/sim/odometer/reset", s.resetOdometer)
+	s.handle("/sim/reset", s.reset)
+	s.handle("/sim/origin", s.origin)
+	s.handle("/geo/tolocal", s.geoToLocal)
+	s.handle("/geo/togeo", s.geoToGeo)
+	s.handle("/sim/setstate", s.setState)
+	s.handle("/sim/fault", s.fault)
+	s.handle("/sim/truth", s.truth)
+	s.handle("/sim/snapshot", s.snapshot)
+	s.handle("/sim/restore", s.restore)
+}
+
+// health answers readiness: 200 once the engine has processed a tick and
+// keeps ticking, 503 otherwise. ?verbose=0 leaves out the details.
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	s.probe(w, r, func(h sim.Health) (bool, string) { return h.Ready, h.Reason })
+}
+
+// live answers liveness: 200 while the engine loop is running, even before
+// its first tick or while it lags.
+func (s *Server) live(w http.ResponseWriter, r *http.Request) {
+	s.probe(w, r, func(h sim.Health) (bool, string) {
+		if !h.Running {
+			return false, h.Reason
+		}
+		return true, ""
+	})
+}
+
+func (s *Server) probe(w http.ResponseWriter, r *http.Request, check func(sim.Health) (bool, string)) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	w.Header().Set("X-Dropped-Commands", strconv.FormatUint(s.eng.DroppedCommands(), 10))
+	w.Header().Set("Cache-Control", "no-store")
+
+	h := s.eng.Health()
+	ok, reason := check(h)
+	code, status := http.StatusOK, "ok"
+	if !ok {
+		code, status = http.StatusServiceUnavailable, "unavailable"
+	}
+	if r.URL.Query().Get("verbose") == "0" {
+		resp := map[string]any{"status": status}
+		if reason != "" {
+			resp["reason"] = reason
+		}
+		writeJSON(w, code, resp)
+		return
+	}
+	writeJSON(w, code, struct {
+		Status          string `json:"status"`
+		CommandsDropped uint64 `json:"commandsDropped"`
+		sim.Health
+	}{status, s.eng.DroppedCommands(), h})
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	st, err := s.eng.Stats(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) wind(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		rep, err := s.eng.Wind(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+
+	case http.MethodPut:
+		// either components or speed and direction (blowing toward)
+		var body struct {
+			Wx           *float64 `json:"wx"`
+			Wy           *float64 `json:"wy"`
+			Speed        *float64 `json:"speed"`
+			DirectionDeg *float64 `json:"directionDeg"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		var wind env.Wind
+		switch {
+		case body.Wx != nil && body.Wy != nil && body.Speed == nil && body.DirectionDeg == nil:
+			wind = env.Wind{Wx: *body.Wx, Wy: *body.Wy}
+		case body.Speed != nil && body.DirectionDeg != nil && body.Wx == nil && body.Wy == nil:
+			if *body.Speed < 0 {
+				errorJSON(w, http.StatusBadRequest, invalid("invalid_speed", "speed", "speed must be >= 0"))
+				return
+			}
+			wind = env.FromSpeedAndDir(*body.Speed, *body.DirectionDeg)
+		default:
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_wind", "", "give either wx and wy or speed and directionDeg"))
+			return
+		}
+		if err := s.eng.SetWind(ctx, wind); err != nil {
+			switch {
+			case errors.Is(err, sim.ErrReplay):
+				errorJSON(w, http.StatusConflict, err)
+			case ctx.Err() != nil:
+				errorJSON(w, http.StatusRequestTimeout, err)
+			default:
+				errorJSON(w, http.StatusBadRequest, err)
+			}
+			return
+		}
+		rep, err := s.eng.Wind(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut)
+	}
+}
+
+func (s *Server) scenario(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	sc, err := s.eng.Scenario(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+		} else {
+			errorJSON(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, sc)
+}
+
+func (s *Server) environmentAt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// each of ?lat=&lon=&alt= defaults to the aircraft's position
+	var q sim.ConditionsQuery
+	for _, p := range []struct {
+		name  string
+		dst   **float64
+		limit float64
+	}{{"lat", &q.Lat, 90}, {"lon", &q.Lon, 180}, {"alt", &q.Alt, 1e6}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(math.Abs(f) <= p.limit) {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_"+p.name, p.name, "%s must be a number within ±%g", p.name, p.limit))
+			return
+		}
+		*p.dst = &f
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	c, err := s.eng.ConditionsAt(ctx, q)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) obstacles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	obs, err := s.eng.Obstacles(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(obs), "obstacles": obs})
+}
+
+func (s *Server) terrainParams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	tp, err := s.eng.TerrainParams(ctx)
+	if err != nil {
+		switch {
+		case errors.Is(err, sim.ErrNoTerrain):
+			errorJSON(w, http.StatusNotFound, err)
+		case ctx.Err() != nil:
+			errorJSON(w, http.StatusRequestTimeout, err)
+		default:
+			errorJSON(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, tp)
+}
+
+func (s *Server) terrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	var lat, lon float64
+	if err := queryFloats(r, []string{"lat", "lon"}, &lat, &lon); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateLatLon(lat, lon); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	p, err := s.eng.TerrainAt(ctx, lat, lon)
+	if err != nil {
+		terrainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) terrainGrid(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var q sim.TerrainGridQuery
+	if err := decodeJSON(w, r, &q); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := q.Validate(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	g, err := s.eng.TerrainGrid(ctx, q)
+	if err != nil {
+		terrainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (s *Server) terrainProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var q sim.ProfileQuery
+	if err := decodeJSON(w, r, &q); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := q.Validate(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	samples, err := s.eng.TerrainProfile(ctx, q)
+	if err != nil {
+		terrainError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(samples), "samples": samples})
+}
+
+// terrainError answers a failed terrain query: 404 without terrain, 400
+// for a route over the sample cap, otherwise a timeout.
+func terrainError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sim.ErrNoTerrain):
+		errorJSON(w, http.StatusNotFound, err)
+	case errors.Is(err, sim.ErrTooManySamples):
+		errorJSON(w, http.StatusBadRequest, err)
+	default:
+		errorJSON(w, http.StatusRequestTimeout, err)
+	}
+}
+
+func (s *Server) state(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// ?truth=1 skips sensor noise and faults; it is as privileged as /sim/truth.
+	q := r.URL.Query().Get("truth")
+	truth := q != "" && q != "0" && q != "false"
+	if truth && !s.allowFaults {
+		jsonError(w, http.StatusForbidden, "truth is disabled (start the server with -allow-faults)")
+		return
+	}
+	if truth && !s.authorize(w, r, ScopeControl) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	view, err := s.parseStateView(ctx, r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	get := s.eng.GetState
+	if truth {
+		get = s.eng.Truth
+	}
+	st, err := get(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	out, err := s.render(view, st)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Default and bound of /state/wait's ?timeoutS, the bound below common
+// proxy idle timeouts.
+const (
+	defaultStateWait = 25 * time.Second
+	maxStateWait     = 60 * time.Second
+
+	// stateWaitWriteSlack is the time /state/wait leaves itself to write
+	// the answer after the wait.
+	stateWaitWriteSlack = 10 * time.Second
+)
+
+// stateWait long-polls for a state newer than ?afterSeq, for clients that
+// cannot keep an event stream open. It answers at once when there is one,
+// or with the next state published, or 204 after ?timeoutS.
+func (s *Server) stateWait(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	q := r.URL.Query()
+	var after uint64
+	if v := q.Get("afterSeq"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_after_seq", "afterSeq", "afterSeq must be a state seq"))
+			return
+		}
+		after = n
+	}
+	wait := defaultStateWait
+	if v := q.Get("timeoutS"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(t >= 0) || t > maxStateWait.Seconds() {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_timeout", "timeoutS", "timeoutS must be between 0 and %g", maxStateWait.Seconds()))
+			return
+		}
+		wait = time.Duration(t * float64(time.Second))
+	}
+	view, err := s.parseStateView(r.Context(), r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// The wait may outlast the http.Server's ReadTimeout and WriteTimeout.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Now().Add(wait + stateWaitWriteSlack))
+
+	// A subscription starts with the current state, which carries the seq
+	// of the last one published, and then delivers each new one.
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	ch, unsub := s.eng.Subscribe(ctx)
+	defer unsub()
+	for {
+		select {
+		case <-ctx.Done():
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case st, ok := <-ch:
+			if !ok {
+				// a wait that ends before the engine takes the
+				// subscription gets a closed channel too
+				if ctx.Err() != nil {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				jsonError(w, http.StatusServiceUnavailable, "engine stopped")
+				return
+			}
+			if st.Seq <= after {
+				continue
+			}
+			out, err := s.render(view, st)
+			if err != nil {
+				errorJSON(w, http.StatusBadRequest, err)
+				return
+			}
+			w.Header().Set("X-State-Seq", strconv.FormatUint(st.Seq, 10))
+			writeJSON(w, http.StatusOK, out)
+			return
+		}
+	}
+}
+
+func (s *Server) gotoCmd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var body struct {
+		Lat    float64  `json:"lat"`
+		Lon    float64  `json:"lon"`
+		Alt    float64  `json:"alt"`
+		Speed  float64  `json:"speed,omitempty"`
+		AGL    float64  `json:"agl,omitempty"`
+		AltRef string   `json:"altRef,omitempty"`
+		UTM    *sim.UTM `json:"utm,omitempty"`
+	}
+
+	if err := decodeJSON(w, r, &body); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	// Validate inputs
+	lat, lon, err := fromUTM(body.Lat, body.Lon, body.UTM)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	body.Lat, body.Lon = lat, lon
+	if err := validateLatLon(body.Lat, body.Lon); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	from, err := s.rangeOrigin(ctx, body.Lat, body.Lon)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	if err := s.checkRange(from, body.Lat, body.Lon); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	ref, err := s.altRef(ctx, body.AltRef)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.checkAlt(body.Alt, ref); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := checkSpeedAGL(body.Speed, body.AGL); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !s.submit(w, r, sim.GoToCommand{
+		At:     s.eng.Now(),
+		Lat:    body.Lat,
+		Lon:    body.Lon,
+		Alt:    body.Alt,
+		Speed:  body.Speed,
+		AGL:    body.AGL,
+		AltRef: ref,
+	}) {
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "goto"})
+}
+
+func (s *Server) trajectoryCmd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var body struct {
+		Waypoints []waypointIn `json:"waypoints"`
+		Loop      bool         `json:"loop,omitempty"`
+	}
+
+	if err := decodeJSON(w, r, &body); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	wps, err := s.waypoints(ctx, body.Waypoints)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !s.submit(w, r, sim.TrajectoryCommand{
+		At:        s.eng.Now(),
+		Waypoints: wps,
+		Loop:      body.Loop,
+	}) {
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":  "accepted",
+		"type":    "trajectory",
+		"count":   len(wps),
+		"lengthM": routeLengthM(wps, body.Loop),
+	})
+}
+
+// waypointIn is a waypoint of a request, given by lat/lon or UTM.
+type waypointIn struct {
+	sim.Waypoint
+	UTM *sim.UTM `json:"utm,omitempty"`
+}
+
+// waypoints validates the waypoints of a trajectory, naming the offending
+// one in the error.
+func (s *Server) waypoints(ctx context.Context, in []waypointIn) ([]sim.Waypoint, error) {
+	if len(in) == 0 {
+		return nil, invalid("waypoints_empty", "waypoints", "waypoints required")
+	}
+	if s.maxWaypoints > 0 && len(in) > s.maxWaypoints {
+		return nil, &apiError{code: "too_many_waypoints", field: "waypoints",
+			msg:     fmt.Sprintf("%d waypoints submitted; at most %d are allowed", len(in), s.maxWaypoints),
+			details: map[string]any{"count": len(in), "maxWaypoints": s.maxWaypoints}}
+	}
+	wps := make([]sim.Waypoint, len(in))
+	for i, w := range in {
+		wp, err := s.waypoint(ctx, w)
+		if err != nil {
+			return nil, atField(fmt.Sprintf("waypoints[%d]", i), err)
+		}
+		wps[i] = wp
+	}
+	from, err := s.rangeOrigin(ctx, wps[0].Lat, wps[0].Lon)
+	if err != nil {
+		return nil, &apiError{status: http.StatusRequestTimeout, code: "timeout", msg: err.Error()}
+	}
+	for i, wp := range wps {
+		if err := s.checkRange(from, wp.Lat, wp.Lon); err != nil {
+			return nil, atField(fmt.Sprintf("waypoints[%d]", i), err)
+		}
+	}
+	return wps, nil
+}
+
+func (s *Server) waypoint(ctx context.Context, in waypointIn) (sim.Waypoint, error) {
+	wp := in.Waypoint
+	lat, lon, err := fromUTM(wp.Lat, wp.Lon, in.UTM)
+	if err != nil {
+		return wp, err
+	}
+	wp.Lat, wp.Lon = lat, lon
+	if err := validateLatLon(wp.Lat, wp.Lon); err != nil {
+		return wp, err
+	}
+	ref, err := s.altRef(ctx, string(wp.AltRef))
+	if err != nil {
+		return wp, err
+	}
+	wp.AltRef = ref
+	if err := s.checkAlt(wp.Alt, ref); err != nil {
+		return wp, err
+	}
+	return wp, checkSpeedAGL(wp.Speed, wp.AGL)
+}
+
+// routeLengthM is the length of a trajectory in metres, to a decimetre,
+// counting the leg back to the start of a loop.
+func routeLengthM(wps []sim.Waypoint, loop bool) float64 {
+	lengthM := sim.RouteLengthM(wps)
+	if loop && len(wps) > 0 {
+		first, last := wps[0], wps[len(wps)-1]
+		lengthM += sim.HaversineM(last.Lat, last.Lon, first.Lat, first.Lon)
+	}
+	return math.Round(lengthM*10) / 10
+}
+
+func (s *Server) stopCmd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if !s.submit(w, r, sim.StopCommand{At: s.eng.Now()}) {
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "stop"})
+}
+
+func (s *Server) holdCmd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if !s.submit(w, r, sim.HoldCommand{At: s.eng.Now()}) {
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "hold"})
+}
+
+// activeCmd returns the command being flown (GET) or replaces it with a
+// hold (DELETE).
+func (s *Server) activeCmd(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		a, err := s.eng.Active(ctx)
+		switch {
+		case errors.Is(err, sim.ErrNoActiveCommand):
+			errorJSON(w, http.StatusNotFound, err)
+		case err != nil:
+			errorJSON(w, http.StatusRequestTimeout, err)
+		default:
+			writeJSON(w, http.StatusOK, a)
+		}
+
+	case http.MethodDelete:
+		cancelled, err := s.eng.CancelActive(ctx)
+		switch {
+		case errors.Is(err, sim.ErrNoActiveCommand):
+			errorJSON(w, http.StatusNotFound, err)
+		case errors.Is(err, sim.ErrReplay):
+			errorJSON(w, http.StatusConflict, err)
+		case err != nil:
+			errorJSON(w, http.StatusRequestTimeout, err)
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{"status": "holding", "cancelled": cancelled})
+		}
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	since, limit, ok := historyQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	states, err := s.eng.History(ctx, since, limit)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+// commandHistory returns the command log, oldest first, optionally only
+// the commands of one ?type= or ?outcome=.
+func (s *Server) commandHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	q := r.URL.Query()
+	query := sim.CommandLogQuery{Limit: defaultCommandLimit}
+	switch t := sim.CommandType(q.Get("type")); t {
+	case "", sim.CmdGoTo, sim.CmdTrajectory, sim.CmdHold, sim.CmdStop, sim.CmdSetState:
+		query.Type = t
+	default:
+		errorJSON(w, http.StatusBadRequest, invalid("invalid_type", "type", "unknown command type %q", t))
+		return
+	}
+	if v := q.Get("outcome"); v != "" {
+		o, err := sim.ParseCommandOutcome(v)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_outcome", "outcome", "%v", err))
+			return
+		}
+		query.Outcome = o
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > sim.MaxCommandLog {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_limit", "limit", "limit must be between 1 and %d", sim.MaxCommandLog))
+			return
+		}
+		query.Limit = n
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	recs, err := s.eng.CommandLog(ctx, query)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	if recs == nil {
+		recs = []sim.CommandRecord{}
+	}
+	writeJSON(w, http.StatusOK, recs)
+}
+
+// historyQuery reads ?since= and ?limit= of the history endpoints,
+// answering 400 itself when they are invalid.
+func historyQuery(w http.ResponseWriter, r *http.Request) (since time.Time, limit int, ok bool) {
+	q := r.URL.Query()
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_since", "since", "since must be an RFC3339 timestamp"))
+			return since, 0, false
+		}
+		since = t
+	}
+	limit = defaultHistoryLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHistoryLimit {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_limit", "limit", "limit must be between 1 and %d", maxHistoryLimit))
+			return since, 0, false
+		}
+		limit = n
+	}
+	return since, limit, true
+}
+
+func (s *Server) params(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		l, err := s.eng.Params(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+
+	case http.MethodPatch:
+		var patch sim.LimitsPatch
+		if err := decodeJSON(w, r, &patch); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := patch.Apply(sim.Limits{}).Validate(); err != nil {
+			errorJSON(w, http.StatusBadRequest, paramError(err))
+			return
+		}
+		l, err := s.eng.SetParams(ctx, patch)
+		if err != nil {
+			if ctx.Err() != nil {
+				errorJSON(w, http.StatusRequestTimeout, err)
+				return
+			}
+			errorJSON(w, http.StatusBadRequest, paramError(err))
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPatch)
+	}
+}
+
+// paramError codes a limit the engine rejected as invalid_param, under
+// the limit's name.
+func paramError(err error) error {
+	var pe *sim.ParamError
+	if errors.As(err, &pe) {
+		return invalid("invalid_param", pe.Param, "%v", err)
+	}
+	return err
+}
+
+func (s *Server) config(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		c, err := s.eng.RuntimeConfig(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, c)
+
+	case http.MethodPatch:
+		var raw map[string]json.RawMessage
+		if err := decodeJSON(w, r, &raw); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		for k := range raw {
+			switch {
+			case k == "origin":
+				errorJSON(w, http.StatusBadRequest, invalid("read_only", k, "origin is read-only here: move it with POST /sim/origin"))
+				return
+			case sim.IsRuntimeReadOnly(k):
+				errorJSON(w, http.StatusBadRequest, invalid("read_only", k, "%s is read-only: it takes a restart to change", k))
+				return
+			}
+		}
+		var patch sim.RuntimeConfigPatch
+		body, _ := json.Marshal(raw)
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&patch); err != nil {
+			errorJSON(w, http.StatusBadRequest, jsonDecodeError(err))
+			return
+		}
+		c, err := s.eng.SetRuntimeConfig(ctx, patch)
+		if err != nil {
+			switch {
+			case errors.Is(err, sim.ErrReplay):
+				errorJSON(w, http.StatusConflict, err)
+			case ctx.Err() != nil:
+				errorJSON(w, http.StatusRequestTimeout, err)
+			default:
+				errorJSON(w, http.StatusBadRequest, err)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, c)
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPatch)
+	}
+}
+
+func (s *Server) geofence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		g, err := s.eng.Geofence(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		if g == nil {
+			writeError(w, http.StatusNotFound, "geofence_not_found", "no geofence set", nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, g)
+
+	case http.MethodPost:
+		var g sim.Geofence
+		if err := decodeJSON(w, r, &g); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := g.Validate(); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.eng.SetGeofence(ctx, &g); err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+
+	case http.MethodDelete:
+		if err := s.eng.SetGeofence(ctx, nil); err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "cleared"})
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
+
+func (s *Server) weather(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		cells, err := s.eng.WeatherCells(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, cells)
+
+	case http.MethodPost:
+		var c env.WeatherCell
+		if err := decodeJSON(w, r, &c); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.eng.AddWeatherCell(ctx, c); err != nil {
+			switch {
+			case errors.Is(err, sim.ErrReplay), errors.Is(err, env.ErrWeatherCellExists):
+				errorJSON(w, http.StatusConflict, err)
+			case ctx.Err() != nil:
+				errorJSON(w, http.StatusRequestTimeout, err)
+			default:
+				errorJSON(w, http.StatusBadRequest, err)
+			}
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"status": "ok", "id": c.ID})
+
+	case http.MethodDelete:
+		// ?id= removes one cell, no id removes them all
+		if err := s.eng.RemoveWeatherCell(ctx, r.URL.Query().Get("id")); err != nil {
+			if errors.Is(err, sim.ErrNoWeatherCell) {
+				errorJSON(w, http.StatusNotFound, err)
+				return
+			}
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "cleared"})
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
+
+func (s *Server) microburst(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		bursts, err := s.eng.Microbursts(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, bursts)
+
+	case http.MethodPost:
+		var m env.Microburst
+		if err := decodeJSON(w, r, &m); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		id, err := s.eng.TriggerMicroburst(ctx, m)
+		if err != nil {
+			switch {
+			case errors.Is(err, sim.ErrReplay), errors.Is(err, env.ErrMicroburstExists):
+				errorJSON(w, http.StatusConflict, err)
+			case ctx.Err() != nil:
+				errorJSON(w, http.StatusRequestTimeout, err)
+			default:
+				errorJSON(w, http.StatusBadRequest, err)
+			}
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"status": "ok", "id": id})
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *Server) battery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// An empty body recharges to 100%.
+	var body struct {
+		Pct *float64 `json:"pct,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &body); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	pct := 100.0
+	if body.Pct != nil {
+		pct = *body.Pct
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := s.eng.SetBatteryPct(ctx, pct); err != nil {
+		switch {
+		case errors.Is(err, sim.ErrNoBattery):
+			errorJSON(w, http.StatusConflict, err)
+		case ctx.Err() != nil:
+			errorJSON(w, http.StatusRequestTimeout, err)
+		default:
+			errorJSON(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "batteryPct": pct})
 }
 
-func NewServer(eng *sim.Engine) *Server {
-	s := &Server{eng: eng, mux: http.NewServeMux()}
-	s.routes()
-	return s
-}
+func (s *Server) resetOdometer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
 
-func (s *Server) Handler() http.Handler { return s.mux }
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
 
-func (s *Server) routes() {
-	s.mux.HandleFunc("/health", s.health)
-	s.mux.HandleFunc("/state", s.state)
+	if err := s.eng.ResetOdometer(ctx); err != nil {
+		if errors.Is(err, sim.ErrReplay) {
+			errorJSON(w, http.StatusConflict, err)
+			return
+		}
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "reset"})
+}
 
-	s.mux.HandleFunc("/command/goto", s.gotoCmd)
-	s.mux.HandleFunc("/command/trajectory", s.trajectoryCmd)
+func (s *Server) reset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
 
-	s.mux.HandleFunc("/command/stop", s.stopCmd)
-	s.mux.HandleFunc("/command/hold", s.holdCmd)
+	// An empty body resets to the configured start position.
+	var pos *sim.ResetPosition
+	if r.ContentLength != 0 {
+		pos = &sim.ResetPosition{}
+		if err := decodeJSON(w, r, pos); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := pos.Validate(); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.checkCeiling(pos.Alt); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+	}
 
-	s.mux.HandleFunc("/stream", s.streamSSE)
-}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
 
-func (s *Server) health(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "GET only", http.StatusMethodNotAllowed)
+	if pos != nil {
+		from, err := s.rangeOrigin(ctx, pos.Lat, pos.Lon)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
+			return
+		}
+		if err := s.checkRange(from, pos.Lat, pos.Lon); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if err := s.eng.Reset(ctx, pos); err != nil {
+		switch {
+		case errors.Is(err, sim.ErrReplay):
+			errorJSON(w, http.StatusConflict, err)
+			return
+		case errors.Is(err, sim.ErrOutOfRange), errors.Is(err, sim.ErrBelowFloor):
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		errorJSON(w, http.StatusRequestTimeout, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok\n"))
+	writeJSON(w, http.StatusOK, map[string]any{"status": "reset"})
 }
 
-func (s *Server) state(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "GET only", http.StatusMethodNotAllowed)
+func (s *Server) origin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	st, err := s.eng.GetState(ctx)
+	if r.Method == http.MethodPost {
+		var body struct {
+			Lat float64 `json:"lat"`
+			Lon float64 `json:"lon"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := validateLatLon(body.Lat, body.Lon); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.eng.SetOrigin(ctx, body.Lat, body.Lon); err != nil {
+			switch {
+			case errors.Is(err, sim.ErrCommandActive), errors.Is(err, sim.ErrReplay):
+				errorJSON(w, http.StatusConflict, err)
+			case ctx.Err() != nil:
+				errorJSON(w, http.StatusRequestTimeout, err)
+			default:
+				errorJSON(w, http.StatusBadRequest, err)
+			}
+			return
+		}
+	}
+
+	o, err := s.eng.Origin(ctx)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusRequestTimeout)
+		errorJSON(w, http.StatusRequestTimeout, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, st)
+	writeJSON(w, http.StatusOK, o)
 }
 
-func (s *Server) gotoCmd(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+// geoToLocal converts lat/lon/alt to the local frame with the engine's own
+// GeoRef.
+func (s *Server) geoToLocal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
 		return
 	}
 
-	var body struct {
-		Lat   float64 `json:"lat"`
-		Lon   float64 `json:"lon"`
-		Alt   float64 `json:"alt"`
-		Speed float64 `json:"speed,omitempty"`
+	var lat, lon, alt float64
+	if err := queryFloats(r, []string{"lat", "lon", "alt"}, &lat, &lon, &alt); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateLatLon(lat, lon); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
 	}
 
-	if err := decodeJSON(w, r, &body); err != nil {
-		jsonError(w, http.StatusBadRequest, err.Error())
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	g, err := s.eng.GeoRef(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
 		return
 	}
+	p := g.GeoToLocal(lat, lon, alt)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"x": p.X, "y": p.Y, "z": p.Z,
+		"origin":     sim.Origin{Lat: g.OriginLat, Lon: g.OriginLon},
+		"projection": g.Projection,
+	})
+}
 
-	// Validate inputs
-	if err := validateLatLon(body.Lat, body.Lon); err != nil {
-		jsonError(w, http.StatusBadRequest, err.Error())
+// geoToGeo converts a local x/y/z back to lat/lon/alt with the engine's own
+// GeoRef.
+func (s *Server) geoToGeo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
 		return
 	}
-	if body.Alt < -500 {
-		jsonError(w, http.StatusBadRequest, "alt must be >= -500 meters")
+
+	var p vector.Vec3
+	if err := queryFloats(r, []string{"x", "y", "z"}, &p.X, &p.Y, &p.Z); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
 		return
 	}
-	if body.Speed < 0 {
-		jsonError(w, http.StatusBadRequest, "speed must be >= 0")
+	if d := math.Hypot(p.X, p.Y); d > maxLocalM {
+		errorJSON(w, http.StatusBadRequest, &apiError{
+			code:    "out_of_range",
+			msg:     fmt.Sprintf("x and y must be within %g meters of the origin", maxLocalM),
+			details: map[string]any{"distanceM": math.Round(d), "maxRangeM": maxLocalM},
+		})
 		return
 	}
 
-	s.eng.Submit(sim.GoToCommand{
-		At:    time.Now(),
-		Lat:   body.Lat,
-		Lon:   body.Lon,
-		Alt:   body.Alt,
-		Speed: body.Speed,
-	})
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
 
-	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "goto"})
+	g, err := s.eng.GeoRef(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	lat, lon, alt := g.LocalToGeo(p)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"lat": lat, "lon": lon, "alt": alt,
+		"origin":     sim.Origin{Lat: g.OriginLat, Lon: g.OriginLon},
+		"projection": g.Projection,
+	})
 }
 
-func (s *Server) trajectoryCmd(w http.ResponseWriter, r *http.Request) {
+// maxLocalM bounds the local positions /geo/togeo converts, well inside
+// the Earth's radius, past which the vertical through a point on the
+// tangent plane misses the ellipsoid.
+const maxLocalM = 5_000_000.0
+
+func (s *Server) setState(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if !s.allowTeleport {
+		jsonError(w, http.StatusForbidden, "setstate is disabled (start the server with -allow-teleport)")
 		return
 	}
 
-	var body struct {
-		Waypoints []sim.Waypoint `json:"waypoints"`
-		Loop      bool           `json:"loop,omitempty"`
+	var cmd sim.SetStateCommand
+	if err := decodeJSON(w, r, &cmd); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := cmd.Validate(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
 	}
+	if err := s.checkCeiling(cmd.Alt); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	cmd.At = s.eng.Now()
 
-	if err := decodeJSON(w, r, &body); err != nil {
-		jsonError(w, http.StatusBadRequest, err.Error())
+	if !s.submit(w, r, cmd) {
 		return
 	}
-	if len(body.Waypoints) == 0 {
-		jsonError(w, http.StatusBadRequest, "waypoints required")
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "setstate"})
+}
+
+func (s *Server) fault(w http.ResponseWriter, r *http.Request) {
+	if !s.allowFaults {
+		jsonError(w, http.StatusForbidden, "fault injection is disabled (start the server with -allow-faults)")
 		return
 	}
 
-	// Validate each waypoint
-	for i, wp := range body.Waypoints {
-		if err := validateLatLon(wp.Lat, wp.Lon); err != nil {
-			jsonError(w, http.StatusBadRequest, fmt.Sprintf("waypoints[%d]: %s", i, err.Error()))
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		faults, err := s.eng.Faults(ctx)
+		if err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
 			return
 		}
-		if wp.Alt < -500 {
-			jsonError(w, http.StatusBadRequest, fmt.Sprintf("waypoints[%d]: alt must be >= -500 meters", i))
+		writeJSON(w, http.StatusOK, faults)
+
+	case http.MethodPost:
+		var f sim.Fault
+		if err := decodeJSON(w, r, &f); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.eng.InjectFault(ctx, f); err != nil {
+			switch {
+			case errors.Is(err, sim.ErrReplay), errors.Is(err, sim.ErrFaultActive):
+				errorJSON(w, http.StatusConflict, err)
+			case ctx.Err() != nil:
+				errorJSON(w, http.StatusRequestTimeout, err)
+			default:
+				errorJSON(w, http.StatusBadRequest, err)
+			}
 			return
 		}
-		if wp.Speed < 0 {
-			jsonError(w, http.StatusBadRequest, fmt.Sprintf("waypoints[%d]: speed must be >= 0", i))
+		writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "kind": f.Kind})
+
+	case http.MethodDelete:
+		if err := s.eng.ClearFaults(ctx); err != nil {
+			errorJSON(w, http.StatusRequestTimeout, err)
 			return
 		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "cleared"})
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost, http.MethodDelete)
 	}
+}
 
-	s.eng.Submit(sim.TrajectoryCommand{
-		At:        time.Now(),
-		Waypoints: body.Waypoints,
-		Loop:      body.Loop,
-	})
+func (s *Server) truth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if !s.allowFaults {
+		jsonError(w, http.StatusForbidden, "truth is disabled (start the server with -allow-faults)")
+		return
+	}
 
-	writeJSON(w, http.StatusAccepted, map[string]any{
-		"status": "accepted",
-		"type":   "trajectory",
-		"count":  len(body.Waypoints),
-	})
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	st, err := s.eng.Truth(ctx)
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
 }
 
-func (s *Server) stopCmd(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+func (s *Server) snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
 		return
 	}
-	s.eng.Submit(sim.StopCommand{At: time.Now()})
-	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "stop"})
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	snap, err := s.eng.Snapshot(ctx)
+	if errors.Is(err, sim.ErrReplay) {
+		errorJSON(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
 }
 
-func (s *Server) holdCmd(w http.ResponseWriter, r *http.Request) {
+func (s *Server) restore(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
-	s.eng.Submit(sim.HoldCommand{At: time.Now()})
-	writeJSON(w, http.StatusAccepted, map[string]any{"status": "accepted", "type": "hold"})
+
+	var snap sim.Snapshot
+	if err := decodeJSON(w, r, &snap); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := snap.Validate(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := s.eng.Restore(ctx, snap); err != nil {
+		if errors.Is(err, sim.ErrReplay) {
+			errorJSON(w, http.StatusConflict, err)
+			return
+		}
+		errorJSON(w, http.StatusRequestTimeout, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "restored"})
 }
 
 func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "GET only", http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodGet)
 		return
 	}
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+	if _, ok := w.(http.Flusher); !ok {
+		jsonError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	// Optional per-client decimation, e.g. ?hz=2 for a map overlay.
+	var opts []sim.SubscribeOption
+	if v := r.URL.Query().Get("hz"); v != "" {
+		hz, err := strconv.ParseFloat(v, 64)
+		if err != nil || hz <= 0 || math.IsInf(hz, 0) {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_hz", "hz", "hz must be a positive number"))
+			return
+		}
+		opts = append(opts, sim.WithMaxRate(hz))
+	}
+	view, err := s.parseStateView(r.Context(), r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -195,70 +1649,339 @@ func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request) {
 	// Helps with Nginx / reverse-proxy buffering
 	w.Header().Set("X-Accel-Buffering", "no")
 
+	// A reconnecting EventSource sends the id of the last state it got;
+	// the states it missed are replayed from the history when they are
+	// still there, and an "event: reset" says they are not.
 	ctx := r.Context()
-	ch, unsub := s.eng.Subscribe(ctx)
+	var (
+		ch    <-chan sim.AircraftState
+		unsub func()
+		reset bool
+	)
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		seq, err := strconv.ParseUint(last, 10, 64)
+		var resumed bool
+		ch, unsub, resumed = s.eng.SubscribeAfter(ctx, seq, opts...)
+		reset = err != nil || !resumed
+	} else {
+		ch, unsub = s.eng.Subscribe(ctx, opts...)
+	}
 	defer unsub()
+	s.log.Info("stream connected", "id", requestID(r), "path", r.URL.Path, "lastEventId", r.Header.Get("Last-Event-ID"), "reset", reset)
+	defer s.log.Info("stream disconnected", "id", requestID(r), "path", r.URL.Path)
+
+	sw := s.newSSEWriter(w)
+	ping, stopPing := s.heartbeat()
+	defer stopPing()
 
 	// comment line (keeps some proxies happy)
-	fmt.Fprintf(w, ": connected\n\n")
-	flusher.Flush()
+	sw.comment("connected")
+	if reset {
+		b, _ := json.Marshal(map[string]string{"lastEventId": r.Header.Get("Last-Event-ID")})
+		sw.event("", "reset", b)
+	}
+	if sw.flush() != nil {
+		return
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-ping:
+			sw.comment("ping")
 		case st, ok := <-ch:
 			if !ok {
+				// the engine has stopped
+				sw.bye()
 				return
 			}
-			b, err := json.Marshal(st)
+			if !filter.pass(st) {
+				continue
+			}
+			// traffic goes out as its own event so viewers can tell it apart
+			traffic := st.Traffic
+			st.Traffic = nil
+			out, err := s.render(view, st)
+			if err != nil {
+				// the terrain was checked when the stream opened
+				return
+			}
+			b, err := json.Marshal(out)
 			if err != nil {
 				// if marshal fails, end stream (rare)
 				return
 			}
-			fmt.Fprintf(w, "event: state\n")
-			fmt.Fprintf(w, "data: %s\n\n", b)
-			flusher.Flush()
+			sw.event(strconv.FormatUint(st.Seq, 10), "state", b)
+			if len(traffic) > 0 {
+				if b, err = json.Marshal(traffic); err != nil {
+					return
+				}
+				sw.event("", "traffic", b)
+			}
+		}
+		if err := sw.flush(); err != nil {
+			s.log.Info("stream write failed", "id", requestID(r), "err", err)
+			return
+		}
+	}
+}
+
+func (s *Server) eventsSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	if _, ok := w.(http.Flusher); !ok {
+		jsonError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ctx := r.Context()
+	ch, unsub := s.eng.SubscribeEvents(ctx)
+	defer unsub()
+	s.log.Info("stream connected", "id", requestID(r), "path", r.URL.Path)
+	defer s.log.Info("stream disconnected", "id", requestID(r), "path", r.URL.Path)
+
+	sw := s.newSSEWriter(w)
+	ping, stopPing := s.heartbeat()
+	defer stopPing()
+
+	sw.comment("connected")
+	if sw.flush() != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping:
+			sw.comment("ping")
+		case ev, ok := <-ch:
+			if !ok {
+				sw.bye()
+				return
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				return
+			}
+			// event name is the kind, e.g. "event: waypoint_reached"
+			sw.event("", string(ev.Kind), b)
+		}
+		if err := sw.flush(); err != nil {
+			s.log.Info("stream write failed", "id", requestID(r), "err", err)
+			return
 		}
 	}
 }
 
 // ---- helpers ----
 
+// submit forwards cmd to the engine. Replay engines cannot be commanded, which
+// is answered with 409 instead of a misleading 202.
+func (s *Server) submit(w http.ResponseWriter, r *http.Request, cmd sim.Command) bool {
+	ctx, cancel := context.WithTimeout(r.Context(), submitTimeout)
+	defer cancel()
+
+	switch err := s.eng.Submit(ctx, cmd); {
+	case err == nil:
+		return true
+	case errors.Is(err, sim.ErrReplay):
+		errorJSON(w, http.StatusConflict, err)
+	case errors.Is(err, sim.ErrOverloaded):
+		w.Header().Set("Retry-After", "1")
+		errorJSON(w, http.StatusServiceUnavailable, err)
+	default:
+		errorJSON(w, http.StatusInternalServerError, err)
+	}
+	return false
+}
+
 func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodyBytes)
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 
 	if err := dec.Decode(dst); err != nil {
-		var syntaxErr *json.SyntaxError
-		if errors.As(err, &syntaxErr) {
-			return fmt.Errorf("invalid json syntax at byte %d", syntaxErr.Offset)
-		}
-		return fmt.Errorf("invalid json: %w", err)
+		return jsonDecodeError(err)
 	}
 	// Ensure there's no extra trailing content
 	if dec.More() {
-		return fmt.Errorf("invalid json: multiple values in body")
+		return &apiError{code: "invalid_json", msg: "invalid json: multiple values in body"}
+	}
+	return nil
+}
+
+// jsonDecodeError codes an error from decoding a request body.
+func jsonDecodeError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		sizeErr   *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &sizeErr):
+		return &apiError{status: http.StatusRequestEntityTooLarge, code: "body_too_large",
+			msg:     fmt.Sprintf("body exceeds the limit of %d bytes", sizeErr.Limit),
+			details: map[string]any{"limitBytes": sizeErr.Limit}}
+	case errors.As(err, &syntaxErr):
+		return &apiError{code: "invalid_json", msg: fmt.Sprintf("invalid json syntax at byte %d", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		return &apiError{code: "invalid_type", field: typeErr.Field, msg: fmt.Sprintf("invalid json: %v", err)}
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		name, _ = strconv.Unquote(name)
+		return &apiError{code: "unknown_field", field: name, msg: fmt.Sprintf("invalid json: %v", err)}
+	}
+	return &apiError{code: "invalid_json", msg: fmt.Sprintf("invalid json: %v", err)}
+}
+
+// queryFloats parses the named query parameters into dst, in order, each
+// required and finite.
+func queryFloats(r *http.Request, names []string, dst ...*float64) error {
+	for i, name := range names {
+		f, err := strconv.ParseFloat(r.URL.Query().Get(name), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("%s is required and must be a number", name)
+		}
+		*dst[i] = f
 	}
 	return nil
 }
 
 func validateLatLon(lat, lon float64) error {
-	if lat < -90 || lat > 90 {
-		return fmt.Errorf("lat must be between -90 and 90")
+	if !(lat >= -90 && lat <= 90) {
+		return invalid("invalid_latitude", "lat", "lat must be between -90 and 90")
 	}
-	if lon < -180 || lon > 180 {
-		return fmt.Errorf("lon must be between -180 and 180")
+	if !(lon >= -180 && lon <= 180) {
+		return invalid("invalid_longitude", "lon", "lon must be between -180 and 180")
 	}
 	return nil
 }
 
-func jsonError(w http.ResponseWriter, code int, msg string) {
-	writeJSON(w, code, map[string]any{
-		"error":  msg,
-		"status": "rejected",
-	})
+// checkSpeedAGL validates the speed and height above terrain of a goto or
+// waypoint.
+func checkSpeedAGL(speed, agl float64) error {
+	if speed < 0 {
+		return invalid("invalid_speed", "speed", "speed must be >= 0")
+	}
+	if agl < 0 {
+		return invalid("invalid_agl", "agl", "agl must be >= 0")
+	}
+	return nil
+}
+
+// fromUTM returns the lat/lon of a request that gave either lat/lon or a
+// UTM position.
+func fromUTM(lat, lon float64, u *sim.UTM) (float64, float64, error) {
+	if u == nil {
+		return lat, lon, nil
+	}
+	if lat != 0 || lon != 0 {
+		return 0, 0, invalid("ambiguous_position", "utm", "give either lat/lon or utm, not both")
+	}
+	lat, lon, err := u.LatLon()
+	if err != nil {
+		return 0, 0, invalid("invalid_utm", "utm", "%v", err)
+	}
+	return lat, lon, nil
+}
+
+// wantUTM parses the fmt query parameter: empty, or utm to add the UTM
+// position to states.
+func wantUTM(r *http.Request) (bool, error) {
+	switch f := r.URL.Query().Get("fmt"); f {
+	case "":
+		return false, nil
+	case "utm":
+		return true, nil
+	default:
+		return false, invalid("invalid_fmt", "fmt", "unknown fmt %q (want utm)", f)
+	}
+}
+
+// withUTM adds st's UTM position, when it is inside the grid.
+func withUTM(st sim.AircraftState) sim.AircraftState {
+	if u, err := sim.LatLonToUTM(st.Lat, st.Lon); err == nil {
+		st.UTM = &u
+	}
+	return st
+}
+
+// altRef parses an altitude datum; an AltRefAGL one needs terrain in the
+// environment.
+func (s *Server) altRef(ctx context.Context, raw string) (sim.AltRef, error) {
+	ref, err := sim.ParseAltRef(raw)
+	if err != nil {
+		return "", invalid("invalid_alt_ref", "altRef", "%v", err)
+	}
+	if ref != sim.AltRefAGL {
+		return ref, nil
+	}
+	if _, err := s.eng.TerrainParams(ctx); errors.Is(err, sim.ErrNoTerrain) {
+		return "", invalid("no_terrain", "altRef", "altRef %q needs terrain in the environment", ref)
+	}
+	return ref, nil
+}
+
+// checkAlt validates a commanded altitude in ref. The ceiling is checked
+// here only for MSL altitudes; the engine holds it for the others.
+func (s *Server) checkAlt(alt float64, ref sim.AltRef) error {
+	switch {
+	case ref == sim.AltRefAGL && alt < 0:
+		return invalid("invalid_altitude", "alt", "alt must be >= 0 meters above the terrain")
+	case alt < -500:
+		return invalid("invalid_altitude", "alt", "alt must be >= -500 meters")
+	case ref == sim.AltRefMSL:
+		return s.checkCeiling(alt)
+	}
+	return nil
+}
+
+// rangeOrigin returns the point the operating radius is measured from for
+// a command whose first target is lat, lon: the origin, or that target
+// while an automatic origin waits to snap to it.
+func (s *Server) rangeOrigin(ctx context.Context, lat, lon float64) (sim.Origin, error) {
+	o, err := s.eng.Origin(ctx)
+	if err != nil {
+		return o, err
+	}
+	if o.Auto {
+		o.Lat, o.Lon = lat, lon
+	}
+	return o, nil
+}
+
+// checkRange rejects a target farther from the origin than the engine's
+// maximum operating radius, giving the distance in the details.
+func (s *Server) checkRange(from sim.Origin, lat, lon float64) error {
+	limit := s.eng.MaxRangeM()
+	d := sim.HaversineM(from.Lat, from.Lon, lat, lon)
+	if d <= limit {
+		return nil
+	}
+	return &apiError{
+		code: "out_of_range",
+		msg: fmt.Sprintf("target is %.1f km from the origin, beyond the %.1f km operating radius",
+			d/1000, limit/1000),
+		details: map[string]any{"distanceM": math.Round(d), "maxRangeM": limit},
+	}
+}
+
+// checkCeiling rejects altitudes above the engine's service ceiling.
+func (s *Server) checkCeiling(alt float64) error {
+	if c := s.eng.Ceiling(); c > 0 && alt > c {
+		return invalid("above_ceiling", "alt", "alt %.0f m is above the %.0f m service ceiling", alt, c)
+	}
+	return nil
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
@@ -0,0 +1,258 @@
+package api_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/fakeclock"
+)
+
+// testServer is the API over an engine running on a fake clock.
+type testServer struct {
+	t   testing.TB
+	clk *fakeclock.Clock
+	eng *sim.Engine
+	srv *httptest.Server
+	// stop ends the engine's loop and waits for it, as the server's
+	// shutdown does; the test's cleanup calls it too.
+	stop func()
+}
+
+// newTestServer starts cfg's engine on a fake clock and serves the API
+// over it until the test ends.
+func newTestServer(t testing.TB, cfg sim.Config, opts ...api.Option) *testServer {
+	t.Helper()
+	clk := fakeclock.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cfg.Clock = clk
+	eng, err := sim.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		eng.Run(ctx)
+	}()
+	opts = append([]api.Option{api.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	srv := httptest.NewServer(api.NewServer(eng, opts...).Handler())
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(func() {
+		srv.Close()
+		stop()
+	})
+	clk.WaitForTicker()
+	return &testServer{t: t, clk: clk, eng: eng, srv: srv, stop: stop}
+}
+
+// ticks advances the engine n ticks of 50 ms and waits until it has
+// handled them.
+func (ts *testServer) ticks(n int) {
+	ts.t.Helper()
+	for i := 0; i < n; i++ {
+		ts.clk.Advance(50 * time.Millisecond)
+	}
+	if _, err := ts.eng.GetState(context.Background()); err != nil {
+		ts.t.Fatal(err)
+	}
+}
+
+// do sends a request with body (none when empty) and the header pairs in
+// hdr, and returns the response with its body read.
+func (ts *testServer) do(method, path, body string, hdr ...string) (*http.Response, []byte) {
+	ts.t.Helper()
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, ts.srv.URL+path, rd)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(hdr); i += 2 {
+		req.Header.Set(hdr[i], hdr[i+1])
+	}
+	resp, err := ts.srv.Client().Do(req)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return resp, b
+}
+
+// getJSON GETs path, wants 200 and decodes the body into v.
+func (ts *testServer) getJSON(path string, v any) {
+	ts.t.Helper()
+	resp, b := ts.do(http.MethodGet, path, "")
+	if resp.StatusCode != http.StatusOK {
+		ts.t.Fatalf("GET %s: %d %s", path, resp.StatusCode, b)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		ts.t.Fatalf("GET %s: %v in %s", path, err, b)
+	}
+}
+
+// sseEvent is one event of a stream; comments come through with only
+// comment set.
+type sseEvent struct {
+	id, event, data, comment string
+}
+
+// stream opens an event stream at path with the header pairs in hdr and
+// delivers its events until the stream ends or the test does.
+func (ts *testServer) stream(path string, hdr ...string) (*http.Response, <-chan sseEvent) {
+	ts.t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.srv.URL+path, nil)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	for i := 0; i+1 < len(hdr); i += 2 {
+		req.Header.Set(hdr[i], hdr[i+1])
+	}
+	resp, err := ts.srv.Client().Do(req)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	ts.t.Cleanup(func() {
+		cancel()
+		resp.Body.Close()
+	})
+	ch := make(chan sseEvent, 1024)
+	go func() {
+		defer close(ch)
+		var body io.Reader = resp.Body
+		if resp.Header.Get("Content-Encoding") == "gzip" {
+			// asked for explicitly, so the transport left it compressed
+			gz, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				return
+			}
+			body = gz
+		}
+		sc := bufio.NewScanner(body)
+		sc.Buffer(nil, 1<<20)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev != (sseEvent{}) {
+					ch <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				ev.comment = strings.TrimSpace(line[1:])
+			default:
+				k, v, _ := strings.Cut(line, ": ")
+				switch k {
+				case "id":
+					ev.id = v
+				case "event":
+					ev.event = v
+				case "data":
+					ev.data = v
+				}
+			}
+		}
+	}()
+	return resp, ch
+}
+
+// next returns the next event named one of names from ch, skipping
+// comments and other events, and fails the test after two seconds without
+// one.
+func next(t testing.TB, ch <-chan sseEvent, names ...string) sseEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				t.Fatalf("stream ended waiting for %q", names)
+			}
+			for _, name := range names {
+				if ev.event == name {
+					return ev
+				}
+			}
+		case <-timeout:
+			t.Fatalf("no %q event in two seconds", names)
+		}
+	}
+}
+
+// apiError is the body of an error response.
+type apiError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details"`
+}
+
+// wantError checks that a response is the error envelope with status and
+// code, and returns the error.
+func wantError(t *testing.T, resp *http.Response, body []byte, status int, code string) apiError {
+	t.Helper()
+	var env struct {
+		Error *apiError `json:"error"`
+	}
+	if resp.StatusCode != status {
+		t.Errorf("%s %s: status %d, want %d (%s)", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, status, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: error Content-Type %q", resp.Request.Method, resp.Request.URL.Path, ct)
+	}
+	if err := json.Unmarshal(body, &env); err != nil || env.Error == nil {
+		t.Fatalf("%s %s: not an error envelope: %s", resp.Request.Method, resp.Request.URL.Path, body)
+	}
+	if env.Error.Code != code {
+		t.Errorf("%s %s: code %q, want %q (%s)", resp.Request.Method, resp.Request.URL.Path, env.Error.Code, code, env.Error.Message)
+	}
+	if env.Error.Message == "" {
+		t.Errorf("%s %s: error without a message", resp.Request.Method, resp.Request.URL.Path)
+	}
+	return *env.Error
+}
+
+func TestNotFoundAndMethodEnvelope(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+
+	resp, b := ts.do(http.MethodGet, "/v1/nowhere", "")
+	e := wantError(t, resp, b, http.StatusNotFound, "route_not_found")
+	if e.Details["path"] != "/v1/nowhere" {
+		t.Errorf("details %v", e.Details)
+	}
+	// a route whose resource is missing has its own code
+	resp, b = ts.do(http.MethodGet, "/v1/geofence", "")
+	wantError(t, resp, b, http.StatusNotFound, "geofence_not_found")
+
+	resp, b = ts.do(http.MethodGet, "/v1/command/goto", "")
+	e = wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+	if got := resp.Header.Get("Allow"); got != http.MethodPost {
+		t.Errorf("Allow %q", got)
+	}
+	if allow, _ := e.Details["allow"].([]any); len(allow) != 1 || allow[0] != http.MethodPost {
+		t.Errorf("details %v", e.Details)
+	}
+}
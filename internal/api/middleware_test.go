@@ -0,0 +1,48 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+func TestRequestIDs(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct {
+		in   string
+		keep bool
+	}{
+		{"", false},
+		{"client-42", true},
+		{"has space", false},
+		{strings.Repeat("x", 129), false},
+	} {
+		var hdr []string
+		if c.in != "" {
+			hdr = []string{"X-Request-ID", c.in}
+		}
+		resp, _ := ts.do(http.MethodGet, "/v1/state", "", hdr...)
+		got := resp.Header.Get("X-Request-ID")
+		if got == "" || (got == c.in) != c.keep {
+			t.Errorf("X-Request-ID %q answered as %q", c.in, got)
+		}
+	}
+
+	// the ID is the source of the command it submits
+	_, events := ts.stream("/v1/events")
+	resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.01,"lon":8,"alt":100}`, "X-Request-ID", "goto-1")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(1)
+	var ev sim.Event
+	if err := json.Unmarshal([]byte(next(t, events, string(sim.EventCommandActivated)).data), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Command != sim.CmdGoTo || ev.Source != "goto-1" {
+		t.Errorf("activated %s from %q, want goto from goto-1", ev.Command, ev.Source)
+	}
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// Mission is a named trajectory kept to be flown again.
+type Mission struct {
+	Name      string         `json:"name"`
+	Waypoints []sim.Waypoint `json:"waypoints"`
+	Loop      bool           `json:"loop,omitempty"`
+	LengthM   float64        `json:"lengthM"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+var missionName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// MissionStore holds the missions of /missions in memory and, when it was
+// loaded from a file, writes them back to that file on every change.
+type MissionStore struct {
+	mu       sync.Mutex
+	path     string
+	missions map[string]Mission
+}
+
+// NewMissionStore returns an empty store kept in memory only.
+func NewMissionStore() *MissionStore {
+	return &MissionStore{missions: map[string]Mission{}}
+}
+
+// LoadMissionStore returns the store backed by the JSON file at path,
+// starting empty when the file does not exist yet.
+func LoadMissionStore(path string) (*MissionStore, error) {
+	m := NewMissionStore()
+	m.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Mission
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, ms := range list {
+		if !missionName.MatchString(ms.Name) || len(ms.Waypoints) == 0 {
+			return nil, fmt.Errorf("%s: invalid mission %q", path, ms.Name)
+		}
+		m.missions[ms.Name] = ms
+	}
+	return m, nil
+}
+
+// WithMissions sets the mission store; the default keeps missions in
+// memory only.
+func WithMissions(m *MissionStore) Option {
+	return func(s *Server) { s.missions = m }
+}
+
+// List returns the missions by name.
+func (m *MissionStore) List() []Mission {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.list()
+}
+
+func (m *MissionStore) list() []Mission {
+	list := make([]Mission, 0, len(m.missions))
+	for _, ms := range m.missions {
+		list = append(list, ms)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns the mission called name.
+func (m *MissionStore) Get(name string) (Mission, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ms, ok := m.missions[name]
+	return ms, ok
+}
+
+// Put adds ms or replaces the mission of the same name, reporting which.
+// When the file cannot be written the store is left unchanged.
+func (m *MissionStore) Put(ms Mission) (created bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, existed := m.missions[ms.Name]
+	m.missions[ms.Name] = ms
+	if err := m.save(); err != nil {
+		if existed {
+			m.missions[ms.Name] = old
+		} else {
+			delete(m.missions, ms.Name)
+		}
+		return false, err
+	}
+	return !existed, nil
+}
+
+// Delete removes the mission called name, reporting whether there was one.
+func (m *MissionStore) Delete(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.missions[name]
+	if !ok {
+		return false, nil
+	}
+	delete(m.missions, name)
+	if err := m.save(); err != nil {
+		m.missions[name] = old
+		return false, err
+	}
+	return true, nil
+}
+
+// save writes the missions to the store's file, if it has one, through a
+// temporary file so a crash never leaves half a file behind.
+func (m *MissionStore) save() error {
+	if m.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(m.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.path)
+}
+
+// missionList lists (GET) or stores (POST) missions.
+func (s *Server) missionList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.missions.List())
+
+	case http.MethodPost:
+		var body struct {
+			Name      string       `json:"name"`
+			Waypoints []waypointIn `json:"waypoints"`
+			Loop      bool         `json:"loop,omitempty"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		if !missionName.MatchString(body.Name) {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_name", "name",
+				"name must be 1 to 64 letters, digits, '.', '_' or '-', starting with a letter or digit"))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		wps, err := s.waypoints(ctx, body.Waypoints)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		ms := Mission{Name: body.Name, Waypoints: wps, Loop: body.Loop,
+			LengthM: routeLengthM(wps, body.Loop), UpdatedAt: time.Now().UTC()}
+		created, err := s.missions.Put(ms)
+		if err != nil {
+			errorJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		code := http.StatusOK
+		if created {
+			code = http.StatusCreated
+		}
+		writeJSON(w, code, ms)
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// mission serves /missions/{name} (GET, DELETE) and /missions/{name}/fly
+// (POST).
+func (s *Server) mission(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, apiPrefix), "/missions/")
+	name, action, _ := strings.Cut(rest, "/")
+	switch action {
+	case "":
+	case "fly":
+		s.flyMission(w, r, name)
+		return
+	default:
+		notFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ms, ok := s.missions.Get(name)
+		if !ok {
+			missionNotFound(w, name)
+			return
+		}
+		writeJSON(w, http.StatusOK, ms)
+
+	case http.MethodDelete:
+		ok, err := s.missions.Delete(name)
+		switch {
+		case err != nil:
+			errorJSON(w, http.StatusInternalServerError, err)
+		case !ok:
+			missionNotFound(w, name)
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{"status": "deleted", "name": name})
+		}
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// flyMission submits a mission as a trajectory, checking its waypoints
+// again against the limits in force now.
+func (s *Server) flyMission(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	ms, ok := s.missions.Get(name)
+	if !ok {
+		missionNotFound(w, name)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	in := make([]waypointIn, len(ms.Waypoints))
+	for i, wp := range ms.Waypoints {
+		in[i].Waypoint = wp
+	}
+	wps, err := s.waypoints(ctx, in)
+	if err != nil {
+		errorJSON(w, http.StatusConflict, fmt.Errorf("mission %q no longer validates: %w", name, err))
+		return
+	}
+
+	if !s.submit(w, r, sim.TrajectoryCommand{
+		At:        s.eng.Now(),
+		Waypoints: wps,
+		Loop:      ms.Loop,
+		Mission:   ms.Name,
+	}) {
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":  "accepted",
+		"type":    "trajectory",
+		"mission": ms.Name,
+		"count":   len(wps),
+		"lengthM": routeLengthM(wps, ms.Loop),
+	})
+}
+
+func missionNotFound(w http.ResponseWriter, name string) {
+	writeError(w, http.StatusNotFound, "mission_not_found", fmt.Sprintf("no mission %q", name), nil)
+}
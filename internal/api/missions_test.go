@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"math"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+// survey runs 379 m east along 47.005°N.
+const survey = `{"name":"survey-1","waypoints":[{"lat":47.005,"lon":8,"alt":1000},{"lat":47.005,"lon":8.005,"alt":1000}]}`
+
+func TestMissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missions.json")
+	store, err := api.LoadMissionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000}, api.WithMissions(store))
+
+	resp, b := ts.do(http.MethodPost, "/v1/missions", survey)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: %d %s", resp.StatusCode, b)
+	}
+	if resp, b = ts.do(http.MethodPost, "/v1/missions", survey); resp.StatusCode != http.StatusOK {
+		t.Errorf("replace: %d %s", resp.StatusCode, b)
+	}
+	var ms api.Mission
+	ts.getJSON("/v1/missions/survey-1", &ms)
+	if len(ms.Waypoints) != 2 || math.Abs(ms.LengthM-379) > 1 {
+		t.Errorf("mission %+v", ms)
+	}
+
+	// the file holds it for the next start
+	again, err := api.LoadMissionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := again.Get("survey-1"); !ok || len(got.Waypoints) != 2 {
+		t.Errorf("reloaded %+v, %v", got, ok)
+	}
+
+	if resp, b = ts.do(http.MethodPost, "/v1/missions/survey-1/fly", ""); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("fly: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(1)
+	var st sim.AircraftState
+	ts.getJSON("/v1/state", &st)
+	if st.ActiveCommand != string(sim.CmdTrajectory) || st.Mission != "survey-1" {
+		t.Errorf("flying %q mission %q", st.ActiveCommand, st.Mission)
+	}
+
+	if resp, b = ts.do(http.MethodDelete, "/v1/missions/survey-1", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("delete: %d %s", resp.StatusCode, b)
+	}
+	var list []api.Mission
+	ts.getJSON("/v1/missions", &list)
+	if len(list) != 0 {
+		t.Errorf("missions after the delete: %+v", list)
+	}
+	if again, err = api.LoadMissionStore(path); err != nil || len(again.List()) != 0 {
+		t.Errorf("reloaded after the delete: %+v, %v", again.List(), err)
+	}
+}
+
+func TestMissionErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct {
+		method, path, body string
+		status             int
+		code, field        string
+	}{
+		{http.MethodPost, "/v1/missions", `{"name":"-x","waypoints":[{"lat":47,"lon":8,"alt":100}]}`, http.StatusBadRequest, "invalid_name", "name"},
+		{http.MethodPost, "/v1/missions", `{"name":"x","waypoints":[{"lat":95,"lon":8,"alt":100}]}`, http.StatusBadRequest, "invalid_latitude", "waypoints[0].lat"},
+		{http.MethodPost, "/v1/missions", `{"name":"x","waypoints":[]}`, http.StatusBadRequest, "waypoints_empty", "waypoints"},
+		{http.MethodGet, "/v1/missions/nope", "", http.StatusNotFound, "mission_not_found", ""},
+		{http.MethodDelete, "/v1/missions/nope", "", http.StatusNotFound, "mission_not_found", ""},
+		{http.MethodPost, "/v1/missions/nope/fly", "", http.StatusNotFound, "mission_not_found", ""},
+		{http.MethodGet, "/v1/missions/nope/land", "", http.StatusNotFound, "route_not_found", ""},
+		{http.MethodPut, "/v1/missions", "", http.StatusMethodNotAllowed, "method_not_allowed", ""},
+		{http.MethodGet, "/v1/missions/nope/fly", "", http.StatusMethodNotAllowed, "method_not_allowed", ""},
+	} {
+		resp, b := ts.do(c.method, c.path, c.body)
+		e := wantError(t, resp, b, c.status, c.code)
+		if f, _ := e.Details["field"].(string); f != c.field {
+			t.Errorf("%s %s: field %q, want %q", c.method, c.path, f, c.field)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package api_test
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+func TestGoToAcrossTheAntimeridian(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: -17, OriginLon: 179.9, InitialAlt: 1000})
+	resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":-17,"lon":-179.9,"alt":1000,"speed":60}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	var st sim.AircraftState
+	crossed := false
+	for i := 0; i < 600; i++ {
+		ts.ticks(20)
+		st = sim.AircraftState{}
+		ts.getJSON("/v1/state", &st)
+		if !(st.Lon >= -180 && st.Lon < 180) {
+			t.Fatalf("longitude %g outside [-180, 180)", st.Lon)
+		}
+		if st.Lon < 0 {
+			crossed = true
+		}
+		if st.GroundSpeedMps > 30 && math.Abs(st.TrackDeg-90) > 10 {
+			t.Fatalf("flying a track of %.0f°, not east", st.TrackDeg)
+		}
+		if st.ActiveCommand == "" {
+			break
+		}
+	}
+	if st.ActiveCommand != "" {
+		t.Fatalf("still flying %q after ten minutes, at %g, %g", st.ActiveCommand, st.Lat, st.Lon)
+	}
+	if !crossed {
+		t.Error("never crossed the antimeridian")
+	}
+	// 0.2° of longitude at 17°S
+	if want := sim.HaversineM(-17, 179.9, -17, -179.9); st.DistanceFlownM > want+200 {
+		t.Errorf("flew %.0f m for a %.0f m hop", st.DistanceFlownM, want)
+	}
+}
+
+func TestOriginErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, MaxOriginLatDeg: 70})
+	for _, c := range []struct {
+		name, method, body string
+		status             int
+		code               string
+	}{
+		{"beyond the latitude limit", http.MethodPost, `{"lat":71,"lon":8}`, http.StatusBadRequest, "bad_request"},
+		{"not a latitude", http.MethodPost, `{"lat":91,"lon":8}`, http.StatusBadRequest, "invalid_latitude"},
+		{"not a longitude", http.MethodPost, `{"lat":47,"lon":181}`, http.StatusBadRequest, "invalid_longitude"},
+		{"unknown field", http.MethodPost, `{"lat":47,"lon":8,"alt":3}`, http.StatusBadRequest, "unknown_field"},
+		{"wrong method", http.MethodDelete, "", http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		resp, b := ts.do(c.method, "/v1/sim/origin", c.body)
+		wantError(t, resp, b, c.status, c.code)
+	}
+
+	var o sim.Origin
+	ts.getJSON("/v1/sim/origin", &o)
+	if o.Lat != 47 || o.Lon != 8 {
+		t.Errorf("origin moved to %g, %g by rejected requests", o.Lat, o.Lon)
+	}
+
+	if resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.01,"lon":8,"alt":1000}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(1)
+	resp, b := ts.do(http.MethodPost, "/v1/sim/origin", `{"lat":69,"lon":8}`)
+	wantError(t, resp, b, http.StatusConflict, "command_active")
+
+	if resp, b := ts.do(http.MethodPost, "/v1/command/stop", ""); resp.StatusCode >= 300 {
+		t.Fatalf("stop: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(1)
+	resp, b = ts.do(http.MethodPost, "/v1/sim/origin", `{"lat":69,"lon":8}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("origin at the limit: %d %s", resp.StatusCode, b)
+	}
+}
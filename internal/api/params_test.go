@@ -0,0 +1,86 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+func TestParams(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	var l sim.Limits
+	ts.getJSON("/v1/sim/params", &l)
+	if l != sim.DefaultLimits() {
+		t.Errorf("GET %+v, want the defaults %+v", l, sim.DefaultLimits())
+	}
+	resp, b := ts.do(http.MethodPatch, "/v1/sim/params", `{"maxClimbRate":3,"posTolM":10}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("patch: %d %s", resp.StatusCode, b)
+	}
+	want := sim.DefaultLimits()
+	want.MaxClimbRate, want.PosTolM = 3, 10
+	if err := json.Unmarshal(b, &l); err != nil || l != want {
+		t.Errorf("patch answered %+v (%v), want %+v", l, err, want)
+	}
+	ts.getJSON("/v1/sim/params", &l)
+	if l != want {
+		t.Errorf("GET after the patch %+v, want %+v", l, want)
+	}
+}
+
+func TestParamsErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct {
+		method, body string
+		status       int
+		code, field  string
+	}{
+		{http.MethodPatch, `{"maxClimbRate":-1}`, http.StatusBadRequest, "invalid_param", "maxClimbRate"},
+		{http.MethodPatch, `{"posTolM":1,"maxBankDeg":90}`, http.StatusBadRequest, "invalid_param", "maxBankDeg"},
+		{http.MethodPatch, `{"maxClimb":3}`, http.StatusBadRequest, "unknown_field", "maxClimb"},
+		{http.MethodPatch, `{"maxClimbRate":"fast"}`, http.StatusBadRequest, "invalid_type", "maxClimbRate"},
+		{http.MethodPatch, `{"maxClimbRate":`, http.StatusBadRequest, "invalid_json", ""},
+		{http.MethodPost, `{}`, http.StatusMethodNotAllowed, "method_not_allowed", ""},
+	} {
+		resp, b := ts.do(c.method, "/v1/sim/params", c.body)
+		e := wantError(t, resp, b, c.status, c.code)
+		if c.field != "" && e.Details["field"] != c.field {
+			t.Errorf("%s: details %v, want field %s", c.body, e.Details, c.field)
+		}
+	}
+	var l sim.Limits
+	ts.getJSON("/v1/sim/params", &l)
+	if l != sim.DefaultLimits() {
+		t.Errorf("rejected patches left %+v", l)
+	}
+}
+
+// The history's query parameters, shared by /history, /history.geojson
+// and the track exports.
+func TestHistoryParamsErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(3)
+	for _, path := range []string{"/v1/history", "/v1/history.geojson", "/v1/export/track.gpx"} {
+		for _, c := range []struct{ query, code, field string }{
+			{"since=yesterday", "invalid_since", "since"},
+			{"since=2026-01-01", "invalid_since", "since"},
+			{"limit=0", "invalid_limit", "limit"},
+			{"limit=x", "invalid_limit", "limit"},
+			{"limit=1000000", "invalid_limit", "limit"},
+		} {
+			resp, b := ts.do(http.MethodGet, path+"?"+c.query, "")
+			e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+			if e.Details["field"] != c.field {
+				t.Errorf("%s?%s: details %v", path, c.query, e.Details)
+			}
+		}
+	}
+
+	var states []sim.AircraftState
+	ts.getJSON("/v1/history?since=2026-01-01T00:00:00.05Z&limit=1", &states)
+	if len(states) != 1 {
+		t.Errorf("%d states, want 1", len(states))
+	}
+}
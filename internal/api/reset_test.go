@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/sim"
+)
+
+func TestResetClearsFaultsAndWeather(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000}, api.WithFaults(true))
+	for _, c := range []struct{ path, body string }{
+		{"/v1/sim/fault", `{"kind":"gps_freeze","durationS":600}`},
+		{"/v1/environment/weather", `{"id":"cb","lat":47.01,"lon":8,"radiusM":500,"turbulenceMps":3}`},
+		{"/v1/environment/microburst", `{"lat":47,"lon":8.01,"radiusM":500,"peakDownMps":10,"durationS":600}`},
+	} {
+		if resp, b := ts.do(http.MethodPost, c.path, c.body); resp.StatusCode >= 300 {
+			t.Fatalf("%s: %d %s", c.path, resp.StatusCode, b)
+		}
+	}
+	ts.ticks(1)
+
+	if resp, b := ts.do(http.MethodPost, "/v1/sim/reset", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("reset: %d %s", resp.StatusCode, b)
+	}
+	var faults []sim.ActiveFault
+	ts.getJSON("/v1/sim/fault", &faults)
+	var cells []env.WeatherCell
+	ts.getJSON("/v1/environment/weather", &cells)
+	var bursts []env.Microburst
+	ts.getJSON("/v1/environment/microburst", &bursts)
+	if len(faults) != 0 || len(cells) != 0 || len(bursts) != 0 {
+		t.Errorf("after the reset: faults %v, cells %v, microbursts %v", faults, cells, bursts)
+	}
+}
+
+func TestResetPositionErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 500, MaxRangeM: 50_000,
+		Environment: env.Terrain{SafetyMarginM: 10, Provider: env.SyntheticTerrain{BaseM: 200}}})
+	for _, c := range []struct {
+		name, body string
+		code       string
+	}{
+		{"out of range", `{"lat":48,"lon":8,"alt":500}`, "out_of_range"},
+		{"below the floor", `{"lat":47.1,"lon":8,"alt":205}`, "below_floor"},
+	} {
+		resp, b := ts.do(http.MethodPost, "/v1/sim/reset", c.body)
+		e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+		if c.code == "out_of_range" && e.Details["distanceM"] == nil {
+			t.Errorf("%s: details %v", c.name, e.Details)
+		}
+	}
+	if resp, b := ts.do(http.MethodPost, "/v1/sim/reset", `{"lat":47.1,"lon":8,"alt":500}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("reset within the limits: %d %s", resp.StatusCode, b)
+	}
+}
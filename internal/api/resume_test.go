@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+// seqOf returns the id of a state event, checking it is the seq of the
+// state it carries.
+func seqOf(t *testing.T, ev sseEvent) uint64 {
+	t.Helper()
+	id, err := strconv.ParseUint(ev.id, 10, 64)
+	if err != nil {
+		t.Fatalf("state event id %q", ev.id)
+	}
+	var st struct{ Seq uint64 }
+	if err := json.Unmarshal([]byte(ev.data), &st); err != nil || st.Seq != id {
+		t.Fatalf("event id %d carries seq %d (%v)", id, st.Seq, err)
+	}
+	return id
+}
+
+func TestStreamResume(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(3)
+
+	resp, events := ts.stream("/v1/stream")
+	last := seqOf(t, next(t, events, "state"))
+	for i := 0; i < 5; i++ {
+		ts.ticks(1)
+		if seq := seqOf(t, next(t, events, "state")); seq != last+1 {
+			t.Fatalf("live seq %d after %d", seq, last)
+		}
+		last++
+	}
+	resp.Body.Close()
+
+	// ten states go by while the client is away
+	ts.ticks(10)
+	_, events = ts.stream("/v1/stream", "Last-Event-ID", strconv.FormatUint(last, 10))
+	for i := 0; i < 13; i++ {
+		if i == 10 {
+			// the replay has caught up; the rest come live
+			ts.ticks(3)
+		}
+		ev := next(t, events, "state", "reset")
+		if ev.event == "reset" {
+			t.Fatal("reset though the history holds the missed states")
+		}
+		if seq := seqOf(t, ev); seq != last+1 {
+			t.Fatalf("resumed seq %d after %d", seq, last)
+		}
+		last++
+	}
+}
+
+func TestStreamResumeBeyondHistory(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, HistorySize: 20})
+	ts.ticks(5)
+	resp, events := ts.stream("/v1/stream")
+	last := seqOf(t, next(t, events, "state"))
+	resp.Body.Close()
+	ts.ticks(50)
+	now := last + 50
+
+	for _, id := range []string{strconv.FormatUint(last, 10), "not-a-seq", "999999"} {
+		resp, events := ts.stream("/v1/stream", "Last-Event-ID", id)
+		ev := next(t, events, "state", "reset")
+		if ev.event != "reset" {
+			t.Fatalf("Last-Event-ID %s: first event %q, want reset", id, ev.event)
+		}
+		var reset struct{ LastEventID string }
+		if err := json.Unmarshal([]byte(ev.data), &reset); err != nil || reset.LastEventID != id {
+			t.Errorf("reset %s", ev.data)
+		}
+		// then the current state, and live ones after it
+		if seq := seqOf(t, next(t, events, "state")); seq != now {
+			t.Errorf("Last-Event-ID %s: current seq %d, want %d", id, seq, now)
+		}
+		ts.ticks(1)
+		now++
+		if seq := seqOf(t, next(t, events, "state")); seq != now {
+			t.Errorf("Last-Event-ID %s: live seq %d, want %d", id, seq, now)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, q := range []string{"hz=0", "hz=-1", "hz=x", "hz=Inf"} {
+		resp, b := ts.do(http.MethodGet, "/v1/stream?"+q, "")
+		e := wantError(t, resp, b, http.StatusBadRequest, "invalid_hz")
+		if e.Details["field"] != "hz" {
+			t.Errorf("%s: details %v", q, e.Details)
+		}
+	}
+	resp, b := ts.do(http.MethodPost, "/v1/stream", "")
+	wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+}
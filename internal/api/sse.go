@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Defaults of the server-sent event streams.
+const (
+	DefaultSSEHeartbeat    = 15 * time.Second
+	DefaultSSEWriteTimeout = 10 * time.Second
+)
+
+// WithSSEHeartbeat sets how often /stream and /events send a ": ping"
+// comment, so that proxies do not close a connection that carries no
+// events for a while. Zero or negative turns the heartbeat off.
+func WithSSEHeartbeat(d time.Duration) Option {
+	return func(s *Server) { s.sseHeartbeat = d }
+}
+
+// WithSSEWriteTimeout bounds each write to a stream; a client that does not
+// take the bytes in time is disconnected. Zero or negative means no bound.
+func WithSSEWriteTimeout(d time.Duration) Option {
+	return func(s *Server) { s.sseWriteTimeout = d }
+}
+
+// sseWriter writes the frames of one stream. The first failed write, a
+// timeout included, sticks: every later call returns it, and the handler
+// treats it as the client having gone.
+type sseWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+	err     error
+}
+
+// newSSEWriter lifts the http.Server's ReadTimeout and WriteTimeout, which
+// would cut a stream off after a fixed time; each write gets its own
+// deadline instead.
+func (s *Server) newSSEWriter(w http.ResponseWriter) *sseWriter {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	return &sseWriter{w: w, rc: rc, timeout: s.sseWriteTimeout}
+}
+
+// heartbeat returns a channel ticking at the server's heartbeat interval,
+// nil when it is off, and the function stopping it.
+func (s *Server) heartbeat() (<-chan time.Time, func()) {
+	if s.sseHeartbeat <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(s.sseHeartbeat)
+	return t.C, t.Stop
+}
+
+// event writes one event; id is left out when empty.
+func (sw *sseWriter) event(id, name string, data []byte) {
+	if id != "" {
+		sw.printf("id: %s\n", id)
+	}
+	sw.printf("event: %s\ndata: %s\n\n", name, data)
+}
+
+// comment writes a comment line, which clients ignore.
+func (sw *sseWriter) comment(text string) {
+	sw.printf(": %s\n\n", text)
+}
+
+func (sw *sseWriter) printf(format string, args ...any) {
+	if sw.err != nil {
+		return
+	}
+	if sw.timeout > 0 {
+		err := sw.rc.SetWriteDeadline(time.Now().Add(sw.timeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			sw.err = err
+			return
+		}
+	}
+	_, sw.err = fmt.Fprintf(sw.w, format, args...)
+}
+
+// bye tells the client the server ends the stream, as opposed to the
+// connection failing.
+func (sw *sseWriter) bye() {
+	sw.event("", "bye", []byte(`{"reason":"shutdown"}`))
+	_ = sw.flush()
+}
+
+// flush sends what was written and returns the first error so far.
+func (sw *sseWriter) flush() error {
+	if sw.err == nil {
+		sw.err = sw.rc.Flush()
+	}
+	return sw.err
+}
@@ -0,0 +1,203 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+// smallBuffers shrinks the send buffer of every connection it accepts, so
+// that a client that stops reading blocks the server's writes after a few
+// frames rather than a few megabytes.
+type smallBuffers struct{ net.Listener }
+
+func (l smallBuffers) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if tc, ok := c.(*net.TCPConn); ok {
+		_ = tc.SetWriteBuffer(4096)
+	}
+	return c, err
+}
+
+// hungServer serves the API over ts's engine with the given SSE write
+// timeout, on small buffers.
+func hungServer(t *testing.T, ts *testServer, writeTimeout time.Duration) *httptest.Server {
+	t.Helper()
+	h := api.NewServer(ts.eng,
+		api.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		api.WithSSEWriteTimeout(writeTimeout),
+		api.WithSSEHeartbeat(0),
+	).Handler()
+	srv := httptest.NewUnstartedServer(h)
+	srv.Listener = smallBuffers{srv.Listener}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// hungClient opens /v1/stream on srv and never reads a byte of it.
+func hungClient(t *testing.T, srv *httptest.Server) net.Conn {
+	t.Helper()
+	c, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	_ = c.(*net.TCPConn).SetReadBuffer(4096)
+	fmt.Fprintf(c, "GET /v1/stream HTTP/1.1\r\nHost: %s\r\n\r\n", srv.Listener.Addr())
+	return c
+}
+
+// stall ticks until none of the engine's n subscribers takes any more
+// frames, which they do once their handlers are stuck writing to clients
+// that do not read.
+func (ts *testServer) stall(n int) {
+	ts.t.Helper()
+	ts.waitFor(func(st sim.Stats) bool { return len(st.Subscribers) == n })
+	delivered := make([]uint64, n)
+	for i := 0; i < 100; i++ {
+		ts.ticks(50)
+		time.Sleep(20 * time.Millisecond)
+		st, err := ts.eng.Stats(context.Background())
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		if len(st.Subscribers) != n {
+			ts.t.Fatalf("%d subscribers, want %d", len(st.Subscribers), n)
+		}
+		stalled := true
+		for j, sub := range st.Subscribers {
+			stalled = stalled && sub.Delivered == delivered[j] && sub.Dropped > 0
+			delivered[j] = sub.Delivered
+		}
+		if stalled {
+			return
+		}
+	}
+	ts.t.Fatal("the streams never stalled")
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8}, api.WithSSEHeartbeat(20*time.Millisecond))
+	for _, path := range []string{"/v1/stream", "/v1/events"} {
+		_, events := ts.stream(path)
+		// no tick, so nothing but comments comes until the pings
+		timeout := time.After(2 * time.Second)
+		for pinged := false; !pinged; {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					t.Fatalf("%s ended before a ping", path)
+				}
+				pinged = ev.comment == "ping"
+			case <-timeout:
+				t.Fatalf("%s: no ping in two seconds", path)
+			}
+		}
+	}
+}
+
+func TestSSEByeOnShutdown(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	_, states := ts.stream("/v1/stream")
+	_, events := ts.stream("/v1/events")
+	ts.ticks(1)
+	next(t, states, "state")
+
+	ts.stop()
+	for _, ch := range []<-chan sseEvent{states, events} {
+		if ev := next(t, ch, "bye"); ev.data != `{"reason":"shutdown"}` {
+			t.Errorf("bye %s", ev.data)
+		}
+		// and the stream ends after it
+		for range ch {
+		}
+	}
+}
+
+func TestSSEDropsHungClient(t *testing.T) {
+	const writeTimeout = 200 * time.Millisecond
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	// the same client on a server without a write timeout, to tell the
+	// timeout's doing from anything else that might end a stream
+	bounded, unbounded := hungServer(t, ts, writeTimeout), hungServer(t, ts, 0)
+	hung := hungClient(t, bounded)
+	ts.waitFor(func(st sim.Stats) bool { return len(st.Subscribers) == 1 })
+	hungClient(t, unbounded)
+	ts.stall(2)
+	st, err := ts.eng.Stats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep := st.Subscribers[1].ID
+
+	stalled := time.Now()
+	for {
+		ts.ticks(1)
+		if st, err = ts.eng.Stats(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(st.Subscribers) < 2 {
+			break
+		}
+		if time.Since(stalled) > writeTimeout+2*time.Second {
+			t.Fatalf("hung client still subscribed %v after the stream stalled", time.Since(stalled))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(st.Subscribers) != 1 || st.Subscribers[0].ID != keep {
+		t.Fatalf("subscribers %+v, want only %d, the stream without a write timeout", st.Subscribers, keep)
+	}
+	// and its connection is closed: what was sent drains, then EOF
+	_ = hung.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.Copy(io.Discard, hung); err != nil {
+		t.Errorf("hung connection not closed: %v", err)
+	}
+
+	// the unbounded stream waits on its client for good
+	for end := time.Now().Add(3 * writeTimeout); time.Now().Before(end); time.Sleep(10 * time.Millisecond) {
+		ts.ticks(1)
+	}
+	if st, err = ts.eng.Stats(context.Background()); err != nil || len(st.Subscribers) != 1 {
+		t.Errorf("subscribers %+v (%v) without a write timeout", st.Subscribers, err)
+	}
+
+	// and a client that reads is served as before
+	_, events := ts.stream("/v1/stream")
+	next(t, events, "state")
+	ts.ticks(1)
+	next(t, events, "state")
+}
+
+func TestShutdownWithHungClient(t *testing.T) {
+	const (
+		writeTimeout    = 300 * time.Millisecond
+		shutdownTimeout = 2 * time.Second
+	)
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	srv := hungServer(t, ts, writeTimeout)
+	hungClient(t, srv)
+	ts.stall(1)
+
+	// as cmd/server does: stop the simulation, which ends the streams,
+	// then shut the server down
+	ts.stop()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v after %v", err, time.Since(start))
+	}
+	if _, err := http.Get(srv.URL + "/v1/live"); err == nil {
+		t.Error("server still answering after shutdown")
+	}
+}
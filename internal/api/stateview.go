@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/sim"
+)
+
+// stateView is how one client asked to see states on /state or /stream:
+// the altitude datum (?altRef=), the UTM position (?fmt=utm), the units
+// (?units=) and the fields to keep (?fields=).
+type stateView struct {
+	altRef   sim.AltRef
+	utm      bool
+	aviation bool
+	fields   []string // nil keeps every field
+}
+
+// Conversions from SI for ?units=aviation.
+const (
+	ftPerM        = 1 / 0.3048
+	ktPerMps      = 3600 / 1852.0
+	fpmPerMps     = 60 / 0.3048
+	unitsSI       = "si"
+	unitsAviation = "aviation"
+)
+
+// aviationUnits lists the state fields ?units=aviation converts, with the
+// name they go out under: fields named after their SI unit are renamed.
+var aviationUnits = map[string]struct {
+	name  string
+	scale float64
+}{
+	"alt":              {"alt", ftPerM},
+	"aglM":             {"aglFt", ftPerM},
+	"targetAglM":       {"targetAglFt", ftPerM},
+	"densityAltitudeM": {"densityAltitudeFt", ftPerM},
+	"vx":               {"vx", ktPerMps},
+	"vy":               {"vy", ktPerMps},
+	"gvx":              {"gvx", ktPerMps},
+	"gvy":              {"gvy", ktPerMps},
+	"groundSpeedMps":   {"groundSpeedKt", ktPerMps},
+	"headwindMps":      {"headwindKt", ktPerMps},
+	"crosswindMps":     {"crosswindKt", ktPerMps},
+	"estimatedWindX":   {"estimatedWindX", ktPerMps},
+	"estimatedWindY":   {"estimatedWindY", ktPerMps},
+	"vz":               {"vz", fpmPerMps},
+	"gvz":              {"gvz", fpmPerMps},
+	"verticalSpeedMps": {"verticalSpeedFpm", fpmPerMps},
+}
+
+// stateFields are the JSON names of the state's fields.
+var stateFields = func() []string {
+	t := reflect.TypeOf(sim.AircraftState{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// fieldNames returns the state's field names in the given units, sorted.
+func fieldNames(aviation bool) []string {
+	names := make([]string, len(stateFields))
+	for i, name := range stateFields {
+		if c, ok := aviationUnits[name]; ok && aviation {
+			name = c.name
+		}
+		names[i] = name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseStateView reads a stateView from r's query.
+func (s *Server) parseStateView(ctx context.Context, r *http.Request) (stateView, error) {
+	q := r.URL.Query()
+	var v stateView
+	var err error
+	if v.altRef, err = s.altRef(ctx, q.Get("altRef")); err != nil {
+		return v, err
+	}
+	if v.utm, err = wantUTM(r); err != nil {
+		return v, err
+	}
+	switch u := q.Get("units"); u {
+	case "", unitsSI:
+	case unitsAviation:
+		v.aviation = true
+	default:
+		return v, invalid("invalid_units", "units", "unknown units %q (want %s or %s)", u, unitsSI, unitsAviation)
+	}
+	if f := q.Get("fields"); f != "" {
+		valid := fieldNames(v.aviation)
+		for _, name := range strings.Split(f, ",") {
+			name = strings.TrimSpace(name)
+			if i := sort.SearchStrings(valid, name); i == len(valid) || valid[i] != name {
+				return v, invalid("unknown_field", "fields", "unknown field %q; valid fields are %s", name, strings.Join(valid, ","))
+			}
+			v.fields = append(v.fields, name)
+		}
+	}
+	return v, nil
+}
+
+// render returns st as the client asked to see it: the state itself, or a
+// map once units or fields apply. Only the output changes; the engine's
+// state stays SI.
+func (s *Server) render(v stateView, st sim.AircraftState) (any, error) {
+	st, err := s.eng.InDatum(st, v.altRef)
+	if err != nil {
+		return nil, err
+	}
+	if v.utm {
+		st = withUTM(st)
+	}
+	if !v.aviation && v.fields == nil {
+		return st, nil
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if v.aviation {
+		for name, c := range aviationUnits {
+			x, ok := m[name].(float64)
+			if !ok {
+				continue
+			}
+			delete(m, name)
+			m[c.name] = x * c.scale
+		}
+	}
+	if v.fields == nil {
+		return m, nil
+	}
+	out := make(map[string]any, len(v.fields))
+	for _, name := range v.fields {
+		// fields left out of this state (omitempty) stay out
+		if x, ok := m[name]; ok {
+			out[name] = x
+		}
+	}
+	return out, nil
+}
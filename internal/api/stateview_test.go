@@ -0,0 +1,146 @@
+package api_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+// keys returns the sorted keys of a JSON object.
+func keys(t *testing.T, b []byte) []string {
+	t.Helper()
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("%v in %s", err, b)
+	}
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func TestStateFields(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(2)
+	for _, c := range []struct {
+		query string
+		want  string
+	}{
+		{"fields=lat,lon,alt,headingDeg", "alt,headingDeg,lat,lon"},
+		{"fields=seq", "seq"},
+		{"fields=lat,%20lon", "lat,lon"},
+		{"units=aviation&fields=alt,groundSpeedKt,verticalSpeedFpm", "alt,groundSpeedKt,verticalSpeedFpm"},
+		// left out of this state (omitempty), so left out of the answer
+		{"fields=lat,aglM", "lat"},
+	} {
+		resp, b := ts.do(http.MethodGet, "/v1/state?"+c.query, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: %d %s", c.query, resp.StatusCode, b)
+		}
+		if got := strings.Join(keys(t, b), ","); got != c.want {
+			t.Errorf("%s: keys %s, want %s", c.query, got, c.want)
+		}
+	}
+}
+
+func TestStateAviationUnits(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	if resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.05,"lon":8,"alt":1500,"speed":40}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(100)
+	var si map[string]any
+	ts.getJSON("/v1/state", &si)
+	var av map[string]any
+	ts.getJSON("/v1/state?units=aviation", &av)
+	for _, c := range []struct {
+		si, av string
+		scale  float64
+	}{
+		{"alt", "alt", 1 / 0.3048},
+		{"groundSpeedMps", "groundSpeedKt", 3600 / 1852.0},
+		{"vy", "vy", 3600 / 1852.0},
+		{"verticalSpeedMps", "verticalSpeedFpm", 60 / 0.3048},
+		{"vz", "vz", 60 / 0.3048},
+	} {
+		x, _ := si[c.si].(float64)
+		y, ok := av[c.av].(float64)
+		if !ok || math.Abs(y-x*c.scale) > 1e-6*math.Max(1, math.Abs(y)) {
+			t.Errorf("%s %v is %s %v in aviation units, want %v", c.si, x, c.av, av[c.av], x*c.scale)
+		}
+		if c.si != c.av {
+			if _, ok := av[c.si]; ok {
+				t.Errorf("%s still present in aviation units", c.si)
+			}
+		}
+	}
+	if si["lat"] != av["lat"] || si["headingDeg"] != av["headingDeg"] {
+		t.Errorf("unconverted fields differ: %v, %v and %v, %v", si["lat"], si["headingDeg"], av["lat"], av["headingDeg"])
+	}
+
+	// the conversion is output only
+	var st sim.AircraftState
+	ts.getJSON("/v1/state", &st)
+	if st.GroundSpeedMps > 45 || st.Alt > 1600 {
+		t.Errorf("state no longer SI: %.1f m/s at %.0f m", st.GroundSpeedMps, st.Alt)
+	}
+}
+
+func TestStreamFields(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct {
+		query string
+		want  string
+	}{
+		{"fields=lat,lon,alt,headingDeg", "alt,headingDeg,lat,lon"},
+		{"units=aviation&fields=groundSpeedKt,alt", "alt,groundSpeedKt"},
+	} {
+		resp, events := ts.stream("/v1/stream?" + c.query)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("%s: %d %s", c.query, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		next(t, events, "state")
+		for i := 0; i < 5; i++ {
+			ts.ticks(1)
+			ev := next(t, events, "state")
+			if got := strings.Join(keys(t, []byte(ev.data)), ","); got != c.want {
+				t.Errorf("%s: event keys %s, want %s", c.query, got, c.want)
+			}
+			if ev.id == "" {
+				t.Errorf("%s: state event without an id", c.query)
+			}
+		}
+	}
+}
+
+func TestStateViewErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, path := range []string{"/v1/state", "/v1/stream", "/v1/state/wait"} {
+		for _, c := range []struct {
+			query string
+			code  string
+			field string
+		}{
+			{"fields=lat,bogus", "unknown_field", "fields"},
+			{"units=aviation&fields=groundSpeedMps", "unknown_field", "fields"},
+			{"fields=groundSpeedKt", "unknown_field", "fields"},
+			{"units=metric", "invalid_units", "units"},
+		} {
+			resp, b := ts.do(http.MethodGet, path+"?"+c.query, "")
+			e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+			if e.Details["field"] != c.field {
+				t.Errorf("%s?%s: details %v", path, c.query, e.Details)
+			}
+			if strings.Contains(c.query, "fields") && !strings.Contains(e.Message, "valid fields are") {
+				t.Errorf("%s?%s: %q does not list the valid fields", path, c.query, e.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// waitFor polls the engine until cond holds of its stats, failing the test
+// after two seconds.
+func (ts *testServer) waitFor(cond func(sim.Stats) bool) {
+	ts.t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		st, err := ts.eng.Stats(context.Background())
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		if cond(st) {
+			return
+		}
+	}
+	ts.t.Fatal("engine stats never met the condition")
+}
+
+func TestStateWait(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(3)
+	var st sim.AircraftState
+	ts.getJSON("/v1/state", &st)
+	cur := st.Seq
+
+	// a newer state is there already
+	resp, b := ts.do(http.MethodGet, "/v1/state/wait?afterSeq="+strconv.FormatUint(cur-1, 10), "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%d %s", resp.StatusCode, b)
+	}
+	if err := json.Unmarshal(b, &st); err != nil || st.Seq != cur || resp.Header.Get("X-State-Seq") != strconv.FormatUint(cur, 10) {
+		t.Errorf("seq %d, X-State-Seq %s, want %d", st.Seq, resp.Header.Get("X-State-Seq"), cur)
+	}
+
+	// nothing newer before the timeout
+	start := time.Now()
+	resp, b = ts.do(http.MethodGet, "/v1/state/wait?timeoutS=0.05&afterSeq="+strconv.FormatUint(cur, 10), "")
+	if resp.StatusCode != http.StatusNoContent || len(b) != 0 {
+		t.Errorf("timeout: %d %s", resp.StatusCode, b)
+	}
+	if el := time.Since(start); el < 50*time.Millisecond || el > time.Second {
+		t.Errorf("timed out after %v", el)
+	}
+
+	// projected, the seq is still in the header
+	resp, b = ts.do(http.MethodGet, "/v1/state/wait?fields=lat,lon&afterSeq=0", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-State-Seq") != strconv.FormatUint(cur, 10) {
+		t.Errorf("fields: %d %s, X-State-Seq %q", resp.StatusCode, b, resp.Header.Get("X-State-Seq"))
+	}
+}
+
+func TestStateWaitTimeoutBeforeSubscribing(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(1)
+	// a zero timeout ends before the engine hands over the subscription
+	for i := 0; i < 200; i++ {
+		resp, b := ts.do(http.MethodGet, "/v1/state/wait?timeoutS=0&afterSeq=1000", "")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("wait %d: %d %s, want 204", i, resp.StatusCode, b)
+		}
+	}
+}
+
+func TestStateWaitManyWaiters(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(3)
+	var st sim.AircraftState
+	ts.getJSON("/v1/state", &st)
+	cur := st.Seq
+
+	const waiters = 64
+	var wg sync.WaitGroup
+	seqs := make(chan uint64, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := ts.srv.Client().Get(ts.srv.URL + "/v1/state/wait?timeoutS=10&afterSeq=" + strconv.FormatUint(cur, 10))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			var st sim.AircraftState
+			if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&st) != nil {
+				t.Errorf("waiter: %d", resp.StatusCode)
+				return
+			}
+			seqs <- st.Seq
+		}()
+	}
+	// every waiter holds a subscription before the next state
+	ts.waitFor(func(s sim.Stats) bool { return len(s.Subscribers) == waiters })
+	ts.ticks(1)
+	wg.Wait()
+	close(seqs)
+	n := 0
+	for seq := range seqs {
+		n++
+		if seq != cur+1 {
+			t.Errorf("waiter got seq %d, want %d", seq, cur+1)
+		}
+	}
+	if n != waiters {
+		t.Errorf("%d of %d waiters answered", n, waiters)
+	}
+	// and each has dropped it: at once, or, when the burst overflows the
+	// engine's unsubscribe queue, at the next publish as its context is over
+	ts.ticks(1)
+	ts.waitFor(func(s sim.Stats) bool { return len(s.Subscribers) == 0 })
+}
+
+func TestStateWaitErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct{ query, code, field string }{
+		{"afterSeq=x", "invalid_after_seq", "afterSeq"},
+		{"afterSeq=-1", "invalid_after_seq", "afterSeq"},
+		{"timeoutS=61", "invalid_timeout", "timeoutS"},
+		{"timeoutS=-1", "invalid_timeout", "timeoutS"},
+		{"timeoutS=NaN", "invalid_timeout", "timeoutS"},
+	} {
+		resp, b := ts.do(http.MethodGet, "/v1/state/wait?"+c.query, "")
+		e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+		if e.Details["field"] != c.field {
+			t.Errorf("%s: details %v", c.query, e.Details)
+		}
+	}
+	resp, b := ts.do(http.MethodPost, "/v1/state/wait", "")
+	wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// Defaults of the /stream filters.
+const (
+	defaultChangeEpsM     = 1.0
+	defaultWarnKeepaliveS = 10.0
+)
+
+// streamFilter drops the frames of one /stream connection that a client
+// asked not to see: with onchange those that moved less than epsM from the
+// last frame sent and changed no command or warning, with warningsOnly all
+// but those changing the set of warnings, plus one every keepalive.
+type streamFilter struct {
+	onChange     bool
+	warningsOnly bool
+	epsM         float64
+	keepalive    time.Duration
+
+	last *sim.AircraftState // last frame let through
+}
+
+// parseStreamFilter reads ?onchange=1 (with ?eps=<m>) and ?only=warnings
+// (with ?keepalive=<s>). It returns nil when the stream is not filtered.
+func parseStreamFilter(r *http.Request) (*streamFilter, error) {
+	q := r.URL.Query()
+	f := &streamFilter{epsM: defaultChangeEpsM, keepalive: time.Duration(defaultWarnKeepaliveS * float64(time.Second))}
+	switch v := q.Get("onchange"); v {
+	case "", "0", "false":
+	case "1", "true":
+		f.onChange = true
+	default:
+		return nil, invalid("invalid_onchange", "onchange", "onchange must be 0 or 1")
+	}
+	switch v := q.Get("only"); v {
+	case "":
+	case "warnings":
+		f.warningsOnly = true
+	default:
+		return nil, invalid("invalid_only", "only", "unknown only %q (want warnings)", v)
+	}
+	if v := q.Get("eps"); v != "" {
+		eps, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(eps >= 0) || math.IsInf(eps, 1) {
+			return nil, invalid("invalid_eps", "eps", "eps must be a distance in metres >= 0")
+		}
+		f.epsM = eps
+	}
+	if v := q.Get("keepalive"); v != "" {
+		s, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(s >= 0) || math.IsInf(s, 1) {
+			return nil, invalid("invalid_keepalive", "keepalive", "keepalive must be a number of seconds >= 0")
+		}
+		f.keepalive = time.Duration(s * float64(time.Second))
+	}
+	if !f.onChange && !f.warningsOnly {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// pass reports whether st goes out, remembering it when it does. A nil
+// filter passes everything.
+func (f *streamFilter) pass(st sim.AircraftState) bool {
+	if f == nil {
+		return true
+	}
+	if f.last == nil || f.changed(st) {
+		f.last = &st
+		return true
+	}
+	return false
+}
+
+func (f *streamFilter) changed(st sim.AircraftState) bool {
+	last := *f.last
+	if !sameWarnings(last.Warnings, st.Warnings) {
+		return true
+	}
+	if f.warningsOnly {
+		return f.keepalive > 0 && st.TS.Sub(last.TS) >= f.keepalive
+	}
+	if st.ActiveCommand != last.ActiveCommand || st.TargetIndex != last.TargetIndex || st.Ditched != last.Ditched {
+		return true
+	}
+	moved := math.Hypot(sim.HaversineM(last.Lat, last.Lon, st.Lat, st.Lon), st.Alt-last.Alt)
+	return moved > f.epsM
+}
+
+// sameWarnings compares the warning codes of two states as sets, so a
+// message that only updates a number does not count as a change.
+func sameWarnings(a, b []sim.Warning) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	codes := map[string]int{}
+	for _, w := range a {
+		codes[w.Code]++
+	}
+	for _, w := range b {
+		if codes[w.Code] == 0 {
+			return false
+		}
+		codes[w.Code]--
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// drain collects the state events that arrive on ch until it has been
+// quiet for a moment.
+func drain(ch <-chan sseEvent) []sim.AircraftState {
+	var states []sim.AircraftState
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return states
+			}
+			if ev.event == "state" {
+				var st sim.AircraftState
+				_ = json.Unmarshal([]byte(ev.data), &st)
+				states = append(states, st)
+			}
+		case <-time.After(100 * time.Millisecond):
+			return states
+		}
+	}
+}
+
+// paced advances the engine n ticks, one at a time, each once the state
+// of the last has come out of the unfiltered stream ch, so that no
+// subscriber falls behind by a channel's worth of frames.
+func (ts *testServer) paced(n int, ch <-chan sseEvent) {
+	ts.t.Helper()
+	for i := 0; i < n; i++ {
+		ts.ticks(1)
+		next(ts.t, ch, "state")
+	}
+}
+
+// noneDropped fails the test when the engine dropped frames for a slow
+// subscriber, which would make the counts of a filter meaningless.
+func (ts *testServer) noneDropped() {
+	ts.t.Helper()
+	st, err := ts.eng.Stats(context.Background())
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	if st.FramesDropped != 0 {
+		ts.t.Fatalf("%d frames dropped", st.FramesDropped)
+	}
+}
+
+func TestStreamOnChangeHeld(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	if resp, b := ts.do(http.MethodPost, "/v1/command/hold", ""); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("hold: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(20)
+
+	_, all := ts.stream("/v1/stream")
+	_, changes := ts.stream("/v1/stream?onchange=1")
+	next(t, all, "state")
+	next(t, changes, "state")
+	ts.paced(200, all)
+	ts.noneDropped()
+	if n := len(drain(changes)); n > 2 {
+		t.Errorf("%d frames in 200 ticks of a held aircraft with onchange=1", n)
+	}
+}
+
+func TestStreamOnChangeMoving(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	if resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.1,"lon":8,"alt":1000,"speed":40}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(100)
+	_, all := ts.stream("/v1/stream")
+	_, events := ts.stream("/v1/stream?onchange=1&eps=50")
+	next(t, all, "state")
+	first := next(t, events, "state")
+	var last sim.AircraftState
+	_ = json.Unmarshal([]byte(first.data), &last)
+	ts.paced(200, all)
+	ts.noneDropped()
+	states := drain(events)
+	// 10 s at 40 m/s is 400 m, a frame past every 50
+	if n := len(states); n < 6 || n > 9 {
+		t.Errorf("%d frames for 400 m with eps=50", n)
+	}
+	for _, st := range states {
+		if d := math.Hypot(sim.HaversineM(last.Lat, last.Lon, st.Lat, st.Lon), st.Alt-last.Alt); d <= 50 {
+			t.Errorf("frame %d moved %.1f m from the last", st.Seq, d)
+		}
+		last = st
+	}
+}
+
+func TestStreamWarningsOnly(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000, CrosswindLimitMps: 5})
+	if resp, b := ts.do(http.MethodPost, "/v1/command/goto", `{"lat":47.1,"lon":8,"alt":1000,"speed":40}`); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("goto: %d %s", resp.StatusCode, b)
+	}
+	ts.ticks(100)
+	_, all := ts.stream("/v1/stream")
+	_, quiet := ts.stream("/v1/stream?only=warnings&keepalive=0")
+	_, kept := ts.stream("/v1/stream?only=warnings&keepalive=2")
+	next(t, all, "state")
+	next(t, quiet, "state")
+	next(t, kept, "state")
+
+	ts.paced(100, all)
+	ts.noneDropped()
+	if n := len(drain(quiet)); n != 0 {
+		t.Errorf("%d frames without a warning change", n)
+	}
+	// one every 2 s of the 5 s
+	if n := len(drain(kept)); n != 2 {
+		t.Errorf("%d keepalive frames in 5 s at keepalive=2", n)
+	}
+
+	// a crosswind over the limit raises a warning, and calm clears it
+	for _, c := range []struct {
+		wind string
+		warn bool
+	}{
+		{`{"wx":10,"wy":0}`, true},
+		{`{"wx":0,"wy":0}`, false},
+	} {
+		if resp, b := ts.do(http.MethodPut, "/v1/environment/wind", c.wind); resp.StatusCode != http.StatusOK {
+			t.Fatalf("wind: %d %s", resp.StatusCode, b)
+		}
+		ts.paced(20, all)
+		states := drain(quiet)
+		if len(states) != 1 {
+			t.Fatalf("wind %s: %d frames, want the one changing the warnings", c.wind, len(states))
+		}
+		has := false
+		for _, w := range states[0].Warnings {
+			has = has || w.Code == sim.WarnCrosswindLimit
+		}
+		if has != c.warn {
+			t.Errorf("wind %s: warnings %v", c.wind, states[0].Warnings)
+		}
+	}
+}
+
+func TestStreamFilterErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, c := range []struct{ query, code, field string }{
+		{"onchange=yes", "invalid_onchange", "onchange"},
+		{"onchange=1&eps=-1", "invalid_eps", "eps"},
+		{"onchange=1&eps=x", "invalid_eps", "eps"},
+		{"only=errors", "invalid_only", "only"},
+		{"only=warnings&keepalive=-2", "invalid_keepalive", "keepalive"},
+		{"only=warnings&keepalive=Inf", "invalid_keepalive", "keepalive"},
+	} {
+		resp, b := ts.do(http.MethodGet, "/v1/stream?"+c.query, "")
+		e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+		if e.Details["field"] != c.field {
+			t.Errorf("%s: details %v", c.query, e.Details)
+		}
+	}
+}
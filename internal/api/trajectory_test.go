@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+func TestTrajectoryLength(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	wps := []sim.Waypoint{{Lat: 47, Lon: 8}, {Lat: 47.1, Lon: 8}, {Lat: 47.1, Lon: 8.2}}
+	open := sim.HaversineM(47, 8, 47.1, 8) + sim.HaversineM(47.1, 8, 47.1, 8.2)
+	closing := sim.HaversineM(47.1, 8.2, 47, 8)
+	for _, c := range []struct {
+		loop bool
+		want float64
+	}{
+		{false, open},
+		{true, open + closing},
+	} {
+		body := `{"waypoints":[{"lat":47,"lon":8,"alt":1000},{"lat":47.1,"lon":8,"alt":1000},{"lat":47.1,"lon":8.2,"alt":1000}]`
+		if c.loop {
+			body += `,"loop":true`
+		}
+		resp, b := ts.do(http.MethodPost, "/v1/command/trajectory", body+"}")
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("loop %v: %d %s", c.loop, resp.StatusCode, b)
+		}
+		var out struct {
+			Status, Type string
+			Count        int
+			LengthM      float64
+		}
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Status != "accepted" || out.Type != "trajectory" || out.Count != len(wps) {
+			t.Errorf("loop %v: %+v", c.loop, out)
+		}
+		if math.Abs(out.LengthM-c.want) > 0.05 {
+			t.Errorf("loop %v: lengthM %.1f, want %.1f", c.loop, out.LengthM, c.want)
+		}
+		if got := sim.RouteLengthM(wps); math.Abs(got-open) > 1e-6 {
+			t.Errorf("RouteLengthM = %.3f, want %.3f", got, open)
+		}
+	}
+}
+
+func TestTrajectoryErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8}, api.WithMaxWaypoints(3))
+	wp := `{"lat":47,"lon":8,"alt":1000}`
+	for _, c := range []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{"no waypoints", `{"waypoints":[]}`, http.StatusBadRequest, "waypoints_empty", "waypoints"},
+		{"too many", `{"waypoints":[` + strings.Repeat(wp+",", 3) + wp + `]}`, http.StatusBadRequest, "too_many_waypoints", "waypoints"},
+		{"bad latitude", `{"waypoints":[` + wp + `,{"lat":95,"lon":8,"alt":1000}]}`, http.StatusBadRequest, "invalid_latitude", "waypoints[1].lat"},
+		{"bad longitude", `{"waypoints":[{"lat":47,"lon":200,"alt":1000}]}`, http.StatusBadRequest, "invalid_longitude", "waypoints[0].lon"},
+		{"negative speed", `{"waypoints":[{"lat":47,"lon":8,"alt":1000,"speed":-1}]}`, http.StatusBadRequest, "invalid_speed", "waypoints[0].speed"},
+		{"below ground", `{"waypoints":[{"lat":47,"lon":8,"alt":-600}]}`, http.StatusBadRequest, "invalid_altitude", "waypoints[0].alt"},
+		{"out of range", `{"waypoints":[` + wp + `,{"lat":50,"lon":8,"alt":1000}]}`, http.StatusBadRequest, "out_of_range", "waypoints[1]"},
+		{"unknown field", `{"waypoints":[` + wp + `],"speed":3}`, http.StatusBadRequest, "unknown_field", "speed"},
+		{"wrong type", `{"waypoints":[{"lat":"47","lon":8,"alt":1000}]}`, http.StatusBadRequest, "invalid_type", "waypoints.0.lat"},
+		{"bad json", `{"waypoints":[`, http.StatusBadRequest, "invalid_json", ""},
+		{"two values", `{"waypoints":[` + wp + `]} {}`, http.StatusBadRequest, "invalid_json", ""},
+	} {
+		resp, b := ts.do(http.MethodPost, "/v1/command/trajectory", c.body)
+		e := wantError(t, resp, b, c.status, c.code)
+		if f, _ := e.Details["field"].(string); f != c.field {
+			t.Errorf("%s: field %q, want %q", c.name, f, c.field)
+		}
+		if c.code == "out_of_range" && e.Details["distanceM"] == nil {
+			t.Errorf("%s: details %v", c.name, e.Details)
+		}
+	}
+
+	resp, b := ts.do(http.MethodPost, "/v1/command/trajectory", `{"waypoints":[`+wp+`,"`+strings.Repeat("x", 1<<20)+`"]}`)
+	e := wantError(t, resp, b, http.StatusRequestEntityTooLarge, "body_too_large")
+	if e.Details["limitBytes"] == nil {
+		t.Errorf("details %v", e.Details)
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// uiFiles is the built-in page: plain HTML, CSS and script, no build step.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// ui serves the built-in page under /ui/. The files are open to all, like
+// /version; the page sends the API key the user gives it with its requests.
+func (s *Server) ui() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.StripPrefix("/ui/", http.FileServer(http.FS(files)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, http.MethodGet, http.MethodHead)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/ui")
+		name = strings.TrimPrefix(name, "/")
+		if name == "" {
+			name = "index.html"
+		}
+		if _, err := fs.Stat(files, name); err != nil {
+			notFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(w, r)
+	})
+}
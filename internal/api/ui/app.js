@@ -0,0 +1,206 @@
+// The built-in page of flight-simulator2: a live map of the aircraft fed by
+// /v1/stream, and forms that send commands through the API. Plain script,
+// no build step; Leaflet comes from its CDN.
+"use strict";
+
+const API = "/v1";
+const TRAIL_POINTS = 2000;
+
+const $ = (id) => document.getElementById(id);
+
+let apiKey = localStorage.getItem("fs2.apiKey") || "";
+
+function headers(extra) {
+  const h = Object.assign({}, extra);
+  if (apiKey) h["X-API-Key"] = apiKey;
+  return h;
+}
+
+// ---- map ----
+
+const map = L.map("map").setView([32.0853, 34.7818], 12);
+L.tileLayer("https://tile.openstreetmap.org/{z}/{x}/{y}.png", {
+  maxZoom: 19,
+  attribution: '&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors',
+}).addTo(map);
+
+const trail = L.polyline([], { color: "#1565c0", weight: 2 }).addTo(map);
+const aircraft = L.circleMarker([0, 0], { radius: 7, color: "#0d47a1", fillOpacity: 0.9 });
+const planned = L.polyline([], { color: "#6a1b9a", weight: 1, dashArray: "4 4" }).addTo(map);
+let centered = false;
+
+map.on("click", (ev) => {
+  const { lat, lng } = ev.latlng;
+  if (ev.originalEvent.shiftKey) {
+    const ta = document.querySelector("#trajectory textarea");
+    const alt = document.querySelector("#goto [name=alt]").value || 500;
+    ta.value += (ta.value && !ta.value.endsWith("\n") ? "\n" : "") + `${lat.toFixed(6)},${lng.toFixed(6)},${alt}`;
+    ta.closest("details").open = true;
+    showPlanned();
+    return;
+  }
+  document.querySelector("#goto [name=lat]").value = lat.toFixed(6);
+  document.querySelector("#goto [name=lon]").value = lng.toFixed(6);
+});
+
+// ---- telemetry ----
+
+const fmt = (v, digits, unit) => (v === undefined || v === null ? "–" : `${v.toFixed(digits)} ${unit}`);
+
+function show(st) {
+  const pos = [st.lat, st.lon];
+  aircraft.setLatLng(pos);
+  if (!map.hasLayer(aircraft)) aircraft.addTo(map);
+  trail.addLatLng(pos);
+  const pts = trail.getLatLngs();
+  if (pts.length > TRAIL_POINTS) trail.setLatLngs(pts.slice(pts.length - TRAIL_POINTS));
+  if (!centered) {
+    map.setView(pos, 14);
+    centered = true;
+  }
+
+  $("alt").textContent = fmt(st.alt, 1, "m");
+  $("speed").textContent = fmt(st.groundSpeedMps, 1, "m/s");
+  $("vs").textContent = fmt(st.verticalSpeedMps, 1, "m/s");
+  $("heading").textContent = fmt(st.headingDeg, 0, "°");
+  $("track").textContent = fmt(st.trackDeg, 0, "°");
+  let cmd = st.activeCommand || "idle";
+  if (st.activeCommand === "trajectory") cmd += ` → wp ${st.targetIndex || 0}`;
+  if (st.mission) cmd += ` (${st.mission})`;
+  $("command").textContent = cmd;
+  $("battery").textContent = st.batteryPct === undefined ? "–" : `${st.batteryPct.toFixed(0)} %`;
+
+  const list = $("warnings");
+  list.replaceChildren();
+  for (const w of st.warnings || []) {
+    const li = document.createElement("li");
+    li.className = w.severity || "";
+    li.textContent = w.message ? `${w.code}: ${w.message}` : w.code;
+    list.appendChild(li);
+  }
+}
+
+function link(text, cls) {
+  $("link").textContent = text;
+  $("link").className = "muted " + cls;
+}
+
+// ---- stream ----
+
+// stream reads /v1/stream with fetch rather than EventSource, which cannot
+// send the API key header, and resumes from the last event id it saw.
+async function stream() {
+  let lastId = "";
+  let backoff = 1000;
+  for (;;) {
+    try {
+      const h = headers({ Accept: "text/event-stream" });
+      if (lastId) h["Last-Event-ID"] = lastId;
+      const resp = await fetch(`${API}/stream?hz=5`, { headers: h });
+      if (!resp.ok) throw new Error(await errorText(resp));
+      link("live", "ok");
+      backoff = 1000;
+      const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buf = "";
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) break;
+        buf += value;
+        let end;
+        while ((end = buf.indexOf("\n\n")) >= 0) {
+          const ev = parseEvent(buf.slice(0, end));
+          buf = buf.slice(end + 2);
+          if (ev.id) lastId = ev.id;
+          if (ev.event === "state") show(JSON.parse(ev.data));
+          if (ev.event === "reset") trail.setLatLngs([]);
+        }
+      }
+      link("stream ended, reconnecting…", "down");
+    } catch (err) {
+      link(`disconnected: ${err.message}`, "down");
+    }
+    await new Promise((r) => setTimeout(r, backoff));
+    backoff = Math.min(backoff * 2, 15000);
+  }
+}
+
+function parseEvent(block) {
+  const ev = { event: "message", data: "" };
+  for (const line of block.split("\n")) {
+    if (line.startsWith(":")) continue;
+    const i = line.indexOf(":");
+    const field = i < 0 ? line : line.slice(0, i);
+    const value = i < 0 ? "" : line.slice(i + 1).replace(/^ /, "");
+    if (field === "data") ev.data += (ev.data ? "\n" : "") + value;
+    else if (field === "event" || field === "id") ev[field] = value;
+  }
+  return ev;
+}
+
+// ---- commands ----
+
+async function errorText(resp) {
+  try {
+    const body = await resp.json();
+    if (body.error) return `${body.error.code}: ${body.error.message}`;
+  } catch (_) {}
+  return `${resp.status} ${resp.statusText}`;
+}
+
+async function send(path, body) {
+  const opts = { method: "POST", headers: headers({ "Content-Type": "application/json" }) };
+  if (body !== undefined) opts.body = JSON.stringify(body);
+  try {
+    const resp = await fetch(API + path, opts);
+    $("result").textContent = resp.ok ? `${path}: accepted` : await errorText(resp);
+    return resp.ok;
+  } catch (err) {
+    $("result").textContent = err.message;
+    return false;
+  }
+}
+
+function num(v) {
+  return v === "" ? undefined : Number(v);
+}
+
+function waypoints() {
+  const text = document.querySelector("#trajectory textarea").value;
+  return text
+    .split("\n")
+    .map((l) => l.trim())
+    .filter((l) => l)
+    .map((l) => {
+      const [lat, lon, alt, speed] = l.split(",").map((s) => num(s.trim()));
+      return { lat, lon, alt: alt || 0, speed };
+    });
+}
+
+function showPlanned() {
+  planned.setLatLngs(waypoints().filter((w) => !isNaN(w.lat) && !isNaN(w.lon)).map((w) => [w.lat, w.lon]));
+}
+
+$("goto").addEventListener("submit", (ev) => {
+  ev.preventDefault();
+  const f = ev.target.elements;
+  send("/command/goto", { lat: num(f.lat.value), lon: num(f.lon.value), alt: num(f.alt.value), speed: num(f.speed.value) });
+});
+
+$("trajectory").addEventListener("input", showPlanned);
+$("trajectory").addEventListener("submit", (ev) => {
+  ev.preventDefault();
+  send("/command/trajectory", { waypoints: waypoints(), loop: ev.target.elements.loop.checked });
+});
+
+$("hold").addEventListener("click", () => send("/command/hold"));
+$("stop").addEventListener("click", () => send("/command/stop"));
+
+$("key").elements.key.value = apiKey;
+$("key").addEventListener("submit", (ev) => {
+  ev.preventDefault();
+  apiKey = ev.target.elements.key.value.trim();
+  localStorage.setItem("fs2.apiKey", apiKey);
+  $("result").textContent = apiKey ? "API key saved" : "API key cleared";
+});
+
+stream();
@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+func TestUIAssets(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	pages := map[string][]byte{}
+	for _, c := range []struct {
+		path, contentType string
+	}{
+		{"/ui/", "text/html; charset=utf-8"},
+		{"/ui/index.html", "text/html; charset=utf-8"},
+		{"/ui/app.js", "text/javascript; charset=utf-8"},
+		{"/ui/style.css", "text/css; charset=utf-8"},
+	} {
+		resp, b := ts.do(http.MethodGet, c.path, "")
+		if resp.StatusCode != http.StatusOK || len(b) == 0 {
+			t.Errorf("%s: %d, %d bytes", c.path, resp.StatusCode, len(b))
+			continue
+		}
+		if got := resp.Header.Get("Content-Type"); got != c.contentType {
+			t.Errorf("%s: Content-Type %q, want %q", c.path, got, c.contentType)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("%s: Cache-Control %q", c.path, got)
+		}
+		pages[c.path] = b
+	}
+	resp, b := ts.do(http.MethodHead, "/ui/app.js", "")
+	if resp.StatusCode != http.StatusOK || len(b) != 0 {
+		t.Errorf("HEAD: %d with %d bytes", resp.StatusCode, len(b))
+	}
+
+	// /ui sends the browser on to the page
+	client := *ts.srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(ts.srv.URL + "/ui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/ui/" {
+		t.Errorf("/ui: %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// the page loads its own script and style, relative to /ui/
+	index := string(pages["/ui/"])
+	for _, ref := range []string{`src="app.js"`, `href="style.css"`} {
+		if !strings.Contains(index, ref) {
+			t.Errorf("index.html does not reference %s", ref)
+		}
+	}
+
+	// the script talks to /v1, and every path it uses is a route there
+	app := string(pages["/ui/app.js"])
+	if !strings.Contains(app, `const API = "/v1";`) {
+		t.Fatal("app.js does not address the API under /v1")
+	}
+	paths := []string{"/stream"}
+	for _, m := range regexp.MustCompile(`send\("(/[^"]+)"`).FindAllStringSubmatch(app, -1) {
+		paths = append(paths, m[1])
+	}
+	if len(paths) < 5 {
+		t.Fatalf("found only %v in app.js", paths)
+	}
+	for _, p := range paths {
+		// a method the route does not take proves the route exists without
+		// sending a command
+		resp, b := ts.do(http.MethodPut, "/v1"+p, "")
+		wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+	}
+}
+
+func TestUIErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	for _, path := range []string{"/ui/nope.js", "/ui/ui/index.html", "/ui/../ui/missing"} {
+		resp, b := ts.do(http.MethodGet, path, "")
+		wantError(t, resp, b, http.StatusNotFound, "route_not_found")
+	}
+	resp, b := ts.do(http.MethodPost, "/ui/", "")
+	wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+	if got := resp.Header.Get("Allow"); got != "GET, HEAD" {
+		t.Errorf("Allow %q", got)
+	}
+}
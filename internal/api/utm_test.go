@@ -0,0 +1,130 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+)
+
+// utmJSON is u as a request gives it.
+func utmJSON(t *testing.T, u sim.UTM) string {
+	t.Helper()
+	b, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGoToUTM(t *testing.T) {
+	for _, c := range []struct {
+		name                 string
+		originLat, originLon float64
+		lat, lon             float64
+		zone                 int // 0 for the point's own
+	}{
+		{"own zone", 47, 8, 47.01, 8.02, 0},
+		// 6°E is the boundary of zones 31 and 32; a survey in zone 32
+		// gives the point just west of it in that zone
+		{"across a zone boundary", 47, 6, 47, 5.99, 32},
+		{"southern hemisphere", -33.9, 151.2, -33.91, 151.21, 0},
+	} {
+		ts := newTestServer(t, sim.Config{OriginLat: c.originLat, OriginLon: c.originLon})
+		u, err := sim.LatLonToUTM(c.lat, c.lon)
+		if c.zone != 0 {
+			u, err = sim.LatLonToUTMZone(c.lat, c.lon, c.zone)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.South != (c.lat < 0) {
+			t.Errorf("%s: %+v", c.name, u)
+		}
+		resp, b := ts.do(http.MethodPost, "/v1/command/goto", fmt.Sprintf(`{"utm":%s,"alt":1000}`, utmJSON(t, u)))
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("%s: %d %s", c.name, resp.StatusCode, b)
+		}
+		ts.ticks(1)
+		var a activeOut
+		ts.getJSON("/v1/command/active", &a)
+		if d := sim.HaversineM(a.Command.Lat, a.Command.Lon, c.lat, c.lon); d > 0.01 {
+			t.Errorf("%s: flying to %g, %g, %.3f m from %g, %g", c.name, a.Command.Lat, a.Command.Lon, d, c.lat, c.lon)
+		}
+	}
+}
+
+func TestTrajectoryUTM(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: -33.9, OriginLon: 151.2})
+	u, err := sim.LatLonToUTM(-33.95, 151.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"waypoints":[{"lat":-33.9,"lon":151.2,"alt":500},{"utm":%s,"alt":500}]}`, utmJSON(t, u))
+	resp, b := ts.do(http.MethodPost, "/v1/command/trajectory", body)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("%d %s", resp.StatusCode, b)
+	}
+	var out struct{ LengthM float64 }
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := sim.HaversineM(-33.9, 151.2, -33.95, 151.2); math.Abs(out.LengthM-want) > 0.1 {
+		t.Errorf("lengthM %.1f, want %.1f", out.LengthM, want)
+	}
+}
+
+func TestStateUTM(t *testing.T) {
+	for _, c := range []struct{ lat, lon float64 }{{47, 8}, {-33.9, 151.2}} {
+		ts := newTestServer(t, sim.Config{OriginLat: c.lat, OriginLon: c.lon})
+		ts.ticks(1)
+		var st sim.AircraftState
+		ts.getJSON("/v1/state?fmt=utm", &st)
+		want, err := sim.LatLonToUTM(st.Lat, st.Lon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.UTM == nil || *st.UTM != want {
+			t.Errorf("%g, %g: utm %+v, want %+v", c.lat, c.lon, st.UTM, want)
+		}
+		st = sim.AircraftState{}
+		ts.getJSON("/v1/state", &st)
+		if st.UTM != nil {
+			t.Errorf("utm %+v without fmt=utm", st.UTM)
+		}
+	}
+}
+
+func TestUTMErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	u, err := sim.LatLonToUTM(47.01, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utm := utmJSON(t, u)
+	for _, c := range []struct {
+		name, path, body string
+		code, field      string
+	}{
+		{"both", "/v1/command/goto", `{"lat":47,"lon":8,"utm":` + utm + `,"alt":1000}`, "ambiguous_position", "utm"},
+		{"zone 0", "/v1/command/goto", `{"utm":{"zone":0,"easting":500000,"northing":5200000},"alt":1000}`, "invalid_utm", "utm"},
+		{"zone 61", "/v1/command/trajectory", `{"waypoints":[{"utm":{"zone":61,"easting":500000,"northing":5200000},"alt":1000}]}`, "invalid_utm", "waypoints[0].utm"},
+		{"both in a waypoint", "/v1/command/trajectory", `{"waypoints":[{"lat":47,"utm":` + utm + `,"alt":1000}]}`, "ambiguous_position", "waypoints[0].utm"},
+		{"far off", "/v1/command/goto", `{"utm":{"zone":32,"easting":500000,"northing":1000000},"alt":1000}`, "out_of_range", ""},
+	} {
+		resp, b := ts.do(http.MethodPost, c.path, c.body)
+		e := wantError(t, resp, b, http.StatusBadRequest, c.code)
+		if f, _ := e.Details["field"].(string); f != c.field {
+			t.Errorf("%s: field %q, want %q", c.name, f, c.field)
+		}
+	}
+
+	resp, b := ts.do(http.MethodGet, "/v1/state?fmt=mgrs", "")
+	e := wantError(t, resp, b, http.StatusBadRequest, "invalid_fmt")
+	if e.Details["field"] != "fmt" {
+		t.Errorf("details %v", e.Details)
+	}
+}
@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+func TestVersion(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8}, api.WithBuild("1.2.3"))
+	for _, path := range []string{"/version", "/v1/version"} {
+		resp, b := ts.do(http.MethodGet, path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, resp.StatusCode, b)
+		}
+		var v map[string]string
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v["apiVersion"] != api.APIVersion || v["build"] != "1.2.3" || v["go"] == "" {
+			t.Errorf("%s: %v", path, v)
+		}
+		if got := resp.Header.Get("X-API-Version"); got != api.APIVersion {
+			t.Errorf("%s: X-API-Version %q", path, got)
+		}
+		if resp.Header.Get("Deprecation") != "" {
+			t.Errorf("%s is not deprecated", path)
+		}
+		resp, b = ts.do(http.MethodPost, path, "")
+		wantError(t, resp, b, http.StatusMethodNotAllowed, "method_not_allowed")
+	}
+}
+
+func TestUnprefixedAliases(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8})
+	ts.ticks(5)
+	for _, c := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodGet, "/state", "", http.StatusOK},
+		{http.MethodGet, "/state?fields=lat,lon,alt", "", http.StatusOK},
+		{http.MethodGet, "/live", "", http.StatusOK},
+		{http.MethodGet, "/history?limit=3", "", http.StatusOK},
+		{http.MethodGet, "/commands/history", "", http.StatusOK},
+		{http.MethodGet, "/config", "", http.StatusOK},
+		{http.MethodGet, "/sim/origin", "", http.StatusOK},
+		{http.MethodGet, "/environment/wind", "", http.StatusOK},
+		{http.MethodGet, "/geo/tolocal?lat=47.01&lon=8.01&alt=10", "", http.StatusOK},
+		{http.MethodGet, "/command/active", "", http.StatusNotFound},
+		{http.MethodGet, "/state?fields=nope", "", http.StatusBadRequest},
+		{http.MethodPost, "/command/goto", `{"lat":91,"lon":8,"alt":1000}`, http.StatusBadRequest},
+		{http.MethodPost, "/command/goto", `{"lat":47`, http.StatusBadRequest},
+		{http.MethodDelete, "/state", "", http.StatusMethodNotAllowed},
+	} {
+		canon, cb := ts.do(c.method, "/v1"+c.path, c.body)
+		alias, ab := ts.do(c.method, c.path, c.body)
+		if canon.StatusCode != c.status || alias.StatusCode != c.status {
+			t.Errorf("%s %s: %d under /v1 and %d unprefixed, want %d", c.method, c.path, canon.StatusCode, alias.StatusCode, c.status)
+		}
+		if !bytes.Equal(cb, ab) {
+			t.Errorf("%s %s: bodies differ:\n/v1: %s\nunprefixed: %s", c.method, c.path, cb, ab)
+		}
+		if canon.Header.Get("Content-Type") != alias.Header.Get("Content-Type") {
+			t.Errorf("%s %s: Content-Type %q and %q", c.method, c.path, canon.Header.Get("Content-Type"), alias.Header.Get("Content-Type"))
+		}
+		for _, r := range []*http.Response{canon, alias} {
+			if got := r.Header.Get("X-API-Version"); got != api.APIVersion {
+				t.Errorf("%s %s: X-API-Version %q", c.method, r.Request.URL.Path, got)
+			}
+			if r.Header.Get("X-Request-ID") == "" {
+				t.Errorf("%s %s: no X-Request-ID", c.method, r.Request.URL.Path)
+			}
+		}
+		if canon.Header.Get("Deprecation") != "" || canon.Header.Get("Link") != "" {
+			t.Errorf("%s /v1%s: deprecated", c.method, c.path)
+		}
+		path, _, _ := strings.Cut(c.path, "?")
+		if alias.Header.Get("Deprecation") != "true" {
+			t.Errorf("%s %s: Deprecation %q", c.method, c.path, alias.Header.Get("Deprecation"))
+		}
+		if want := "</v1" + path + `>; rel="successor-version"`; alias.Header.Get("Link") != want {
+			t.Errorf("%s %s: Link %q, want %q", c.method, c.path, alias.Header.Get("Link"), want)
+		}
+	}
+
+	for _, path := range []string{"/nowhere", "/v1/nowhere", "/v2/state"} {
+		resp, b := ts.do(http.MethodGet, path, "")
+		wantError(t, resp, b, http.StatusNotFound, "route_not_found")
+	}
+}
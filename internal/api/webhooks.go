@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// Webhook delivery: each matching event is POSTed to a hook up to
+// webhookAttempts times, backing off from webhookBackoff, and a hook whose
+// deliveries fail webhookMaxFailures times in a row is disabled.
+const (
+	maxWebhooks        = 100
+	webhookQueue       = 256
+	webhookAttempts    = 4
+	webhookBackoff     = time.Second
+	webhookMaxFailures = 5
+	webhookTimeout     = 5 * time.Second
+)
+
+// WithWebhookBackoff sets the wait before the second attempt at a delivery;
+// it doubles for each further attempt. Zero or negative keeps the default
+// of one second.
+func WithWebhookBackoff(d time.Duration) Option {
+	return func(s *Server) { s.hookBackoff = d }
+}
+
+// WithWebhookTimeout bounds each attempt at a delivery, from connecting to
+// reading the answer. Zero or negative keeps the default of five seconds.
+func WithWebhookTimeout(d time.Duration) Option {
+	return func(s *Server) { s.hookTimeout = d }
+}
+
+// WebhookStatus says whether a hook is still called.
+type WebhookStatus string
+
+const (
+	// WebhookActive is a hook that is called.
+	WebhookActive WebhookStatus = "active"
+	// WebhookDisabled is a hook whose deliveries failed too often in a
+	// row; delete it and register it again to resume.
+	WebhookDisabled WebhookStatus = "disabled"
+)
+
+// Webhook is a registered URL and what is known of its deliveries.
+type Webhook struct {
+	ID     string          `json:"id"`
+	URL    string          `json:"url"`
+	Events []sim.EventKind `json:"events"` // empty = every kind
+	Status WebhookStatus   `json:"status"`
+
+	Delivered uint64 `json:"delivered"`
+	// Failures counts the failed deliveries since the last good one;
+	// Dropped the events that found the hook's queue full.
+	Failures      int        `json:"failures"`
+	Dropped       uint64     `json:"dropped"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// webhookPayload is the body POSTed for an event, with the state as of
+// the event's tick.
+type webhookPayload struct {
+	Hook  string             `json:"hook"`
+	Event sim.Event          `json:"event"`
+	State *sim.AircraftState `json:"state,omitempty"`
+}
+
+type webhook struct {
+	Webhook // guarded by webhookHub.mu
+	queue   chan webhookDelivery
+	done    chan struct{} // closed when the hook is deleted
+}
+
+type webhookDelivery struct {
+	kind sim.EventKind
+	body []byte
+}
+
+func (w *webhook) wants(kind sim.EventKind) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, k := range w.Events {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookHub delivers engine events to the registered hooks. One goroutine
+// follows the engine's events, which queue for it without ever blocking
+// the actor, and hands each one to the workers of the hooks that want it;
+// a worker per hook POSTs them in order. Both start with the first hook.
+type webhookHub struct {
+	eng     *sim.Engine
+	log     *slog.Logger
+	client  *http.Client
+	agent   string
+	backoff time.Duration
+
+	mu    sync.Mutex
+	hooks map[string]*webhook
+	start sync.Once
+}
+
+func newWebhookHub(eng *sim.Engine, log *slog.Logger, build string, backoff, timeout time.Duration) *webhookHub {
+	if backoff <= 0 {
+		backoff = webhookBackoff
+	}
+	if timeout <= 0 {
+		timeout = webhookTimeout
+	}
+	return &webhookHub{
+		eng:     eng,
+		log:     log,
+		client:  &http.Client{Timeout: timeout},
+		agent:   "flight-simulator2/" + build,
+		backoff: backoff,
+		hooks:   map[string]*webhook{},
+	}
+}
+
+func (h *webhookHub) list() []Webhook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := make([]Webhook, 0, len(h.hooks))
+	for _, w := range h.hooks {
+		list = append(list, w.Webhook)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+func (h *webhookHub) get(id string) (Webhook, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.hooks[id]
+	if !ok {
+		return Webhook{}, false
+	}
+	return w.Webhook, true
+}
+
+func (h *webhookHub) add(rawURL string, events []sim.EventKind) (Webhook, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.hooks) >= maxWebhooks {
+		return Webhook{}, &apiError{status: http.StatusConflict, code: "too_many_webhooks",
+			msg: fmt.Sprintf("at most %d webhooks can be registered", maxWebhooks)}
+	}
+	w := &webhook{
+		Webhook: Webhook{ID: newRequestID(), URL: rawURL, Events: events, Status: WebhookActive,
+			CreatedAt: time.Now().UTC()},
+		queue: make(chan webhookDelivery, webhookQueue),
+		done:  make(chan struct{}),
+	}
+	h.hooks[w.ID] = w
+	h.start.Do(func() { go h.follow() })
+	go h.work(w)
+	return w.Webhook, nil
+}
+
+func (h *webhookHub) remove(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.hooks[id]
+	if ok {
+		delete(h.hooks, id)
+		close(w.done)
+	}
+	return ok
+}
+
+// follow reads the engine's events until the engine stops.
+func (h *webhookHub) follow() {
+	for {
+		ch, unsub := h.eng.SubscribeEvents(context.Background())
+		for ev := range ch {
+			h.dispatch(ev)
+		}
+		unsub()
+		// The engine closes the channel when it stops, or when this
+		// subscriber fell too far behind; only the latter is worth a retry.
+		time.Sleep(100 * time.Millisecond)
+		if !h.eng.Health().Running {
+			return
+		}
+		h.log.Warn("webhooks fell behind the engine's events; resubscribing")
+	}
+}
+
+// dispatch queues ev for every active hook that wants it. A hook whose
+// queue is full misses it.
+func (h *webhookHub) dispatch(ev sim.Event) {
+	h.mu.Lock()
+	var targets []*webhook
+	for _, w := range h.hooks {
+		if w.Status == WebhookActive && w.wants(ev.Kind) {
+			targets = append(targets, w)
+		}
+	}
+	h.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	payload := webhookPayload{Event: ev}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	if st, err := h.eng.GetState(ctx); err == nil {
+		payload.State = &st
+	}
+	cancel()
+	for _, w := range targets {
+		payload.Hook = w.ID
+		body, err := json.Marshal(payload)
+		if err != nil {
+			h.log.Error("webhook payload", "hook", w.ID, "err", err)
+			continue
+		}
+		select {
+		case w.queue <- webhookDelivery{kind: ev.Kind, body: body}:
+		default:
+			h.mu.Lock()
+			w.Dropped++
+			h.mu.Unlock()
+		}
+	}
+}
+
+// work delivers w's queue until w is deleted. Events that arrive after w
+// was disabled are dropped.
+func (h *webhookHub) work(w *webhook) {
+	for {
+		select {
+		case <-w.done:
+			return
+		case d := <-w.queue:
+			h.mu.Lock()
+			disabled := w.Status == WebhookDisabled
+			h.mu.Unlock()
+			if disabled {
+				continue
+			}
+			err := h.deliver(w, d)
+			now := time.Now().UTC()
+			h.mu.Lock()
+			w.LastAttemptAt = &now
+			if err == nil {
+				w.Delivered++
+				w.Failures = 0
+				w.LastError = ""
+			} else {
+				w.Failures++
+				w.LastError = err.Error()
+				if w.Failures >= webhookMaxFailures {
+					w.Status = WebhookDisabled
+					h.log.Warn("webhook disabled", "hook", w.ID, "url", w.URL, "failures", w.Failures, "err", err)
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// deliver POSTs d to w, retrying network errors, 429s and 5xxs with a
+// doubling backoff. It gives up early when w is deleted.
+func (h *webhookHub) deliver(w *webhook, d webhookDelivery) error {
+	wait := h.backoff
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		var retry bool
+		retry, err = h.post(w, d)
+		if err == nil || !retry || attempt == webhookAttempts {
+			break
+		}
+		select {
+		case <-w.done:
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	return err
+}
+
+func (h *webhookHub) post(w *webhook, d webhookDelivery) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", h.agent)
+	req.Header.Set("X-Webhook-ID", w.ID)
+	req.Header.Set("X-Event-Kind", string(d.kind))
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s answered %s", w.URL, resp.Status)
+}
+
+// webhookList lists (GET) or registers (POST) webhooks.
+func (s *Server) webhookList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.webhooks.list())
+
+	case http.MethodPost:
+		var body struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events,omitempty"`
+		}
+		if err := decodeJSON(w, r, &body); err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		u, err := url.Parse(body.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errorJSON(w, http.StatusBadRequest, invalid("invalid_url", "url", "url must be an absolute http or https URL"))
+			return
+		}
+		events := make([]sim.EventKind, 0, len(body.Events))
+		for i, e := range body.Events {
+			k, err := sim.ParseEventKind(strings.TrimSpace(e))
+			if err != nil {
+				errorJSON(w, http.StatusBadRequest, invalid("invalid_event", fmt.Sprintf("events[%d]", i), "%v", err))
+				return
+			}
+			events = append(events, k)
+		}
+		hook, err := s.webhooks.add(u.String(), events)
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, hook)
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// webhook serves /webhooks/{id} (GET, DELETE).
+func (s *Server) webhook(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, apiPrefix), "/webhooks/")
+	switch r.Method {
+	case http.MethodGet:
+		hook, ok := s.webhooks.get(id)
+		if !ok {
+			webhookNotFound(w, id)
+			return
+		}
+		writeJSON(w, http.StatusOK, hook)
+
+	case http.MethodDelete:
+		if !s.webhooks.remove(id) {
+			webhookNotFound(w, id)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "deleted", "id": id})
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+func webhookNotFound(w http.ResponseWriter, id string) {
+	writeError(w, http.StatusNotFound, "webhook_not_found", fmt.Sprintf("no webhook %q", id), nil)
+}
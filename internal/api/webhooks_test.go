@@ -0,0 +1,266 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/api"
+	"flight-simulator2/internal/sim"
+)
+
+// hookCall is one request a receiver got.
+type hookCall struct {
+	header http.Header
+	body   webhookBody
+}
+
+// webhookBody is what the server POSTs for an event.
+type webhookBody struct {
+	Hook  string
+	Event sim.Event
+	State *sim.AircraftState
+}
+
+// receiver serves hook calls, answering each with status(n) for the nth
+// call, and passes them on.
+func receiver(t *testing.T, status func(n int) int) (*httptest.Server, <-chan hookCall) {
+	t.Helper()
+	calls := make(chan hookCall, 64)
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c hookCall
+		c.header = r.Header.Clone()
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &c.body); err != nil {
+			t.Errorf("hook body: %v in %s", err, b)
+		}
+		calls <- c
+		w.WriteHeader(status(int(n.Add(1))))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, calls
+}
+
+// hook registers a webhook at url for events, and waits until the server
+// follows the engine's events.
+func (ts *testServer) hook(url string, events ...string) api.Webhook {
+	ts.t.Helper()
+	body, _ := json.Marshal(map[string]any{"url": url, "events": events})
+	resp, b := ts.do(http.MethodPost, "/v1/webhooks", string(body))
+	if resp.StatusCode != http.StatusCreated {
+		ts.t.Fatalf("register %s: %d %s", url, resp.StatusCode, b)
+	}
+	var h api.Webhook
+	if err := json.Unmarshal(b, &h); err != nil {
+		ts.t.Fatal(err)
+	}
+	ts.waitFor(func(st sim.Stats) bool { return st.EventSubscribers == 1 })
+	return h
+}
+
+// command submits a command and ticks once, so that it is taken.
+func (ts *testServer) command(path, body string) {
+	ts.t.Helper()
+	if resp, b := ts.do(http.MethodPost, path, body); resp.StatusCode != http.StatusAccepted {
+		ts.t.Fatalf("%s: %d %s", path, resp.StatusCode, b)
+	}
+	ts.ticks(1)
+}
+
+// hookState polls hook id until cond holds of it, failing the test after
+// five seconds.
+func (ts *testServer) hookState(id string, cond func(api.Webhook) bool) api.Webhook {
+	ts.t.Helper()
+	var h api.Webhook
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		ts.getJSON("/v1/webhooks/"+id, &h)
+		if cond(h) {
+			return h
+		}
+	}
+	ts.t.Fatalf("webhook %s never met the condition: %+v", id, h)
+	return h
+}
+
+func call(t *testing.T, calls <-chan hookCall) hookCall {
+	t.Helper()
+	select {
+	case c := <-calls:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook call")
+		return hookCall{}
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000}, api.WithBuild("1.2.3"))
+	rcv, calls := receiver(t, func(int) int { return http.StatusNoContent })
+	h := ts.hook(rcv.URL, string(sim.EventCommandActivated))
+	if h.Status != api.WebhookActive || len(h.Events) != 1 {
+		t.Fatalf("registered %+v", h)
+	}
+
+	ts.command("/v1/command/goto", `{"lat":47.01,"lon":8,"alt":1000}`)
+	c := call(t, calls)
+	if got := c.header.Get("X-Webhook-ID"); got != h.ID {
+		t.Errorf("X-Webhook-ID %q, want %q", got, h.ID)
+	}
+	if got := c.header.Get("X-Event-Kind"); got != string(sim.EventCommandActivated) {
+		t.Errorf("X-Event-Kind %q", got)
+	}
+	if got := c.header.Get("User-Agent"); got != "flight-simulator2/1.2.3" {
+		t.Errorf("User-Agent %q", got)
+	}
+	if c.body.Hook != h.ID || c.body.Event.Kind != sim.EventCommandActivated || c.body.Event.Command != sim.CmdGoTo {
+		t.Errorf("payload %+v", c.body)
+	}
+	if c.body.State == nil || c.body.State.ActiveCommand != string(sim.CmdGoTo) {
+		t.Errorf("payload state %+v", c.body.State)
+	}
+
+	// a hold supersedes the goto and a trajectory the hold, but only the
+	// trajectory's activation is wanted
+	ts.command("/v1/command/hold", "")
+	ts.command("/v1/command/trajectory", `{"waypoints":[{"lat":47.01,"lon":8,"alt":1000}]}`)
+	if c = call(t, calls); c.body.Event.Kind != sim.EventCommandActivated || c.body.Event.Command != sim.CmdTrajectory {
+		t.Errorf("second call %s %s, want the trajectory's activation", c.body.Event.Kind, c.body.Event.Command)
+	}
+	got := ts.hookState(h.ID, func(h api.Webhook) bool { return h.Delivered == 2 })
+	if got.Failures != 0 || got.LastError != "" || got.LastAttemptAt == nil {
+		t.Errorf("after two deliveries %+v", got)
+	}
+	select {
+	case c := <-calls:
+		t.Errorf("unwanted call for %s", c.body.Event.Kind)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var list []api.Webhook
+	ts.getJSON("/v1/webhooks", &list)
+	if len(list) != 1 || list[0].ID != h.ID {
+		t.Errorf("list %+v", list)
+	}
+	if resp, b := ts.do(http.MethodDelete, "/v1/webhooks/"+h.ID, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete: %d %s", resp.StatusCode, b)
+	}
+	resp, b := ts.do(http.MethodGet, "/v1/webhooks/"+h.ID, "")
+	wantError(t, resp, b, http.StatusNotFound, "webhook_not_found")
+}
+
+func TestWebhookRetries(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000},
+		api.WithWebhookBackoff(20*time.Millisecond))
+	// two 5xxs and a 429, then the delivery goes through
+	answers := []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusTooManyRequests}
+	rcv, calls := receiver(t, func(n int) int {
+		if n <= len(answers) {
+			return answers[n-1]
+		}
+		return http.StatusOK
+	})
+	h := ts.hook(rcv.URL, string(sim.EventHold))
+
+	ts.command("/v1/command/hold", "")
+	var at []time.Time
+	for i := 0; i < 4; i++ {
+		if c := call(t, calls); c.body.Event.Kind != sim.EventHold {
+			t.Fatalf("attempt %d for %s", i+1, c.body.Event.Kind)
+		}
+		at = append(at, time.Now())
+	}
+	// the waits double: 20, 40, then 80 ms
+	for i, min := range []time.Duration{20, 40, 80} {
+		if gap := at[i+1].Sub(at[i]); gap < min*time.Millisecond {
+			t.Errorf("attempt %d came %v after the one before, want at least %v ms", i+2, gap, min)
+		}
+	}
+	if got := ts.hookState(h.ID, func(h api.Webhook) bool { return h.Delivered == 1 }); got.Failures != 0 {
+		t.Errorf("after the retried delivery %+v", got)
+	}
+
+	// a 4xx other than 429 is not retried
+	rcv2, calls2 := receiver(t, func(int) int { return http.StatusNotFound })
+	h2 := ts.hook(rcv2.URL, string(sim.EventStop))
+	ts.command("/v1/command/stop", "")
+	call(t, calls2)
+	got := ts.hookState(h2.ID, func(h api.Webhook) bool { return h.Failures == 1 })
+	if !strings.Contains(got.LastError, "404") || got.Status != api.WebhookActive {
+		t.Errorf("after a 404 %+v", got)
+	}
+	select {
+	case <-calls2:
+		t.Error("a 404 was retried")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhookDisabled(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000},
+		api.WithWebhookBackoff(time.Millisecond))
+	rcv, calls := receiver(t, func(int) int { return http.StatusInternalServerError })
+	h := ts.hook(rcv.URL, string(sim.EventHold))
+
+	// four attempts at each of five deliveries, then no more
+	for i := 1; i <= 5; i++ {
+		ts.command("/v1/command/hold", "")
+		for a := 0; a < 4; a++ {
+			call(t, calls)
+		}
+		want := api.WebhookActive
+		if i == 5 {
+			want = api.WebhookDisabled
+		}
+		if got := ts.hookState(h.ID, func(h api.Webhook) bool { return h.Failures == i }); got.Status != want {
+			t.Errorf("after %d failed deliveries: %+v", i, got)
+		}
+	}
+	ts.command("/v1/command/hold", "")
+	select {
+	case <-calls:
+		t.Error("a disabled hook was called")
+	case <-time.After(100 * time.Millisecond):
+	}
+	got := ts.hookState(h.ID, func(api.Webhook) bool { return true })
+	if got.Status != api.WebhookDisabled || got.Delivered != 0 || !strings.Contains(got.LastError, "500") {
+		t.Errorf("disabled hook %+v", got)
+	}
+}
+
+func TestWebhookHungReceiver(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000},
+		api.WithWebhookTimeout(time.Minute))
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(release) })
+	rcv, calls := receiver(t, func(int) int { return http.StatusOK })
+	ts.hook(hung.URL)
+	ts.hook(rcv.URL, string(sim.EventHold))
+
+	// the engine ticks on and the other hook gets every event, while the
+	// hung one holds its first call
+	start := ts.eng.Health().Ticks
+	for i := 0; i < 20; i++ {
+		ts.command("/v1/command/hold", "")
+		if c := call(t, calls); c.body.Event.Kind != sim.EventHold {
+			t.Fatalf("call %d for %s", i, c.body.Event.Kind)
+		}
+	}
+	var st sim.AircraftState
+	ts.getJSON("/v1/state", &st)
+	if got := ts.eng.Health().Ticks - start; got != 20 {
+		t.Errorf("%d ticks handled, want 20", got)
+	}
+}
@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+	"flight-simulator2/internal/sim"
+)
+
+func TestWindComponentsOverHTTP(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000})
+	// 10 m/s blowing toward the east, across a northbound track from the
+	// left and behind an eastbound one
+	resp, b := ts.do(http.MethodPut, "/v1/environment/wind", `{"speed":10,"directionDeg":90}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("put: %d %s", resp.StatusCode, b)
+	}
+	var rep sim.WindReport
+	if err := json.Unmarshal(b, &rep); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(rep.Wx-10) > 1e-9 || math.Abs(rep.Wy) > 1e-9 || math.Abs(rep.DirectionDeg-90) > 1e-9 || math.Abs(rep.SpeedMps-10) > 1e-9 {
+		t.Errorf("report %+v", rep)
+	}
+	var got sim.WindReport
+	ts.getJSON("/v1/environment/wind", &got)
+	if got.Wx != rep.Wx || got.Wy != rep.Wy {
+		t.Errorf("GET %+v after PUT %+v", got, rep)
+	}
+
+	for _, c := range []struct {
+		name                string
+		target              string
+		headwind, crosswind float64
+	}{
+		{"north", `{"lat":47.05,"lon":8,"alt":1000,"speed":40}`, 0, -10},
+		{"east", `{"lat":47,"lon":8.07,"alt":1000,"speed":40}`, -10, 0},
+	} {
+		if resp, b := ts.do(http.MethodPost, "/v1/command/goto", c.target); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("%s: %d %s", c.name, resp.StatusCode, b)
+		}
+		ts.ticks(400)
+		var st sim.AircraftState
+		ts.getJSON("/v1/state", &st)
+		head, cross := sim.WindComponents(vector.Vec3{X: 10}, st.TrackDeg)
+		if math.Abs(st.HeadwindMps-head) > 1e-6 || math.Abs(st.CrosswindMps-cross) > 1e-6 {
+			t.Errorf("%s: headwind %.3f, crosswind %.3f on a %.1f° track, want %.3f, %.3f",
+				c.name, st.HeadwindMps, st.CrosswindMps, st.TrackDeg, head, cross)
+		}
+		if math.Abs(st.HeadwindMps-c.headwind) > 2.5 || math.Abs(st.CrosswindMps-c.crosswind) > 2.5 {
+			t.Errorf("%s: headwind %.1f, crosswind %.1f, want about %g, %g", c.name, st.HeadwindMps, st.CrosswindMps, c.headwind, c.crosswind)
+		}
+	}
+}
+
+func TestWindErrors(t *testing.T) {
+	ts := newTestServer(t, sim.Config{OriginLat: 47, OriginLon: 8, MaxWindMps: 30})
+	for _, c := range []struct {
+		name, method, body string
+		status             int
+		code               string
+	}{
+		{"both forms", http.MethodPut, `{"wx":1,"wy":2,"speed":3,"directionDeg":4}`, http.StatusBadRequest, "invalid_wind"},
+		{"half a vector", http.MethodPut, `{"wx":1}`, http.StatusBadRequest, "invalid_wind"},
+		{"nothing", http.MethodPut, `{}`, http.StatusBadRequest, "invalid_wind"},
+		{"negative speed", http.MethodPut, `{"speed":-1,"directionDeg":0}`, http.StatusBadRequest, "invalid_speed"},
+		{"too strong", http.MethodPut, `{"wx":30,"wy":30}`, http.StatusBadRequest, "wind_too_strong"},
+		{"unknown field", http.MethodPut, `{"wx":1,"wy":1,"wz":1}`, http.StatusBadRequest, "unknown_field"},
+		{"wrong method", http.MethodPost, `{"wx":1,"wy":1}`, http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		resp, b := ts.do(c.method, "/v1/environment/wind", c.body)
+		wantError(t, resp, b, c.status, c.code)
+	}
+	var rep sim.WindReport
+	ts.getJSON("/v1/environment/wind", &rep)
+	if rep.SpeedMps != 0 {
+		t.Errorf("rejected requests left a wind of %+v", rep)
+	}
+}
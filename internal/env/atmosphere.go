@@ -0,0 +1,50 @@
+package env
+
+import "math"
+
+// International Standard Atmosphere constants.
+const (
+	SeaLevelTempK     = 288.15
+	SeaLevelPressPa   = 101325.0
+	SeaLevelDensity   = 1.225 // kg/m³
+	isaLapseKPerM     = 0.0065
+	isaTropopauseM    = 11000.0
+	isaGasConstant    = 287.05287 // J/(kg·K), dry air
+	isaGravity        = 9.80665
+	isaPressExponent  = isaGravity / (isaGasConstant * isaLapseKPerM) // ≈ 5.2559
+	isaTropopauseK    = SeaLevelTempK - isaLapseKPerM*isaTropopauseM
+	isaDensityExpTrop = isaPressExponent - 1
+)
+
+// ISA returns the temperature, pressure and density at altitude altM in
+// the standard atmosphere, with the temperature shifted by tempOffsetC
+// (ISA+15 is a hot day). The pressure does not depend on the offset.
+// The model covers the troposphere and the isothermal layer above it.
+func ISA(altM, tempOffsetC float64) (tempK, pressurePa, density float64) {
+	if altM <= isaTropopauseM {
+		tempK = SeaLevelTempK - isaLapseKPerM*altM
+		pressurePa = SeaLevelPressPa * math.Pow(tempK/SeaLevelTempK, isaPressExponent)
+	} else {
+		tempK = isaTropopauseK
+		p11 := SeaLevelPressPa * math.Pow(isaTropopauseK/SeaLevelTempK, isaPressExponent)
+		pressurePa = p11 * math.Exp(-isaGravity/(isaGasConstant*isaTropopauseK)*(altM-isaTropopauseM))
+	}
+	tempK += tempOffsetC
+	return tempK, pressurePa, pressurePa / (isaGasConstant * tempK)
+}
+
+// DensityRatio is the density at altM relative to sea level (σ).
+func DensityRatio(altM, tempOffsetC float64) float64 {
+	_, _, rho := ISA(altM, tempOffsetC)
+	return rho / SeaLevelDensity
+}
+
+// DensityAltitude is the standard-atmosphere altitude with the given density.
+func DensityAltitude(density float64) float64 {
+	sigma := density / SeaLevelDensity
+	_, _, rho11 := ISA(isaTropopauseM, 0)
+	if density >= rho11 {
+		return SeaLevelTempK / isaLapseKPerM * (1 - math.Pow(sigma, 1/isaDensityExpTrop))
+	}
+	return isaTropopauseM - isaGasConstant*isaTropopauseK/isaGravity*math.Log(density/rho11)
+}
@@ -0,0 +1,322 @@
+package env
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnDEMCoverage is the code of the warning raised the first time the
+// aircraft is outside the elevation model in each 1°×1° area.
+const WarnDEMCoverage = "dem-coverage"
+
+// Unprojector converts local positions back to latitude and longitude. The
+// engine's geo reference implements it next to Projector.
+type Unprojector interface {
+	LocalToGeo(p vector.Vec3) (lat, lon, alt float64)
+}
+
+// DEM is a TerrainProvider backed by a digital elevation model: a grid of
+// elevation posts Rows by Cols, the first at (Lat0, Lon0) in the south-west
+// and spaced DLat and DLon degrees. Elevations are in metres, row by row
+// from the south, NaN where the model has no data.
+//
+// GroundAltitude interpolates bilinearly between the four posts around a
+// position. Outside the grid, or next to a post without data, it returns
+// DefaultM, and Terrain raises a WarnDEMCoverage caution once per 1°×1°
+// area. Positions are converted with the geo reference the engine binds
+// (see GeoBinder); until then every query gets DefaultM.
+//
+// DEM must be used as a pointer. Load one with LoadHGT, LoadASCIIGrid or
+// OpenDEM.
+type DEM struct {
+	Lat0, Lon0 float64
+	DLat, DLon float64
+	Rows, Cols int
+	Elev       []float32
+	DefaultM   float64
+	// Source is the file the model was read from, if any; it lets the
+	// terrain be described in a scenario.
+	Source string
+
+	geo    Unprojector
+	warned map[[2]int]bool
+}
+
+// Validate checks the grid has at least 2×2 posts, a positive spacing and
+// one elevation per post.
+func (d *DEM) Validate() error {
+	if d.Rows < 2 || d.Cols < 2 {
+		return fmt.Errorf("dem: need at least 2x2 posts, got %dx%d", d.Rows, d.Cols)
+	}
+	if !(d.DLat > 0) || !(d.DLon > 0) {
+		return fmt.Errorf("dem: post spacing must be > 0")
+	}
+	for _, v := range []float64{d.Lat0, d.Lon0, d.DLat, d.DLon, d.DefaultM} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("dem: corner, spacing and default elevation must be finite")
+		}
+	}
+	if len(d.Elev) != d.Rows*d.Cols {
+		return fmt.Errorf("dem: %d elevations for %dx%d posts", len(d.Elev), d.Rows, d.Cols)
+	}
+	return nil
+}
+
+// BindGeo keeps p for converting positions to lat/lon. It must also be an
+// Unprojector, as the engine's geo reference is.
+func (d *DEM) BindGeo(p Projector) {
+	if u, ok := p.(Unprojector); ok {
+		d.geo = u
+	}
+}
+
+// ElevationAt interpolates the elevation at (lat, lon); ok is false outside
+// the grid or next to a post without data.
+func (d *DEM) ElevationAt(lat, lon float64) (elev float64, ok bool) {
+	const eps = 1e-9 // posts on the edge, give or take rounding
+	fr := (lat - d.Lat0) / d.DLat
+	fc := (lon - d.Lon0) / d.DLon
+	if !(fr >= -eps && fr <= float64(d.Rows-1)+eps && fc >= -eps && fc <= float64(d.Cols-1)+eps) {
+		return 0, false
+	}
+	fr = min(max(fr, 0), float64(d.Rows-1))
+	fc = min(max(fc, 0), float64(d.Cols-1))
+	r := min(int(fr), d.Rows-2)
+	c := min(int(fc), d.Cols-2)
+	tr, tc := fr-float64(r), fc-float64(c)
+	post := func(r, c int) float64 { return float64(d.Elev[r*d.Cols+c]) }
+	sw, se := post(r, c), post(r, c+1)
+	nw, ne := post(r+1, c), post(r+1, c+1)
+	elev = (sw*(1-tc)+se*tc)*(1-tr) + (nw*(1-tc)+ne*tc)*tr
+	if math.IsNaN(elev) {
+		return 0, false
+	}
+	return elev, true
+}
+
+// sample returns the elevation under pos, DefaultM when it is not covered.
+func (d *DEM) sample(pos vector.Vec3) (elev, lat, lon float64, ok bool) {
+	if d.geo == nil {
+		return d.DefaultM, 0, 0, false
+	}
+	lat, lon, _ = d.geo.LocalToGeo(pos)
+	if elev, ok = d.ElevationAt(lat, lon); !ok {
+		elev = d.DefaultM
+	}
+	return elev, lat, lon, ok
+}
+
+// GroundAltitude interpolates the model under pos.
+func (d *DEM) GroundAltitude(pos vector.Vec3) float64 {
+	elev, _, _, _ := d.sample(pos)
+	return elev
+}
+
+// Covers reports whether the model has data under pos.
+func (d *DEM) Covers(pos vector.Vec3) bool {
+	_, _, _, ok := d.sample(pos)
+	return ok
+}
+
+// MaxGroundAltitude is the highest post, or DefaultM if that is higher.
+func (d *DEM) MaxGroundAltitude() float64 {
+	hi := d.DefaultM
+	for _, v := range d.Elev {
+		if float64(v) > hi {
+			hi = float64(v)
+		}
+	}
+	return hi
+}
+
+// TerrainWarnings returns a WarnDEMCoverage caution the first time pos is
+// outside the model in its 1°×1° area.
+func (d *DEM) TerrainWarnings(pos vector.Vec3) []Warning {
+	_, lat, lon, ok := d.sample(pos)
+	if ok || d.geo == nil {
+		return nil
+	}
+	area := [2]int{int(math.Floor(lat)), int(math.Floor(lon))}
+	if d.warned[area] {
+		return nil
+	}
+	if d.warned == nil {
+		d.warned = map[[2]int]bool{}
+	}
+	d.warned[area] = true
+	return []Warning{{
+		Code:     WarnDEMCoverage,
+		Severity: SeverityCaution,
+		Message:  fmt.Sprintf("no elevation data around %.3f, %.3f; assuming %.0f m", lat, lon, d.DefaultM),
+	}}
+}
+
+// MemoryBytes is the size of the elevation posts in memory.
+func (d *DEM) MemoryBytes() int {
+	return len(d.Elev) * 4
+}
+
+var hgtName = regexp.MustCompile(`(?i)^([NS])(\d{2})([EW])(\d{3})`)
+
+// LoadHGT reads an SRTM .hgt tile: big-endian int16 posts in a square grid
+// (1201 or 3601 per side) from the north-west corner, one degree wide. The
+// south-west corner comes from the tile's name, e.g. N32E034.hgt. Voids
+// (-32768) have no data.
+func LoadHGT(r io.Reader, name string) (*DEM, error) {
+	m := hgtName.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return nil, fmt.Errorf("dem: %s: tile name must look like N32E034.hgt", name)
+	}
+	lat, _ := strconv.Atoi(m[2])
+	lon, _ := strconv.Atoi(m[4])
+	if strings.EqualFold(m[1], "S") {
+		lat = -lat
+	}
+	if strings.EqualFold(m[3], "W") {
+		lon = -lon
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("dem: %s: %w", name, err)
+	}
+	n := int(math.Round(math.Sqrt(float64(len(data) / 2))))
+	if n < 2 || 2*n*n != len(data) {
+		return nil, fmt.Errorf("dem: %s: %d bytes is not a square grid of int16 posts", name, len(data))
+	}
+	d := &DEM{
+		Lat0: float64(lat), Lon0: float64(lon),
+		DLat: 1 / float64(n-1), DLon: 1 / float64(n-1),
+		Rows: n, Cols: n,
+		Elev:   make([]float32, n*n),
+		Source: name,
+	}
+	for i := 0; i < n; i++ {
+		row := n - 1 - i // the file starts in the north
+		for j := 0; j < n; j++ {
+			v := int16(binary.BigEndian.Uint16(data[2*(i*n+j):]))
+			if v == -32768 {
+				d.Elev[row*n+j] = float32(math.NaN())
+			} else {
+				d.Elev[row*n+j] = float32(v)
+			}
+		}
+	}
+	return d, nil
+}
+
+// LoadASCIIGrid reads an ESRI ASCII grid (.asc) in geographic coordinates:
+// a header of ncols, nrows, xllcorner or xllcenter, yllcorner or yllcenter,
+// cellsize and optionally NODATA_value, then nrows lines of values from the
+// north. Errors name the header key or the value's row and column.
+func LoadASCIIGrid(r io.Reader) (*DEM, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	sc.Split(bufio.ScanWords)
+
+	header := map[string]float64{}
+	var first string
+	for sc.Scan() {
+		key := strings.ToLower(sc.Text())
+		if _, err := strconv.ParseFloat(key, 64); err == nil {
+			first = key
+			break
+		}
+		if !sc.Scan() {
+			return nil, fmt.Errorf("dem: header %s has no value", key)
+		}
+		v, err := strconv.ParseFloat(sc.Text(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("dem: header %s: %w", key, err)
+		}
+		header[key] = v
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("dem: %w", err)
+	}
+	for _, k := range []string{"ncols", "nrows", "cellsize"} {
+		if _, ok := header[k]; !ok {
+			return nil, fmt.Errorf("dem: header %s is missing", k)
+		}
+	}
+	d := &DEM{
+		Rows: int(header["nrows"]), Cols: int(header["ncols"]),
+		DLat: header["cellsize"], DLon: header["cellsize"],
+	}
+	// posts sit at cell centers
+	for _, axis := range []struct {
+		name string
+		dst  *float64
+	}{{"x", &d.Lon0}, {"y", &d.Lat0}} {
+		if v, ok := header[axis.name+"llcenter"]; ok {
+			*axis.dst = v
+		} else if v, ok := header[axis.name+"llcorner"]; ok {
+			*axis.dst = v + d.DLat/2
+		} else {
+			return nil, fmt.Errorf("dem: header %[1]sllcorner or %[1]sllcenter is missing", axis.name)
+		}
+	}
+	if d.Rows < 2 || d.Cols < 2 || d.Rows*d.Cols > 1<<28 {
+		return nil, fmt.Errorf("dem: need between 2x2 and 2^28 posts, got %dx%d", d.Rows, d.Cols)
+	}
+	nodata, hasNodata := header["nodata_value"]
+
+	d.Elev = make([]float32, d.Rows*d.Cols)
+	for i := 0; i < d.Rows*d.Cols; i++ {
+		word := first
+		if i > 0 {
+			if !sc.Scan() {
+				if err := sc.Err(); err != nil {
+					return nil, fmt.Errorf("dem: %w", err)
+				}
+				return nil, fmt.Errorf("dem: %d values for %dx%d posts", i, d.Rows, d.Cols)
+			}
+			word = sc.Text()
+		}
+		v, err := strconv.ParseFloat(word, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("dem: row %d, column %d: bad value %q", i/d.Cols+1, i%d.Cols+1, word)
+		}
+		row := d.Rows - 1 - i/d.Cols // the file starts in the north
+		if hasNodata && v == nodata {
+			v = math.NaN()
+		}
+		d.Elev[row*d.Cols+i%d.Cols] = float32(v)
+	}
+	if sc.Scan() {
+		return nil, fmt.Errorf("dem: more than %dx%d values", d.Rows, d.Cols)
+	}
+	return d, d.Validate()
+}
+
+// OpenDEM loads an elevation model from an .hgt or .asc file.
+func OpenDEM(path string) (*DEM, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("dem: %w", err)
+	}
+	defer f.Close()
+	var d *DEM
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".hgt":
+		d, err = LoadHGT(bufio.NewReader(f), path)
+	case ".asc":
+		d, err = LoadASCIIGrid(f)
+	default:
+		return nil, fmt.Errorf("dem: %s: want an .hgt or .asc file", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Source = path
+	return d, nil
+}
@@ -0,0 +1,209 @@
+package env
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// tinyDEM loads testdata/tiny.asc: posts 0.01° apart from 47°N 8°E,
+//
+//	47.02  300 310 320 ---
+//	47.01  200 210 220 230
+//	47.00  100 110 120 130
+//	       8.00 ... 8.03
+func tinyDEM(t *testing.T) *DEM {
+	t.Helper()
+	d, err := OpenDEM(filepath.Join("testdata", "tiny.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDEMElevationAt(t *testing.T) {
+	d := tinyDEM(t)
+	for _, c := range []struct {
+		name     string
+		lat, lon float64
+		want     float64
+		ok       bool
+	}{
+		{"south-west post", 47.00, 8.00, 100, true},
+		{"south-east post", 47.00, 8.03, 130, true},
+		{"north-west post", 47.02, 8.00, 300, true},
+		{"east edge", 47.01, 8.03, 230, true},
+		{"west edge", 47.01, 8.00, 200, true},
+		{"between two posts", 47.00, 8.005, 105, true},
+		{"cell center", 47.005, 8.005, 155, true},
+		{"upper cell center", 47.015, 8.015, 265, true},
+		{"a quarter in", 47.0025, 8.0175, 142.5, true},
+		{"next to the void", 47.015, 8.025, 0, false},
+		{"on the void", 47.02, 8.03, 0, false},
+		{"south of the grid", 46.99, 8.01, 0, false},
+		{"east of the grid", 47.01, 8.04, 0, false},
+	} {
+		got, ok := d.ElevationAt(c.lat, c.lon)
+		if ok != c.ok || ok && math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("%s: ElevationAt(%g, %g) = %g, %v, want %g, %v", c.name, c.lat, c.lon, got, ok, c.want, c.ok)
+		}
+	}
+	if d.MemoryBytes() != 12*4 {
+		t.Errorf("MemoryBytes %d, want 48", d.MemoryBytes())
+	}
+	if d.MaxGroundAltitude() != 320 {
+		t.Errorf("MaxGroundAltitude %g, want 320", d.MaxGroundAltitude())
+	}
+}
+
+func TestDEMTerrain(t *testing.T) {
+	d := tinyDEM(t)
+	d.DefaultM = 50
+	terrain := Terrain{SafetyMarginM: 20, Provider: d}
+	at := func(lat, lon float64) vector.Vec3 { return vector.Vec3{X: lon * 1000, Y: lat * 1000, Z: 1000} }
+
+	// until the engine binds its geo reference every query is the default
+	if got := terrain.GroundAltitude(at(47.005, 8.005)); got != 50 {
+		t.Errorf("unbound GroundAltitude %g, want the default 50", got)
+	}
+	BindGeo(&Chain{Effects: []Environment{terrain}}, flatProjector{})
+
+	for _, c := range []struct {
+		name     string
+		lat, lon float64
+		ground   float64
+		covered  bool
+		warns    bool
+	}{
+		{"covered", 47.005, 8.005, 155, true, false},
+		{"outside", 46.5, 8.0, 50, false, true},
+		{"outside again, same area", 46.6, 8.2, 50, false, false},
+		{"void in a covered area", 47.02, 8.03, 50, false, true},
+		{"another area", 45.5, 8.0, 50, false, true},
+		{"back over the grid", 47.015, 8.015, 265, true, false},
+	} {
+		pos := at(c.lat, c.lon)
+		if got := terrain.GroundAltitude(pos); math.Abs(got-c.ground) > 1e-6 {
+			t.Errorf("%s: GroundAltitude %g, want %g", c.name, got, c.ground)
+		}
+		if got := terrain.Covers(pos); got != c.covered {
+			t.Errorf("%s: Covers %v, want %v", c.name, got, c.covered)
+		}
+		res, err := terrain.Apply(Context{Dt: 0.05, Pos: pos})
+		if err != nil {
+			t.Fatal(err)
+		}
+		warned := len(res.Warnings) == 1 && res.Warnings[0].Code == WarnDEMCoverage
+		if warned != c.warns || len(res.Warnings) > 1 {
+			t.Errorf("%s: warnings %+v, want a coverage caution: %v", c.name, res.Warnings, c.warns)
+		}
+	}
+}
+
+// hgt encodes posts, given from the north, as an .hgt tile.
+func hgt(posts ...int16) []byte {
+	var b bytes.Buffer
+	for _, v := range posts {
+		binary.Write(&b, binary.BigEndian, v)
+	}
+	return b.Bytes()
+}
+
+func TestLoadHGT(t *testing.T) {
+	// a 3×3 tile, half a degree between posts
+	tile := hgt(
+		700, 800, -32768,
+		400, 500, 600,
+		100, 200, 300,
+	)
+	for _, c := range []struct {
+		name     string
+		lat, lon float64
+		want     float64
+		ok       bool
+	}{
+		{"N32E034.hgt", 32, 34, 100, true},
+		{"N32E034.hgt", 32, 35, 300, true},
+		{"N32E034.hgt", 32, 34.25, 150, true},
+		{"N32E034.hgt", 32.5, 34, 400, true},
+		{"N32E034.hgt", 32.25, 34.25, 300, true},
+		{"N32E034.hgt", 33, 35, 0, false},       // the void
+		{"N32E034.hgt", 32.75, 34.75, 0, false}, // next to it
+		{"S01W002.hgt", -1, -2, 100, true},
+		{"s01w002.HGT", -0.75, -1, 450, true},
+		{"S01W002.hgt", 0, -1.75, 750, true},
+	} {
+		d, err := LoadHGT(bytes.NewReader(tile), c.name)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if err := d.Validate(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		got, ok := d.ElevationAt(c.lat, c.lon)
+		if ok != c.ok || ok && math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("%s: ElevationAt(%g, %g) = %g, %v, want %g, %v", c.name, c.lat, c.lon, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestLoadHGTRejects(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"tile.hgt", hgt(1, 2, 3, 4), "tile name must look like"},
+		{"N32E034.hgt", hgt(1, 2, 3), "not a square grid"},
+		{"N32E034.hgt", hgt(1), "not a square grid"},
+		{"N32E034.hgt", []byte{1, 2, 3, 4, 5, 6, 7}, "not a square grid"},
+	} {
+		_, err := LoadHGT(bytes.NewReader(c.data), c.name)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s with %d bytes: %v, want %q", c.name, len(c.data), err, c.err)
+		}
+	}
+}
+
+func TestLoadASCIIGridRejects(t *testing.T) {
+	const header = "ncols 2\nnrows 2\nxllcorner 0\nyllcorner 0\ncellsize 1\n"
+	for _, c := range []struct {
+		name, grid, err string
+	}{
+		{"no ncols", "nrows 2\nxllcorner 0\nyllcorner 0\ncellsize 1\n1 2 3 4", "header ncols is missing"},
+		{"no cellsize", "ncols 2\nnrows 2\nxllcorner 0\nyllcorner 0\n1 2 3 4", "header cellsize is missing"},
+		{"no corner", "ncols 2\nnrows 2\nyllcorner 0\ncellsize 1\n1 2 3 4", "xllcorner or xllcenter is missing"},
+		{"bad header value", "ncols two\n", "header ncols"},
+		{"header without value", "ncols", "header ncols has no value"},
+		{"too small", "ncols 1\nnrows 2\nxllcorner 0\nyllcorner 0\ncellsize 1\n1 2", "need between 2x2"},
+		{"too few values", header + "1 2 3", "3 values for 2x2 posts"},
+		{"too many values", header + "1 2 3 4 5", "more than 2x2 values"},
+		{"bad value", header + "1 2\n3 x", `row 2, column 2: bad value "x"`},
+		{"infinite value", header + "1 Inf 3 4", "row 1, column 2"},
+		{"zero cellsize", "ncols 2\nnrows 2\nxllcorner 0\nyllcorner 0\ncellsize 0\n1 2 3 4", "spacing must be > 0"},
+	} {
+		_, err := LoadASCIIGrid(strings.NewReader(c.grid))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: %v, want %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestOpenDEMRejects(t *testing.T) {
+	dir := t.TempDir()
+	tif := filepath.Join(dir, "x.tif")
+	if err := os.WriteFile(tif, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{tif, filepath.Join(dir, "missing.asc")} {
+		if _, err := OpenDEM(path); err == nil {
+			t.Errorf("OpenDEM(%s) succeeded", path)
+		}
+	}
+}
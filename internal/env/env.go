@@ -1,17 +1,76 @@
 package env
 
 import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
 	"flight-simulator2/internal/geometry/vector"
 )
 
+// InterfaceVersion is the version of the Environment interface. Version 1
+// was Apply(dt, pos, vel); version 2 passes a Context and returns an error.
+// Version 1 effects keep working through Legacy.
+const InterfaceVersion = 2
+
+// WarnEnvironmentError is raised by the engine when an effect fails; the
+// step then runs as if the environment were calm.
+const WarnEnvironmentError = "environment-error"
+
 // Environment is an interface for applying environmental effects to the aircraft.
-// Each implementation can modify the aircraft's velocity or position based on
-// environmental factors like wind, terrain, or other atmospheric conditions.
+// Each implementation reports how the air moves (wind, gusts, thermals) and
+// may constrain the aircraft's position or velocity (terrain, no-fly zones).
 type Environment interface {
-	// Apply takes the current position and velocity of the aircraft and returns
-	// the modified position, velocity, and an optional warning message.
-	// The dt parameter is the time step in seconds since the last update.
-	Apply(dt float64, pos vector.Vec3, vel vector.Vec3) (vector.Vec3, vector.Vec3, string)
+	// Apply takes the state of the aircraft at the start of a step and
+	// returns its Result. An error aborts the effect for this step.
+	Apply(c Context) (Result, error)
+}
+
+// Context is what an effect knows about the step it is applied to.
+type Context struct {
+	// SimTime is the simulation time at the end of the step; Dt is the
+	// step length in seconds.
+	SimTime time.Time
+	Dt      float64
+	// Pos and Vel are the local position (m east/north/up) and air
+	// velocity of the aircraft.
+	Pos vector.Vec3
+	Vel vector.Vec3
+	// AGL is the height above the ground (m), or Pos.Z without terrain.
+	AGL float64
+	// AircraftID identifies the aircraft; empty for the ownship.
+	AircraftID string
+	// Commanded is the air velocity guidance asked for this step and
+	// Command the type of the active command, empty when there is none.
+	Commanded vector.Vec3
+	Command   string
+	// PointMass is set when the engine's motion model already includes
+	// gravity and drag.
+	PointMass bool
+}
+
+// Result is what an effect does to the aircraft during one step.
+type Result struct {
+	// Pos and Vel are the position and air velocity after the effect's
+	// constraints; effects that only move the air return them unchanged.
+	Pos vector.Vec3
+	Vel vector.Vec3
+	// Wind is the velocity of the air mass (m/s east/north/up) the effect
+	// adds. The engine moves the aircraft by ground velocity = air
+	// velocity + wind.
+	Wind vector.Vec3
+	// ClimbLoss and SpeedLoss are the fractions (0..1) of the climb rate
+	// and commanded speed the aircraft cannot reach, e.g. under ice. The
+	// engine applies them from the next step on.
+	ClimbLoss float64
+	SpeedLoss float64
+	Warnings  []Warning
+}
+
+// Unchanged returns a Result that leaves pos and vel as they are.
+func Unchanged(pos, vel vector.Vec3) Result {
+	return Result{Pos: pos, Vel: vel}
 }
 
 // Chain is a composite environment that applies multiple environment effects in sequence.
@@ -21,18 +80,279 @@ type Chain struct {
 
 // Apply applies all environment effects in the chain, in order.
 // The position and velocity are passed through each effect in sequence,
-// with the output of one effect becoming the input to the next.
-// The last non-empty warning message is returned.
-func (c *Chain) Apply(dt float64, pos vector.Vec3, vel vector.Vec3) (vector.Vec3, vector.Vec3, string) {
-	var warning string
+// with the output of one effect becoming the input to the next. The winds
+// of all effects add up, and their warnings are returned in chain order,
+// each identical warning once. The first error stops the chain.
+func (c *Chain) Apply(ctx Context) (Result, error) {
+	res := Unchanged(ctx.Pos, ctx.Vel)
 	for _, effect := range c.Effects {
-		newPos, newVel, w := effect.Apply(dt, pos, vel)
-		if w != "" {
-			warning = w
+		r, err := effect.Apply(ctx)
+		if err != nil {
+			return res, fmt.Errorf("%s: %w", effectName(effect), err)
+		}
+		res.Pos, res.Vel = r.Pos, r.Vel
+		res.Wind = res.Wind.Add(r.Wind)
+		res.ClimbLoss = CombineLoss(res.ClimbLoss, r.ClimbLoss)
+		res.SpeedLoss = CombineLoss(res.SpeedLoss, r.SpeedLoss)
+		res.Warnings = append(res.Warnings, r.Warnings...)
+		ctx.AGL += r.Pos.Z - ctx.Pos.Z
+		ctx.Pos, ctx.Vel = r.Pos, r.Vel
+	}
+	res.Warnings = Dedup(res.Warnings)
+	return res, nil
+}
+
+// CombineLoss stacks two performance losses: what is left is the product
+// of what each leaves.
+func CombineLoss(a, b float64) float64 {
+	return 1 - (1-a)*(1-b)
+}
+
+// LegacyEnvironment is the version 1 Environment interface.
+type LegacyEnvironment interface {
+	Apply(dt float64, pos vector.Vec3, vel vector.Vec3) Result
+}
+
+// Legacy adapts a version 1 effect to the current interface.
+type Legacy struct {
+	Effect LegacyEnvironment
+}
+
+// Apply calls the wrapped effect with the step length, position and velocity.
+func (l Legacy) Apply(c Context) (Result, error) {
+	return l.Effect.Apply(c.Dt, c.Pos, c.Vel), nil
+}
+
+// Ground is implemented by effects that know the terrain height, such as Terrain.
+type Ground interface {
+	GroundAltitude(pos vector.Vec3) float64
+}
+
+// GroundAltitude returns the highest ground under pos reported by e,
+// looking inside chains. ok is false when no effect knows the terrain.
+func GroundAltitude(e Environment, pos vector.Vec3) (alt float64, ok bool) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if a, found := GroundAltitude(effect, pos); found && (!ok || a > alt) {
+				alt, ok = a, true
+			}
+		}
+		return alt, ok
+	case Ground:
+		return f.GroundAltitude(pos), true
+	}
+	return 0, false
+}
+
+// Coverage is implemented by effects and terrain providers whose data ends
+// somewhere, such as DEM: Covers reports whether pos is within it.
+type Coverage interface {
+	Covers(pos vector.Vec3) bool
+}
+
+// GroundCovered reports whether every effect of e with a Coverage, looking
+// inside chains, has data at pos. Outside it GroundAltitude is a fallback.
+func GroundCovered(e Environment, pos vector.Vec3) bool {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if !GroundCovered(effect, pos) {
+				return false
+			}
+		}
+	case Coverage:
+		return f.Covers(pos)
+	}
+	return true
+}
+
+// Slope is implemented by effects that know the ground's slope, such as
+// Terrain.
+type Slope interface {
+	SlopeDeg(pos vector.Vec3) float64
+}
+
+// SlopeAt returns the slope of the first effect of e with a Slope, looking
+// inside chains. ok is false when no effect knows it.
+func SlopeAt(e Environment, pos vector.Vec3) (deg float64, ok bool) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if d, found := SlopeAt(effect, pos); found {
+				return d, true
+			}
+		}
+	case Slope:
+		return f.SlopeDeg(pos), true
+	}
+	return 0, false
+}
+
+// Floor is implemented by effects that enforce a minimum altitude, such as Terrain.
+type Floor interface {
+	MinAltitude(pos vector.Vec3) float64
+}
+
+// MinAltitude returns the highest floor enforced by e at pos, looking inside
+// chains. ok is false when no effect enforces one.
+func MinAltitude(e Environment, pos vector.Vec3) (alt float64, ok bool) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if a, found := MinAltitude(effect, pos); found && (!ok || a > alt) {
+				alt, ok = a, true
+			}
+		}
+		return alt, ok
+	case Floor:
+		return f.MinAltitude(pos), true
+	}
+	return 0, false
+}
+
+// BoundedFloor is a Floor that knows the highest value it takes anywhere.
+type BoundedFloor interface {
+	Floor
+	MaxMinAltitude() float64
+}
+
+// MaxMinAltitude returns the highest floor enforced by e anywhere, looking
+// inside chains. ok is false when no effect reports a bounded floor; a
+// floor of +Inf counts as unbounded.
+func MaxMinAltitude(e Environment) (alt float64, ok bool) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if a, found := MaxMinAltitude(effect); found && (!ok || a > alt) {
+				alt, ok = a, true
+			}
+		}
+		return alt, ok
+	case BoundedFloor:
+		if a := f.MaxMinAltitude(); !math.IsInf(a, 1) {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// WindSource is implemented by effects that move the air. WindAt returns
+// the horizontal wind the effect adds at pos and a short human-readable
+// detail for diagnostics.
+type WindSource interface {
+	WindAt(pos vector.Vec3) (vector.Vec3, string)
+}
+
+// WindComponent is one effect's share of the wind at a position.
+type WindComponent struct {
+	Effect string  `json:"effect"`
+	Wx     float64 `json:"wx"`
+	Wy     float64 `json:"wy"`
+	Wz     float64 `json:"wz,omitempty"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// WindAt sums the wind of every WindSource in e at pos, looking inside
+// chains, and lists each contribution in chain order.
+func WindAt(e Environment, pos vector.Vec3) (vector.Vec3, []WindComponent) {
+	switch f := e.(type) {
+	case *Chain:
+		var (
+			sum   vector.Vec3
+			parts []WindComponent
+		)
+		for _, effect := range f.Effects {
+			w, ps := WindAt(effect, pos)
+			sum = sum.Add(w)
+			parts = append(parts, ps...)
 		}
-		pos, vel = newPos, newVel
+		return sum, parts
+	case WindSource:
+		w, detail := f.WindAt(pos)
+		return w, []WindComponent{{Effect: effectName(f), Wx: w.X, Wy: w.Y, Wz: w.Z, Detail: detail}}
 	}
-	return pos, vel, warning
+	return vector.Vec3{}, nil
+}
+
+// Membership is a named region, such as a no-fly zone or storm cell, that
+// contains a position.
+type Membership struct {
+	Effect string `json:"effect"`
+	Name   string `json:"name"`
+	// DepthM is how far inside the region the position is.
+	DepthM float64 `json:"depthM"`
+}
+
+// Regional is implemented by effects made of named regions. MembershipsAt
+// lists the regions containing pos, sorted by name.
+type Regional interface {
+	MembershipsAt(pos vector.Vec3) []Membership
+}
+
+// MembershipsAt lists the regions of every Regional effect in e that
+// contain pos, looking inside chains, in chain order.
+func MembershipsAt(e Environment, pos vector.Vec3) []Membership {
+	switch f := e.(type) {
+	case *Chain:
+		var out []Membership
+		for _, effect := range f.Effects {
+			out = append(out, MembershipsAt(effect, pos)...)
+		}
+		return out
+	case Regional:
+		ms := f.MembershipsAt(pos)
+		for i := range ms {
+			ms[i].Effect = effectName(f)
+		}
+		return ms
+	}
+	return nil
+}
+
+// Validator is implemented by effects that can check their own
+// configuration.
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks every Validator in e, looking inside chains, and returns
+// the first failure prefixed with the effect's name.
+func Validate(e Environment) error {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if err := Validate(effect); err != nil {
+				return err
+			}
+		}
+	case Validator:
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", effectName(f), err)
+		}
+	}
+	return nil
+}
+
+// Names lists the effects of e by type name, looking inside chains, in
+// chain order.
+func Names(e Environment) []string {
+	switch f := e.(type) {
+	case nil:
+		return nil
+	case *Chain:
+		var out []string
+		for _, effect := range f.Effects {
+			out = append(out, Names(effect)...)
+		}
+		return out
+	}
+	return []string{effectName(e)}
+}
+
+// effectName is the type name of an effect without package or pointer.
+func effectName(e any) string {
+	return strings.TrimPrefix(strings.TrimPrefix(fmt.Sprintf("%T", e), "*"), "env.")
 }
 
 // NoOp is an environment that does nothing.
@@ -40,6 +360,6 @@ var NoOp Environment = noOpEnv{}
 
 type noOpEnv struct{}
 
-func (noOpEnv) Apply(dt float64, pos, vel vector.Vec3) (vector.Vec3, vector.Vec3, string) {
-	return pos, vel, ""
+func (noOpEnv) Apply(c Context) (Result, error) {
+	return Unchanged(c.Pos, c.Vel), nil
 }
@@ -0,0 +1,100 @@
+package env
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// stubEffect returns a fixed result, moving the aircraft up by Climb.
+type stubEffect struct {
+	Climb    float64
+	Wind     vector.Vec3
+	Loss     float64
+	Warnings []Warning
+	Err      error
+}
+
+func (s stubEffect) Apply(c Context) (Result, error) {
+	if s.Err != nil {
+		return Result{}, s.Err
+	}
+	pos := c.Pos
+	pos.Z += s.Climb
+	return Result{Pos: pos, Vel: c.Vel, Wind: s.Wind, ClimbLoss: s.Loss, Warnings: s.Warnings}, nil
+}
+
+func TestChainCollectsWarnings(t *testing.T) {
+	low := Warning{Code: "low", Severity: SeverityCaution, Message: "low"}
+	gust := Warning{Code: "gust", Severity: SeverityInfo}
+	ice := Warning{Code: "ice", Severity: SeverityWarning, Message: "icing"}
+	for _, c := range []struct {
+		name    string
+		effects []Environment
+		want    []Warning
+	}{
+		{"none", []Environment{stubEffect{}, stubEffect{}}, nil},
+		{"one effect", []Environment{stubEffect{Warnings: []Warning{low}}}, []Warning{low}},
+		{"every effect in order", []Environment{
+			stubEffect{Warnings: []Warning{ice}},
+			stubEffect{},
+			stubEffect{Warnings: []Warning{low, gust}},
+		}, []Warning{ice, low, gust}},
+		{"identical warnings once", []Environment{
+			stubEffect{Warnings: []Warning{low}},
+			stubEffect{Warnings: []Warning{low, gust}},
+		}, []Warning{low, gust}},
+		{"same code, different message", []Environment{
+			stubEffect{Warnings: []Warning{low}},
+			stubEffect{Warnings: []Warning{{Code: "low", Severity: SeverityCaution, Message: "lower"}}},
+		}, []Warning{low, {Code: "low", Severity: SeverityCaution, Message: "lower"}}},
+		{"nested chain", []Environment{
+			&Chain{Effects: []Environment{stubEffect{Warnings: []Warning{gust}}}},
+			stubEffect{Warnings: []Warning{ice}},
+		}, []Warning{gust, ice}},
+	} {
+		res, err := (&Chain{Effects: c.effects}).Apply(Context{Dt: 0.05})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(res.Warnings, c.want) {
+			t.Errorf("%s: warnings %v, want %v", c.name, res.Warnings, c.want)
+		}
+	}
+}
+
+func TestChainCombinesEffects(t *testing.T) {
+	chain := &Chain{Effects: []Environment{
+		stubEffect{Climb: 5, Wind: vector.Vec3{X: 3}, Loss: 0.5},
+		stubEffect{Climb: 2, Wind: vector.Vec3{Y: -1, Z: 2}, Loss: 0.2},
+	}}
+	res, err := chain.Apply(Context{Pos: vector.Vec3{Z: 100}, AGL: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Pos.Z != 107 || res.Wind != (vector.Vec3{X: 3, Y: -1, Z: 2}) {
+		t.Errorf("pos %v, wind %v", res.Pos, res.Wind)
+	}
+	if want := 1 - 0.5*0.8; res.ClimbLoss != want {
+		t.Errorf("climb loss %g, want %g", res.ClimbLoss, want)
+	}
+
+	boom := errors.New("boom")
+	chain.Effects = append(chain.Effects, stubEffect{Err: boom}, stubEffect{Warnings: []Warning{{Code: "late"}}})
+	if _, err := chain.Apply(Context{}); !errors.Is(err, boom) || !strings.HasPrefix(err.Error(), "stubEffect: ") {
+		t.Errorf("Apply = %v, want the effect's error prefixed with its name", err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	ws := []Warning{{Code: "a", Message: "first"}, {Code: "b"}}
+	if got := Summary(ws); got != "a: first; b" {
+		t.Errorf("Summary = %q", got)
+	}
+	if Summary(nil) != "" {
+		t.Error("Summary of no warnings is not empty")
+	}
+}
@@ -0,0 +1,54 @@
+package env
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Gravity makes an uncommanded aircraft glide instead of hovering: with no
+// active command and no vertical speed asked for, it sinks at SinkRateMps
+// and its horizontal airspeed decays with time constant AirspeedDecayS
+// (0 leaves the engine's own deceleration in charge). Commands are never
+// fought, and it stands down under the point-mass model, which has its own
+// gravity and drag.
+//
+// Gravity keeps the glide speed between calls and must be used as a pointer.
+type Gravity struct {
+	SinkRateMps    float64 `json:"sinkRateMps"`
+	AirspeedDecayS float64 `json:"airspeedDecayS,omitempty"`
+
+	gliding bool
+	glide   vector.Vec3 // horizontal air velocity of the glide
+}
+
+// Validate checks the values are finite and not negative.
+func (g *Gravity) Validate() error {
+	for _, v := range []float64{g.SinkRateMps, g.AirspeedDecayS} {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return fmt.Errorf("gravity: sink rate and airspeed decay must be finite and >= 0")
+		}
+	}
+	return nil
+}
+
+// Apply sets the glide's sink rate and decayed airspeed while the aircraft
+// is uncommanded.
+func (g *Gravity) Apply(c Context) (Result, error) {
+	res := Unchanged(c.Pos, c.Vel)
+	if c.PointMass || c.Command != "" || c.Commanded.Z != 0 || c.AGL <= 0 {
+		g.gliding = false
+		return res, nil
+	}
+	if !g.gliding {
+		g.gliding = true
+		g.glide = vector.Vec3{X: c.Vel.X, Y: c.Vel.Y}
+	}
+	if g.AirspeedDecayS > 0 {
+		g.glide = g.glide.Mul(math.Exp(-c.Dt / g.AirspeedDecayS))
+		res.Vel.X, res.Vel.Y = g.glide.X, g.glide.Y
+	}
+	res.Vel.Z = min(res.Vel.Z, -g.SinkRateMps)
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package env
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultGroundEffectMinFactor is used when GroundEffect.MinFactor is zero.
+const DefaultGroundEffectMinFactor = 0.3
+
+// GroundEffect makes the aircraft float near the surface: below HeightM
+// above ground (Context.AGL) the descent rate is scaled by a factor that
+// falls linearly from 1 at HeightM to MinFactor (default
+// DefaultGroundEffectMinFactor) on the ground, so a steady descent flares
+// out. Climbs are left alone.
+//
+// The engine pulls the velocity back toward the commanded descent every
+// step, so GroundEffect remembers how much it slowed the last one and scales
+// the descent as it would have been without it; the attenuation does not
+// compound with the tick rate. It only ever slows a descent, so it composes
+// with Terrain, which clips what is left at the floor.
+//
+// GroundEffect must be used as a pointer. Note that Terrain keeps the
+// aircraft SafetyMarginM above ground, so HeightM has to exceed the margin
+// to have an effect.
+type GroundEffect struct {
+	HeightM   float64 `json:"heightM"`
+	MinFactor float64 `json:"minFactor,omitempty"`
+
+	slowed float64 // m/s taken off the last step's descent
+}
+
+// Validate checks the height is positive and the factor between 0 and 1.
+func (g *GroundEffect) Validate() error {
+	if math.IsNaN(g.HeightM) || math.IsInf(g.HeightM, 0) || g.HeightM <= 0 {
+		return fmt.Errorf("ground effect: heightM must be finite and > 0")
+	}
+	if !(g.MinFactor >= 0 && g.MinFactor <= 1) {
+		return fmt.Errorf("ground effect: minFactor must be between 0 and 1")
+	}
+	return nil
+}
+
+// Factor is the share of the descent rate kept at agl metres above ground.
+func (g *GroundEffect) Factor(agl float64) float64 {
+	if agl >= g.HeightM {
+		return 1
+	}
+	minFactor := g.MinFactor
+	if minFactor == 0 {
+		minFactor = DefaultGroundEffectMinFactor
+	}
+	return minFactor + (1-minFactor)*max(agl, 0)/g.HeightM
+}
+
+// Apply scales a descent within HeightM of the ground.
+func (g *GroundEffect) Apply(c Context) (Result, error) {
+	res := Unchanged(c.Pos, c.Vel)
+	f := g.Factor(c.AGL)
+	if f >= 1 || c.Vel.Z >= 0 {
+		g.slowed = 0
+		return res, nil
+	}
+	// the descent without the last step's attenuation, but never faster
+	// than what guidance asks for or the incoming velocity
+	free := max(c.Vel.Z-g.slowed, min(c.Commanded.Z, c.Vel.Z))
+	res.Vel.Z = free * f
+	g.slowed = res.Vel.Z - free
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package env
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestGroundEffectFactor(t *testing.T) {
+	g := &GroundEffect{HeightM: 20}
+	half := &GroundEffect{HeightM: 20, MinFactor: 0.5}
+	for _, c := range []struct {
+		name string
+		g    *GroundEffect
+		agl  float64
+		want float64
+	}{
+		{"above", g, 50, 1},
+		{"at the height", g, 20, 1},
+		{"halfway", g, 10, 0.65},
+		{"on the ground", g, 0, DefaultGroundEffectMinFactor},
+		{"below ground", g, -3, DefaultGroundEffectMinFactor},
+		{"min factor", half, 0, 0.5},
+		{"min factor halfway", half, 10, 0.75},
+	} {
+		if got := c.g.Factor(c.agl); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("%s: Factor(%g) = %g, want %g", c.name, c.agl, got, c.want)
+		}
+	}
+}
+
+func TestGroundEffectLeavesClimbsAlone(t *testing.T) {
+	g := &GroundEffect{HeightM: 20}
+	vel := vector.Vec3{X: 30, Z: 2}
+	res, err := g.Apply(Context{Dt: 0.05, Pos: vector.Vec3{Z: 5}, AGL: 5, Vel: vel, Commanded: vel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Vel != vel {
+		t.Errorf("climb became %+v", res.Vel)
+	}
+}
+
+// TestGroundEffectFlare descends onto flat ground through a Chain of
+// GroundEffect and Terrain the way the engine does, pulling the velocity
+// back toward the commanded sink rate every step.
+func TestGroundEffectFlare(t *testing.T) {
+	const (
+		sink   = -4.0
+		margin = 2.0
+		pull   = 5.0 // m/s² back toward the commanded velocity
+	)
+	for _, tickHz := range []float64{10, 20, 100} {
+		dt := 1 / tickHz
+		ge := &GroundEffect{HeightM: 20}
+		chain := &Chain{Effects: []Environment{ge, Terrain{SafetyMarginM: margin, Provider: FlatTerrain(0)}}}
+		pos, vel := vector.Vec3{Z: 40}, vector.Vec3{Z: sink}
+		commanded := vector.Vec3{Z: sink}
+		prevRate, floored := math.Inf(1), false
+		for step := 0; step < int(60*tickHz); step++ {
+			vel.Z += max(min(commanded.Z-vel.Z, pull*dt), -pull*dt)
+			res, err := chain.Apply(Context{Dt: dt, Pos: pos, Vel: vel, AGL: pos.Z, Commanded: commanded})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range res.Warnings {
+				if w.Code == WarnTerrainContact {
+					t.Fatalf("%g Hz: touched down at step %d", tickHz, step)
+				}
+			}
+			vel = res.Vel
+			agl := pos.Z
+			pos = res.Pos.Add(vel.Mul(dt))
+			if floored = floored || agl <= margin; floored {
+				continue
+			}
+			rate := -vel.Z
+			// the sink rate follows the factor, without compounding
+			if want := -sink * ge.Factor(agl); math.Abs(rate-want) > 1e-9 {
+				t.Fatalf("%g Hz: sink %.3f m/s at %.2f m, want %.3f", tickHz, rate, agl, want)
+			}
+			// and only ever eases off on the way down
+			if rate > prevRate+1e-9 {
+				t.Fatalf("%g Hz: sink rose from %.3f to %.3f m/s at %.2f m", tickHz, prevRate, rate, agl)
+			}
+			prevRate = rate
+		}
+		// Terrain holds it at the margin without bouncing
+		if pos.Z < margin-0.5 || pos.Z > margin+0.5 {
+			t.Errorf("%g Hz: settled at %.2f m, want about %g", tickHz, pos.Z, margin)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// GustFront is a wind shift sweeping across the area: a straight front
+// through (StartX, StartY), in metres east and north of the sim origin,
+// moving toward DirectionDeg (0=north, 90=east) at SpeedMps. Ahead of it the
+// wind is WindA, behind it WindB. Across a transition band WidthM wide,
+// centered on the front, the two are blended smoothly and a gust of up to
+// TurbulenceMps (seeded with Seed) is added, strongest mid-band.
+//
+// Time counts from the first step the front is applied to. GustFront keeps
+// that and the gust between calls and must be used as a pointer.
+type GustFront struct {
+	StartX        float64 `json:"startX"`
+	StartY        float64 `json:"startY"`
+	DirectionDeg  float64 `json:"directionDeg"`
+	SpeedMps      float64 `json:"speedMps"`
+	WidthM        float64 `json:"widthM"`
+	WindA         Wind    `json:"windA"`
+	WindB         Wind    `json:"windB"`
+	TurbulenceMps float64 `json:"turbulenceMps,omitempty"`
+	Seed          int64   `json:"seed,omitempty"`
+
+	start   time.Time
+	elapsed float64     // seconds since start, as of the last step
+	gust    *Turbulence // unit intensity, scaled by the band
+}
+
+// Validate checks the values are finite and the speed, width and turbulence
+// not negative.
+func (g *GustFront) Validate() error {
+	for _, v := range []float64{g.StartX, g.StartY, g.DirectionDeg, g.SpeedMps, g.WidthM, g.TurbulenceMps} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("gust front: values must be finite")
+		}
+	}
+	if g.SpeedMps < 0 || g.WidthM < 0 || g.TurbulenceMps < 0 {
+		return fmt.Errorf("gust front: speedMps, widthM and turbulenceMps must be >= 0")
+	}
+	if err := g.WindA.Validate(); err != nil {
+		return fmt.Errorf("gust front: windA: %w", err)
+	}
+	if err := g.WindB.Validate(); err != nil {
+		return fmt.Errorf("gust front: windB: %w", err)
+	}
+	return nil
+}
+
+// Ahead returns how far pos is ahead of the front, along its direction of
+// travel, at the time of the last step; negative behind it.
+func (g *GustFront) Ahead(pos vector.Vec3) float64 {
+	sin, cos := math.Sincos(g.DirectionDeg * math.Pi / 180)
+	travelled := g.SpeedMps * g.elapsed
+	return (pos.X-g.StartX)*sin + (pos.Y-g.StartY)*cos - travelled
+}
+
+// through returns how far through the band the front has passed pos: 0
+// ahead of it, 1 behind it.
+func (g *GustFront) through(pos vector.Vec3) float64 {
+	d := g.Ahead(pos)
+	if g.WidthM == 0 {
+		if d < 0 {
+			return 1
+		}
+		return 0
+	}
+	return min(max(0.5-d/g.WidthM, 0), 1)
+}
+
+// Apply advances the front and the band's gust and reports the wind at
+// the aircraft.
+func (g *GustFront) Apply(c Context) (Result, error) {
+	res := Unchanged(c.Pos, c.Vel)
+	if g.start.IsZero() {
+		g.start = c.SimTime.Add(-time.Duration(c.Dt * float64(time.Second)))
+	}
+	g.elapsed = c.SimTime.Sub(g.start).Seconds()
+	if g.TurbulenceMps > 0 {
+		if g.gust == nil {
+			g.gust = &Turbulence{IntensityMps: 1, Seed: g.Seed}
+		}
+		if _, err := g.gust.Apply(c); err != nil {
+			return res, err
+		}
+	}
+	res.Wind, _ = g.WindAt(c.Pos)
+	return res, nil
+}
+
+// WindAt reports the blended wind at pos, with the band's gust.
+func (g *GustFront) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	p := g.through(pos)
+	s := p * p * (3 - 2*p) // smoothstep: the share of WindB
+	w := vector.Vec3{
+		X: g.WindA.Wx + (g.WindB.Wx-g.WindA.Wx)*s,
+		Y: g.WindA.Wy + (g.WindB.Wy-g.WindA.Wy)*s,
+	}
+	detail := "ahead of the front"
+	switch {
+	case p >= 1:
+		detail = "behind the front"
+	case p > 0:
+		detail = fmt.Sprintf("in the front, %.0f%% through", p*100)
+		if g.gust != nil {
+			// 4p(1-p) peaks at 1 mid-band
+			w = w.Add(g.gust.Gust().Mul(g.TurbulenceMps * 4 * p * (1 - p)))
+		}
+	}
+	return w, detail
+}
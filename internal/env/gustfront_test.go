@@ -0,0 +1,138 @@
+package env
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// stepFront applies g for seconds in steps of dt at pos and returns the
+// last result.
+func stepFront(t *testing.T, g *GustFront, pos vector.Vec3, seconds, dt float64) Result {
+	t.Helper()
+	var res Result
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for range int(math.Round(seconds / dt)) {
+		now = now.Add(time.Duration(dt * float64(time.Second)))
+		var err error
+		if res, err = g.Apply(Context{SimTime: now, Dt: dt, Pos: pos}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res
+}
+
+func TestGustFrontPassage(t *testing.T) {
+	// a front moving east at 10 m/s from x=0, northerly 5 m/s ahead and a
+	// westerly 15 m/s behind, 200 m wide
+	front := GustFront{DirectionDeg: 90, SpeedMps: 10, WidthM: 200, WindA: Wind{Wy: -5}, WindB: Wind{Wx: 15}}
+	for _, c := range []struct {
+		name    string
+		x       float64
+		seconds float64
+		ahead   float64
+		wind    vector.Vec3
+		detail  string
+	}{
+		{"well ahead", 1000, 10, 900, vector.Vec3{Y: -5}, "ahead of the front"},
+		{"band edge ahead", 200, 10, 100, vector.Vec3{Y: -5}, "ahead of the front"},
+		{"quarter through", 150, 10, 50, vector.Vec3{X: 15 * 0.15625, Y: -5 * 0.84375}, "in the front, 25% through"},
+		{"on the front", 100, 10, 0, vector.Vec3{X: 7.5, Y: -2.5}, "in the front, 50% through"},
+		{"band edge behind", 0, 10, -100, vector.Vec3{X: 15}, "behind the front"},
+		{"passed", 100, 60, -500, vector.Vec3{X: 15}, "behind the front"},
+		// north-south makes no difference to a front moving east
+		{"north of the start", 100, 10, 0, vector.Vec3{X: 7.5, Y: -2.5}, "in the front, 50% through"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			g := front
+			pos := vector.Vec3{X: c.x, Z: 500}
+			if c.name == "north of the start" {
+				pos.Y = 3000
+			}
+			res := stepFront(t, &g, pos, c.seconds, 0.05)
+			if got := g.Ahead(pos); math.Abs(got-c.ahead) > 1e-6 {
+				t.Errorf("Ahead = %.3f, want %g", got, c.ahead)
+			}
+			if res.Wind.Sub(c.wind).Length() > 1e-9 {
+				t.Errorf("wind %v, want %v", res.Wind, c.wind)
+			}
+			if _, detail := g.WindAt(pos); detail != c.detail {
+				t.Errorf("detail %q, want %q", detail, c.detail)
+			}
+			if res.Pos != pos {
+				t.Errorf("the front moved the aircraft to %v", res.Pos)
+			}
+		})
+	}
+}
+
+func TestGustFrontDirection(t *testing.T) {
+	// a sharp front moving north-east at 20 m/s passes a point 1 km along
+	// its track after 50 s
+	d := 1000 / math.Sqrt2
+	pos := vector.Vec3{X: -500 + d, Y: 100 + d}
+	for _, c := range []struct {
+		seconds float64
+		want    vector.Vec3
+	}{
+		{49, vector.Vec3{X: 2}},
+		{51, vector.Vec3{Y: 8}},
+	} {
+		g := &GustFront{StartX: -500, StartY: 100, DirectionDeg: 45, SpeedMps: 20, WindA: Wind{Wx: 2}, WindB: Wind{Wy: 8}}
+		if res := stepFront(t, g, pos, c.seconds, 0.5); res.Wind != c.want {
+			t.Errorf("after %g s wind %v, want %v", c.seconds, res.Wind, c.want)
+		}
+	}
+}
+
+func TestGustFrontTurbulence(t *testing.T) {
+	mean := vector.Vec3{X: 7.5, Y: -2.5}
+	for _, c := range []struct {
+		name  string
+		x     float64
+		gusty bool
+	}{
+		{"ahead", 300, false},
+		{"in the band", 100, true},
+		{"behind", -100, false},
+	} {
+		g := &GustFront{DirectionDeg: 90, SpeedMps: 10, WidthM: 200, WindA: Wind{Wy: -5}, WindB: Wind{Wx: 15}, TurbulenceMps: 4, Seed: 3}
+		pos := vector.Vec3{X: c.x}
+		res := stepFront(t, g, pos, 10, 0.05)
+		calm := &GustFront{DirectionDeg: 90, SpeedMps: 10, WidthM: 200, WindA: Wind{Wy: -5}, WindB: Wind{Wx: 15}}
+		want := stepFront(t, calm, pos, 10, 0.05).Wind
+		if gusty := res.Wind != want; gusty != c.gusty {
+			t.Errorf("%s: wind %v against %v without turbulence", c.name, res.Wind, want)
+		}
+		if c.gusty {
+			// mid-band the gust is the unit gust at full turbulence
+			if got := res.Wind.Sub(mean); got.Sub(g.gust.Gust().Mul(4)).Length() > 1e-9 {
+				t.Errorf("%s: gust %v, want %v", c.name, got, g.gust.Gust().Mul(4))
+			}
+		}
+	}
+}
+
+func TestGustFrontValidate(t *testing.T) {
+	for _, c := range []struct {
+		g   GustFront
+		err string
+	}{
+		{GustFront{SpeedMps: 10, WidthM: 100}, ""},
+		{GustFront{StartX: math.Inf(1)}, "must be finite"},
+		{GustFront{DirectionDeg: math.NaN()}, "must be finite"},
+		{GustFront{SpeedMps: -1}, ">= 0"},
+		{GustFront{WidthM: -1}, ">= 0"},
+		{GustFront{TurbulenceMps: -1}, ">= 0"},
+		{GustFront{WindA: Wind{Wx: math.NaN()}}, "windA"},
+		{GustFront{WindB: Wind{Wy: math.Inf(-1)}}, "windB"},
+	} {
+		err := c.g.Validate()
+		if c.err == "" && err != nil || c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.g, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package env
+
+import (
+	"fmt"
+	"math"
+)
+
+// Icing warning codes, by ice load.
+const (
+	WarnIcingTrace  = "icing-trace"  // some ice, load below IcingSevereLoad
+	WarnIcingSevere = "icing-severe" // load at or above IcingSevereLoad
+)
+
+// IcingSevereLoad is the ice load (0..1) from which icing counts as severe.
+const IcingSevereLoad = 0.5
+
+// Icing builds up ice while the aircraft is inside the band from BaseAltM to
+// TopAltM (and, when either is set, from MinTempC to MaxTempC of the ISA
+// temperature). The ice load grows by AccretionRate per second up to 1 and
+// sheds at ShedRate per second (default a quarter of AccretionRate) outside
+// the band.
+//
+// A full load costs MaxDegradation of the climb rate and SpeedDegradation of
+// the commanded speed; the loss is proportional to the load and reported in
+// Result.ClimbLoss and Result.SpeedLoss.
+//
+// Icing keeps the ice load between calls and must be used as a pointer.
+type Icing struct {
+	BaseAltM         float64 `json:"baseAltM"`
+	TopAltM          float64 `json:"topAltM"`
+	MinTempC         float64 `json:"minTempC,omitempty"`
+	MaxTempC         float64 `json:"maxTempC,omitempty"`
+	AccretionRate    float64 `json:"accretionRate"`
+	ShedRate         float64 `json:"shedRate,omitempty"`
+	MaxDegradation   float64 `json:"maxDegradation"`
+	SpeedDegradation float64 `json:"speedDegradation,omitempty"`
+
+	load float64
+}
+
+// Validate checks the band is ordered and the rates and fractions are sane.
+func (i *Icing) Validate() error {
+	for _, v := range []float64{i.BaseAltM, i.TopAltM, i.MinTempC, i.MaxTempC, i.AccretionRate, i.ShedRate, i.MaxDegradation, i.SpeedDegradation} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("icing: values must be finite")
+		}
+	}
+	if i.TopAltM <= i.BaseAltM {
+		return fmt.Errorf("icing: topAltM must be above baseAltM")
+	}
+	if i.MaxTempC < i.MinTempC {
+		return fmt.Errorf("icing: maxTempC must be >= minTempC")
+	}
+	if i.AccretionRate <= 0 || i.ShedRate < 0 {
+		return fmt.Errorf("icing: accretion rate must be > 0 and shed rate >= 0")
+	}
+	if i.MaxDegradation < 0 || i.MaxDegradation > 1 || i.SpeedDegradation < 0 || i.SpeedDegradation > 1 {
+		return fmt.Errorf("icing: degradations must be between 0 and 1")
+	}
+	return nil
+}
+
+// Load returns the current ice load, 0 (clean) to 1.
+func (i *Icing) Load() float64 { return i.load }
+
+// inBand reports whether altitude z is in the icing band.
+func (i *Icing) inBand(z float64) bool {
+	if z < i.BaseAltM || z > i.TopAltM {
+		return false
+	}
+	if i.MinTempC == 0 && i.MaxTempC == 0 {
+		return true
+	}
+	tempK, _, _ := ISA(z, 0)
+	t := tempK - 273.15
+	return t >= i.MinTempC && t <= i.MaxTempC
+}
+
+// Apply accretes or sheds ice over the step and reports the resulting loss
+// of performance.
+func (i *Icing) Apply(c Context) (Result, error) {
+	res := Unchanged(c.Pos, c.Vel)
+	if i.inBand(c.Pos.Z) {
+		i.load = math.Min(i.load+i.AccretionRate*c.Dt, 1)
+	} else {
+		shed := i.ShedRate
+		if shed == 0 {
+			shed = i.AccretionRate / 4
+		}
+		i.load = math.Max(i.load-shed*c.Dt, 0)
+	}
+	if i.load == 0 {
+		return res, nil
+	}
+
+	res.ClimbLoss = i.load * i.MaxDegradation
+	res.SpeedLoss = i.load * i.SpeedDegradation
+	w := Warning{
+		Code:     WarnIcingTrace,
+		Severity: SeverityCaution,
+		Message:  fmt.Sprintf("ice load %.0f%%, climb rate -%.0f%%", 100*i.load, 100*res.ClimbLoss),
+	}
+	if i.load >= IcingSevereLoad {
+		w.Code, w.Severity = WarnIcingSevere, SeverityWarning
+	}
+	res.Warnings = []Warning{w}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package env
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestIcingLoad(t *testing.T) {
+	type leg struct{ alt, seconds float64 }
+	band := Icing{BaseAltM: 2000, TopAltM: 4000, AccretionRate: 0.02, MaxDegradation: 0.6, SpeedDegradation: 0.2}
+	cold := band
+	cold.MinTempC, cold.MaxTempC = -10, 0 // ISA: 2300 m to 3800 m
+	fastShed := band
+	fastShed.ShedRate = 0.1
+	for _, c := range []struct {
+		name string
+		ice  Icing
+		legs []leg
+		load float64
+		code string
+	}{
+		{"below the band", band, []leg{{1000, 60}}, 0, ""},
+		{"trace", band, []leg{{3000, 10}}, 0.2, WarnIcingTrace},
+		{"severe", band, []leg{{3000, 30}}, 0.6, WarnIcingSevere},
+		{"full", band, []leg{{3000, 120}}, 1, WarnIcingSevere},
+		{"sheds at a quarter rate", band, []leg{{3000, 30}, {5000, 40}}, 0.4, WarnIcingTrace},
+		{"sheds completely", band, []leg{{3000, 30}, {1000, 200}}, 0, ""},
+		{"shed rate", fastShed, []leg{{3000, 30}, {1000, 3}}, 0.3, WarnIcingTrace},
+		{"too warm for the band", cold, []leg{{2100, 60}}, 0, ""},
+		{"cold enough", cold, []leg{{3000, 10}}, 0.2, WarnIcingTrace},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ice := c.ice
+			if err := ice.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var res Result
+			for _, l := range c.legs {
+				for s := 0.0; s < l.seconds-1e-9; s += 0.1 {
+					var err error
+					if res, err = ice.Apply(Context{Dt: 0.1, Pos: vector.Vec3{Z: l.alt}}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if math.Abs(ice.Load()-c.load) > 1e-6 {
+				t.Errorf("load %.4f, want %g", ice.Load(), c.load)
+			}
+			if math.Abs(res.ClimbLoss-c.load*0.6) > 1e-6 || math.Abs(res.SpeedLoss-c.load*0.2) > 1e-6 {
+				t.Errorf("losses climb %.3f speed %.3f at load %g", res.ClimbLoss, res.SpeedLoss, c.load)
+			}
+			switch {
+			case c.code == "" && len(res.Warnings) != 0:
+				t.Errorf("warnings %v on a clean aircraft", res.Warnings)
+			case c.code != "" && (len(res.Warnings) != 1 || res.Warnings[0].Code != c.code):
+				t.Errorf("warnings %v, want %s", res.Warnings, c.code)
+			}
+		})
+	}
+}
+
+func TestIcingValidate(t *testing.T) {
+	for _, c := range []struct {
+		ice Icing
+		err string
+	}{
+		{Icing{BaseAltM: 4000, TopAltM: 3000, AccretionRate: 0.1}, "topAltM must be above baseAltM"},
+		{Icing{TopAltM: 3000, MinTempC: 0, MaxTempC: -10, AccretionRate: 0.1}, "maxTempC must be >= minTempC"},
+		{Icing{TopAltM: 3000}, "accretion rate must be > 0"},
+		{Icing{TopAltM: 3000, AccretionRate: 0.1, MaxDegradation: 1.5}, "between 0 and 1"},
+		{Icing{TopAltM: math.Inf(1), AccretionRate: 0.1}, "must be finite"},
+	} {
+		if err := c.ice.Validate(); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.ice, err, c.err)
+		}
+	}
+}
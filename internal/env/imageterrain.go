@@ -0,0 +1,134 @@
+package env
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // register the PNG decoder for NewImageTerrain
+	"math"
+	"os"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// ImageTerrain is a TerrainProvider read from a grayscale heightmap: black
+// is MinElevM, white MaxElevM, linearly in between. Pixels are
+// MetersPerPixel apart, rows running south, with the image's center at
+// Offset (metres east and north of the sim origin). Between pixel centers
+// the height is interpolated bilinearly; outside the image the edge value
+// applies.
+type ImageTerrain struct {
+	// Source is the file the image was read from, if any.
+	Source         string
+	Offset         vector.Vec3
+	MetersPerPixel float64
+	MinElevM       float64
+	MaxElevM       float64
+
+	w, h    int
+	heights []float32 // row by row from the top
+}
+
+// NewImageTerrain reads a PNG heightmap from path (see ImageTerrain).
+func NewImageTerrain(path string, originOffset vector.Vec3, metersPerPixel, minElev, maxElev float64) (*ImageTerrain, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("heightmap: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("heightmap: %s: %w", path, err)
+	}
+	t, err := NewImageTerrainFromImage(img, originOffset, metersPerPixel, minElev, maxElev)
+	if err != nil {
+		return nil, err
+	}
+	t.Source = path
+	return t, nil
+}
+
+// NewImageTerrainFromImage builds an ImageTerrain from a decoded image;
+// colour images are read by their luminance.
+func NewImageTerrainFromImage(img image.Image, originOffset vector.Vec3, metersPerPixel, minElev, maxElev float64) (*ImageTerrain, error) {
+	for _, v := range []float64{originOffset.X, originOffset.Y, metersPerPixel, minElev, maxElev} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("heightmap: offset, scale and elevations must be finite")
+		}
+	}
+	if metersPerPixel <= 0 {
+		return nil, fmt.Errorf("heightmap: metres per pixel must be > 0")
+	}
+	if maxElev < minElev {
+		return nil, fmt.Errorf("heightmap: maximum elevation must be >= minimum")
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("heightmap: image is empty")
+	}
+	t := &ImageTerrain{
+		Offset:         vector.Vec3{X: originOffset.X, Y: originOffset.Y},
+		MetersPerPixel: metersPerPixel,
+		MinElevM:       minElev,
+		MaxElevM:       maxElev,
+		w:              b.Dx(),
+		h:              b.Dy(),
+		heights:        make([]float32, b.Dx()*b.Dy()),
+	}
+	for y := 0; y < t.h; y++ {
+		for x := 0; x < t.w; x++ {
+			g := color.Gray16Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray16)
+			t.heights[y*t.w+x] = float32(minElev + (maxElev-minElev)*float64(g.Y)/0xffff)
+		}
+	}
+	return t, nil
+}
+
+// Size returns the image's width and height in pixels.
+func (t *ImageTerrain) Size() (w, h int) {
+	return t.w, t.h
+}
+
+// PixelHeight returns the elevation of pixel (x, y), counted from the top
+// left; out-of-range pixels take the nearest edge pixel's.
+func (t *ImageTerrain) PixelHeight(x, y int) float64 {
+	x = min(max(x, 0), t.w-1)
+	y = min(max(y, 0), t.h-1)
+	return float64(t.heights[y*t.w+x])
+}
+
+// pixel returns the pixel coordinates of pos, with pixel centers at whole
+// numbers.
+func (t *ImageTerrain) pixel(pos vector.Vec3) (px, py float64) {
+	px = (pos.X-t.Offset.X)/t.MetersPerPixel + float64(t.w-1)/2
+	py = (t.Offset.Y-pos.Y)/t.MetersPerPixel + float64(t.h-1)/2
+	return px, py
+}
+
+// Covers reports whether pos is within the image, out to the edge pixels'
+// outer sides.
+func (t *ImageTerrain) Covers(pos vector.Vec3) bool {
+	px, py := t.pixel(pos)
+	return px >= -0.5 && px <= float64(t.w)-0.5 && py >= -0.5 && py <= float64(t.h)-0.5
+}
+
+// GroundAltitude interpolates the heightmap under pos.
+func (t *ImageTerrain) GroundAltitude(pos vector.Vec3) float64 {
+	px, py := t.pixel(pos)
+	px = min(max(px, 0), float64(t.w-1))
+	py = min(max(py, 0), float64(t.h-1))
+	x0, y0 := int(px), int(py)
+	tx, ty := px-float64(x0), py-float64(y0)
+	top := t.PixelHeight(x0, y0)*(1-tx) + t.PixelHeight(x0+1, y0)*tx
+	bottom := t.PixelHeight(x0, y0+1)*(1-tx) + t.PixelHeight(x0+1, y0+1)*tx
+	return top*(1-ty) + bottom*ty
+}
+
+// MaxGroundAltitude is the highest pixel's elevation.
+func (t *ImageTerrain) MaxGroundAltitude() float64 {
+	hi := math.Inf(-1)
+	for _, v := range t.heights {
+		hi = max(hi, float64(v))
+	}
+	return hi
+}
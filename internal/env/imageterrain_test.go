@@ -0,0 +1,157 @@
+package env
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// heightmap is a 16×16 image on a 0–6553.5 m scale, so a gray level of
+// 10·h encodes h metres: 100 m everywhere but for a few known pixels.
+func heightmap() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, 16, 16))
+	for y := range 16 {
+		for x := range 16 {
+			img.SetGray16(x, y, color.Gray16{Y: 1000})
+		}
+	}
+	for _, p := range []struct{ x, y, elev int }{
+		{3, 4, 1234},
+		{4, 4, 2234},
+		{3, 5, 334},
+		{4, 5, 4334},
+		{0, 0, 0},
+		{15, 15, 6553},
+		{15, 0, 500},
+	} {
+		img.SetGray16(p.x, p.y, color.Gray16{Y: uint16(p.elev * 10)})
+	}
+	return img
+}
+
+func TestImageTerrainRoundTrip(t *testing.T) {
+	// pixels 10 m apart, the image centered 1 km east and 2 km north
+	offset := vector.Vec3{X: 1000, Y: 2000}
+	center := func(x, y float64) vector.Vec3 {
+		return vector.Vec3{X: offset.X + (x-7.5)*10, Y: offset.Y - (y-7.5)*10}
+	}
+	path := filepath.Join(t.TempDir(), "heightmap.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, heightmap()); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	fromFile, err := NewImageTerrain(path, offset, 10, 0, 6553.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromImage, err := NewImageTerrainFromImage(heightmap(), offset, 10, 0, 6553.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name    string
+		pos     vector.Vec3
+		want    float64
+		covered bool
+	}{
+		{"pixel", center(3, 4), 1234, true},
+		{"next pixel east", center(4, 4), 2234, true},
+		{"pixel to the south", center(3, 5), 334, true},
+		{"between east and west", center(3.5, 4), 1734, true},
+		{"between north and south", center(3, 4.5), 784, true},
+		{"between all four", center(3.5, 4.5), 2034, true},
+		{"a quarter in", center(3.25, 4.75), 0.75*(0.75*334+0.25*4334) + 0.25*(0.75*1234+0.25*2234), true},
+		{"north-west corner", center(0, 0), 0, true},
+		{"south-east corner", center(15, 15), 6553, true},
+		{"the outer side of the edge", center(-0.5, 0), 0, true},
+		{"beyond the north-east corner", center(20, -3), 500, false},
+		{"beyond the south-east corner", center(15, 40), 6553, false},
+		{"plain ground", center(10, 10), 100, true},
+	} {
+		for _, terrain := range []*ImageTerrain{fromFile, fromImage} {
+			if got := terrain.GroundAltitude(c.pos); math.Abs(got-c.want) > 1e-3 {
+				t.Errorf("%s: GroundAltitude = %.4f, want %.4f", c.name, got, c.want)
+			}
+			if got := terrain.Covers(c.pos); got != c.covered {
+				t.Errorf("%s: Covers = %v, want %v", c.name, got, c.covered)
+			}
+		}
+	}
+	if w, h := fromFile.Size(); w != 16 || h != 16 {
+		t.Errorf("Size %dx%d, want 16x16", w, h)
+	}
+	if got := fromFile.MaxGroundAltitude(); math.Abs(got-6553) > 1e-3 {
+		t.Errorf("MaxGroundAltitude %g, want 6553", got)
+	}
+	if fromFile.Source != path || fromImage.Source != "" {
+		t.Errorf("Source %q and %q", fromFile.Source, fromImage.Source)
+	}
+}
+
+func TestImageTerrainColour(t *testing.T) {
+	// colour is read by its luminance, 8-bit gray scaled to the full range
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	img.Set(1, 0, color.RGBA{0, 0, 0, 255})
+	terrain, err := NewImageTerrainFromImage(img, vector.Vec3{}, 1, 200, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := terrain.PixelHeight(0, 0); got != 300 {
+		t.Errorf("white is %g m, want 300", got)
+	}
+	if got := terrain.PixelHeight(1, 0); got != 200 {
+		t.Errorf("black is %g m, want 200", got)
+	}
+	gray := image.NewGray(image.Rect(0, 0, 1, 1))
+	gray.SetGray(0, 0, color.Gray{Y: 51}) // a fifth
+	if terrain, err = NewImageTerrainFromImage(gray, vector.Vec3{}, 1, 0, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if got := terrain.PixelHeight(0, 0); math.Abs(got-200) > 1e-3 {
+		t.Errorf("a fifth of white is %g m, want 200", got)
+	}
+}
+
+func TestImageTerrainRejects(t *testing.T) {
+	img := heightmap()
+	for _, c := range []struct {
+		name       string
+		img        image.Image
+		scale      float64
+		minE, maxE float64
+		err        string
+	}{
+		{"zero scale", img, 0, 0, 100, "metres per pixel must be > 0"},
+		{"negative scale", img, -1, 0, 100, "metres per pixel must be > 0"},
+		{"NaN scale", img, math.NaN(), 0, 100, "must be finite"},
+		{"infinite elevation", img, 1, 0, math.Inf(1), "must be finite"},
+		{"inverted range", img, 1, 100, 0, "maximum elevation must be >= minimum"},
+		{"empty", image.NewGray(image.Rect(0, 0, 0, 0)), 1, 0, 100, "image is empty"},
+	} {
+		_, err := NewImageTerrainFromImage(c.img, vector.Vec3{}, c.scale, c.minE, c.maxE)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: %v, want %q", c.name, err, c.err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "not.png")
+	if err := os.WriteFile(path, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, filepath.Join(t.TempDir(), "missing.png")} {
+		if _, err := NewImageTerrain(p, vector.Vec3{}, 1, 0, 100); err == nil || !strings.HasPrefix(err.Error(), "heightmap: ") {
+			t.Errorf("NewImageTerrain(%s) = %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Unit conversions for METAR wind speeds.
+const (
+	knotMps = 1852.0 / 3600
+	kmhMps  = 1000.0 / 3600
+)
+
+// METARWind is the decoded wind group of a METAR.
+type METARWind struct {
+	// Wind is the mean wind as a drift: a METAR direction is where the wind
+	// blows FROM, so 27015KT (from the west) gives a positive Wx (drift to
+	// the east) and 36010KT a negative Wy. Variable and calm winds are zero.
+	Wind Wind
+
+	FromDeg  float64 // direction the wind blows from; 0 when variable or calm
+	SpeedMps float64
+	GustMps  float64 // 0 without a G group
+	Variable bool    // VRB direction
+}
+
+var metarWindRe = regexp.MustCompile(`^(\d{3}|VRB)(\d{2,3})(?:G(\d{2,3}))?(KT|MPS|KMH)$`)
+
+// ParseMETAR finds and decodes the wind group ("27015G25KT", "VRB03KT",
+// "00000KT") of a METAR report. Speeds are converted to m/s.
+func ParseMETAR(s string) (METARWind, error) {
+	var group []string
+	for _, tok := range strings.Fields(strings.ToUpper(s)) {
+		if m := metarWindRe.FindStringSubmatch(tok); m != nil {
+			group = m
+			break
+		}
+	}
+	if group == nil {
+		return METARWind{}, fmt.Errorf("metar %q: no wind group like 27015KT, VRB03KT or 27015G25KT", s)
+	}
+
+	unit := knotMps
+	switch group[4] {
+	case "MPS":
+		unit = 1
+	case "KMH":
+		unit = kmhMps
+	}
+	speed, _ := strconv.Atoi(group[2])
+	out := METARWind{SpeedMps: float64(speed) * unit}
+	if group[3] != "" {
+		gust, _ := strconv.Atoi(group[3])
+		if gust < speed {
+			return METARWind{}, fmt.Errorf("metar wind %s: gust below the mean speed", group[0])
+		}
+		out.GustMps = float64(gust) * unit
+	}
+
+	if group[1] == "VRB" {
+		out.Variable = true
+		return out, nil
+	}
+	from, _ := strconv.Atoi(group[1])
+	if from > 360 {
+		return METARWind{}, fmt.Errorf("metar wind %s: direction %d° out of range", group[0], from)
+	}
+	if speed == 0 {
+		return out, nil // calm
+	}
+	out.FromDeg = float64(from)
+	// FromSpeedAndDir takes the direction the wind blows toward
+	out.Wind = FromSpeedAndDir(out.SpeedMps, float64((from+180)%360))
+	return out, nil
+}
+
+// FromMETAR returns the mean wind of a METAR's wind group. See ParseMETAR
+// for the gust and METARWind for the sign convention.
+func FromMETAR(s string) (Wind, error) {
+	w, err := ParseMETAR(s)
+	if err != nil {
+		return Wind{}, err
+	}
+	return w.Wind, nil
+}
@@ -0,0 +1,85 @@
+package env
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMETARFixtures(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "metar.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reports := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" && !strings.HasPrefix(line, "#") {
+			reports[strings.Fields(line)[0]] = line
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	const kt = 1852.0 / 3600
+	for _, c := range []struct {
+		station              string
+		fromDeg, speed, gust float64
+		variable             bool
+		wx, wy               float64
+	}{
+		{"LSZH", 240, 12 * kt, 0, false, 12 * kt * math.Sin(60*math.Pi/180), 12 * kt * math.Cos(60*math.Pi/180)},
+		{"EGLL", 270, 15 * kt, 25 * kt, false, 15 * kt, 0},
+		{"KJFK", 0, 3 * kt, 0, true, 0, 0},
+		{"UUEE", 360, 5, 0, false, 0, -5},
+		{"LSGG", 0, 0, 0, false, 0, 0},
+		{"ULLI", 90, 10, 0, false, -10, 0},
+	} {
+		report, ok := reports[c.station]
+		if !ok {
+			t.Errorf("%s: not in testdata/metar.txt", c.station)
+			continue
+		}
+		got, err := ParseMETAR(report)
+		if err != nil {
+			t.Errorf("%s: %v", c.station, err)
+			continue
+		}
+		if got.FromDeg != c.fromDeg || math.Abs(got.SpeedMps-c.speed) > 1e-9 || math.Abs(got.GustMps-c.gust) > 1e-9 || got.Variable != c.variable {
+			t.Errorf("%s: %+v, want from %g° at %.3f m/s gusting %.3f, variable %v", c.station, got, c.fromDeg, c.speed, c.gust, c.variable)
+		}
+		if math.Abs(got.Wind.Wx-c.wx) > 1e-9 || math.Abs(got.Wind.Wy-c.wy) > 1e-9 {
+			t.Errorf("%s: drift (%.3f, %.3f), want (%.3f, %.3f)", c.station, got.Wind.Wx, got.Wind.Wy, c.wx, c.wy)
+		}
+		w, err := FromMETAR(report)
+		if err != nil || w != got.Wind {
+			t.Errorf("%s: FromMETAR = %+v, %v, want %+v", c.station, w, err, got.Wind)
+		}
+	}
+}
+
+func TestParseMETARRejects(t *testing.T) {
+	for _, c := range []struct {
+		name, report, want string
+	}{
+		{"empty", "", "no wind group"},
+		{"no wind group", "LSZH 171150Z 9999 FEW040 15/08 Q1018", "no wind group"},
+		{"one-digit speed", "LSZH 2701KT", "no wind group"},
+		{"four-digit speed", "LSZH 2701234KT", "no wind group"},
+		{"unknown unit", "LSZH 27015MPH", "no wind group"},
+		{"trailing letters", "LSZH 27015KTS", "no wind group"},
+		{"gust without speed", "LSZH 270G25KT", "no wind group"},
+		{"direction out of range", "LSZH 40010KT", "out of range"},
+		{"gust below mean", "LSZH 27025G15KT", "gust below"},
+	} {
+		_, err := ParseMETAR(c.report)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: error %v, want one containing %q", c.name, err, c.want)
+		}
+	}
+}
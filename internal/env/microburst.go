@@ -0,0 +1,200 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnMicroburst is raised while the aircraft is inside an active microburst.
+const WarnMicroburst = "microburst"
+
+// ErrMicroburstExists is returned by Microbursts.Add for an ID already active.
+var ErrMicroburstExists = errors.New("microburst already active")
+
+// Microburst defaults, used for zero fields.
+const (
+	DefaultMicroburstRadiusM   = 800.0
+	DefaultMicroburstPeakDown  = 12.0
+	DefaultMicroburstDurationS = 45.0
+)
+
+// Microburst is a short-lived downburst centered at Lat/Lon. The downdraft
+// peaks at PeakDownMps over the center and fades past RadiusM; around it
+// the air spreads out, strongest (also PeakDownMps) at RadiusM. The burst
+// ends by itself after DurationS.
+type Microburst struct {
+	ID          string  `json:"id"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	RadiusM     float64 `json:"radiusM"`
+	PeakDownMps float64 `json:"peakDownMps"`
+	DurationS   float64 `json:"durationS"`
+	// RemainingS is reported by Microbursts.Active; it is ignored on input.
+	RemainingS float64 `json:"remainingS,omitempty"`
+}
+
+// Validate checks the values are finite, in range and not negative.
+func (m Microburst) Validate() error {
+	for _, v := range []float64{m.Lat, m.Lon, m.RadiusM, m.PeakDownMps, m.DurationS} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("microburst: values must be finite")
+		}
+	}
+	if m.Lat < -90 || m.Lat > 90 || m.Lon < -180 || m.Lon > 180 {
+		return fmt.Errorf("microburst: lat/lon out of range")
+	}
+	if m.RadiusM < 0 || m.PeakDownMps < 0 || m.DurationS < 0 {
+		return fmt.Errorf("microburst: radiusM, peakDownMps and durationS must be >= 0")
+	}
+	return nil
+}
+
+// withDefaults fills zero fields with the Default* values.
+func (m Microburst) withDefaults() Microburst {
+	if m.RadiusM == 0 {
+		m.RadiusM = DefaultMicroburstRadiusM
+	}
+	if m.PeakDownMps == 0 {
+		m.PeakDownMps = DefaultMicroburstPeakDown
+	}
+	if m.DurationS == 0 {
+		m.DurationS = DefaultMicroburstDurationS
+	}
+	return m
+}
+
+// windAt is the air velocity the burst causes at pos.
+func (m Microburst) windAt(center, pos vector.Vec3) vector.Vec3 {
+	dx, dy := pos.X-center.X, pos.Y-center.Y
+	d := math.Hypot(dx, dy)
+	r := d / m.RadiusM
+	w := vector.Vec3{Z: -m.PeakDownMps * math.Exp(-2*r*r)}
+	if d > 1e-9 {
+		// r·exp((1-r²)/2) is 1 at r = 1 and falls off on both sides
+		out := m.PeakDownMps * r * math.Exp((1-r*r)/2)
+		w.X, w.Y = out*dx/d, out*dy/d
+	}
+	return w
+}
+
+type microburst struct {
+	cfg    Microburst
+	center vector.Vec3
+	left   float64 // seconds until it ends
+}
+
+// Microbursts holds the active microbursts, counts down their duration and
+// drops them once it has passed. Callers convert Microburst.Lat/Lon to the
+// local frame when adding.
+//
+// Microbursts keeps state between calls and must be used as a pointer.
+type Microbursts struct {
+	bursts []*microburst
+	nextID int
+}
+
+// Add starts m with its center at the local position center and returns
+// the ID it was given (m.ID, or "mb<n>" when empty).
+func (b *Microbursts) Add(m Microburst, center vector.Vec3) (string, error) {
+	if err := m.Validate(); err != nil {
+		return "", err
+	}
+	m = m.withDefaults()
+	m.RemainingS = 0
+	if m.ID == "" {
+		b.nextID++
+		m.ID = fmt.Sprintf("mb%d", b.nextID)
+	}
+	for _, mb := range b.bursts {
+		if mb.cfg.ID == m.ID {
+			return "", fmt.Errorf("%w: %q", ErrMicroburstExists, m.ID)
+		}
+	}
+	b.bursts = append(b.bursts, &microburst{cfg: m, center: vector.Vec3{X: center.X, Y: center.Y}, left: m.DurationS})
+	return m.ID, nil
+}
+
+// Rebase moves the microburst centers to a new local frame.
+func (b *Microbursts) Rebase(move func(vector.Vec3) vector.Vec3) {
+	for _, mb := range b.bursts {
+		c := move(mb.center)
+		mb.center = vector.Vec3{X: c.X, Y: c.Y}
+	}
+}
+
+// Clear ends all microbursts.
+func (b *Microbursts) Clear() { b.bursts = nil }
+
+// Len returns the number of active microbursts.
+func (b *Microbursts) Len() int { return len(b.bursts) }
+
+// Active returns the active microbursts with RemainingS set and their local
+// centers, in start order.
+func (b *Microbursts) Active() ([]Microburst, []vector.Vec3) {
+	cfgs := make([]Microburst, 0, len(b.bursts))
+	centers := make([]vector.Vec3, 0, len(b.bursts))
+	for _, mb := range b.bursts {
+		m := mb.cfg
+		m.RemainingS = mb.left
+		cfgs = append(cfgs, m)
+		centers = append(centers, mb.center)
+	}
+	return cfgs, centers
+}
+
+// WindAt reports the summed wind of the active microbursts at pos.
+func (b *Microbursts) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	var sum vector.Vec3
+	for _, mb := range b.bursts {
+		sum = sum.Add(mb.cfg.windAt(mb.center, pos))
+	}
+	return sum, fmt.Sprintf("%d active", len(b.bursts))
+}
+
+// MembershipsAt lists the microbursts within their radius of pos.
+func (b *Microbursts) MembershipsAt(pos vector.Vec3) []Membership {
+	var out []Membership
+	for _, mb := range b.bursts {
+		if d := mb.cfg.RadiusM - math.Hypot(pos.X-mb.center.X, pos.Y-mb.center.Y); d >= 0 {
+			out = append(out, Membership{Name: mb.cfg.ID, DepthM: d})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Apply reports the wind of every active microburst, warns about those the
+// aircraft is in and removes the ones whose duration has passed.
+func (b *Microbursts) Apply(c Context) (Result, error) {
+	res := Unchanged(c.Pos, c.Vel)
+	if len(b.bursts) == 0 {
+		return res, nil
+	}
+	var inside []string
+	active := b.bursts[:0]
+	for _, mb := range b.bursts {
+		res.Wind = res.Wind.Add(mb.cfg.windAt(mb.center, c.Pos))
+		if math.Hypot(c.Pos.X-mb.center.X, c.Pos.Y-mb.center.Y) <= mb.cfg.RadiusM {
+			inside = append(inside, mb.cfg.ID)
+		}
+		if mb.left -= c.Dt; mb.left > 0 {
+			active = append(active, mb)
+		}
+	}
+	b.bursts = active
+
+	if len(inside) > 0 {
+		sort.Strings(inside)
+		res.Warnings = []Warning{{
+			Code:     WarnMicroburst,
+			Severity: SeverityWarning,
+			Message:  "inside microburst " + strings.Join(inside, ", "),
+		}}
+	}
+	return res, nil
+}
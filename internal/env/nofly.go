@@ -0,0 +1,364 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnNoFlyZone is the code of the warning raised inside a no-fly zone.
+const WarnNoFlyZone = "no-fly-zone"
+
+// NoFlyMode is how NoFlyZones reacts to a penetration.
+type NoFlyMode string
+
+const (
+	NoFlyAdvisory NoFlyMode = "advisory" // only warn
+	NoFlyHard     NoFlyMode = "hard"     // push the aircraft back out
+)
+
+// noFlyExitM is how far beyond the boundary the hard mode places the aircraft.
+const noFlyExitM = 0.5
+
+// NoFlyZone is a keep-out volume: a polygon (Polygon, [lon, lat] pairs as
+// in GeoJSON) or a cylinder of RadiusM around (Lat, Lon), between FloorM
+// and CeilingM. A zero CeilingM means unlimited.
+type NoFlyZone struct {
+	Name     string       `json:"name"`
+	Polygon  [][2]float64 `json:"polygon,omitempty"`
+	Lat      float64      `json:"lat,omitempty"`
+	Lon      float64      `json:"lon,omitempty"`
+	RadiusM  float64      `json:"radiusM,omitempty"`
+	FloorM   float64      `json:"floorM,omitempty"`
+	CeilingM float64      `json:"ceilingM,omitempty"`
+}
+
+// Validate checks the zone has a name and exactly one sane shape.
+func (z NoFlyZone) Validate() error {
+	if z.Name == "" {
+		return fmt.Errorf("no-fly zone needs a name")
+	}
+	cylinder := z.RadiusM != 0
+	switch {
+	case cylinder && len(z.Polygon) > 0:
+		return fmt.Errorf("zone %s: either a polygon or a radius, not both", z.Name)
+	case !cylinder && len(z.Polygon) < 3:
+		return fmt.Errorf("zone %s: needs a polygon with at least 3 points or a radiusM", z.Name)
+	case cylinder && !(z.RadiusM > 0):
+		return fmt.Errorf("zone %s: radiusM must be > 0", z.Name)
+	}
+	pts := z.Polygon
+	if cylinder {
+		pts = [][2]float64{{z.Lon, z.Lat}}
+	}
+	for i, p := range pts {
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+			return fmt.Errorf("zone %s: point %d out of range", z.Name, i)
+		}
+	}
+	if math.IsNaN(z.FloorM) || math.IsNaN(z.CeilingM) || (z.CeilingM != 0 && z.CeilingM <= z.FloorM) {
+		return fmt.Errorf("zone %s: ceilingM must be above floorM", z.Name)
+	}
+	return nil
+}
+
+// Projector converts geographic coordinates into the local ENU frame.
+// sim.GeoRef implements it.
+type Projector interface {
+	GeoToLocal(lat, lon, alt float64) vector.Vec3
+}
+
+// GeoBinder is implemented by effects configured in lat/lon. The engine
+// calls BindGeo with its geo reference before the first step.
+type GeoBinder interface {
+	BindGeo(p Projector)
+}
+
+// BindGeo hands p to every GeoBinder in e, looking inside chains.
+func BindGeo(e Environment, p Projector) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			BindGeo(effect, p)
+		}
+	case GeoBinder:
+		f.BindGeo(p)
+	}
+}
+
+// Rebaser is implemented by effects holding positions in the local frame
+// that were placed from lat/lon, such as Weather. Rebase moves each of them
+// with move when the frame's origin changes.
+type Rebaser interface {
+	Rebase(move func(vector.Vec3) vector.Vec3)
+}
+
+// Rebase calls Rebase on every Rebaser in e, looking inside chains.
+func Rebase(e Environment, move func(vector.Vec3) vector.Vec3) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			Rebase(effect, move)
+		}
+	case Rebaser:
+		f.Rebase(move)
+	}
+}
+
+type noFlyVolume struct {
+	cfg    NoFlyZone
+	poly   []vector.Vec3 // Z unused
+	center vector.Vec3
+}
+
+// penetration returns how deep pos is inside the volume (<= 0 outside) and
+// the point just outside the boundary along the shortest way out.
+func (v noFlyVolume) penetration(pos vector.Vec3) (float64, vector.Vec3) {
+	if pos.Z < v.cfg.FloorM || (v.cfg.CeilingM != 0 && pos.Z > v.cfg.CeilingM) {
+		return 0, pos
+	}
+	p := vector.Vec3{X: pos.X, Y: pos.Y}
+
+	var depth float64
+	var exit vector.Vec3 // horizontal exit point
+	if v.poly == nil {
+		d := p.Sub(v.center)
+		r := math.Hypot(d.X, d.Y)
+		depth = v.cfg.RadiusM - r
+		if depth <= 0 {
+			return 0, pos
+		}
+		dir := vector.Vec3{X: 1}
+		if r > 1e-9 {
+			dir = d.Mul(1 / r)
+		}
+		exit = v.center.Add(dir.Mul(v.cfg.RadiusM + noFlyExitM))
+	} else {
+		if !pointInPolygon(p, v.poly) {
+			return 0, pos
+		}
+		depth = math.Inf(1)
+		for i, j := 0, len(v.poly)-1; i < len(v.poly); j, i = i, i+1 {
+			q := closestOnSegment(p, v.poly[j], v.poly[i])
+			if d := math.Hypot(p.X-q.X, p.Y-q.Y); d < depth {
+				depth = d
+				out := q.Sub(p)
+				if d > 1e-9 {
+					out = out.Mul(1 / d)
+				}
+				exit = q.Add(out.Mul(noFlyExitM))
+			}
+		}
+	}
+
+	best := vector.Vec3{X: exit.X, Y: exit.Y, Z: pos.Z}
+	// leaving through the floor or ceiling may be shorter
+	if d := pos.Z - v.cfg.FloorM; d < depth && v.cfg.FloorM > 0 {
+		depth, best = d, vector.Vec3{X: pos.X, Y: pos.Y, Z: v.cfg.FloorM - noFlyExitM}
+	}
+	if v.cfg.CeilingM != 0 {
+		if d := v.cfg.CeilingM - pos.Z; d < depth {
+			depth, best = d, vector.Vec3{X: pos.X, Y: pos.Y, Z: v.cfg.CeilingM + noFlyExitM}
+		}
+	}
+	return math.Max(depth, 1e-9), best
+}
+
+// pointInPolygon casts a ray toward +X; it handles concave polygons.
+func pointInPolygon(p vector.Vec3, poly []vector.Vec3) bool {
+	inside := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[j], poly[i]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func closestOnSegment(p, a, b vector.Vec3) vector.Vec3 {
+	ab := b.Sub(a)
+	l2 := ab.X*ab.X + ab.Y*ab.Y
+	t := 0.0
+	if l2 > 0 {
+		t = math.Max(0, math.Min(1, ((p.X-a.X)*ab.X+(p.Y-a.Y)*ab.Y)/l2))
+	}
+	return a.Add(ab.Mul(t))
+}
+
+// NoFlyZones keeps the aircraft out of keep-out volumes. In NoFlyAdvisory
+// mode it only warns with the zone name and penetration depth; in
+// NoFlyHard mode it also moves the aircraft back out along the shortest
+// exit, as Terrain clips the altitude, and cancels the velocity carrying it
+// inward. The zones take effect once the engine has bound its geo
+// reference (see GeoBinder).
+//
+// NoFlyZones must be used as a pointer.
+type NoFlyZones struct {
+	Zones []NoFlyZone `json:"zones"`
+	Mode  NoFlyMode   `json:"mode"`
+
+	volumes []noFlyVolume
+}
+
+// Validate checks the mode and every zone.
+func (n *NoFlyZones) Validate() error {
+	switch n.Mode {
+	case "", NoFlyAdvisory, NoFlyHard:
+	default:
+		return fmt.Errorf("unknown no-fly mode %q (want %q or %q)", n.Mode, NoFlyAdvisory, NoFlyHard)
+	}
+	for _, z := range n.Zones {
+		if err := z.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BindGeo converts the zones into the local frame.
+func (n *NoFlyZones) BindGeo(p Projector) {
+	n.volumes = n.volumes[:0]
+	for _, z := range n.Zones {
+		v := noFlyVolume{cfg: z}
+		if z.RadiusM > 0 {
+			v.center = p.GeoToLocal(z.Lat, z.Lon, 0)
+		} else {
+			for _, pt := range z.Polygon {
+				v.poly = append(v.poly, p.GeoToLocal(pt[1], pt[0], 0))
+			}
+		}
+		n.volumes = append(n.volumes, v)
+	}
+}
+
+// Inside lists the zones containing pos with their penetration depths.
+func (n *NoFlyZones) Inside(pos vector.Vec3) map[string]float64 {
+	out := map[string]float64{}
+	for _, v := range n.volumes {
+		if d, _ := v.penetration(pos); d > 0 {
+			out[v.cfg.Name] = d
+		}
+	}
+	return out
+}
+
+// MembershipsAt lists the zones containing pos with their penetration depths.
+func (n *NoFlyZones) MembershipsAt(pos vector.Vec3) []Membership {
+	var out []Membership
+	for name, d := range n.Inside(pos) {
+		out = append(out, Membership{Name: name, DepthM: d})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Apply warns about, and in hard mode ejects the aircraft from, every zone it is in.
+func (n *NoFlyZones) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+
+	var hits []string
+	for _, v := range n.volumes {
+		depth, exit := v.penetration(pos)
+		if depth <= 0 {
+			continue
+		}
+		hits = append(hits, fmt.Sprintf("%s (%.0f m inside)", v.cfg.Name, depth))
+		if n.Mode != NoFlyHard {
+			continue
+		}
+		out := exit.Sub(pos)
+		if l := math.Sqrt(out.Dot(out)); l > 1e-9 {
+			out = out.Mul(1 / l)
+			if in := vel.Dot(out); in < 0 {
+				vel = vel.Sub(out.Mul(in))
+			}
+		}
+		pos = exit
+	}
+	if len(hits) == 0 {
+		return Unchanged(pos, vel), nil
+	}
+	sort.Strings(hits)
+	msg := "inside " + strings.Join(hits, ", ")
+	if n.Mode == NoFlyHard {
+		msg = "pushed out of " + strings.Join(hits, ", ")
+	}
+	return Result{Pos: pos, Vel: vel, Warnings: []Warning{{Code: WarnNoFlyZone, Severity: SeverityWarning, Message: msg}}}, nil
+}
+
+// geoJSON is the subset of a GeoJSON FeatureCollection LoadNoFlyZones reads.
+type geoJSON struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Type       string `json:"type"`
+		Properties struct {
+			Name     string  `json:"name"`
+			RadiusM  float64 `json:"radiusM"`
+			FloorM   float64 `json:"floorM"`
+			CeilingM float64 `json:"ceilingM"`
+		} `json:"properties"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// LoadNoFlyZones reads zones from a GeoJSON FeatureCollection. Polygon
+// features use their outer ring; Point features need a radiusM property
+// and become cylinders. The name, floorM and ceilingM properties are read
+// for both.
+func LoadNoFlyZones(r io.Reader, mode NoFlyMode) (*NoFlyZones, error) {
+	var doc geoJSON
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("no-fly zones: %w", err)
+	}
+	if doc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("no-fly zones: want a FeatureCollection, got %q", doc.Type)
+	}
+	n := &NoFlyZones{Mode: mode}
+	for i, f := range doc.Features {
+		z := NoFlyZone{
+			Name:     f.Properties.Name,
+			FloorM:   f.Properties.FloorM,
+			CeilingM: f.Properties.CeilingM,
+		}
+		if z.Name == "" {
+			z.Name = fmt.Sprintf("zone-%d", i+1)
+		}
+		switch f.Geometry.Type {
+		case "Polygon":
+			var rings [][][2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil || len(rings) == 0 {
+				return nil, fmt.Errorf("no-fly zones: features[%d]: bad polygon coordinates", i)
+			}
+			z.Polygon = rings[0]
+			// GeoJSON rings repeat the first point at the end
+			if k := len(z.Polygon); k > 1 && z.Polygon[0] == z.Polygon[k-1] {
+				z.Polygon = z.Polygon[:k-1]
+			}
+		case "Point":
+			var pt [2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &pt); err != nil {
+				return nil, fmt.Errorf("no-fly zones: features[%d]: bad point coordinates", i)
+			}
+			z.Lon, z.Lat, z.RadiusM = pt[0], pt[1], f.Properties.RadiusM
+			if z.RadiusM <= 0 {
+				return nil, fmt.Errorf("no-fly zones: features[%d]: a point needs a positive radiusM property", i)
+			}
+		default:
+			return nil, fmt.Errorf("no-fly zones: features[%d]: unsupported geometry %q (want Polygon or Point)", i, f.Geometry.Type)
+		}
+		n.Zones = append(n.Zones, z)
+	}
+	if err := n.Validate(); err != nil {
+		return nil, fmt.Errorf("no-fly zones: %w", err)
+	}
+	return n, nil
+}
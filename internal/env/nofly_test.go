@@ -0,0 +1,142 @@
+package env
+
+import (
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// flatProjector maps a thousandth of a degree to a metre in both axes, so
+// zones and grids can be written in round numbers.
+type flatProjector struct{}
+
+func (flatProjector) GeoToLocal(lat, lon, alt float64) vector.Vec3 {
+	return vector.Vec3{X: lon * 1000, Y: lat * 1000, Z: alt}
+}
+
+func (flatProjector) LocalToGeo(p vector.Vec3) (lat, lon, alt float64) {
+	return p.Y / 1000, p.X / 1000, p.Z
+}
+
+func TestNoFlyZones(t *testing.T) {
+	square := NoFlyZone{Name: "square", Polygon: [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}} // 1 km
+	tower := NoFlyZone{Name: "tower", Lat: 5, Lon: 5, RadiusM: 200, FloorM: 100, CeilingM: 600}
+	for _, c := range []struct {
+		name    string
+		mode    NoFlyMode
+		pos     vector.Vec3
+		vel     vector.Vec3
+		warn    string
+		wantPos vector.Vec3
+		wantVel vector.Vec3
+	}{
+		{name: "outside", mode: NoFlyHard, pos: vector.Vec3{X: 1200, Y: 500, Z: 300}},
+		{
+			name: "advisory only warns", mode: NoFlyAdvisory,
+			pos: vector.Vec3{X: 900, Y: 500, Z: 300}, vel: vector.Vec3{X: -20},
+			warn: "inside square (100 m inside)", wantPos: vector.Vec3{X: 900, Y: 500, Z: 300}, wantVel: vector.Vec3{X: -20},
+		},
+		{
+			name: "hard pushes out the nearest side", mode: NoFlyHard,
+			pos: vector.Vec3{X: 900, Y: 500, Z: 300}, vel: vector.Vec3{X: -20, Y: 5},
+			warn: "pushed out of square (100 m inside)", wantPos: vector.Vec3{X: 1000.5, Y: 500, Z: 300}, wantVel: vector.Vec3{Y: 5},
+		},
+		{
+			name: "hard keeps outward velocity", mode: NoFlyHard,
+			pos: vector.Vec3{X: 500, Y: 50, Z: 300}, vel: vector.Vec3{Y: -10},
+			warn: "pushed out of square (50 m inside)", wantPos: vector.Vec3{X: 500, Y: -0.5, Z: 300}, wantVel: vector.Vec3{Y: -10},
+		},
+		{
+			name: "cylinder", mode: NoFlyHard,
+			pos: vector.Vec3{X: 5150, Y: 5000, Z: 400}, vel: vector.Vec3{X: -30},
+			warn: "pushed out of tower (50 m inside)", wantPos: vector.Vec3{X: 5200.5, Y: 5000, Z: 400},
+		},
+		{name: "under the floor", mode: NoFlyHard, pos: vector.Vec3{X: 5000, Y: 5000, Z: 50}},
+		{name: "over the ceiling", mode: NoFlyHard, pos: vector.Vec3{X: 5000, Y: 5000, Z: 700}},
+		{
+			name: "out through the floor", mode: NoFlyHard,
+			pos: vector.Vec3{X: 5000, Y: 5000, Z: 120}, vel: vector.Vec3{Z: 3},
+			warn: "pushed out of tower (20 m inside)", wantPos: vector.Vec3{X: 5000, Y: 5000, Z: 99.5},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			n := &NoFlyZones{Zones: []NoFlyZone{square, tower}, Mode: c.mode}
+			if err := n.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			BindGeo(&Chain{Effects: []Environment{n}}, flatProjector{})
+			res, err := n.Apply(Context{Pos: c.pos, Vel: c.vel})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.warn == "" {
+				if len(res.Warnings) != 0 || res.Pos != c.pos || res.Vel != c.vel {
+					t.Errorf("outside every zone: %+v", res)
+				}
+				return
+			}
+			if len(res.Warnings) != 1 || res.Warnings[0].Code != WarnNoFlyZone || res.Warnings[0].Message != c.warn {
+				t.Errorf("warnings %v, want %q", res.Warnings, c.warn)
+			}
+			if res.Pos.Sub(c.wantPos).Length() > 1e-6 || res.Vel.Sub(c.wantVel).Length() > 1e-6 {
+				t.Errorf("moved to %v at %v, want %v at %v", res.Pos, res.Vel, c.wantPos, c.wantVel)
+			}
+		})
+	}
+}
+
+func TestNoFlyZoneValidate(t *testing.T) {
+	tri := [][2]float64{{0, 0}, {1, 0}, {0, 1}}
+	for _, c := range []struct {
+		zone NoFlyZone
+		err  string
+	}{
+		{NoFlyZone{Name: "ok", Polygon: tri}, ""},
+		{NoFlyZone{Polygon: tri}, "needs a name"},
+		{NoFlyZone{Name: "z", Polygon: tri[:2]}, "at least 3 points"},
+		{NoFlyZone{Name: "z", Polygon: tri, RadiusM: 10}, "not both"},
+		{NoFlyZone{Name: "z", RadiusM: -5}, "radiusM must be > 0"},
+		{NoFlyZone{Name: "z", Polygon: [][2]float64{{0, 0}, {181, 0}, {0, 1}}}, "point 1 out of range"},
+		{NoFlyZone{Name: "z", Polygon: tri, FloorM: 500, CeilingM: 400}, "ceilingM must be above floorM"},
+	} {
+		err := c.zone.Validate()
+		if c.err == "" && err != nil || c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.zone, err, c.err)
+		}
+	}
+}
+
+func TestLoadNoFlyZones(t *testing.T) {
+	const doc = `{"type": "FeatureCollection", "features": [
+	  {"type": "Feature", "properties": {"name": "airport", "ceilingM": 900},
+	   "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]}},
+	  {"type": "Feature", "properties": {"radiusM": 300},
+	   "geometry": {"type": "Point", "coordinates": [8, 47]}}
+	]}`
+	n, err := LoadNoFlyZones(strings.NewReader(doc), NoFlyAdvisory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Zones) != 2 || len(n.Zones[0].Polygon) != 4 || n.Zones[0].CeilingM != 900 {
+		t.Fatalf("zones %+v", n.Zones)
+	}
+	if z := n.Zones[1]; z.Name != "zone-2" || z.Lat != 47 || z.Lon != 8 || z.RadiusM != 300 {
+		t.Errorf("point zone %+v", z)
+	}
+
+	for _, c := range []struct{ name, doc, err string }{
+		{"not a collection", `{"type": "Feature"}`, "want a FeatureCollection"},
+		{"line", `{"type": "FeatureCollection", "features": [{"geometry": {"type": "LineString", "coordinates": []}}]}`, "unsupported geometry"},
+		{"point without radius", `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Point", "coordinates": [1, 2]}}]}`, "positive radiusM"},
+		{"bad polygon", `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Polygon", "coordinates": [1, 2]}}]}`, "bad polygon"},
+		{"truncated", `{"type": "FeatureCollection", "features": [`, "unexpected EOF"},
+	} {
+		if _, err := LoadNoFlyZones(strings.NewReader(c.doc), NoFlyHard); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: LoadNoFlyZones = %v, want %q", c.name, err, c.err)
+		}
+	}
+	if err := (&NoFlyZones{Mode: "soft"}).Validate(); err == nil {
+		t.Error("Validate accepted an unknown mode")
+	}
+}
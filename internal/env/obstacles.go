@@ -0,0 +1,156 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnObstacle is the code of the warning raised near an obstacle.
+const WarnObstacle = "obstacle"
+
+// Obstacle is a man-made point obstacle such as a tower or building: a
+// cylinder of RadiusM around (Lat, Lon) rising HeightM above the ground, or
+// to HeightM above sea level with MSL.
+type Obstacle struct {
+	Name    string  `json:"name"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	HeightM float64 `json:"heightM"`
+	RadiusM float64 `json:"radiusM"`
+	MSL     bool    `json:"msl,omitempty"`
+}
+
+// Validate checks the obstacle has a name, a position in range and a
+// positive height and radius.
+func (o Obstacle) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("obstacle needs a name")
+	}
+	if !(math.Abs(o.Lat) <= 90) || !(math.Abs(o.Lon) <= 180) {
+		return fmt.Errorf("obstacle %s: position out of range", o.Name)
+	}
+	if !(o.RadiusM > 0) || math.IsInf(o.RadiusM, 1) {
+		return fmt.Errorf("obstacle %s: radiusM must be finite and > 0", o.Name)
+	}
+	if math.IsNaN(o.HeightM) || math.IsInf(o.HeightM, 0) || (!o.MSL && o.HeightM <= 0) {
+		return fmt.Errorf("obstacle %s: heightM must be finite, and > 0 above ground", o.Name)
+	}
+	return nil
+}
+
+// Obstacles warns when the aircraft is within an obstacle's radius and
+// below its top plus MarginM. In NoFlyHard mode it also deflects the
+// aircraft sideways out of the radius, as NoFlyZones does, and cancels the
+// velocity carrying it inward; NoFlyAdvisory (the default) only warns.
+//
+// Heights above ground are measured from the ground under the aircraft
+// (Context.AGL), or from Ground at the obstacle when set, for use outside an
+// engine. The obstacles take effect once the engine has bound its geo
+// reference (see GeoBinder). Obstacles must be used as a pointer.
+type Obstacles struct {
+	Obstacles []Obstacle `json:"obstacles"`
+	MarginM   float64    `json:"marginM,omitempty"`
+	Mode      NoFlyMode  `json:"mode,omitempty"`
+	Ground    Ground     `json:"-"`
+
+	centers []vector.Vec3 // local, by index of Obstacles
+}
+
+// Validate checks the mode, the margin and every obstacle.
+func (o *Obstacles) Validate() error {
+	switch o.Mode {
+	case "", NoFlyAdvisory, NoFlyHard:
+	default:
+		return fmt.Errorf("unknown obstacle mode %q (want %q or %q)", o.Mode, NoFlyAdvisory, NoFlyHard)
+	}
+	if !(o.MarginM >= 0) || math.IsInf(o.MarginM, 1) {
+		return fmt.Errorf("obstacles: marginM must be finite and >= 0")
+	}
+	for _, ob := range o.Obstacles {
+		if err := ob.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BindGeo converts the obstacle positions into the local frame.
+func (o *Obstacles) BindGeo(p Projector) {
+	o.centers = o.centers[:0]
+	for _, ob := range o.Obstacles {
+		o.centers = append(o.centers, p.GeoToLocal(ob.Lat, ob.Lon, 0))
+	}
+}
+
+// top returns the altitude of obstacle i's top, with ground the height of
+// the ground under the aircraft.
+func (o *Obstacles) top(i int, ground float64) float64 {
+	ob := o.Obstacles[i]
+	if ob.MSL {
+		return ob.HeightM
+	}
+	if o.Ground != nil {
+		ground = o.Ground.GroundAltitude(o.centers[i])
+	}
+	return ground + ob.HeightM
+}
+
+// Apply warns about, and in hard mode deflects the aircraft from, every
+// obstacle it is too close to.
+func (o *Obstacles) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+	ground := c.Pos.Z - c.AGL
+
+	var hits []string
+	for i, center := range o.centers {
+		ob := o.Obstacles[i]
+		d := vector.Vec3{X: pos.X - center.X, Y: pos.Y - center.Y}
+		r := math.Hypot(d.X, d.Y)
+		below := o.top(i, ground) + o.MarginM - pos.Z
+		if r >= ob.RadiusM || below <= 0 {
+			continue
+		}
+		hits = append(hits, fmt.Sprintf("%s (%.0f m from its center, %.0f m below the top and margin)", ob.Name, r, below))
+		if o.Mode != NoFlyHard {
+			continue
+		}
+		out := vector.Vec3{X: 1}
+		if r > 1e-9 {
+			out = d.Mul(1 / r)
+		}
+		exit := center.Add(out.Mul(ob.RadiusM + noFlyExitM))
+		pos.X, pos.Y = exit.X, exit.Y
+		if in := vel.Dot(out); in < 0 {
+			vel = vel.Sub(out.Mul(in))
+		}
+	}
+	if len(hits) == 0 {
+		return Unchanged(pos, vel), nil
+	}
+	sort.Strings(hits)
+	msg := "obstacle: " + strings.Join(hits, ", ")
+	if o.Mode == NoFlyHard {
+		msg = "deflected from obstacle: " + strings.Join(hits, ", ")
+	}
+	return Result{Pos: pos, Vel: vel, Warnings: []Warning{{Code: WarnObstacle, Severity: SeverityWarning, Message: msg}}}, nil
+}
+
+// ObstaclesIn lists the obstacles of every Obstacles effect in e, looking
+// inside chains.
+func ObstaclesIn(e Environment) []Obstacle {
+	switch f := e.(type) {
+	case *Chain:
+		var out []Obstacle
+		for _, effect := range f.Effects {
+			out = append(out, ObstaclesIn(effect)...)
+		}
+		return out
+	case *Obstacles:
+		return append([]Obstacle(nil), f.Obstacles...)
+	}
+	return nil
+}
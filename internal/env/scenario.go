@@ -0,0 +1,507 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Scenario describes an environment chain as data. LoadScenario builds the
+// chain from JSON and DescribeScenario turns a chain back into a Scenario.
+type Scenario struct {
+	Effects []EffectSpec `json:"effects"`
+}
+
+// EffectSpec is one effect of a Scenario. In JSON it is an object with a
+// "type" and that type's parameters:
+//
+//	{"type": "wind", "wx": 5, "wy": 2}
+//
+// Params points to the effect itself (e.g. *Turbulence) or, for "wind" and
+// "metar", to a small parameter struct.
+type EffectSpec struct {
+	Type   string
+	Params any
+}
+
+// WindParams are the parameters of a "wind" effect: either the components
+// or a speed and the direction it blows toward (see FromSpeedAndDir).
+type WindParams struct {
+	Wx           *float64 `json:"wx,omitempty"`
+	Wy           *float64 `json:"wy,omitempty"`
+	SpeedMps     *float64 `json:"speedMps,omitempty"`
+	DirectionDeg *float64 `json:"directionDeg,omitempty"`
+}
+
+// METARParams are the parameters of a "metar" effect: the wind of Report.
+type METARParams struct {
+	Report string `json:"report"`
+}
+
+// TerrainParams are the parameters of a "terrain" effect: the safety margin
+// and at most one surface, the synthetic one by default.
+type TerrainParams struct {
+	SafetyMarginM   float64           `json:"safetyMarginM"`
+	RecoveryRateMps float64           `json:"recoveryRateMps,omitempty"`
+	SlopeBaselineM  float64           `json:"slopeBaselineM,omitempty"`
+	FlatElevationM  *float64          `json:"flatElevationM,omitempty"`
+	Synthetic       *SyntheticTerrain `json:"synthetic,omitempty"`
+	// DEMFile is an .hgt or .asc elevation model (see OpenDEM) and
+	// DEMDefaultM the elevation assumed outside it.
+	DEMFile     string  `json:"demFile,omitempty"`
+	DEMDefaultM float64 `json:"demDefaultM,omitempty"`
+	// Heightmap is a grayscale PNG (see NewImageTerrain).
+	Heightmap *HeightmapParams `json:"heightmap,omitempty"`
+	// CacheTiles, when > 0, caches the surface's answers in that many
+	// tiles (see TerrainCache).
+	CacheTiles int `json:"cacheTiles,omitempty"`
+	// Water marks part of the surface as water (see WaterSurface).
+	Water *WaterParams `json:"water,omitempty"`
+}
+
+// WaterParams describe a WaterSurface over the terrain's surface: the
+// elevation at or below which it is water, the water's level, and an
+// optional mask file read like an elevation model.
+type WaterParams struct {
+	ThresholdM float64 `json:"thresholdM"`
+	SeaLevelM  float64 `json:"seaLevelM,omitempty"`
+	MaskFile   string  `json:"maskFile,omitempty"`
+}
+
+// HeightmapParams describe an ImageTerrain: the PNG file, where its center
+// lies, its scale and the elevations of black and white.
+type HeightmapParams struct {
+	File           string  `json:"file"`
+	OffsetX        float64 `json:"offsetX,omitempty"`
+	OffsetY        float64 `json:"offsetY,omitempty"`
+	MetersPerPixel float64 `json:"metersPerPixel"`
+	MinElevM       float64 `json:"minElevM"`
+	MaxElevM       float64 `json:"maxElevM"`
+}
+
+// WeatherParams are the parameters of a "weather" effect: storm cells
+// placed at their lat/lon, drifting with the given seed.
+type WeatherParams struct {
+	Seed  int64         `json:"seed,omitempty"`
+	Cells []WeatherCell `json:"cells"`
+}
+
+// scenarioType describes one effect type of the scenario format. Effects
+// are ordered by rank in the chain, so that constraints see the air moved
+// by every wind and terrain clips last.
+type scenarioType struct {
+	rank   int
+	params func() any
+	build  func(p any) (Environment, error)
+}
+
+var scenarioTypes = map[string]scenarioType{
+	"wind": {0, func() any { return &WindParams{} }, func(p any) (Environment, error) {
+		w := p.(*WindParams)
+		switch {
+		case w.Wx != nil && w.Wy != nil && w.SpeedMps == nil && w.DirectionDeg == nil:
+			wind := Wind{Wx: *w.Wx, Wy: *w.Wy}
+			return wind, wind.Validate()
+		case w.SpeedMps != nil && w.DirectionDeg != nil && w.Wx == nil && w.Wy == nil:
+			if !(*w.SpeedMps >= 0) {
+				return nil, fmt.Errorf("speedMps must be >= 0")
+			}
+			wind := FromSpeedAndDir(*w.SpeedMps, *w.DirectionDeg)
+			return wind, wind.Validate()
+		}
+		return nil, fmt.Errorf("give either wx and wy or speedMps and directionDeg")
+	}},
+	"metar": {0, func() any { return &METARParams{} }, func(p any) (Environment, error) {
+		return FromMETAR(p.(*METARParams).Report)
+	}},
+	"windProfile": {0, func() any { return &WindProfile{} }, func(p any) (Environment, error) {
+		wp := *p.(*WindProfile)
+		return wp, wp.Validate()
+	}},
+	"windField": {0, func() any { return &WindField{} }, func(p any) (Environment, error) {
+		f := p.(*WindField)
+		return f, f.Validate()
+	}},
+	"windSchedule": {0, func() any { return &WindSchedule{} }, func(p any) (Environment, error) {
+		s := p.(*WindSchedule)
+		return s, s.Validate()
+	}},
+	"gustFront": {0, func() any { return &GustFront{} }, func(p any) (Environment, error) {
+		g := p.(*GustFront)
+		return g, g.Validate()
+	}},
+	"turbulence": {1, func() any { return &Turbulence{} }, func(p any) (Environment, error) {
+		t := p.(*Turbulence)
+		return t, t.Validate()
+	}},
+	"thermals": {1, func() any { return &Thermals{} }, func(p any) (Environment, error) {
+		ts := *p.(*Thermals)
+		return ts, ts.Validate()
+	}},
+	"weather": {2, func() any { return &WeatherParams{} }, func(p any) (Environment, error) {
+		w := p.(*WeatherParams)
+		return NewWeatherAt(w.Seed, w.Cells)
+	}},
+	"glide": {3, func() any { return &Gravity{} }, func(p any) (Environment, error) {
+		g := p.(*Gravity)
+		return g, g.Validate()
+	}},
+	"icing": {3, func() any { return &Icing{} }, func(p any) (Environment, error) {
+		i := p.(*Icing)
+		return i, i.Validate()
+	}},
+	"groundEffect": {4, func() any { return &GroundEffect{} }, func(p any) (Environment, error) {
+		g := p.(*GroundEffect)
+		return g, g.Validate()
+	}},
+	"noFly": {5, func() any { return &NoFlyZones{} }, func(p any) (Environment, error) {
+		n := p.(*NoFlyZones)
+		if n.Mode == "" {
+			n.Mode = NoFlyAdvisory
+		}
+		return n, n.Validate()
+	}},
+	"obstacles": {5, func() any { return &Obstacles{} }, func(p any) (Environment, error) {
+		o := p.(*Obstacles)
+		if o.Mode == "" {
+			o.Mode = NoFlyAdvisory
+		}
+		return o, o.Validate()
+	}},
+	"terrain": {6, func() any { return &TerrainParams{} }, func(p any) (Environment, error) {
+		tp := p.(*TerrainParams)
+		if !(tp.SafetyMarginM >= 0) {
+			return nil, fmt.Errorf("safetyMarginM must be >= 0")
+		}
+		if !(tp.RecoveryRateMps >= 0) {
+			return nil, fmt.Errorf("recoveryRateMps must be >= 0")
+		}
+		if !(tp.SlopeBaselineM >= 0) || math.IsInf(tp.SlopeBaselineM, 1) {
+			return nil, fmt.Errorf("slopeBaselineM must be finite and >= 0")
+		}
+		t := Terrain{SafetyMarginM: tp.SafetyMarginM, RecoveryRateMps: tp.RecoveryRateMps, SlopeBaselineM: tp.SlopeBaselineM}
+		surfaces := 0
+		for _, set := range []bool{tp.FlatElevationM != nil, tp.Synthetic != nil, tp.DEMFile != "", tp.Heightmap != nil} {
+			if set {
+				surfaces++
+			}
+		}
+		switch {
+		case surfaces > 1:
+			return nil, fmt.Errorf("give at most one of flatElevationM, synthetic, demFile and heightmap")
+		case tp.Heightmap != nil:
+			hm := tp.Heightmap
+			img, err := NewImageTerrain(hm.File, vector.Vec3{X: hm.OffsetX, Y: hm.OffsetY}, hm.MetersPerPixel, hm.MinElevM, hm.MaxElevM)
+			if err != nil {
+				return nil, err
+			}
+			t.Provider = img
+		case tp.DEMFile != "":
+			d, err := OpenDEM(tp.DEMFile)
+			if err != nil {
+				return nil, err
+			}
+			d.DefaultM = tp.DEMDefaultM
+			if err := d.Validate(); err != nil {
+				return nil, err
+			}
+			t.Provider = d
+		case tp.FlatElevationM != nil:
+			if math.IsNaN(*tp.FlatElevationM) || math.IsInf(*tp.FlatElevationM, 0) {
+				return nil, fmt.Errorf("flatElevationM must be finite")
+			}
+			t.Provider = FlatTerrain(*tp.FlatElevationM)
+		case tp.Synthetic != nil:
+			if err := tp.Synthetic.Validate(); err != nil {
+				return nil, err
+			}
+			t.Provider = *tp.Synthetic
+		}
+		if tp.CacheTiles < 0 {
+			return nil, fmt.Errorf("cacheTiles must be >= 0")
+		}
+		if tp.CacheTiles > 0 {
+			t.Provider = &TerrainCache{Provider: t.provider(), MaxTiles: tp.CacheTiles}
+		}
+		if tp.Water != nil {
+			w := &WaterSurface{Land: t.provider(), ThresholdM: tp.Water.ThresholdM, SeaLevelM: tp.Water.SeaLevelM}
+			if tp.Water.MaskFile != "" {
+				m, err := OpenDEM(tp.Water.MaskFile)
+				if err != nil {
+					return nil, err
+				}
+				w.Mask = m
+			}
+			if err := w.Validate(); err != nil {
+				return nil, err
+			}
+			t.Provider = w
+		}
+		return t, nil
+	}},
+}
+
+// DescribeTerrain returns the parameters t is built from, the synthetic
+// surface's included when t has no provider.
+func DescribeTerrain(t Terrain) (TerrainParams, error) {
+	tp := TerrainParams{SafetyMarginM: t.SafetyMarginM, RecoveryRateMps: t.RecoveryRateMps, SlopeBaselineM: t.SlopeBaselineM}
+	prov := t.provider()
+	if w, ok := prov.(*WaterSurface); ok {
+		tp.Water = &WaterParams{ThresholdM: w.ThresholdM, SeaLevelM: w.SeaLevelM}
+		if w.Mask != nil {
+			if w.Mask.Source == "" {
+				return tp, fmt.Errorf("water mask was not read from a file")
+			}
+			tp.Water.MaskFile = w.Mask.Source
+		}
+		prov = w.Land
+	}
+	if c, ok := prov.(*TerrainCache); ok {
+		tp.CacheTiles = c.MaxTiles
+		if tp.CacheTiles <= 0 {
+			tp.CacheTiles = DefaultTerrainCacheTiles
+		}
+		prov = c.Provider
+	}
+	switch p := prov.(type) {
+	case FlatSurface:
+		tp.FlatElevationM = &p.ElevationM
+	case SyntheticTerrain:
+		tp.Synthetic = &p
+	case *ImageTerrain:
+		if p.Source == "" {
+			return tp, fmt.Errorf("terrain heightmap was not read from a file")
+		}
+		tp.Heightmap = &HeightmapParams{
+			File: p.Source, OffsetX: p.Offset.X, OffsetY: p.Offset.Y,
+			MetersPerPixel: p.MetersPerPixel, MinElevM: p.MinElevM, MaxElevM: p.MaxElevM,
+		}
+	case *DEM:
+		if p.Source == "" {
+			return tp, fmt.Errorf("terrain elevation model was not read from a file")
+		}
+		tp.DEMFile, tp.DEMDefaultM = p.Source, p.DefaultM
+	default:
+		return tp, fmt.Errorf("terrain provider %T has no scenario form", p)
+	}
+	return tp, nil
+}
+
+// scenarioTypeNames lists the known types for error messages.
+func scenarioTypeNames() string {
+	names := make([]string, 0, len(scenarioTypes))
+	for name := range scenarioTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// MarshalJSON writes the spec as its parameters with "type" first.
+func (s EffectSpec) MarshalJSON() ([]byte, error) {
+	typ, err := json.Marshal(s.Type)
+	if err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(s.Params)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(params, []byte("{")) {
+		return nil, fmt.Errorf("%s: parameters must be an object", s.Type)
+	}
+	out := append([]byte(`{"type":`), typ...)
+	if rest := bytes.TrimSpace(params[1:]); !bytes.Equal(rest, []byte("}")) {
+		out = append(append(out, ','), rest...)
+	} else {
+		out = append(out, '}')
+	}
+	return out, nil
+}
+
+// UnmarshalJSON reads the type and decodes the other fields strictly into
+// that type's parameters.
+func (s *EffectSpec) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	raw, ok := fields["type"]
+	if !ok {
+		return errors.New(`missing "type"`)
+	}
+	if err := json.Unmarshal(raw, &s.Type); err != nil {
+		return fmt.Errorf("type: %w", err)
+	}
+	st, ok := scenarioTypes[s.Type]
+	if !ok {
+		return fmt.Errorf("unknown effect type %q (want one of %s)", s.Type, scenarioTypeNames())
+	}
+	delete(fields, "type")
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	s.Params = st.params()
+	dec := json.NewDecoder(bytes.NewReader(rest))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(s.Params); err != nil {
+		return fmt.Errorf("%s: %w", s.Type, err)
+	}
+	return nil
+}
+
+// LoadScenario reads a Scenario from JSON and builds its chain (see
+// Scenario.Build). Errors name the line and column or the effect's index.
+func LoadScenario(r io.Reader) (*Chain, error) {
+	s, err := ReadScenario(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.Build()
+}
+
+// ReadScenario reads and decodes a Scenario from JSON without building it.
+func ReadScenario(r io.Reader) (Scenario, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("scenario: %w", err)
+	}
+	var s Scenario
+	// decode the effects one at a time, so an error can say which one failed
+	var top struct {
+		Effects []json.RawMessage `json:"effects"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&top); err != nil {
+		var syn *json.SyntaxError
+		if errors.As(err, &syn) {
+			line, col := position(data, syn.Offset)
+			return Scenario{}, fmt.Errorf("scenario: line %d, column %d: %w", line, col, err)
+		}
+		return Scenario{}, fmt.Errorf("scenario: %w", err)
+	}
+	for i, raw := range top.Effects {
+		var spec EffectSpec
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return Scenario{}, fmt.Errorf("scenario: effects[%d]: %w", i, err)
+		}
+		s.Effects = append(s.Effects, spec)
+	}
+	return s, nil
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (line, col int) {
+	before := data[:min(int(offset), len(data))]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// Build validates every effect and chains them, ordered by kind: winds,
+// then turbulence and thermals, weather cells, glide and icing, ground
+// effect, no-fly zones and obstacles and finally terrain. Effects of the same kind keep their order.
+func (s Scenario) Build() (*Chain, error) {
+	type ranked struct {
+		rank   int
+		effect Environment
+	}
+	var effects []ranked
+	for i, spec := range s.Effects {
+		st, ok := scenarioTypes[spec.Type]
+		if !ok {
+			return nil, fmt.Errorf("scenario: effects[%d]: unknown effect type %q (want one of %s)", i, spec.Type, scenarioTypeNames())
+		}
+		params := spec.Params
+		if params == nil {
+			params = st.params()
+		}
+		e, err := st.build(params)
+		if err != nil {
+			return nil, fmt.Errorf("scenario: effects[%d] (%s): %w", i, spec.Type, err)
+		}
+		effects = append(effects, ranked{st.rank, e})
+	}
+	sort.SliceStable(effects, func(i, j int) bool { return effects[i].rank < effects[j].rank })
+	c := &Chain{Effects: make([]Environment, 0, len(effects))}
+	for _, r := range effects {
+		c.Effects = append(c.Effects, r.effect)
+	}
+	return c, nil
+}
+
+// DescribeScenario returns the Scenario that builds e, looking inside
+// chains. Effects outside the scenario format, such as Legacy wrappers,
+// are an error.
+func DescribeScenario(e Environment) (Scenario, error) {
+	s := Scenario{Effects: []EffectSpec{}}
+	if e == nil {
+		return s, nil
+	}
+	var add func(e Environment) error
+	add = func(e Environment) error {
+		var spec EffectSpec
+		switch f := e.(type) {
+		case *Chain:
+			for _, effect := range f.Effects {
+				if err := add(effect); err != nil {
+					return err
+				}
+			}
+			return nil
+		case Wind:
+			spec = EffectSpec{"wind", &WindParams{Wx: &f.Wx, Wy: &f.Wy}}
+		case WindProfile:
+			spec = EffectSpec{"windProfile", &f}
+		case *WindField:
+			spec = EffectSpec{"windField", f}
+		case *WindSchedule:
+			spec = EffectSpec{"windSchedule", f}
+		case *GustFront:
+			spec = EffectSpec{"gustFront", f}
+		case *Turbulence:
+			spec = EffectSpec{"turbulence", f}
+		case Thermals:
+			spec = EffectSpec{"thermals", &f}
+		case *Weather:
+			cells := append([]WeatherCell{}, f.pending...)
+			for _, c := range f.cells {
+				cells = append(cells, c.cfg)
+			}
+			spec = EffectSpec{"weather", &WeatherParams{Seed: f.seed, Cells: cells}}
+		case *Gravity:
+			spec = EffectSpec{"glide", f}
+		case *Icing:
+			spec = EffectSpec{"icing", f}
+		case *GroundEffect:
+			spec = EffectSpec{"groundEffect", f}
+		case *NoFlyZones:
+			spec = EffectSpec{"noFly", f}
+		case *Obstacles:
+			spec = EffectSpec{"obstacles", f}
+		case Terrain:
+			tp, err := DescribeTerrain(f)
+			if err != nil {
+				return fmt.Errorf("scenario: %w", err)
+			}
+			spec = EffectSpec{"terrain", &tp}
+		default:
+			return fmt.Errorf("scenario: %s has no scenario form", effectName(e))
+		}
+		s.Effects = append(s.Effects, spec)
+		return nil
+	}
+	if err := add(e); err != nil {
+		return Scenario{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,79 @@
+package env
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadScenarioFixture(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "scenario.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := LoadScenario(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// winds first, terrain last, whatever the order of the file
+	want := "Wind,Wind,Turbulence,Thermals,Terrain"
+	if got := strings.Join(Names(c), ","); got != want {
+		t.Fatalf("chain %s, want %s", got, want)
+	}
+	terr := c.Effects[4].(Terrain)
+	if d, ok := terr.Provider.(*DEM); !ok || d.DefaultM != 50 || terr.SafetyMarginM != 30 {
+		t.Errorf("terrain %+v", terr)
+	}
+
+	// the described scenario builds the same chain again
+	s, err := DescribeScenario(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := LoadScenario(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("%v in %s", err, b)
+	}
+	if got := strings.Join(Names(again), ","); got != want {
+		t.Errorf("described chain %s, want %s", got, want)
+	}
+	if again.Effects[0] != c.Effects[0] || again.Effects[1] != c.Effects[1] {
+		t.Errorf("winds %v, %v after the round trip, want %v, %v", again.Effects[0], again.Effects[1], c.Effects[0], c.Effects[1])
+	}
+}
+
+func TestLoadScenarioRejects(t *testing.T) {
+	for _, c := range []struct {
+		name, json, want string
+	}{
+		{"syntax", "{\n  \"effects\": [\n    {\"type\": \"wind\",}\n  ]\n}", "line 3, column"},
+		{"not an object", `[]`, "scenario:"},
+		{"unknown top-level field", `{"effects": [], "extra": 1}`, `unknown field "extra"`},
+		{"effects not a list", `{"effects": {"type": "wind"}}`, "scenario:"},
+		{"missing type", `{"effects": [{"wx": 1, "wy": 2}]}`, `effects[0]: missing "type"`},
+		{"type not a string", `{"effects": [{"type": 3}]}`, "effects[0]: type:"},
+		{"unknown type", `{"effects": [{"type": "wind"}, {"type": "fog"}]}`, `effects[1]: unknown effect type "fog"`},
+		{"unknown parameter", `{"effects": [{"type": "turbulence", "intensity": 2}]}`, `effects[0]: turbulence: json: unknown field "intensity"`},
+		{"wrong parameter type", `{"effects": [{"type": "metar", "report": 27015}]}`, "effects[0]: metar:"},
+		{"wind in both forms", `{"effects": [{"type": "wind", "wx": 1, "wy": 2, "speedMps": 3, "directionDeg": 4}]}`, "effects[0] (wind): give either"},
+		{"half a wind", `{"effects": [{"type": "wind", "wx": 1}]}`, "effects[0] (wind): give either"},
+		{"negative wind speed", `{"effects": [{"type": "wind", "speedMps": -1, "directionDeg": 0}]}`, "speedMps must be >= 0"},
+		{"bad metar", `{"effects": [{"type": "metar", "report": "LSZH 171150Z 9999"}]}`, "effects[0] (metar): metar"},
+		{"two surfaces", `{"effects": [{"type": "terrain", "safetyMarginM": 10, "flatElevationM": 0, "demFile": "testdata/tiny.asc"}]}`, "at most one of"},
+		{"missing elevation model", `{"effects": [{"type": "terrain", "safetyMarginM": 10, "demFile": "testdata/nowhere.asc"}]}`, "effects[0] (terrain):"},
+		{"negative margin", `{"effects": [{"type": "terrain", "safetyMarginM": -1}]}`, "safetyMarginM must be >= 0"},
+		{"bad thermal", `{"effects": [{"type": "thermals", "columns": [{"radiusM": 0, "strengthMps": 1, "topAltM": 100}]}]}`, "effects[0] (thermals): columns[0]"},
+	} {
+		_, err := LoadScenario(strings.NewReader(c.json))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: error %v, want one containing %q", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package env
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Defaults of SyntheticTerrain's noise.
+const (
+	DefaultSyntheticOctaves     = 4
+	DefaultSyntheticPersistence = 0.5
+)
+
+// SyntheticTerrain is a TerrainProvider for a generated landscape around
+// BaseM: a long wave of AmplitudeM along x, a ridge wave of RidgeAmplitudeM
+// along x+y (ScaleM and RidgeScaleM are the metres per radian) and value
+// noise of up to NoiseAmplitudeM. The noise has features NoiseWavelengthM
+// apart, with Octaves finer layers each half the wavelength and Persistence
+// times the amplitude of the one before (defaults DefaultSyntheticOctaves
+// and DefaultSyntheticPersistence).
+//
+// The elevation depends only on the parameters and Seed, so the same
+// parameters give the same terrain in every run and replay. A part with a
+// zero amplitude is left out, and its scale may be zero.
+type SyntheticTerrain struct {
+	BaseM           float64 `json:"baseM,omitempty"`
+	AmplitudeM      float64 `json:"amplitudeM"`
+	ScaleM          float64 `json:"scaleM"`
+	RidgeAmplitudeM float64 `json:"ridgeAmplitudeM"`
+	RidgeScaleM     float64 `json:"ridgeScaleM"`
+
+	NoiseAmplitudeM  float64 `json:"noiseAmplitudeM,omitempty"`
+	NoiseWavelengthM float64 `json:"noiseWavelengthM,omitempty"`
+	Octaves          int     `json:"octaves,omitempty"`
+	Persistence      float64 `json:"persistence,omitempty"`
+	Seed             int64   `json:"seed,omitempty"`
+}
+
+// DefaultSyntheticTerrain is the wavy surface Terrain uses without a
+// provider.
+func DefaultSyntheticTerrain() SyntheticTerrain {
+	return SyntheticTerrain{AmplitudeM: 100, ScaleM: 1000, RidgeAmplitudeM: 50, RidgeScaleM: 500}
+}
+
+// Validate checks the values are finite, the scale of every part with an
+// amplitude positive, and the octaves and persistence in range.
+func (s SyntheticTerrain) Validate() error {
+	for _, v := range []float64{s.BaseM, s.AmplitudeM, s.ScaleM, s.RidgeAmplitudeM, s.RidgeScaleM, s.NoiseAmplitudeM, s.NoiseWavelengthM, s.Persistence} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("synthetic terrain: values must be finite")
+		}
+	}
+	for _, part := range []struct {
+		name       string
+		amp, scale float64
+	}{{"scaleM", s.AmplitudeM, s.ScaleM}, {"ridgeScaleM", s.RidgeAmplitudeM, s.RidgeScaleM}, {"noiseWavelengthM", s.NoiseAmplitudeM, s.NoiseWavelengthM}} {
+		if part.amp != 0 && !(part.scale > 0) {
+			return fmt.Errorf("synthetic terrain: %s must be > 0 with an amplitude", part.name)
+		}
+	}
+	if s.Octaves < 0 || s.Octaves > 16 {
+		return fmt.Errorf("synthetic terrain: octaves must be between 0 and 16")
+	}
+	if s.Persistence < 0 || s.Persistence > 1 {
+		return fmt.Errorf("synthetic terrain: persistence must be between 0 and 1")
+	}
+	return nil
+}
+
+// GroundAltitude is the base plus the waves and the noise at pos.
+func (s SyntheticTerrain) GroundAltitude(pos vector.Vec3) float64 {
+	alt := s.BaseM
+	if s.AmplitudeM != 0 {
+		alt += math.Sin(pos.X/s.ScaleM) * s.AmplitudeM
+	}
+	if s.RidgeAmplitudeM != 0 {
+		alt += math.Sin((pos.X+pos.Y)/s.RidgeScaleM) * s.RidgeAmplitudeM
+	}
+	if s.NoiseAmplitudeM != 0 {
+		alt += s.noise(pos.X, pos.Y) * s.NoiseAmplitudeM
+	}
+	return alt
+}
+
+// MaxGroundAltitude is the highest the waves and noise reach together.
+func (s SyntheticTerrain) MaxGroundAltitude() float64 {
+	return s.BaseM + math.Abs(s.AmplitudeM) + math.Abs(s.RidgeAmplitudeM) + math.Abs(s.NoiseAmplitudeM)
+}
+
+// noise sums the octaves of value noise at (x, y), scaled into [-1, 1].
+func (s SyntheticTerrain) noise(x, y float64) float64 {
+	octaves := s.Octaves
+	if octaves == 0 {
+		octaves = DefaultSyntheticOctaves
+	}
+	persistence := s.Persistence
+	if persistence == 0 {
+		persistence = DefaultSyntheticPersistence
+	}
+	sum, norm := 0.0, 0.0
+	amp, freq := 1.0, 1/s.NoiseWavelengthM
+	for k := 0; k < octaves; k++ {
+		sum += amp * valueNoise(s.Seed, k, x*freq, y*freq)
+		norm += amp
+		amp *= persistence
+		freq *= 2
+	}
+	return sum / norm
+}
+
+// valueNoise interpolates pseudo-random values in [-1, 1] on the integer
+// lattice, smoothly so the surface has no creases.
+func valueNoise(seed int64, octave int, x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	ix, iy := int64(x0), int64(y0)
+	u, v := fade(x-x0), fade(y-y0)
+	a := lattice(seed, octave, ix, iy)
+	b := lattice(seed, octave, ix+1, iy)
+	c := lattice(seed, octave, ix, iy+1)
+	d := lattice(seed, octave, ix+1, iy+1)
+	return (a*(1-u)+b*u)*(1-v) + (c*(1-u)+d*u)*v
+}
+
+// fade is the quintic smoothstep 6t⁵-15t⁴+10t³.
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// lattice hashes a lattice point into [-1, 1] (splitmix64's finalizer).
+func lattice(seed int64, octave int, ix, iy int64) float64 {
+	h := uint64(seed)*0x9e3779b97f4a7c15 ^ uint64(ix)*0xbf58476d1ce4e5b9 ^ uint64(iy)*0x94d049bb133111eb ^ uint64(octave+1)*0xd6e8feb86659fd93
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return float64(h>>11)/(1<<53)*2 - 1
+}
@@ -1,49 +1,263 @@
 package env
 
 import (
+	"fmt"
 	"math"
 
 	"flight-simulator2/internal/geometry/vector"
 )
 
+// Terrain warning codes, one per level of terrain proximity:
+// WarnTerrainProximity (caution) within twice the safety margin,
+// WarnTerrainFloor while Terrain climbs the aircraft back above the margin,
+// and WarnTerrainContact when it had to clip the altitude to the surface
+// itself (WarnDitched instead over water).
+const (
+	WarnTerrainProximity = "terrain-proximity"
+	WarnTerrainFloor     = "terrain-floor"
+	WarnTerrainContact   = "terrain-contact"
+)
+
+// DefaultTerrainRecoveryRateMps is the climb rate Terrain recovers the
+// safety margin at when RecoveryRateMps is zero.
+const DefaultTerrainRecoveryRateMps = 10.0
+
+// DefaultSlopeBaselineM is the baseline Terrain differences the elevation
+// over for SlopeDeg when SlopeBaselineM is zero.
+const DefaultSlopeBaselineM = 10.0
+
+// terrainRecoveryAccel is how quickly (m/s²) the recovery climb is
+// entered, about a 1 g pull-up.
+const terrainRecoveryAccel = 10.0
+
+// TerrainProvider gives the ground height under a position, in metres.
+// Providers that know their highest point also implement
+// MaxGroundAltitude() float64, which lets a ceiling be checked against them.
+// Providers configured in lat/lon implement GeoBinder, those with
+// something to report implement TerrainWarner, and those with limited
+// data implement Coverage.
+type TerrainProvider interface {
+	GroundAltitude(pos vector.Vec3) float64
+}
+
+// TerrainWarner is a TerrainProvider that reports warnings at a position,
+// such as DEM's coverage caution. Terrain adds them to its result.
+type TerrainWarner interface {
+	TerrainWarnings(pos vector.Vec3) []Warning
+}
+
+// FlatSurface is a TerrainProvider at the same elevation everywhere.
+type FlatSurface struct {
+	ElevationM float64
+}
+
+// FlatTerrain returns a flat surface at elevM metres.
+func FlatTerrain(elevM float64) FlatSurface {
+	return FlatSurface{ElevationM: elevM}
+}
+
+// GroundAltitude is the elevation.
+func (f FlatSurface) GroundAltitude(vector.Vec3) float64 {
+	return f.ElevationM
+}
+
+// MaxGroundAltitude is the elevation.
+func (f FlatSurface) MaxGroundAltitude() float64 {
+	return f.ElevationM
+}
+
 // Terrain implements an environment effect that simulates ground collision detection
-// and prevents the aircraft from flying below the terrain plus a safety margin.
+// and keeps the aircraft above the terrain plus a safety margin. Below the
+// margin it commands an emergency climb rather than moving the aircraft; only
+// below the surface itself is the altitude clipped.
 type Terrain struct {
 	// SafetyMarginM is the minimum allowed altitude above terrain in meters
-	SafetyMarginM float64
+	SafetyMarginM float64 `json:"safetyMarginM"`
+	// RecoveryRateMps is the climb rate the margin is regained at
+	// (default DefaultTerrainRecoveryRateMps).
+	RecoveryRateMps float64 `json:"recoveryRateMps,omitempty"`
+	// SlopeBaselineM is the distance SlopeDeg differences the elevation
+	// over (default DefaultSlopeBaselineM).
+	SlopeBaselineM float64 `json:"slopeBaselineM,omitempty"`
+	// Provider gives the ground height; nil means DefaultSyntheticTerrain.
+	Provider TerrainProvider `json:"-"`
+}
+
+// provider returns the provider, defaulting to the synthetic surface.
+func (t Terrain) provider() TerrainProvider {
+	if t.Provider == nil {
+		return DefaultSyntheticTerrain()
+	}
+	return t.Provider
 }
 
-// GroundAltitude calculates the terrain height at a given position.
-// This is a simple synthetic terrain function that can be replaced with real elevation data.
-// Currently, it creates a wavy terrain pattern for demonstration purposes.
+// BindGeo passes p on to a provider configured in lat/lon.
+func (t Terrain) BindGeo(p Projector) {
+	if b, ok := t.Provider.(GeoBinder); ok {
+		b.BindGeo(p)
+	}
+}
+
+// Validate checks a provider that can check itself.
+func (t Terrain) Validate() error {
+	if v, ok := t.Provider.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Covers reports whether the provider has data at pos; providers without a
+// Coverage cover everywhere.
+func (t Terrain) Covers(pos vector.Vec3) bool {
+	if c, ok := t.Provider.(Coverage); ok {
+		return c.Covers(pos)
+	}
+	return true
+}
+
+// IsWater reports whether the provider has water under pos; providers
+// without a WaterMask are all land.
+func (t Terrain) IsWater(pos vector.Vec3) bool {
+	if w, ok := t.Provider.(WaterMask); ok {
+		return w.IsWater(pos)
+	}
+	return false
+}
+
+// GroundAltitude returns the provider's terrain height at a given position.
 func (t Terrain) GroundAltitude(pos vector.Vec3) float64 {
-	// Create a simple wavy terrain pattern
-	wave1 := math.Sin(pos.X/1000) * 100
-	wave2 := math.Sin((pos.X+pos.Y)/500) * 50
-	return wave1 + wave2
+	return t.provider().GroundAltitude(pos)
+}
+
+// CacheStats returns the counters of the provider's TerrainCache, looking
+// through a WaterSurface; ok is false without one.
+func (t Terrain) CacheStats() (st TerrainCacheStats, ok bool) {
+	p := t.Provider
+	if w, isWater := p.(*WaterSurface); isWater {
+		p = w.Land
+	}
+	if c, isCache := p.(*TerrainCache); isCache {
+		return c.Stats(), true
+	}
+	return TerrainCacheStats{}, false
+}
+
+// SlopeDeg is the steepest slope of the ground at pos, in degrees from the
+// horizontal, from central differences SlopeBaselineM apart.
+func (t Terrain) SlopeDeg(pos vector.Vec3) float64 {
+	b := t.SlopeBaselineM
+	if b <= 0 {
+		b = DefaultSlopeBaselineM
+	}
+	return SlopeDeg(t.provider(), pos, b)
 }
 
-// Apply enforces terrain collision detection and applies ground effect.
-// If the aircraft is below the terrain plus safety margin, it will be moved up
-// and its vertical velocity will be set to zero if it was descending.
-func (t Terrain) Apply(dt float64, pos vector.Vec3, vel vector.Vec3) (vector.Vec3, vector.Vec3, string) {
+// SlopeDeg is the steepest slope of g's ground at pos, in degrees, from
+// the elevation differences across baselineM east-west and north-south.
+func SlopeDeg(g Ground, pos vector.Vec3, baselineM float64) float64 {
+	h := baselineM / 2
+	at := func(dx, dy float64) float64 {
+		return g.GroundAltitude(vector.Vec3{X: pos.X + dx, Y: pos.Y + dy})
+	}
+	gx := (at(h, 0) - at(-h, 0)) / baselineM
+	gy := (at(0, h) - at(0, -h)) / baselineM
+	return math.Atan(math.Hypot(gx, gy)) * 180 / math.Pi
+}
+
+// Apply enforces terrain collision detection.
+// Within twice the safety margin it raises a WarnTerrainProximity caution.
+// If the aircraft is below the terrain plus safety margin, its vertical
+// velocity is raised toward RecoveryRateMps so it climbs back smoothly. If it
+// is below the terrain itself, it is moved up to the surface and a descent is
+// stopped; over water that is a ditching, reported as WarnDitched.
+func (t Terrain) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+
 	groundAlt := t.GroundAltitude(pos)
 	minAllowedAlt := groundAlt + t.SafetyMarginM
+	var warnings []Warning
+	if w, ok := t.Provider.(TerrainWarner); ok {
+		warnings = w.TerrainWarnings(pos)
+	}
 
-	// Check for ground collision
-	if pos.Z < minAllowedAlt {
-		// Move aircraft to minimum allowed altitude
-		pos.Z = minAllowedAlt
-
-		// If descending, stop vertical movement
+	switch {
+	case pos.Z < groundAlt:
+		pos.Z = groundAlt
 		if vel.Z < 0 {
 			vel.Z = 0
 		}
+		warnings = append(warnings, SurfaceContact(t.IsWater(pos)))
+	case pos.Z < minAllowedAlt:
+		rate := t.RecoveryRateMps
+		if rate <= 0 {
+			rate = DefaultTerrainRecoveryRateMps
+		}
+		if vel.Z < rate {
+			vel.Z = min(vel.Z+terrainRecoveryAccel*c.Dt, rate)
+		}
+		warnings = append(warnings, Warning{
+			Code:     WarnTerrainFloor,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("climbing to regain the %.0f m terrain margin", t.SafetyMarginM),
+		})
+	case pos.Z < groundAlt+2*t.SafetyMarginM:
+		warnings = append(warnings, Warning{
+			Code:     WarnTerrainProximity,
+			Severity: SeverityCaution,
+			Message:  fmt.Sprintf("%.0f m above the terrain, within twice the %.0f m margin", pos.Z-groundAlt, t.SafetyMarginM),
+		})
+	}
+
+	res := Unchanged(pos, vel)
+	res.Warnings = warnings
+	return res, nil
+}
 
-		return pos, vel, "terrain-floor: altitude clipped to safety margin"
+// MinAltitude is the safety floor at pos, which Apply climbs back above.
+func (t Terrain) MinAltitude(pos vector.Vec3) float64 {
+	return t.GroundAltitude(pos) + t.SafetyMarginM
+}
+
+// MaxGroundAltitude is the highest terrain height GroundAltitude returns,
+// or +Inf when the provider does not say.
+func (t Terrain) MaxGroundAltitude() float64 {
+	if b, ok := t.provider().(interface{ MaxGroundAltitude() float64 }); ok {
+		return b.MaxGroundAltitude()
 	}
+	return math.Inf(1)
+}
 
-	return pos, vel, ""
+// MaxMinAltitude is the highest safety floor anywhere.
+func (t Terrain) MaxMinAltitude() float64 {
+	return t.MaxGroundAltitude() + t.SafetyMarginM
+}
+
+// SurfaceContact is the warning raised when the aircraft reaches the terrain
+// surface: WarnTerrainContact, or WarnDitched if it is water.
+func SurfaceContact(water bool) Warning {
+	if water {
+		return Warning{Code: WarnDitched, Severity: SeverityWarning, Message: "ditched on water"}
+	}
+	return Warning{
+		Code:     WarnTerrainContact,
+		Severity: SeverityWarning,
+		Message:  "hard floor contact, altitude clipped to the terrain",
+	}
+}
+
+// FindTerrain returns the first Terrain in e, looking inside chains.
+func FindTerrain(e Environment) (Terrain, bool) {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if t, ok := FindTerrain(effect); ok {
+				return t, true
+			}
+		}
+	case Terrain:
+		return f, true
+	}
+	return Terrain{}, false
 }
 
 // DefaultTerrain returns a Terrain with a reasonable default safety margin.
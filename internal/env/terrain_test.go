@@ -0,0 +1,164 @@
+package env
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// slopedSurface is a provider that does not know its highest point.
+type slopedSurface struct{}
+
+func (slopedSurface) GroundAltitude(pos vector.Vec3) float64 { return pos.X / 10 }
+
+func TestTerrainDefaultProvider(t *testing.T) {
+	// without a provider Terrain behaves exactly as over the default
+	// synthetic surface
+	implicit := Terrain{SafetyMarginM: 50}
+	explicit := Terrain{SafetyMarginM: 50, Provider: DefaultSyntheticTerrain()}
+	for x := -3000.0; x <= 3000; x += 250 {
+		for y := -3000.0; y <= 3000; y += 500 {
+			ground := DefaultSyntheticTerrain().GroundAltitude(vector.Vec3{X: x, Y: y})
+			for _, above := range []float64{-20, 10, 60, 500} {
+				c := Context{Dt: 0.05, Pos: vector.Vec3{X: x, Y: y, Z: ground + above}, Vel: vector.Vec3{X: 40, Z: -3}}
+				a, errA := implicit.Apply(c)
+				b, errB := explicit.Apply(c)
+				if errA != nil || errB != nil || !reflect.DeepEqual(a, b) {
+					t.Fatalf("at %v: %+v, %v without a provider, %+v, %v with the default", c.Pos, a, errA, b, errB)
+				}
+			}
+		}
+	}
+	if implicit.MaxGroundAltitude() != 150 || explicit.MaxGroundAltitude() != 150 {
+		t.Errorf("MaxGroundAltitude %g and %g, want 150", implicit.MaxGroundAltitude(), explicit.MaxGroundAltitude())
+	}
+}
+
+func TestTerrainApply(t *testing.T) {
+	flat := Terrain{SafetyMarginM: 50, Provider: FlatTerrain(300)}
+	for _, c := range []struct {
+		name    string
+		terrain Terrain
+		alt, vz float64
+		wantAlt float64
+		wantVz  float64
+		code    string
+	}{
+		{"well above", flat, 1000, -5, 1000, -5, ""},
+		{"within twice the margin", flat, 380, -5, 380, -5, WarnTerrainProximity},
+		{"below the margin", flat, 330, -5, 330, -4.5, WarnTerrainFloor},
+		{"recovering", flat, 330, 9.9, 330, 10, WarnTerrainFloor},
+		{"climbing faster", flat, 330, 15, 330, 15, WarnTerrainFloor},
+		{"below the surface", flat, 290, -5, 300, 0, WarnTerrainContact},
+		{"below the surface climbing", flat, 290, 3, 300, 3, WarnTerrainContact},
+		{"recovery rate", Terrain{SafetyMarginM: 50, RecoveryRateMps: 2, Provider: FlatTerrain(300)}, 330, 1.9, 330, 2, WarnTerrainFloor},
+		{"custom provider", Terrain{SafetyMarginM: 10, Provider: slopedSurface{}}, 95, 0, 100, 0, WarnTerrainContact},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := c.terrain.Apply(Context{Dt: 0.05, Pos: vector.Vec3{X: 1000, Z: c.alt}, Vel: vector.Vec3{Y: 30, Z: c.vz}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(res.Pos.Z-c.wantAlt) > 1e-9 || math.Abs(res.Vel.Z-c.wantVz) > 1e-9 {
+				t.Errorf("alt %g, vz %g, want %g, %g", res.Pos.Z, res.Vel.Z, c.wantAlt, c.wantVz)
+			}
+			if res.Vel.Y != 30 {
+				t.Errorf("horizontal velocity changed to %v", res.Vel)
+			}
+			var codes []string
+			for _, w := range res.Warnings {
+				codes = append(codes, w.Code)
+			}
+			if c.code == "" && len(codes) != 0 || c.code != "" && (len(codes) != 1 || codes[0] != c.code) {
+				t.Errorf("warnings %v, want %q", codes, c.code)
+			}
+		})
+	}
+}
+
+func TestTerrainBounds(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		terrain       Terrain
+		maxGround     float64
+		maxFloor      float64
+		floorBounded  bool
+		groundAtX1000 float64
+	}{
+		{"flat", Terrain{SafetyMarginM: 50, Provider: FlatTerrain(300)}, 300, 350, true, 300},
+		{"synthetic", Terrain{SafetyMarginM: 20, Provider: SyntheticTerrain{BaseM: 40, AmplitudeM: 10, ScaleM: 100}}, 50, 70, true, 40 + 10*math.Sin(10)},
+		{"unbounded provider", Terrain{SafetyMarginM: 10, Provider: slopedSurface{}}, math.Inf(1), math.Inf(1), false, 100},
+	} {
+		if got := c.terrain.MaxGroundAltitude(); got != c.maxGround {
+			t.Errorf("%s: MaxGroundAltitude %g, want %g", c.name, got, c.maxGround)
+		}
+		chain := &Chain{Effects: []Environment{Calm(), c.terrain}}
+		floor, ok := MaxMinAltitude(chain)
+		if ok != c.floorBounded || ok && floor != c.maxFloor {
+			t.Errorf("%s: MaxMinAltitude %g, %v, want %g, %v", c.name, floor, ok, c.maxFloor, c.floorBounded)
+		}
+		pos := vector.Vec3{X: 1000}
+		if got, ok := GroundAltitude(chain, pos); !ok || math.Abs(got-c.groundAtX1000) > 1e-9 {
+			t.Errorf("%s: GroundAltitude %g, %v, want %g", c.name, got, ok, c.groundAtX1000)
+		}
+		if found, ok := FindTerrain(chain); !ok || found.SafetyMarginM != c.terrain.SafetyMarginM {
+			t.Errorf("%s: FindTerrain %+v, %v", c.name, found, ok)
+		}
+	}
+	if _, ok := FindTerrain(&Chain{Effects: []Environment{Calm()}}); ok {
+		t.Errorf("FindTerrain found terrain in a calm chain")
+	}
+}
+
+func TestSlopeMatchesAnalytical(t *testing.T) {
+	s := SyntheticTerrain{BaseM: 200, AmplitudeM: 100, ScaleM: 1000, RidgeAmplitudeM: 50, RidgeScaleM: 500}
+	// the gradient of A·sin(x/S) + R·sin((x+y)/Rs)
+	analytical := func(p vector.Vec3) float64 {
+		ridge := s.RidgeAmplitudeM / s.RidgeScaleM * math.Cos((p.X+p.Y)/s.RidgeScaleM)
+		gx := s.AmplitudeM/s.ScaleM*math.Cos(p.X/s.ScaleM) + ridge
+		return math.Atan(math.Hypot(gx, ridge)) * 180 / math.Pi
+	}
+	for _, c := range []struct {
+		name     string
+		baseline float64
+		tolDeg   float64
+	}{
+		{"default baseline", 0, 1e-3},
+		{"1 m", 1, 1e-4},
+		{"50 m", 50, 0.02},
+	} {
+		terrain := Terrain{SlopeBaselineM: c.baseline, Provider: s}
+		worst := 0.0
+		for x := -2000.0; x <= 2000; x += 37 {
+			for y := -1000.0; y <= 1000; y += 53 {
+				p := vector.Vec3{X: x, Y: y}
+				worst = max(worst, math.Abs(terrain.SlopeDeg(p)-analytical(p)))
+			}
+		}
+		if worst > c.tolDeg {
+			t.Errorf("%s: finite differences off the analytical slope by up to %.5f°, want within %g°", c.name, worst, c.tolDeg)
+		}
+	}
+}
+
+func TestSlopeKnownSurfaces(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		env  Environment
+		want float64
+		ok   bool
+	}{
+		{"flat", Terrain{Provider: FlatTerrain(300)}, 0, true},
+		{"1 in 10", Terrain{Provider: slopedSurface{}}, math.Atan(0.1) * 180 / math.Pi, true},
+		{"in a chain", &Chain{Effects: []Environment{Calm(), Terrain{Provider: slopedSurface{}}}}, math.Atan(0.1) * 180 / math.Pi, true},
+		{"45°", Terrain{Provider: SyntheticTerrain{AmplitudeM: 100, ScaleM: 100}}, 45, true},
+		{"no terrain", Calm(), 0, false},
+	} {
+		got, ok := SlopeAt(c.env, vector.Vec3{X: 0, Y: 0, Z: 500})
+		if ok != c.ok || math.Abs(got-c.want) > 0.02 {
+			t.Errorf("%s: SlopeAt = %.4f, %v, want %.4f, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package env
+
+import (
+	"container/list"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Defaults of a TerrainCache whose fields are zero.
+const (
+	DefaultTerrainCacheTileM    = 1000.0
+	DefaultTerrainCacheTiles    = 64
+	DefaultTerrainCacheTileSize = 4096
+)
+
+// TerrainCacheStats counts a TerrainCache's lookups.
+type TerrainCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // tiles dropped to make room
+	Tiles     int    `json:"tiles"`
+}
+
+// TerrainCache is a TerrainProvider that remembers the answers of an
+// expensive Provider, such as a DEM, so that the floor check, the
+// look-ahead, terrain following and AGL reporting asking about the same
+// positions every tick cost one lookup. Answers are kept exactly, keyed by
+// the horizontal position (the ground must not depend on altitude), in
+// square tiles TileM metres on a side; the MaxTiles most recently used
+// tiles are kept, each with up to TileEntries answers. The last query is
+// memoised ahead of the tiles.
+//
+// The other capabilities of Provider (GeoBinder, Coverage, WaterMask,
+// TerrainWarner and MaxGroundAltitude) pass through uncached. Binding a new
+// geo reference empties the cache. TerrainCache must be used as a pointer
+// and, like the engine's environment, from one goroutine at a time.
+type TerrainCache struct {
+	Provider    TerrainProvider
+	TileM       float64
+	MaxTiles    int
+	TileEntries int
+
+	stats   TerrainCacheStats
+	tiles   map[[2]int64]*list.Element
+	lru     list.List // of *cacheTile, most recently used first
+	last    [2]float64
+	lastAlt float64
+	hasLast bool
+}
+
+// cacheTile holds the answers within one tile.
+type cacheTile struct {
+	key  [2]int64
+	alts map[[2]float64]float64
+}
+
+// NewTerrainCache wraps p with the default tile size and counts.
+func NewTerrainCache(p TerrainProvider) *TerrainCache {
+	return &TerrainCache{Provider: p}
+}
+
+// GroundAltitude returns the cached answer for pos, asking Provider on a
+// miss.
+func (c *TerrainCache) GroundAltitude(pos vector.Vec3) float64 {
+	xy := [2]float64{pos.X, pos.Y}
+	if c.hasLast && c.last == xy {
+		c.stats.Hits++
+		return c.lastAlt
+	}
+	t := c.tile(xy)
+	alt, ok := t.alts[xy]
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+		alt = c.Provider.GroundAltitude(pos)
+		if len(t.alts) < c.tileEntries() {
+			t.alts[xy] = alt
+		}
+	}
+	c.last, c.lastAlt, c.hasLast = xy, alt, true
+	return alt
+}
+
+// tile returns the tile holding xy, making it the most recently used and
+// evicting the least recently used one past MaxTiles.
+func (c *TerrainCache) tile(xy [2]float64) *cacheTile {
+	size := c.TileM
+	if size <= 0 {
+		size = DefaultTerrainCacheTileM
+	}
+	key := [2]int64{int64(math.Floor(xy[0] / size)), int64(math.Floor(xy[1] / size))}
+	if el, ok := c.tiles[key]; ok {
+		c.lru.MoveToFront(el)
+		return el.Value.(*cacheTile)
+	}
+	if c.tiles == nil {
+		c.tiles = map[[2]int64]*list.Element{}
+	}
+	maxTiles := c.MaxTiles
+	if maxTiles <= 0 {
+		maxTiles = DefaultTerrainCacheTiles
+	}
+	for c.lru.Len() >= maxTiles {
+		old := c.lru.Remove(c.lru.Back()).(*cacheTile)
+		delete(c.tiles, old.key)
+		c.stats.Evictions++
+	}
+	t := &cacheTile{key: key, alts: map[[2]float64]float64{}}
+	c.tiles[key] = c.lru.PushFront(t)
+	return t
+}
+
+func (c *TerrainCache) tileEntries() int {
+	if c.TileEntries <= 0 {
+		return DefaultTerrainCacheTileSize
+	}
+	return c.TileEntries
+}
+
+// Reset empties the cache, keeping the counters.
+func (c *TerrainCache) Reset() {
+	c.tiles = nil
+	c.lru.Init()
+	c.hasLast = false
+}
+
+// Stats returns the lookup counters.
+func (c *TerrainCache) Stats() TerrainCacheStats {
+	st := c.stats
+	st.Tiles = c.lru.Len()
+	return st
+}
+
+// BindGeo passes p on to Provider and empties the cache, whose positions
+// were converted with the previous reference.
+func (c *TerrainCache) BindGeo(p Projector) {
+	if b, ok := c.Provider.(GeoBinder); ok {
+		b.BindGeo(p)
+	}
+	c.Reset()
+}
+
+// Validate is Provider's.
+func (c *TerrainCache) Validate() error {
+	if v, ok := c.Provider.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Covers is Provider's coverage.
+func (c *TerrainCache) Covers(pos vector.Vec3) bool {
+	if cv, ok := c.Provider.(Coverage); ok {
+		return cv.Covers(pos)
+	}
+	return true
+}
+
+// IsWater is Provider's water mask.
+func (c *TerrainCache) IsWater(pos vector.Vec3) bool {
+	if w, ok := c.Provider.(WaterMask); ok {
+		return w.IsWater(pos)
+	}
+	return false
+}
+
+// MaxGroundAltitude is Provider's, or +Inf when it does not say.
+func (c *TerrainCache) MaxGroundAltitude() float64 {
+	if b, ok := c.Provider.(interface{ MaxGroundAltitude() float64 }); ok {
+		return b.MaxGroundAltitude()
+	}
+	return math.Inf(1)
+}
+
+// TerrainWarnings passes on Provider's warnings.
+func (c *TerrainCache) TerrainWarnings(pos vector.Vec3) []Warning {
+	if tw, ok := c.Provider.(TerrainWarner); ok {
+		return tw.TerrainWarnings(pos)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package env
+
+import (
+	"math/rand"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// countingSurface counts the lookups that reach it.
+type countingSurface struct {
+	Ground TerrainProvider
+	calls  int
+}
+
+func (c *countingSurface) GroundAltitude(pos vector.Vec3) float64 {
+	c.calls++
+	return c.Ground.GroundAltitude(pos)
+}
+
+func TestTerrainCacheMatchesProvider(t *testing.T) {
+	surface := SyntheticTerrain{AmplitudeM: 100, ScaleM: 1000, RidgeAmplitudeM: 50, RidgeScaleM: 500, NoiseAmplitudeM: 30, NoiseWavelengthM: 400, Seed: 2}
+	for _, c := range []struct {
+		name  string
+		cache *TerrainCache
+	}{
+		{"defaults", NewTerrainCache(surface)},
+		{"evicting", &TerrainCache{Provider: surface, TileM: 100, MaxTiles: 4, TileEntries: 8}},
+	} {
+		rng := rand.New(rand.NewSource(1))
+		var seen []vector.Vec3
+		for i := range 20_000 {
+			var pos vector.Vec3
+			if len(seen) > 0 && rng.Intn(2) == 0 {
+				pos = seen[rng.Intn(len(seen))] // ask again
+				pos.Z = rng.Float64() * 3000    // at any altitude
+			} else {
+				pos = vector.Vec3{X: rng.Float64()*4000 - 2000, Y: rng.Float64()*4000 - 2000}
+				seen = append(seen, pos)
+			}
+			if got, want := c.cache.GroundAltitude(pos), surface.GroundAltitude(pos); got != want {
+				t.Fatalf("%s: query %d at %v: cached %v, provider %v", c.name, i, pos, got, want)
+			}
+		}
+		st := c.cache.Stats()
+		if st.Hits == 0 || st.Misses == 0 || st.Hits+st.Misses != 20_000 {
+			t.Errorf("%s: %+v", c.name, st)
+		}
+		if c.cache.MaxTiles > 0 && (st.Evictions == 0 || st.Tiles > c.cache.MaxTiles) {
+			t.Errorf("%s: %d tiles, %d evictions", c.name, st.Tiles, st.Evictions)
+		}
+	}
+}
+
+func TestTerrainCacheSteadyFlight(t *testing.T) {
+	// the same ten points over one tile, tick after tick, reach the provider
+	// once each
+	surface := &countingSurface{Ground: DefaultSyntheticTerrain()}
+	cache := NewTerrainCache(surface)
+	for range 100 {
+		for i := range 10 {
+			cache.GroundAltitude(vector.Vec3{X: float64(i) * 40, Y: 300, Z: 1000})
+		}
+	}
+	if surface.calls != 10 {
+		t.Errorf("provider asked %d times, want 10", surface.calls)
+	}
+	if st := cache.Stats(); st.Misses != 10 || st.Hits != 990 || st.Tiles != 1 {
+		t.Errorf("%+v, want 10 misses, 990 hits in one tile", st)
+	}
+	// a new geo reference starts over
+	cache.BindGeo(nil)
+	cache.GroundAltitude(vector.Vec3{X: 0, Y: 300})
+	if surface.calls != 11 {
+		t.Errorf("provider asked %d times after BindGeo, want 11", surface.calls)
+	}
+}
+
+func BenchmarkTerrainCache(b *testing.B) {
+	// steady flight over one tile: a position per tick, and the look-ahead
+	// asking the same samples again
+	surface := SyntheticTerrain{AmplitudeM: 100, ScaleM: 1000, NoiseAmplitudeM: 30, NoiseWavelengthM: 400, Octaves: 4}
+	for _, c := range []struct {
+		name     string
+		provider TerrainProvider
+	}{
+		{"uncached", surface},
+		{"cached", NewTerrainCache(surface)},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			for i := range b.N {
+				c.provider.GroundAltitude(vector.Vec3{X: float64(i%200) * 4, Y: 500})
+			}
+		})
+	}
+}
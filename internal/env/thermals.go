@@ -0,0 +1,92 @@
+package env
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Thermal is a column of rising air centered on (CenterX, CenterY), in
+// metres east and north of the sim origin. The lift is StrengthMps at the
+// center and falls off as a Gaussian to about 5% at RadiusM; there is none
+// above TopAltM. With SinkMps set, a ring of sinking air of that peak
+// strength surrounds the column out to twice its radius.
+type Thermal struct {
+	CenterX     float64 `json:"centerX"`
+	CenterY     float64 `json:"centerY"`
+	RadiusM     float64 `json:"radiusM"`
+	StrengthMps float64 `json:"strengthMps"`
+	TopAltM     float64 `json:"topAltM"`
+	SinkMps     float64 `json:"sinkMps,omitempty"`
+}
+
+// Validate checks the column has a positive radius and finite values.
+func (t Thermal) Validate() error {
+	for _, v := range []float64{t.CenterX, t.CenterY, t.RadiusM, t.StrengthMps, t.TopAltM, t.SinkMps} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("thermal values must be finite")
+		}
+	}
+	if t.RadiusM <= 0 {
+		return fmt.Errorf("thermal radiusM must be > 0")
+	}
+	if t.SinkMps < 0 {
+		return fmt.Errorf("thermal sinkMps must be >= 0")
+	}
+	return nil
+}
+
+// verticalAt is the vertical air speed the column causes at pos.
+func (t Thermal) verticalAt(pos vector.Vec3) float64 {
+	if pos.Z > t.TopAltM {
+		return 0
+	}
+	r := math.Hypot(pos.X-t.CenterX, pos.Y-t.CenterY) / t.RadiusM
+	switch {
+	case r < 1:
+		return t.StrengthMps * math.Exp(-3*r*r)
+	case r < 2:
+		return -t.SinkMps * math.Sin(math.Pi*(r-1))
+	}
+	return 0
+}
+
+// Thermals moves the aircraft vertically with the air inside thermal
+// columns. Overlapping columns add up.
+type Thermals struct {
+	Columns []Thermal `json:"columns"`
+}
+
+// Validate checks every column.
+func (ts Thermals) Validate() error {
+	for i, t := range ts.Columns {
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("columns[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// VerticalAt is the summed vertical air speed at pos (m/s, positive up).
+func (ts Thermals) VerticalAt(pos vector.Vec3) float64 {
+	w := 0.0
+	for _, t := range ts.Columns {
+		w += t.verticalAt(pos)
+	}
+	return w
+}
+
+// WindAt reports the vertical air speed at pos.
+func (ts Thermals) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	return vector.Vec3{Z: ts.VerticalAt(pos)}, "vertical"
+}
+
+// Apply reports the vertical air speed as wind.
+func (ts Thermals) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+
+	res := Unchanged(pos, vel)
+	res.Wind.Z = ts.VerticalAt(pos)
+	return res, nil
+}
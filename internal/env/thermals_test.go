@@ -0,0 +1,61 @@
+package env
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestThermalsVertical(t *testing.T) {
+	core := Thermal{CenterX: 1000, CenterY: 0, RadiusM: 200, StrengthMps: 4, TopAltM: 2000, SinkMps: 1}
+	dry := Thermal{CenterX: 1000, CenterY: 0, RadiusM: 200, StrengthMps: 4, TopAltM: 2000}
+	for _, c := range []struct {
+		name    string
+		columns []Thermal
+		pos     vector.Vec3
+		want    float64
+	}{
+		{"centre", []Thermal{core}, vector.Vec3{X: 1000, Z: 500}, 4},
+		{"half radius", []Thermal{core}, vector.Vec3{X: 1100, Z: 500}, 4 * math.Exp(-0.75)},
+		{"edge of the column", []Thermal{core}, vector.Vec3{X: 1000, Y: 199.999, Z: 500}, 4 * math.Exp(-3)},
+		{"peak sink at 1.5 radii", []Thermal{core}, vector.Vec3{X: 700, Z: 500}, -1},
+		{"no sink ring", []Thermal{dry}, vector.Vec3{X: 700, Z: 500}, 0},
+		{"beyond the ring", []Thermal{core}, vector.Vec3{X: 1401, Z: 500}, 0},
+		{"above the top", []Thermal{core}, vector.Vec3{X: 1000, Z: 2001}, 0},
+		{"at the top", []Thermal{core}, vector.Vec3{X: 1000, Z: 2000}, 4},
+		{"overlapping columns add", []Thermal{core, {CenterX: 1000, RadiusM: 50, StrengthMps: 2, TopAltM: 3000}}, vector.Vec3{X: 1000, Z: 500}, 6},
+		{"no columns", nil, vector.Vec3{X: 1000, Z: 500}, 0},
+	} {
+		ts := Thermals{Columns: c.columns}
+		if got := ts.VerticalAt(c.pos); math.Abs(got-c.want) > 1e-3 {
+			t.Errorf("%s: vertical %.4f, want %.4f", c.name, got, c.want)
+		}
+		res, err := ts.Apply(Context{Pos: c.pos, Vel: vector.Vec3{X: 30}, Dt: 0.05})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Wind != (vector.Vec3{Z: ts.VerticalAt(c.pos)}) || res.Pos != c.pos || res.Vel != (vector.Vec3{X: 30}) {
+			t.Errorf("%s: Apply = %+v, want only a vertical wind", c.name, res)
+		}
+	}
+}
+
+func TestThermalsValidate(t *testing.T) {
+	for _, c := range []struct {
+		column Thermal
+		err    string
+	}{
+		{Thermal{RadiusM: 100, StrengthMps: 3, TopAltM: 1500}, ""},
+		{Thermal{RadiusM: 100, StrengthMps: -2, TopAltM: 1500}, ""}, // a downdraft
+		{Thermal{RadiusM: 0, StrengthMps: 3}, "radiusM must be > 0"},
+		{Thermal{RadiusM: 100, SinkMps: -1}, "sinkMps must be >= 0"},
+		{Thermal{RadiusM: 100, CenterX: math.Inf(1)}, "must be finite"},
+	} {
+		err := Thermals{Columns: []Thermal{c.column}}.Validate()
+		if c.err == "" && err != nil || c.err != "" && (err == nil || !strings.Contains(err.Error(), "columns[0]: ") || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.column, err, c.err)
+		}
+	}
+}
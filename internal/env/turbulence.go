@@ -0,0 +1,140 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// DefaultCorrelationTimeS is used when Turbulence.CorrelationTimeS is zero.
+const DefaultCorrelationTimeS = 2.0
+
+// Defaults of the height scaling of Turbulence (see Turbulence.ScaleWithAGL).
+const (
+	DefaultTurbulenceFullBelowM     = 300.0
+	DefaultTurbulenceResidualAboveM = 1000.0
+	DefaultTurbulenceResidual       = 0.1
+)
+
+// Turbulence adds a random horizontal gust to the wind. The gust is a
+// first-order Gauss–Markov process per axis: it has zero mean, a standard
+// deviation of IntensityMps and decorrelates over CorrelationTimeS. The
+// sequence depends only on Seed and the step sizes, so runs repeat exactly.
+//
+// With ScaleWithAGL the intensity follows the height above ground, as
+// mechanical turbulence does: full below FullBelowM, falling linearly to
+// Residual of it at ResidualAboveM and above (defaults
+// DefaultTurbulenceFullBelowM, DefaultTurbulenceResidualAboveM and
+// DefaultTurbulenceResidual). The height is Context.AGL, or measured against
+// Ground when set, for use outside an engine.
+//
+// Turbulence keeps state between calls and must be used as a pointer. Put it
+// after Wind in a Chain so the gust adds to the mean wind.
+type Turbulence struct {
+	IntensityMps     float64 `json:"intensityMps"`
+	CorrelationTimeS float64 `json:"correlationTimeS,omitempty"`
+	Seed             int64   `json:"seed,omitempty"`
+
+	ScaleWithAGL   bool    `json:"scaleWithAGL,omitempty"`
+	FullBelowM     float64 `json:"fullBelowM,omitempty"`
+	ResidualAboveM float64 `json:"residualAboveM,omitempty"`
+	Residual       float64 `json:"residual,omitempty"`
+	Ground         Ground  `json:"-"`
+
+	rng  *rand.Rand
+	unit vector.Vec3 // the gust at unit intensity
+	gust vector.Vec3
+}
+
+// Validate checks the intensity and correlation time are finite and not
+// negative, and that the height scaling is ordered.
+func (t *Turbulence) Validate() error {
+	for _, v := range []float64{t.IntensityMps, t.CorrelationTimeS, t.FullBelowM, t.ResidualAboveM} {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return fmt.Errorf("turbulence: intensity, correlation time and heights must be finite and >= 0")
+		}
+	}
+	if !(t.Residual >= 0 && t.Residual <= 1) {
+		return fmt.Errorf("turbulence: residual must be between 0 and 1")
+	}
+	if full, residual, _ := t.heights(); t.ScaleWithAGL && residual <= full {
+		return fmt.Errorf("turbulence: residualAboveM must be above fullBelowM")
+	}
+	return nil
+}
+
+// heights returns the height scaling with defaults filled in.
+func (t *Turbulence) heights() (full, residualAbove, residual float64) {
+	full, residualAbove, residual = t.FullBelowM, t.ResidualAboveM, t.Residual
+	if full == 0 {
+		full = DefaultTurbulenceFullBelowM
+	}
+	if residualAbove == 0 {
+		residualAbove = DefaultTurbulenceResidualAboveM
+	}
+	if residual == 0 {
+		residual = DefaultTurbulenceResidual
+	}
+	return full, residualAbove, residual
+}
+
+// Scale is the share of IntensityMps applied agl metres above ground: 1
+// without ScaleWithAGL.
+func (t *Turbulence) Scale(agl float64) float64 {
+	if !t.ScaleWithAGL {
+		return 1
+	}
+	full, residualAbove, residual := t.heights()
+	switch {
+	case agl <= full:
+		return 1
+	case agl >= residualAbove:
+		return residual
+	}
+	return 1 - (1-residual)*(agl-full)/(residualAbove-full)
+}
+
+// Apply advances the gust by dt and reports it as wind. A zero intensity
+// leaves everything untouched.
+func (t *Turbulence) Apply(c Context) (Result, error) {
+	dt, pos, vel := c.Dt, c.Pos, c.Vel
+
+	res := Unchanged(pos, vel)
+	if t.IntensityMps <= 0 || dt <= 0 {
+		return res, nil
+	}
+	tau := t.CorrelationTimeS
+	if tau <= 0 {
+		tau = DefaultCorrelationTimeS
+	}
+	if t.rng == nil {
+		t.rng = rand.New(rand.NewSource(t.Seed))
+		// start in the stationary distribution rather than at calm
+		t.unit = vector.Vec3{X: t.rng.NormFloat64(), Y: t.rng.NormFloat64()}
+	}
+
+	a := math.Exp(-dt / tau)
+	s := math.Sqrt(1 - a*a)
+	t.unit.X = a*t.unit.X + s*t.rng.NormFloat64()
+	t.unit.Y = a*t.unit.Y + s*t.rng.NormFloat64()
+
+	agl := c.AGL
+	if t.Ground != nil {
+		agl = pos.Z - t.Ground.GroundAltitude(pos)
+	}
+	t.gust = t.unit.Mul(t.IntensityMps * t.Scale(agl))
+	res.Wind = t.gust
+	return res, nil
+}
+
+// Gust returns the current gust velocity (m/s east/north).
+func (t *Turbulence) Gust() vector.Vec3 {
+	return t.gust
+}
+
+// WindAt reports the gust of the last step; it is the same everywhere.
+func (t *Turbulence) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	return t.gust, "gust"
+}
@@ -0,0 +1,164 @@
+package env
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// gusts applies t n times with steps of dt and returns the winds.
+func gusts(t *Turbulence, n int, dt float64) []vector.Vec3 {
+	out := make([]vector.Vec3, n)
+	for i := range out {
+		res, _ := t.Apply(Context{Dt: dt, Pos: vector.Vec3{Z: 1000}, AGL: 1000})
+		out[i] = res.Wind
+	}
+	return out
+}
+
+func TestTurbulenceStatistics(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		turb      Turbulence
+		dt        float64
+		wantSigma float64
+		wantCorr  float64 // autocorrelation at one correlation time
+	}{
+		{"default correlation", Turbulence{IntensityMps: 3, Seed: 1}, 0.05, 3, math.Exp(-1)},
+		{"long correlation", Turbulence{IntensityMps: 1.5, CorrelationTimeS: 5, Seed: 2}, 0.1, 1.5, math.Exp(-1)},
+		{"coarse steps", Turbulence{IntensityMps: 2, CorrelationTimeS: 1, Seed: 3}, 0.5, 2, math.Exp(-1)},
+		{"calm", Turbulence{Seed: 4}, 0.05, 0, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			turb := c.turb
+			const n = 200_000
+			ws := gusts(&turb, n, c.dt)
+			var sq, vert float64
+			for _, w := range ws {
+				sq += w.X*w.X + w.Y*w.Y
+				vert += math.Abs(w.Z)
+			}
+			sigma := math.Sqrt(sq / (2 * n))
+			if math.Abs(sigma-c.wantSigma) > 0.05*c.wantSigma+1e-12 {
+				t.Errorf("gust sigma %.3f, want %g", sigma, c.wantSigma)
+			}
+			if vert != 0 {
+				t.Errorf("turbulence added a vertical gust")
+			}
+			if c.wantSigma == 0 {
+				return
+			}
+			tau := c.turb.CorrelationTimeS
+			if tau == 0 {
+				tau = DefaultCorrelationTimeS
+			}
+			lag := int(math.Round(tau / c.dt))
+			var cov float64
+			for i := lag; i < n; i++ {
+				cov += ws[i].X*ws[i-lag].X + ws[i].Y*ws[i-lag].Y
+			}
+			corr := cov / float64(2*(n-lag)) / (sigma * sigma)
+			if math.Abs(corr-c.wantCorr) > 0.05 {
+				t.Errorf("autocorrelation after %g s is %.3f, want %.3f", tau, corr, c.wantCorr)
+			}
+		})
+	}
+}
+
+func TestTurbulenceSeeded(t *testing.T) {
+	a, b, c := &Turbulence{IntensityMps: 2, Seed: 9}, &Turbulence{IntensityMps: 2, Seed: 9}, &Turbulence{IntensityMps: 2, Seed: 10}
+	wa, wb, wc := gusts(a, 100, 0.05), gusts(b, 100, 0.05), gusts(c, 100, 0.05)
+	for i := range wa {
+		if wa[i] != wb[i] {
+			t.Fatalf("step %d: %v and %v from the same seed", i, wa[i], wb[i])
+		}
+	}
+	if wa[0] == wc[0] {
+		t.Errorf("seeds 9 and 10 gave the same gust %v", wa[0])
+	}
+	if a.Gust() != wa[len(wa)-1] {
+		t.Errorf("Gust %v, want the last step's %v", a.Gust(), wa[len(wa)-1])
+	}
+}
+
+func TestTurbulenceValidate(t *testing.T) {
+	for _, c := range []struct {
+		turb Turbulence
+		err  string
+	}{
+		{Turbulence{IntensityMps: 2}, ""},
+		{Turbulence{IntensityMps: -1}, "finite and >= 0"},
+		{Turbulence{IntensityMps: 1, CorrelationTimeS: math.NaN()}, "finite and >= 0"},
+		{Turbulence{IntensityMps: 1, Residual: 1.5}, "between 0 and 1"},
+		{Turbulence{IntensityMps: 1, ScaleWithAGL: true, FullBelowM: 800, ResidualAboveM: 500}, "must be above fullBelowM"},
+	} {
+		err := c.turb.Validate()
+		if c.err == "" && err != nil || c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.turb, err, c.err)
+		}
+	}
+}
+
+func TestTurbulenceScale(t *testing.T) {
+	scaled := &Turbulence{ScaleWithAGL: true}
+	custom := &Turbulence{ScaleWithAGL: true, FullBelowM: 100, ResidualAboveM: 500, Residual: 0.5}
+	for _, c := range []struct {
+		name string
+		turb *Turbulence
+		agl  float64
+		want float64
+	}{
+		{"unscaled", &Turbulence{}, 5000, 1},
+		{"on the ground", scaled, 0, 1},
+		{"full", scaled, 300, 1},
+		{"halfway", scaled, 650, 0.55},
+		{"residual", scaled, 1000, DefaultTurbulenceResidual},
+		{"far above", scaled, 2000, DefaultTurbulenceResidual},
+		{"custom full", custom, 100, 1},
+		{"custom halfway", custom, 300, 0.75},
+		{"custom residual", custom, 800, 0.5},
+	} {
+		if got := c.turb.Scale(c.agl); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("%s: Scale(%g) = %g, want %g", c.name, c.agl, got, c.want)
+		}
+	}
+}
+
+func TestTurbulenceStrongerNearGround(t *testing.T) {
+	// the same seed at 50 m and 2000 m above a 400 m surface, the height
+	// taken from the context or from Ground
+	variance := func(turb *Turbulence, c Context) float64 {
+		var sq float64
+		const n = 20_000
+		for range n {
+			res, err := turb.Apply(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sq += res.Wind.X*res.Wind.X + res.Wind.Y*res.Wind.Y
+		}
+		return sq / (2 * n)
+	}
+	ground := FlatTerrain(400)
+	for _, c := range []struct {
+		name     string
+		ground   Ground
+		low, top Context
+	}{
+		{"context AGL", nil, Context{Dt: 0.05, Pos: vector.Vec3{Z: 450}, AGL: 50}, Context{Dt: 0.05, Pos: vector.Vec3{Z: 2400}, AGL: 2000}},
+		{"ground", ground, Context{Dt: 0.05, Pos: vector.Vec3{Z: 450}}, Context{Dt: 0.05, Pos: vector.Vec3{Z: 2400}}},
+	} {
+		low := variance(&Turbulence{IntensityMps: 3, Seed: 5, ScaleWithAGL: true, Ground: c.ground}, c.low)
+		top := variance(&Turbulence{IntensityMps: 3, Seed: 5, ScaleWithAGL: true, Ground: c.ground}, c.top)
+		// the same draws, scaled by 1 and by the residual
+		if math.Abs(top/low-DefaultTurbulenceResidual*DefaultTurbulenceResidual) > 1e-9 {
+			t.Errorf("%s: variance %.4f at 50 m and %.4f at 2000 m, want a ratio of %g", c.name, low, top,
+				DefaultTurbulenceResidual*DefaultTurbulenceResidual)
+		}
+		if math.Abs(low-9) > 0.9 {
+			t.Errorf("%s: variance %.3f at 50 m, want the full 9", c.name, low)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package env
+
+import "strings"
+
+// Severity ranks a Warning.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityCaution Severity = "caution"
+	SeverityWarning Severity = "warning"
+)
+
+// Warning is a condition reported by an environment effect or the engine.
+// Code is stable and meant for programs; Message is for humans.
+type Warning struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// String formats the warning as "code: message", or just the code.
+func (w Warning) String() string {
+	if w.Message == "" {
+		return w.Code
+	}
+	return w.Code + ": " + w.Message
+}
+
+// Dedup returns ws without repeats of an identical warning, keeping the
+// first of each in order.
+func Dedup(ws []Warning) []Warning {
+	var out []Warning
+	seen := make(map[Warning]bool, len(ws))
+	for _, w := range ws {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Summary joins warnings into a single "; "-separated line.
+func Summary(ws []Warning) string {
+	parts := make([]string, len(ws))
+	for i, w := range ws {
+		parts[i] = w.String()
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,119 @@
+package env
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnDitched is the code of the warning raised when the aircraft comes down
+// on water; the engine then stays put until it is reset.
+const WarnDitched = "ditched"
+
+// WaterMask is implemented by terrain providers, and effects such as
+// Terrain, that know where the surface is water.
+type WaterMask interface {
+	IsWater(pos vector.Vec3) bool
+}
+
+// WaterAt reports whether any effect of e with a WaterMask, looking inside
+// chains, has water at pos.
+func WaterAt(e Environment, pos vector.Vec3) bool {
+	switch f := e.(type) {
+	case *Chain:
+		for _, effect := range f.Effects {
+			if WaterAt(effect, pos) {
+				return true
+			}
+		}
+	case WaterMask:
+		return f.IsWater(pos)
+	}
+	return false
+}
+
+// WaterSurface is a TerrainProvider that adds water to Land: positions the
+// Mask marks as water, or outside the mask where Land is at or below
+// ThresholdM, are water. There the ground is the water's surface, SeaLevelM
+// or Land's elevation if higher (a lake), rather than the bathymetry.
+//
+// Mask is a grid of posts read like an elevation model (see OpenDEM), 1 for
+// water and 0 for land; a position is water where the interpolated value is
+// at least a half. WaterSurface must be used as a pointer.
+type WaterSurface struct {
+	Land       TerrainProvider
+	ThresholdM float64
+	SeaLevelM  float64
+	Mask       *DEM
+}
+
+// Validate checks Land is set, and valid when it can say, and the levels
+// are finite.
+func (w *WaterSurface) Validate() error {
+	if w.Land == nil {
+		return fmt.Errorf("water: no land provider")
+	}
+	if math.IsNaN(w.ThresholdM) || math.IsInf(w.ThresholdM, 0) || math.IsNaN(w.SeaLevelM) || math.IsInf(w.SeaLevelM, 0) {
+		return fmt.Errorf("water: threshold and sea level must be finite")
+	}
+	if v, ok := w.Land.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// BindGeo passes p on to Land and the mask.
+func (w *WaterSurface) BindGeo(p Projector) {
+	if b, ok := w.Land.(GeoBinder); ok {
+		b.BindGeo(p)
+	}
+	if w.Mask != nil {
+		w.Mask.BindGeo(p)
+	}
+}
+
+// IsWater reports whether the surface under pos is water.
+func (w *WaterSurface) IsWater(pos vector.Vec3) bool {
+	if w.Mask != nil {
+		if _, lat, lon, ok := w.Mask.sample(pos); ok {
+			v, _ := w.Mask.ElevationAt(lat, lon)
+			return v >= 0.5
+		}
+	}
+	return w.Land.GroundAltitude(pos) <= w.ThresholdM
+}
+
+// GroundAltitude is Land's elevation, raised to SeaLevelM over water.
+func (w *WaterSurface) GroundAltitude(pos vector.Vec3) float64 {
+	elev := w.Land.GroundAltitude(pos)
+	if w.IsWater(pos) {
+		return max(elev, w.SeaLevelM)
+	}
+	return elev
+}
+
+// Covers is Land's coverage.
+func (w *WaterSurface) Covers(pos vector.Vec3) bool {
+	if c, ok := w.Land.(Coverage); ok {
+		return c.Covers(pos)
+	}
+	return true
+}
+
+// MaxGroundAltitude is Land's, or SeaLevelM if that is higher; +Inf when
+// Land does not say.
+func (w *WaterSurface) MaxGroundAltitude() float64 {
+	if b, ok := w.Land.(interface{ MaxGroundAltitude() float64 }); ok {
+		return max(b.MaxGroundAltitude(), w.SeaLevelM)
+	}
+	return math.Inf(1)
+}
+
+// TerrainWarnings passes on Land's warnings.
+func (w *WaterSurface) TerrainWarnings(pos vector.Vec3) []Warning {
+	if tw, ok := w.Land.(TerrainWarner); ok {
+		return tw.TerrainWarnings(pos)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Weather warning codes.
+const (
+	WarnWeatherCell        = "weather-cell"        // inside a storm cell
+	WarnWeatherApproaching = "weather-approaching" // within a cell's margin
+)
+
+// ErrWeatherCellExists is returned by Weather.Add for a duplicate ID.
+var ErrWeatherCellExists = errors.New("weather cell already exists")
+
+// DefaultWeatherMarginM is the approach margin used when WeatherCell.MarginM is zero.
+const DefaultWeatherMarginM = 1000.0
+
+// weatherCorrelationS is the correlation time of the drift inside a cell;
+// storm turbulence is choppier than the ambient kind.
+const weatherCorrelationS = 0.5
+
+// WeatherCell is a circular storm cell that drifts with (DriftVx, DriftVy)
+// from its starting center. Inside it the aircraft is thrown about by
+// random drift of TurbulenceMps (standard deviation per axis).
+type WeatherCell struct {
+	ID            string  `json:"id"`
+	Lat           float64 `json:"lat"`
+	Lon           float64 `json:"lon"`
+	RadiusM       float64 `json:"radiusM"`
+	MarginM       float64 `json:"marginM,omitempty"`
+	DriftVx       float64 `json:"driftVx,omitempty"` // m/s east
+	DriftVy       float64 `json:"driftVy,omitempty"` // m/s north
+	TurbulenceMps float64 `json:"turbulenceMps"`
+}
+
+// Validate checks the cell has an ID, a positive radius and sane values.
+func (c WeatherCell) Validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("weather cell needs an id")
+	}
+	for _, v := range []float64{c.Lat, c.Lon, c.RadiusM, c.MarginM, c.DriftVx, c.DriftVy, c.TurbulenceMps} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("weather cell %s: values must be finite", c.ID)
+		}
+	}
+	if c.Lat < -90 || c.Lat > 90 || c.Lon < -180 || c.Lon > 180 {
+		return fmt.Errorf("weather cell %s: lat/lon out of range", c.ID)
+	}
+	if c.RadiusM <= 0 {
+		return fmt.Errorf("weather cell %s: radiusM must be > 0", c.ID)
+	}
+	if c.MarginM < 0 || c.TurbulenceMps < 0 {
+		return fmt.Errorf("weather cell %s: marginM and turbulenceMps must be >= 0", c.ID)
+	}
+	return nil
+}
+
+type weatherCell struct {
+	cfg    WeatherCell
+	center vector.Vec3 // local, moves with the drift
+	gust   vector.Vec3
+}
+
+// Weather holds the storm cells and moves them every step. The cells are
+// in the local frame; callers convert WeatherCell.Lat/Lon when adding.
+//
+// Weather keeps state between calls and must be used as a pointer.
+type Weather struct {
+	cells   []*weatherCell
+	rng     *rand.Rand
+	seed    int64
+	pending []WeatherCell // placed by BindGeo
+}
+
+// NewWeather returns an empty Weather whose drift is seeded with seed.
+func NewWeather(seed int64) *Weather {
+	return &Weather{rng: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+// NewWeatherAt returns a Weather that places cells at their Lat/Lon once
+// the engine binds its geo reference (see GeoBinder), as scenarios need.
+func NewWeatherAt(seed int64, cells []WeatherCell) (*Weather, error) {
+	ids := map[string]bool{}
+	for _, c := range cells {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		if ids[c.ID] {
+			return nil, fmt.Errorf("%w: %q", ErrWeatherCellExists, c.ID)
+		}
+		ids[c.ID] = true
+	}
+	w := NewWeather(seed)
+	w.pending = cells
+	return w, nil
+}
+
+// Seed returns the seed of the storm drift.
+func (w *Weather) Seed() int64 { return w.seed }
+
+// BindGeo places the cells given to NewWeatherAt.
+func (w *Weather) BindGeo(p Projector) {
+	for _, c := range w.pending {
+		// validated by NewWeatherAt
+		_ = w.Add(c, p.GeoToLocal(c.Lat, c.Lon, 0))
+	}
+	w.pending = nil
+}
+
+// Rebase moves the cell centers to a new local frame.
+func (w *Weather) Rebase(move func(vector.Vec3) vector.Vec3) {
+	for _, c := range w.cells {
+		c.center = move(c.center)
+	}
+}
+
+// Add places c with its center at the local position center. IDs must be unique.
+func (w *Weather) Add(c WeatherCell, center vector.Vec3) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	for _, cell := range w.cells {
+		if cell.cfg.ID == c.ID {
+			return fmt.Errorf("%w: %q", ErrWeatherCellExists, c.ID)
+		}
+	}
+	if c.MarginM == 0 {
+		c.MarginM = DefaultWeatherMarginM
+	}
+	w.cells = append(w.cells, &weatherCell{cfg: c, center: vector.Vec3{X: center.X, Y: center.Y}})
+	return nil
+}
+
+// Remove deletes the cell with the given ID and reports whether it existed.
+func (w *Weather) Remove(id string) bool {
+	for i, cell := range w.cells {
+		if cell.cfg.ID == id {
+			w.cells = append(w.cells[:i], w.cells[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes all cells.
+func (w *Weather) Clear() { w.cells = nil }
+
+// Len returns the number of cells.
+func (w *Weather) Len() int { return len(w.cells) }
+
+// Cells returns the cells with their current local centers, in insertion order.
+func (w *Weather) Cells() ([]WeatherCell, []vector.Vec3) {
+	cfgs := make([]WeatherCell, 0, len(w.cells))
+	centers := make([]vector.Vec3, 0, len(w.cells))
+	for _, cell := range w.cells {
+		cfgs = append(cfgs, cell.cfg)
+		centers = append(centers, cell.center)
+	}
+	return cfgs, centers
+}
+
+// WindAt reports the current storm gusts of the cells containing pos.
+func (w *Weather) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	var sum vector.Vec3
+	for _, cell := range w.cells {
+		if cell.depth(pos) >= 0 {
+			sum = sum.Add(cell.gust)
+		}
+	}
+	return sum, "storm gusts"
+}
+
+// MembershipsAt lists the cells containing pos.
+func (w *Weather) MembershipsAt(pos vector.Vec3) []Membership {
+	var out []Membership
+	for _, cell := range w.cells {
+		if d := cell.depth(pos); d >= 0 {
+			out = append(out, Membership{Name: cell.cfg.ID, DepthM: d})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// depth is how far inside the cell pos is; negative outside.
+func (c *weatherCell) depth(pos vector.Vec3) float64 {
+	return c.cfg.RadiusM - math.Hypot(pos.X-c.center.X, pos.Y-c.center.Y)
+}
+
+// Apply moves the cells by dt, reports the storm gusts of every cell the
+// aircraft is in as wind and warns about the cells it is in or near.
+func (w *Weather) Apply(c Context) (Result, error) {
+	dt, pos, vel := c.Dt, c.Pos, c.Vel
+
+	res := Unchanged(pos, vel)
+	if len(w.cells) == 0 {
+		return res, nil
+	}
+	var inside, near []string
+	for _, cell := range w.cells {
+		cell.center = cell.center.Add(vector.Vec3{X: cell.cfg.DriftVx, Y: cell.cfg.DriftVy}.Mul(dt))
+		d := -cell.depth(pos)
+		switch {
+		case d <= 0:
+			inside = append(inside, cell.cfg.ID)
+			a := math.Exp(-dt / weatherCorrelationS)
+			s := cell.cfg.TurbulenceMps * math.Sqrt(1-a*a)
+			cell.gust.X = a*cell.gust.X + s*w.rng.NormFloat64()
+			cell.gust.Y = a*cell.gust.Y + s*w.rng.NormFloat64()
+			res.Wind = res.Wind.Add(cell.gust)
+		case d <= cell.cfg.MarginM:
+			near = append(near, fmt.Sprintf("%s (%.0f m)", cell.cfg.ID, d))
+			cell.gust = vector.Vec3{}
+		default:
+			cell.gust = vector.Vec3{}
+		}
+	}
+
+	if len(inside) > 0 {
+		sort.Strings(inside)
+		res.Warnings = append(res.Warnings, Warning{
+			Code:     WarnWeatherCell,
+			Severity: SeverityWarning,
+			Message:  "inside storm cell " + strings.Join(inside, ", "),
+		})
+	}
+	if len(near) > 0 {
+		sort.Strings(near)
+		res.Warnings = append(res.Warnings, Warning{
+			Code:     WarnWeatherApproaching,
+			Severity: SeverityCaution,
+			Message:  "approaching storm cell " + strings.Join(near, ", "),
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package env
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestWeatherWarnings(t *testing.T) {
+	storm := WeatherCell{ID: "cb1", RadiusM: 2000, MarginM: 1000, TurbulenceMps: 3}
+	drifting := storm
+	drifting.DriftVx = 20 // 1200 m east after a minute
+	for _, c := range []struct {
+		name  string
+		cell  WeatherCell
+		pos   vector.Vec3
+		steps int
+		code  string
+	}{
+		{"inside", storm, vector.Vec3{X: 1500}, 1, WarnWeatherCell},
+		{"inside the margin", storm, vector.Vec3{X: 2500}, 1, WarnWeatherApproaching},
+		{"clear", storm, vector.Vec3{X: 3500}, 1, ""},
+		{"default margin", WeatherCell{ID: "cb2", RadiusM: 2000}, vector.Vec3{Y: -2900}, 1, WarnWeatherApproaching},
+		{"drifted onto the aircraft", drifting, vector.Vec3{X: 3000}, 1200, WarnWeatherCell},
+		{"drifted away", drifting, vector.Vec3{X: -1500}, 1200, WarnWeatherApproaching},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := NewWeather(1)
+			if err := w.Add(c.cell, vector.Vec3{}); err != nil {
+				t.Fatal(err)
+			}
+			var res Result
+			for i := 0; i < c.steps; i++ {
+				var err error
+				if res, err = w.Apply(Context{Dt: 0.05, Pos: c.pos}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var codes []string
+			for _, w := range res.Warnings {
+				codes = append(codes, w.Code)
+				if !strings.Contains(w.Message, c.cell.ID) {
+					t.Errorf("warning %q does not name the cell", w.Message)
+				}
+			}
+			if want := c.code; len(codes) != 0 && codes[0] != want || len(codes) == 0 && want != "" || len(codes) > 1 {
+				t.Errorf("warnings %v, want [%s]", codes, want)
+			}
+			if inside := c.code == WarnWeatherCell; inside != (res.Wind != vector.Vec3{}) {
+				t.Errorf("gust %v inside=%v", res.Wind, inside)
+			}
+		})
+	}
+}
+
+func TestWeatherGustIntensity(t *testing.T) {
+	w := NewWeather(3)
+	if err := w.Add(WeatherCell{ID: "cb", RadiusM: 5000, TurbulenceMps: 4}, vector.Vec3{}); err != nil {
+		t.Fatal(err)
+	}
+	const n = 100_000
+	var sq float64
+	for i := 0; i < n; i++ {
+		res, _ := w.Apply(Context{Dt: 0.05})
+		sq += res.Wind.X*res.Wind.X + res.Wind.Y*res.Wind.Y
+	}
+	if sigma := math.Sqrt(sq / (2 * n)); math.Abs(sigma-4) > 0.2 {
+		t.Errorf("storm gust sigma %.2f, want 4", sigma)
+	}
+}
+
+func TestWeatherCells(t *testing.T) {
+	w := NewWeather(1)
+	cell := WeatherCell{ID: "a", RadiusM: 100}
+	if err := w.Add(cell, vector.Vec3{X: 10, Y: 20, Z: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Add(cell, vector.Vec3{}); !errors.Is(err, ErrWeatherCellExists) {
+		t.Errorf("duplicate Add = %v, want ErrWeatherCellExists", err)
+	}
+	if err := w.Add(WeatherCell{ID: "b"}, vector.Vec3{}); err == nil {
+		t.Error("Add accepted a cell without a radius")
+	}
+	cfgs, centers := w.Cells()
+	if len(cfgs) != 1 || cfgs[0].MarginM != DefaultWeatherMarginM || centers[0] != (vector.Vec3{X: 10, Y: 20}) {
+		t.Errorf("Cells = %+v at %v", cfgs, centers)
+	}
+	if !w.Remove("a") || w.Remove("a") || w.Len() != 0 {
+		t.Error("Remove did not delete the cell exactly once")
+	}
+	if _, err := NewWeatherAt(1, []WeatherCell{cell, cell}); !errors.Is(err, ErrWeatherCellExists) {
+		t.Errorf("NewWeatherAt with a duplicate = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package env
 
 import (
+	"errors"
 	"math"
 
 	"flight-simulator2/internal/geometry/vector"
@@ -10,17 +11,55 @@ import (
 // The wind is specified in meters per second in the east (Wx) and north (Wy) directions.
 type Wind struct {
 	// Wx is the eastward component of the wind in m/s (positive = east, negative = west)
-	Wx float64
+	Wx float64 `json:"wx"`
 	// Wy is the northward component of the wind in m/s (positive = north, negative = south)
-	Wy float64
+	Wy float64 `json:"wy"`
 }
 
-// Apply applies wind as a constant ground drift.
-// We modify position directly (ground track), without changing the aircraft's own velocity.
-func (w Wind) Apply(dt float64, pos vector.Vec3, vel vector.Vec3) (vector.Vec3, vector.Vec3, string) {
-	// Wind affects ground track but not the aircraft's airspeed
-	drift := vector.Vec3{X: w.Wx * dt, Y: w.Wy * dt}
-	return pos.Add(drift), vel, ""
+// Apply reports the constant wind. It affects the ground track but not
+// the aircraft's airspeed.
+func (w Wind) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+
+	res := Unchanged(pos, vel)
+	res.Wind = vector.Vec3{X: w.Wx, Y: w.Wy}
+	return res, nil
+}
+
+// WindAt reports the constant wind.
+func (w Wind) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	return vector.Vec3{X: w.Wx, Y: w.Wy}, "constant"
+}
+
+// Validate rejects non-finite components.
+func (w Wind) Validate() error {
+	for _, v := range []float64{w.Wx, w.Wy} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("wind components must be finite")
+		}
+	}
+	return nil
+}
+
+// ReplaceWind returns e with its mean wind (e itself or the first Wind,
+// WindProfile, WindField or WindSchedule of a Chain) replaced by w. Chains
+// are updated in place; when e has no mean wind, w is put in front of it.
+func ReplaceWind(e Environment, w Wind) Environment {
+	switch f := e.(type) {
+	case nil, Wind, WindProfile, *WindField, *WindSchedule:
+		return w
+	case *Chain:
+		for i, effect := range f.Effects {
+			switch effect.(type) {
+			case Wind, WindProfile, *WindField, *WindSchedule:
+				f.Effects[i] = w
+				return f
+			}
+		}
+		f.Effects = append([]Environment{w}, f.Effects...)
+		return f
+	}
+	return &Chain{Effects: []Environment{w, e}}
 }
 
 // Calm returns a Wind with zero velocity (no wind).
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestFromSpeedAndDir(t *testing.T) {
+	for _, c := range []struct {
+		speed, dir float64
+		wx, wy     float64
+	}{
+		{10, 0, 0, 10},
+		{10, 90, 10, 0},
+		{10, 180, 0, -10},
+		{10, 270, -10, 0},
+		{10, 45, 10 / math.Sqrt2, 10 / math.Sqrt2},
+		{0, 123, 0, 0},
+	} {
+		w := FromSpeedAndDir(c.speed, c.dir)
+		if math.Abs(w.Wx-c.wx) > 1e-9 || math.Abs(w.Wy-c.wy) > 1e-9 {
+			t.Errorf("FromSpeedAndDir(%g, %g) = %+v, want (%g, %g)", c.speed, c.dir, w, c.wx, c.wy)
+		}
+	}
+}
+
+func TestWindLeavesAirVelocityAlone(t *testing.T) {
+	pos, vel := vector.Vec3{X: 1, Y: 2, Z: 300}, vector.Vec3{X: 40, Y: -3, Z: 1}
+	res, err := Wind{Wx: 5, Wy: -7}.Apply(Context{Dt: 0.05, Pos: pos, Vel: vel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Pos != pos || res.Vel != vel || res.Wind != (vector.Vec3{X: 5, Y: -7}) {
+		t.Errorf("Apply = %+v, want the wind reported and the state unchanged", res)
+	}
+	if err := (Wind{Wx: math.NaN()}).Validate(); err == nil {
+		t.Error("Validate accepted a NaN component")
+	}
+}
+
+func TestReplaceWind(t *testing.T) {
+	w := Wind{Wx: 3}
+	therm := Thermals{}
+	for _, c := range []struct {
+		name string
+		in   Environment
+		want []Environment // effects of the resulting chain; nil for w alone
+	}{
+		{"nil", nil, nil},
+		{"constant", Wind{Wy: 9}, nil},
+		{"profile", WindProfile{}, nil},
+		{"other effect", therm, []Environment{w, therm}},
+		{"chain with wind", &Chain{Effects: []Environment{therm, Wind{Wy: 1}}}, []Environment{therm, w}},
+		{"chain without wind", &Chain{Effects: []Environment{therm}}, []Environment{w, therm}},
+	} {
+		got := ReplaceWind(c.in, w)
+		if c.want == nil {
+			if got != Environment(w) {
+				t.Errorf("%s: ReplaceWind = %#v, want the new wind", c.name, got)
+			}
+			continue
+		}
+		chain, ok := got.(*Chain)
+		if !ok || len(chain.Effects) != len(c.want) {
+			t.Errorf("%s: ReplaceWind = %#v", c.name, got)
+			continue
+		}
+		for i := range c.want {
+			if _, isWind := c.want[i].(Wind); isWind != (chain.Effects[i] == Environment(w)) {
+				t.Errorf("%s: effects[%d] = %#v", c.name, i, chain.Effects[i])
+			}
+		}
+	}
+}
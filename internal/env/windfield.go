@@ -0,0 +1,188 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnWindFieldEdge is the code of the warning raised the first time the
+// aircraft leaves a WindField's grid.
+const WarnWindFieldEdge = "wind-field-edge"
+
+// WindLevel is one horizontal slice of a WindField. U and V are the east
+// and north components (m/s) indexed [y][x].
+type WindLevel struct {
+	AltM float64     `json:"altM"`
+	U    [][]float64 `json:"u"`
+	V    [][]float64 `json:"v"`
+}
+
+// WindField is a gridded wind in the local frame: X and Y are the grid
+// coordinates in metres east and north of the sim origin. The wind is
+// interpolated bilinearly within a level and linearly between levels; a
+// single level makes the field 2D. Outside the grid the nearest edge value
+// applies.
+//
+// WindField keeps state between calls and must be used as a pointer.
+type WindField struct {
+	X      []float64   `json:"x"`
+	Y      []float64   `json:"y"`
+	Levels []WindLevel `json:"levels"`
+
+	warned bool
+}
+
+// LoadWindField reads a WindField from JSON and validates it.
+func LoadWindField(r io.Reader) (*WindField, error) {
+	var f WindField
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("wind field: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("wind field: %w", err)
+	}
+	return &f, nil
+}
+
+// Validate checks that the coordinates increase strictly and that every
+// level has len(Y) rows of len(X) finite values.
+func (f *WindField) Validate() error {
+	if err := increasing("x", f.X); err != nil {
+		return err
+	}
+	if err := increasing("y", f.Y); err != nil {
+		return err
+	}
+	if len(f.Levels) == 0 {
+		return fmt.Errorf("at least one level is required")
+	}
+	for k, l := range f.Levels {
+		if math.IsNaN(l.AltM) || math.IsInf(l.AltM, 0) {
+			return fmt.Errorf("levels[%d]: altM must be finite", k)
+		}
+		if k > 0 && l.AltM <= f.Levels[k-1].AltM {
+			return fmt.Errorf("levels[%d]: altM must be above the previous level's", k)
+		}
+		for _, c := range []struct {
+			name string
+			grid [][]float64
+		}{{"u", l.U}, {"v", l.V}} {
+			name, grid := c.name, c.grid
+			if len(grid) != len(f.Y) {
+				return fmt.Errorf("levels[%d].%s: %d rows, want %d (len(y))", k, name, len(grid), len(f.Y))
+			}
+			for j, row := range grid {
+				if len(row) != len(f.X) {
+					return fmt.Errorf("levels[%d].%s[%d]: %d values, want %d (len(x))", k, name, j, len(row), len(f.X))
+				}
+				for i, v := range row {
+					if math.IsNaN(v) || math.IsInf(v, 0) {
+						return fmt.Errorf("levels[%d].%s[%d][%d]: value must be finite", k, name, j, i)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func increasing(name string, xs []float64) error {
+	if len(xs) < 2 {
+		return fmt.Errorf("%s needs at least 2 coordinates", name)
+	}
+	for i, x := range xs {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Errorf("%s[%d] must be finite", name, i)
+		}
+		if i > 0 && x <= xs[i-1] {
+			return fmt.Errorf("%s must increase strictly (at %s[%d])", name, name, i)
+		}
+	}
+	return nil
+}
+
+// cell locates v in xs, returning the lower index and the fraction toward
+// the next coordinate; ok is false when v was clamped to an edge.
+func cell(xs []float64, v float64) (i int, t float64, ok bool) {
+	n := len(xs)
+	switch {
+	case v < xs[0]:
+		return 0, 0, false
+	case v > xs[n-1]:
+		return n - 2, 1, false
+	}
+	i = sort.SearchFloat64s(xs, v) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i > n-2 {
+		i = n - 2
+	}
+	return i, (v - xs[i]) / (xs[i+1] - xs[i]), true
+}
+
+func bilinear(g [][]float64, i, j int, tx, ty float64) float64 {
+	a := g[j][i] + tx*(g[j][i+1]-g[j][i])
+	b := g[j+1][i] + tx*(g[j+1][i+1]-g[j+1][i])
+	return a + ty*(b-a)
+}
+
+// sample returns the wind at pos and whether pos lies inside the grid horizontally.
+func (f *WindField) sample(pos vector.Vec3) (vector.Vec3, bool) {
+	i, tx, okX := cell(f.X, pos.X)
+	j, ty, okY := cell(f.Y, pos.Y)
+	at := func(l WindLevel) vector.Vec3 {
+		return vector.Vec3{X: bilinear(l.U, i, j, tx, ty), Y: bilinear(l.V, i, j, tx, ty)}
+	}
+
+	n := len(f.Levels)
+	var w vector.Vec3
+	switch {
+	case n == 1 || pos.Z <= f.Levels[0].AltM:
+		w = at(f.Levels[0])
+	case pos.Z >= f.Levels[n-1].AltM:
+		w = at(f.Levels[n-1])
+	default:
+		k := sort.Search(n, func(k int) bool { return f.Levels[k].AltM >= pos.Z })
+		lo, hi := f.Levels[k-1], f.Levels[k]
+		t := (pos.Z - lo.AltM) / (hi.AltM - lo.AltM)
+		a, b := at(lo), at(hi)
+		w = a.Add(b.Sub(a).Mul(t))
+	}
+	return w, okX && okY
+}
+
+// Apply reports the interpolated wind. The first time the aircraft is
+// outside the grid it returns a WarnWindFieldEdge warning.
+func (f *WindField) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+
+	w, inside := f.sample(pos)
+	res := Unchanged(pos, vel)
+	res.Wind = w
+	if !inside && !f.warned {
+		f.warned = true
+		res.Warnings = []Warning{{
+			Code:     WarnWindFieldEdge,
+			Severity: SeverityInfo,
+			Message:  "outside the wind grid, using the nearest edge values",
+		}}
+	}
+	return res, nil
+}
+
+// WindAt reports the interpolated wind at pos.
+func (f *WindField) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	w, inside := f.sample(pos)
+	if !inside {
+		return w, "outside the grid, nearest edge"
+	}
+	return w, "interpolated"
+}
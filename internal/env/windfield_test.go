@@ -0,0 +1,97 @@
+package env
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+const testWindField = `{
+  "x": [0, 1000, 2000],
+  "y": [0, 1000],
+  "levels": [
+    {"altM": 0,    "u": [[0, 10, 20], [0, 10, 20]], "v": [[0, 0, 0], [4, 4, 4]]},
+    {"altM": 2000, "u": [[10, 20, 30], [10, 20, 30]], "v": [[2, 2, 2], [6, 6, 6]]}
+  ]
+}`
+
+func TestWindFieldInterpolation(t *testing.T) {
+	f, err := LoadWindField(strings.NewReader(testWindField))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name   string
+		pos    vector.Vec3
+		u, v   float64
+		inside bool
+	}{
+		{"grid point", vector.Vec3{X: 1000, Y: 0, Z: 0}, 10, 0, true},
+		{"between columns", vector.Vec3{X: 1500, Y: 0, Z: 0}, 15, 0, true},
+		{"cell centre", vector.Vec3{X: 500, Y: 500, Z: 0}, 5, 2, true},
+		{"between levels", vector.Vec3{X: 500, Y: 500, Z: 1000}, 10, 3, true},
+		{"above the top level", vector.Vec3{X: 2000, Y: 1000, Z: 5000}, 30, 6, true},
+		{"below the bottom level", vector.Vec3{X: 0, Y: 0, Z: -50}, 0, 0, true},
+		{"west of the grid", vector.Vec3{X: -500, Y: 1000, Z: 0}, 0, 4, false},
+		{"north-east of the grid", vector.Vec3{X: 9000, Y: 9000, Z: 2000}, 30, 6, false},
+	} {
+		w, how := f.WindAt(c.pos)
+		if math.Abs(w.X-c.u) > 1e-9 || math.Abs(w.Y-c.v) > 1e-9 || w.Z != 0 {
+			t.Errorf("%s: wind %v, want (%g, %g, 0)", c.name, w, c.u, c.v)
+		}
+		if inside := how == "interpolated"; inside != c.inside {
+			t.Errorf("%s: %q, want inside %v", c.name, how, c.inside)
+		}
+	}
+}
+
+func TestWindFieldEdgeWarnsOnce(t *testing.T) {
+	f, err := LoadWindField(strings.NewReader(testWindField))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warned []int
+	for i, x := range []float64{500, 3000, 3500, 500, 4000} {
+		res, err := f.Apply(Context{Pos: vector.Vec3{X: x, Y: 500}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Warnings) > 0 {
+			if res.Warnings[0].Code != WarnWindFieldEdge {
+				t.Fatalf("warning %v", res.Warnings[0])
+			}
+			warned = append(warned, i)
+		}
+	}
+	if len(warned) != 1 || warned[0] != 1 {
+		t.Errorf("edge warnings at steps %v, want only the first step outside (1)", warned)
+	}
+}
+
+func TestLoadWindFieldRejects(t *testing.T) {
+	for _, c := range []struct {
+		name, json, err string
+	}{
+		{"not json", `{"x": [0, 1]`, "unexpected EOF"},
+		{"unknown field", `{"x": [0, 1], "y": [0, 1], "levels": [], "z": 1}`, "unknown field"},
+		{"one column", `{"x": [0], "y": [0, 1], "levels": [{"u": [[0], [0]], "v": [[0], [0]]}]}`, "x needs at least 2"},
+		{"decreasing y", `{"x": [0, 1], "y": [1, 0], "levels": [{"u": [[0, 0], [0, 0]], "v": [[0, 0], [0, 0]]}]}`, "y must increase"},
+		{"no levels", `{"x": [0, 1], "y": [0, 1], "levels": []}`, "at least one level"},
+		{"short row", `{"x": [0, 1], "y": [0, 1], "levels": [{"u": [[0, 0], [0]], "v": [[0, 0], [0, 0]]}]}`, "levels[0].u[1]: 1 values"},
+		{"missing row", `{"x": [0, 1], "y": [0, 1], "levels": [{"u": [[0, 0], [0, 0]], "v": [[0, 0]]}]}`, "levels[0].v: 1 rows"},
+		{
+			"levels out of order",
+			`{"x": [0, 1], "y": [0, 1], "levels": [
+			  {"altM": 500, "u": [[0, 0], [0, 0]], "v": [[0, 0], [0, 0]]},
+			  {"altM": 100, "u": [[0, 0], [0, 0]], "v": [[0, 0], [0, 0]]}]}`,
+			"levels[1]: altM must be above",
+		},
+	} {
+		_, err := LoadWindField(strings.NewReader(c.json))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: LoadWindField = %v, want an error containing %q", c.name, err, c.err)
+		}
+	}
+}
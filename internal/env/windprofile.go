@@ -0,0 +1,118 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WindLayer is the wind at the top of an altitude band.
+type WindLayer struct {
+	TopAltM float64 `json:"topAltM"`
+	Wx      float64 `json:"wx"` // m/s east
+	Wy      float64 `json:"wy"` // m/s north
+}
+
+// WindProfile is a wind that changes with altitude. Each layer gives the
+// wind at its TopAltM; between two tops the components are interpolated
+// linearly. Below the first top the first layer applies, above the last
+// top the last one, and an empty profile is calm.
+type WindProfile struct {
+	Layers []WindLayer `json:"layers"`
+}
+
+// Validate requires strictly increasing, finite layer tops and finite winds.
+func (p WindProfile) Validate() error {
+	for i, l := range p.Layers {
+		for _, v := range []float64{l.TopAltM, l.Wx, l.Wy} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("layers[%d]: values must be finite", i)
+			}
+		}
+		if i > 0 && l.TopAltM <= p.Layers[i-1].TopAltM {
+			return fmt.Errorf("layers[%d]: topAltM must be above the previous layer's", i)
+		}
+	}
+	return nil
+}
+
+// layer returns the index of the band containing z, i.e. the first layer
+// whose top is at or above it (the last one above all tops).
+func (p WindProfile) layer(z float64) int {
+	for i, l := range p.Layers {
+		if z <= l.TopAltM {
+			return i
+		}
+	}
+	return len(p.Layers) - 1
+}
+
+// At returns the wind at altitude z.
+func (p WindProfile) At(z float64) vector.Vec3 {
+	n := len(p.Layers)
+	switch {
+	case n == 0:
+		return vector.Vec3{}
+	case z <= p.Layers[0].TopAltM:
+		return vector.Vec3{X: p.Layers[0].Wx, Y: p.Layers[0].Wy}
+	case z >= p.Layers[n-1].TopAltM:
+		return vector.Vec3{X: p.Layers[n-1].Wx, Y: p.Layers[n-1].Wy}
+	}
+	i := p.layer(z)
+	lo, hi := p.Layers[i-1], p.Layers[i]
+	t := (z - lo.TopAltM) / (hi.TopAltM - lo.TopAltM)
+	return vector.Vec3{
+		X: lo.Wx + t*(hi.Wx-lo.Wx),
+		Y: lo.Wy + t*(hi.Wy-lo.Wy),
+	}
+}
+
+// Apply reports the wind at the aircraft's altitude.
+func (p WindProfile) Apply(c Context) (Result, error) {
+	pos, vel := c.Pos, c.Vel
+
+	res := Unchanged(pos, vel)
+	res.Wind = p.At(pos.Z)
+	return res, nil
+}
+
+// WindAt reports the interpolated wind and the band the altitude is in.
+func (p WindProfile) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	if len(p.Layers) == 0 {
+		return vector.Vec3{}, "empty profile"
+	}
+	i := p.layer(pos.Z)
+	bottom := "surface"
+	if i > 0 {
+		bottom = fmt.Sprintf("%.0f m", p.Layers[i-1].TopAltM)
+	}
+	return p.At(pos.Z), fmt.Sprintf("layer %d of %d (%s to %.0f m)", i+1, len(p.Layers), bottom, p.Layers[i].TopAltM)
+}
+
+// ParseWindProfile reads layers written as "TOP:SPEED@DIR,..." with the top
+// in metres, the speed in m/s and the direction in degrees as taken by
+// FromSpeedAndDir, e.g. "500:3@90,2000:12@270".
+func ParseWindProfile(s string) (WindProfile, error) {
+	var p WindProfile
+	for i, part := range strings.Split(s, ",") {
+		top, wind, ok := strings.Cut(strings.TrimSpace(part), ":")
+		speed, dir, ok2 := strings.Cut(wind, "@")
+		if !ok || !ok2 {
+			return WindProfile{}, fmt.Errorf("layer %d: %q is not TOP:SPEED@DIR", i+1, part)
+		}
+		var vals [3]float64
+		for j, f := range []string{top, speed, dir} {
+			v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil {
+				return WindProfile{}, fmt.Errorf("layer %d: bad number %q", i+1, f)
+			}
+			vals[j] = v
+		}
+		w := FromSpeedAndDir(vals[1], vals[2])
+		p.Layers = append(p.Layers, WindLayer{TopAltM: vals[0], Wx: w.Wx, Wy: w.Wy})
+	}
+	return p, p.Validate()
+}
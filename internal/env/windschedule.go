@@ -0,0 +1,76 @@
+package env
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WindStep is one entry of a WindSchedule: from AtS seconds after the start
+// the wind is (Wx, Wy) m/s east/north.
+type WindStep struct {
+	AtS float64 `json:"atS"`
+	Wx  float64 `json:"wx"`
+	Wy  float64 `json:"wy"`
+}
+
+// WindSchedule is a constant wind that changes at set times. Time counts
+// from the first step the schedule is applied to; before the first entry's
+// AtS its wind applies already.
+//
+// WindSchedule keeps the start time between calls and must be used as a pointer.
+type WindSchedule struct {
+	Steps []WindStep `json:"steps"`
+
+	start   time.Time
+	current int
+}
+
+// Validate checks there is at least one step and that the times increase.
+func (s *WindSchedule) Validate() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("wind schedule needs at least one step")
+	}
+	for i, st := range s.Steps {
+		for _, v := range []float64{st.AtS, st.Wx, st.Wy} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("steps[%d]: values must be finite", i)
+			}
+		}
+		if st.AtS < 0 {
+			return fmt.Errorf("steps[%d]: atS must be >= 0", i)
+		}
+		if i > 0 && st.AtS <= s.Steps[i-1].AtS {
+			return fmt.Errorf("steps[%d]: atS must be after the previous step's", i)
+		}
+	}
+	return nil
+}
+
+// Apply reports the wind of the step due at the end of this one.
+func (s *WindSchedule) Apply(c Context) (Result, error) {
+	res := Unchanged(c.Pos, c.Vel)
+	if len(s.Steps) == 0 {
+		return res, nil
+	}
+	if s.start.IsZero() {
+		s.start = c.SimTime.Add(-time.Duration(c.Dt * float64(time.Second)))
+	}
+	elapsed := c.SimTime.Sub(s.start).Seconds()
+	for s.current+1 < len(s.Steps) && s.Steps[s.current+1].AtS <= elapsed {
+		s.current++
+	}
+	res.Wind, _ = s.WindAt(c.Pos)
+	return res, nil
+}
+
+// WindAt reports the wind of the current step.
+func (s *WindSchedule) WindAt(pos vector.Vec3) (vector.Vec3, string) {
+	if len(s.Steps) == 0 {
+		return vector.Vec3{}, "empty"
+	}
+	st := s.Steps[s.current]
+	return vector.Vec3{X: st.Wx, Y: st.Wy}, fmt.Sprintf("step %d of %d", s.current+1, len(s.Steps))
+}
@@ -0,0 +1,125 @@
+package vector
+
+import "math"
+
+// Quat is a rotation quaternion W + Xi + Yj + Zk. Rotations are unit
+// quaternions; the zero Quat is not one, use IdentityQuat.
+type Quat struct {
+	W float64 `json:"w"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// IdentityQuat returns the rotation that leaves every vector unchanged
+func IdentityQuat() Quat { return Quat{W: 1} }
+
+// QuatFromAxisAngle returns the rotation by rad radians about axis,
+// counter-clockwise looking down the axis; a zero axis gives the identity
+func QuatFromAxisAngle(axis Vec3, rad float64) Quat {
+	n := axis.Normalize()
+	if n == (Vec3{}) {
+		return IdentityQuat()
+	}
+	s, c := math.Sincos(rad / 2)
+	return Quat{W: c, X: n.X * s, Y: n.Y * s, Z: n.Z * s}
+}
+
+// QuatFromEuler returns the rotation of the Tait-Bryan angles (radians)
+// applied yaw about Z first, then pitch about the new Y, then roll about
+// the new X (the aerospace Z-Y-X convention)
+func QuatFromEuler(roll, pitch, yaw float64) Quat {
+	sr, cr := math.Sincos(roll / 2)
+	sp, cp := math.Sincos(pitch / 2)
+	sy, cy := math.Sincos(yaw / 2)
+	return Quat{
+		W: cr*cp*cy + sr*sp*sy,
+		X: sr*cp*cy - cr*sp*sy,
+		Y: cr*sp*cy + sr*cp*sy,
+		Z: cr*cp*sy - sr*sp*cy,
+	}
+}
+
+// ToEuler returns the Z-Y-X angles (radians) of a unit quaternion: roll and
+// yaw in (-π, π], pitch in [-π/2, π/2]. At ±π/2 pitch roll and yaw share
+// one axis; the rotation is then reported with a zero roll.
+func (q Quat) ToEuler() (roll, pitch, yaw float64) {
+	sinp := 2 * (q.W*q.Y - q.Z*q.X)
+	if math.Abs(sinp) >= 1-1e-12 {
+		pitch = math.Copysign(math.Pi/2, sinp)
+		yaw = -2 * math.Atan2(q.X, q.W) * math.Copysign(1, sinp)
+		return 0, pitch, math.Remainder(yaw, 2*math.Pi)
+	}
+	roll = math.Atan2(2*(q.W*q.X+q.Y*q.Z), 1-2*(q.X*q.X+q.Y*q.Y))
+	pitch = math.Asin(sinp)
+	yaw = math.Atan2(2*(q.W*q.Z+q.X*q.Y), 1-2*(q.Y*q.Y+q.Z*q.Z))
+	return roll, pitch, yaw
+}
+
+// Mul returns the Hamilton product q·o: the rotation o followed by q
+func (q Quat) Mul(o Quat) Quat {
+	return Quat{
+		W: q.W*o.W - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+		X: q.W*o.X + q.X*o.W + q.Y*o.Z - q.Z*o.Y,
+		Y: q.W*o.Y - q.X*o.Z + q.Y*o.W + q.Z*o.X,
+		Z: q.W*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.W,
+	}
+}
+
+// Conj returns the conjugate, the inverse of a unit quaternion
+func (q Quat) Conj() Quat { return Quat{W: q.W, X: -q.X, Y: -q.Y, Z: -q.Z} }
+
+// Dot returns the four-dimensional dot product
+func (q Quat) Dot(o Quat) float64 { return q.W*o.W + q.X*o.X + q.Y*o.Y + q.Z*o.Z }
+
+// Length returns the quaternion's magnitude
+func (q Quat) Length() float64 {
+	return math.Hypot(math.Hypot(q.W, q.X), math.Hypot(q.Y, q.Z))
+}
+
+// Normalize returns the unit quaternion in the same direction, or the
+// identity for a zero one
+func (q Quat) Normalize() Quat {
+	n := q.Length()
+	if n == 0 {
+		return IdentityQuat()
+	}
+	return Quat{W: q.W / n, X: q.X / n, Y: q.Y / n, Z: q.Z / n}
+}
+
+// RotateVec rotates v by the unit quaternion q
+func (q Quat) RotateVec(v Vec3) Vec3 {
+	// v + 2u×(u×v + w·v), with u the vector part
+	u := Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Add(v.Mul(q.W))
+	return v.Add(u.Cross(t).Mul(2))
+}
+
+// Slerp interpolates along the shorter arc from q (t=0) to o (t=1) at a
+// constant angular rate. The endpoints are returned exactly; in between
+// the result is normalised.
+func (q Quat) Slerp(o Quat, t float64) Quat {
+	switch t {
+	case 0:
+		return q
+	case 1:
+		return o
+	}
+	d := q.Dot(o)
+	if d < 0 {
+		// q and -q are the same rotation; go the short way
+		o, d = Quat{W: -o.W, X: -o.X, Y: -o.Y, Z: -o.Z}, -d
+	}
+	a, b := 1-t, t
+	if d < 1-1e-9 {
+		theta := math.Acos(d)
+		s := math.Sin(theta)
+		a, b = math.Sin(a*theta)/s, math.Sin(b*theta)/s
+	}
+	return Quat{
+		W: a*q.W + b*o.W,
+		X: a*q.X + b*o.X,
+		Y: a*q.Y + b*o.Y,
+		Z: a*q.Z + b*o.Z,
+	}.Normalize()
+}
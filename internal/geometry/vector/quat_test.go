@@ -0,0 +1,148 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+// sameRotation reports whether q and o rotate alike, q and -q being the
+// same rotation.
+func sameRotation(q, o Quat, tol float64) bool {
+	return 1-math.Abs(q.Dot(o)) < tol
+}
+
+func TestQuatEulerRoundTrip(t *testing.T) {
+	deg := math.Pi / 180
+	for _, c := range []struct {
+		name             string
+		roll, pitch, yaw float64
+	}{
+		{"identity", 0, 0, 0},
+		{"roll", 30 * deg, 0, 0},
+		{"pitch", 0, -20 * deg, 0},
+		{"yaw", 0, 0, 135 * deg},
+		{"all three", 25 * deg, 10 * deg, -60 * deg},
+		{"inverted", 180 * deg, 5 * deg, 90 * deg},
+		{"steep climb", -40 * deg, 89 * deg, 170 * deg},
+		{"steep dive", 10 * deg, -89.9 * deg, -179 * deg},
+	} {
+		q := QuatFromEuler(c.roll, c.pitch, c.yaw)
+		if math.Abs(q.Length()-1) > 1e-12 {
+			t.Errorf("%s: |q| = %v", c.name, q.Length())
+		}
+		roll, pitch, yaw := q.ToEuler()
+		for _, a := range []struct {
+			axis      string
+			got, want float64
+		}{{"roll", roll, c.roll}, {"pitch", pitch, c.pitch}, {"yaw", yaw, c.yaw}} {
+			if math.Abs(math.Remainder(a.got-a.want, 2*math.Pi)) > 1e-9 {
+				t.Errorf("%s: %s %.9f°, want %.9f°", c.name, a.axis, a.got/deg, a.want/deg)
+			}
+		}
+	}
+}
+
+func TestQuatEulerGimbalLock(t *testing.T) {
+	deg := math.Pi / 180
+	for _, c := range []struct {
+		name             string
+		roll, pitch, yaw float64
+	}{
+		{"nose up", 0, 90 * deg, 0},
+		{"nose up, yawed", 0, 90 * deg, 40 * deg},
+		{"nose up, rolled and yawed", 30 * deg, 90 * deg, 40 * deg},
+		{"nose down, rolled and yawed", 30 * deg, -90 * deg, 40 * deg},
+		{"nose down, rolled the other way", -70 * deg, -90 * deg, 100 * deg},
+	} {
+		q := QuatFromEuler(c.roll, c.pitch, c.yaw)
+		roll, pitch, yaw := q.ToEuler()
+		if roll != 0 {
+			t.Errorf("%s: roll %v, want 0 at gimbal lock", c.name, roll/deg)
+		}
+		if math.Abs(pitch-c.pitch) > 1e-9 {
+			t.Errorf("%s: pitch %v°, want %v°", c.name, pitch/deg, c.pitch/deg)
+		}
+		if yaw <= -math.Pi || yaw > math.Pi {
+			t.Errorf("%s: yaw %v outside (-π, π]", c.name, yaw)
+		}
+		// roll and yaw share an axis, but the rotation is the same one
+		if back := QuatFromEuler(roll, pitch, yaw); !sameRotation(back, q, 1e-12) {
+			t.Errorf("%s: %.3f° %.3f° %.3f° rebuilds %+v, want %+v", c.name, roll/deg, pitch/deg, yaw/deg, back, q)
+		}
+	}
+}
+
+func TestQuatRotateVec(t *testing.T) {
+	x, y, z := Vec3{X: 1}, Vec3{Y: 1}, Vec3{Z: 1}
+	for _, c := range []struct {
+		name string
+		q    Quat
+		v    Vec3
+		want Vec3
+	}{
+		{"identity", IdentityQuat(), Vec3{1, 2, 3}, Vec3{1, 2, 3}},
+		{"quarter about z", QuatFromAxisAngle(z, math.Pi/2), x, y},
+		{"quarter about x", QuatFromAxisAngle(x, math.Pi/2), y, z},
+		{"quarter about y", QuatFromAxisAngle(y, math.Pi/2), z, x},
+		{"half about z", QuatFromAxisAngle(z, math.Pi), Vec3{1, 2, 3}, Vec3{-1, -2, 3}},
+		{"unnormalised axis", QuatFromAxisAngle(Vec3{Z: 5}, math.Pi/2), x, y},
+		{"zero axis", QuatFromAxisAngle(Vec3{}, 1), x, x},
+		{"third about the diagonal", QuatFromAxisAngle(Vec3{1, 1, 1}, 2*math.Pi/3), x, y},
+		{"yaw", QuatFromEuler(0, 0, math.Pi/2), x, y},
+		{"conjugate undoes", QuatFromAxisAngle(z, math.Pi/2).Conj(), y, x},
+		// Mul applies the right-hand rotation first
+		{"product", QuatFromAxisAngle(x, math.Pi/2).Mul(QuatFromAxisAngle(z, math.Pi/2)), x, z},
+		{"product reversed", QuatFromAxisAngle(z, math.Pi/2).Mul(QuatFromAxisAngle(x, math.Pi/2)), x, y},
+	} {
+		if got := c.q.RotateVec(c.v); got.Sub(c.want).Length() > 1e-12 {
+			t.Errorf("%s: RotateVec(%v) = %v, want %v", c.name, c.v, got, c.want)
+		}
+	}
+	q := QuatFromEuler(0.3, -0.2, 1.1)
+	if got := q.Mul(q.Conj()); !sameRotation(got, IdentityQuat(), 1e-15) {
+		t.Errorf("q·q* = %+v, want the identity", got)
+	}
+	if got := (Quat{W: 2, X: 2, Y: 2, Z: 2}).Normalize(); math.Abs(got.W-0.5)+math.Abs(got.X-0.5)+math.Abs(got.Y-0.5)+math.Abs(got.Z-0.5) > 1e-15 {
+		t.Errorf("Normalize = %+v", got)
+	}
+	if got := (Quat{}).Normalize(); got != IdentityQuat() {
+		t.Errorf("Normalize of zero = %+v, want the identity", got)
+	}
+}
+
+func TestQuatSlerp(t *testing.T) {
+	z := Vec3{Z: 1}
+	a, b := IdentityQuat(), QuatFromAxisAngle(z, math.Pi/2)
+	for _, c := range []struct {
+		name string
+		q, o Quat
+		t    float64
+		want Quat
+	}{
+		{"start", a, b, 0, a},
+		{"end", a, b, 1, b},
+		{"middle", a, b, 0.5, QuatFromAxisAngle(z, math.Pi/4)},
+		{"quarter", a, b, 0.25, QuatFromAxisAngle(z, math.Pi/8)},
+		// -b is the same rotation; Slerp must still take the short way
+		{"short way", a, Quat{W: -b.W, X: -b.X, Y: -b.Y, Z: -b.Z}, 0.5, QuatFromAxisAngle(z, math.Pi/4)},
+		{"nearly equal", a, QuatFromAxisAngle(z, 1e-6), 0.5, QuatFromAxisAngle(z, 5e-7)},
+		{"equal", b, b, 0.3, b},
+	} {
+		got := c.q.Slerp(c.o, c.t)
+		if !sameRotation(got, c.want, 1e-12) {
+			t.Errorf("%s: Slerp = %+v, want %+v", c.name, got, c.want)
+		}
+		if math.Abs(got.Length()-1) > 1e-12 {
+			t.Errorf("%s: |Slerp| = %v", c.name, got.Length())
+		}
+	}
+	// constant angular rate: equal steps turn equal angles
+	prev := a
+	for i := 1; i <= 10; i++ {
+		q := a.Slerp(b, float64(i)/10)
+		if step := 2 * math.Acos(min(math.Abs(prev.Dot(q)), 1)); math.Abs(step-math.Pi/20) > 1e-9 {
+			t.Errorf("step %d turned %v rad, want π/20", i, step)
+		}
+		prev = q
+	}
+}
@@ -0,0 +1,75 @@
+package vector
+
+import "math"
+
+// Vec2 represents a horizontal vector with X=east, Y=north (meters)
+type Vec2 struct{ X, Y float64 }
+
+// Add returns the sum of two vectors
+func (v Vec2) Add(o Vec2) Vec2 { return Vec2{v.X + o.X, v.Y + o.Y} }
+
+// Sub returns the difference between two vectors
+func (v Vec2) Sub(o Vec2) Vec2 { return Vec2{v.X - o.X, v.Y - o.Y} }
+
+// Mul scales a vector by a scalar
+func (v Vec2) Mul(k float64) Vec2 { return Vec2{v.X * k, v.Y * k} }
+
+// Dot returns the dot product of two vectors
+func (v Vec2) Dot(o Vec2) float64 { return v.X*o.X + v.Y*o.Y }
+
+// Cross returns the Z component of the cross product, positive when o is
+// counter-clockwise of v
+func (v Vec2) Cross(o Vec2) float64 { return v.X*o.Y - v.Y*o.X }
+
+// Length returns the vector's magnitude
+func (v Vec2) Length() float64 { return math.Hypot(v.X, v.Y) }
+
+// LengthSquared returns the squared magnitude, v·v
+func (v Vec2) LengthSquared() float64 { return v.Dot(v) }
+
+// Normalize returns a unit vector in the same direction, or the zero
+// vector for a zero-length one
+func (v Vec2) Normalize() Vec2 {
+	n := v.Length()
+	if n == 0 {
+		return Vec2{}
+	}
+	return Vec2{v.X / n, v.Y / n}
+}
+
+// Angle returns the signed angle (radians, in (-π, π]) from v to o,
+// positive counter-clockwise. It is 0 when either is zero.
+func (v Vec2) Angle(o Vec2) float64 {
+	if (v.X == 0 && v.Y == 0) || (o.X == 0 && o.Y == 0) {
+		return 0
+	}
+	a := math.Atan2(v.Cross(o), v.Dot(o))
+	if a == -math.Pi {
+		return math.Pi
+	}
+	return a
+}
+
+// Rotate rotates the vector counter-clockwise by rad radians
+func (v Vec2) Rotate(rad float64) Vec2 {
+	s, c := math.Sincos(rad)
+	return Vec2{v.X*c - v.Y*s, v.X*s + v.Y*c}
+}
+
+// Lerp interpolates linearly from v (t=0) to o (t=1)
+func (v Vec2) Lerp(o Vec2, t float64) Vec2 { return v.Add(o.Sub(v).Mul(t)) }
+
+// ClampLength returns v shortened to max when it is longer; a max of zero
+// or less gives the zero vector
+func (v Vec2) ClampLength(max float64) Vec2 {
+	if max <= 0 {
+		return Vec2{}
+	}
+	if n := v.Length(); n > max {
+		return Vec2{v.X / n * max, v.Y / n * max}
+	}
+	return v
+}
+
+// Vec3 returns the vector with the given vertical component
+func (v Vec2) Vec3(z float64) Vec3 { return Vec3{X: v.X, Y: v.Y, Z: z} }
@@ -0,0 +1,75 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVec2Arithmetic(t *testing.T) {
+	a, b := Vec2{3, 4}, Vec2{-1, 2}
+	for _, c := range []struct {
+		name      string
+		got, want Vec2
+	}{
+		{"Add", a.Add(b), Vec2{2, 6}},
+		{"Sub", a.Sub(b), Vec2{4, 2}},
+		{"Mul", a.Mul(-2), Vec2{-6, -8}},
+		{"Normalize", a.Normalize(), Vec2{0.6, 0.8}},
+		{"Normalize zero", Vec2{}.Normalize(), Vec2{}},
+		{"Lerp start", a.Lerp(b, 0), a},
+		{"Lerp end", a.Lerp(b, 1), b},
+		{"Lerp middle", a.Lerp(b, 0.5), Vec2{1, 3}},
+		{"Lerp beyond", a.Lerp(b, 2), Vec2{-5, 0}},
+		{"ClampLength shorter", a.ClampLength(2.5), Vec2{1.5, 2}},
+		{"ClampLength within", a.ClampLength(10), a},
+		{"ClampLength zero", a.ClampLength(0), Vec2{}},
+	} {
+		if c.got.Sub(c.want).Length() > 1e-12 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if got := a.Dot(b); got != 5 {
+		t.Errorf("Dot = %v, want 5", got)
+	}
+	if got := a.Cross(b); got != 10 {
+		t.Errorf("Cross = %v, want 10", got)
+	}
+	if got := a.Length(); got != 5 {
+		t.Errorf("Length = %v, want 5", got)
+	}
+	if got := a.LengthSquared(); got != 25 {
+		t.Errorf("LengthSquared = %v, want 25", got)
+	}
+	if got := a.Vec3(7); got != (Vec3{3, 4, 7}) {
+		t.Errorf("Vec3 = %v", got)
+	}
+	if got := (Vec3{3, 4, 7}).XY(); got != a {
+		t.Errorf("XY = %v", got)
+	}
+}
+
+func TestVec2Angle(t *testing.T) {
+	east, north := Vec2{X: 1}, Vec2{Y: 1}
+	for _, c := range []struct {
+		name string
+		v, o Vec2
+		want float64
+	}{
+		{"same", east, Vec2{X: 5}, 0},
+		{"counter-clockwise", east, north, math.Pi / 2},
+		{"clockwise", north, east, -math.Pi / 2},
+		{"opposite", east, Vec2{X: -1}, math.Pi},
+		// -0 in Y would give -π from Atan2
+		{"opposite, negative zero", Vec2{X: -1, Y: math.Copysign(0, -1)}, east, math.Pi},
+		{"45", east, Vec2{1, 1}, math.Pi / 4},
+		{"zero v", Vec2{}, north, 0},
+		{"zero o", north, Vec2{}, 0},
+	} {
+		if got := c.v.Angle(c.o); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("%s: Angle = %v, want %v", c.name, got, c.want)
+		}
+	}
+	if got := (Vec3{X: 1, Z: 9}).Angle2D(Vec3{Y: 2, Z: -3}); math.Abs(got-math.Pi/2) > 1e-12 {
+		t.Errorf("Angle2D = %v, want π/2", got)
+	}
+}
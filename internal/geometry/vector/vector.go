@@ -1,6 +1,8 @@
-// Package vector provides 3D vector operations
+// Package vector provides 3D vector and rotation operations
 package vector
 
+import "math"
+
 // NewVec3 creates a new 3D vector with the given components
 func NewVec3(x, y, z float64) Vec3 {
 	return Vec3{X: x, Y: y, Z: z}
@@ -19,8 +21,18 @@ func (v Vec3) Sub(o Vec3) Vec3 { return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
 // Mul scales a vector by a scalar
 func (v Vec3) Mul(k float64) Vec3 { return Vec3{v.X * k, v.Y * k, v.Z * k} }
 
-// Norm returns the vector's magnitude (Euclidean norm)
-func (v Vec3) Norm() float64 { return v.Dot(v) }
+// Length returns the vector's magnitude (Euclidean norm), without
+// overflowing for large components
+func (v Vec3) Length() float64 { return math.Hypot(math.Hypot(v.X, v.Y), v.Z) }
+
+// LengthSquared returns the squared magnitude, v·v
+func (v Vec3) LengthSquared() float64 { return v.Dot(v) }
+
+// Norm returns the vector's magnitude (Euclidean norm). It used to return
+// the squared magnitude.
+//
+// Deprecated: use Length, or LengthSquared for the square.
+func (v Vec3) Norm() float64 { return v.Length() }
 
 // Dot returns the dot product of two vectors
 func (v Vec3) Dot(o Vec3) float64 { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
@@ -34,11 +46,46 @@ func (v Vec3) Cross(o Vec3) Vec3 {
 	}
 }
 
-// Normalize returns a unit vector in the same direction
+// Normalize returns a unit vector in the same direction, or the zero
+// vector for a zero-length one
 func (v Vec3) Normalize() Vec3 {
-	norm := v.Norm()
-	if norm == 0 {
+	n := v.Length()
+	if n == 0 {
+		return Vec3{}
+	}
+	return Vec3{v.X / n, v.Y / n, v.Z / n}
+}
+
+// Horizontal returns the vector with its vertical (Z) component dropped
+func (v Vec3) Horizontal() Vec3 { return Vec3{X: v.X, Y: v.Y} }
+
+// XY returns the horizontal components as a Vec2
+func (v Vec3) XY() Vec2 { return Vec2{X: v.X, Y: v.Y} }
+
+// Angle2D returns the signed angle (radians, in (-π, π]) from v to o in
+// the horizontal plane, positive counter-clockwise (from east toward
+// north). It is 0 when either is horizontally zero.
+func (v Vec3) Angle2D(o Vec3) float64 { return v.XY().Angle(o.XY()) }
+
+// RotateZ rotates the vector counter-clockwise about the Z axis by rad
+// radians
+func (v Vec3) RotateZ(rad float64) Vec3 {
+	h := v.XY().Rotate(rad)
+	return Vec3{X: h.X, Y: h.Y, Z: v.Z}
+}
+
+// Lerp interpolates linearly from v (t=0) to o (t=1); t outside [0, 1]
+// extrapolates
+func (v Vec3) Lerp(o Vec3, t float64) Vec3 { return v.Add(o.Sub(v).Mul(t)) }
+
+// ClampLength returns v shortened to max when it is longer; a max of zero
+// or less gives the zero vector
+func (v Vec3) ClampLength(max float64) Vec3 {
+	if max <= 0 {
 		return Vec3{}
 	}
-	return v.Mul(1 / norm)
+	if n := v.Length(); n > max {
+		return Vec3{v.X / n * max, v.Y / n * max, v.Z / n * max}
+	}
+	return v
 }
@@ -0,0 +1,89 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		v    Vec3
+		want Vec3
+	}{
+		{"zero", Vec3{}, Vec3{}},
+		{"unit", Vec3{X: 1}, Vec3{X: 1}},
+		{"3-4-0", Vec3{3, 4, 0}, Vec3{0.6, 0.8, 0}},
+		{"negative", Vec3{0, 0, -7}, Vec3{0, 0, -1}},
+		{"2-3-6", Vec3{2, 3, 6}, Vec3{2.0 / 7, 3.0 / 7, 6.0 / 7}},
+		// squaring would overflow or underflow these
+		{"huge", Vec3{3e200, 4e200, 0}, Vec3{0.6, 0.8, 0}},
+		{"tiny", Vec3{0, 3e-200, 4e-200}, Vec3{0, 0.6, 0.8}},
+	} {
+		got := c.v.Normalize()
+		if got.Sub(c.want).Length() > 1e-15 {
+			t.Errorf("%s: Normalize(%v) = %v, want %v", c.name, c.v, got, c.want)
+		}
+		if c.v != (Vec3{}) && math.Abs(got.Length()-1) > 1e-15 {
+			t.Errorf("%s: |Normalize(%v)| = %v", c.name, c.v, got.Length())
+		}
+	}
+}
+
+func TestLength(t *testing.T) {
+	for _, c := range []struct {
+		v          Vec3
+		length, sq float64
+	}{
+		{Vec3{}, 0, 0},
+		{Vec3{3, 4, 0}, 5, 25},
+		{Vec3{2, -3, 6}, 7, 49},
+		{Vec3{3e200, 4e200, 0}, 5e200, math.Inf(1)},
+	} {
+		if got := c.v.Length(); math.Abs(got-c.length) > 1e-15*c.length {
+			t.Errorf("Length(%v) = %v, want %v", c.v, got, c.length)
+		}
+		if got := c.v.LengthSquared(); got != c.sq {
+			t.Errorf("LengthSquared(%v) = %v, want %v", c.v, got, c.sq)
+		}
+		// Norm used to be the square; it is Length now
+		if got := c.v.Norm(); got != c.v.Length() {
+			t.Errorf("Norm(%v) = %v, want Length %v", c.v, got, c.v.Length())
+		}
+	}
+}
+
+func TestVec3Helpers(t *testing.T) {
+	v := Vec3{3, 4, 12}
+	for _, c := range []struct {
+		name      string
+		got, want Vec3
+	}{
+		{"NewVec3", NewVec3(3, 4, 12), v},
+		{"Horizontal", v.Horizontal(), Vec3{3, 4, 0}},
+		{"Cross x×y", Vec3{X: 1}.Cross(Vec3{Y: 1}), Vec3{Z: 1}},
+		{"Cross y×x", Vec3{Y: 1}.Cross(Vec3{X: 1}), Vec3{Z: -1}},
+		{"Cross parallel", v.Cross(v.Mul(2)), Vec3{}},
+		{"RotateZ quarter", v.RotateZ(math.Pi / 2), Vec3{-4, 3, 12}},
+		{"RotateZ half", v.RotateZ(math.Pi), Vec3{-3, -4, 12}},
+		{"RotateZ back", v.RotateZ(-math.Pi / 2), Vec3{4, -3, 12}},
+		{"Lerp start", v.Lerp(Vec3{}, 0), v},
+		{"Lerp end", v.Lerp(Vec3{}, 1), Vec3{}},
+		{"Lerp quarter", v.Lerp(Vec3{7, 0, 0}, 0.25), Vec3{4, 3, 9}},
+		{"Lerp extrapolates", Vec3{}.Lerp(Vec3{1, 1, 1}, -1), Vec3{-1, -1, -1}},
+		{"ClampLength shorter", v.ClampLength(6.5), Vec3{1.5, 2, 6}},
+		{"ClampLength within", v.ClampLength(13), v},
+		{"ClampLength zero", v.ClampLength(0), Vec3{}},
+		{"ClampLength negative", v.ClampLength(-1), Vec3{}},
+	} {
+		if c.got.Sub(c.want).Length() > 1e-12 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	// RotateZ keeps the length
+	for _, rad := range []float64{0.1, 1, 2.5, -4} {
+		if got := v.RotateZ(rad).Length(); math.Abs(got-13) > 1e-12 {
+			t.Errorf("|RotateZ(%g)| = %v, want 13", rad, got)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package mavlink speaks the small part of MAVLink 2 that ground stations
+// such as QGroundControl and Mission Planner need to show the simulated
+// aircraft and fly it: framing, the checksum, the telemetry messages and
+// the commands and mission upload a ground station sends.
+package mavlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Start bytes of MAVLink 2 and MAVLink 1 frames.
+const (
+	stx   = 0xFD
+	stxV1 = 0xFE
+)
+
+// headerLen is the length of a MAVLink 2 header, stx included; a frame
+// ends with a two-byte checksum, and a signed one with a 13-byte signature
+// after it. A MAVLink 1 header is headerLenV1 long.
+const (
+	headerLen   = 10
+	headerLenV1 = 6
+	signLen     = 13
+
+	flagSigned = 0x01 // incompatibility flag of a signed frame
+)
+
+// Message is a MAVLink message that can be framed.
+type Message interface {
+	// MsgID is the message's id in the common dialect.
+	MsgID() uint32
+	// CRCExtra is the seed derived from the message definition that the
+	// checksum ends with, so that both ends agree on the layout.
+	CRCExtra() byte
+	// payload is the message's fields in wire order, little endian.
+	payload() []byte
+}
+
+// Encoder frames messages from one system and component, numbering them.
+type Encoder struct {
+	SysID  uint8
+	CompID uint8
+	seq    uint8
+}
+
+// Frame returns m as a MAVLink 2 frame. Trailing zero bytes of the payload
+// are left out, as the protocol asks; the receiver fills them back in.
+func (e *Encoder) Frame(m Message) []byte {
+	p := m.payload()
+	n := len(p)
+	for n > 1 && p[n-1] == 0 {
+		n--
+	}
+	id := m.MsgID()
+	b := make([]byte, 0, headerLen+n+2)
+	b = append(b, stx, byte(n), 0, 0, e.seq, e.SysID, e.CompID, byte(id), byte(id>>8), byte(id>>16))
+	b = append(b, p[:n]...)
+	crc := checksum(b[1:], m.CRCExtra())
+	b = binary.LittleEndian.AppendUint16(b, crc)
+	e.seq++
+	return b
+}
+
+// Frame is a decoded message with its sender.
+type Frame struct {
+	Seq    uint8
+	SysID  uint8
+	CompID uint8
+	Msg    Message
+}
+
+// ErrChecksum is returned by Decode for a frame whose checksum is wrong.
+var ErrChecksum = errors.New("mavlink: bad checksum")
+
+// Decode reads the frames of a datagram, MAVLink 2 or 1. Frames of
+// messages this package does not know are skipped, as their checksum
+// cannot be verified; signatures are not checked. The frames decoded
+// before a bad one are returned with the error.
+func Decode(b []byte) ([]Frame, error) {
+	var frames []Frame
+	for len(b) > 0 {
+		var (
+			hlen, n, tail  int
+			seq, sys, comp uint8
+			id             uint32
+		)
+		switch b[0] {
+		case stx:
+			if len(b) < headerLen {
+				return frames, errors.New("mavlink: short frame")
+			}
+			hlen, n, tail = headerLen, int(b[1]), 2
+			if b[2]&flagSigned != 0 {
+				tail += signLen
+			}
+			seq, sys, comp = b[4], b[5], b[6]
+			id = uint32(b[7]) | uint32(b[8])<<8 | uint32(b[9])<<16
+		case stxV1:
+			if len(b) < headerLenV1 {
+				return frames, errors.New("mavlink: short frame")
+			}
+			hlen, n, tail = headerLenV1, int(b[1]), 2
+			seq, sys, comp, id = b[2], b[3], b[4], uint32(b[5])
+		default:
+			return frames, fmt.Errorf("mavlink: bad start byte %#x", b[0])
+		}
+		if len(b) < hlen+n+tail {
+			return frames, errors.New("mavlink: short frame")
+		}
+		frame := b[:hlen+n+tail]
+		b = b[len(frame):]
+		info, ok := messages[id]
+		if !ok {
+			continue
+		}
+		crc := binary.LittleEndian.Uint16(frame[hlen+n:])
+		if checksum(frame[1:hlen+n], info.extra) != crc {
+			return frames, fmt.Errorf("%w (message %d from %d/%d)", ErrChecksum, id, sys, comp)
+		}
+		// a truncated payload ends in zeros; a longer one has extensions
+		// this package does not read
+		p := make([]byte, info.size)
+		copy(p, frame[hlen:hlen+n])
+		frames = append(frames, Frame{Seq: seq, SysID: sys, CompID: comp, Msg: info.decode(p)})
+	}
+	return frames, nil
+}
+
+// checksum is the CRC-16/MCRF4XX (X.25) of b followed by extra.
+func checksum(b []byte, extra byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, c := range b {
+		crc = crcAccumulate(crc, c)
+	}
+	return crcAccumulate(crc, extra)
+}
+
+func crcAccumulate(crc uint16, c byte) uint16 {
+	t := c ^ byte(crc)
+	t ^= t << 4
+	return crc>>8 ^ uint16(t)<<8 ^ uint16(t)<<3 ^ uint16(t)>>4
+}
@@ -0,0 +1,202 @@
+package mavlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// sample is one message of every type this package decodes, with every
+// field set so a misplaced byte shows.
+var sample = []Message{
+	Heartbeat{CustomMode: 0x01020304, Type: 2, Autopilot: 3, BaseMode: ModeFlagGuided | ModeFlagArmed, SystemStatus: StateActive},
+	Attitude{TimeBootMs: 123456, Roll: 0.1, Pitch: -0.2, Yaw: 3.1, RollSpeed: 0.01, PitchSpeed: -0.02, YawSpeed: 0.03},
+	GlobalPositionInt{TimeBootMs: 99, Lat: 470000001, Lon: -80000001, Alt: 1234567, RelativeAlt: 567890, Vx: -120, Vy: 340, Vz: -5, Hdg: 35999},
+	VFRHUD{Airspeed: 41.5, Groundspeed: 40.25, Alt: 1234.5, Climb: -1.5, Heading: 359, Throttle: 65},
+	CommandLong{Params: [7]float32{1, 2, 3, 4, 5, 6, 7}, Command: CmdDoChangeSpeed, TargetSystem: 1, TargetComponent: 1, Confirmation: 2},
+	CommandAck{Command: CmdNavTakeoff, Result: ResultDenied, Progress: 50, ResultParam2: -7, TargetSystem: 255, TargetComponent: 190},
+	SetPositionTargetGlobalInt{TimeBootMs: 1, LatInt: 470100000, LonInt: 80200000, Alt: 1500, Vx: 1, Vy: 2, Vz: 3,
+		Afx: 4, Afy: 5, Afz: 6, Yaw: 7, YawRate: 8, TypeMask: IgnoreVelocity, TargetSystem: 1, TargetComponent: 1, CoordinateFrame: FrameGlobalRelativeAltInt},
+	MissionCount{Count: 12, TargetSystem: 1, TargetComponent: 1, MissionType: 2},
+	MissionItemInt{Params: [4]float32{1, 2, 3, 4}, X: 470000000, Y: 80000000, Z: 120, Seq: 3, Command: CmdNavWaypoint,
+		TargetSystem: 1, TargetComponent: 1, Frame: FrameGlobalRelativeAltInt, Current: 1, Autocontinue: 1, MissionType: 1},
+	MissionRequestInt{Seq: 513, TargetSystem: 255, TargetComponent: 190, MissionType: 1},
+	MissionAck{TargetSystem: 255, TargetComponent: 190, Type: MissionInvalidSequence, MissionType: 1},
+}
+
+func TestChecksum(t *testing.T) {
+	// the check value of CRC-16/MCRF4XX
+	crc := uint16(0xFFFF)
+	for _, c := range []byte("123456789") {
+		crc = crcAccumulate(crc, c)
+	}
+	if crc != 0x6F91 {
+		t.Errorf("crc of 123456789 = %#04x, want 0x6f91", crc)
+	}
+}
+
+func TestFrameDecodeRoundTrip(t *testing.T) {
+	e := &Encoder{SysID: 1, CompID: 1}
+	for i, m := range sample {
+		b := e.Frame(m)
+		if b[0] != stx || int(b[1]) != len(b)-headerLen-2 {
+			t.Errorf("%T: header % x for a %d-byte frame", m, b[:headerLen], len(b))
+		}
+		if info := messages[m.MsgID()]; info.extra != m.CRCExtra() || info.size != len(m.payload()) {
+			t.Errorf("%T: decoder has extra %d size %d, message %d and %d", m, info.extra, info.size, m.CRCExtra(), len(m.payload()))
+		}
+		frames, err := Decode(b)
+		if err != nil || len(frames) != 1 {
+			t.Fatalf("%T: %d frames, %v", m, len(frames), err)
+		}
+		want := Frame{Seq: uint8(i), SysID: 1, CompID: 1, Msg: m}
+		if !reflect.DeepEqual(frames[0], want) {
+			t.Errorf("%T: decoded %+v, want %+v", m, frames[0], want)
+		}
+	}
+
+	// a datagram of several frames decodes in order
+	var datagram []byte
+	for _, m := range sample {
+		datagram = append(datagram, e.Frame(m)...)
+	}
+	frames, err := Decode(datagram)
+	if err != nil || len(frames) != len(sample) {
+		t.Fatalf("%d frames, %v", len(frames), err)
+	}
+	for i, f := range frames {
+		if !reflect.DeepEqual(f.Msg, sample[i]) || f.Seq != uint8(len(sample)+i) {
+			t.Errorf("frame %d: seq %d, %+v", i, f.Seq, f.Msg)
+		}
+	}
+}
+
+func TestFrameTruncatesTrailingZeros(t *testing.T) {
+	e := &Encoder{SysID: 1, CompID: 1}
+	for _, c := range []struct {
+		m Message
+		n int // payload bytes on the wire
+	}{
+		// the targets and result_param2 are zero, so only the command,
+		// result and progress go out
+		{CommandAck{Command: CmdNavLand, Result: ResultAccepted, Progress: 1}, 4},
+		// and with nothing else set, the command's high byte too
+		{CommandAck{Command: CmdNavLand}, 1},
+		// an all-zero payload keeps one byte
+		{MissionAck{}, 1},
+		{GlobalPositionInt{TimeBootMs: 5}, 1},
+		// zeros in the middle stay
+		{MissionCount{Count: 3, MissionType: 1}, 5},
+	} {
+		b := e.Frame(c.m)
+		if int(b[1]) != c.n || len(b) != headerLen+c.n+2 {
+			t.Errorf("%+v: %d payload bytes in a %d-byte frame, want %d", c.m, b[1], len(b), c.n)
+		}
+		frames, err := Decode(b)
+		if err != nil || len(frames) != 1 || !reflect.DeepEqual(frames[0].Msg, c.m) {
+			t.Errorf("%+v: decoded %+v, %v", c.m, frames, err)
+		}
+	}
+}
+
+// frameV1 frames m as MAVLink 1 does: the whole payload and a one-byte id.
+func frameV1(seq, sys, comp uint8, m Message) []byte {
+	p := m.payload()
+	b := append([]byte{stxV1, byte(len(p)), seq, sys, comp, byte(m.MsgID())}, p...)
+	return binary.LittleEndian.AppendUint16(b, checksum(b[1:], m.CRCExtra()))
+}
+
+func TestDecodeMAVLink1(t *testing.T) {
+	m := CommandLong{Params: [7]float32{0, 0, 0, 0, 47.1, 8.2, 500}, Command: CmdNavWaypoint, TargetSystem: 1, TargetComponent: 1}
+	b := frameV1(7, 255, 190, m)
+	// and a MAVLink 2 frame after it in the same datagram
+	b = append(b, (&Encoder{SysID: 255, CompID: 190}).Frame(MissionCount{Count: 2, TargetSystem: 1, TargetComponent: 1})...)
+	frames, err := Decode(b)
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("%d frames, %v", len(frames), err)
+	}
+	if want := (Frame{Seq: 7, SysID: 255, CompID: 190, Msg: m}); !reflect.DeepEqual(frames[0], want) {
+		t.Errorf("decoded %+v, want %+v", frames[0], want)
+	}
+	if mc, ok := frames[1].Msg.(MissionCount); !ok || mc.Count != 2 {
+		t.Errorf("second frame %+v", frames[1])
+	}
+
+	b = frameV1(0, 255, 190, m)
+	b[len(b)-1] ^= 0xFF
+	if _, err := Decode(b); !errors.Is(err, ErrChecksum) {
+		t.Errorf("corrupted MAVLink 1 frame: %v", err)
+	}
+}
+
+func TestDecodeSigned(t *testing.T) {
+	m := SetPositionTargetGlobalInt{LatInt: 470000000, LonInt: 80000000, Alt: 100, TypeMask: IgnoreVelocity, CoordinateFrame: FrameGlobalInt}
+	b := (&Encoder{SysID: 255, CompID: 190}).Frame(m)
+	// flag it signed: the checksum covers the flags, and the signature
+	// (link id, timestamp, signature) follows the checksum
+	b[2] |= flagSigned
+	n := len(b) - 2
+	b = binary.LittleEndian.AppendUint16(b[:n], checksum(b[1:n], m.CRCExtra()))
+	b = append(b, 1, 0x10, 0x20, 0x30, 0x40, 0x50, 0x60, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6)
+	b = append(b, (&Encoder{SysID: 255, CompID: 190}).Frame(MissionAck{Type: MissionAccepted})...)
+
+	frames, err := Decode(b)
+	if err != nil || len(frames) != 2 {
+		t.Fatalf("%d frames, %v", len(frames), err)
+	}
+	if !reflect.DeepEqual(frames[0].Msg, m) {
+		t.Errorf("decoded %+v, want %+v", frames[0].Msg, m)
+	}
+	if _, ok := frames[1].Msg.(MissionAck); !ok {
+		t.Errorf("frame after the signature: %+v", frames[1])
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	e := &Encoder{SysID: 255, CompID: 190}
+	good := e.Frame(Heartbeat{Type: 6})
+
+	corrupt := func(i int) []byte {
+		b := e.Frame(VFRHUD{Airspeed: 30, Groundspeed: 31, Alt: 500, Heading: 90})
+		b[i] ^= 0x01
+		return b
+	}
+	for _, c := range []struct {
+		name   string
+		b      []byte
+		frames int
+		err    error // nil for an error that is not ErrChecksum
+	}{
+		{"payload byte", corrupt(headerLen + 2), 0, ErrChecksum},
+		{"checksum byte", corrupt(headerLen + 18), 0, ErrChecksum},
+		{"sender", corrupt(5), 0, ErrChecksum},
+		{"good frame then a bad one", append(append([]byte{}, good...), corrupt(headerLen)...), 1, ErrChecksum},
+		{"bad start byte", []byte{0x55, 1, 2, 3}, 0, nil},
+		{"short header", good[:headerLen-1], 0, nil},
+		{"short payload", good[:len(good)-1], 0, nil},
+		{"short MAVLink 1 header", []byte{stxV1, 9, 0}, 0, nil},
+	} {
+		frames, err := Decode(c.b)
+		if err == nil || (c.err != nil) != errors.Is(err, ErrChecksum) || len(frames) != c.frames {
+			t.Errorf("%s: %d frames, %v", c.name, len(frames), err)
+		}
+	}
+
+	// a message this package does not know is skipped, checksum unchecked
+	unknown := []byte{stx, 2, 0, 0, 0, 1, 1, 0x2C, 0x01, 0, 0xAA, 0xBB, 0, 0}
+	frames, err := Decode(append(unknown, good...))
+	if err != nil || len(frames) != 1 {
+		t.Errorf("unknown message: %d frames, %v", len(frames), err)
+	}
+}
+
+func TestEncoderNumbersFrames(t *testing.T) {
+	e := &Encoder{SysID: 1, CompID: 1}
+	for i := 0; i < 300; i++ {
+		b := e.Frame(Heartbeat{})
+		if b[4] != uint8(i) {
+			t.Fatalf("frame %d has seq %d", i, b[4])
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package mavlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// Source is the command source (sim.WithCommandSource) of the commands
+// MAVLink sends; the client is the ground station's address.
+const Source = "mavlink"
+
+// DefaultMaxMissionItems is the largest mission upload accepted when
+// InputConfig.MaxMissionItems is zero.
+const DefaultMaxMissionItems = 2000
+
+// Mission upload timing: an item that does not arrive is asked for again
+// every missionRetryInterval, and the upload is abandoned after
+// missionRetries such requests.
+const (
+	missionRetryInterval = 1500 * time.Millisecond
+	missionRetries       = 4
+)
+
+// velocityLeadS is how far ahead a velocity setpoint is flown to: the
+// aircraft keeps the velocity for this long unless another setpoint
+// follows, as ground stations send them several times a second.
+const velocityLeadS = 10.0
+
+// InputConfig configures an Input.
+type InputConfig struct {
+	// MaxMissionItems is the largest mission accepted; zero means
+	// DefaultMaxMissionItems and a negative value no limit.
+	MaxMissionItems int
+	// Logger receives what was rejected; nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Input flies the engine from the MAVLink a ground station sends to a
+// Link. It takes COMMAND_LONG with NAV_TAKEOFF, NAV_LAND and
+// NAV_RETURN_TO_LAUNCH, answered with COMMAND_ACK;
+// SET_POSITION_TARGET_GLOBAL_INT, as a goto to the position or along the
+// velocity; and mission uploads (MISSION_COUNT, then MISSION_ITEM_INT
+// requested one by one with MISSION_REQUEST_INT), flown as a trajectory
+// once complete and answered with MISSION_ACK. Commands are submitted with
+// Source. Launch, for return-to-launch, is where the aircraft was when
+// Run started.
+type Input struct {
+	link     *Link
+	eng      *sim.Engine
+	log      *slog.Logger
+	maxItems int
+
+	home   sim.AircraftState
+	upload *missionUpload
+}
+
+// missionUpload is a mission being received. Items must arrive in order;
+// any other item makes the expected one be asked for again.
+type missionUpload struct {
+	from      *net.UDPAddr
+	sys, comp uint8
+	items     []MissionItemInt
+	count     int
+	asked     time.Time
+	retries   int
+}
+
+// NewInput returns an Input for link that submits to eng.
+func NewInput(link *Link, eng *sim.Engine, cfg InputConfig) *Input {
+	in := &Input{link: link, eng: eng, log: cfg.Logger, maxItems: cfg.MaxMissionItems}
+	if in.log == nil {
+		in.log = slog.Default()
+	}
+	if in.maxItems == 0 {
+		in.maxItems = DefaultMaxMissionItems
+	}
+	return in
+}
+
+type datagram struct {
+	frames []Frame
+	from   *net.UDPAddr
+}
+
+// Run handles the link's frames until ctx is done. It stops reading the
+// socket then, but leaves it open.
+func (in *Input) Run(ctx context.Context) {
+	if st, err := in.getState(ctx); err == nil {
+		in.home = st
+	}
+	received := make(chan datagram, 64)
+	go in.read(ctx, received)
+
+	tick := time.NewTicker(missionRetryInterval / 4)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-received:
+			for _, f := range d.frames {
+				in.handle(ctx, f, d.from)
+			}
+		case now := <-tick.C:
+			in.checkUpload(now)
+		}
+	}
+}
+
+// read decodes datagrams until ctx is done, remembering their senders as
+// peers of the link.
+func (in *Input) read(ctx context.Context, out chan<- datagram) {
+	stop := context.AfterFunc(ctx, func() { _ = in.link.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+	buf := make([]byte, 65536)
+	for {
+		n, from, err := in.link.read(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				in.log.Warn("mavlink read", "err", err)
+			}
+			return
+		}
+		frames, err := Decode(buf[:n])
+		if err != nil {
+			in.log.Debug("mavlink decode", "from", from.String(), "err", err)
+		}
+		if len(frames) == 0 {
+			continue
+		}
+		in.link.addPeer(from)
+		select {
+		case out <- datagram{frames: frames, from: from}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// forUs reports whether a message targets this aircraft, directly or by
+// broadcast.
+func (in *Input) forUs(sys, comp uint8) bool {
+	return (sys == 0 || sys == in.link.SysID()) && (comp == 0 || comp == in.link.CompID())
+}
+
+func (in *Input) handle(ctx context.Context, f Frame, from *net.UDPAddr) {
+	switch m := f.Msg.(type) {
+	case CommandLong:
+		if in.forUs(m.TargetSystem, m.TargetComponent) {
+			result := in.command(ctx, m, from)
+			in.reply(CommandAck{Command: m.Command, Result: result, TargetSystem: f.SysID, TargetComponent: f.CompID}, from)
+		}
+	case SetPositionTargetGlobalInt:
+		if in.forUs(m.TargetSystem, m.TargetComponent) {
+			if err := in.setpoint(ctx, m, from); err != nil {
+				in.log.Warn("mavlink setpoint rejected", "from", from.String(), "err", err)
+			}
+		}
+	case MissionCount:
+		if in.forUs(m.TargetSystem, m.TargetComponent) {
+			in.missionCount(m, f, from)
+		}
+	case MissionItemInt:
+		if in.forUs(m.TargetSystem, m.TargetComponent) {
+			in.missionItem(ctx, m, from)
+		}
+	case MissionAck:
+		// a ground station cancelling its upload
+		if in.forUs(m.TargetSystem, m.TargetComponent) && in.upload != nil && sameAddr(in.upload.from, from) {
+			in.log.Info("mavlink mission upload cancelled", "from", from.String())
+			in.upload = nil
+		}
+	}
+}
+
+func (in *Input) reply(m Message, to *net.UDPAddr) {
+	if err := in.link.Send(m, to); err != nil {
+		in.log.Warn("mavlink reply failed", "to", to.String(), "err", err)
+	}
+}
+
+// command runs a COMMAND_LONG and returns its MAV_RESULT.
+func (in *Input) command(ctx context.Context, m CommandLong, from *net.UDPAddr) uint8 {
+	st, err := in.getState(ctx)
+	if err != nil {
+		return ResultTemporarilyRejected
+	}
+	var cmd sim.GoToCommand
+	switch m.Command {
+	case CmdNavTakeoff:
+		// param7 is the height to climb to above the ground
+		h := float64(m.Params[6])
+		if !(h > 0) {
+			return ResultDenied
+		}
+		cmd = sim.GoToCommand{Lat: st.Lat, Lon: st.Lon, AGL: h}
+	case CmdNavLand:
+		// param5/param6 are where to land, or unset for here
+		lat, lon := st.Lat, st.Lon
+		if p, ok := position(m.Params[4], m.Params[5]); ok {
+			lat, lon = p[0], p[1]
+		}
+		cmd = sim.GoToCommand{Lat: lat, Lon: lon, Alt: in.landingAlt(ctx, lat, lon)}
+	case CmdNavReturnToLaunch:
+		if in.home.TS.IsZero() {
+			return ResultDenied
+		}
+		cmd = sim.GoToCommand{Lat: in.home.Lat, Lon: in.home.Lon, Alt: math.Max(st.Alt, in.home.Alt)}
+	default:
+		return ResultUnsupported
+	}
+	cmd.At = in.eng.Now()
+	if err := in.submit(ctx, cmd, from, [2]float64{cmd.Lat, cmd.Lon}); err != nil {
+		in.log.Warn("mavlink command rejected", "command", m.Command, "from", from.String(), "err", err)
+		if errors.Is(err, sim.ErrOverloaded) {
+			return ResultTemporarilyRejected
+		}
+		return ResultDenied
+	}
+	return ResultAccepted
+}
+
+// setpoint flies a SET_POSITION_TARGET_GLOBAL_INT: to the position when it
+// is given, at the horizontal speed of the velocity if that is given too;
+// otherwise along the velocity, or holding when it is zero.
+func (in *Input) setpoint(ctx context.Context, m SetPositionTargetGlobalInt, from *net.UDPAddr) error {
+	usePos := m.TypeMask&IgnorePosition != IgnorePosition
+	useVel := m.TypeMask&IgnoreVelocity != IgnoreVelocity
+	speed := 0.0
+	if useVel {
+		speed = math.Hypot(float64(m.Vx), float64(m.Vy))
+	}
+	switch {
+	case usePos:
+		cmd := sim.GoToCommand{At: in.eng.Now(), Lat: float64(m.LatInt) / 1e7, Lon: float64(m.LonInt) / 1e7, Speed: speed}
+		if err := checkLatLon(cmd.Lat, cmd.Lon); err != nil {
+			return err
+		}
+		var err error
+		if cmd.Alt, cmd.AGL, err = altitude(m.CoordinateFrame, float64(m.Alt)); err != nil {
+			return err
+		}
+		return in.submit(ctx, cmd, from, [2]float64{cmd.Lat, cmd.Lon})
+
+	case useVel:
+		if math.IsNaN(speed) || math.IsInf(speed, 0) || math.IsNaN(float64(m.Vz)) || math.IsInf(float64(m.Vz), 0) {
+			return errors.New("velocity is not finite")
+		}
+		if speed < 0.1 && math.Abs(float64(m.Vz)) < 0.1 {
+			return in.submit(ctx, sim.HoldCommand{At: in.eng.Now()}, from)
+		}
+		st, err := in.getState(ctx)
+		if err != nil {
+			return err
+		}
+		cmd := sim.GoToCommand{At: in.eng.Now(), Lat: st.Lat, Lon: st.Lon, Alt: st.Alt - float64(m.Vz)*velocityLeadS, Speed: speed}
+		if speed >= 0.1 {
+			bearing := math.Atan2(float64(m.Vy), float64(m.Vx)) * 180 / math.Pi
+			cmd.Lat, cmd.Lon = sim.DestinationPoint(st.Lat, st.Lon, bearing, speed*velocityLeadS)
+		}
+		return in.submit(ctx, cmd, from, [2]float64{cmd.Lat, cmd.Lon})
+	}
+	return nil
+}
+
+// missionCount starts an upload, replacing any other.
+func (in *Input) missionCount(m MissionCount, f Frame, from *net.UDPAddr) {
+	ack := func(result uint8) {
+		in.reply(MissionAck{TargetSystem: f.SysID, TargetComponent: f.CompID, Type: result, MissionType: m.MissionType}, from)
+	}
+	switch {
+	case m.MissionType != MissionTypeMission:
+		ack(MissionUnsupported)
+	case m.Count == 0:
+		// an empty mission clears nothing the sim keeps
+		in.upload = nil
+		ack(MissionAccepted)
+	case in.maxItems > 0 && int(m.Count) > in.maxItems:
+		ack(MissionNoSpace)
+	default:
+		if in.upload != nil {
+			in.log.Info("mavlink mission upload restarted", "from", from.String())
+		}
+		in.upload = &missionUpload{from: from, sys: f.SysID, comp: f.CompID, count: int(m.Count)}
+		in.request(time.Now())
+	}
+}
+
+// missionItem takes the item the upload waits for, and asks again for it
+// when another one arrives.
+func (in *Input) missionItem(ctx context.Context, m MissionItemInt, from *net.UDPAddr) {
+	u := in.upload
+	if u == nil || !sameAddr(u.from, from) || m.MissionType != MissionTypeMission {
+		return
+	}
+	if int(m.Seq) != len(u.items) {
+		in.log.Debug("mavlink mission item out of order", "seq", m.Seq, "want", len(u.items))
+		in.request(time.Now())
+		return
+	}
+	u.items = append(u.items, m)
+	u.retries = 0
+	if len(u.items) < u.count {
+		in.request(time.Now())
+		return
+	}
+	in.upload = nil
+	result := in.flyMission(ctx, u)
+	in.reply(MissionAck{TargetSystem: u.sys, TargetComponent: u.comp, Type: result, MissionType: MissionTypeMission}, u.from)
+}
+
+// request asks for the next item of the upload.
+func (in *Input) request(now time.Time) {
+	u := in.upload
+	u.asked = now
+	in.reply(MissionRequestInt{Seq: uint16(len(u.items)), TargetSystem: u.sys, TargetComponent: u.comp,
+		MissionType: MissionTypeMission}, u.from)
+}
+
+// checkUpload asks again for an item that did not come, and abandons the
+// upload after missionRetries tries; nothing of it is flown.
+func (in *Input) checkUpload(now time.Time) {
+	u := in.upload
+	if u == nil || now.Sub(u.asked) < missionRetryInterval {
+		return
+	}
+	if u.retries >= missionRetries {
+		in.log.Warn("mavlink mission upload timed out", "from", u.from.String(), "received", len(u.items), "count", u.count)
+		in.upload = nil
+		in.reply(MissionAck{TargetSystem: u.sys, TargetComponent: u.comp, Type: MissionCancelled, MissionType: MissionTypeMission}, u.from)
+		return
+	}
+	u.retries++
+	in.request(now)
+}
+
+// flyMission turns a complete upload into a trajectory and submits it,
+// returning the MAV_MISSION_RESULT.
+func (in *Input) flyMission(ctx context.Context, u *missionUpload) uint8 {
+	st, err := in.getState(ctx)
+	if err != nil {
+		return MissionError
+	}
+	var (
+		wps   []sim.Waypoint
+		pts   [][2]float64
+		speed float64
+	)
+	// last is where the mission has got to, for items without a position
+	last := sim.Waypoint{Lat: st.Lat, Lon: st.Lon, Alt: st.Alt}
+	for _, it := range u.items {
+		wp := sim.Waypoint{Lat: float64(it.X) / 1e7, Lon: float64(it.Y) / 1e7, Speed: speed}
+		unset := it.X == 0 && it.Y == 0
+		switch it.Command {
+		case CmdDoChangeSpeed:
+			if s := float64(it.Params[1]); s > 0 {
+				speed = s
+			}
+			continue
+		case CmdNavWaypoint, CmdNavTakeoff:
+			if unset && it.Command == CmdNavTakeoff {
+				wp.Lat, wp.Lon = last.Lat, last.Lon
+			}
+			if !isGlobal(it.Frame) {
+				return MissionUnsupportedFrame
+			}
+			if wp.Alt, wp.AGL, err = altitude(it.Frame, float64(it.Z)); err != nil {
+				return MissionInvalidParam7
+			}
+		case CmdNavLand:
+			if unset {
+				wp.Lat, wp.Lon = last.Lat, last.Lon
+			}
+			wp.Alt = in.landingAlt(ctx, wp.Lat, wp.Lon)
+		case CmdNavReturnToLaunch:
+			if in.home.TS.IsZero() {
+				return MissionDenied
+			}
+			wp.Lat, wp.Lon, wp.Alt = in.home.Lat, in.home.Lon, math.Max(last.Alt, in.home.Alt)
+		default:
+			in.log.Warn("mavlink mission item unsupported", "seq", it.Seq, "command", it.Command)
+			return MissionUnsupported
+		}
+		if !(math.Abs(wp.Lat) <= 90) {
+			return MissionInvalidParam5X
+		}
+		if !(math.Abs(wp.Lon) <= 180) {
+			return MissionInvalidParam6Y
+		}
+		wps = append(wps, wp)
+		pts = append(pts, [2]float64{wp.Lat, wp.Lon})
+		last = wp
+		if wp.AGL > 0 {
+			// the altitude above the ground is not known here
+			last.Alt = st.Alt
+		}
+	}
+	if len(wps) == 0 {
+		return MissionInvalid
+	}
+	if err := in.submit(ctx, sim.TrajectoryCommand{At: in.eng.Now(), Waypoints: wps}, u.from, pts...); err != nil {
+		in.log.Warn("mavlink mission rejected", "from", u.from.String(), "err", err)
+		if errors.Is(err, sim.ErrOutOfRange) {
+			return MissionDenied
+		}
+		return MissionError
+	}
+	return MissionAccepted
+}
+
+// submit checks that the points are within the operating radius, as the
+// engine would reject the command otherwise after it was acknowledged,
+// and submits cmd from the ground station at from.
+func (in *Input) submit(ctx context.Context, cmd sim.Command, from *net.UDPAddr, pts ...[2]float64) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if len(pts) > 0 {
+		o, err := in.eng.Origin(ctx)
+		if err != nil {
+			return err
+		}
+		lat0, lon0 := o.Lat, o.Lon
+		if o.Auto {
+			lat0, lon0 = pts[0][0], pts[0][1]
+		}
+		for _, p := range pts {
+			if d := sim.HaversineM(lat0, lon0, p[0], p[1]); d > in.eng.MaxRangeM() {
+				return fmt.Errorf("%w: %.6f, %.6f is %.1f km from the origin", sim.ErrOutOfRange, p[0], p[1], d/1000)
+			}
+		}
+	}
+	ctx = sim.WithCommandClient(sim.WithCommandSource(ctx, Source), from.String())
+	return in.eng.Submit(ctx, cmd)
+}
+
+func (in *Input) getState(ctx context.Context) (sim.AircraftState, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return in.eng.GetState(ctx)
+}
+
+// landingAlt is the lowest altitude the engine lets the aircraft fly at
+// over lat, lon: the ground plus the terrain safety margin, or sea level
+// without terrain.
+func (in *Input) landingAlt(ctx context.Context, lat, lon float64) float64 {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	p, err := in.eng.TerrainAt(ctx, lat, lon)
+	switch {
+	case err != nil:
+		return 0
+	case p.MinSafeAltM != nil:
+		return *p.MinSafeAltM
+	case p.ElevationM != nil:
+		return *p.ElevationM
+	}
+	return 0
+}
+
+// altitude reads an altitude in frame as an MSL altitude or, for the
+// relative and terrain frames, a height to hold above the terrain: the
+// sim has no home altitude, and reports relative_alt above the ground too.
+func altitude(frame uint8, z float64) (msl, agl float64, err error) {
+	if math.IsNaN(z) || math.IsInf(z, 0) {
+		return 0, 0, errors.New("altitude is not finite")
+	}
+	switch frame {
+	case FrameGlobal, FrameGlobalInt:
+		return z, 0, nil
+	case FrameGlobalRelativeAlt, FrameGlobalRelativeAltInt, FrameGlobalTerrainAlt, FrameGlobalTerrainAltInt:
+		if z <= 0 {
+			return 0, 0, fmt.Errorf("height above the ground %g m is not positive", z)
+		}
+		return 0, z, nil
+	}
+	return 0, 0, fmt.Errorf("coordinate frame %d is not supported", frame)
+}
+
+func isGlobal(frame uint8) bool {
+	_, _, err := altitude(frame, 1)
+	return err == nil
+}
+
+// position reads a COMMAND_LONG latitude and longitude, which are unset
+// when zero or NaN.
+func position(lat, lon float32) ([2]float64, bool) {
+	p := [2]float64{float64(lat), float64(lon)}
+	if math.IsNaN(p[0]) || math.IsNaN(p[1]) || (p[0] == 0 && p[1] == 0) || checkLatLon(p[0], p[1]) != nil {
+		return p, false
+	}
+	return p, true
+}
+
+func checkLatLon(lat, lon float64) error {
+	if !(math.Abs(lat) <= 90) || !(math.Abs(lon) <= 180) {
+		return fmt.Errorf("position %g, %g is out of range", lat, lon)
+	}
+	return nil
+}
+
+func sameAddr(a, b *net.UDPAddr) bool { return a.String() == b.String() }
@@ -0,0 +1,492 @@
+package mavlink
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/fakeclock"
+)
+
+// gcs is a ground station talking to an Input. send hands frames to the
+// Input directly, so that each is handled before send returns; the
+// replies come back over the link's socket.
+type gcs struct {
+	t    *testing.T
+	ctx  context.Context
+	eng  *sim.Engine
+	link *Link
+	in   *Input
+	conn *net.UDPConn
+	enc  Encoder
+}
+
+// newGCS starts an engine on a fake clock and an Input for it on a
+// loopback link.
+func newGCS(t *testing.T, cfg InputConfig) *gcs {
+	t.Helper()
+	clk := fakeclock.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 1000, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		eng.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	clk.WaitForTicker()
+
+	link, err := Listen("127.0.0.1:0", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { link.Close() })
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &gcs{t: t, ctx: ctx, eng: eng, link: link, in: NewInput(link, eng, cfg), conn: conn,
+		enc: Encoder{SysID: 255, CompID: 190}}
+}
+
+func (g *gcs) addr() *net.UDPAddr { return g.conn.LocalAddr().(*net.UDPAddr) }
+
+// send has the Input handle m as a frame from the ground station.
+func (g *gcs) send(m Message) { g.sendFrom(g.addr(), m) }
+
+func (g *gcs) sendFrom(from *net.UDPAddr, m Message) {
+	g.in.handle(g.ctx, Frame{SysID: g.enc.SysID, CompID: g.enc.CompID, Msg: m}, from)
+}
+
+// write sends m over UDP, for an Input that is running.
+func (g *gcs) write(m Message) {
+	g.t.Helper()
+	if _, err := g.conn.WriteToUDP(g.enc.Frame(m), g.link.LocalAddr().(*net.UDPAddr)); err != nil {
+		g.t.Fatal(err)
+	}
+}
+
+// recv returns the next message the aircraft sends within d.
+func (g *gcs) recv(d time.Duration) Message {
+	g.t.Helper()
+	_ = g.conn.SetReadDeadline(time.Now().Add(d))
+	buf := make([]byte, 1024)
+	n, err := g.conn.Read(buf)
+	if err != nil {
+		g.t.Fatalf("no reply: %v", err)
+	}
+	frames, err := Decode(buf[:n])
+	if err != nil || len(frames) != 1 {
+		g.t.Fatalf("reply of %d frames: %v", len(frames), err)
+	}
+	if f := frames[0]; f.SysID != g.link.SysID() || f.CompID != g.link.CompID() {
+		g.t.Errorf("reply from %d/%d, want %d/%d", f.SysID, f.CompID, g.link.SysID(), g.link.CompID())
+	}
+	return frames[0].Msg
+}
+
+func (g *gcs) reply() Message {
+	g.t.Helper()
+	return g.recv(2 * time.Second)
+}
+
+// quiet checks that the aircraft sends nothing.
+func (g *gcs) quiet() {
+	g.t.Helper()
+	_ = g.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	buf := make([]byte, 1024)
+	if n, err := g.conn.Read(buf); err == nil {
+		frames, _ := Decode(buf[:n])
+		g.t.Fatalf("unexpected reply %+v", frames)
+	}
+}
+
+// commands waits until the engine has logged n commands and returns them.
+func (g *gcs) commands(n int) []sim.CommandRecord {
+	g.t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		recs, err := g.eng.CommandLog(g.ctx, sim.CommandLogQuery{})
+		if err != nil {
+			g.t.Fatal(err)
+		}
+		if len(recs) > n {
+			g.t.Fatalf("%d commands logged, want %d: %+v", len(recs), n, recs[n:])
+		}
+		if len(recs) == n {
+			return recs
+		}
+		if time.Now().After(deadline) {
+			g.t.Fatalf("%d commands logged, want %d", len(recs), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// last waits for the nth command and returns it.
+func (g *gcs) last(n int) sim.Command {
+	g.t.Helper()
+	recs := g.commands(n)
+	r := recs[n-1]
+	if r.Source != Source || r.Client != g.addr().String() {
+		g.t.Errorf("command from %q %q, want %q %q", r.Source, r.Client, Source, g.addr())
+	}
+	return r.Command
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestCommandAck(t *testing.T) {
+	g := newGCS(t, InputConfig{})
+	takeoff := func(h float32) CommandLong {
+		return CommandLong{Command: CmdNavTakeoff, Params: [7]float32{6: h}, TargetSystem: 1, TargetComponent: 1}
+	}
+	logged := 0
+	for _, c := range []struct {
+		name   string
+		cmd    CommandLong
+		result uint8
+	}{
+		{"takeoff", takeoff(50), ResultAccepted},
+		{"takeoff to no height", takeoff(0), ResultDenied},
+		{"takeoff to NaN", takeoff(float32(math.NaN())), ResultDenied},
+		{"land here", CommandLong{Command: CmdNavLand}, ResultAccepted},
+		{"land there", CommandLong{Command: CmdNavLand, Params: [7]float32{4: 47.01, 5: 8.02}}, ResultAccepted},
+		{"land out of range", CommandLong{Command: CmdNavLand, Params: [7]float32{4: 10, 5: 10}}, ResultDenied},
+		// launch is only known once Run has started
+		{"return without a launch", CommandLong{Command: CmdNavReturnToLaunch}, ResultDenied},
+		{"change speed", CommandLong{Command: CmdDoChangeSpeed, Params: [7]float32{1: 30}}, ResultUnsupported},
+		{"arm", CommandLong{Command: 400, Params: [7]float32{1}}, ResultUnsupported},
+	} {
+		g.send(c.cmd)
+		ack, ok := g.reply().(CommandAck)
+		if !ok {
+			t.Fatalf("%s: reply is not a COMMAND_ACK", c.name)
+		}
+		want := CommandAck{Command: c.cmd.Command, Result: c.result, TargetSystem: 255, TargetComponent: 190}
+		if ack != want {
+			t.Errorf("%s: %+v, want %+v", c.name, ack, want)
+		}
+		if c.result == ResultAccepted {
+			logged++
+		}
+		g.commands(logged)
+	}
+
+	recs := g.commands(3)
+	if cmd, ok := recs[0].Command.(sim.GoToCommand); !ok || !near(cmd.Lat, 47) || !near(cmd.Lon, 8) || cmd.AGL != 50 {
+		t.Errorf("takeoff flies %+v, want a climb to 50 m above 47, 8", recs[0].Command)
+	}
+	if cmd, ok := recs[2].Command.(sim.GoToCommand); !ok || math.Abs(cmd.Lat-47.01) > 1e-5 || math.Abs(cmd.Lon-8.02) > 1e-5 {
+		t.Errorf("land flies %+v, want 47.01, 8.02", recs[2].Command)
+	}
+	for _, r := range recs {
+		if r.Source != Source || r.Client != g.addr().String() {
+			t.Errorf("command from %q %q, want %q %q", r.Source, r.Client, Source, g.addr())
+		}
+	}
+
+	// commands for another aircraft or component are not answered
+	for _, m := range []CommandLong{
+		{Command: CmdNavTakeoff, Params: [7]float32{6: 50}, TargetSystem: 2},
+		{Command: CmdNavTakeoff, Params: [7]float32{6: 50}, TargetSystem: 1, TargetComponent: 2},
+	} {
+		g.send(m)
+	}
+	g.quiet()
+	g.commands(3)
+}
+
+func TestSetPositionTargetTypeMask(t *testing.T) {
+	g := newGCS(t, InputConfig{})
+	target := func(mask uint16) SetPositionTargetGlobalInt {
+		return SetPositionTargetGlobalInt{LatInt: 470100000, LonInt: 80200000, Alt: 1500, Vx: 3, Vy: 4, Vz: -2,
+			TypeMask: mask, CoordinateFrame: FrameGlobalInt, TargetSystem: 1, TargetComponent: 1}
+	}
+	n := 0
+
+	// the position alone
+	g.send(target(IgnoreVelocity))
+	n++
+	if cmd, ok := g.last(n).(sim.GoToCommand); !ok || !near(cmd.Lat, 47.01) || !near(cmd.Lon, 8.02) || cmd.Alt != 1500 || cmd.AGL != 0 || cmd.Speed != 0 {
+		t.Errorf("position: %+v", g.last(n))
+	}
+	// the position, at the horizontal speed of the velocity
+	g.send(target(0))
+	n++
+	if cmd, ok := g.last(n).(sim.GoToCommand); !ok || !near(cmd.Lat, 47.01) || !near(cmd.Speed, 5) {
+		t.Errorf("position and velocity: %+v", g.last(n))
+	}
+	// the position is ignored only as a whole, and the velocity likewise
+	g.send(target(0x0001 | 0x0008))
+	n++
+	if cmd, ok := g.last(n).(sim.GoToCommand); !ok || !near(cmd.Lat, 47.01) || cmd.Alt != 1500 || !near(cmd.Speed, 5) {
+		t.Errorf("partial masks: %+v", g.last(n))
+	}
+	// a relative altitude is a height above the ground
+	rel := target(IgnoreVelocity)
+	rel.CoordinateFrame, rel.Alt = FrameGlobalRelativeAltInt, 120
+	g.send(rel)
+	n++
+	if cmd, ok := g.last(n).(sim.GoToCommand); !ok || cmd.AGL != 120 || cmd.Alt != 0 {
+		t.Errorf("relative altitude: %+v", g.last(n))
+	}
+
+	// the velocity alone: along it from where the aircraft is
+	st, err := g.eng.GetState(g.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vel := target(IgnorePosition)
+	vel.Vx, vel.Vy = 10, 0
+	g.send(vel)
+	n++
+	cmd, ok := g.last(n).(sim.GoToCommand)
+	if !ok {
+		t.Fatalf("velocity: %+v", g.last(n))
+	}
+	if d := sim.HaversineM(st.Lat, st.Lon, cmd.Lat, cmd.Lon); math.Abs(d-10*velocityLeadS) > 0.5 || cmd.Lat <= st.Lat {
+		t.Errorf("velocity north flies %.1f m to %.6f, %.6f from %.6f, %.6f", d, cmd.Lat, cmd.Lon, st.Lat, st.Lon)
+	}
+	if !near(cmd.Speed, 10) || !near(cmd.Alt, st.Alt+2*velocityLeadS) {
+		t.Errorf("velocity: speed %g alt %g, want 10 and %g", cmd.Speed, cmd.Alt, st.Alt+2*velocityLeadS)
+	}
+	// a zero velocity holds
+	stop := target(IgnorePosition)
+	stop.Vx, stop.Vy, stop.Vz = 0, 0, 0
+	g.send(stop)
+	n++
+	if _, ok := g.last(n).(sim.HoldCommand); !ok {
+		t.Errorf("zero velocity: %+v", g.last(n))
+	}
+
+	// neither, or nothing that can be flown, submits nothing
+	none := target(IgnorePosition | IgnoreVelocity)
+	north := target(IgnoreVelocity)
+	north.LatInt = 910000000
+	ground := target(IgnoreVelocity)
+	ground.CoordinateFrame, ground.Alt = FrameGlobalTerrainAltInt, 0
+	local := target(IgnoreVelocity)
+	local.CoordinateFrame = 1 // MAV_FRAME_LOCAL_NED
+	far := target(IgnoreVelocity)
+	far.LatInt, far.LonInt = 100000000, 100000000
+	inf := target(IgnorePosition)
+	inf.Vx = float32(math.Inf(1))
+	other := target(IgnoreVelocity)
+	other.TargetSystem = 2
+	for _, m := range []SetPositionTargetGlobalInt{none, north, ground, local, far, inf, other} {
+		g.send(m)
+	}
+	g.commands(n)
+	// setpoints are not answered
+	g.quiet()
+}
+
+// upload sends a MISSION_COUNT and the items as they are asked for,
+// returning the MISSION_ACK.
+func (g *gcs) upload(items ...MissionItemInt) MissionAck {
+	g.t.Helper()
+	g.send(MissionCount{Count: uint16(len(items)), TargetSystem: 1, TargetComponent: 1})
+	for {
+		switch m := g.reply().(type) {
+		case MissionRequestInt:
+			if int(m.Seq) >= len(items) {
+				g.t.Fatalf("item %d of %d asked for", m.Seq, len(items))
+			}
+			g.send(items[m.Seq])
+		case MissionAck:
+			return m
+		default:
+			g.t.Fatalf("reply %+v during an upload", m)
+		}
+	}
+}
+
+func item(seq uint16, cmd uint16, frame uint8, lat, lon, z float64) MissionItemInt {
+	return MissionItemInt{Seq: seq, Command: cmd, Frame: frame, X: int32(lat * 1e7), Y: int32(lon * 1e7), Z: float32(z),
+		TargetSystem: 1, TargetComponent: 1, Autocontinue: 1}
+}
+
+func TestMissionUpload(t *testing.T) {
+	g := newGCS(t, InputConfig{MaxMissionItems: 5})
+	speed := item(1, CmdDoChangeSpeed, FrameGlobalInt, 0, 0, 0)
+	speed.Params[1] = 30
+	items := []MissionItemInt{
+		item(0, CmdNavTakeoff, FrameGlobalRelativeAltInt, 0, 0, 50),
+		speed,
+		item(2, CmdNavWaypoint, FrameGlobalInt, 47.01, 8.01, 1200),
+		item(3, CmdNavLand, FrameGlobalInt, 47.02, 8.01, 0),
+	}
+
+	// the sequence, with an item out of order asked for again
+	g.send(MissionCount{Count: 4, TargetSystem: 1, TargetComponent: 1})
+	for i, seq := range []uint16{0, 1, 2, 2, 3} {
+		req, ok := g.reply().(MissionRequestInt)
+		if want := (MissionRequestInt{Seq: seq, TargetSystem: 255, TargetComponent: 190}); !ok || req != want {
+			t.Fatalf("request %d: %+v, want %+v", i, req, want)
+		}
+		if i == 2 {
+			g.send(items[3])
+			continue
+		}
+		g.send(items[seq])
+	}
+	if ack := g.reply(); ack != (MissionAck{TargetSystem: 255, TargetComponent: 190, Type: MissionAccepted}) {
+		t.Fatalf("ack %+v", ack)
+	}
+	tr, ok := g.last(1).(sim.TrajectoryCommand)
+	if !ok || len(tr.Waypoints) != 3 {
+		t.Fatalf("mission flies %+v, want a trajectory of 3 waypoints", g.last(1))
+	}
+	if wp := tr.Waypoints[0]; !near(wp.Lat, 47) || !near(wp.Lon, 8) || wp.AGL != 50 || wp.Speed != 0 {
+		t.Errorf("takeoff %+v", wp)
+	}
+	if wp := tr.Waypoints[1]; !near(wp.Lat, 47.01) || !near(wp.Lon, 8.01) || wp.Alt != 1200 || wp.Speed != 30 {
+		t.Errorf("waypoint %+v", wp)
+	}
+	if wp := tr.Waypoints[2]; !near(wp.Lat, 47.02) || wp.Alt != 0 || wp.Speed != 30 {
+		t.Errorf("landing %+v", wp)
+	}
+
+	// answered without asking for items
+	for _, c := range []struct {
+		name  string
+		count MissionCount
+		want  uint8
+	}{
+		{"fence", MissionCount{Count: 3, MissionType: 1}, MissionUnsupported},
+		{"too long", MissionCount{Count: 6}, MissionNoSpace},
+		{"empty", MissionCount{Count: 0}, MissionAccepted},
+	} {
+		g.send(c.count)
+		if ack, ok := g.reply().(MissionAck); !ok || ack.Type != c.want || ack.MissionType != c.count.MissionType {
+			t.Errorf("%s: %+v, want result %d", c.name, ack, c.want)
+		}
+	}
+
+	// complete uploads that cannot be flown
+	for _, c := range []struct {
+		name  string
+		items []MissionItemInt
+		want  uint8
+	}{
+		{"local frame", []MissionItemInt{item(0, CmdNavWaypoint, 1, 47.01, 8, 100)}, MissionUnsupportedFrame},
+		{"no height", []MissionItemInt{item(0, CmdNavWaypoint, FrameGlobalRelativeAltInt, 47.01, 8, 0)}, MissionInvalidParam7},
+		{"latitude", []MissionItemInt{item(0, CmdNavWaypoint, FrameGlobalInt, 91, 8, 100)}, MissionInvalidParam5X},
+		{"unsupported", []MissionItemInt{item(0, 115, FrameGlobalInt, 0, 0, 0)}, MissionUnsupported},
+		{"only a speed", []MissionItemInt{speed}, MissionInvalid},
+		{"out of range", []MissionItemInt{item(0, CmdNavWaypoint, FrameGlobalInt, 10, 10, 100)}, MissionDenied},
+		{"return without a launch", []MissionItemInt{item(0, CmdNavReturnToLaunch, FrameGlobalInt, 0, 0, 0)}, MissionDenied},
+	} {
+		c.items[0].Seq = 0
+		if ack := g.upload(c.items...); ack.Type != c.want {
+			t.Errorf("%s: %+v, want result %d", c.name, ack, c.want)
+		}
+	}
+	g.commands(1)
+}
+
+func TestMissionUploadRetries(t *testing.T) {
+	g := newGCS(t, InputConfig{})
+	items := []MissionItemInt{
+		item(0, CmdNavWaypoint, FrameGlobalInt, 47.01, 8, 1100),
+		item(1, CmdNavWaypoint, FrameGlobalInt, 47.02, 8, 1100),
+	}
+	request := func(seq uint16) {
+		t.Helper()
+		if req, ok := g.reply().(MissionRequestInt); !ok || req.Seq != seq {
+			t.Fatalf("%+v, want a request for item %d", req, seq)
+		}
+	}
+
+	g.send(MissionCount{Count: 2, TargetSystem: 1, TargetComponent: 1})
+	request(0)
+	asked := g.in.upload.asked
+	g.in.checkUpload(asked.Add(missionRetryInterval - time.Millisecond))
+	g.quiet()
+	g.in.checkUpload(asked.Add(missionRetryInterval))
+	request(0)
+
+	// an item that arrives starts the retries over
+	g.send(items[0])
+	request(1)
+	// items from another ground station are not the upload's
+	g.sendFrom(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}, items[1])
+	g.quiet()
+	for i := 0; i < missionRetries; i++ {
+		g.in.checkUpload(g.in.upload.asked.Add(missionRetryInterval))
+		request(1)
+	}
+	g.in.checkUpload(g.in.upload.asked.Add(missionRetryInterval))
+	if ack, ok := g.reply().(MissionAck); !ok || ack.Type != MissionCancelled {
+		t.Fatalf("after %d retries: %+v, want a cancelled ack", missionRetries, ack)
+	}
+	if g.in.upload != nil {
+		t.Error("upload kept after it timed out")
+	}
+	// nothing of it is flown, and the late item is ignored
+	g.send(items[1])
+	g.quiet()
+	g.commands(0)
+
+	// a ground station cancels its own upload with MISSION_ACK
+	g.send(MissionCount{Count: 2, TargetSystem: 1, TargetComponent: 1})
+	request(0)
+	g.send(MissionAck{TargetSystem: 1, TargetComponent: 1, Type: MissionCancelled})
+	if g.in.upload != nil {
+		t.Error("upload kept after the ground station cancelled it")
+	}
+	g.send(items[0])
+	g.quiet()
+	g.commands(0)
+}
+
+// TestInputOverUDP runs an Input on its socket: commands are answered to
+// the sender, which becomes a peer, launch is known, and an item that does
+// not come is asked for again.
+func TestInputOverUDP(t *testing.T) {
+	g := newGCS(t, InputConfig{})
+	go g.in.Run(g.ctx)
+
+	g.write(CommandLong{Command: CmdNavReturnToLaunch, TargetSystem: 1})
+	if ack, ok := g.reply().(CommandAck); !ok || ack.Command != CmdNavReturnToLaunch || ack.Result != ResultAccepted {
+		t.Fatalf("return to launch: %+v", ack)
+	}
+	if cmd, ok := g.last(1).(sim.GoToCommand); !ok || !near(cmd.Lat, 47) || !near(cmd.Lon, 8) || cmd.Alt != 1000 {
+		t.Errorf("return to launch flies %+v", g.last(1))
+	}
+	if peers := g.link.Peers(); len(peers) != 1 || peers[0].String() != g.addr().String() {
+		t.Errorf("peers %v, want %v", peers, g.addr())
+	}
+
+	g.write(MissionCount{Count: 1, TargetSystem: 1, TargetComponent: 1})
+	if req, ok := g.reply().(MissionRequestInt); !ok || req.Seq != 0 {
+		t.Fatalf("%+v, want a request for item 0", req)
+	}
+	start := time.Now()
+	if req, ok := g.recv(2 * missionRetryInterval).(MissionRequestInt); !ok || req.Seq != 0 {
+		t.Fatalf("%+v, want item 0 asked for again", req)
+	}
+	if d := time.Since(start); d < missionRetryInterval-100*time.Millisecond {
+		t.Errorf("asked again after %v, before the %v retry interval", d, missionRetryInterval)
+	}
+	g.write(item(0, CmdNavWaypoint, FrameGlobalInt, 47.01, 8, 1100))
+	if ack, ok := g.reply().(MissionAck); !ok || ack.Type != MissionAccepted {
+		t.Fatalf("ack %+v", ack)
+	}
+	g.commands(2)
+}
@@ -0,0 +1,101 @@
+package mavlink
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// maxPeers bounds the ground stations a Link remembers.
+const maxPeers = 16
+
+// Link is the UDP socket the aircraft talks MAVLink on. Output and Input
+// share one, because a ground station answers to the address telemetry
+// comes from, and one sequence of frame numbers. It also remembers the
+// ground stations that have sent it frames, so that they get telemetry
+// without being named as targets.
+type Link struct {
+	conn *net.UDPConn
+
+	mu    sync.Mutex
+	enc   Encoder
+	peers map[string]*net.UDPAddr
+}
+
+// Listen opens a Link on addr, such as ":14555"; an empty addr picks a free
+// port, for a link that only sends. Zero ids mean DefaultSysID and
+// DefaultCompID.
+func Listen(addr string, sysID, compID uint8) (*Link, error) {
+	var laddr *net.UDPAddr
+	if addr != "" {
+		var err error
+		if laddr, err = net.ResolveUDPAddr("udp", addr); err != nil {
+			return nil, fmt.Errorf("mavlink listen %q: %w", addr, err)
+		}
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, fmt.Errorf("mavlink: %w", err)
+	}
+	if sysID == 0 {
+		sysID = DefaultSysID
+	}
+	if compID == 0 {
+		compID = DefaultCompID
+	}
+	return &Link{conn: conn, enc: Encoder{SysID: sysID, CompID: compID}, peers: map[string]*net.UDPAddr{}}, nil
+}
+
+// LocalAddr is the address the link listens on.
+func (l *Link) LocalAddr() net.Addr { return l.conn.LocalAddr() }
+
+// SysID and CompID are the aircraft's ids.
+func (l *Link) SysID() uint8  { return l.enc.SysID }
+func (l *Link) CompID() uint8 { return l.enc.CompID }
+
+// Close closes the socket.
+func (l *Link) Close() error { return l.conn.Close() }
+
+// Frame frames m as the aircraft's next frame.
+func (l *Link) Frame(m Message) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Frame(m)
+}
+
+// Send frames m and writes it to addr.
+func (l *Link) Send(m Message, addr *net.UDPAddr) error {
+	_, err := l.conn.WriteToUDP(l.Frame(m), addr)
+	return err
+}
+
+// write writes a framed message to addr.
+func (l *Link) write(frame []byte, addr *net.UDPAddr) error {
+	_, err := l.conn.WriteToUDP(frame, addr)
+	return err
+}
+
+// read reads one datagram.
+func (l *Link) read(b []byte) (int, *net.UDPAddr, error) {
+	return l.conn.ReadFromUDP(b)
+}
+
+// addPeer remembers a ground station that sent a frame, up to maxPeers.
+func (l *Link) addPeer(addr *net.UDPAddr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.peers) < maxPeers {
+		l.peers[addr.String()] = addr
+	}
+}
+
+// Peers returns the ground stations that have sent frames.
+func (l *Link) Peers() []*net.UDPAddr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	peers := make([]*net.UDPAddr, 0, len(l.peers))
+	for _, p := range l.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
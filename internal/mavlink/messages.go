@@ -0,0 +1,397 @@
+package mavlink
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Values of the HEARTBEAT fields used here, from the common dialect.
+const (
+	TypeGeneric      = 0 // MAV_TYPE_GENERIC
+	AutopilotGeneric = 0 // MAV_AUTOPILOT_GENERIC
+
+	ModeFlagGuided = 8   // MAV_MODE_FLAG_GUIDED_ENABLED
+	ModeFlagArmed  = 128 // MAV_MODE_FLAG_SAFETY_ARMED
+
+	StateStandby   = 3 // MAV_STATE_STANDBY
+	StateActive    = 4 // MAV_STATE_ACTIVE
+	StateEmergency = 6 // MAV_STATE_EMERGENCY
+
+	protocolVersion = 3
+)
+
+// Heartbeat (HEARTBEAT, #0) announces the system and its state; ground
+// stations drop a vehicle that stops sending it.
+type Heartbeat struct {
+	CustomMode   uint32
+	Type         uint8
+	Autopilot    uint8
+	BaseMode     uint8
+	SystemStatus uint8
+}
+
+func (Heartbeat) MsgID() uint32  { return 0 }
+func (Heartbeat) CRCExtra() byte { return 50 }
+
+func (m Heartbeat) payload() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, m.CustomMode)
+	return append(b, m.Type, m.Autopilot, m.BaseMode, m.SystemStatus, protocolVersion)
+}
+
+// GlobalPositionInt (GLOBAL_POSITION_INT, #33) is the position in the
+// protocol's integer units.
+type GlobalPositionInt struct {
+	TimeBootMs  uint32
+	Lat, Lon    int32 // degrees × 1e7
+	Alt         int32 // MSL, mm
+	RelativeAlt int32 // above the ground, mm
+	// Ground velocity, cm/s: Vx north, Vy east, Vz down.
+	Vx, Vy, Vz int16
+	Hdg        uint16 // centidegrees in [0, 35999], math.MaxUint16 when unknown
+}
+
+func (GlobalPositionInt) MsgID() uint32  { return 33 }
+func (GlobalPositionInt) CRCExtra() byte { return 104 }
+
+func (m GlobalPositionInt) payload() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, m.TimeBootMs)
+	for _, v := range []int32{m.Lat, m.Lon, m.Alt, m.RelativeAlt} {
+		b = binary.LittleEndian.AppendUint32(b, uint32(v))
+	}
+	for _, v := range []int16{m.Vx, m.Vy, m.Vz} {
+		b = binary.LittleEndian.AppendUint16(b, uint16(v))
+	}
+	return binary.LittleEndian.AppendUint16(b, m.Hdg)
+}
+
+// VFRHUD (VFR_HUD, #74) is what a head-up display shows.
+type VFRHUD struct {
+	Airspeed    float32 // m/s
+	Groundspeed float32 // m/s
+	Alt         float32 // MSL, m
+	Climb       float32 // m/s, positive up
+	Heading     int16   // degrees in [0, 360)
+	Throttle    uint16  // percent
+}
+
+func (VFRHUD) MsgID() uint32  { return 74 }
+func (VFRHUD) CRCExtra() byte { return 20 }
+
+func (m VFRHUD) payload() []byte {
+	var b []byte
+	for _, v := range []float32{m.Airspeed, m.Groundspeed, m.Alt, m.Climb} {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	b = binary.LittleEndian.AppendUint16(b, uint16(m.Heading))
+	return binary.LittleEndian.AppendUint16(b, m.Throttle)
+}
+
+// Attitude (ATTITUDE, #30) is the orientation in radians, yaw in
+// [-π, π], and its rates in rad/s.
+type Attitude struct {
+	TimeBootMs                      uint32
+	Roll, Pitch, Yaw                float32
+	RollSpeed, PitchSpeed, YawSpeed float32
+}
+
+func (Attitude) MsgID() uint32  { return 30 }
+func (Attitude) CRCExtra() byte { return 39 }
+
+func (m Attitude) payload() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, m.TimeBootMs)
+	for _, v := range []float32{m.Roll, m.Pitch, m.Yaw, m.RollSpeed, m.PitchSpeed, m.YawSpeed} {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	return b
+}
+
+// COMMAND_LONG commands handled, from MAV_CMD.
+const (
+	CmdNavWaypoint       = 16
+	CmdNavReturnToLaunch = 20
+	CmdNavLand           = 21
+	CmdNavTakeoff        = 22
+	CmdDoChangeSpeed     = 178
+)
+
+// Results of COMMAND_ACK, from MAV_RESULT.
+const (
+	ResultAccepted            = 0
+	ResultTemporarilyRejected = 1
+	ResultDenied              = 2
+	ResultUnsupported         = 3
+	ResultFailed              = 4
+)
+
+// CommandLong (COMMAND_LONG, #76) asks the vehicle to run a command.
+type CommandLong struct {
+	Params          [7]float32
+	Command         uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	Confirmation    uint8
+}
+
+func (CommandLong) MsgID() uint32  { return 76 }
+func (CommandLong) CRCExtra() byte { return 152 }
+
+func (m CommandLong) payload() []byte {
+	var b []byte
+	for _, v := range m.Params {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	b = binary.LittleEndian.AppendUint16(b, m.Command)
+	return append(b, m.TargetSystem, m.TargetComponent, m.Confirmation)
+}
+
+// CommandAck (COMMAND_ACK, #77) answers a COMMAND_LONG.
+type CommandAck struct {
+	Command         uint16
+	Result          uint8
+	Progress        uint8
+	ResultParam2    int32
+	TargetSystem    uint8
+	TargetComponent uint8
+}
+
+func (CommandAck) MsgID() uint32  { return 77 }
+func (CommandAck) CRCExtra() byte { return 143 }
+
+func (m CommandAck) payload() []byte {
+	b := binary.LittleEndian.AppendUint16(nil, m.Command)
+	b = append(b, m.Result, m.Progress)
+	b = binary.LittleEndian.AppendUint32(b, uint32(m.ResultParam2))
+	return append(b, m.TargetSystem, m.TargetComponent)
+}
+
+// Coordinate frames of positions, from MAV_FRAME.
+const (
+	FrameGlobal               = 0
+	FrameGlobalRelativeAlt    = 3
+	FrameGlobalInt            = 5
+	FrameGlobalRelativeAltInt = 6
+	FrameGlobalTerrainAlt     = 10
+	FrameGlobalTerrainAltInt  = 11
+)
+
+// Bits of SetPositionTargetGlobalInt.TypeMask that mark fields to ignore,
+// from POSITION_TARGET_TYPEMASK.
+const (
+	IgnorePosition = 0x0007
+	IgnoreVelocity = 0x0038
+)
+
+// SetPositionTargetGlobalInt (SET_POSITION_TARGET_GLOBAL_INT, #86) is a
+// position or velocity setpoint. Velocity is north, east, down in m/s.
+type SetPositionTargetGlobalInt struct {
+	TimeBootMs      uint32
+	LatInt, LonInt  int32 // degrees × 1e7
+	Alt             float32
+	Vx, Vy, Vz      float32
+	Afx, Afy, Afz   float32
+	Yaw, YawRate    float32
+	TypeMask        uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	CoordinateFrame uint8
+}
+
+func (SetPositionTargetGlobalInt) MsgID() uint32  { return 86 }
+func (SetPositionTargetGlobalInt) CRCExtra() byte { return 5 }
+
+func (m SetPositionTargetGlobalInt) payload() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, m.TimeBootMs)
+	b = binary.LittleEndian.AppendUint32(b, uint32(m.LatInt))
+	b = binary.LittleEndian.AppendUint32(b, uint32(m.LonInt))
+	for _, v := range []float32{m.Alt, m.Vx, m.Vy, m.Vz, m.Afx, m.Afy, m.Afz, m.Yaw, m.YawRate} {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	b = binary.LittleEndian.AppendUint16(b, m.TypeMask)
+	return append(b, m.TargetSystem, m.TargetComponent, m.CoordinateFrame)
+}
+
+// MissionTypeMission is the mission_type of a flight plan, as opposed to a
+// geofence or rally points.
+const MissionTypeMission = 0
+
+// Results of MISSION_ACK, from MAV_MISSION_RESULT.
+const (
+	MissionAccepted         = 0
+	MissionError            = 1
+	MissionUnsupportedFrame = 2
+	MissionUnsupported      = 3
+	MissionNoSpace          = 4
+	MissionInvalid          = 5
+	MissionInvalidParam5X   = 10
+	MissionInvalidParam6Y   = 11
+	MissionInvalidParam7    = 12
+	MissionInvalidSequence  = 13
+	MissionDenied           = 14
+	MissionCancelled        = 15
+)
+
+// MissionCount (MISSION_COUNT, #44) starts a mission upload.
+type MissionCount struct {
+	Count           uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	MissionType     uint8
+}
+
+func (MissionCount) MsgID() uint32  { return 44 }
+func (MissionCount) CRCExtra() byte { return 221 }
+
+func (m MissionCount) payload() []byte {
+	b := binary.LittleEndian.AppendUint16(nil, m.Count)
+	return append(b, m.TargetSystem, m.TargetComponent, m.MissionType)
+}
+
+// MissionItemInt (MISSION_ITEM_INT, #73) is one item of a mission.
+type MissionItemInt struct {
+	Params          [4]float32
+	X, Y            int32 // latitude, longitude × 1e7
+	Z               float32
+	Seq             uint16
+	Command         uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	Frame           uint8
+	Current         uint8
+	Autocontinue    uint8
+	MissionType     uint8
+}
+
+func (MissionItemInt) MsgID() uint32  { return 73 }
+func (MissionItemInt) CRCExtra() byte { return 38 }
+
+func (m MissionItemInt) payload() []byte {
+	var b []byte
+	for _, v := range m.Params {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(m.X))
+	b = binary.LittleEndian.AppendUint32(b, uint32(m.Y))
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(m.Z))
+	b = binary.LittleEndian.AppendUint16(b, m.Seq)
+	b = binary.LittleEndian.AppendUint16(b, m.Command)
+	return append(b, m.TargetSystem, m.TargetComponent, m.Frame, m.Current, m.Autocontinue, m.MissionType)
+}
+
+// MissionRequestInt (MISSION_REQUEST_INT, #51) asks for a mission item.
+type MissionRequestInt struct {
+	Seq             uint16
+	TargetSystem    uint8
+	TargetComponent uint8
+	MissionType     uint8
+}
+
+func (MissionRequestInt) MsgID() uint32  { return 51 }
+func (MissionRequestInt) CRCExtra() byte { return 196 }
+
+func (m MissionRequestInt) payload() []byte {
+	b := binary.LittleEndian.AppendUint16(nil, m.Seq)
+	return append(b, m.TargetSystem, m.TargetComponent, m.MissionType)
+}
+
+// MissionAck (MISSION_ACK, #47) ends a mission upload, or cancels it.
+type MissionAck struct {
+	TargetSystem    uint8
+	TargetComponent uint8
+	Type            uint8 // a Mission* result
+	MissionType     uint8
+}
+
+func (MissionAck) MsgID() uint32  { return 47 }
+func (MissionAck) CRCExtra() byte { return 153 }
+
+func (m MissionAck) payload() []byte {
+	return []byte{m.TargetSystem, m.TargetComponent, m.Type, m.MissionType}
+}
+
+// messageInfo is what Decode needs of a message: its checksum seed, the
+// length of the payload with the extensions read here, and a decoder.
+type messageInfo struct {
+	extra  byte
+	size   int
+	decode func(p []byte) Message
+}
+
+var messages = map[uint32]messageInfo{
+	0: {50, 9, func(p []byte) Message {
+		r := reader(p)
+		return Heartbeat{CustomMode: r.u32(), Type: r.u8(), Autopilot: r.u8(), BaseMode: r.u8(), SystemStatus: r.u8()}
+	}},
+	30: {39, 28, func(p []byte) Message {
+		r := reader(p)
+		return Attitude{TimeBootMs: r.u32(), Roll: r.f32(), Pitch: r.f32(), Yaw: r.f32(),
+			RollSpeed: r.f32(), PitchSpeed: r.f32(), YawSpeed: r.f32()}
+	}},
+	33: {104, 28, func(p []byte) Message {
+		r := reader(p)
+		return GlobalPositionInt{TimeBootMs: r.u32(), Lat: r.i32(), Lon: r.i32(), Alt: r.i32(), RelativeAlt: r.i32(),
+			Vx: r.i16(), Vy: r.i16(), Vz: r.i16(), Hdg: r.u16()}
+	}},
+	74: {20, 20, func(p []byte) Message {
+		r := reader(p)
+		return VFRHUD{Airspeed: r.f32(), Groundspeed: r.f32(), Alt: r.f32(), Climb: r.f32(), Heading: r.i16(), Throttle: r.u16()}
+	}},
+	76: {152, 33, func(p []byte) Message {
+		r := reader(p)
+		var m CommandLong
+		for i := range m.Params {
+			m.Params[i] = r.f32()
+		}
+		m.Command, m.TargetSystem, m.TargetComponent, m.Confirmation = r.u16(), r.u8(), r.u8(), r.u8()
+		return m
+	}},
+	77: {143, 10, func(p []byte) Message {
+		r := reader(p)
+		return CommandAck{Command: r.u16(), Result: r.u8(), Progress: r.u8(), ResultParam2: r.i32(),
+			TargetSystem: r.u8(), TargetComponent: r.u8()}
+	}},
+	86: {5, 53, func(p []byte) Message {
+		r := reader(p)
+		return SetPositionTargetGlobalInt{TimeBootMs: r.u32(), LatInt: r.i32(), LonInt: r.i32(), Alt: r.f32(),
+			Vx: r.f32(), Vy: r.f32(), Vz: r.f32(), Afx: r.f32(), Afy: r.f32(), Afz: r.f32(), Yaw: r.f32(), YawRate: r.f32(),
+			TypeMask: r.u16(), TargetSystem: r.u8(), TargetComponent: r.u8(), CoordinateFrame: r.u8()}
+	}},
+	44: {221, 5, func(p []byte) Message {
+		r := reader(p)
+		return MissionCount{Count: r.u16(), TargetSystem: r.u8(), TargetComponent: r.u8(), MissionType: r.u8()}
+	}},
+	73: {38, 38, func(p []byte) Message {
+		r := reader(p)
+		var m MissionItemInt
+		for i := range m.Params {
+			m.Params[i] = r.f32()
+		}
+		m.X, m.Y, m.Z, m.Seq, m.Command = r.i32(), r.i32(), r.f32(), r.u16(), r.u16()
+		m.TargetSystem, m.TargetComponent, m.Frame, m.Current, m.Autocontinue, m.MissionType = r.u8(), r.u8(), r.u8(), r.u8(), r.u8(), r.u8()
+		return m
+	}},
+	51: {196, 5, func(p []byte) Message {
+		r := reader(p)
+		return MissionRequestInt{Seq: r.u16(), TargetSystem: r.u8(), TargetComponent: r.u8(), MissionType: r.u8()}
+	}},
+	47: {153, 4, func(p []byte) Message {
+		r := reader(p)
+		return MissionAck{TargetSystem: r.u8(), TargetComponent: r.u8(), Type: r.u8(), MissionType: r.u8()}
+	}},
+}
+
+// reader reads little-endian fields off the front of a payload that is at
+// least as long as the fields.
+type reader []byte
+
+func (r *reader) next(n int) []byte {
+	b := (*r)[:n]
+	*r = (*r)[n:]
+	return b
+}
+
+func (r *reader) u8() uint8    { return r.next(1)[0] }
+func (r *reader) u16() uint16  { return binary.LittleEndian.Uint16(r.next(2)) }
+func (r *reader) i16() int16   { return int16(r.u16()) }
+func (r *reader) u32() uint32  { return binary.LittleEndian.Uint32(r.next(4)) }
+func (r *reader) i32() int32   { return int32(r.u32()) }
+func (r *reader) f32() float32 { return math.Float32frombits(r.u32()) }
@@ -0,0 +1,134 @@
+package mavlink
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// Defaults of OutputConfig and Listen.
+const (
+	DefaultRateHz = 5.0
+	DefaultSysID  = 1
+	DefaultCompID = 1 // MAV_COMP_ID_AUTOPILOT1
+)
+
+// heartbeatInterval is how often HEARTBEAT goes out, whatever the rate.
+const heartbeatInterval = time.Second
+
+// OutputConfig configures an Output.
+type OutputConfig struct {
+	// Targets are the host:port UDP addresses every frame is sent to,
+	// such as a ground station's 127.0.0.1:14550, besides the link's
+	// peers.
+	Targets []string
+	// RateHz is how many times a second the position, HUD and attitude
+	// messages are sent; zero means DefaultRateHz.
+	RateHz float64
+	// Logger receives send errors; nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Output sends the engine's telemetry as MAVLink 2 over a Link: a
+// HEARTBEAT every second, and GLOBAL_POSITION_INT, VFR_HUD and ATTITUDE
+// for each state of a subscription limited to the configured rate.
+type Output struct {
+	link    *Link
+	targets []*net.UDPAddr
+	rate    float64
+	log     *slog.Logger
+	failing map[string]bool // targets whose last send failed, logged once
+}
+
+// NewOutput resolves the targets telemetry is sent to over link.
+func NewOutput(link *Link, cfg OutputConfig) (*Output, error) {
+	if cfg.RateHz < 0 {
+		return nil, fmt.Errorf("mavlink: rate %g Hz is negative", cfg.RateHz)
+	}
+	o := &Output{link: link, rate: cfg.RateHz, log: cfg.Logger, failing: map[string]bool{}}
+	if o.rate == 0 {
+		o.rate = DefaultRateHz
+	}
+	if o.log == nil {
+		o.log = slog.Default()
+	}
+	for _, t := range cfg.Targets {
+		addr, err := net.ResolveUDPAddr("udp", t)
+		if err != nil {
+			return nil, fmt.Errorf("mavlink target %q: %w", t, err)
+		}
+		o.targets = append(o.targets, addr)
+	}
+	return o, nil
+}
+
+// Run sends eng's telemetry until ctx is done or the engine stops.
+func (o *Output) Run(ctx context.Context, eng *sim.Engine) {
+	states, unsub := eng.Subscribe(ctx, sim.WithMaxRate(o.rate))
+	defer unsub()
+	tick := time.NewTicker(heartbeatInterval)
+	defer tick.Stop()
+
+	var last sim.AircraftState
+	var boot time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			if !last.TS.IsZero() {
+				o.send(heartbeat(last))
+			}
+		case st, ok := <-states:
+			if !ok {
+				return
+			}
+			if boot.IsZero() {
+				boot = st.TS
+				o.send(heartbeat(st))
+			}
+			o.send(globalPosition(st, boot))
+			o.send(vfrHUD(st))
+			o.send(attitude(st, last, boot))
+			last = st
+		}
+	}
+}
+
+// send frames m and writes it to every target and peer. A destination
+// that cannot be reached is logged when it starts failing and when it
+// recovers.
+func (o *Output) send(m Message) {
+	frame := o.link.Frame(m)
+	dests := o.targets
+	for _, p := range o.link.Peers() {
+		if !o.isTarget(p) {
+			dests = append(dests[:len(dests):len(dests)], p)
+		}
+	}
+	for _, addr := range dests {
+		key := addr.String()
+		err := o.link.write(frame, addr)
+		switch {
+		case err != nil && !o.failing[key]:
+			o.failing[key] = true
+			o.log.Warn("mavlink send failed", "target", key, "err", err)
+		case err == nil && o.failing[key]:
+			delete(o.failing, key)
+			o.log.Info("mavlink send recovered", "target", key)
+		}
+	}
+}
+
+func (o *Output) isTarget(addr *net.UDPAddr) bool {
+	for _, t := range o.targets {
+		if t.String() == addr.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package mavlink
+
+import (
+	"math"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// The state is mapped to MAVLink as follows. Positions are scaled to the
+// protocol's integers (degrees × 1e7, millimetres, cm/s); the sim's ground
+// velocity, east/north/up, becomes north/east/down. The heading of
+// GLOBAL_POSITION_INT and VFR_HUD is the state's headingDeg, true and from
+// the air velocity, while ATTITUDE carries rollDeg, pitchDeg and yawDeg.
+// The sim has no home position, so relative_alt is the height above the
+// terrain where that is known and the altitude otherwise.
+
+// heartbeat describes the aircraft: always armed and guided, active while
+// it flies a command, in emergency once ditched.
+func heartbeat(st sim.AircraftState) Heartbeat {
+	hb := Heartbeat{
+		Type:         TypeGeneric,
+		Autopilot:    AutopilotGeneric,
+		BaseMode:     ModeFlagArmed | ModeFlagGuided,
+		SystemStatus: StateStandby,
+	}
+	switch {
+	case st.Ditched:
+		hb.SystemStatus = StateEmergency
+	case st.ActiveCommand != "":
+		hb.SystemStatus = StateActive
+	}
+	return hb
+}
+
+func globalPosition(st sim.AircraftState, boot time.Time) GlobalPositionInt {
+	rel := st.Alt
+	if st.AGLM != nil {
+		rel = *st.AGLM
+	}
+	return GlobalPositionInt{
+		TimeBootMs:  bootMs(st.TS, boot),
+		Lat:         int32(math.Round(st.Lat * 1e7)),
+		Lon:         int32(math.Round(st.Lon * 1e7)),
+		Alt:         int32(math.Round(st.Alt * 1000)),
+		RelativeAlt: int32(math.Round(rel * 1000)),
+		Vx:          clampInt16(st.GVy * 100),
+		Vy:          clampInt16(st.GVx * 100),
+		Vz:          clampInt16(-st.GVz * 100),
+		Hdg:         uint16(math.Round(wrap360(st.HeadingDeg)*100)) % 36000,
+	}
+}
+
+func vfrHUD(st sim.AircraftState) VFRHUD {
+	return VFRHUD{
+		Airspeed:    float32(math.Hypot(st.Vx, st.Vy)),
+		Groundspeed: float32(st.GroundSpeedMps),
+		Alt:         float32(st.Alt),
+		Climb:       float32(st.VerticalSpeedMps),
+		Heading:     int16(math.Round(wrap360(st.HeadingDeg))) % 360,
+	}
+}
+
+// attitude converts st's attitude, with the rates taken against prev; a
+// zero prev gives zero rates.
+func attitude(st, prev sim.AircraftState, boot time.Time) Attitude {
+	a := Attitude{
+		TimeBootMs: bootMs(st.TS, boot),
+		Roll:       float32(radians(st.RollDeg)),
+		Pitch:      float32(radians(st.PitchDeg)),
+		Yaw:        float32(radians(wrap180(st.YawDeg))),
+	}
+	if dt := st.TS.Sub(prev.TS).Seconds(); !prev.TS.IsZero() && dt > 0 {
+		a.RollSpeed = float32(radians(st.RollDeg-prev.RollDeg) / dt)
+		a.PitchSpeed = float32(radians(st.PitchDeg-prev.PitchDeg) / dt)
+		a.YawSpeed = float32(radians(wrap180(st.YawDeg-prev.YawDeg)) / dt)
+	}
+	return a
+}
+
+// bootMs is the simulation time from boot to ts, as MAVLink's time since
+// boot.
+func bootMs(ts, boot time.Time) uint32 {
+	if ts.Before(boot) {
+		return 0
+	}
+	return uint32(ts.Sub(boot).Milliseconds())
+}
+
+func clampInt16(v float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v))))
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// wrap360 maps deg into [0, 360).
+func wrap360(deg float64) float64 {
+	d := math.Mod(deg, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// wrap180 maps deg into [-180, 180).
+func wrap180(deg float64) float64 {
+	return wrap360(deg+180) - 180
+}
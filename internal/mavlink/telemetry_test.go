@@ -0,0 +1,145 @@
+package mavlink
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/fakeclock"
+)
+
+func TestTelemetryMapping(t *testing.T) {
+	boot := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	agl := 250.4
+	st := sim.AircraftState{
+		Lat: 47.1234567, Lon: -8.7654321, Alt: 1200.25, AGLM: &agl,
+		Vx: 30, Vy: 40, GVx: 12.34, GVy: -20.5, GVz: 1.5,
+		GroundSpeedMps: 23.9, VerticalSpeedMps: 1.5,
+		HeadingDeg: -90.4, RollDeg: 10, PitchDeg: -5, YawDeg: 200,
+		TS: boot.Add(2500 * time.Millisecond), ActiveCommand: "goto",
+	}
+
+	gp := globalPosition(st, boot)
+	want := GlobalPositionInt{TimeBootMs: 2500, Lat: 471234567, Lon: -87654321, Alt: 1200250, RelativeAlt: 250400,
+		// north, east, down
+		Vx: -2050, Vy: 1234, Vz: -150, Hdg: 26960}
+	if gp != want {
+		t.Errorf("GLOBAL_POSITION_INT %+v, want %+v", gp, want)
+	}
+	if gp := globalPosition(sim.AircraftState{Alt: 80, TS: boot}, boot); gp.RelativeAlt != 80000 {
+		t.Errorf("relative_alt without terrain %d, want the altitude", gp.RelativeAlt)
+	}
+	if gp := globalPosition(sim.AircraftState{GVx: 500, TS: boot}, boot); gp.Vy != math.MaxInt16 {
+		t.Errorf("vy at 500 m/s = %d, want it clamped", gp.Vy)
+	}
+
+	hud := vfrHUD(st)
+	if hud.Airspeed != 50 || hud.Groundspeed != 23.9 || hud.Alt != 1200.25 || hud.Climb != 1.5 || hud.Heading != 270 {
+		t.Errorf("VFR_HUD %+v", hud)
+	}
+
+	prev := st
+	prev.TS = st.TS.Add(-500 * time.Millisecond)
+	prev.RollDeg, prev.YawDeg = 5, 170
+	att := attitude(st, prev, boot)
+	near := func(got float32, want float64) bool { return math.Abs(float64(got)-want) < 1e-6 }
+	// yaw 200° is -160°, and the rates are per second
+	if !near(att.Roll, 10*math.Pi/180) || !near(att.Pitch, -5*math.Pi/180) || !near(att.Yaw, -160*math.Pi/180) ||
+		!near(att.RollSpeed, 10*math.Pi/180) || att.PitchSpeed != 0 || !near(att.YawSpeed, 60*math.Pi/180) {
+		t.Errorf("ATTITUDE %+v", att)
+	}
+	if att := attitude(st, sim.AircraftState{}, boot); att.RollSpeed != 0 || att.YawSpeed != 0 {
+		t.Errorf("ATTITUDE without a previous state %+v", att)
+	}
+
+	for _, c := range []struct {
+		st   sim.AircraftState
+		want uint8
+	}{
+		{sim.AircraftState{}, StateStandby},
+		{st, StateActive},
+		{sim.AircraftState{Ditched: true, ActiveCommand: "goto"}, StateEmergency},
+	} {
+		if hb := heartbeat(c.st); hb.SystemStatus != c.want || hb.BaseMode != ModeFlagArmed|ModeFlagGuided {
+			t.Errorf("%+v: HEARTBEAT %+v", c.st, hb)
+		}
+	}
+}
+
+func TestOutputSendsTelemetry(t *testing.T) {
+	gcs, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gcs.Close()
+
+	clk := fakeclock.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, InitialAlt: 500, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := Listen("", 42, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer link.Close()
+	out, err := NewOutput(link, OutputConfig{Targets: []string{gcs.LocalAddr().String()}, RateHz: 20,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		eng.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	clk.WaitForTicker()
+	go out.Run(ctx, eng)
+
+	// tick until the first states come out: a heartbeat, then the
+	// position, HUD and attitude of each
+	got := map[uint32]Frame{}
+	buf := make([]byte, 512)
+	for deadline := time.Now().Add(5 * time.Second); len(got) < 4 && time.Now().Before(deadline); {
+		clk.Advance(50 * time.Millisecond)
+		gcs.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+		for {
+			n, err := gcs.Read(buf)
+			if err != nil {
+				break
+			}
+			frames, err := Decode(buf[:n])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range frames {
+				if f.SysID != 42 || f.CompID != DefaultCompID {
+					t.Errorf("frame from %d/%d", f.SysID, f.CompID)
+				}
+				got[f.Msg.MsgID()] = f
+			}
+		}
+	}
+	if len(got) < 4 {
+		t.Fatalf("got messages %v, want all four", got)
+	}
+	if hb := got[0].Msg.(Heartbeat); hb.SystemStatus != StateStandby {
+		t.Errorf("HEARTBEAT %+v", hb)
+	}
+	if gp := got[33].Msg.(GlobalPositionInt); gp.Lat != 470000000 || gp.Lon != 80000000 || gp.Alt != 500000 {
+		t.Errorf("GLOBAL_POSITION_INT %+v", gp)
+	}
+	if hud := got[74].Msg.(VFRHUD); hud.Alt != 500 {
+		t.Errorf("VFR_HUD %+v", hud)
+	}
+}
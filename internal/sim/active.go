@@ -0,0 +1,164 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// ErrNoActiveCommand is returned by Active and CancelActive when the
+// aircraft is flying no command.
+var ErrNoActiveCommand = errors.New("no active command")
+
+// ActiveCommand describes the command the aircraft is flying.
+type ActiveCommand struct {
+	Type CommandType `json:"type"`
+	// Command holds its parameters, altitudes converted to MSL as the
+	// engine flies them.
+	Command Command `json:"command"`
+	Source  string  `json:"source,omitempty"`
+
+	// ReceivedAt is the command's At, stamped by the caller with
+	// Engine.Now. StartedAt is when the loop took the command off its
+	// queue, read from the same clock, so it is never before ReceivedAt.
+	// Under Step there is no running clock and StartedAt is simulation
+	// time, the Config.StartTime base ElapsedS is measured in.
+	ReceivedAt time.Time `json:"receivedAt"`
+	StartedAt  time.Time `json:"startedAt"`
+	ElapsedS   float64   `json:"elapsedS"`
+
+	// TargetIndex is the waypoint a trajectory is flying to; its
+	// waypoints are in Command.
+	TargetIndex *int `json:"targetIndex,omitempty"`
+	// Progress is left out for a hold.
+	Progress *CommandProgress `json:"progress,omitempty"`
+}
+
+// CommandProgress measures a goto or trajectory, in straight lines between
+// its points.
+type CommandProgress struct {
+	// DistanceToTargetM is to the goto target or the current waypoint.
+	DistanceToTargetM float64 `json:"distanceToTargetM"`
+	// RemainingM is DistanceToTargetM plus the legs after the target; for
+	// a looping trajectory up to the end of the lap.
+	RemainingM float64 `json:"remainingM"`
+	// TotalM is the route from where the command was taken; for a loop
+	// past its first lap, from the last waypoint.
+	TotalM float64 `json:"totalM"`
+	// Fraction is the share of TotalM flown, 0 to 1.
+	Fraction float64 `json:"fraction"`
+
+	WaypointsReached int `json:"waypointsReached,omitempty"` // this lap
+	Laps             int `json:"laps,omitempty"`             // completed laps of a loop
+}
+
+// Active returns the command being flown, or ErrNoActiveCommand.
+func (e *Engine) Active(ctx context.Context) (ActiveCommand, error) {
+	var (
+		a  ActiveCommand
+		ok bool
+	)
+	err := e.call(ctx, func() { a, ok = e.activeCommand() })
+	if err == nil && !ok {
+		err = ErrNoActiveCommand
+	}
+	return a, err
+}
+
+// CancelActive replaces the command being flown with a hold, as a hold
+// submitted from ctx's command source would. It returns the type of the
+// command it cancelled; a hold is left as it is. Without a command it
+// returns ErrNoActiveCommand.
+func (e *Engine) CancelActive(ctx context.Context) (CommandType, error) {
+	if e.replay != nil {
+		return "", ErrReplay
+	}
+	var cancelled CommandType
+	err := e.call(ctx, func() {
+		if e.active == nil {
+			return
+		}
+		cancelled = e.active.Type()
+		if cancelled != CmdHold {
+			e.handleCommand(submission{cmd: HoldCommand{At: e.now}, source: commandSource(ctx), client: commandClient(ctx)})
+		}
+	})
+	if err == nil && cancelled == "" {
+		err = ErrNoActiveCommand
+	}
+	return cancelled, err
+}
+
+// takenAt is when the loop takes a command off its queue, or ends the
+// active one (see endCommandRecord). While Run is
+// going it is read from the clock commands are stamped with (Engine.Now),
+// which the last tick can be up to a tick behind; under Step it is
+// simulation time.
+func (e *Engine) takenAt() time.Time {
+	if e.running {
+		return e.clock.Now()
+	}
+	return e.now
+}
+
+// startActive notes when and where the active command was taken. It runs
+// inside the actor.
+func (e *Engine) startActive(at time.Time) {
+	e.activeSince, e.activeFrom, e.trajLaps = at, e.pos, 0
+}
+
+func (e *Engine) activeCommand() (ActiveCommand, bool) {
+	if e.active == nil {
+		return ActiveCommand{}, false
+	}
+	a := ActiveCommand{
+		Type:       e.active.Type(),
+		Command:    e.active,
+		Source:     e.activeSource,
+		ReceivedAt: e.active.ReceivedAt(),
+		StartedAt:  e.activeSince,
+		ElapsedS:   math.Max(e.now.Sub(e.activeSince).Seconds(), 0),
+	}
+	switch c := e.active.(type) {
+	case GoToCommand:
+		target := e.geo.GeoToLocal(c.Lat, c.Lon, c.Alt)
+		a.Progress = progress(target.Sub(e.pos).Length(), 0, target.Sub(e.activeFrom).Length())
+	case TrajectoryCommand:
+		if e.trajIdx < 0 || e.trajIdx >= len(e.traj) {
+			break
+		}
+		idx := e.trajIdx
+		a.TargetIndex = &idx
+		pts := make([]vector.Vec3, len(e.traj))
+		for i, wp := range e.traj {
+			pts[i] = e.geo.GeoToLocal(wp.Lat, wp.Lon, wp.Alt)
+		}
+		legs := func(from, to int) float64 {
+			d := 0.0
+			for i := from; i < to; i++ {
+				d += pts[i+1].Sub(pts[i]).Length()
+			}
+			return d
+		}
+		start := e.activeFrom
+		if e.trajLaps > 0 {
+			start = pts[len(pts)-1]
+		}
+		total := pts[0].Sub(start).Length() + legs(0, len(pts)-1)
+		a.Progress = progress(pts[idx].Sub(e.pos).Length(), legs(idx, len(pts)-1), total)
+		a.Progress.WaypointsReached = idx
+		a.Progress.Laps = e.trajLaps
+	}
+	return a, true
+}
+
+func progress(toTarget, after, total float64) *CommandProgress {
+	p := &CommandProgress{DistanceToTargetM: toTarget, RemainingM: toTarget + after, TotalM: total, Fraction: 1}
+	if total > 0 {
+		p.Fraction = math.Min(math.Max(1-p.RemainingM/total, 0), 1)
+	}
+	return p
+}
@@ -0,0 +1,54 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Atmosphere scales performance with air density from the International
+// Standard Atmosphere. It is off unless Enabled, so the limits then hold
+// at every altitude.
+type Atmosphere struct {
+	Enabled bool
+	// Exponent k: the climb rate and commanded speed are multiplied by σ^k,
+	// σ being the density ratio to sea level (default 1).
+	Exponent float64
+	// TempOffsetC shifts the ISA temperature (ISA+15 is a hot day).
+	TempOffsetC float64
+}
+
+// Validate rejects negative or non-finite values.
+func (a Atmosphere) Validate() error {
+	if math.IsNaN(a.Exponent) || math.IsInf(a.Exponent, 0) || a.Exponent < 0 {
+		return fmt.Errorf("atmosphere exponent must be >= 0")
+	}
+	if math.IsNaN(a.TempOffsetC) || math.IsInf(a.TempOffsetC, 0) || a.TempOffsetC <= -200 {
+		return fmt.Errorf("atmosphere temperature offset out of range")
+	}
+	return nil
+}
+
+func (a Atmosphere) withDefaults() Atmosphere {
+	if a.Exponent == 0 {
+		a.Exponent = 1
+	}
+	return a
+}
+
+// scale reduces the desired velocity by the density factor at altM.
+func (a Atmosphere) scale(desired vector.Vec3, altM float64) vector.Vec3 {
+	if !a.Enabled {
+		return desired
+	}
+	f := math.Pow(env.DensityRatio(altM, a.TempOffsetC), a.Exponent)
+	return desired.Mul(math.Min(f, 1))
+}
+
+// densityAltitude is the value for AircraftState.DensityAltitudeM.
+func (a Atmosphere) densityAltitude(altM float64) float64 {
+	_, _, rho := env.ISA(altM, a.TempOffsetC)
+	return env.DensityAltitude(rho)
+}
@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// DefaultAttitudeTimeConstS is the attitude smoothing time constant used
+// when Config.AttitudeTimeConstS is zero.
+const DefaultAttitudeTimeConstS = 0.5
+
+// Below this horizontal air speed (m/s) yaw is held and pitch/roll go to zero.
+const attitudeMinSpeed = 0.5
+
+// attitude is an approximate orientation derived from the motion: yaw from
+// the air velocity heading, pitch from the flight path angle and roll from
+// the bank a coordinated turn would need for the current lateral
+// acceleration. The orientation is held as a quaternion, rotating the body
+// axes (forward, right, down) to north-east-down, and follows its target
+// along the shorter arc through a first-order lag, so that it turns
+// smoothly through any combination of angles.
+type attitude struct {
+	tau float64
+
+	q       vector.Quat
+	prevVel vector.Vec3
+}
+
+// euler returns the roll, pitch and yaw (degrees) of the orientation, yaw
+// in [0, 360).
+func (a *attitude) euler() (roll, pitch, yaw float64) {
+	r, p, y := a.q.ToEuler()
+	return r * 180 / math.Pi, p * 180 / math.Pi, math.Mod(y*180/math.Pi+360, 360)
+}
+
+// attitudeQuat is the orientation of roll, pitch and yaw in degrees.
+func attitudeQuat(roll, pitch, yaw float64) vector.Quat {
+	return vector.QuatFromEuler(roll*math.Pi/180, pitch*math.Pi/180, yaw*math.Pi/180)
+}
+
+// update moves the attitude toward the one implied by vel, dt seconds after
+// the previous update.
+func (a *attitude) update(vel vector.Vec3, dt float64) {
+	if dt <= 0 {
+		return
+	}
+	accel := vel.Sub(a.prevVel).Mul(1 / dt)
+	a.prevVel = vel
+
+	_, _, held := a.euler()
+	roll, pitch, yaw := 0.0, 0.0, held
+	if hs := vel.Horizontal().Length(); hs >= attitudeMinSpeed {
+		yaw = HeadingDegFromVec(vel)
+		pitch = math.Atan2(vel.Z, hs) * 180 / math.Pi
+		// Lateral acceleration, positive to the right of the track.
+		lat := accel.XY().Cross(vel.XY()) / hs
+		roll = math.Atan2(lat, gravity) * 180 / math.Pi
+	}
+
+	k := 1.0
+	if a.tau > 0 {
+		k = 1 - math.Exp(-dt/a.tau)
+	}
+	a.q = a.q.Slerp(attitudeQuat(roll, pitch, yaw), k)
+}
+
+// reset jumps straight to the attitude implied by vel, e.g. after a restore.
+func (a *attitude) reset(vel vector.Vec3) {
+	a.prevVel = vel
+	_, _, yaw := a.euler()
+	pitch := 0.0
+	if hs := vel.Horizontal().Length(); hs >= attitudeMinSpeed {
+		yaw = HeadingDegFromVec(vel)
+		pitch = math.Atan2(vel.Z, hs) * 180 / math.Pi
+	}
+	a.q = attitudeQuat(0, pitch, yaw)
+}
@@ -0,0 +1,55 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestTurnBankLimited(t *testing.T) {
+	const g = 9.80665
+	for _, bank := range []float64{20, 30, 45} {
+		h, err := simtest.New(sim.Config{
+			OriginLat: 47, OriginLon: 8,
+			InitialVy:          80, // north at 80 m/s
+			Limits:             sim.Limits{MaxBankDeg: bank},
+			AttitudeTimeConstS: 0.05,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a target far to the east: a right turn of 90 degrees
+		if err := h.Submit(sim.GoToCommand{Lat: 47, Lon: 8.2, Alt: 1000, Speed: 80}); err != nil {
+			t.Fatal(err)
+		}
+		prev := h.Steps(1)
+		peakRoll, minRatio := 0.0, math.Inf(1)
+		for i := 0; i < 20*30; i++ {
+			st := h.Steps(1)
+			if st.RollDeg > bank+1e-9 || st.RollDeg < -1e-9 {
+				t.Fatalf("bank %g: roll %.3f at step %d", bank, st.RollDeg, i)
+			}
+			peakRoll = math.Max(peakRoll, st.RollDeg)
+
+			v := st.GroundSpeedMps
+			turn := math.Remainder(st.TrackDeg-prev.TrackDeg, 360) * math.Pi / 180
+			prev = st
+			if math.Abs(turn) < 1e-6 || v < 5 {
+				continue
+			}
+			// radius of the flown track against the tightest the bank allows
+			r := v * h.Dt / math.Abs(turn)
+			minR := v * v / (g * math.Tan(bank*math.Pi/180))
+			minRatio = math.Min(minRatio, r/minR)
+		}
+		if math.Abs(peakRoll-bank) > 0.5 {
+			t.Errorf("bank %g: peak roll %.2f", bank, peakRoll)
+		}
+		// a saturated turn flies the v²/(g·tan φ) radius, never tighter
+		if math.Abs(minRatio-1) > 0.02 {
+			t.Errorf("bank %g: tightest turn radius is %.3f of v²/(g·tan φ)", bank, minRatio)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnCeiling is the code of the warning raised while the service ceiling
+// holds the aircraft down.
+const WarnCeiling = "ceiling"
+
+// ceilingWarning is raised when a climb is refused at the ceiling.
+func (e *Engine) ceilingWarning() []Warning {
+	return []Warning{{
+		Code:     WarnCeiling,
+		Severity: env.SeverityCaution,
+		Message:  fmt.Sprintf("climb refused at the %.0f m service ceiling", e.ceiling),
+	}}
+}
+
+// limitClimb clamps the desired vertical velocity so the aircraft levels
+// off at the ceiling: no faster than it can stop within the remaining
+// height under MaxVertAccel, and never through it within dt.
+func (e *Engine) limitClimb(desired vector.Vec3, dt float64) (vector.Vec3, []Warning) {
+	if e.ceiling == 0 || desired.Z <= 0 {
+		return desired, nil
+	}
+	left := max(e.ceiling-e.pos.Z, 0)
+	room := min(left/dt, math.Sqrt(2*e.limits.MaxVertAccel*left))
+	if desired.Z <= room {
+		return desired, nil
+	}
+	desired.Z = max(room, 0)
+	if e.pos.Z < e.ceiling {
+		// still levelling off below it
+		return desired, nil
+	}
+	return desired, e.ceilingWarning()
+}
+
+// clipCeiling keeps the position at or below the ceiling after a step,
+// for overshoots the smoothed velocity could not avoid.
+func (e *Engine) clipCeiling() []Warning {
+	if e.ceiling == 0 || e.pos.Z <= e.ceiling {
+		return nil
+	}
+	e.pos.Z = e.ceiling
+	if e.vel.Z > 0 {
+		e.vel.Z = 0
+	}
+	return e.ceilingWarning()
+}
+
+// Ceiling returns the service ceiling in metres, or 0 when there is none.
+// It is fixed when the engine is created.
+func (e *Engine) Ceiling() float64 { return e.ceiling }
+
+// validateCeiling checks that the ceiling clears the highest terrain floor
+// and the initial altitude.
+func validateCeiling(cfg Config) error {
+	c := cfg.CeilingM
+	if c == 0 {
+		return nil
+	}
+	if !(c > 0) || c > 1e6 {
+		return fmt.Errorf("ceiling must be a positive altitude")
+	}
+	if cfg.Environment != nil {
+		if floor, ok := env.MaxMinAltitude(cfg.Environment); ok && c < floor {
+			return fmt.Errorf("ceiling %.1f m is below the highest terrain safety floor %.1f m", c, floor)
+		}
+	}
+	if cfg.InitialAlt > c {
+		return fmt.Errorf("initial altitude %.1f m is above the ceiling %.1f m", cfg.InitialAlt, c)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxCommandLog is how many commands the engine remembers for CommandLog;
+// older records are dropped.
+const MaxCommandLog = 1000
+
+// CommandOutcome is what became of a command.
+type CommandOutcome string
+
+const (
+	// OutcomeActive is a command still being flown.
+	OutcomeActive CommandOutcome = "active"
+	// OutcomeCompleted is a goto or trajectory that arrived, or a stop or
+	// set-state, which complete as they are taken.
+	OutcomeCompleted CommandOutcome = "completed"
+	// OutcomeSuperseded is a command ended before it completed, by
+	// another command, a reset, an empty battery or ditching.
+	OutcomeSuperseded CommandOutcome = "superseded"
+	// OutcomeRejected is a command the engine refused: its queue was full,
+	// it is replaying a recording, or a target was beyond the maximum
+	// operating radius.
+	OutcomeRejected CommandOutcome = "rejected"
+)
+
+// ParseCommandOutcome validates an outcome name.
+func ParseCommandOutcome(s string) (CommandOutcome, error) {
+	switch o := CommandOutcome(s); o {
+	case OutcomeActive, OutcomeCompleted, OutcomeSuperseded, OutcomeRejected:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown outcome %q (want %s, %s, %s or %s)", s,
+		OutcomeActive, OutcomeCompleted, OutcomeSuperseded, OutcomeRejected)
+}
+
+// CommandRecord is one command in the command log.
+type CommandRecord struct {
+	Seq     uint64      `json:"seq"` // numbers the commands from 1
+	Type    CommandType `json:"type"`
+	Command Command     `json:"command"`
+	// Source and Client are where the command came from (see
+	// WithCommandSource and WithCommandClient).
+	Source string `json:"source,omitempty"`
+	Client string `json:"client,omitempty"`
+
+	ReceivedAt time.Time      `json:"receivedAt"`        // the command's At
+	TakenAt    *time.Time     `json:"takenAt,omitempty"` // by the engine, on ReceivedAt's clock (see ActiveCommand)
+	Outcome    CommandOutcome `json:"outcome"`
+	EndedAt    *time.Time     `json:"endedAt,omitempty"`
+	// SupersededBy is what ended a superseded command: a command type,
+	// "reset", or the warning that ended it (battery_empty, ditched).
+	SupersededBy string `json:"supersededBy,omitempty"`
+	// Detail says why a command was rejected.
+	Detail string `json:"detail,omitempty"`
+}
+
+// CommandLogQuery selects records from the command log. Zero fields select
+// everything.
+type CommandLogQuery struct {
+	Type    CommandType
+	Outcome CommandOutcome
+	Limit   int // the latest Limit matching records
+}
+
+type commandClientKey struct{}
+
+// WithCommandClient returns a copy of ctx that makes Submit record client,
+// such as a remote address, with the command in the command log.
+func WithCommandClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, commandClientKey{}, client)
+}
+
+func commandClient(ctx context.Context) string {
+	c, _ := ctx.Value(commandClientKey{}).(string)
+	return c
+}
+
+// CommandLog returns the commands matching q, oldest first.
+func (e *Engine) CommandLog(ctx context.Context, q CommandLogQuery) ([]CommandRecord, error) {
+	var out []CommandRecord
+	err := e.call(ctx, func() {
+		for i := len(e.cmdLog) - 1; i >= 0 && (q.Limit <= 0 || len(out) < q.Limit); i-- {
+			r := e.cmdLog[i]
+			if (q.Type == "" || r.Type == q.Type) && (q.Outcome == "" || r.Outcome == q.Outcome) {
+				out = append(out, *r)
+			}
+		}
+	})
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, err
+}
+
+// logCommand adds the command sub the engine takes at now (see takenAt).
+// Stops and set-states complete at once; other commands become the active
+// record. It runs inside the actor.
+func (e *Engine) logCommand(sub submission, now time.Time) {
+	r := e.addCommandRecord(sub)
+	r.TakenAt = &now
+	switch sub.cmd.Type() {
+	case CmdStop, CmdSetState:
+		r.Outcome, r.EndedAt = OutcomeCompleted, &now
+	default:
+		r.Outcome = OutcomeActive
+		e.activeRec = r
+	}
+}
+
+// logRejected adds a command Submit refused with err. It does not wait
+// for the actor: when the actor is too busy to take it, the record is lost.
+func (e *Engine) logRejected(sub submission, err error) {
+	select {
+	case e.callCh <- func() { e.rejectCommand(sub, err) }:
+	default:
+	}
+}
+
+// rejectCommand adds sub to the log as refused with err. It runs inside
+// the actor.
+func (e *Engine) rejectCommand(sub submission, err error) {
+	r := e.addCommandRecord(sub)
+	r.Outcome, r.Detail = OutcomeRejected, err.Error()
+	r.EndedAt = &r.ReceivedAt
+}
+
+func (e *Engine) addCommandRecord(sub submission) *CommandRecord {
+	e.cmdSeq++
+	r := &CommandRecord{
+		Seq:        e.cmdSeq,
+		Type:       sub.cmd.Type(),
+		Command:    sub.cmd,
+		Source:     sub.source,
+		Client:     sub.client,
+		ReceivedAt: sub.cmd.ReceivedAt(),
+	}
+	if len(e.cmdLog) >= MaxCommandLog {
+		copy(e.cmdLog, e.cmdLog[1:])
+		e.cmdLog = e.cmdLog[:len(e.cmdLog)-1]
+	}
+	e.cmdLog = append(e.cmdLog, r)
+	return r
+}
+
+// endCommandRecord closes the active record when ev completes or
+// supersedes its command. emit calls it for every event.
+func (e *Engine) endCommandRecord(ev Event) {
+	r := e.activeRec
+	if r == nil || ev.Command != r.Type {
+		return
+	}
+	switch ev.Kind {
+	case EventCommandCompleted:
+		r.Outcome = OutcomeCompleted
+	case EventCommandSuperseded:
+		r.Outcome = OutcomeSuperseded
+		r.SupersededBy = strings.TrimPrefix(ev.Detail, "superseded by ")
+	default:
+		return
+	}
+	// on the clock TakenAt is read from: ev.TS is the last tick, which a
+	// command taken since would seem to end before it began
+	ts := e.takenAt()
+	r.EndedAt = &ts
+	e.activeRec = nil
+}
@@ -1,6 +1,12 @@
 package sim
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
 
 type CommandType string
 
@@ -9,6 +15,7 @@ const (
 	CmdTrajectory CommandType = "trajectory"
 	CmdHold       CommandType = "hold"
 	CmdStop       CommandType = "stop"
+	CmdSetState   CommandType = "setstate"
 )
 
 type Command interface {
@@ -17,38 +24,183 @@ type Command interface {
 }
 
 type GoToCommand struct {
-	At    time.Time
-	Lat   float64 `json:"lat"`
-	Lon   float64 `json:"lon"`
-	Alt   float64 `json:"alt"`
-	Speed float64 `json:"speed,omitempty"` // m/s
+	At    time.Time `json:"at"`
+	Lat   float64   `json:"lat"`
+	Lon   float64   `json:"lon"`
+	Alt   float64   `json:"alt"`
+	Speed float64   `json:"speed,omitempty"` // m/s
+	// AGL, when > 0, follows the terrain this many metres above it on the
+	// way instead of flying to Alt, climbing ahead of rising ground.
+	AGL float64 `json:"agl,omitempty"`
+	// AltRef is the datum of Alt (default AltRefMSL). The engine converts
+	// Alt to MSL when it takes the command.
+	AltRef AltRef `json:"altRef,omitempty"`
 }
 
 func (c GoToCommand) Type() CommandType     { return CmdGoTo }
 func (c GoToCommand) ReceivedAt() time.Time { return c.At }
 
 type Waypoint struct {
-	Lat   float64 `json:"lat"`
-	Lon   float64 `json:"lon"`
-	Alt   float64 `json:"alt"`
-	Speed float64 `json:"speed,omitempty"` // m/s optional
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+	Alt    float64 `json:"alt"`
+	Speed  float64 `json:"speed,omitempty"`  // m/s optional
+	AGL    float64 `json:"agl,omitempty"`    // m above terrain to follow instead of Alt
+	AltRef AltRef  `json:"altRef,omitempty"` // datum of Alt, converted to MSL by the engine
 }
 
 type TrajectoryCommand struct {
-	At        time.Time
+	At        time.Time  `json:"at"`
 	Waypoints []Waypoint `json:"waypoints"`
 	Loop      bool       `json:"loop,omitempty"`
+	// Mission names the stored mission the trajectory came from, if any.
+	Mission string `json:"mission,omitempty"`
 }
 
 func (c TrajectoryCommand) Type() CommandType     { return CmdTrajectory }
 func (c TrajectoryCommand) ReceivedAt() time.Time { return c.At }
 
-type HoldCommand struct{ At time.Time }
+type HoldCommand struct {
+	At time.Time `json:"at"`
+}
 
 func (c HoldCommand) Type() CommandType     { return CmdHold }
 func (c HoldCommand) ReceivedAt() time.Time { return c.At }
 
-type StopCommand struct{ At time.Time }
+type StopCommand struct {
+	At time.Time `json:"at"`
+}
 
 func (c StopCommand) Type() CommandType     { return CmdStop }
 func (c StopCommand) ReceivedAt() time.Time { return c.At }
+
+// SetStateCommand places the aircraft at an exact position and velocity
+// (local east/north/up m/s) without flying there. It clears any active
+// command, and the next published state carries exactly these values.
+type SetStateCommand struct {
+	At  time.Time `json:"at"`
+	Lat float64   `json:"lat"`
+	Lon float64   `json:"lon"`
+	Alt float64   `json:"alt"`
+	Vx  float64   `json:"vx"`
+	Vy  float64   `json:"vy"`
+	Vz  float64   `json:"vz"`
+}
+
+func (c SetStateCommand) Type() CommandType     { return CmdSetState }
+func (c SetStateCommand) ReceivedAt() time.Time { return c.At }
+
+// Validate checks that all values are finite and lat/lon are in range.
+func (c SetStateCommand) Validate() error {
+	for _, f := range []float64{c.Lat, c.Lon, c.Alt, c.Vx, c.Vy, c.Vz} {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("state contains a non-finite value")
+		}
+	}
+	if c.Lat < -90 || c.Lat > 90 {
+		return fmt.Errorf("lat must be between -90 and 90")
+	}
+	if c.Lon < -180 || c.Lon > 180 {
+		return fmt.Errorf("lon must be between -180 and 180")
+	}
+	return nil
+}
+
+// submission is a command on its way to the actor with its source.
+type submission struct {
+	cmd    Command
+	source string
+	client string
+}
+
+// sourceGeofence is the source of the commands the geofence issues itself.
+const sourceGeofence = "geofence"
+
+type commandSourceKey struct{}
+
+// WithCommandSource returns a copy of ctx that makes Submit record source,
+// such as a request ID, with the command.
+func WithCommandSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, commandSourceKey{}, source)
+}
+
+func commandSource(ctx context.Context) string {
+	s, _ := ctx.Value(commandSourceKey{}).(string)
+	return s
+}
+
+// CommandEnvelope carries a Command together with its type discriminator so
+// it survives a JSON round trip:
+//
+//	{"type": "goto", "command": {"at": "...", "lat": 32.1, ...}}
+type CommandEnvelope struct {
+	Command Command
+	// Source is where the command came from, such as an HTTP request ID.
+	Source string
+}
+
+type commandEnvelopeJSON struct {
+	Type    CommandType     `json:"type"`
+	Command json.RawMessage `json:"command"`
+	Source  string          `json:"source,omitempty"`
+}
+
+func (env CommandEnvelope) MarshalJSON() ([]byte, error) {
+	if env.Command == nil {
+		return []byte("null"), nil
+	}
+	raw, err := json.Marshal(env.Command)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(commandEnvelopeJSON{Type: env.Command.Type(), Command: raw, Source: env.Source})
+}
+
+func (env *CommandEnvelope) UnmarshalJSON(b []byte) error {
+	var j commandEnvelopeJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	cmd, err := decodeCommand(j.Type, j.Command)
+	if err != nil {
+		return err
+	}
+	env.Command = cmd
+	env.Source = j.Source
+	return nil
+}
+
+func decodeCommand(t CommandType, raw json.RawMessage) (Command, error) {
+	var (
+		cmd Command
+		err error
+	)
+	switch t {
+	case CmdGoTo:
+		var c GoToCommand
+		err = json.Unmarshal(raw, &c)
+		cmd = c
+	case CmdTrajectory:
+		var c TrajectoryCommand
+		err = json.Unmarshal(raw, &c)
+		cmd = c
+	case CmdHold:
+		var c HoldCommand
+		err = json.Unmarshal(raw, &c)
+		cmd = c
+	case CmdStop:
+		var c StopCommand
+		err = json.Unmarshal(raw, &c)
+		cmd = c
+	case CmdSetState:
+		var c SetStateCommand
+		err = json.Unmarshal(raw, &c)
+		cmd = c
+	default:
+		return nil, fmt.Errorf("unknown command type %q", t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s command: %w", t, err)
+	}
+	return cmd, nil
+}
@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"context"
+
+	"flight-simulator2/internal/env"
+)
+
+// Conditions is what the environment does at one point, as the engine's own
+// effects see it.
+type Conditions struct {
+	Lat  float64    `json:"lat"`
+	Lon  float64    `json:"lon"`
+	Alt  float64    `json:"alt"`
+	Wind WindReport `json:"wind"`
+	// GroundAltM and AGLM are null without terrain; FloorM is the highest
+	// altitude floor the effects enforce there, null when there is none.
+	GroundAltM *float64 `json:"groundAltM"`
+	AGLM       *float64 `json:"aglM"`
+	FloorM     *float64 `json:"floorM"`
+	// Regions lists the no-fly zones, storm cells and microbursts
+	// containing the point.
+	Regions []env.Membership `json:"regions"`
+}
+
+// ConditionsQuery selects the point for ConditionsAt; nil fields default
+// to the aircraft's position as published, with any sensor noise and
+// faults, so the query reveals nothing GetState does not.
+type ConditionsQuery struct {
+	Lat, Lon, Alt *float64
+}
+
+// Obstacles lists the obstacles of the configured environment.
+func (e *Engine) Obstacles(ctx context.Context) ([]env.Obstacle, error) {
+	var obs []env.Obstacle
+	err := e.call(ctx, func() {
+		obs = env.ObstaclesIn(e.environment)
+	})
+	if obs == nil {
+		obs = []env.Obstacle{}
+	}
+	return obs, err
+}
+
+// ConditionsAt reports the environment at the queried point. It runs on the
+// engine goroutine, so it sees the effects between two ticks.
+func (e *Engine) ConditionsAt(ctx context.Context, q ConditionsQuery) (Conditions, error) {
+	var c Conditions
+	err := e.call(ctx, func() {
+		st := e.buildSnapshot(e.now, nil)
+		e.degrade(&st)
+		c.Lat, c.Lon, c.Alt = st.Lat, st.Lon, st.Alt
+		if q.Lat != nil {
+			c.Lat = *q.Lat
+		}
+		if q.Lon != nil {
+			c.Lon = *q.Lon
+		}
+		if q.Alt != nil {
+			c.Alt = *q.Alt
+		}
+		pos := e.geo.GeoToLocal(c.Lat, c.Lon, c.Alt)
+
+		effects := e.effects()
+		c.Wind = e.windAt(pos)
+		if ground, ok := env.GroundAltitude(effects, pos); ok {
+			agl := pos.Z - ground
+			c.GroundAltM, c.AGLM = &ground, &agl
+		}
+		if floor, ok := env.MinAltitude(effects, pos); ok {
+			c.FloorM = &floor
+		}
+		c.Regions = env.MembershipsAt(effects, pos)
+		if c.Regions == nil {
+			c.Regions = []env.Membership{}
+		}
+	})
+	return c, err
+}
@@ -0,0 +1,68 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Wind component limit warning codes.
+const (
+	WarnCrosswindLimit = "crosswind-limit"
+	WarnTailwindLimit  = "tailwind-limit"
+)
+
+// minTrackSpeedMps is the ground speed below which the wind components are
+// taken relative to the air heading, as the ground track is undefined.
+const minTrackSpeedMps = 1.0
+
+// WindComponents splits the horizontal wind w (m/s east/north, blowing
+// toward) along a track (degrees, 0=north, 90=east): headwind is positive
+// against the track (negative for a tailwind), crosswind positive when the
+// wind comes from the right of the track.
+func WindComponents(w vector.Vec3, trackDeg float64) (headwind, crosswind float64) {
+	// the track and its right, turned clockwise from north
+	along := vector.Vec3{Y: 1}.RotateZ(-trackDeg * math.Pi / 180)
+	right := along.RotateZ(-math.Pi / 2)
+	return -w.Horizontal().Dot(along), -w.Horizontal().Dot(right)
+}
+
+// updateWindComponents computes the headwind and crosswind of the last tick
+// from the estimated wind when there is one, otherwise from the
+// environment's, and checks them against the configured limits.
+func (e *Engine) updateWindComponents() []Warning {
+	var w vector.Vec3
+	if e.windEst != nil {
+		w, _ = e.windEst.Estimate()
+	} else {
+		w, _ = env.WindAt(e.effects(), e.pos)
+	}
+	track := HeadingDegFromVec(e.gvel)
+	if e.gvel.Horizontal().Length() < minTrackSpeedMps {
+		track = HeadingDegFromVec(e.vel)
+	}
+	e.headwind, e.crosswind = WindComponents(w, track)
+
+	var warnings []Warning
+	if e.crosswindLimit > 0 && math.Abs(e.crosswind) > e.crosswindLimit {
+		side := "right"
+		if e.crosswind < 0 {
+			side = "left"
+		}
+		warnings = append(warnings, Warning{
+			Code:     WarnCrosswindLimit,
+			Severity: env.SeverityCaution,
+			Message:  fmt.Sprintf("crosswind %.1f m/s from the %s exceeds the %.1f m/s limit", math.Abs(e.crosswind), side, e.crosswindLimit),
+		})
+	}
+	if e.tailwindLimit > 0 && -e.headwind > e.tailwindLimit {
+		warnings = append(warnings, Warning{
+			Code:     WarnTailwindLimit,
+			Severity: env.SeverityCaution,
+			Message:  fmt.Sprintf("tailwind %.1f m/s exceeds the %.1f m/s limit", -e.headwind, e.tailwindLimit),
+		})
+	}
+	return warnings
+}
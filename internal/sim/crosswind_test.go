@@ -0,0 +1,120 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestWindComponents(t *testing.T) {
+	// 10 m/s from each cardinal direction; the wind vector blows toward
+	from := map[string]vector.Vec3{
+		"north": {Y: -10},
+		"east":  {X: -10},
+		"south": {Y: 10},
+		"west":  {X: 10},
+	}
+	for _, c := range []struct {
+		track     float64
+		from      string
+		head, xwd float64
+	}{
+		{0, "north", 10, 0},
+		{0, "east", 0, 10},
+		{0, "south", -10, 0},
+		{0, "west", 0, -10},
+		{90, "north", 0, -10},
+		{90, "east", 10, 0},
+		{90, "south", 0, 10},
+		{90, "west", -10, 0},
+		{180, "north", -10, 0},
+		{180, "east", 0, -10},
+		{180, "south", 10, 0},
+		{180, "west", 0, 10},
+		{270, "north", 0, 10},
+		{270, "east", -10, 0},
+		{270, "south", 0, -10},
+		{270, "west", 10, 0},
+	} {
+		head, xwd := sim.WindComponents(from[c.from], c.track)
+		if math.Abs(head-c.head) > 1e-9 || math.Abs(xwd-c.xwd) > 1e-9 {
+			t.Errorf("track %g, wind from the %s: headwind %.3f, crosswind %.3f, want %g, %g",
+				c.track, c.from, head, xwd, c.head, c.xwd)
+		}
+	}
+	// the vertical part plays no role
+	if head, xwd := sim.WindComponents(vector.Vec3{Y: -10, Z: 5}, 0); head != 10 || math.Abs(xwd) > 1e-9 {
+		t.Errorf("updraft changed the components: %g, %g", head, xwd)
+	}
+}
+
+func TestWindComponentLimits(t *testing.T) {
+	// a goto to the north at 50 m/s, 10 s in
+	for _, c := range []struct {
+		name       string
+		wind       env.Wind
+		xwdLimit   float64
+		tailLimit  float64
+		wantCodes  []string
+		otherCodes []string
+	}{
+		{"no limits", env.Wind{Wx: -12, Wy: 8}, 0, 0, nil, []string{sim.WarnCrosswindLimit, sim.WarnTailwindLimit}},
+		{"crosswind from the right", env.Wind{Wx: -12}, 10, 0, []string{sim.WarnCrosswindLimit}, []string{sim.WarnTailwindLimit}},
+		{"crosswind from the left", env.Wind{Wx: 12}, 10, 0, []string{sim.WarnCrosswindLimit}, nil},
+		{"crosswind within", env.Wind{Wx: -12}, 15, 0, nil, []string{sim.WarnCrosswindLimit}},
+		{"tailwind", env.Wind{Wy: 8}, 0, 5, []string{sim.WarnTailwindLimit}, []string{sim.WarnCrosswindLimit}},
+		{"headwind is no tailwind", env.Wind{Wy: -8}, 0, 5, nil, []string{sim.WarnTailwindLimit}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h, err := simtest.New(sim.Config{
+				OriginLat: 47, OriginLon: 8, Environment: c.wind,
+				CrosswindLimitMps: c.xwdLimit, TailwindLimitMps: c.tailLimit,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Submit(sim.GoToCommand{Lat: 47.2, Lon: 8, Alt: 1000, Speed: 50}); err != nil {
+				t.Fatal(err)
+			}
+			st := h.Steps(200)
+			// relative to the track flown, which the wind bends off north
+			head, xwd := sim.WindComponents(vector.Vec3{X: c.wind.Wx, Y: c.wind.Wy}, st.TrackDeg)
+			if math.Abs(st.HeadwindMps-head) > 1e-6 || math.Abs(st.CrosswindMps-xwd) > 1e-6 {
+				t.Errorf("headwind %.3f, crosswind %.3f, want %.3f, %.3f", st.HeadwindMps, st.CrosswindMps, head, xwd)
+			}
+			if math.Signbit(st.CrosswindMps) != math.Signbit(-c.wind.Wx) && c.wind.Wx != 0 {
+				t.Errorf("crosswind %.3f from the wrong side for wind %+v", st.CrosswindMps, c.wind)
+			}
+			codes := map[string]bool{}
+			for _, w := range st.Warnings {
+				codes[w.Code] = true
+			}
+			for _, code := range c.wantCodes {
+				if !codes[code] {
+					t.Errorf("no %s in %+v", code, st.Warnings)
+				}
+			}
+			for _, code := range c.otherCodes {
+				if codes[code] {
+					t.Errorf("unexpected %s", code)
+				}
+			}
+		})
+	}
+}
+
+func TestWindComponentLimitsValidated(t *testing.T) {
+	for _, cfg := range []sim.Config{
+		{CrosswindLimitMps: -1},
+		{TailwindLimitMps: -1},
+		{CrosswindLimitMps: math.NaN()},
+	} {
+		if _, err := sim.New(cfg); err == nil {
+			t.Errorf("New(%+v) accepted", cfg)
+		}
+	}
+}
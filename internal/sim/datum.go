@@ -0,0 +1,55 @@
+package sim
+
+import "flight-simulator2/internal/env"
+
+// groundAtGeo is the terrain elevation at lat, lon, or 0 without terrain.
+func (e *Engine) groundAtGeo(lat, lon float64) float64 {
+	if e.environment == nil {
+		return 0
+	}
+	ground, _ := env.GroundAltitude(e.environment, e.geo.GeoToLocal(lat, lon, 0))
+	return ground
+}
+
+// normalizeAlt converts the altitudes of a goto or trajectory to MSL, so
+// that a trajectory mixing datums is flown in one.
+func (e *Engine) normalizeAlt(cmd Command) Command {
+	switch c := cmd.(type) {
+	case GoToCommand:
+		if c.AltRef != "" && c.AltRef != AltRefMSL {
+			c.Alt = AltToMSL(c.Alt, c.AltRef, e.geoidM, e.groundAtGeo(c.Lat, c.Lon))
+			c.AltRef = ""
+		}
+		return c
+	case TrajectoryCommand:
+		wps := make([]Waypoint, len(c.Waypoints))
+		for i, wp := range c.Waypoints {
+			if wp.AltRef != "" && wp.AltRef != AltRefMSL {
+				wp.Alt = AltToMSL(wp.Alt, wp.AltRef, e.geoidM, e.groundAtGeo(wp.Lat, wp.Lon))
+				wp.AltRef = ""
+			}
+			wps[i] = wp
+		}
+		c.Waypoints = wps
+		return c
+	}
+	return cmd
+}
+
+// InDatum returns st with Alt in ref. An AltRefAGL altitude needs terrain
+// in the environment (st.AGLM); without it InDatum fails with ErrNoTerrain.
+func (e *Engine) InDatum(st AircraftState, ref AltRef) (AircraftState, error) {
+	switch ref {
+	case "":
+		ref = AltRefMSL
+	case AltRefAGL:
+		if st.AGLM == nil {
+			return st, ErrNoTerrain
+		}
+		st.Alt = *st.AGLM
+	case AltRefWGS84:
+		st.Alt = AltFromMSL(st.Alt, ref, e.geoidM, 0)
+	}
+	st.AltRef = ref
+	return st, nil
+}
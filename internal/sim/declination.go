@@ -0,0 +1,121 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Declination is the magnetic declination model: DeclinationDeg returns the
+// angle from true to magnetic north at lat/lon in degrees, positive when
+// magnetic north lies east of true north.
+type Declination interface {
+	DeclinationDeg(lat, lon float64) float64
+}
+
+// ConstantDeclination is the same declination everywhere, which is good
+// enough within a few tens of kilometres of the origin.
+type ConstantDeclination float64
+
+// DeclinationDeg returns c.
+func (c ConstantDeclination) DeclinationDeg(lat, lon float64) float64 { return float64(c) }
+
+// DeclinationGrid is a declination table, e.g. exported from the World
+// Magnetic Model: Deg[i][j] is the declination at Lats[i], Lons[j]. Values
+// between the nodes are interpolated bilinearly; outside the grid the
+// nearest edge applies.
+type DeclinationGrid struct {
+	Lats []float64   `json:"lats"`
+	Lons []float64   `json:"lons"`
+	Deg  [][]float64 `json:"deg"`
+}
+
+// LoadDeclinationGrid reads a DeclinationGrid from JSON and validates it.
+func LoadDeclinationGrid(r io.Reader) (*DeclinationGrid, error) {
+	var g DeclinationGrid
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&g); err != nil {
+		return nil, fmt.Errorf("declination grid: %w", err)
+	}
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("declination grid: %w", err)
+	}
+	return &g, nil
+}
+
+// Validate checks the coordinates increase strictly within range and that
+// Deg has len(Lats) rows of len(Lons) values within ±180°.
+func (g *DeclinationGrid) Validate() error {
+	for _, axis := range []struct {
+		name  string
+		xs    []float64
+		limit float64
+	}{{"lats", g.Lats, 90}, {"lons", g.Lons, 180}} {
+		if len(axis.xs) < 2 {
+			return fmt.Errorf("%s needs at least 2 coordinates", axis.name)
+		}
+		for i, x := range axis.xs {
+			if !(math.Abs(x) <= axis.limit) {
+				return fmt.Errorf("%s[%d] out of range", axis.name, i)
+			}
+			if i > 0 && x <= axis.xs[i-1] {
+				return fmt.Errorf("%s must increase strictly (at %s[%d])", axis.name, axis.name, i)
+			}
+		}
+	}
+	if len(g.Deg) != len(g.Lats) {
+		return fmt.Errorf("deg: %d rows, want %d (len(lats))", len(g.Deg), len(g.Lats))
+	}
+	for i, row := range g.Deg {
+		if len(row) != len(g.Lons) {
+			return fmt.Errorf("deg[%d]: %d values, want %d (len(lons))", i, len(row), len(g.Lons))
+		}
+		for j, v := range row {
+			if !(math.Abs(v) <= 180) {
+				return fmt.Errorf("deg[%d][%d] must be within ±180", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// DeclinationDeg interpolates the table at lat/lon. Differences between
+// neighbouring nodes are taken the short way round, so cells spanning ±180°
+// (near the magnetic poles) interpolate sensibly.
+func (g *DeclinationGrid) DeclinationDeg(lat, lon float64) float64 {
+	i, ty := gridCell(g.Lats, lat)
+	j, tx := gridCell(g.Lons, lon)
+	base := g.Deg[i][j]
+	rel := func(v float64) float64 { return wrap180(v - base) }
+	a := tx * rel(g.Deg[i][j+1])
+	b := rel(g.Deg[i+1][j]) + tx*(rel(g.Deg[i+1][j+1])-rel(g.Deg[i+1][j]))
+	return wrap180(base + a + ty*(b-a))
+}
+
+// gridCell locates v in xs, clamped to the ends, returning the lower index
+// and the fraction toward the next coordinate.
+func gridCell(xs []float64, v float64) (int, float64) {
+	n := len(xs)
+	switch {
+	case v <= xs[0]:
+		return 0, 0
+	case v >= xs[n-1]:
+		return n - 2, 1
+	}
+	i := min(max(sort.SearchFloat64s(xs, v)-1, 0), n-2)
+	return i, (v - xs[i]) / (xs[i+1] - xs[i])
+}
+
+// wrap180 brings deg into [-180, 180).
+func wrap180(deg float64) float64 {
+	return math.Mod(math.Mod(deg+180, 360)+360, 360) - 180
+}
+
+// MagneticHeadingDeg converts a true heading to a magnetic one given the
+// declination (east positive), wrapped into [0, 360).
+func MagneticHeadingDeg(trueDeg, declinationDeg float64) float64 {
+	return math.Mod(math.Mod(trueDeg-declinationDeg, 360)+360, 360)
+}
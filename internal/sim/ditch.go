@@ -0,0 +1,30 @@
+package sim
+
+import (
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// checkDitched puts the engine in its ditched state the first time a step
+// raised env.WarnDitched, and keeps the warning up once it is there. A
+// ditched aircraft drops its command and stays put until Reset.
+func (e *Engine) checkDitched(warnings []Warning) []Warning {
+	if e.ditched {
+		return mergeWarnings(warnings, []Warning{env.SurfaceContact(true)})
+	}
+	for _, w := range warnings {
+		if w.Code != env.WarnDitched {
+			continue
+		}
+		e.ditched = true
+		e.vel = vector.Vec3{}
+		if e.active != nil {
+			e.emit(Event{Kind: EventCommandSuperseded, Command: e.active.Type(), Detail: env.WarnDitched})
+			e.active = nil
+			e.traj = nil
+			e.trajIdx = 0
+		}
+		break
+	}
+	return warnings
+}
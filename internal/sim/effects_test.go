@@ -0,0 +1,138 @@
+package sim
+
+import (
+	"context"
+	"testing"
+
+	"flight-simulator2/internal/env"
+)
+
+// submitNow queues cmd for the next step without a running engine.
+func submitNow(t testing.TB, e *Engine, cmd Command) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := e.Submit(ctx, cmd); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEffectsChainReused(t *testing.T) {
+	e, err := New(Config{OriginLat: 47, OriginLon: 8, Environment: env.Wind{Wx: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := e.effects()
+	if allocs := testing.AllocsPerRun(100, func() { e.effects() }); allocs != 0 {
+		t.Errorf("effects allocates %g times a call", allocs)
+	}
+	e.Step(0.05)
+	if e.effects() != chain {
+		t.Fatalf("a step rebuilt the chain")
+	}
+
+	lengths := func() int { return len(e.effects().Effects) }
+	if err := e.weather.Add(env.WeatherCell{ID: "cb", Lat: 47, Lon: 8, RadiusM: 500}, e.geo.GeoToLocal(47, 8, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if n := lengths(); n != 2 || e.effects().Effects[1] != e.weather {
+		t.Fatalf("a storm cell left %d effects", n)
+	}
+	if _, err := e.microbursts.Add(env.Microburst{Lat: 47, Lon: 8, RadiusM: 500, PeakDownMps: 5, DurationS: 1}, e.geo.GeoToLocal(47, 8, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if n := lengths(); n != 3 || e.effects().Effects[2] != e.microbursts {
+		t.Fatalf("a microburst left %d effects", n)
+	}
+	e.setEnvironment(env.Wind{Wy: -3})
+	if got := e.effects().Effects[0]; got != (env.Wind{Wy: -3}) {
+		t.Fatalf("the chain kept the replaced environment %v", got)
+	}
+	// the microburst expires by itself
+	for range 30 {
+		e.Step(0.05)
+	}
+	if n := lengths(); n != 2 {
+		t.Errorf("%d effects after the microburst ended, want 2", n)
+	}
+	e.weather.Clear()
+	if n := lengths(); n != 1 {
+		t.Errorf("%d effects after clearing the weather, want 1", n)
+	}
+}
+
+func TestCachedEffectsUnchangedOutput(t *testing.T) {
+	// the same flight with the chain reused and with it rebuilt every step,
+	// as it used to be, through wind changes, a storm cell and a microburst
+	fly := func(rebuild bool) []AircraftState {
+		e, err := New(Config{
+			OriginLat: 47, OriginLon: 8, Seed: 7,
+			Environment: &env.Chain{Effects: []env.Environment{
+				env.Wind{Wx: 4},
+				&env.Turbulence{IntensityMps: 1, Seed: 3},
+				env.Terrain{SafetyMarginM: 50, Provider: env.DefaultSyntheticTerrain()},
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		submitNow(t, e, GoToCommand{Lat: 47.05, Lon: 8.02, Alt: 1200, Speed: 60})
+		var states []AircraftState
+		for i := range 600 {
+			switch i {
+			case 100:
+				e.setEnvironment(env.ReplaceWind(e.environment, env.Wind{Wx: -6, Wy: 2}))
+			case 200:
+				if err := e.weather.Add(env.WeatherCell{ID: "cb", Lat: 47.02, Lon: 8.01, RadiusM: 2000, TurbulenceMps: 3}, e.geo.GeoToLocal(47.02, 8.01, 0)); err != nil {
+					t.Fatal(err)
+				}
+			case 300:
+				if _, err := e.microbursts.Add(env.Microburst{Lat: 47.03, Lon: 8.01, RadiusM: 1500, PeakDownMps: 6, DurationS: 5}, e.geo.GeoToLocal(47.03, 8.01, 0)); err != nil {
+					t.Fatal(err)
+				}
+			case 450:
+				e.weather.Clear()
+			}
+			if rebuild {
+				e.chain = nil
+			}
+			states = append(states, e.Step(0.05))
+		}
+		return states
+	}
+	cached, rebuilt := fly(false), fly(true)
+	for i := range cached {
+		a, b := cached[i], rebuilt[i]
+		if a.Lat != b.Lat || a.Lon != b.Lon || a.Alt != b.Alt || a.Vx != b.Vx || a.Vy != b.Vy || a.Vz != b.Vz ||
+			a.GVx != b.GVx || a.GVy != b.GVy || a.GVz != b.GVz || len(a.Warnings) != len(b.Warnings) {
+			t.Fatalf("step %d differs:\ncached  %+v\nrebuilt %+v", i, a, b)
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		env  env.Environment
+	}{
+		{"calm", nil},
+		{"wind and terrain", &env.Chain{Effects: []env.Environment{
+			env.Wind{Wx: 4},
+			&env.Turbulence{IntensityMps: 1},
+			env.Terrain{SafetyMarginM: 50},
+		}}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			e, err := New(Config{OriginLat: 47, OriginLon: 8, Environment: c.env})
+			if err != nil {
+				b.Fatal(err)
+			}
+			submitNow(b, e, GoToCommand{Lat: 48, Lon: 8, Alt: 1000})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				e.Step(0.05)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// What the engine does once the battery is empty.
+const (
+	OnEmptyDescend = "descend" // drop the command and descend at the max climb rate
+	OnEmptyFreeze  = "freeze"  // stop all motion in place
+)
+
+// Battery-related warning codes.
+const (
+	WarnBatteryLow      = "battery-low"
+	WarnBatteryCritical = "battery-critical"
+	WarnBatteryEmpty    = "battery-empty"
+)
+
+// ErrNoBattery is returned by battery operations when the energy model is off.
+var ErrNoBattery = errors.New("battery model is not enabled (BatteryWh is 0)")
+
+// Battery configures the energy model. It is disabled while BatteryWh is 0.
+type Battery struct {
+	BatteryWh    float64 // capacity
+	CruisePowerW float64 // draw while moving horizontally
+	ClimbPowerW  float64 // draw while climbing
+	HoverPowerW  float64 // draw while stationary
+
+	// Warning thresholds in percent of capacity (defaults 20 and 10).
+	LowPct      float64
+	CriticalPct float64
+
+	// OnEmpty is OnEmptyDescend (default) or OnEmptyFreeze.
+	OnEmpty string
+}
+
+func (b Battery) enabled() bool { return b.BatteryWh > 0 }
+
+func (b Battery) withDefaults() Battery {
+	if b.LowPct == 0 {
+		b.LowPct = 20
+	}
+	if b.CriticalPct == 0 {
+		b.CriticalPct = 10
+	}
+	if b.OnEmpty == "" {
+		b.OnEmpty = OnEmptyDescend
+	}
+	return b
+}
+
+// Validate rejects negative or non-finite values and unknown OnEmpty modes.
+func (b Battery) Validate() error {
+	for _, f := range []struct {
+		name string
+		v    float64
+	}{
+		{"batteryWh", b.BatteryWh},
+		{"cruisePowerW", b.CruisePowerW},
+		{"climbPowerW", b.ClimbPowerW},
+		{"hoverPowerW", b.HoverPowerW},
+		{"lowPct", b.LowPct},
+		{"criticalPct", b.CriticalPct},
+	} {
+		if math.IsNaN(f.v) || math.IsInf(f.v, 0) {
+			return fmt.Errorf("%s must be finite", f.name)
+		}
+		if f.v < 0 {
+			return fmt.Errorf("%s must be >= 0", f.name)
+		}
+	}
+	switch b.OnEmpty {
+	case "", OnEmptyDescend, OnEmptyFreeze:
+	default:
+		return fmt.Errorf("onEmpty must be %q or %q", OnEmptyDescend, OnEmptyFreeze)
+	}
+	return nil
+}
+
+// energy is the actor-owned battery state.
+type energy struct {
+	cfg         Battery
+	remainingWh float64
+	powerW      float64 // draw during the last tick
+}
+
+func newEnergy(b Battery) *energy {
+	if !b.enabled() {
+		return nil
+	}
+	b = b.withDefaults()
+	return &energy{cfg: b, remainingWh: b.BatteryWh}
+}
+
+func (en *energy) empty() bool { return en != nil && en.remainingWh <= 0 }
+
+// frozen reports whether the aircraft must stay put.
+func (en *energy) frozen() bool { return en.empty() && en.cfg.OnEmpty == OnEmptyFreeze }
+
+// consume draws power for dt seconds in the regime implied by vel.
+func (en *energy) consume(vel vector.Vec3, dt float64) {
+	if en == nil {
+		return
+	}
+	switch {
+	case en.empty():
+		en.powerW = 0
+	case vel.Z > 0.5:
+		en.powerW = en.cfg.ClimbPowerW
+	case vel.Horizontal().Length() > 1:
+		en.powerW = en.cfg.CruisePowerW
+	default:
+		en.powerW = en.cfg.HoverPowerW
+	}
+	en.remainingWh = math.Max(0, en.remainingWh-en.powerW*dt/3600)
+}
+
+func (en *energy) pct() float64 { return 100 * en.remainingWh / en.cfg.BatteryWh }
+
+// enduranceS is the time left at the current draw; ok is false when
+// nothing is being drawn and the endurance is unbounded.
+func (en *energy) enduranceS() (s float64, ok bool) {
+	if en.empty() {
+		return 0, true
+	}
+	if en.powerW <= 0 {
+		return 0, false
+	}
+	return en.remainingWh * 3600 / en.powerW, true
+}
+
+func (en *energy) warnings() []Warning {
+	if en == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("%.0f%% remaining", en.pct())
+	switch pct := en.pct(); {
+	case en.empty():
+		return []Warning{{Code: WarnBatteryEmpty, Severity: env.SeverityWarning}}
+	case pct <= en.cfg.CriticalPct:
+		return []Warning{{Code: WarnBatteryCritical, Severity: env.SeverityWarning, Message: msg}}
+	case pct <= en.cfg.LowPct:
+		return []Warning{{Code: WarnBatteryLow, Severity: env.SeverityCaution, Message: msg}}
+	}
+	return nil
+}
+
+// SetBatteryPct sets the remaining charge, e.g. to recharge between tests.
+func (e *Engine) SetBatteryPct(ctx context.Context, pct float64) error {
+	if e.energy == nil {
+		return ErrNoBattery
+	}
+	if math.IsNaN(pct) || pct < 0 || pct > 100 {
+		return fmt.Errorf("pct must be between 0 and 100")
+	}
+	return e.call(ctx, func() {
+		e.energy.remainingWh = e.energy.cfg.BatteryWh * pct / 100
+	})
+}
@@ -2,64 +2,564 @@ package sim
 
 import (
 	"context"
+	"errors"
 	"flight-simulator2/internal/env"
 	"flight-simulator2/internal/geometry/vector"
+	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"sync/atomic"
 	"time"
 )
 
+// ErrOverloaded is returned by Submit when the command queue stays full.
+var ErrOverloaded = errors.New("engine command queue is full")
+
 type stateReq struct {
 	reply chan AircraftState
 }
 
 type subscribeReq struct {
-	ch chan AircraftState
+	ch  chan AircraftState
+	sub *stateSub
+
+	// resume, when set, asks for the published states after *resume
+	// instead of the current one; ch is then made by the actor and sent
+	// back on resumed.
+	resume  *uint64
+	resumed chan resumeReply
 }
 
 type Engine struct {
-	geo GeoRef
+	geo          GeoRef
+	autoOrigin   bool    // snap geo to the first target (Config.AutoOrigin)
+	maxOriginLat float64 // Config.MaxOriginLatDeg
+	geoidM       float64 // Config.GeoidOffsetM
 
 	// Actor channels
-	cmdCh       chan Command
+	cmdCh       chan submission
 	stateReqCh  chan stateReq
 	subscribeCh chan subscribeReq
 	unsubCh     chan chan AircraftState
+	callCh      chan func()
+
+	eventSubCh   chan chan Event
+	eventUnsubCh chan chan Event
 
 	tickHz      float64
+	publishHz   float64
 	environment env.Environment
+	aircraftID  string
+	declination Declination
+	windEst     *WindEstimator // nil unless Config.EstimateWind
+	airStep     vector.Vec3    // displacement through the air this tick
+	maxWind     float64
+	weather     *env.Weather // runtime storm cells, applied after environment
+	microbursts *env.Microbursts
+	// the chain effects() last built, kept until the environment is
+	// replaced (setEnvironment) or weather cells or microbursts come or go
+	chain                     *env.Chain
+	chainWeather, chainBursts bool
+	// wind components of the last tick, and their limits (0 = none)
+	headwind, crosswind           float64
+	crosswindLimit, tailwindLimit float64
+	terrainLookahead              float64 // seconds; 0 = off
+	follow                        terrainFollow
+	terrainTicks                  int // Config.TerrainHysteresisTicks
+	terrainDeb                    terrainDebounce
+	ditched                       bool // came down on water; see checkDitched
+	// performance lost to the environment (env.Result) in the last step
+	climbLoss   float64
+	speedLoss   float64
+	clock       Clock
+	limits      Limits
+	ceiling     float64
+	maxRange    float64 // Config.MaxRangeM
+	atmosphere  Atmosphere
+	integrator  Integrator
+	subSteps    int
+	physics     Physics
+	airframe    Airframe
+	energy      *energy // nil when no battery is configured
+	extrapolate bool
+	fence       *fence // nil when no geofence is set; actor-owned
+	rec         *recorder
+	replay      *replayState // non-nil for engines built by NewReplay
+
+	traffic       []*intruder // actor-owned
+	trafficHorizM float64
+	trafficVertM  float64
+
+	droppedCmds atomic.Uint64 // commands rejected by Submit
+
+	// loopRunning and heart are written by Run and read by Health from
+	// any goroutine.
+	loopRunning atomic.Bool
+	heart       atomic.Pointer[heartbeat]
+
+	// Actor-owned state. Only the goroutine inside Run (or the caller driving
+	// Step) may touch these fields.
+	running  bool
+	now      time.Time
+	home     vector.Vec3 // start position and velocity, used by Reset
+	homeVel  vector.Vec3
+	pos      vector.Vec3
+	vel      vector.Vec3 // "air" velocity
+	gvel     vector.Vec3 // ground velocity over the last tick
+	att      attitude
+	odoM     float64 // ground track length since start/reset
+	odoTimeS float64
+	active   Command
+	traj     []Waypoint
+	trajIdx  int
+	trajLoop bool
+	subs     map[chan AircraftState]*stateSub
+	history  *stateRing
+	seq      uint64 // Seq of the last published state
+
+	activeSource string      // where active was submitted from (WithCommandSource)
+	activeSince  time.Time   // when active was taken
+	activeFrom   vector.Vec3 // and where
+	trajLaps     int         // laps a looping trajectory has completed
+
+	cmdLog    []*CommandRecord // oldest first, at most MaxCommandLog
+	cmdSeq    uint64
+	activeRec *CommandRecord // cmdLog's record of active, if any
+
+	eventSubs map[chan Event]*eventSub
+
+	// ✅ Keep last warnings in actor-owned state so GET /state can return them too.
+	lastWarnings []Warning
+
+	trafficStates []TrafficState // from the last tick
+
+	stat engineStats
+
+	// lastPublish is the time of the last published state; publishNow
+	// forces the next tick to publish regardless of PublishHz.
+	lastPublish time.Time
+	publishNow  bool
+
+	// resetPending tags the next published state with WarnReset.
+	resetPending bool
+	// teleported makes the next tick publish the state set by a
+	// SetStateCommand without moving the aircraft.
+	teleported bool
+
+	// noise and faults degrade the published state; posNoise (ENU) and
+	// noiseE/noiseN are this tick's offsets in metres.
+	noise          SensorNoise
+	posNoise       vector.Vec3
+	faults         map[FaultKind]*faultState
+	noiseE, noiseN float64
+	rng            *rand.Rand
 }
 
 type Config struct {
 	OriginLat float64
 	OriginLon float64
-	TickHz    float64
+	// Projection selects the lat/lon conversion of the local frame
+	// (default ProjectionWGS84; see GeoRef). MaxOriginLatDeg is the
+	// highest |OriginLat| accepted (default DefaultMaxOriginLatDeg).
+	// AutoOrigin moves the origin to the first goto or trajectory target
+	// (see SetOrigin), for scenarios far from the configured one.
+	Projection      Projection
+	MaxOriginLatDeg float64
+	AutoOrigin      bool
+	// GeoidOffsetM is the height of the geoid (MSL) above the WGS84
+	// ellipsoid, taken as constant around the origin, for altitudes in
+	// AltRefWGS84.
+	GeoidOffsetM float64
+	TickHz       float64
+
+	// PublishHz is how often states are fanned out to subscribers, history
+	// and the recorder; it defaults to and is clamped at TickHz. Events
+	// (command transitions, waypoints, warning changes) publish immediately.
+	PublishHz float64
+
+	// Initial state. InitialLat/InitialLon default to the origin and
+	// InitialAlt to 1000 m; the velocity (m/s east/north/up) to zero.
+	// Reset returns here as well.
+	InitialLat float64
+	InitialLon float64
+	InitialAlt float64
+	InitialVx  float64
+	InitialVy  float64
+	InitialVz  float64
+
+	// Dynamics limits; zero fields fall back to DefaultLimits.
+	Limits
+
+	// CeilingM is the service ceiling (m): climbs stop there with a
+	// WarnCeiling warning. It must clear the highest terrain safety floor
+	// of Environment. 0 means no ceiling.
+	CeilingM float64
+
+	// MaxRangeM is the maximum operating radius (m) around the origin:
+	// goto and trajectory targets farther away are rejected with
+	// ErrOutOfRange, so a typo cannot send the aircraft across the globe.
+	// 0 picks DefaultMaxRangeM.
+	MaxRangeM float64
+
+	// Atmosphere scales the climb rate and commanded speed with density.
+	Atmosphere Atmosphere
+
+	// Integrator selects position integration (default IntegratorEuler).
+	// SubSteps splits every tick into that many guidance+physics steps
+	// (default 1), which keeps low tick rates from overshooting waypoints.
+	Integrator Integrator
+	SubSteps   int
+
+	// Physics selects the motion model (default PhysicsKinematic). Airframe
+	// parameterizes PhysicsPointMass; zero fields fall back to DefaultAirframe.
+	Physics  Physics
+	Airframe Airframe
+
+	// Energy model; disabled while BatteryWh is 0.
+	Battery
+
+	// Geofence, when set, bounds the flight area (see SetGeofence).
+	Geofence *Geofence
+
+	// Traffic lists scripted targets flown alongside the ownship. A target
+	// within TrafficHorizM horizontally and TrafficVertM vertically raises a
+	// WarnTraffic advisory (defaults DefaultTrafficHorizM, DefaultTrafficVertM).
+	Traffic       []TrafficTarget
+	TrafficHorizM float64
+	TrafficVertM  float64
+
+	// ExtrapolateState makes GetState dead-reckon the last tick forward to
+	// the time of the request along the ground velocity, by at most one tick
+	// interval. Otherwise GetState returns the last tick, which is up to one
+	// tick interval old.
+	ExtrapolateState bool
+
+	// SensorNoise perturbs the published lat/lon/alt; GetState and
+	// subscribers see the noisy values, Truth the exact ones.
+	SensorNoise
+	// Seed seeds the generator behind SensorNoise and FaultGPSNoise.
+	Seed int64
+
+	// AttitudeTimeConstS smooths the derived roll/pitch/yaw
+	// (default DefaultAttitudeTimeConstS).
+	AttitudeTimeConstS float64
+
+	// StartTime is the simulation time used by Step before the first step.
+	// Run ignores it and starts from Clock.Now().
+	StartTime time.Time
+
+	// Clock drives Run. Defaults to the wall clock.
+	Clock Clock
 
 	Environment env.Environment
+	// Declination converts HeadingDeg to HeadingMagDeg. Nil means zero
+	// declination, so both headings agree.
+	Declination Declination
+
+	// AircraftID is passed to environment effects in env.Context.
+	AircraftID string
+	// EstimateWind publishes a wind estimate from the aircraft's own motion
+	// (see WindEstimator) filtered with WindEstimateTauS.
+	EstimateWind     bool
+	WindEstimateTauS float64
+
+	// MaxWindMps caps the wind SetWind accepts (default DefaultMaxWindMps).
+	MaxWindMps float64
+	// CrosswindLimitMps and TailwindLimitMps raise the WarnCrosswindLimit
+	// and WarnTailwindLimit cautions when exceeded; 0 means no limit.
+	CrosswindLimitMps float64
+	TailwindLimitMps  float64
+	// TerrainLookaheadS projects the ground velocity this many seconds
+	// ahead every tick and raises WarnTerrainCaution, WarnTerrainWarning or
+	// WarnTerrainPullUp when the path dips below the terrain safety floor
+	// (see timeToImpact); 0 turns the look-ahead off.
+	TerrainLookaheadS float64
+	// TerrainHysteresisTicks is how many ticks in a row a new terrain
+	// proximity level (env.WarnTerrainProximity, WarnTerrainFloor or
+	// WarnTerrainContact, or none) must last before the published warnings
+	// change (default DefaultTerrainHysteresisTicks); a contact is published
+	// at once.
+	TerrainHysteresisTicks int
+
+	// RecordTo, when set, receives every published state and every accepted
+	// command as JSONL records (see Record). NewReplay plays such a recording back.
+	RecordTo io.Writer
+
+	// HistorySize is how many published states the engine keeps for History.
+	// Zero means ten minutes at the publish rate; a negative value disables it.
+	HistorySize int
+
+	// Restore, when set, starts the engine from a previously taken Snapshot
+	// instead of the origin.
+	Restore *Snapshot
 }
 
-func New(cfg Config) *Engine {
-	if cfg.TickHz <= 0 {
+func New(cfg Config) (*Engine, error) {
+	if !(cfg.TickHz >= 0) || math.IsInf(cfg.TickHz, 0) {
+		return nil, fmt.Errorf("tick rate %g must be a positive number of Hz", cfg.TickHz)
+	}
+	if !(cfg.PublishHz >= 0) {
+		return nil, fmt.Errorf("publish rate %g must be a positive number of Hz", cfg.PublishHz)
+	}
+	if cfg.TickHz == 0 {
 		cfg.TickHz = 20
 	}
-	return &Engine{
-		geo:         GeoRef{OriginLat: cfg.OriginLat, OriginLon: cfg.OriginLon},
-		cmdCh:       make(chan Command, 128),
-		stateReqCh:  make(chan stateReq, 32),
-		subscribeCh: make(chan subscribeReq, 32),
-		unsubCh:     make(chan chan AircraftState, 32),
+	if cfg.PublishHz == 0 || cfg.PublishHz > cfg.TickHz {
+		cfg.PublishHz = cfg.TickHz
+	}
+	if err := cfg.Limits.Validate(); err != nil {
+		return nil, err
+	}
+	if !(cfg.MaxOriginLatDeg >= 0 && cfg.MaxOriginLatDeg <= 90) {
+		return nil, fmt.Errorf("origin latitude limit must be between 0 and 90 degrees")
+	}
+	if cfg.MaxOriginLatDeg == 0 {
+		cfg.MaxOriginLatDeg = DefaultMaxOriginLatDeg
+	}
+	if !(math.Abs(cfg.OriginLat) <= cfg.MaxOriginLatDeg) {
+		return nil, fmt.Errorf("origin latitude %g is beyond ±%g degrees", cfg.OriginLat, cfg.MaxOriginLatDeg)
+	}
+	if !(math.Abs(cfg.OriginLon) <= 180) {
+		return nil, fmt.Errorf("origin longitude %g must be between -180 and 180", cfg.OriginLon)
+	}
+	if math.IsNaN(cfg.GeoidOffsetM) || math.IsInf(cfg.GeoidOffsetM, 0) {
+		return nil, fmt.Errorf("geoid offset must be finite")
+	}
+	if cfg.Projection == "" {
+		cfg.Projection = ProjectionWGS84
+	}
+	if err := cfg.Projection.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Integrator == "" {
+		cfg.Integrator = IntegratorEuler
+	}
+	if err := cfg.Integrator.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.SubSteps < 0 {
+		return nil, fmt.Errorf("sub-steps must be >= 0")
+	}
+	if cfg.SubSteps == 0 {
+		cfg.SubSteps = 1
+	}
+	if cfg.Physics == "" {
+		cfg.Physics = PhysicsKinematic
+	}
+	if err := cfg.Physics.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Airframe.Validate(); err != nil {
+		return nil, fmt.Errorf("airframe: %w", err)
+	}
+	if err := cfg.Battery.Validate(); err != nil {
+		return nil, fmt.Errorf("battery: %w", err)
+	}
+	if cfg.Geofence != nil {
+		if err := cfg.Geofence.Validate(); err != nil {
+			return nil, fmt.Errorf("geofence: %w", err)
+		}
+	}
+	ids := map[string]bool{}
+	for _, t := range cfg.Traffic {
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		if ids[t.ID] {
+			return nil, fmt.Errorf("duplicate traffic id %q", t.ID)
+		}
+		ids[t.ID] = true
+	}
+	if cfg.TrafficHorizM < 0 || cfg.TrafficVertM < 0 {
+		return nil, fmt.Errorf("traffic thresholds must be >= 0")
+	}
+	if err := env.Validate(cfg.Environment); err != nil {
+		return nil, fmt.Errorf("environment: %w", err)
+	}
+	if cfg.TrafficHorizM == 0 {
+		cfg.TrafficHorizM = DefaultTrafficHorizM
+	}
+	if cfg.TrafficVertM == 0 {
+		cfg.TrafficVertM = DefaultTrafficVertM
+	}
+	if err := cfg.SensorNoise.Validate(); err != nil {
+		return nil, fmt.Errorf("sensor noise: %w", err)
+	}
+	if cfg.AttitudeTimeConstS < 0 || math.IsNaN(cfg.AttitudeTimeConstS) {
+		return nil, fmt.Errorf("attitude time constant must be >= 0")
+	}
+	if cfg.AttitudeTimeConstS == 0 {
+		cfg.AttitudeTimeConstS = DefaultAttitudeTimeConstS
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = RealClock()
+	}
+	if cfg.InitialLat == 0 && cfg.InitialLon == 0 {
+		cfg.InitialLat, cfg.InitialLon = cfg.OriginLat, cfg.OriginLon
+	}
+	if cfg.InitialAlt == 0 {
+		cfg.InitialAlt = 1000
+	}
+	if err := (SetStateCommand{
+		Lat: cfg.InitialLat, Lon: cfg.InitialLon, Alt: cfg.InitialAlt,
+		Vx: cfg.InitialVx, Vy: cfg.InitialVy, Vz: cfg.InitialVz,
+	}).Validate(); err != nil {
+		return nil, fmt.Errorf("initial state: %w", err)
+	}
+	if err := validateCeiling(cfg); err != nil {
+		return nil, err
+	}
+	if !(cfg.MaxRangeM >= 0) || math.IsInf(cfg.MaxRangeM, 1) {
+		return nil, fmt.Errorf("maximum operating radius must be finite and >= 0")
+	}
+	if cfg.MaxRangeM == 0 {
+		cfg.MaxRangeM = DefaultMaxRangeM
+	}
+	if err := cfg.Atmosphere.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxWindMps < 0 || math.IsNaN(cfg.MaxWindMps) {
+		return nil, fmt.Errorf("max wind must be >= 0")
+	}
+	if cfg.MaxWindMps == 0 {
+		cfg.MaxWindMps = DefaultMaxWindMps
+	}
+	if cfg.WindEstimateTauS < 0 || math.IsNaN(cfg.WindEstimateTauS) {
+		return nil, fmt.Errorf("wind estimate time constant must be >= 0")
+	}
+	if !(cfg.CrosswindLimitMps >= 0) || !(cfg.TailwindLimitMps >= 0) {
+		return nil, fmt.Errorf("crosswind and tailwind limits must be >= 0")
+	}
+	if cfg.TerrainHysteresisTicks < 0 {
+		return nil, fmt.Errorf("terrain hysteresis must be >= 0 ticks")
+	}
+	if cfg.TerrainHysteresisTicks == 0 {
+		cfg.TerrainHysteresisTicks = DefaultTerrainHysteresisTicks
+	}
+	if !(cfg.TerrainLookaheadS >= 0) || math.IsInf(cfg.TerrainLookaheadS, 1) {
+		return nil, fmt.Errorf("terrain look-ahead must be finite and >= 0")
+	}
+	if cfg.HistorySize == 0 {
+		cfg.HistorySize = int(10 * 60 * cfg.PublishHz)
+	}
+	e := &Engine{
+		geo:          GeoRef{OriginLat: cfg.OriginLat, OriginLon: cfg.OriginLon, Projection: cfg.Projection},
+		autoOrigin:   cfg.AutoOrigin,
+		maxOriginLat: cfg.MaxOriginLatDeg,
+		geoidM:       cfg.GeoidOffsetM,
+		cmdCh:        make(chan submission, 128),
+		stateReqCh:   make(chan stateReq, 32),
+		subscribeCh:  make(chan subscribeReq, 32),
+		unsubCh:      make(chan chan AircraftState, 32),
+		callCh:       make(chan func(), 32),
+
+		eventSubCh:   make(chan chan Event, 32),
+		eventUnsubCh: make(chan chan Event, 32),
+
 		tickHz:      cfg.TickHz,
+		publishHz:   cfg.PublishHz,
 		environment: cfg.Environment,
+		clock:       cfg.Clock,
+		limits:      cfg.Limits.withDefaults(),
+		ceiling:     cfg.CeilingM,
+		maxRange:    cfg.MaxRangeM,
+		atmosphere:  cfg.Atmosphere.withDefaults(),
+		integrator:  cfg.Integrator,
+		subSteps:    cfg.SubSteps,
+		physics:     cfg.Physics,
+		airframe:    cfg.Airframe.withDefaults(),
+		energy:      newEnergy(cfg.Battery),
+		extrapolate: cfg.ExtrapolateState,
+		rec:         newRecorder(cfg.RecordTo),
+		now:         cfg.StartTime,
+		att:         attitude{tau: cfg.AttitudeTimeConstS},
+		subs:        map[chan AircraftState]*stateSub{},
+		history:     newStateRing(cfg.HistorySize),
+		eventSubs:   map[chan Event]*eventSub{},
+		faults:      map[FaultKind]*faultState{},
+		noise:       cfg.SensorNoise,
+		weather:     env.NewWeather(cfg.Seed),
+		microbursts: &env.Microbursts{},
+		aircraftID:  cfg.AircraftID,
+		declination: cfg.Declination,
+		maxWind:     cfg.MaxWindMps,
+		rng:         rand.New(rand.NewSource(cfg.Seed)),
+
+		crosswindLimit: cfg.CrosswindLimitMps,
+		tailwindLimit:  cfg.TailwindLimitMps,
+
+		terrainLookahead: cfg.TerrainLookaheadS,
+		terrainTicks:     cfg.TerrainHysteresisTicks,
+
+		trafficHorizM: cfg.TrafficHorizM,
+		trafficVertM:  cfg.TrafficVertM,
+	}
+	e.home = e.geo.GeoToLocal(cfg.InitialLat, cfg.InitialLon, cfg.InitialAlt)
+	e.homeVel = vector.Vec3{X: cfg.InitialVx, Y: cfg.InitialVy, Z: cfg.InitialVz}
+	if cfg.EstimateWind {
+		e.windEst = &WindEstimator{TauS: cfg.WindEstimateTauS}
 	}
+	if cfg.Environment != nil {
+		env.BindGeo(cfg.Environment, e.geo)
+		if floor, ok := env.MinAltitude(cfg.Environment, e.home); ok && e.home.Z < floor {
+			return nil, fmt.Errorf("initial altitude %.1f m is below the terrain safety floor %.1f m", cfg.InitialAlt, floor)
+		}
+	}
+	if cfg.Geofence != nil {
+		e.fence = newFence(*cfg.Geofence, e.geo)
+	}
+	e.traffic = newIntruders(cfg.Traffic, e.geo)
+	e.pos = e.home
+	e.vel = e.homeVel
+	e.gvel = e.homeVel
+	e.att.reset(e.vel)
+
+	if cfg.Restore != nil {
+		if err := cfg.Restore.Validate(); err != nil {
+			return nil, fmt.Errorf("restore: %w", err)
+		}
+		e.restore(*cfg.Restore)
+	}
+	return e, nil
 }
 
-func (e *Engine) Submit(cmd Command) {
+// Now returns the current time of the engine's clock. Callers stamping
+// commands should use it so ReceivedAt stays on the same time base as the loop.
+func (e *Engine) Now() time.Time { return e.clock.Now() }
+
+// Submit queues cmd for the actor loop. If the queue is full it waits for
+// room until ctx is done and then gives up with ErrOverloaded; rejected
+// commands are counted in DroppedCommands. Replay engines return ErrReplay.
+//
+// The source set on ctx with WithCommandSource goes with the command into
+// its events and the flight recording.
+func (e *Engine) Submit(ctx context.Context, cmd Command) error {
+	sub := submission{cmd: cmd, source: commandSource(ctx), client: commandClient(ctx)}
+	if e.replay != nil {
+		e.logRejected(sub, ErrReplay)
+		return ErrReplay
+	}
 	select {
-	case e.cmdCh <- cmd:
+	case e.cmdCh <- sub:
+		return nil
 	default:
-		// drop if overloaded (or you can block / log)
+	}
+	select {
+	case e.cmdCh <- sub:
+		return nil
+	case <-ctx.Done():
+		e.droppedCmds.Add(1)
+		e.logRejected(sub, ErrOverloaded)
+		return ErrOverloaded
 	}
 }
 
+// DroppedCommands returns how many commands Submit has rejected.
+func (e *Engine) DroppedCommands() uint64 { return e.droppedCmds.Load() }
+
+// GetState returns the state as of the last tick, or, with
+// Config.ExtrapolateState, dead-reckoned to now (see AircraftState.ExtrapolatedS).
 func (e *Engine) GetState(ctx context.Context) (AircraftState, error) {
 	req := stateReq{reply: make(chan AircraftState, 1)}
 	select {
@@ -76,11 +576,18 @@ func (e *Engine) GetState(ctx context.Context) (AircraftState, error) {
 	}
 }
 
-func (e *Engine) Subscribe(ctx context.Context) (<-chan AircraftState, func()) {
+// Subscribe streams published states until ctx ends or unsub is called.
+// Frames are dropped for a subscriber that does not keep up; see
+// WithMaxRate to receive fewer of them instead.
+func (e *Engine) Subscribe(ctx context.Context, opts ...SubscribeOption) (<-chan AircraftState, func()) {
 	ch := make(chan AircraftState, 32)
+	sub := &stateSub{ctx: ctx}
+	for _, opt := range opts {
+		opt(sub)
+	}
 
 	select {
-	case e.subscribeCh <- subscribeReq{ch: ch}:
+	case e.subscribeCh <- subscribeReq{ch: ch, sub: sub}:
 	case <-ctx.Done():
 		close(ch)
 		return ch, func() {}
@@ -95,254 +602,758 @@ func (e *Engine) Subscribe(ctx context.Context) (<-chan AircraftState, func()) {
 	return ch, unsub
 }
 
+// call runs fn inside the actor and waits for it to finish.
+func (e *Engine) call(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	select {
+	case e.callCh <- func() { fn(); close(done) }:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetOdometer zeroes DistanceFlownM and FlightTimeS.
+func (e *Engine) ResetOdometer(ctx context.Context) error {
+	if e.replay != nil {
+		return ErrReplay
+	}
+	return e.call(ctx, func() {
+		e.odoM = 0
+		e.odoTimeS = 0
+	})
+}
+
 func (e *Engine) Run(ctx context.Context) error {
-	// Actor-owned state
-	now := time.Now()
-
-	pos := e.geo.GeoToLocal(e.geo.OriginLat, e.geo.OriginLon, 1000) // start at 1000m
-	vel := vector.Vec3{}                                            // "air" velocity
-
-	var active Command
-	var traj []Waypoint
-	trajIdx := 0
-	trajLoop := false
-
-	subs := map[chan AircraftState]struct{}{}
-
-	// ✅ Keep last warning in actor-owned state so GET /state can return it too.
-	lastWarning := ""
-
-	// Simple tuning
-	posTolM := 25.0
-	altTolM := 10.0
-	defaultSpeed := 80.0
-	maxClimbRate := 8.0
-	maxHorizAccel := 12.0
-	maxVertAccel := 5.0
-
-	buildSnapshot := func(ts time.Time, warning string) AircraftState {
-		lat, lon, alt := e.geo.LocalToGeo(pos)
-		st := AircraftState{
-			Lat: lat, Lon: lon, Alt: alt,
-			Vx: vel.X, Vy: vel.Y, Vz: vel.Z,
-			HeadingDeg:  HeadingDegFromVec(vel),
-			TS:          ts,
-			Warning:     warning,
-			TargetIndex: trajIdx,
+	e.now = e.clock.Now()
+	if e.replay != nil {
+		e.replay.start = e.now
+	}
+	e.running = true
+	e.startHeartbeat(e.now)
+	e.loopRunning.Store(true)
+	defer func() {
+		e.running = false
+		e.loopRunning.Store(false)
+	}()
+
+	tick := e.clock.NewTicker(time.Duration(float64(time.Second) / e.tickHz))
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for ch := range e.subs {
+				close(ch)
+			}
+			for ch := range e.eventSubs {
+				close(ch)
+			}
+			return nil
+
+		case req := <-e.subscribeCh:
+			e.handleSubscribe(req)
+
+		case ch := <-e.unsubCh:
+			e.handleUnsubscribe(ch)
+
+		case ch := <-e.eventSubCh:
+			e.handleEventSubscribe(ch)
+
+		case ch := <-e.eventUnsubCh:
+			e.handleEventUnsubscribe(ch)
+
+		case req := <-e.stateReqCh:
+			// ✅ return latest warnings, not an always-empty list
+			req.reply <- e.current()
+
+		case sub := <-e.cmdCh:
+			e.handleCommand(sub)
+
+		case fn := <-e.callCh:
+			fn()
+
+		case t := <-tick.C():
+			dt := t.Sub(e.now).Seconds()
+			if dt <= 0 {
+				dt = 1.0 / e.tickHz
+			}
+			if dt > 1.5/e.tickHz {
+				e.stat.overruns++
+			}
+			if e.replay != nil {
+				e.replayTo(t)
+				e.beat(t)
+				continue
+			}
+			e.advance(t, dt)
+			e.beat(t)
 		}
-		if active != nil {
-			st.ActiveCommand = string(active.Type())
+	}
+}
+
+// Step advances the simulation by dt seconds without a ticker and returns the
+// state published for that step. Pending commands, subscriptions and state
+// requests are processed first, in the order Run would see them when idle.
+//
+// Step is meant for headless, deterministic tests: simulation time starts at
+// Config.StartTime and moves forward only by the dt values given here.
+// It must not be called while Run is active.
+func (e *Engine) Step(dt float64) AircraftState {
+	if dt <= 0 {
+		dt = 1.0 / e.tickHz
+	}
+	e.drain()
+	now := e.now.Add(time.Duration(dt * float64(time.Second)))
+	if e.replay != nil {
+		return e.replayTo(now)
+	}
+	return e.advance(now, dt)
+}
+
+// drain processes every queued actor message without blocking.
+func (e *Engine) drain() {
+	for {
+		select {
+		case req := <-e.subscribeCh:
+			e.handleSubscribe(req)
+		case ch := <-e.unsubCh:
+			e.handleUnsubscribe(ch)
+		case ch := <-e.eventSubCh:
+			e.handleEventSubscribe(ch)
+		case ch := <-e.eventUnsubCh:
+			e.handleEventUnsubscribe(ch)
+		case req := <-e.stateReqCh:
+			req.reply <- e.current()
+		case sub := <-e.cmdCh:
+			e.handleCommand(sub)
+		case fn := <-e.callCh:
+			fn()
+		default:
+			return
 		}
+	}
+}
+
+func (e *Engine) handleSubscribe(req subscribeReq) {
+	e.stat.nextSubID++
+	req.sub.id = e.stat.nextSubID
+	if req.resume != nil {
+		e.handleResume(req)
+		return
+	}
+	e.subs[req.ch] = req.sub
+	st := e.current()
+	req.sub.last = st.TS
+	req.sub.delivered++
+	req.ch <- st
+}
+
+// current returns the latest state without advancing the simulation.
+func (e *Engine) current() AircraftState {
+	if e.replay != nil {
+		return e.replay.last
+	}
+	st := e.buildSnapshot(e.now, e.lastWarnings)
+	st.Seq = e.seq
+	if !e.extrapolate || !e.running {
+		e.degrade(&st)
 		return st
 	}
 
-	publish := func(st AircraftState) {
-		for ch := range subs {
-			select {
-			case ch <- st:
-			default:
-				// slow subscriber -> drop frame
+	tick := time.Duration(float64(time.Second) / e.tickHz)
+	age := e.clock.Now().Sub(e.now)
+	if age <= 0 {
+		e.degrade(&st)
+		return st
+	}
+	if age > tick {
+		age = tick
+	}
+	dt := age.Seconds()
+	st.Lat, st.Lon, st.Alt = e.geo.LocalToGeo(e.pos.Add(e.gvel.Mul(dt)))
+	st.TS = e.now.Add(age)
+	st.ExtrapolatedS = dt
+	e.degrade(&st)
+	return st
+}
+
+func (e *Engine) handleUnsubscribe(ch chan AircraftState) {
+	if _, ok := e.subs[ch]; ok {
+		delete(e.subs, ch)
+		close(ch)
+	}
+}
+
+func (e *Engine) handleCommand(sub submission) {
+	if e.replay != nil {
+		return
+	}
+	cmd, source := e.normalizeAlt(sub.cmd), sub.source
+	sub.cmd = cmd
+	if err := e.checkRange(cmd); err != nil {
+		// the API checks this first; this catches other callers of Submit
+		e.rejectCommand(sub, err)
+		return
+	}
+	e.rec.write(Record{Kind: RecordCommand, TS: e.now, Command: &CommandEnvelope{Command: cmd, Source: source}})
+	e.emitCommandChange(cmd, source)
+	taken := e.takenAt()
+	e.logCommand(sub, taken)
+	e.activeSource = source
+
+	switch cmd.Type() {
+	case CmdStop:
+		e.active = nil
+		e.traj = nil
+		e.trajIdx = 0
+		e.vel = vector.Vec3{}
+		e.lastWarnings = nil
+
+	case CmdHold:
+		e.active = cmd
+		e.traj = nil
+		e.trajIdx = 0
+		e.vel = vector.Vec3{}
+		e.lastWarnings = nil
+		e.startActive(taken)
+
+	case CmdSetState:
+		c := cmd.(SetStateCommand)
+		e.active = nil
+		e.traj = nil
+		e.trajIdx = 0
+		e.pos = e.geo.GeoToLocal(c.Lat, c.Lon, c.Alt)
+		e.vel = vector.Vec3{X: c.Vx, Y: c.Vy, Z: c.Vz}
+		e.gvel = e.vel
+		e.att.reset(e.vel)
+		e.lastWarnings = nil
+		e.teleported = true
+
+	case CmdGoTo, CmdTrajectory:
+		e.autoRebase(cmd)
+		e.setActive(cmd)
+		e.startActive(taken)
+	}
+}
+
+func (e *Engine) setActive(cmd Command) {
+	e.active = cmd
+	e.traj = nil
+	e.trajIdx = 0
+	e.trajLoop = false
+
+	if tc, ok := cmd.(TrajectoryCommand); ok {
+		e.traj = tc.Waypoints
+		e.trajIdx = 0
+		e.trajLoop = tc.Loop
+	}
+}
+
+// advance runs one simulation tick ending at now, publishes the resulting
+// snapshot and returns it. The tick is split into Config.SubSteps physics
+// steps, each re-running guidance, dynamics and environment effects.
+func (e *Engine) advance(now time.Time, dt float64) AircraftState {
+	began := time.Now() // wall clock, for Stats only
+	defer func() { e.stat.tick(dt, time.Since(began)) }()
+	e.now = now
+
+	var warnings []Warning
+	start := e.pos
+	e.airStep = vector.Vec3{}
+	teleported := e.teleported
+	e.teleported = false
+	switch {
+	case teleported:
+		// publish the SetStateCommand values as they are
+	case e.energy.frozen(), e.ditched:
+		e.vel = vector.Vec3{}
+	default:
+		h := dt / float64(e.subSteps)
+		stepStart := now.Add(-time.Duration(dt * float64(time.Second)))
+		for i := 0; i < e.subSteps; i++ {
+			desired := e.guide()
+			if e.energy.empty() {
+				desired = e.emptyBatteryDescent()
 			}
+			desired = e.atmosphere.scale(desired, e.pos.Z)
+			desired = e.applyLosses(desired)
+			desired, ceilingWarnings := e.limitClimb(desired, h)
+			// keep each code once, as raised by the latest sub-step
+			warnings = mergeWarnings(warnings, ceilingWarnings)
+			at := stepStart.Add(time.Duration(float64(i+1) * h * float64(time.Second)))
+			warnings = mergeWarnings(warnings, e.integrate(desired, at, h))
+		}
+	}
+	if !teleported {
+		e.gvel = e.pos.Sub(start).Mul(1 / dt)
+		if e.windEst != nil {
+			e.windEst.Update(e.pos.Sub(start), e.airStep.Mul(1/dt), dt)
 		}
 	}
+	e.odoM += e.pos.Sub(start).Horizontal().Length()
+	e.odoTimeS += dt
+	e.att.update(e.vel, dt)
+	warnings = e.checkDitched(warnings)
+	warnings = e.terrainDeb.debounceTerrain(warnings, e.terrainTicks)
 
-	setActive := func(cmd Command) {
-		active = cmd
-		traj = nil
-		trajIdx = 0
-		trajLoop = false
+	warnings = append(warnings, e.updateWindComponents()...)
+	warnings = append(warnings, e.checkTerrainAhead()...)
+	warnings = append(warnings, e.checkTerrainFollow()...)
 
-		if tc, ok := cmd.(TrajectoryCommand); ok {
-			traj = tc.Waypoints
-			trajIdx = 0
-			trajLoop = tc.Loop
-		}
+	e.energy.consume(e.vel, dt)
+	warnings = append(warnings, e.energy.warnings()...)
+	warnings = append(warnings, e.checkFence()...)
+	var trafficWarnings []Warning
+	e.trafficStates, trafficWarnings = e.updateTraffic(dt)
+	warnings = append(warnings, trafficWarnings...)
+	e.drawNoise()
+	e.updateFaults()
+
+	// ✅ store warnings for GET /state responses
+	e.emitWarningChange(e.lastWarnings, warnings)
+	e.lastWarnings = warnings
+	e.flushEvents()
+
+	if !e.publishDue(now) && e.running {
+		// Run ignores the result; only Step needs a state every tick.
+		return AircraftState{}
+	}
+	if e.resetPending {
+		reset := Warning{Code: WarnReset, Severity: env.SeverityInfo, Message: "simulation reset"}
+		warnings = append([]Warning{reset}, warnings...)
+		e.resetPending = false
+	}
+	st := e.buildSnapshot(now, warnings)
+	e.degrade(&st)
+	if e.publishDue(now) {
+		e.publishNow = false
+		e.lastPublish = now
+		e.publish(&st)
 	}
+	return st
+}
 
-	dist2D := func(a vector.Vec3) float64 {
-		return math.Sqrt(a.X*a.X + a.Y*a.Y)
+// publishDue reports whether a state should be published at now.
+func (e *Engine) publishDue(now time.Time) bool {
+	if e.publishNow || e.resetPending || e.publishHz >= e.tickHz || e.lastPublish.IsZero() {
+		return true
 	}
+	// half a tick of slack so ticker jitter does not skip a publish
+	interval := 1/e.publishHz - 0.5/e.tickHz
+	return now.Sub(e.lastPublish).Seconds() >= interval
+}
 
-	normalize2D := func(v vector.Vec3) vector.Vec3 {
-		n := dist2D(v)
-		if n < 1e-9 {
-			return vector.Vec3{}
-		}
-		return vector.Vec3{X: v.X / n, Y: v.Y / n, Z: 0}
+// emptyBatteryDescent drops the active command and returns a straight
+// descent at the maximum rate.
+func (e *Engine) emptyBatteryDescent() vector.Vec3 {
+	if e.active != nil {
+		e.emit(Event{Kind: EventCommandSuperseded, Command: e.active.Type(), Detail: WarnBatteryEmpty})
+		e.active = nil
+		e.traj = nil
+		e.trajIdx = 0
 	}
+	return vector.Vec3{Z: -e.limits.MaxClimbRate}
+}
 
-	computeDesiredVel := func(target vector.Vec3, speed float64) vector.Vec3 {
-		delta := vector.Vec3{X: target.X - pos.X, Y: target.Y - pos.Y, Z: target.Z - pos.Z}
-		horiz := vector.Vec3{X: delta.X, Y: delta.Y, Z: 0}
-		hDist := dist2D(horiz)
+// guide computes the desired velocity from the active command, advancing
+// waypoints and completing commands on arrival.
+func (e *Engine) guide() vector.Vec3 {
+	desired := vector.Vec3{}
+	e.follow = terrainFollow{}
+	if e.active == nil {
+		return desired
+	}
 
-		desired := vector.Vec3{}
+	switch c := e.active.(type) {
+	case GoToCommand:
+		target := e.geo.GeoToLocal(c.Lat, c.Lon, c.Alt)
+		speed := c.Speed
+		if speed <= 0 {
+			speed = e.limits.DefaultSpeed
+		}
+		if c.AGL > 0 {
+			target.Z = e.followTerrain(target, speed, c.AGL)
+		}
+
+		desired = e.computeDesiredVel(target, speed)
 
-		if hDist > posTolM {
-			dir := normalize2D(horiz)
-			desired.X = dir.X * speed
-			desired.Y = dir.Y * speed
+		// arrival check
+		if e.arrived(target) {
+			e.active = nil
+			desired = vector.Vec3{}
+			e.emit(Event{Kind: EventCommandCompleted, Command: CmdGoTo})
 		}
 
-		if delta.Z > altTolM {
-			desired.Z = maxClimbRate
-		} else if delta.Z < -altTolM {
-			desired.Z = -maxClimbRate
-		} else {
-			desired.Z = 0
+	case TrajectoryCommand:
+		if len(e.traj) == 0 || e.trajIdx < 0 || e.trajIdx >= len(e.traj) {
+			e.active = nil
+			desired = vector.Vec3{}
+			e.emit(Event{Kind: EventCommandCompleted, Command: CmdTrajectory})
+			break
 		}
 
-		return desired
-	}
+		wp := e.traj[e.trajIdx]
+		target := e.geo.GeoToLocal(wp.Lat, wp.Lon, wp.Alt)
+		speed := wp.Speed
+		if speed <= 0 {
+			speed = e.limits.DefaultSpeed
+		}
+		if wp.AGL > 0 {
+			target.Z = e.followTerrain(target, speed, wp.AGL)
+		}
+
+		desired = e.computeDesiredVel(target, speed)
 
-	approach := func(cur, des float64, amax float64, dt float64) float64 {
-		diff := des - cur
-		maxStep := amax * dt
-		if diff > maxStep {
-			return cur + maxStep
+		if e.arrived(target) {
+			e.emit(waypointEvent(e.trajIdx))
+			e.trajIdx++
+			if e.trajIdx >= len(e.traj) {
+				if e.trajLoop {
+					e.trajIdx = 0
+					e.trajLaps++
+				} else {
+					e.active = nil
+					desired = vector.Vec3{}
+					e.emit(Event{Kind: EventCommandCompleted, Command: CmdTrajectory})
+				}
+			}
 		}
-		if diff < -maxStep {
-			return cur - maxStep
+
+	case HoldCommand:
+		desired = vector.Vec3{}
+	}
+	return desired
+}
+
+// integrate advances velocity and position by dt, ending at simulation
+// time at, and returns the environment warnings for the step.
+func (e *Engine) integrate(desired vector.Vec3, at time.Time, dt float64) []Warning {
+	var warnings []Warning
+	prev := e.vel
+
+	if e.physics == PhysicsPointMass {
+		// thrust is cut without an active command, so the aircraft glides
+		e.vel = e.airframe.pointMassStep(e.vel, desired, e.active != nil && !e.energy.empty(), dt)
+	} else {
+		// smooth toward desired velocity (air velocity)
+		e.vel = e.approachVel(e.vel, desired, dt)
+	}
+	approached := e.vel
+
+	// apply environment effects (wind moves the air, terrain clips altitude, etc.)
+	var wind vector.Vec3
+	e.climbLoss, e.speedLoss = 0, 0
+	for _, effect := range e.effects().Effects {
+		res, err := effect.Apply(e.envContext(at, dt, desired))
+		if err != nil {
+			warnings = append(warnings, Warning{
+				Code:     env.WarnEnvironmentError,
+				Severity: env.SeverityWarning,
+				Message:  err.Error(),
+			})
+			continue
 		}
-		return des
+		e.pos, e.vel, wind = res.Pos, res.Vel, wind.Add(res.Wind)
+		e.climbLoss = env.CombineLoss(e.climbLoss, res.ClimbLoss)
+		e.speedLoss = env.CombineLoss(e.speedLoss, res.SpeedLoss)
+		warnings = append(warnings, res.Warnings...)
+	}
+
+	// integrate position by ground velocity = air velocity + wind
+	step := e.vel
+	if e.integrator == IntegratorMidpoint {
+		// average the velocity over the step, keeping any correction the
+		// environment made (e.g. a cancelled descent)
+		step = prev.Add(approached).Mul(0.5).Add(e.vel.Sub(approached))
+	}
+	e.airStep = e.airStep.Add(step.Mul(dt))
+	step = step.Add(wind)
+	e.pos.X += step.X * dt
+	e.pos.Y += step.Y * dt
+	e.pos.Z += step.Z * dt
+
+	warnings = append(warnings, e.clipFloor()...)
+	return append(warnings, e.clipCeiling()...)
+}
+
+// applyLosses scales the desired velocity by the environment's performance
+// losses: the horizontal speed by SpeedLoss and a climb by ClimbLoss.
+func (e *Engine) applyLosses(desired vector.Vec3) vector.Vec3 {
+	keep := 1 - e.speedLoss
+	desired.X *= keep
+	desired.Y *= keep
+	if desired.Z > 0 {
+		desired.Z *= 1 - e.climbLoss
+	}
+	return desired
+}
+
+// clipFloor keeps the position at or above the terrain surface after a
+// step, for descents the wind drove into it. Below the safety margin alone
+// the terrain effect's recovery climb is left to bring the aircraft back
+// up on the following steps.
+func (e *Engine) clipFloor() []Warning {
+	if e.environment == nil {
+		return nil
+	}
+	ground, ok := env.GroundAltitude(e.environment, e.pos)
+	if !ok || e.pos.Z >= ground {
+		return nil
 	}
+	e.pos.Z = ground
+	if e.vel.Z < 0 {
+		e.vel.Z = 0
+	}
+	return []Warning{env.SurfaceContact(env.WaterAt(e.environment, e.pos))}
+}
 
-	approachVel := func(cur, des vector.Vec3, dt float64) vector.Vec3 {
-		return vector.Vec3{
-			X: approach(cur.X, des.X, maxHorizAccel, dt),
-			Y: approach(cur.Y, des.Y, maxHorizAccel, dt),
-			Z: approach(cur.Z, des.Z, maxVertAccel, dt),
+// effects lists the environment effects applied every step, in order: the
+// configured environment, then any runtime weather cells and microbursts.
+// The chain is built once and reused until that list changes; callers must
+// not modify it.
+func (e *Engine) effects() *env.Chain {
+	weather, bursts := e.weather.Len() > 0, e.microbursts.Len() > 0
+	if e.chain != nil && weather == e.chainWeather && bursts == e.chainBursts {
+		return e.chain
+	}
+	c := &env.Chain{}
+	if e.environment != nil {
+		c.Effects = append(c.Effects, e.environment)
+	}
+	if weather {
+		c.Effects = append(c.Effects, e.weather)
+	}
+	if bursts {
+		c.Effects = append(c.Effects, e.microbursts)
+	}
+	e.chain, e.chainWeather, e.chainBursts = c, weather, bursts
+	return c
+}
+
+// setEnvironment replaces the configured environment, and with it the
+// chain effects returns.
+func (e *Engine) setEnvironment(environment env.Environment) {
+	e.environment = environment
+	e.chain = nil
+}
+
+// envContext describes the current sub-step to environment effects.
+func (e *Engine) envContext(at time.Time, dt float64, desired vector.Vec3) env.Context {
+	agl := e.pos.Z
+	if e.environment != nil {
+		if ground, ok := env.GroundAltitude(e.environment, e.pos); ok {
+			agl -= ground
 		}
 	}
+	c := env.Context{
+		SimTime: at, Dt: dt, Pos: e.pos, Vel: e.vel, AGL: agl, AircraftID: e.aircraftID,
+		Commanded: desired,
+		PointMass: e.physics == PhysicsPointMass,
+	}
+	if e.active != nil {
+		c.Command = string(e.active.Type())
+	}
+	return c
+}
 
-	tick := time.NewTicker(time.Duration(float64(time.Second) / e.tickHz))
-	defer tick.Stop()
+func (e *Engine) buildSnapshot(ts time.Time, warnings []Warning) AircraftState {
+	roll, pitch, yaw := e.att.euler()
+	lat, lon, alt := e.geo.LocalToGeo(e.pos)
+	st := AircraftState{
+		Lat: lat, Lon: lon, Alt: alt,
+		Vx: e.vel.X, Vy: e.vel.Y, Vz: e.vel.Z,
+		GVx: e.gvel.X, GVy: e.gvel.Y, GVz: e.gvel.Z,
+		GroundSpeedMps:   e.gvel.Horizontal().Length(),
+		VerticalSpeedMps: e.gvel.Z,
+		TrackDeg:         HeadingDegFromVec(e.gvel),
+		DistanceFlownM:   e.odoM,
+		FlightTimeS:      e.odoTimeS,
+		HeadingDeg:       HeadingDegFromVec(e.vel),
+		RollDeg:          roll,
+		PitchDeg:         pitch,
+		YawDeg:           yaw,
+		Quaternion:       e.att.q,
+		TS:               ts,
+		Warnings:         warnings,
+		Warning:          env.Summary(warnings),
+		TargetIndex:      e.trajIdx,
+	}
+	if e.active != nil {
+		st.ActiveCommand = string(e.active.Type())
+		if tc, ok := e.active.(TrajectoryCommand); ok {
+			st.Mission = tc.Mission
+		}
+	}
+	st.AltRef = AltRefMSL
+	st.Ditched = e.ditched
+	st.HeadingMagDeg = st.HeadingDeg
+	if e.declination != nil {
+		st.HeadingMagDeg = MagneticHeadingDeg(st.HeadingDeg, e.declination.DeclinationDeg(lat, lon))
+	}
+	if e.environment != nil {
+		if ground, ok := env.GroundAltitude(e.environment, e.pos); ok {
+			agl := e.pos.Z - ground
+			st.AGLM = &agl
+		}
+	}
+	if e.follow.on {
+		target := e.follow.aglM
+		st.TargetAGLM = &target
+	}
+	if e.atmosphere.Enabled {
+		st.DensityAltitudeM = e.atmosphere.densityAltitude(e.pos.Z)
+	}
+	if e.windEst != nil {
+		w, conf := e.windEst.Estimate()
+		st.EstimatedWindX, st.EstimatedWindY, st.EstimatedWindConfidence = &w.X, &w.Y, &conf
+	}
+	st.HeadwindMps, st.CrosswindMps = e.headwind, e.crosswind
+	st.FenceDistanceM = e.fenceDistance()
+	st.Traffic = e.trafficStates
+	if e.energy != nil {
+		pct := e.energy.pct()
+		st.BatteryPct = &pct
+		if s, ok := e.energy.enduranceS(); ok {
+			st.EnduranceS = &s
+		}
+	}
+	return st
+}
 
-	for {
+func (e *Engine) publish(p *AircraftState) {
+	e.seq++
+	p.Seq = e.seq
+	st := *p
+	if e.replay == nil {
+		e.rec.write(Record{Kind: RecordState, TS: st.TS, State: &st})
+	}
+	e.history.push(st)
+	e.stat.published++
+	for ch, sub := range e.subs {
+		if sub.ctx.Err() != nil {
+			// unsub may not have got through; the context says it is over
+			e.handleUnsubscribe(ch)
+			continue
+		}
+		if !sub.wants(st) {
+			continue
+		}
 		select {
-		case <-ctx.Done():
-			for ch := range subs {
-				close(ch)
-			}
-			return nil
+		case ch <- st:
+			sub.last = st.TS
+			sub.delivered++
+		default:
+			// slow subscriber -> drop frame
+			sub.dropped++
+			e.stat.framesDropped++
+		}
+	}
+}
 
-		case req := <-e.subscribeCh:
-			subs[req.ch] = struct{}{}
-			req.ch <- buildSnapshot(now, lastWarning)
+// arrived reports whether pos is within the arrival tolerances of target.
+func (e *Engine) arrived(target vector.Vec3) bool {
+	d := target.Sub(e.pos)
+	return d.Horizontal().Length() <= e.limits.PosTolM && math.Abs(d.Z) <= e.limits.AltTolM
+}
 
-		case ch := <-e.unsubCh:
-			if _, ok := subs[ch]; ok {
-				delete(subs, ch)
-				close(ch)
-			}
+func (e *Engine) computeDesiredVel(target vector.Vec3, speed float64) vector.Vec3 {
+	delta := target.Sub(e.pos)
+	horiz := delta.Horizontal()
+	hDist := horiz.Length()
 
-		case req := <-e.stateReqCh:
-			// ✅ return latest warning, not an always-empty string
-			req.reply <- buildSnapshot(now, lastWarning)
-
-		case cmd := <-e.cmdCh:
-			switch cmd.Type() {
-			case CmdStop:
-				active = nil
-				traj = nil
-				trajIdx = 0
-				vel = vector.Vec3{}
-				lastWarning = ""
-
-			case CmdHold:
-				active = cmd
-				traj = nil
-				trajIdx = 0
-				vel = vector.Vec3{}
-				lastWarning = ""
-
-			case CmdGoTo, CmdTrajectory:
-				setActive(cmd)
-			}
+	desired := vector.Vec3{}
 
-		case t := <-tick.C:
-			dt := t.Sub(now).Seconds()
-			if dt <= 0 {
-				dt = 1.0 / e.tickHz
-			}
-			now = t
-
-			warning := ""
-
-			// compute desired velocity from active command
-			desired := vector.Vec3{}
-			if active != nil {
-				switch c := active.(type) {
-				case GoToCommand:
-					target := e.geo.GeoToLocal(c.Lat, c.Lon, c.Alt)
-					speed := c.Speed
-					if speed <= 0 {
-						speed = defaultSpeed
-					}
-
-					desired = computeDesiredVel(target, speed)
-
-					// arrival check
-					d := vector.Vec3{X: target.X - pos.X, Y: target.Y - pos.Y, Z: target.Z - pos.Z}
-					if dist2D(vector.Vec3{X: d.X, Y: d.Y}) <= posTolM && math.Abs(d.Z) <= altTolM {
-						active = nil
-						desired = vector.Vec3{}
-					}
-
-				case TrajectoryCommand:
-					if len(traj) == 0 || trajIdx < 0 || trajIdx >= len(traj) {
-						active = nil
-						desired = vector.Vec3{}
-						break
-					}
-
-					wp := traj[trajIdx]
-					target := e.geo.GeoToLocal(wp.Lat, wp.Lon, wp.Alt)
-					speed := wp.Speed
-					if speed <= 0 {
-						speed = defaultSpeed
-					}
-
-					desired = computeDesiredVel(target, speed)
-
-					d := vector.Vec3{X: target.X - pos.X, Y: target.Y - pos.Y, Z: target.Z - pos.Z}
-					if dist2D(vector.Vec3{X: d.X, Y: d.Y}) <= posTolM && math.Abs(d.Z) <= altTolM {
-						trajIdx++
-						if trajIdx >= len(traj) {
-							if trajLoop {
-								trajIdx = 0
-							} else {
-								active = nil
-								desired = vector.Vec3{}
-							}
-						}
-					}
-
-				case HoldCommand:
-					desired = vector.Vec3{}
-				}
-			}
+	if hDist > e.limits.PosTolM {
+		dir := horiz.Normalize()
+		desired.X = dir.X * speed
+		desired.Y = dir.Y * speed
+	}
 
-			// smooth toward desired velocity (air velocity)
-			vel = approachVel(vel, desired, dt)
+	if delta.Z > e.limits.AltTolM {
+		desired.Z = e.limits.MaxClimbRate
+	} else if delta.Z < -e.limits.AltTolM {
+		desired.Z = -e.limits.MaxClimbRate
+	} else {
+		desired.Z = 0
+	}
 
-			// apply environment effects (wind affects position, terrain clips altitude, etc.)
-			if e.environment != nil {
-				p2, v2, warn := e.environment.Apply(dt, pos, vel)
-				pos, vel = p2, v2
-				warning = warn
-			}
+	return desired
+}
+
+// mergeWarnings adds ws to dst, replacing all entries with a code raised
+// again in ws. Distinct warnings sharing a code within ws are all kept.
+func mergeWarnings(dst, ws []Warning) []Warning {
+	if len(ws) == 0 {
+		return dst
+	}
+	raised := make(map[string]bool, len(ws))
+	for _, w := range ws {
+		raised[w.Code] = true
+	}
+	out := dst[:0]
+	for _, w := range dst {
+		if !raised[w.Code] {
+			out = append(out, w)
+		}
+	}
+	return append(out, env.Dedup(ws)...)
+}
 
-			// integrate position by air velocity (wind drift already applied in env)
-			pos.X += vel.X * dt
-			pos.Y += vel.Y * dt
-			pos.Z += vel.Z * dt
+func approach(cur, des float64, amax float64, dt float64) float64 {
+	diff := des - cur
+	maxStep := amax * dt
+	if diff > maxStep {
+		return cur + maxStep
+	}
+	if diff < -maxStep {
+		return cur - maxStep
+	}
+	return des
+}
 
-			// ✅ store warning for GET /state responses
-			lastWarning = warning
+func (e *Engine) approachVel(cur, des vector.Vec3, dt float64) vector.Vec3 {
+	next := vector.Vec3{
+		X: approach(cur.X, des.X, e.limits.MaxHorizAccel, dt),
+		Y: approach(cur.Y, des.Y, e.limits.MaxHorizAccel, dt),
+		Z: approach(cur.Z, des.Z, e.limits.MaxVertAccel, dt),
+	}
+	return e.limitBank(cur, next, dt)
+}
 
-			st := buildSnapshot(now, warning)
-			publish(st)
-		}
+// limitBank caps the part of the change from cur to next that turns the
+// track, at the lateral acceleration of a coordinated turn banked at
+// MaxBankDeg. Speeding up and slowing down along the track are left alone.
+//
+// The cap is scaled down while the step slows the aircraft, so the bank
+// the attitude derives from the turn at the end of the step, relative to
+// the slower velocity, stays within MaxBankDeg as well.
+func (e *Engine) limitBank(cur, next vector.Vec3, dt float64) vector.Vec3 {
+	hs := cur.Horizontal().Length()
+	if hs < attitudeMinSpeed || dt <= 0 {
+		return next
+	}
+	along := cur.XY().Mul(1 / hs)
+	accel := next.XY().Sub(cur.XY()).Mul(1 / dt)
+	lat := accel.Cross(along)
+	maxLat := gravity * math.Tan(e.limits.MaxBankDeg*math.Pi/180)
+	if ahead := hs + accel.Dot(along)*dt; ahead < hs {
+		maxLat *= math.Max(ahead, 0) / hs
+	}
+	if math.Abs(lat) <= maxLat {
+		return next
 	}
+	// Cross is positive for an acceleration to the right of the track,
+	// and right of along is (along.Y, -along.X).
+	right := vector.Vec2{X: along.Y, Y: -along.X}
+	trim := right.Mul((lat - math.Copysign(maxLat, lat)) * dt)
+	next.X -= trim.X
+	next.Y -= trim.Y
+	return next
 }
@@ -0,0 +1,191 @@
+package sim_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/fakeclock"
+)
+
+// runEngine starts eng.Run on clk and stops it when the test ends.
+func runEngine(t *testing.T, eng *sim.Engine, clk *fakeclock.Clock) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		eng.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	clk.WaitForTicker()
+	return ctx
+}
+
+func TestGoToArrivesOnFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := fakeclock.New(start)
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runEngine(t, eng, clk)
+	events, unsub := eng.SubscribeEvents(ctx)
+	defer unsub()
+
+	target := sim.GoToCommand{At: clk.Now(), Lat: 47.005, Lon: 8.005, Alt: 1100, Speed: 50}
+	if err := eng.Submit(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+
+	var arrived *sim.Event
+	for i := 0; i < 20*120 && arrived == nil; i++ {
+		clk.Advance(50 * time.Millisecond)
+		// GetState goes through the actor, so the tick has been handled.
+		if _, err := eng.GetState(ctx); err != nil {
+			t.Fatal(err)
+		}
+		for drained := false; !drained; {
+			select {
+			case ev := <-events:
+				if ev.Kind == sim.EventCommandCompleted && ev.Command == sim.CmdGoTo {
+					arrived = &ev
+				}
+			default:
+				drained = true
+			}
+		}
+	}
+	if arrived == nil {
+		t.Fatal("no goto completion event after two minutes of fake time")
+	}
+
+	st, err := eng.GetState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ActiveCommand != "" {
+		t.Errorf("active command %q after arrival", st.ActiveCommand)
+	}
+	if !arrived.TS.Equal(st.TS) {
+		t.Errorf("arrival event at %v, last tick at %v", arrived.TS, st.TS)
+	}
+	if el := arrived.TS.Sub(start); el < 10*time.Second || el > 60*time.Second {
+		t.Errorf("arrived after %v of fake time", el)
+	}
+	// within the default arrival tolerances of 25 m and 10 m
+	const mPerDegLat = 111_200.0
+	dN := (st.Lat - target.Lat) * mPerDegLat
+	dE := (st.Lon - target.Lon) * mPerDegLat * math.Cos(target.Lat*math.Pi/180)
+	if d := math.Hypot(dE, dN); d > 25.5 {
+		t.Errorf("arrived %.1f m from the target", d)
+	}
+	if math.Abs(st.Alt-target.Alt) > 10 {
+		t.Errorf("arrived at alt %.1f, want %.0f±10", st.Alt, target.Alt)
+	}
+}
+
+func TestActiveCommandTimesShareAClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := fakeclock.New(start)
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runEngine(t, eng, clk)
+	clk.Advance(100 * time.Millisecond)
+	if _, err := eng.GetState(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// between ticks, so the last tick is behind the clock the command is
+	// stamped with
+	clk.Advance(30 * time.Millisecond)
+	at := eng.Now()
+	if err := eng.Submit(ctx, sim.GoToCommand{At: at, Lat: 47.01, Lon: 8, Alt: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := eng.Active(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.ReceivedAt.Equal(at) || !a.StartedAt.Equal(at) {
+		t.Errorf("received %v, started %v, want both at %v", a.ReceivedAt, a.StartedAt, at)
+	}
+	if a.ElapsedS != 0 {
+		t.Errorf("elapsed %gs before the next tick", a.ElapsedS)
+	}
+	log, err := eng.CommandLog(ctx, sim.CommandLogQuery{})
+	if err != nil || len(log) != 1 || log[0].TakenAt == nil || !log[0].TakenAt.Equal(at) {
+		t.Errorf("command log %+v (%v), want it taken at %v", log, err, at)
+	}
+	clk.Advance(20 * time.Millisecond)
+	if a, err = eng.Active(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(a.ElapsedS-0.02) > 1e-9 {
+		t.Errorf("elapsed %gs at the next tick, want 0.02", a.ElapsedS)
+	}
+}
+
+func TestSupersededBetweenTicksEndsAfterItWasTaken(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := fakeclock.New(start)
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runEngine(t, eng, clk)
+	clk.Advance(100 * time.Millisecond)
+	if _, err := eng.GetState(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Submit only queues a command; wait for the engine to take it before
+	// the clock moves on
+	taken := func(n int) []sim.CommandRecord {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			log, err := eng.CommandLog(ctx, sim.CommandLogQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(log) == n {
+				return log
+			}
+		}
+		t.Fatalf("the engine never took command %d", n)
+		return nil
+	}
+
+	// two commands between the same two ticks: the goto is taken and
+	// superseded after the last tick
+	clk.Advance(10 * time.Millisecond)
+	if err := eng.Submit(ctx, sim.GoToCommand{At: eng.Now(), Lat: 47.01, Lon: 8, Alt: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	taken(1)
+	clk.Advance(20 * time.Millisecond)
+	if err := eng.Submit(ctx, sim.HoldCommand{At: eng.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	log := taken(2)
+	gotoRec, hold := log[0], log[1]
+	if gotoRec.Outcome != sim.OutcomeSuperseded || gotoRec.TakenAt == nil || gotoRec.EndedAt == nil {
+		t.Fatalf("goto record %+v", gotoRec)
+	}
+	if !gotoRec.TakenAt.Equal(start.Add(110 * time.Millisecond)) {
+		t.Errorf("goto taken at %v, want between the ticks", gotoRec.TakenAt)
+	}
+	if gotoRec.EndedAt.Before(*gotoRec.TakenAt) {
+		t.Errorf("goto ended at %v, before it was taken at %v", gotoRec.EndedAt, gotoRec.TakenAt)
+	}
+	if hold.TakenAt == nil || !gotoRec.EndedAt.Equal(*hold.TakenAt) {
+		t.Errorf("goto ended at %v, hold taken at %v", gotoRec.EndedAt, hold.TakenAt)
+	}
+}
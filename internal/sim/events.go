@@ -0,0 +1,190 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"flight-simulator2/internal/env"
+)
+
+type EventKind string
+
+const (
+	EventWaypointReached   EventKind = "waypoint_reached"
+	EventCommandActivated  EventKind = "command_activated"
+	EventCommandCompleted  EventKind = "command_completed"
+	EventCommandSuperseded EventKind = "command_superseded"
+	EventWarningRaised     EventKind = "warning_raised"
+	EventWarningCleared    EventKind = "warning_cleared"
+	EventHold              EventKind = "hold"
+	EventStop              EventKind = "stop"
+	EventReset             EventKind = "reset"
+	// EventLanded is the aircraft coming down onto the terrain or water:
+	// a terrain-contact or ditched warning being raised.
+	EventLanded EventKind = "landed"
+)
+
+// ParseEventKind validates an event kind name.
+func ParseEventKind(s string) (EventKind, error) {
+	switch k := EventKind(s); k {
+	case EventWaypointReached, EventCommandActivated, EventCommandCompleted, EventCommandSuperseded,
+		EventWarningRaised, EventWarningCleared, EventHold, EventStop, EventReset, EventLanded:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown event kind %q", s)
+}
+
+// Event is a discrete occurrence emitted by the actor, such as a waypoint
+// being reached. Unlike state frames, events are never dropped for a slow
+// subscriber: they queue until delivered (see maxPendingEvents).
+type Event struct {
+	Kind   EventKind `json:"kind"`
+	TS     time.Time `json:"ts"`
+	Detail string    `json:"detail,omitempty"`
+
+	Command  CommandType `json:"command,omitempty"`
+	Waypoint *int        `json:"waypoint,omitempty"`
+	// Source is where the command came from (see WithCommandSource), on
+	// the events of that command.
+	Source string `json:"source,omitempty"`
+}
+
+// maxPendingEvents bounds the backlog kept for a subscriber that stopped
+// reading. A subscriber exceeding it is disconnected (its channel is closed)
+// rather than silently missing events.
+const maxPendingEvents = 4096
+
+type eventSub struct {
+	ch      chan Event
+	pending []Event
+}
+
+// flush delivers queued events without blocking. It reports false when the
+// backlog overflowed and the subscriber must be dropped.
+func (s *eventSub) flush() bool {
+	for len(s.pending) > 0 {
+		select {
+		case s.ch <- s.pending[0]:
+			s.pending = s.pending[1:]
+		default:
+			return len(s.pending) <= maxPendingEvents
+		}
+	}
+	s.pending = nil
+	return true
+}
+
+// SubscribeEvents streams engine events until ctx ends or unsub is called.
+func (e *Engine) SubscribeEvents(ctx context.Context) (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+
+	select {
+	case e.eventSubCh <- ch:
+	case <-ctx.Done():
+		close(ch)
+		return ch, func() {}
+	}
+
+	unsub := func() {
+		select {
+		case e.eventUnsubCh <- ch:
+		default:
+		}
+	}
+	return ch, unsub
+}
+
+func (e *Engine) handleEventSubscribe(ch chan Event) {
+	e.eventSubs[ch] = &eventSub{ch: ch}
+}
+
+func (e *Engine) handleEventUnsubscribe(ch chan Event) {
+	if _, ok := e.eventSubs[ch]; ok {
+		delete(e.eventSubs, ch)
+		close(ch)
+	}
+}
+
+// emit queues ev for every event subscriber.
+func (e *Engine) emit(ev Event) {
+	if ev.TS.IsZero() {
+		ev.TS = e.now
+	}
+	if ev.Source == "" && e.active != nil && ev.Command == e.active.Type() {
+		ev.Source = e.activeSource
+	}
+	e.endCommandRecord(ev)
+	for _, s := range e.eventSubs {
+		s.pending = append(s.pending, ev)
+	}
+	e.flushEvents()
+	// publish the state that goes with the event without waiting for PublishHz
+	e.publishNow = true
+}
+
+func (e *Engine) flushEvents() {
+	for ch, s := range e.eventSubs {
+		if !s.flush() {
+			delete(e.eventSubs, ch)
+			close(ch)
+		}
+	}
+}
+
+// emitCommandChange reports the transition from the active command to cmd,
+// submitted from source.
+func (e *Engine) emitCommandChange(cmd Command, source string) {
+	if e.active != nil {
+		e.emit(Event{
+			Kind:    EventCommandSuperseded,
+			Command: e.active.Type(),
+			Detail:  fmt.Sprintf("superseded by %s", cmd.Type()),
+		})
+	}
+	switch cmd.Type() {
+	case CmdStop:
+		e.emit(Event{Kind: EventStop, Command: CmdStop, Source: source})
+	case CmdHold:
+		e.emit(Event{Kind: EventHold, Command: CmdHold, Source: source})
+	case CmdSetState:
+		e.emit(Event{Kind: EventCommandCompleted, Command: CmdSetState, Detail: "state set", Source: source})
+	default:
+		e.emit(Event{Kind: EventCommandActivated, Command: cmd.Type(), Source: source})
+	}
+}
+
+// emitWarningChange reports warnings starting or clearing, by code. A
+// warning whose message changes while its code stays active is not re-raised.
+func (e *Engine) emitWarningChange(prev, cur []Warning) {
+	active := func(ws []Warning, code string) bool {
+		for _, w := range ws {
+			if w.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+	for _, w := range prev {
+		if !active(cur, w.Code) {
+			e.emit(Event{Kind: EventWarningCleared, Detail: w.Code})
+		}
+	}
+	for _, w := range cur {
+		if !active(prev, w.Code) {
+			e.emit(Event{Kind: EventWarningRaised, Detail: w.String()})
+			if w.Code == env.WarnTerrainContact || w.Code == env.WarnDitched {
+				e.emit(Event{Kind: EventLanded, Detail: w.Code})
+			}
+		}
+	}
+}
+
+func waypointEvent(idx int) Event {
+	return Event{
+		Kind:     EventWaypointReached,
+		Command:  CmdTrajectory,
+		Waypoint: &idx,
+		Detail:   fmt.Sprintf("waypoint %d reached", idx),
+	}
+}
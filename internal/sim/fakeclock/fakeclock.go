@@ -0,0 +1,130 @@
+// Package fakeclock provides a manually advanced sim.Clock for tests.
+//
+// Time only moves when Advance is called. Each tick that falls due is handed
+// to the ticker's receiver synchronously, so when Advance returns the engine
+// has accepted every tick up to the new time.
+package fakeclock
+
+import (
+	"sync"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// Clock is a sim.Clock whose time is controlled by the caller.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*ticker
+}
+
+// New returns a Clock starting at start.
+func New(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker that fires every d of fake time.
+func (c *Clock) NewTicker(d time.Duration) sim.Ticker {
+	if d <= 0 {
+		panic("fakeclock: non-positive ticker interval")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &ticker{
+		ch:     make(chan time.Time),
+		stop:   make(chan struct{}),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, delivering every tick that falls due
+// in order. It blocks until each tick is received or its ticker is stopped.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		t := c.nextDue(target)
+		if t == nil {
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+		at := t.next
+		c.now = at
+		t.next = at.Add(t.period)
+		c.mu.Unlock()
+
+		select {
+		case t.ch <- at:
+		case <-t.stop:
+		}
+	}
+}
+
+// WaitForTicker blocks until at least one live ticker exists, i.e. until the
+// engine's Run loop has started.
+func (c *Clock) WaitForTicker() {
+	for {
+		c.mu.Lock()
+		n := 0
+		for _, t := range c.tickers {
+			if !t.stopped() {
+				n++
+			}
+		}
+		c.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// nextDue returns the live ticker with the earliest tick at or before target.
+func (c *Clock) nextDue(target time.Time) *ticker {
+	var due *ticker
+	for _, t := range c.tickers {
+		if t.stopped() || t.next.After(target) {
+			continue
+		}
+		if due == nil || t.next.Before(due.next) {
+			due = t
+		}
+	}
+	return due
+}
+
+type ticker struct {
+	ch     chan time.Time
+	stop   chan struct{}
+	once   sync.Once
+	period time.Duration
+	next   time.Time
+}
+
+func (t *ticker) C() <-chan time.Time { return t.ch }
+
+func (t *ticker) Stop() { t.once.Do(func() { close(t.stop) }) }
+
+func (t *ticker) stopped() bool {
+	select {
+	case <-t.stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,148 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// FaultKind selects an injected sensor fault.
+type FaultKind string
+
+const (
+	// FaultGPSFreeze holds the published lat/lon/alt at the values they had
+	// when the fault started.
+	FaultGPSFreeze FaultKind = "gps_freeze"
+	// FaultGPSNoise adds Gaussian horizontal noise of SigmaM to the
+	// published lat/lon, redrawn every tick, on top of any SensorNoise.
+	FaultGPSNoise FaultKind = "gps_noise"
+)
+
+// DefaultFaultDurationS is used when a Fault gives no duration.
+const DefaultFaultDurationS = 10.0
+
+// ErrFaultActive is returned when a fault of the same kind is already active.
+var ErrFaultActive = errors.New("a fault of this kind is already active")
+
+// Fault degrades the published state for a while; the internal (true)
+// state keeps evolving and stays available through Engine.Truth. Faults of
+// different kinds compose in a fixed order: the freeze applies first and
+// noise is added on top of it.
+type Fault struct {
+	Kind      FaultKind `json:"kind"`
+	DurationS float64   `json:"durationS,omitempty"`
+	SigmaM    float64   `json:"sigmaM,omitempty"` // FaultGPSNoise only
+}
+
+// Validate checks the kind and parameters.
+func (f Fault) Validate() error {
+	switch f.Kind {
+	case FaultGPSFreeze:
+	case FaultGPSNoise:
+		if !(f.SigmaM > 0) || math.IsInf(f.SigmaM, 0) {
+			return fmt.Errorf("sigmaM must be > 0 for %s", FaultGPSNoise)
+		}
+	default:
+		return fmt.Errorf("unknown fault kind %q (want %q or %q)", f.Kind, FaultGPSFreeze, FaultGPSNoise)
+	}
+	if f.DurationS < 0 || math.IsNaN(f.DurationS) || math.IsInf(f.DurationS, 0) {
+		return fmt.Errorf("durationS must be >= 0")
+	}
+	return nil
+}
+
+// ActiveFault is a Fault with its expiry time.
+type ActiveFault struct {
+	Fault
+	Until time.Time `json:"until"`
+}
+
+// faultState is the actor-owned record of an active fault.
+type faultState struct {
+	ActiveFault
+	lat, lon, alt float64 // true position at the start (freeze)
+}
+
+// InjectFault starts f. It fails with ErrFaultActive while a fault of the
+// same kind is running.
+func (e *Engine) InjectFault(ctx context.Context, f Fault) error {
+	if e.replay != nil {
+		return ErrReplay
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if f.DurationS == 0 {
+		f.DurationS = DefaultFaultDurationS
+	}
+	var ferr error
+	err := e.call(ctx, func() {
+		if _, ok := e.faults[f.Kind]; ok {
+			ferr = ErrFaultActive
+			return
+		}
+		fs := &faultState{ActiveFault: ActiveFault{
+			Fault: f,
+			Until: e.now.Add(time.Duration(f.DurationS * float64(time.Second))),
+		}}
+		fs.lat, fs.lon, fs.alt = e.geo.LocalToGeo(e.pos)
+		e.faults[f.Kind] = fs
+	})
+	if err != nil {
+		return err
+	}
+	return ferr
+}
+
+// Faults lists the active faults, sorted by kind.
+func (e *Engine) Faults(ctx context.Context) ([]ActiveFault, error) {
+	out := []ActiveFault{}
+	err := e.call(ctx, func() {
+		for _, f := range e.faults {
+			out = append(out, f.ActiveFault)
+		}
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out, err
+}
+
+// ClearFaults ends all active faults.
+func (e *Engine) ClearFaults(ctx context.Context) error {
+	return e.call(ctx, func() {
+		e.faults = map[FaultKind]*faultState{}
+		e.noiseE, e.noiseN = 0, 0
+	})
+}
+
+// Truth returns the internal state without any fault applied.
+func (e *Engine) Truth(ctx context.Context) (AircraftState, error) {
+	var st AircraftState
+	err := e.call(ctx, func() { st = e.buildSnapshot(e.now, e.lastWarnings) })
+	return st, err
+}
+
+// updateFaults expires finished faults and draws this tick's noise.
+func (e *Engine) updateFaults() {
+	for k, f := range e.faults {
+		if !e.now.Before(f.Until) {
+			delete(e.faults, k)
+		}
+	}
+	e.noiseE, e.noiseN = 0, 0
+	if f, ok := e.faults[FaultGPSNoise]; ok {
+		e.noiseE = e.rng.NormFloat64() * f.SigmaM
+		e.noiseN = e.rng.NormFloat64() * f.SigmaM
+	}
+}
+
+// applyFaults degrades st as the active faults dictate.
+func (e *Engine) applyFaults(st *AircraftState) {
+	if f, ok := e.faults[FaultGPSFreeze]; ok {
+		st.Lat, st.Lon, st.Alt = f.lat, f.lon, f.alt
+	}
+	st.Lat += e.noiseN / metersPerDegLat
+	st.Lon = wrapLon(st.Lon + e.noiseE/e.geo.metersPerDegLon())
+}
@@ -1,36 +1,198 @@
 package sim
 
 import (
-	"flight-simulator2/internal/geometry/vector"
+	"fmt"
 	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Projection selects how GeoRef converts between lat/lon and the local frame.
+type Projection string
+
+const (
+	// ProjectionWGS84 maps positions onto the plane tangent to the WGS84
+	// ellipsoid at the origin (geodetic → ECEF → ENU).
+	ProjectionWGS84 Projection = "wgs84"
+	// ProjectionEquirectangular scales degrees by fixed metres per degree
+	// at the origin (the historical behavior). Its error grows past about
+	// 50 km from the origin and toward the poles.
+	ProjectionEquirectangular Projection = "equirectangular"
 )
 
+func (p Projection) validate() error {
+	switch p {
+	case ProjectionWGS84, ProjectionEquirectangular:
+		return nil
+	default:
+		return fmt.Errorf("unknown projection %q (want %q or %q)", p, ProjectionWGS84, ProjectionEquirectangular)
+	}
+}
+
+// GeoRef converts between latitude/longitude/altitude and the local frame:
+// metres east (X) and north (Y) of the origin, and the altitude (Z)
+// unchanged. The zero Projection is ProjectionWGS84.
 type GeoRef struct {
-	OriginLat float64
-	OriginLon float64
+	OriginLat  float64
+	OriginLon  float64
+	Projection Projection
 }
 
 const metersPerDegLat = 111_320.0
 
+// DefaultMaxOriginLatDeg is the highest origin latitude New accepts when
+// Config.MaxOriginLatDeg is zero. Past it the local frame's east and north
+// turn too quickly over a flight's distances.
+const DefaultMaxOriginLatDeg = 85.0
+
+// wrapLon brings a longitude, or longitude difference, into [-180, 180).
+func wrapLon(lon float64) float64 {
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
+
+// AltRef is the datum an altitude is given in.
+type AltRef string
+
+const (
+	// AltRefMSL is height above mean sea level, the engine's own datum.
+	AltRefMSL AltRef = "msl"
+	// AltRefWGS84 is height above the WGS84 ellipsoid: MSL plus the geoid
+	// height (Config.GeoidOffsetM).
+	AltRefWGS84 AltRef = "wgs84"
+	// AltRefAGL is height above the terrain under the position.
+	AltRefAGL AltRef = "agl"
+)
+
+// ParseAltRef checks s names a datum; empty means AltRefMSL.
+func ParseAltRef(s string) (AltRef, error) {
+	switch r := AltRef(s); r {
+	case "":
+		return AltRefMSL, nil
+	case AltRefMSL, AltRefWGS84, AltRefAGL:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown altRef %q (want %q, %q or %q)", s, AltRefMSL, AltRefWGS84, AltRefAGL)
+	}
+}
+
+// AltToMSL converts alt given in ref to MSL, with geoidM the geoid's height
+// above the ellipsoid and groundM the terrain elevation (MSL) below.
+func AltToMSL(alt float64, ref AltRef, geoidM, groundM float64) float64 {
+	switch ref {
+	case AltRefWGS84:
+		return alt - geoidM
+	case AltRefAGL:
+		return alt + groundM
+	}
+	return alt
+}
+
+// AltFromMSL is the inverse of AltToMSL.
+func AltFromMSL(msl float64, ref AltRef, geoidM, groundM float64) float64 {
+	switch ref {
+	case AltRefWGS84:
+		return msl + geoidM
+	case AltRefAGL:
+		return msl - groundM
+	}
+	return msl
+}
+
+// WGS84 ellipsoid.
+const (
+	wgs84A  = 6_378_137.0
+	wgs84F  = 1 / 298.257223563
+	wgs84B  = wgs84A * (1 - wgs84F)
+	wgs84E2 = wgs84F * (2 - wgs84F)
+)
+
 func (g GeoRef) metersPerDegLon() float64 {
 	return metersPerDegLat * math.Cos(g.OriginLat*math.Pi/180.0)
 }
 
+// GeoToLocal converts a position to the local frame, the short way round
+// across the antimeridian. With ProjectionWGS84, X and Y are the east and
+// north components of the point on the ellipsoid below it, so that a
+// vertical line stays vertical whatever its altitude.
 func (g GeoRef) GeoToLocal(lat, lon, alt float64) vector.Vec3 {
-	dLat := lat - g.OriginLat
-	dLon := lon - g.OriginLon
-	return vector.Vec3{
-		X: dLon * g.metersPerDegLon(), // east
-		Y: dLat * metersPerDegLat,     // north
-		Z: alt,
+	if g.Projection == ProjectionEquirectangular {
+		dLat := lat - g.OriginLat
+		dLon := wrapLon(lon - g.OriginLon)
+		return vector.Vec3{
+			X: dLon * g.metersPerDegLon(), // east
+			Y: dLat * metersPerDegLat,     // north
+			Z: alt,
+		}
 	}
+	o := ecef(g.OriginLat, g.OriginLon)
+	d := ecef(lat, lon).Sub(o)
+	east, north, _ := enuAxes(g.OriginLat, g.OriginLon)
+	return vector.Vec3{X: d.Dot(east), Y: d.Dot(north), Z: alt}
 }
 
+// LocalToGeo is the inverse of GeoToLocal, with the longitude in
+// [-180, 180). With ProjectionWGS84 the point X east and Y north on the
+// tangent plane is followed along the origin's vertical to the ellipsoid.
 func (g GeoRef) LocalToGeo(p vector.Vec3) (lat, lon, alt float64) {
-	lat = g.OriginLat + p.Y/metersPerDegLat
-	lon = g.OriginLon + p.X/g.metersPerDegLon()
-	alt = p.Z
-	return
+	if g.Projection == ProjectionEquirectangular {
+		lat = g.OriginLat + p.Y/metersPerDegLat
+		lon = wrapLon(g.OriginLon + p.X/g.metersPerDegLon())
+		return lat, lon, p.Z
+	}
+	east, north, up := enuAxes(g.OriginLat, g.OriginLon)
+	q := ecef(g.OriginLat, g.OriginLon).Add(east.Mul(p.X)).Add(north.Mul(p.Y))
+
+	// solve |q + u·up| on the ellipsoid for the root u nearest zero
+	a2, b2 := wgs84A*wgs84A, wgs84B*wgs84B
+	qa := (up.X*up.X+up.Y*up.Y)/a2 + up.Z*up.Z/b2
+	qb := 2 * ((q.X*up.X+q.Y*up.Y)/a2 + q.Z*up.Z/b2)
+	qc := (q.X*q.X+q.Y*q.Y)/a2 + q.Z*q.Z/b2 - 1
+	disc := math.Sqrt(math.Max(qb*qb-4*qa*qc, 0))
+	u := 0.0
+	if k := -(qb + math.Copysign(disc, qb)) / 2; k != 0 {
+		u = qc / k
+	}
+	lat, lon = geodetic(q.Add(up.Mul(u)))
+	return lat, wrapLon(lon), p.Z
+}
+
+// ecef returns the earth-centred, earth-fixed position of the point on the
+// ellipsoid at lat, lon.
+func ecef(lat, lon float64) vector.Vec3 {
+	phi, lambda := lat*math.Pi/180, lon*math.Pi/180
+	sinPhi := math.Sin(phi)
+	n := wgs84A / math.Sqrt(1-wgs84E2*sinPhi*sinPhi)
+	return vector.Vec3{
+		X: n * math.Cos(phi) * math.Cos(lambda),
+		Y: n * math.Cos(phi) * math.Sin(lambda),
+		Z: n * (1 - wgs84E2) * sinPhi,
+	}
+}
+
+// enuAxes returns the east, north and up unit vectors at lat, lon in ECEF.
+func enuAxes(lat, lon float64) (east, north, up vector.Vec3) {
+	phi, lambda := lat*math.Pi/180, lon*math.Pi/180
+	sp, cp := math.Sin(phi), math.Cos(phi)
+	sl, cl := math.Sin(lambda), math.Cos(lambda)
+	east = vector.Vec3{X: -sl, Y: cl}
+	north = vector.Vec3{X: -sp * cl, Y: -sp * sl, Z: cp}
+	up = vector.Vec3{X: cp * cl, Y: cp * sl, Z: sp}
+	return east, north, up
+}
+
+// geodetic returns the latitude and longitude of an ECEF position near the
+// ellipsoid (Bowring's formula).
+func geodetic(p vector.Vec3) (lat, lon float64) {
+	ep2 := (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	r := math.Hypot(p.X, p.Y)
+	theta := math.Atan2(p.Z*wgs84A, r*wgs84B)
+	st, ct := math.Sin(theta), math.Cos(theta)
+	phi := math.Atan2(p.Z+ep2*wgs84B*st*st*st, r-wgs84E2*wgs84A*ct*ct*ct)
+	return phi * 180 / math.Pi, math.Atan2(p.Y, p.X) * 180 / math.Pi
 }
 
 func HeadingDegFromVec(v vector.Vec3) float64 {
@@ -0,0 +1,204 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// dms converts degrees, minutes and seconds to decimal degrees.
+func dms(d, m, s float64) float64 {
+	return math.Copysign(math.Abs(d)+m/60+s/3600, d)
+}
+
+func TestGeoToLocalWGS84KnownPoints(t *testing.T) {
+	// On the equator the tangent plane's east axis is the chord direction,
+	// so 1° of longitude lies a·sin 1° east and 1° of latitude lies
+	// N(1°)·(1-e²)·sin 1° north (the ECEF z of the point).
+	sin1 := math.Sin(math.Pi / 180)
+	n1 := wgs84A / math.Sqrt(1-wgs84E2*sin1*sin1)
+	for _, c := range []struct {
+		name          string
+		ref           GeoRef
+		lat, lon, alt float64
+		want          vector.Vec3
+		tolM          float64
+	}{
+		{"origin", GeoRef{OriginLat: 47, OriginLon: 8}, 47, 8, 500, vector.Vec3{Z: 500}, 1e-9},
+		{"1° east on the equator", GeoRef{}, 0, 1, 0, vector.Vec3{X: wgs84A * sin1}, 1e-6},
+		{"1° north of the equator", GeoRef{}, 1, 0, 0, vector.Vec3{Y: n1 * (1 - wgs84E2) * sin1}, 1e-6},
+		// 111 313.84 m and 110 568.77 m, against 111 319.49 m and
+		// 110 574.39 m of arc along the surface
+		{"1° east, published arc", GeoRef{}, 0, 1, 0, vector.Vec3{X: 111_319.49}, 6},
+		{"1° north, published arc", GeoRef{}, 1, 0, 0, vector.Vec3{Y: 110_574.39}, 6},
+		// 1 km at 47°N: one minute of latitude is 1852.2 m there
+		{"one minute north at 47°N", GeoRef{OriginLat: 47, OriginLon: 8}, 47 + 1.0/60, 8, 0, vector.Vec3{Y: 1852.2}, 1},
+		{"altitude passes through", GeoRef{OriginLat: -33, OriginLon: 151}, -33, 151, 1234.5, vector.Vec3{Z: 1234.5}, 1e-9},
+	} {
+		got := c.ref.GeoToLocal(c.lat, c.lon, c.alt)
+		if got.Sub(c.want).Length() > c.tolM {
+			t.Errorf("%s: GeoToLocal = %v, want %v ± %g m", c.name, got, c.want, c.tolM)
+		}
+	}
+}
+
+func TestWrapLon(t *testing.T) {
+	for _, c := range []struct{ in, want float64 }{
+		{0, 0},
+		{179.5, 179.5},
+		{180, -180},
+		{-180, -180},
+		{181, -179},
+		{-181, 179},
+		{540, -180},
+		{-359, 1},
+		{725, 5},
+	} {
+		if got := wrapLon(c.in); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("wrapLon(%g) = %g, want %g", c.in, got, c.want)
+		}
+	}
+}
+
+func TestGeoRefAcrossTheAntimeridian(t *testing.T) {
+	for _, proj := range []Projection{ProjectionWGS84, ProjectionEquirectangular} {
+		g := GeoRef{OriginLat: -17, OriginLon: 179.9, Projection: proj}
+		// 0.2° east of the origin is across the antimeridian; the tangent
+		// plane falls away from the parallel by some metres north
+		p := g.GeoToLocal(-17, -179.9, 0)
+		want := 0.2 * metersPerDegLat * math.Cos(17*math.Pi/180)
+		if math.Abs(p.X-want) > 50 || math.Abs(p.Y) > 15 {
+			t.Errorf("%s: -179.9 maps to %v, want about %.0f m east", proj, p, want)
+		}
+		lat, lon, _ := g.LocalToGeo(p)
+		if math.Abs(lat+17) > 1e-7 || math.Abs(lon+179.9) > 1e-7 {
+			t.Errorf("%s: back to %g, %g", proj, lat, lon)
+		}
+		// and westward from the other side
+		g.OriginLon = -179.95
+		if p := g.GeoToLocal(-17, 179.95, 0); !(p.X < 0 && p.X > -11_000) {
+			t.Errorf("%s: 179.95 from -179.95 maps to %v, want about 10.6 km west", proj, p)
+		}
+	}
+}
+
+func TestHaversineKnownDistance(t *testing.T) {
+	// Land's End to John o' Groats, 968.9 km on the 6371 km sphere
+	// (movable-type.co.uk/scripts/latlong.html).
+	lat1, lon1 := dms(50, 3, 59), dms(-5, 42, 53)
+	lat2, lon2 := dms(58, 38, 38), dms(-3, 4, 12)
+	if d := HaversineM(lat1, lon1, lat2, lon2); math.Abs(d-968_900) > 100 {
+		t.Errorf("HaversineM = %.0f m, want 968 900 ± 100", d)
+	}
+	for _, c := range []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 47, 8, 47, 8, 0},
+		{"quarter meridian", 0, 0, 90, 0, math.Pi / 2 * EarthRadiusM},
+		{"antipodes", 0, 0, 0, 180, math.Pi * EarthRadiusM},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, math.Pi / 180 * EarthRadiusM},
+	} {
+		if d := HaversineM(c.lat1, c.lon1, c.lat2, c.lon2); math.Abs(d-c.want) > 1e-6 {
+			t.Errorf("%s: HaversineM = %.6f, want %.6f", c.name, d, c.want)
+		}
+	}
+}
+
+func TestUTMKnownPoints(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		lat, lon float64
+		want     UTM
+		tolM     float64
+	}{
+		// the CN Tower, Toronto: 17T 630084 4833438
+		{"CN Tower", dms(43, 38, 33.24), dms(-79, 23, 13.7), UTM{Zone: 17, Easting: 630_084, Northing: 4_833_438}, 1},
+		// on a central meridian the easting is the false easting and the
+		// northing k0 times the meridian arc (4 429 529.03 m to 40°N,
+		// 1 105 854.83 m to 10°S)
+		{"central meridian", 40, -75, UTM{Zone: 18, Easting: 500_000, Northing: 4_427_757.22}, 0.01},
+		{"equator", 0, 3, UTM{Zone: 31, Easting: 500_000}, 1e-6},
+		{"southern hemisphere", -10, 3, UTM{Zone: 31, Easting: 500_000, Northing: 10_000_000 - 0.9996*1_105_854.83, South: true}, 0.01},
+	} {
+		u, err := LatLonToUTM(c.lat, c.lon)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if u.Zone != c.want.Zone || u.South != c.want.South ||
+			math.Abs(u.Easting-c.want.Easting) > c.tolM || math.Abs(u.Northing-c.want.Northing) > c.tolM {
+			t.Errorf("%s: %+v, want %+v ± %g m", c.name, u, c.want, c.tolM)
+		}
+		lat, lon, err := u.LatLon()
+		// the series are good to well under a centimetre (1e-7°)
+		if err != nil || math.Abs(lat-c.lat) > 1e-7 || math.Abs(lon-c.lon) > 1e-7 {
+			t.Errorf("%s: back to %g, %g (%v)", c.name, lat, lon, err)
+		}
+	}
+}
+
+func TestUTMZones(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		lat, lon float64
+		zone     int
+	}{
+		{"west edge of zone 1", 0, -180, 1},
+		{"antimeridian as +180", 0, 180, 1},
+		{"east edge of zone 60", 0, 179.999, 60},
+		{"just west of 6°E", 45, 5.999, 31},
+		{"on 6°E", 45, 6, 32},
+		{"Greenwich", 51.48, 0, 31},
+		{"Norway widened 32V", 60, 4, 32},
+		{"south of the Norway exception", 55.9, 4, 31},
+		{"Svalbard 31X", 78, 8.9, 31},
+		{"Svalbard 33X", 78, 9, 33},
+		{"Svalbard 35X", 78, 25, 35},
+		{"Svalbard 37X", 78, 40, 37},
+	} {
+		if z := UTMZone(c.lat, c.lon); z != c.zone {
+			t.Errorf("%s: UTMZone(%g, %g) = %d, want %d", c.name, c.lat, c.lon, z, c.zone)
+		}
+	}
+
+	// a point just across a boundary, in the neighbouring zone, lies past
+	// that zone's 3° edge but converts back exactly
+	u, err := LatLonToUTMZone(45, 6.01, 31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Easting < 700_000 {
+		t.Errorf("6.01°E in zone 31 at easting %.0f, want beyond the zone edge", u.Easting)
+	}
+	if lat, lon, _ := u.LatLon(); math.Abs(lat-45) > 1e-7 || math.Abs(lon-6.01) > 1e-7 {
+		t.Errorf("back to %g, %g", lat, lon)
+	}
+
+	for _, c := range []struct {
+		lat, lon float64
+		zone     int
+	}{{85, 0, 31}, {-80.5, 0, 31}, {0, 0, 0}, {0, 0, 61}} {
+		if _, err := LatLonToUTMZone(c.lat, c.lon, c.zone); err == nil {
+			t.Errorf("LatLonToUTMZone(%g, %g, %d) accepted", c.lat, c.lon, c.zone)
+		}
+	}
+}
+
+func TestNewRejectsPolarOrigins(t *testing.T) {
+	for _, cfg := range []Config{
+		{OriginLat: 86},
+		{OriginLat: -89.9},
+		{OriginLat: 71, MaxOriginLatDeg: 70},
+		{OriginLat: 10, MaxOriginLatDeg: 91},
+		{OriginLon: 180.5},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) accepted", cfg)
+		}
+	}
+	if _, err := New(Config{OriginLat: 85, OriginLon: -180}); err != nil {
+		t.Errorf("85°N, 180°W: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package sim
+
+import "math"
+
+// EarthRadiusM is the mean Earth radius the great-circle helpers use.
+const EarthRadiusM = 6_371_008.8
+
+// HaversineM is the great-circle distance in metres between two lat/lon
+// points, on a sphere of EarthRadiusM (within about 0.5% of the
+// ellipsoid).
+func HaversineM(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi := phi2 - phi1
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadiusM * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+// InitialBearingDeg is the true bearing (0 = north, 90 = east) to set off
+// on from the first point along the great circle to the second. It is 0
+// between identical points.
+func InitialBearingDeg(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	if math.Abs(x) < 1e-15 && math.Abs(y) < 1e-15 {
+		return 0
+	}
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// DestinationPoint is the point distM metres from lat, lon along the great
+// circle setting off on bearingDeg, with the longitude in [-180, 180).
+func DestinationPoint(lat, lon, bearingDeg, distM float64) (lat2, lon2 float64) {
+	phi1, lambda1 := lat*math.Pi/180, lon*math.Pi/180
+	theta := bearingDeg * math.Pi / 180
+	delta := distM / EarthRadiusM
+	sinPhi2 := math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta)
+	phi2 := math.Asin(math.Max(-1, math.Min(1, sinPhi2)))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*sinPhi2)
+	return phi2 * 180 / math.Pi, wrapLon(lambda2 * 180 / math.Pi)
+}
+
+// RouteLengthM is the great-circle length of the legs between consecutive
+// waypoints.
+func RouteLengthM(wps []Waypoint) float64 {
+	total := 0.0
+	for i := 1; i < len(wps); i++ {
+		total += HaversineM(wps[i-1].Lat, wps[i-1].Lon, wps[i].Lat, wps[i].Lon)
+	}
+	return total
+}
@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInitialBearingKnownPoints(t *testing.T) {
+	for _, c := range []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+		tolDeg                 float64
+	}{
+		// Land's End to John o' Groats sets off on 009°07′11″
+		// (movable-type.co.uk/scripts/latlong.html)
+		{"Land's End to John o' Groats", dms(50, 3, 59), dms(-5, 42, 53), dms(58, 38, 38), dms(-3, 4, 12), dms(9, 7, 11), 1.0 / 3600},
+		{"due north", 10, 20, 11, 20, 0, 1e-9},
+		{"due east on the equator", 0, 20, 0, 21, 90, 1e-9},
+		{"due south", 10, 20, 9, 20, 180, 1e-9},
+		{"west across the antimeridian", 0, -179.5, 0, 179.5, 270, 1e-9},
+		{"east across the antimeridian", 0, 179.5, 0, -179.5, 90, 1e-9},
+		{"great circle leaves north of east", 40, 0, 40, 10, 86.79, 0.01},
+		{"identical points", 47, 8, 47, 8, 0, 0},
+	} {
+		got := InitialBearingDeg(c.lat1, c.lon1, c.lat2, c.lon2)
+		if math.Abs(math.Remainder(got-c.want, 360)) > c.tolDeg {
+			t.Errorf("%s: InitialBearingDeg = %.6f, want %.6f", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDestinationPointKnownPoints(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		lat, lon         float64
+		bearing, dist    float64
+		wantLat, wantLon float64
+		tolDeg           float64
+	}{
+		// 124.8 km on 096°01′18″ from 53°19′14″N 001°43′47″W reaches
+		// 53°11′18″N 000°08′00″E (movable-type.co.uk)
+		{"published example", dms(53, 19, 14), dms(-1, 43, 47), dms(96, 1, 18), 124_800, dms(53, 11, 18), dms(0, 8, 0), 1.0 / 3600},
+		{"a degree north", 0, 0, 0, math.Pi / 180 * EarthRadiusM, 1, 0, 1e-9},
+		{"over the antimeridian", 0, 179.5, 90, math.Pi / 180 * EarthRadiusM, 0, -179.5, 1e-9},
+		{"zero distance", 47, 8, 123, 0, 47, 8, 1e-12},
+	} {
+		lat, lon := DestinationPoint(c.lat, c.lon, c.bearing, c.dist)
+		if math.Abs(lat-c.wantLat) > c.tolDeg || math.Abs(math.Remainder(lon-c.wantLon, 360)) > c.tolDeg {
+			t.Errorf("%s: DestinationPoint = %.6f, %.6f, want %.6f, %.6f", c.name, lat, lon, c.wantLat, c.wantLon)
+		}
+		if lon < -180 || lon >= 180 {
+			t.Errorf("%s: longitude %g outside [-180, 180)", c.name, lon)
+		}
+		// and the distance back is the one flown
+		if d := HaversineM(c.lat, c.lon, lat, lon); math.Abs(d-c.dist) > 1e-6 {
+			t.Errorf("%s: %.6f m back, flew %.6f", c.name, d, c.dist)
+		}
+	}
+}
+
+func TestRouteLength(t *testing.T) {
+	deg := math.Pi / 180 * EarthRadiusM
+	for _, c := range []struct {
+		name string
+		wps  []Waypoint
+		want float64
+	}{
+		{"none", nil, 0},
+		{"one point", []Waypoint{{Lat: 1, Lon: 2}}, 0},
+		{"two legs on the equator", []Waypoint{{Lon: 0}, {Lon: 1}, {Lon: 3}}, 3 * deg},
+		{"out and back", []Waypoint{{Lat: 0}, {Lat: 2}, {Lat: 0}}, 4 * deg},
+		{"over the antimeridian", []Waypoint{{Lon: 179}, {Lon: -179}}, 2 * deg},
+	} {
+		if got := RouteLengthM(c.wps); math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("%s: RouteLengthM = %.3f, want %.3f", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// FenceAction is what the engine does when the aircraft leaves the geofence.
+type FenceAction string
+
+const (
+	FenceWarn   FenceAction = "warn"   // only raise WarnGeofenceBreach
+	FenceHold   FenceAction = "hold"   // switch to a hold at the breach point
+	FenceReturn FenceAction = "return" // fly back to the fence center at the current altitude
+)
+
+// Geofence warning codes.
+const (
+	WarnGeofenceNear   = "geofence-near"
+	WarnGeofenceBreach = "geofence-breach"
+)
+
+// DefaultFenceMarginM is the warning distance used when Geofence.MarginM is zero.
+const DefaultFenceMarginM = 200.0
+
+// FencePoint is a polygon vertex.
+type FencePoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Geofence is a horizontal boundary the aircraft must stay inside: either a
+// polygon or a circle around (CenterLat, CenterLon). It is a constraint, not
+// a command, so it stays in force across command changes.
+type Geofence struct {
+	Polygon []FencePoint `json:"polygon,omitempty"`
+
+	CenterLat float64 `json:"centerLat,omitempty"`
+	CenterLon float64 `json:"centerLon,omitempty"`
+	RadiusM   float64 `json:"radiusM,omitempty"`
+
+	// MarginM is the distance from the boundary inside which WarnGeofenceNear
+	// is raised and AircraftState.FenceDistanceM is reported.
+	MarginM float64 `json:"marginM,omitempty"`
+	// Action runs once each time the aircraft crosses the boundary outward
+	// (default FenceWarn).
+	Action FenceAction `json:"action,omitempty"`
+}
+
+// Validate checks that exactly one shape is given and all values are sane.
+func (g Geofence) Validate() error {
+	circle := g.RadiusM != 0
+	switch {
+	case circle && len(g.Polygon) > 0:
+		return fmt.Errorf("geofence must be either a polygon or a circle, not both")
+	case !circle && len(g.Polygon) < 3:
+		return fmt.Errorf("geofence needs a polygon with at least 3 points or a radiusM")
+	}
+	if circle {
+		if math.IsNaN(g.RadiusM) || math.IsInf(g.RadiusM, 0) || g.RadiusM < 0 {
+			return fmt.Errorf("radiusM must be a positive finite number")
+		}
+		if err := validateFencePoint(FencePoint{Lat: g.CenterLat, Lon: g.CenterLon}); err != nil {
+			return fmt.Errorf("center: %w", err)
+		}
+	}
+	for i, p := range g.Polygon {
+		if err := validateFencePoint(p); err != nil {
+			return fmt.Errorf("polygon[%d]: %w", i, err)
+		}
+	}
+	if math.IsNaN(g.MarginM) || math.IsInf(g.MarginM, 0) || g.MarginM < 0 {
+		return fmt.Errorf("marginM must be >= 0")
+	}
+	switch g.Action {
+	case "", FenceWarn, FenceHold, FenceReturn:
+	default:
+		return fmt.Errorf("unknown geofence action %q (want %q, %q or %q)", g.Action, FenceWarn, FenceHold, FenceReturn)
+	}
+	return nil
+}
+
+func validateFencePoint(p FencePoint) error {
+	if math.IsNaN(p.Lat) || math.IsNaN(p.Lon) || p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("lat/lon out of range")
+	}
+	return nil
+}
+
+// fence is a Geofence compiled into the local ENU frame.
+type fence struct {
+	cfg     Geofence
+	poly    []vector.Vec3 // Z unused
+	center  vector.Vec3
+	radius  float64
+	margin  float64
+	outside bool // breached on the previous tick
+}
+
+func newFence(g Geofence, geo GeoRef) *fence {
+	f := &fence{cfg: g, radius: g.RadiusM, margin: g.MarginM}
+	if f.margin == 0 {
+		f.margin = DefaultFenceMarginM
+	}
+	if f.cfg.Action == "" {
+		f.cfg.Action = FenceWarn
+	}
+	if g.RadiusM > 0 {
+		f.center = geo.GeoToLocal(g.CenterLat, g.CenterLon, 0)
+		return f
+	}
+	for _, p := range g.Polygon {
+		v := geo.GeoToLocal(p.Lat, p.Lon, 0)
+		f.poly = append(f.poly, v)
+		f.center = f.center.Add(v)
+	}
+	f.center = f.center.Mul(1 / float64(len(f.poly)))
+	return f
+}
+
+// distance returns the horizontal distance from pos to the boundary,
+// positive inside the fence and negative outside.
+func (f *fence) distance(pos vector.Vec3) float64 {
+	p := vector.Vec3{X: pos.X, Y: pos.Y}
+	if f.poly == nil {
+		return f.radius - p.Sub(f.center).Horizontal().Length()
+	}
+
+	d := math.Inf(1)
+	inside := false
+	for i, j := 0, len(f.poly)-1; i < len(f.poly); j, i = i, i+1 {
+		a, b := f.poly[j], f.poly[i]
+		d = math.Min(d, segmentDist(p, a, b))
+		// ray casting toward +X
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	if !inside {
+		return -d
+	}
+	return d
+}
+
+// segmentDist is the horizontal distance from p to the segment ab.
+func segmentDist(p, a, b vector.Vec3) float64 {
+	ab := b.Sub(a)
+	l2 := ab.Dot(ab)
+	t := 0.0
+	if l2 > 0 {
+		t = math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	}
+	return p.Sub(a.Add(ab.Mul(t))).Horizontal().Length()
+}
+
+// checkFence evaluates the geofence after a tick, runs the breach action
+// on an outward crossing and returns the warnings to publish.
+func (e *Engine) checkFence() []Warning {
+	if e.fence == nil {
+		return nil
+	}
+	d := e.fence.distance(e.pos)
+	wasOutside := e.fence.outside
+	e.fence.outside = d < 0
+
+	switch {
+	case d < 0:
+		if !wasOutside {
+			e.fenceBreach()
+		}
+		return []Warning{{
+			Code:     WarnGeofenceBreach,
+			Severity: env.SeverityWarning,
+			Message:  fmt.Sprintf("%.0f m outside, action %s", -d, e.fence.cfg.Action),
+		}}
+	case d < e.fence.margin:
+		return []Warning{{
+			Code:     WarnGeofenceNear,
+			Severity: env.SeverityCaution,
+			Message:  fmt.Sprintf("%.0f m to boundary", d),
+		}}
+	}
+	return nil
+}
+
+func (e *Engine) fenceBreach() {
+	switch e.fence.cfg.Action {
+	case FenceHold:
+		e.handleCommand(submission{cmd: HoldCommand{At: e.now}, source: sourceGeofence})
+	case FenceReturn:
+		lat, lon, _ := e.geo.LocalToGeo(e.fence.center)
+		e.handleCommand(submission{cmd: GoToCommand{At: e.now, Lat: lat, Lon: lon, Alt: e.pos.Z}, source: sourceGeofence})
+	}
+}
+
+// fenceDistance is the value for AircraftState.FenceDistanceM: set only
+// within the warning margin or outside the fence.
+func (e *Engine) fenceDistance() *float64 {
+	if e.fence == nil {
+		return nil
+	}
+	d := e.fence.distance(e.pos)
+	if d >= e.fence.margin {
+		return nil
+	}
+	return &d
+}
+
+// Geofence returns the active geofence, or nil when none is set.
+func (e *Engine) Geofence(ctx context.Context) (*Geofence, error) {
+	var g *Geofence
+	err := e.call(ctx, func() {
+		if e.fence != nil {
+			cfg := e.fence.cfg
+			g = &cfg
+		}
+	})
+	return g, err
+}
+
+// SetGeofence replaces the geofence; nil removes it.
+func (e *Engine) SetGeofence(ctx context.Context, g *Geofence) error {
+	if g != nil {
+		if err := g.Validate(); err != nil {
+			return err
+		}
+	}
+	return e.call(ctx, func() {
+		e.fence = nil
+		if g != nil {
+			e.fence = newFence(*g, e.geo)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package sim
+
+import (
+	"math"
+	"time"
+
+	"flight-simulator2/internal/env"
+)
+
+// DefaultStaleTicks is how many tick intervals may pass without a tick
+// before Health reports the engine loop as stalled. The heartbeat is never
+// considered stale sooner than minStale.
+const DefaultStaleTicks = 10
+
+const minStale = time.Second
+
+// Health describes whether the engine loop is alive. It is read without
+// going through the actor, so it answers even when Run has returned or is
+// stuck.
+type Health struct {
+	// Running is set while Run is executing.
+	Running bool `json:"running"`
+	// Ready is set once Run has processed a tick and the last one is no
+	// older than StaleAfterS.
+	Ready bool `json:"ready"`
+	// Reason says why Ready is false.
+	Reason string `json:"reason,omitempty"`
+
+	Ticks       uint64     `json:"ticks"`
+	LastTick    *time.Time `json:"lastTick,omitempty"`
+	HeartbeatS  float64    `json:"heartbeatAgeS"` // time since LastTick
+	StaleAfterS float64    `json:"staleAfterS"`
+
+	TickHz         float64 `json:"tickHz"`         // configured
+	MeasuredTickHz float64 `json:"measuredTickHz"` // smoothed over recent ticks
+
+	Subscribers      int `json:"subscribers"`
+	EventSubscribers int `json:"eventSubscribers"`
+
+	StartedAt   *time.Time `json:"startedAt,omitempty"` // when Run started
+	UptimeS     float64    `json:"uptimeS"`
+	LastWarning string     `json:"lastWarning,omitempty"`
+}
+
+// heartbeat is what the actor publishes for Health after each tick.
+type heartbeat struct {
+	started          time.Time
+	last             time.Time
+	ticks            uint64
+	rateHz           float64
+	subscribers      int
+	eventSubscribers int
+	lastWarning      string
+}
+
+// startHeartbeat clears the heartbeat as Run starts at t.
+func (e *Engine) startHeartbeat(t time.Time) {
+	e.heart.Store(&heartbeat{started: t})
+}
+
+// beat publishes a heartbeat for the tick at t. It runs inside the actor.
+func (e *Engine) beat(t time.Time) {
+	hb := *e.heart.Load()
+	if hb.ticks > 0 {
+		if d := t.Sub(hb.last).Seconds(); d > 0 {
+			if hb.rateHz == 0 {
+				hb.rateHz = 1 / d
+			} else {
+				hb.rateHz += 0.1 * (1/d - hb.rateHz)
+			}
+		}
+	}
+	hb.last = t
+	hb.ticks++
+	hb.subscribers = len(e.subs)
+	hb.eventSubscribers = len(e.eventSubs)
+	hb.lastWarning = env.Summary(e.lastWarnings)
+	e.heart.Store(&hb)
+}
+
+// Health reports on the engine loop. It may be called from any goroutine
+// and never blocks on the actor.
+func (e *Engine) Health() Health {
+	stale := time.Duration(DefaultStaleTicks * float64(time.Second) / e.tickHz)
+	if stale < minStale {
+		stale = minStale
+	}
+	h := Health{
+		Running:     e.loopRunning.Load(),
+		TickHz:      e.tickHz,
+		StaleAfterS: stale.Seconds(),
+	}
+	now := e.clock.Now()
+	hb := e.heart.Load()
+	if hb != nil {
+		h.StartedAt = &hb.started
+		h.UptimeS = math.Max(now.Sub(hb.started).Seconds(), 0)
+	}
+	if hb != nil && hb.ticks > 0 {
+		h.Ticks = hb.ticks
+		h.LastTick = &hb.last
+		h.HeartbeatS = math.Max(now.Sub(hb.last).Seconds(), 0)
+		h.MeasuredTickHz = hb.rateHz
+		h.Subscribers = hb.subscribers
+		h.EventSubscribers = hb.eventSubscribers
+		h.LastWarning = hb.lastWarning
+	}
+	switch {
+	case !h.Running:
+		h.Reason = "engine loop is not running"
+	case h.Ticks == 0:
+		h.Reason = "no tick processed yet"
+	case now.Sub(hb.last) > stale:
+		h.Reason = "tick heartbeat is stale"
+	default:
+		h.Ready = true
+	}
+	return h
+}
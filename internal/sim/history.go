@@ -0,0 +1,92 @@
+package sim
+
+import (
+	"context"
+	"time"
+)
+
+// stateRing is a fixed-capacity ring buffer of published states, oldest first.
+// It is owned by the actor and never locked.
+type stateRing struct {
+	buf   []AircraftState
+	start int
+	n     int
+}
+
+func newStateRing(capacity int) *stateRing {
+	if capacity <= 0 {
+		return nil
+	}
+	return &stateRing{buf: make([]AircraftState, capacity)}
+}
+
+func (r *stateRing) push(st AircraftState) {
+	if r == nil {
+		return
+	}
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = st
+		r.n++
+		return
+	}
+	r.buf[r.start] = st
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+func (r *stateRing) at(i int) AircraftState {
+	return r.buf[(r.start+i)%len(r.buf)]
+}
+
+// query returns up to limit states newer than since. With a zero since it
+// returns the most recent states; otherwise the oldest ones after since, so a
+// client can page forward by passing the last TS it received.
+func (r *stateRing) query(since time.Time, limit int) []AircraftState {
+	if r == nil || r.n == 0 || limit <= 0 {
+		return []AircraftState{}
+	}
+
+	first := 0
+	if !since.IsZero() {
+		for first < r.n && !r.at(first).TS.After(since) {
+			first++
+		}
+	} else if r.n > limit {
+		first = r.n - limit
+	}
+
+	last := r.n
+	if last-first > limit {
+		last = first + limit
+	}
+	out := make([]AircraftState, 0, last-first)
+	for i := first; i < last; i++ {
+		out = append(out, r.at(i))
+	}
+	return out
+}
+
+// after returns the states published after seq, oldest first, or false
+// when some of them are no longer held or seq was never published. States
+// are pushed with consecutive Seq, so the ring is indexed by it directly.
+func (r *stateRing) after(seq uint64) ([]AircraftState, bool) {
+	if r == nil || r.n == 0 {
+		return nil, false
+	}
+	first, last := r.at(0).Seq, r.at(r.n-1).Seq
+	if seq+1 < first || seq > last {
+		return nil, false
+	}
+	out := make([]AircraftState, 0, last-seq)
+	for i := int(seq + 1 - first); i < r.n; i++ {
+		out = append(out, r.at(i))
+	}
+	return out, true
+}
+
+// History returns recently published states, oldest first. See
+// Config.HistorySize for how far back the buffer reaches.
+func (e *Engine) History(ctx context.Context, since time.Time, limit int) ([]AircraftState, error) {
+	var out []AircraftState
+	err := e.call(ctx, func() { out = e.history.query(since, limit) })
+	return out, err
+}
@@ -0,0 +1,22 @@
+package sim
+
+import "testing"
+
+func TestHistorySizeDefaultsToTenMinutesOfPublishes(t *testing.T) {
+	for _, c := range []struct {
+		tickHz, publishHz float64
+		want              int
+	}{
+		{0, 0, 12000},   // 20 Hz ticks, published every tick
+		{50, 5, 3000},   // decimated publishes
+		{10, 100, 6000}, // publish rate clamped at the tick rate
+	} {
+		e, err := New(Config{TickHz: c.tickHz, PublishHz: c.publishHz})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(e.history.buf); got != c.want {
+			t.Errorf("tick %g Hz, publish %g Hz: history holds %d states, want %d", c.tickHz, c.publishHz, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestIcingSlowsTheClimb(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		degradation float64
+	}{
+		{"clean", 0},
+		{"light", 0.25},
+		{"heavy", 0.75},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ice := &env.Icing{BaseAltM: 0, TopAltM: 5000, AccretionRate: 1, MaxDegradation: c.degradation}
+			h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8, Environment: ice})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Submit(sim.GoToCommand{Lat: 47, Lon: 8, Alt: 3000}); err != nil {
+				t.Fatal(err)
+			}
+			st := h.Steps(20 * 20)
+			want := sim.DefaultLimits().MaxClimbRate * (1 - c.degradation)
+			if math.Abs(st.VerticalSpeedMps-want) > 0.05 {
+				t.Errorf("climbing at %.2f m/s with a full ice load, want %.2f", st.VerticalSpeedMps, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package sim
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestInitialState(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		cfg           Config
+		lat, lon, alt float64
+		vx, vy, vz    float64
+		err           string
+	}{
+		{name: "defaults to the origin", cfg: Config{OriginLat: 47, OriginLon: 8}, lat: 47, lon: 8, alt: 1000},
+		{
+			name: "position",
+			cfg:  Config{OriginLat: 47, OriginLon: 8, InitialLat: 47.01, InitialLon: 8.02, InitialAlt: 450},
+			lat:  47.01, lon: 8.02, alt: 450,
+		},
+		{
+			name: "velocity",
+			cfg:  Config{OriginLat: 47, OriginLon: 8, InitialVx: 30, InitialVy: -40, InitialVz: 2},
+			lat:  47, lon: 8, alt: 1000, vx: 30, vy: -40, vz: 2,
+		},
+		{name: "latitude out of range", cfg: Config{InitialLat: 91, InitialLon: 1}, err: "initial state"},
+		{name: "non-finite velocity", cfg: Config{InitialVx: math.Inf(1)}, err: "non-finite"},
+		{name: "above the ceiling", cfg: Config{InitialAlt: 5000, CeilingM: 3000}, err: "above the ceiling"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := New(c.cfg)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("New = %v, want an error containing %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			check := func(when string) {
+				t.Helper()
+				st := e.current()
+				if math.Abs(st.Lat-c.lat) > 1e-9 || math.Abs(st.Lon-c.lon) > 1e-9 || math.Abs(st.Alt-c.alt) > 1e-6 {
+					t.Errorf("%s: at %g,%g,%g, want %g,%g,%g", when, st.Lat, st.Lon, st.Alt, c.lat, c.lon, c.alt)
+				}
+				if st.Vx != c.vx || st.Vy != c.vy || st.Vz != c.vz {
+					t.Errorf("%s: velocity %g,%g,%g, want %g,%g,%g", when, st.Vx, st.Vy, st.Vz, c.vx, c.vy, c.vz)
+				}
+			}
+			check("start")
+
+			// Reset comes back to the configured state
+			e.pos.X += 500
+			e.vel.Z = -5
+			e.reset(nil)
+			check("reset")
+		})
+	}
+}
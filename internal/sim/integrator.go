@@ -0,0 +1,23 @@
+package sim
+
+import "fmt"
+
+// Integrator selects how position is integrated from velocity each step.
+type Integrator string
+
+const (
+	// IntegratorEuler moves by the end-of-step velocity (the historical behavior).
+	IntegratorEuler Integrator = "euler"
+	// IntegratorMidpoint moves by the mean of the start and end velocities,
+	// which is exact while the acceleration is constant over the step.
+	IntegratorMidpoint Integrator = "midpoint"
+)
+
+func (i Integrator) validate() error {
+	switch i {
+	case IntegratorEuler, IntegratorMidpoint:
+		return nil
+	default:
+		return fmt.Errorf("unknown integrator %q (want %q or %q)", i, IntegratorEuler, IntegratorMidpoint)
+	}
+}
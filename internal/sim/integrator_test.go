@@ -0,0 +1,88 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+// flyTrack flies a goto with the given tick rate and sub-steps and samples
+// the state every half second for a minute.
+func flyTrack(t *testing.T, tickHz float64, subSteps int, integ sim.Integrator) []sim.AircraftState {
+	t.Helper()
+	h, err := simtest.New(sim.Config{
+		OriginLat: 47, OriginLon: 8,
+		TickHz: tickHz, SubSteps: subSteps, Integrator: integ,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Dt = 1 / tickHz
+	// off-axis and climbing, so both horizontal axes and the climb rate
+	// saturate and then settle
+	if err := h.Submit(sim.GoToCommand{Lat: 47.02, Lon: 8.01, Alt: 1150, Speed: 60}); err != nil {
+		t.Fatal(err)
+	}
+	per := int(math.Round(tickHz / 2))
+	var track []sim.AircraftState
+	for i := 0; i < 120; i++ {
+		track = append(track, h.Steps(per))
+	}
+	return track
+}
+
+// separationM is the distance between two states, in metres.
+func separationM(a, b sim.AircraftState) float64 {
+	const mPerDeg = 111_200.0
+	dN := (a.Lat - b.Lat) * mPerDeg
+	dE := (a.Lon - b.Lon) * mPerDeg * math.Cos(a.Lat*math.Pi/180)
+	return math.Sqrt(dE*dE + dN*dN + (a.Alt-b.Alt)*(a.Alt-b.Alt))
+}
+
+func TestSubSteppingMatchesHigherTickRate(t *testing.T) {
+	ref := flyTrack(t, 20, 1, sim.IntegratorEuler)
+	for _, c := range []struct {
+		name     string
+		subSteps int
+		integ    sim.Integrator
+		tolM     float64
+	}{
+		// 2 Hz with 10 sub-steps integrates with the same 50 ms step
+		{"euler x10", 10, sim.IntegratorEuler, 0.01},
+		{"midpoint x10", 10, sim.IntegratorMidpoint, 5},
+		{"midpoint x4", 4, sim.IntegratorMidpoint, 5},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			track := flyTrack(t, 2, c.subSteps, c.integ)
+			worst := 0.0
+			for i := range ref {
+				if !track[i].TS.Equal(ref[i].TS) {
+					t.Fatalf("sample %d at %v, reference at %v", i, track[i].TS, ref[i].TS)
+				}
+				worst = math.Max(worst, separationM(track[i], ref[i]))
+			}
+			if worst > c.tolM {
+				t.Errorf("2 Hz track is up to %.2f m from the 20 Hz one, want <= %g m", worst, c.tolM)
+			}
+			if ref[len(ref)-1].ActiveCommand != "" || track[len(track)-1].ActiveCommand != "" {
+				t.Errorf("goto not completed within a minute")
+			}
+		})
+	}
+}
+
+func TestSingleStepAtLowTickRateDiverges(t *testing.T) {
+	// without sub-steps the 2 Hz track visibly departs from the 20 Hz one,
+	// which is what SubSteps is for
+	ref := flyTrack(t, 20, 1, sim.IntegratorEuler)
+	track := flyTrack(t, 2, 1, sim.IntegratorEuler)
+	worst := 0.0
+	for i := range ref {
+		worst = math.Max(worst, separationM(track[i], ref[i]))
+	}
+	if worst < 1 {
+		t.Errorf("2 Hz without sub-steps stays within %.2f m of 20 Hz", worst)
+	}
+}
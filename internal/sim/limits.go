@@ -0,0 +1,154 @@
+package sim
+
+import (
+	"context"
+	"math"
+)
+
+// Limits are the guidance tolerances and dynamics limits of the simulated
+// aircraft. Zero fields take the value from DefaultLimits.
+type Limits struct {
+	PosTolM       float64 `json:"posTolM"`       // horizontal arrival tolerance (m)
+	AltTolM       float64 `json:"altTolM"`       // vertical arrival tolerance (m)
+	DefaultSpeed  float64 `json:"defaultSpeed"`  // m/s when a command gives none
+	MaxClimbRate  float64 `json:"maxClimbRate"`  // m/s, climb and descent
+	MaxHorizAccel float64 `json:"maxHorizAccel"` // m/s², per horizontal axis
+	MaxVertAccel  float64 `json:"maxVertAccel"`  // m/s²
+	// MaxBankDeg caps the bank of a turn, i.e. the acceleration across
+	// the track at g·tan(MaxBankDeg), so the turn radius at speed v is at
+	// least v²/(g·tan(MaxBankDeg)). It applies to PhysicsKinematic.
+	MaxBankDeg float64 `json:"maxBankDeg"`
+}
+
+// DefaultLimits returns the limits used when a Config leaves them unset.
+func DefaultLimits() Limits {
+	return Limits{
+		PosTolM:       25.0,
+		AltTolM:       10.0,
+		DefaultSpeed:  80.0,
+		MaxClimbRate:  8.0,
+		MaxHorizAccel: 12.0,
+		MaxVertAccel:  5.0,
+		MaxBankDeg:    60.0,
+	}
+}
+
+// ParamError is a limit that fails Validate. Param is its JSON name.
+type ParamError struct {
+	Param string
+	Msg   string
+}
+
+func (e *ParamError) Error() string { return e.Param + " " + e.Msg }
+
+// Validate rejects negative and non-finite limits with a *ParamError.
+func (l Limits) Validate() error {
+	for _, f := range []struct {
+		name string
+		v    float64
+	}{
+		{"posTolM", l.PosTolM},
+		{"altTolM", l.AltTolM},
+		{"defaultSpeed", l.DefaultSpeed},
+		{"maxClimbRate", l.MaxClimbRate},
+		{"maxHorizAccel", l.MaxHorizAccel},
+		{"maxVertAccel", l.MaxVertAccel},
+		{"maxBankDeg", l.MaxBankDeg},
+	} {
+		if math.IsNaN(f.v) || math.IsInf(f.v, 0) {
+			return &ParamError{f.name, "must be finite"}
+		}
+		if f.v < 0 {
+			return &ParamError{f.name, "must be >= 0"}
+		}
+	}
+	if l.MaxBankDeg >= 90 {
+		return &ParamError{"maxBankDeg", "must be below 90"}
+	}
+	return nil
+}
+
+// withDefaults fills zero fields from DefaultLimits.
+func (l Limits) withDefaults() Limits {
+	d := DefaultLimits()
+	if l.PosTolM == 0 {
+		l.PosTolM = d.PosTolM
+	}
+	if l.AltTolM == 0 {
+		l.AltTolM = d.AltTolM
+	}
+	if l.DefaultSpeed == 0 {
+		l.DefaultSpeed = d.DefaultSpeed
+	}
+	if l.MaxClimbRate == 0 {
+		l.MaxClimbRate = d.MaxClimbRate
+	}
+	if l.MaxHorizAccel == 0 {
+		l.MaxHorizAccel = d.MaxHorizAccel
+	}
+	if l.MaxVertAccel == 0 {
+		l.MaxVertAccel = d.MaxVertAccel
+	}
+	if l.MaxBankDeg == 0 {
+		l.MaxBankDeg = d.MaxBankDeg
+	}
+	return l
+}
+
+// LimitsPatch changes a subset of Limits; nil fields are left as they are.
+type LimitsPatch struct {
+	PosTolM       *float64 `json:"posTolM,omitempty"`
+	AltTolM       *float64 `json:"altTolM,omitempty"`
+	DefaultSpeed  *float64 `json:"defaultSpeed,omitempty"`
+	MaxClimbRate  *float64 `json:"maxClimbRate,omitempty"`
+	MaxHorizAccel *float64 `json:"maxHorizAccel,omitempty"`
+	MaxVertAccel  *float64 `json:"maxVertAccel,omitempty"`
+	MaxBankDeg    *float64 `json:"maxBankDeg,omitempty"`
+}
+
+// Apply returns l with the patch applied.
+func (p LimitsPatch) Apply(l Limits) Limits {
+	set := func(dst *float64, v *float64) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	set(&l.PosTolM, p.PosTolM)
+	set(&l.AltTolM, p.AltTolM)
+	set(&l.DefaultSpeed, p.DefaultSpeed)
+	set(&l.MaxClimbRate, p.MaxClimbRate)
+	set(&l.MaxHorizAccel, p.MaxHorizAccel)
+	set(&l.MaxVertAccel, p.MaxVertAccel)
+	set(&l.MaxBankDeg, p.MaxBankDeg)
+	return l
+}
+
+// Params returns the limits currently used by the tick loop.
+func (e *Engine) Params(ctx context.Context) (Limits, error) {
+	var l Limits
+	err := e.call(ctx, func() { l = e.limits })
+	return l, err
+}
+
+// SetParams applies p inside the actor, so the tick loop never sees a
+// partially updated set, and returns the effective limits afterwards.
+// Invalid results are rejected and leave the limits unchanged.
+func (e *Engine) SetParams(ctx context.Context, p LimitsPatch) (Limits, error) {
+	var (
+		l    Limits
+		verr error
+	)
+	err := e.call(ctx, func() {
+		next := p.Apply(e.limits)
+		if verr = next.Validate(); verr != nil {
+			l = e.limits
+			return
+		}
+		e.limits = next
+		l = next
+	})
+	if err != nil {
+		return Limits{}, err
+	}
+	return l, verr
+}
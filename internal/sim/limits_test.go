@@ -0,0 +1,53 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestClimbRateNeverExceedsLimit(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		limits sim.Limits
+		alt    float64
+	}{
+		{"default climb", sim.Limits{}, 3000},
+		{"default descent", sim.Limits{}, 200},
+		{"slow climb", sim.Limits{MaxClimbRate: 2.5, MaxVertAccel: 20}, 1500},
+		{"fast descent", sim.Limits{MaxClimbRate: 15}, 100},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8, Limits: c.limits})
+			if err != nil {
+				t.Fatal(err)
+			}
+			max := c.limits.MaxClimbRate
+			if max == 0 {
+				max = sim.DefaultLimits().MaxClimbRate
+			}
+			if err := h.Submit(sim.GoToCommand{Lat: 47.01, Lon: 8, Alt: c.alt}); err != nil {
+				t.Fatal(err)
+			}
+			peak := 0.0
+			for i := 0; i < 20*600; i++ {
+				st := h.Steps(1)
+				if math.Abs(st.Vz) > max+1e-9 || math.Abs(st.VerticalSpeedMps) > max+1e-9 {
+					t.Fatalf("step %d: vz %.3f, vertical speed %.3f beyond the %g m/s limit", i, st.Vz, st.VerticalSpeedMps, max)
+				}
+				peak = math.Max(peak, math.Abs(st.Vz))
+				if st.ActiveCommand == "" {
+					break
+				}
+			}
+			if h.Last.ActiveCommand != "" {
+				t.Fatalf("goto still active at alt %.0f", h.Last.Alt)
+			}
+			if peak < max-1e-9 {
+				t.Errorf("peak vertical speed %.3f never reached the %g m/s limit", peak, max)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package sim
+
+import (
+	"context"
+
+	"flight-simulator2/internal/env"
+)
+
+// TriggerMicroburst starts m centered at m.Lat/m.Lon on the next tick and
+// returns its ID. It ends by itself after m.DurationS.
+func (e *Engine) TriggerMicroburst(ctx context.Context, m env.Microburst) (string, error) {
+	if e.replay != nil {
+		return "", ErrReplay
+	}
+	if err := m.Validate(); err != nil {
+		return "", err
+	}
+	var (
+		id   string
+		aerr error
+	)
+	err := e.call(ctx, func() {
+		id, aerr = e.microbursts.Add(m, e.geo.GeoToLocal(m.Lat, m.Lon, 0))
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, aerr
+}
+
+// Microbursts lists the active microbursts and the time each has left.
+func (e *Engine) Microbursts(ctx context.Context) ([]env.Microburst, error) {
+	out := []env.Microburst{}
+	err := e.call(ctx, func() {
+		bursts, _ := e.microbursts.Active()
+		out = append(out, bursts...)
+	})
+	return out, err
+}
@@ -0,0 +1,56 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// SensorNoise perturbs the published position the way a GPS receiver
+// wanders; the internal state is unaffected. Offsets are Gaussian, drawn
+// independently every tick from the engine's seeded generator
+// (Config.Seed), so runs with the same seed publish identical states.
+type SensorNoise struct {
+	PositionNoiseSigmaM float64 // horizontal, per axis
+	AltNoiseSigmaM      float64
+}
+
+func (n SensorNoise) enabled() bool { return n.PositionNoiseSigmaM > 0 || n.AltNoiseSigmaM > 0 }
+
+// Validate rejects negative or non-finite sigmas.
+func (n SensorNoise) Validate() error {
+	for _, f := range []struct {
+		name string
+		v    float64
+	}{
+		{"positionNoiseSigmaM", n.PositionNoiseSigmaM},
+		{"altNoiseSigmaM", n.AltNoiseSigmaM},
+	} {
+		if math.IsNaN(f.v) || math.IsInf(f.v, 0) || f.v < 0 {
+			return fmt.Errorf("%s must be a finite number >= 0", f.name)
+		}
+	}
+	return nil
+}
+
+// drawNoise picks this tick's sensor noise offsets.
+func (e *Engine) drawNoise() {
+	if !e.noise.enabled() {
+		return
+	}
+	e.posNoise = vector.Vec3{
+		X: e.rng.NormFloat64() * e.noise.PositionNoiseSigmaM,
+		Y: e.rng.NormFloat64() * e.noise.PositionNoiseSigmaM,
+		Z: e.rng.NormFloat64() * e.noise.AltNoiseSigmaM,
+	}
+}
+
+// degrade turns the true state into the published one: sensor noise
+// first, then any injected faults.
+func (e *Engine) degrade(st *AircraftState) {
+	st.Lat += e.posNoise.Y / metersPerDegLat
+	st.Lon = wrapLon(st.Lon + e.posNoise.X/e.geo.metersPerDegLon())
+	st.Alt += e.posNoise.Z
+	e.applyFaults(st)
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// noiseOffsets steps an idle engine n times and returns the published
+// minus true east, north and up offsets in metres.
+func noiseOffsets(t *testing.T, cfg Config, n int) [][3]float64 {
+	t.Helper()
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([][3]float64, n)
+	for i := range out {
+		pub := e.Step(0.05)
+		truth := e.buildSnapshot(e.now, nil)
+		out[i] = [3]float64{
+			(pub.Lon - truth.Lon) * e.geo.metersPerDegLon(),
+			(pub.Lat - truth.Lat) * metersPerDegLat,
+			pub.Alt - truth.Alt,
+		}
+	}
+	return out
+}
+
+func TestSensorNoise(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		noise      SensorNoise
+		horiz, alt float64 // expected sigmas
+	}{
+		{"off", SensorNoise{}, 0, 0},
+		{"horizontal", SensorNoise{PositionNoiseSigmaM: 3}, 3, 0},
+		{"vertical", SensorNoise{AltNoiseSigmaM: 5}, 0, 5},
+		{"both", SensorNoise{PositionNoiseSigmaM: 2, AltNoiseSigmaM: 4}, 2, 4},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			const n = 4000
+			offs := noiseOffsets(t, Config{OriginLat: 47, OriginLon: 8, SensorNoise: c.noise, Seed: 7}, n)
+			var sum, sq [3]float64
+			for _, o := range offs {
+				for k := range o {
+					sum[k] += o[k]
+					sq[k] += o[k] * o[k]
+				}
+			}
+			for k, want := range []float64{c.horiz, c.horiz, c.alt} {
+				mean := sum[k] / n
+				sigma := math.Sqrt(sq[k]/n - mean*mean)
+				// a 4000-sample estimate is within 5% of sigma with room to spare
+				if math.Abs(sigma-want) > 0.05*want+1e-6 || math.Abs(mean) > 0.1*want+1e-6 {
+					t.Errorf("axis %d: mean %.3f, sigma %.3f; want 0 and %g", k, mean, sigma, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSensorNoiseSeeded(t *testing.T) {
+	cfg := Config{OriginLat: 47, OriginLon: 8, SensorNoise: SensorNoise{PositionNoiseSigmaM: 3, AltNoiseSigmaM: 3}, Seed: 42}
+	a, b := noiseOffsets(t, cfg, 50), noiseOffsets(t, cfg, 50)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("step %d: %v and %v with the same seed", i, a[i], b[i])
+		}
+	}
+	cfg.Seed = 43
+	if c := noiseOffsets(t, cfg, 1); c[0] == a[0] {
+		t.Errorf("seeds 42 and 43 drew the same offset %v", c[0])
+	}
+}
+
+func TestSensorNoiseValidation(t *testing.T) {
+	for _, n := range []SensorNoise{
+		{PositionNoiseSigmaM: -1},
+		{AltNoiseSigmaM: math.NaN()},
+		{PositionNoiseSigmaM: math.Inf(1)},
+	} {
+		if _, err := New(Config{SensorNoise: n}); err == nil || !strings.Contains(err.Error(), "sensor noise") {
+			t.Errorf("New with %+v = %v, want a sensor noise error", n, err)
+		}
+	}
+}
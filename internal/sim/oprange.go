@@ -0,0 +1,48 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxRangeM is the maximum operating radius used when
+// Config.MaxRangeM is zero.
+const DefaultMaxRangeM = 200_000.0
+
+// ErrOutOfRange is returned for a goto or trajectory target farther from
+// the origin than the maximum operating radius.
+var ErrOutOfRange = errors.New("target is beyond the maximum operating radius")
+
+// MaxRangeM returns the maximum operating radius in metres. It is fixed
+// when the engine is created.
+func (e *Engine) MaxRangeM() float64 { return e.maxRange }
+
+// checkRange rejects a goto or trajectory with a target farther than the
+// maximum operating radius from the origin, measured along the great
+// circle. While an automatic origin waits to snap to the first target, it
+// measures from that target instead. It runs inside the actor.
+func (e *Engine) checkRange(cmd Command) error {
+	var pts [][2]float64
+	switch c := cmd.(type) {
+	case GoToCommand:
+		pts = append(pts, [2]float64{c.Lat, c.Lon})
+	case TrajectoryCommand:
+		for _, wp := range c.Waypoints {
+			pts = append(pts, [2]float64{wp.Lat, wp.Lon})
+		}
+	}
+	if len(pts) == 0 {
+		return nil
+	}
+	lat0, lon0 := e.geo.OriginLat, e.geo.OriginLon
+	if e.autoOrigin {
+		lat0, lon0 = pts[0][0], pts[0][1]
+	}
+	for _, p := range pts {
+		if d := HaversineM(lat0, lon0, p[0], p[1]); d > e.maxRange {
+			return fmt.Errorf("%w: %.6f, %.6f is %.1f km from the origin (limit %.1f km)",
+				ErrOutOfRange, p[0], p[1], d/1000, e.maxRange/1000)
+		}
+	}
+	return nil
+}
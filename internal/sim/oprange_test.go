@@ -0,0 +1,73 @@
+package sim_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/fakeclock"
+)
+
+func TestMaxRange(t *testing.T) {
+	clk := fakeclock.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, MaxRangeM: 5000, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eng.MaxRangeM(); got != 5000 {
+		t.Errorf("MaxRangeM %v", got)
+	}
+	ctx := runEngine(t, eng, clk)
+
+	// 4.4 km north is in range, 5.6 km is not, whether as a goto target or
+	// as any waypoint of a trajectory
+	near := sim.Waypoint{Lat: 47.04, Lon: 8, Alt: 1000}
+	far := sim.Waypoint{Lat: 47.05, Lon: 8, Alt: 1000}
+	for _, cmd := range []sim.Command{
+		sim.GoToCommand{At: clk.Now(), Lat: near.Lat, Lon: near.Lon, Alt: near.Alt},
+		sim.GoToCommand{At: clk.Now(), Lat: far.Lat, Lon: far.Lon, Alt: far.Alt},
+		sim.TrajectoryCommand{At: clk.Now(), Waypoints: []sim.Waypoint{near, far}},
+	} {
+		if err := eng.Submit(ctx, cmd); err != nil {
+			t.Fatal(err)
+		}
+		clk.Advance(50 * time.Millisecond)
+	}
+	recs, err := eng.CommandLog(ctx, sim.CommandLogQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sim.CommandOutcome{sim.OutcomeActive, sim.OutcomeRejected, sim.OutcomeRejected}
+	if len(recs) != len(want) {
+		t.Fatalf("%d records, want %d", len(recs), len(want))
+	}
+	for i, r := range recs {
+		if r.Outcome != want[i] {
+			t.Errorf("%s %d: %s (%s), want %s", r.Type, r.Seq, r.Outcome, r.Detail, want[i])
+		}
+		if r.Outcome == sim.OutcomeRejected && !strings.HasPrefix(r.Detail, sim.ErrOutOfRange.Error()) {
+			t.Errorf("%s %d rejected for %q", r.Type, r.Seq, r.Detail)
+		}
+	}
+	st, err := eng.GetState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ActiveCommand != string(sim.CmdGoTo) {
+		t.Errorf("flying %q after the rejected commands", st.ActiveCommand)
+	}
+}
+
+func TestMaxRangeConfig(t *testing.T) {
+	eng, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eng.MaxRangeM(); got != sim.DefaultMaxRangeM {
+		t.Errorf("default MaxRangeM %v", got)
+	}
+	if _, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, MaxRangeM: -1}); err == nil {
+		t.Error("negative MaxRangeM accepted")
+	}
+}
@@ -0,0 +1,140 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// ErrCommandActive is returned by SetOrigin while a command is being flown.
+var ErrCommandActive = errors.New("a command is active")
+
+// Origin is the geographic origin of the local frame.
+type Origin struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	// Auto is set while the origin waits to snap to the first goto or
+	// trajectory target (Config.AutoOrigin).
+	Auto bool `json:"auto,omitempty"`
+}
+
+// Origin returns the current origin of the local frame.
+func (e *Engine) Origin(ctx context.Context) (Origin, error) {
+	var o Origin
+	err := e.call(ctx, func() {
+		o = Origin{Lat: e.geo.OriginLat, Lon: e.geo.OriginLon, Auto: e.autoOrigin}
+	})
+	return o, err
+}
+
+// GeoRef returns the conversion between lat/lon and the local frame the
+// engine currently uses.
+func (e *Engine) GeoRef(ctx context.Context) (GeoRef, error) {
+	var g GeoRef
+	err := e.call(ctx, func() { g = e.geo })
+	return g, err
+}
+
+// SetOrigin moves the origin of the local frame to lat, lon. Everything the
+// engine holds in local coordinates is converted inside the actor, so the
+// aircraft's lat/lon does not jump. Effects placed in lat/lon are bound
+// again; those configured in local metres (a wind field grid, the synthetic
+// terrain) stay where they are in the new frame. It fails with
+// ErrCommandActive while a command is active, and ErrReplay during a replay.
+func (e *Engine) SetOrigin(ctx context.Context, lat, lon float64) error {
+	if err := e.checkOrigin(lat, lon); err != nil {
+		return err
+	}
+	var err error
+	callErr := e.call(ctx, func() {
+		switch {
+		case e.replay != nil:
+			err = ErrReplay
+			return
+		case e.active != nil:
+			err = ErrCommandActive
+			return
+		}
+		e.rebase(lat, lon)
+	})
+	if callErr != nil {
+		return callErr
+	}
+	return err
+}
+
+// checkOrigin validates a new origin as New does.
+func (e *Engine) checkOrigin(lat, lon float64) error {
+	if !(math.Abs(lat) <= e.maxOriginLat) {
+		return fmt.Errorf("origin latitude %g is beyond ±%g degrees", lat, e.maxOriginLat)
+	}
+	if !(math.Abs(lon) <= 180) {
+		return fmt.Errorf("origin longitude %g must be between -180 and 180", lon)
+	}
+	return nil
+}
+
+// rebase moves the origin to lat, lon and converts the local positions and
+// velocities to the new frame.
+func (e *Engine) rebase(lat, lon float64) {
+	old := e.geo
+	e.geo = GeoRef{OriginLat: lat, OriginLon: lon, Projection: old.Projection}
+	e.autoOrigin = false
+	move := func(p vector.Vec3) vector.Vec3 {
+		la, lo, alt := old.LocalToGeo(p)
+		return e.geo.GeoToLocal(la, lo, alt)
+	}
+	// a velocity at p, turned with the frame's east and north
+	turn := func(p, v vector.Vec3) vector.Vec3 {
+		return move(p.Add(v)).Sub(move(p))
+	}
+
+	e.vel, e.gvel = turn(e.pos, e.vel), turn(e.pos, e.gvel)
+	e.pos = move(e.pos)
+	e.homeVel = turn(e.home, e.homeVel)
+	e.home = move(e.home)
+	for _, in := range e.traffic {
+		for i, p := range in.route {
+			in.route[i] = move(p)
+		}
+		in.vel = turn(in.pos, in.vel)
+		in.pos = move(in.pos)
+	}
+	if e.fence != nil {
+		outside := e.fence.outside
+		e.fence = newFence(e.fence.cfg, e.geo)
+		e.fence.outside = outside
+	}
+
+	env.BindGeo(e.environment, e.geo)
+	env.Rebase(e.environment, move)
+	e.weather.Rebase(move)
+	e.microbursts.Rebase(move)
+}
+
+// autoRebase snaps the origin to the first target of cmd when the engine
+// was started with Config.AutoOrigin and it has not moved yet.
+func (e *Engine) autoRebase(cmd Command) {
+	if !e.autoOrigin {
+		return
+	}
+	var lat, lon float64
+	switch c := cmd.(type) {
+	case GoToCommand:
+		lat, lon = c.Lat, c.Lon
+	case TrajectoryCommand:
+		if len(c.Waypoints) == 0 {
+			return
+		}
+		lat, lon = c.Waypoints[0].Lat, c.Waypoints[0].Lon
+	default:
+		return
+	}
+	if e.checkOrigin(lat, lon) == nil {
+		e.rebase(lat, lon)
+	}
+}
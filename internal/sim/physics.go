@@ -0,0 +1,140 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+const gravity = 9.80665 // m/s²
+
+// Physics selects how the commanded velocity turns into motion.
+type Physics string
+
+const (
+	// PhysicsKinematic approaches the desired velocity under acceleration
+	// limits (the default).
+	PhysicsKinematic Physics = "kinematic"
+	// PhysicsPointMass integrates thrust, drag, lift and gravity acting on a
+	// point mass. Without an active command thrust is cut and the aircraft glides.
+	PhysicsPointMass Physics = "pointmass"
+)
+
+// Airframe holds the point-mass parameters used by PhysicsPointMass.
+// Zero fields take the value from DefaultAirframe.
+type Airframe struct {
+	MassKg     float64 `json:"massKg"`
+	MaxThrustN float64 `json:"maxThrustN"` // magnitude bound of the (vectored) thrust
+	// DragCoeff is the lumped parasitic drag: D = DragCoeff·|v|², opposing v.
+	DragCoeff float64 `json:"dragCoeff"`
+	// LiftCoeff is the lumped lift from horizontal airspeed: L = LiftCoeff·|vₕ|²,
+	// capped at the aircraft's weight and acting perpendicular to the flight
+	// path, so an unpowered aircraft trades height for speed.
+	LiftCoeff float64 `json:"liftCoeff"`
+	// ResponseTimeS is the time constant of the velocity-tracking inner loop.
+	ResponseTimeS float64 `json:"responseTimeS"`
+}
+
+// DefaultAirframe describes a light aircraft that cruises at 80 m/s with a
+// thrust-to-weight ratio just above one and stalls around 35 m/s.
+func DefaultAirframe() Airframe {
+	return Airframe{
+		MassKg:        1200,
+		MaxThrustN:    15000,
+		DragCoeff:     0.5,
+		LiftCoeff:     9.6,
+		ResponseTimeS: 1,
+	}
+}
+
+func (a Airframe) withDefaults() Airframe {
+	d := DefaultAirframe()
+	if a.MassKg == 0 {
+		a.MassKg = d.MassKg
+	}
+	if a.MaxThrustN == 0 {
+		a.MaxThrustN = d.MaxThrustN
+	}
+	if a.DragCoeff == 0 {
+		a.DragCoeff = d.DragCoeff
+	}
+	if a.LiftCoeff == 0 {
+		a.LiftCoeff = d.LiftCoeff
+	}
+	if a.ResponseTimeS == 0 {
+		a.ResponseTimeS = d.ResponseTimeS
+	}
+	return a
+}
+
+// Validate rejects negative and non-finite airframe parameters.
+func (a Airframe) Validate() error {
+	for _, f := range []struct {
+		name string
+		v    float64
+	}{
+		{"massKg", a.MassKg},
+		{"maxThrustN", a.MaxThrustN},
+		{"dragCoeff", a.DragCoeff},
+		{"liftCoeff", a.LiftCoeff},
+		{"responseTimeS", a.ResponseTimeS},
+	} {
+		if math.IsNaN(f.v) || math.IsInf(f.v, 0) {
+			return fmt.Errorf("%s must be finite", f.name)
+		}
+		if f.v < 0 {
+			return fmt.Errorf("%s must be >= 0", f.name)
+		}
+	}
+	return nil
+}
+
+func (p Physics) validate() error {
+	switch p {
+	case PhysicsKinematic, PhysicsPointMass:
+		return nil
+	default:
+		return fmt.Errorf("unknown physics mode %q (want %q or %q)", p, PhysicsKinematic, PhysicsPointMass)
+	}
+}
+
+// pointMassStep returns the velocity after dt under thrust, drag, lift and
+// gravity. When powered, thrust is chosen to track desired within the thrust
+// bound; otherwise it is zero.
+func (a Airframe) pointMassStep(vel, desired vector.Vec3, powered bool, dt float64) vector.Vec3 {
+	weight := a.MassKg * gravity
+
+	speed := vel.Length()
+	drag := vel.Mul(-a.DragCoeff * speed)
+
+	hSpeed2 := vel.X*vel.X + vel.Y*vel.Y
+	lift := liftDir(vel, speed).Mul(math.Min(a.LiftCoeff*hSpeed2, weight))
+
+	passive := drag.Add(lift).Add(vector.Vec3{Z: -weight})
+
+	thrust := vector.Vec3{}
+	if powered {
+		// force needed to close the velocity error within the response time
+		want := desired.Sub(vel).Mul(a.MassKg / a.ResponseTimeS)
+		thrust = want.Sub(passive).ClampLength(a.MaxThrustN)
+	}
+
+	accel := thrust.Add(passive).Mul(1 / a.MassKg)
+	return vel.Add(accel.Mul(dt))
+}
+
+// liftDir is the unit vector perpendicular to the flight path in its vertical
+// plane, pointing up.
+func liftDir(vel vector.Vec3, speed float64) vector.Vec3 {
+	up := vector.Vec3{Z: 1}
+	if speed < 1e-9 {
+		return up
+	}
+	along := vel.Mul(1 / speed)
+	perp := up.Sub(along.Mul(along.Z))
+	if perp.Length() < 1e-9 {
+		return vector.Vec3{}
+	}
+	return perp.Normalize()
+}
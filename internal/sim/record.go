@@ -0,0 +1,131 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrReplay is returned when something tries to command a replay engine.
+var ErrReplay = errors.New("engine is replaying a recording and does not accept commands")
+
+// Record kinds written by the flight recorder.
+const (
+	RecordState   = "state"
+	RecordCommand = "command"
+)
+
+// Record is one JSONL line of a flight recording.
+type Record struct {
+	Kind    string           `json:"kind"`
+	TS      time.Time        `json:"ts"`
+	State   *AircraftState   `json:"state,omitempty"`
+	Command *CommandEnvelope `json:"command,omitempty"`
+}
+
+// recorder appends records to a writer. The first write error disables it so
+// a full disk never stalls the actor loop.
+type recorder struct {
+	enc *json.Encoder
+	err error
+}
+
+func newRecorder(w io.Writer) *recorder {
+	if w == nil {
+		return nil
+	}
+	return &recorder{enc: json.NewEncoder(w)}
+}
+
+func (r *recorder) write(rec Record) {
+	if r == nil || r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(rec)
+}
+
+// ReplayOptions configures NewReplay.
+type ReplayOptions struct {
+	// Speed scales playback pacing; 2 plays twice as fast. Defaults to 1.
+	Speed float64
+	// Loop restarts the recording after the last state.
+	Loop bool
+
+	TickHz    float64
+	Clock     Clock
+	StartTime time.Time
+}
+
+type replayState struct {
+	states []AircraftState
+	speed  float64
+	loop   bool
+
+	idx   int
+	start time.Time // engine time at which playback (re)started
+	last  AircraftState
+}
+
+// NewReplay returns an engine that plays back the states of a recording made
+// with Config.RecordTo instead of simulating. It serves GetState and Subscribe
+// like a live engine; commands are rejected with ErrReplay.
+func NewReplay(r io.Reader, opts ReplayOptions) (*Engine, error) {
+	var states []AircraftState
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("replay record %d: %w", line, err)
+		}
+		if rec.Kind == RecordState && rec.State != nil {
+			states = append(states, *rec.State)
+		}
+	}
+	if len(states) == 0 {
+		return nil, errors.New("replay: recording contains no states")
+	}
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	e, err := New(Config{TickHz: opts.TickHz, Clock: opts.Clock, StartTime: opts.StartTime})
+	if err != nil {
+		return nil, err
+	}
+	e.replay = &replayState{
+		states: states,
+		speed:  opts.Speed,
+		loop:   opts.Loop,
+		start:  e.now,
+		last:   states[0],
+	}
+	return e, nil
+}
+
+// Replaying reports whether the engine plays back a recording.
+func (e *Engine) Replaying() bool { return e.replay != nil }
+
+// replayTo publishes every recorded state whose offset from the first one has
+// been reached at engine time now, honoring the playback speed.
+func (e *Engine) replayTo(now time.Time) AircraftState {
+	e.now = now
+	rp := e.replay
+	t0 := rp.states[0].TS
+	elapsed := time.Duration(float64(now.Sub(rp.start)) * rp.speed)
+
+	for rp.idx < len(rp.states) && rp.states[rp.idx].TS.Sub(t0) <= elapsed {
+		rp.last = rp.states[rp.idx]
+		e.publish(&rp.last)
+		rp.idx++
+	}
+	if rp.idx >= len(rp.states) && rp.loop {
+		rp.idx = 0
+		rp.start = now
+	}
+	return rp.last
+}
@@ -0,0 +1,131 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnReset is the code of the info warning on the first state published after a Reset so stream
+// consumers know to clear trails and other accumulated data.
+const WarnReset = "reset"
+
+// ErrBelowFloor is returned by Reset for a position below the terrain
+// safety floor.
+var ErrBelowFloor = errors.New("position is below the terrain safety floor")
+
+// ResetPosition overrides where Reset places the aircraft.
+type ResetPosition struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	Alt float64 `json:"alt"`
+}
+
+// Validate checks that the position is finite and in range.
+func (p ResetPosition) Validate() error {
+	for _, f := range []float64{p.Lat, p.Lon, p.Alt} {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("position contains a non-finite value")
+		}
+	}
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("position out of range")
+	}
+	return nil
+}
+
+// Reset returns the simulation to its initial state: no active command, no
+// faults, storm cells or microbursts, a full battery, a zeroed odometer and
+// a fresh wind estimate, at the configured initial position and velocity,
+// or at pos with zero velocity when given. pos must be within the maximum
+// operating radius (ErrOutOfRange) and above the terrain safety floor
+// (ErrBelowFloor). Subscriptions stay open. The reset runs inside the
+// actor, so no tick observes it half-done, and the next published state
+// carries a WarnReset warning.
+func (e *Engine) Reset(ctx context.Context, pos *ResetPosition) error {
+	if e.replay != nil {
+		return ErrReplay
+	}
+	if pos != nil {
+		if err := pos.Validate(); err != nil {
+			return err
+		}
+	}
+	var err error
+	if cerr := e.call(ctx, func() {
+		if err = e.checkResetPosition(pos); err == nil {
+			e.reset(pos)
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// checkResetPosition holds pos to the limits a goto is held to. It runs
+// inside the actor.
+func (e *Engine) checkResetPosition(pos *ResetPosition) error {
+	if pos == nil {
+		return nil
+	}
+	if !e.autoOrigin {
+		if d := HaversineM(e.geo.OriginLat, e.geo.OriginLon, pos.Lat, pos.Lon); d > e.maxRange {
+			return fmt.Errorf("%w: %.6f, %.6f is %.1f km from the origin (limit %.1f km)",
+				ErrOutOfRange, pos.Lat, pos.Lon, d/1000, e.maxRange/1000)
+		}
+	}
+	p := e.geo.GeoToLocal(pos.Lat, pos.Lon, pos.Alt)
+	if floor, ok := env.MinAltitude(e.environment, p); ok && p.Z < floor {
+		return fmt.Errorf("%w: altitude %.1f m, floor %.1f m", ErrBelowFloor, pos.Alt, floor)
+	}
+	return nil
+}
+
+func (e *Engine) reset(pos *ResetPosition) {
+	if e.active != nil {
+		e.emit(Event{Kind: EventCommandSuperseded, Command: e.active.Type(), Detail: "superseded by reset"})
+	}
+	e.active = nil
+	e.traj = nil
+	e.trajIdx = 0
+	e.trajLoop = false
+	e.trajLaps = 0
+	e.follow = terrainFollow{}
+
+	e.pos, e.vel = e.home, e.homeVel
+	if pos != nil {
+		e.pos = e.geo.GeoToLocal(pos.Lat, pos.Lon, pos.Alt)
+		e.vel = vector.Vec3{}
+	}
+	e.gvel = e.vel
+	e.att.reset(e.vel)
+	for _, in := range e.traffic {
+		in.reset()
+	}
+	e.trafficStates = nil
+	e.odoM = 0
+	e.odoTimeS = 0
+	if e.energy != nil {
+		e.energy.remainingWh = e.energy.cfg.BatteryWh
+		e.energy.powerW = 0
+	}
+
+	e.faults = map[FaultKind]*faultState{}
+	e.noiseE, e.noiseN = 0, 0
+	e.weather.Clear()
+	e.microbursts.Clear()
+	if e.windEst != nil {
+		e.windEst.Reset()
+	}
+
+	e.terrainDeb = terrainDebounce{}
+	e.ditched = false
+	e.emitWarningChange(e.lastWarnings, nil)
+	e.lastWarnings = nil
+	e.resetPending = true
+	e.emit(Event{Kind: EventReset, Detail: "simulation reset"})
+}
@@ -0,0 +1,115 @@
+package sim_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/fakeclock"
+)
+
+func TestResetClearsInjectedState(t *testing.T) {
+	clk := fakeclock.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	eng, err := sim.New(sim.Config{
+		OriginLat: 47, OriginLon: 8, InitialAlt: 500, Clock: clk,
+		Environment: &env.Chain{Effects: []env.Environment{
+			env.Wind{Wx: 5},
+			env.Terrain{SafetyMarginM: 10, Provider: env.SyntheticTerrain{AmplitudeM: 50, ScaleM: 2000}},
+		}},
+		EstimateWind: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runEngine(t, eng, clk)
+
+	if err := eng.InjectFault(ctx, sim.Fault{Kind: sim.FaultGPSNoise, SigmaM: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.AddWeatherCell(ctx, env.WeatherCell{ID: "cb", Lat: 47.01, Lon: 8, RadiusM: 500, TurbulenceMps: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eng.TriggerMicroburst(ctx, env.Microburst{Lat: 47, Lon: 8.01, RadiusM: 500, PeakDownMps: 10, DurationS: 600}); err != nil {
+		t.Fatal(err)
+	}
+	if err := eng.Submit(ctx, sim.GoToCommand{Lat: 47, Lon: 8.1, AGL: 300}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		clk.Advance(100 * time.Millisecond)
+	}
+	st, err := eng.GetState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.TargetAGLM == nil || st.EstimatedWindConfidence == nil || *st.EstimatedWindConfidence == 0 {
+		t.Fatalf("before the reset: target AGL %v, wind confidence %v", st.TargetAGLM, st.EstimatedWindConfidence)
+	}
+
+	if err := eng.Reset(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := eng.Faults(ctx); err != nil || len(f) != 0 {
+		t.Errorf("faults after the reset: %v, %v", f, err)
+	}
+	if c, err := eng.WeatherCells(ctx); err != nil || len(c) != 0 {
+		t.Errorf("weather cells after the reset: %v, %v", c, err)
+	}
+	if m, err := eng.Microbursts(ctx); err != nil || len(m) != 0 {
+		t.Errorf("microbursts after the reset: %v, %v", m, err)
+	}
+	// the published state is the true one again, with nothing left of the
+	// terrain following or the wind estimate
+	truth, err := eng.Truth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err = eng.GetState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Lat != truth.Lat || st.Lon != truth.Lon {
+		t.Errorf("published %.7f, %.7f, true %.7f, %.7f", st.Lat, st.Lon, truth.Lat, truth.Lon)
+	}
+	if st.TargetAGLM != nil {
+		t.Errorf("target AGL %v after the reset", *st.TargetAGLM)
+	}
+	if st.EstimatedWindConfidence != nil && *st.EstimatedWindConfidence != 0 {
+		t.Errorf("wind confidence %v after the reset", *st.EstimatedWindConfidence)
+	}
+}
+
+func TestResetPositionLimits(t *testing.T) {
+	clk := fakeclock.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	eng, err := sim.New(sim.Config{
+		OriginLat: 47, OriginLon: 8, InitialAlt: 500, Clock: clk, MaxRangeM: 50_000,
+		Environment: env.Terrain{SafetyMarginM: 10, Provider: env.SyntheticTerrain{BaseM: 200}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runEngine(t, eng, clk)
+
+	for _, c := range []struct {
+		pos  sim.ResetPosition
+		want error
+	}{
+		{sim.ResetPosition{Lat: 47.1, Lon: 8, Alt: 500}, nil},
+		{sim.ResetPosition{Lat: 48, Lon: 8, Alt: 500}, sim.ErrOutOfRange},
+		{sim.ResetPosition{Lat: 47.1, Lon: 8, Alt: 205}, sim.ErrBelowFloor},
+	} {
+		if err := eng.Reset(ctx, &c.pos); !errors.Is(err, c.want) || (c.want == nil) != (err == nil) {
+			t.Errorf("reset to %+v: %v, want %v", c.pos, err, c.want)
+		}
+	}
+	// a refused reset leaves the aircraft where the last one put it
+	st, err := eng.GetState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Lat < 47.0999 || st.Lat > 47.1001 || st.Alt != 500 {
+		t.Errorf("at %.5f, %.5f, %.1f m after the refused resets", st.Lat, st.Lon, st.Alt)
+	}
+}
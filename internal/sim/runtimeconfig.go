@@ -0,0 +1,174 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+)
+
+// RuntimeConfig is the effective configuration of a running engine, with
+// the defaults New filled in. The fields of RuntimeConfigPatch can be
+// changed live with SetRuntimeConfig; the rest are fixed until a restart
+// and listed in ReadOnly.
+type RuntimeConfig struct {
+	Origin       Origin     `json:"origin"`
+	Projection   Projection `json:"projection"`
+	GeoidOffsetM float64    `json:"geoidOffsetM"`
+	TickHz       float64    `json:"tickHz"`
+	PublishHz    float64    `json:"publishHz"`
+
+	Limits     Limits     `json:"limits"`
+	CeilingM   float64    `json:"ceilingM"`  // 0 = none
+	MaxRangeM  float64    `json:"maxRangeM"` // operating radius around the origin
+	Integrator Integrator `json:"integrator"`
+	SubSteps   int        `json:"subSteps"`
+	Physics    Physics    `json:"physics"`
+
+	// Wind is the horizontal wind at the aircraft, summed over the
+	// environment's effects; a patch replaces the mean wind as SetWind does.
+	Wind              env.Wind `json:"wind"`
+	MaxWindMps        float64  `json:"maxWindMps"`
+	CrosswindLimitMps float64  `json:"crosswindLimitMps"` // 0 = none
+	TailwindLimitMps  float64  `json:"tailwindLimitMps"`  // 0 = none
+	TerrainLookaheadS float64  `json:"terrainLookaheadS"` // 0 = off
+
+	// Environment lists the environment's effects in chain order.
+	Environment []string `json:"environment"`
+	// Features says which optional models are on.
+	Features map[string]bool `json:"features"`
+
+	// ReadOnly names the fields above that SetRuntimeConfig cannot change.
+	ReadOnly []string `json:"readOnly"`
+}
+
+// runtimeReadOnly lists the RuntimeConfig fields fixed at New.
+var runtimeReadOnly = []string{
+	"origin", "projection", "geoidOffsetM", "tickHz", "ceilingM", "maxRangeM", "integrator",
+	"subSteps", "physics", "maxWindMps", "terrainLookaheadS", "environment", "features",
+}
+
+// IsRuntimeReadOnly reports whether the RuntimeConfig field named by its
+// JSON key needs a restart to change.
+func IsRuntimeReadOnly(key string) bool {
+	for _, k := range runtimeReadOnly {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// RuntimeConfigPatch changes the live-settable part of RuntimeConfig; nil
+// fields are left as they are.
+type RuntimeConfigPatch struct {
+	Limits            *LimitsPatch `json:"limits,omitempty"`
+	PublishHz         *float64     `json:"publishHz,omitempty"`
+	Wind              *env.Wind    `json:"wind,omitempty"`
+	CrosswindLimitMps *float64     `json:"crosswindLimitMps,omitempty"`
+	TailwindLimitMps  *float64     `json:"tailwindLimitMps,omitempty"`
+}
+
+// RuntimeConfig returns the engine's effective configuration.
+func (e *Engine) RuntimeConfig(ctx context.Context) (RuntimeConfig, error) {
+	var c RuntimeConfig
+	err := e.call(ctx, func() { c = e.runtimeConfig() })
+	return c, err
+}
+
+func (e *Engine) runtimeConfig() RuntimeConfig {
+	w := e.windAt(e.pos)
+	return RuntimeConfig{
+		Origin:            Origin{Lat: e.geo.OriginLat, Lon: e.geo.OriginLon, Auto: e.autoOrigin},
+		Projection:        e.geo.Projection,
+		GeoidOffsetM:      e.geoidM,
+		TickHz:            e.tickHz,
+		PublishHz:         e.publishHz,
+		Limits:            e.limits,
+		CeilingM:          e.ceiling,
+		MaxRangeM:         e.maxRange,
+		Integrator:        e.integrator,
+		SubSteps:          e.subSteps,
+		Physics:           e.physics,
+		Wind:              env.Wind{Wx: w.Wx, Wy: w.Wy},
+		MaxWindMps:        e.maxWind,
+		CrosswindLimitMps: e.crosswindLimit,
+		TailwindLimitMps:  e.tailwindLimit,
+		TerrainLookaheadS: e.terrainLookahead,
+		Environment:       append([]string{}, env.Names(e.environment)...),
+		Features: map[string]bool{
+			"autoOrigin":       e.autoOrigin,
+			"battery":          e.energy != nil,
+			"estimateWind":     e.windEst != nil,
+			"extrapolateState": e.extrapolate,
+			"geofence":         e.fence != nil,
+			"recording":        e.rec != nil,
+			"replay":           e.replay != nil,
+			"sensorNoise":      e.noise.enabled(),
+			"traffic":          len(e.traffic) > 0,
+		},
+		ReadOnly: runtimeReadOnly,
+	}
+}
+
+// SetRuntimeConfig applies p inside the actor, all of it or, when any part
+// is invalid, none, and returns the effective configuration afterwards.
+// Limits go through the same checks as SetParams and the wind through those
+// of SetWind. It fails with ErrReplay during a replay.
+func (e *Engine) SetRuntimeConfig(ctx context.Context, p RuntimeConfigPatch) (RuntimeConfig, error) {
+	if e.replay != nil {
+		return RuntimeConfig{}, ErrReplay
+	}
+	if p.PublishHz != nil && !(*p.PublishHz > 0) {
+		return RuntimeConfig{}, fmt.Errorf("publishHz must be > 0")
+	}
+	for _, l := range []struct {
+		name string
+		v    *float64
+	}{{"crosswindLimitMps", p.CrosswindLimitMps}, {"tailwindLimitMps", p.TailwindLimitMps}} {
+		if l.v != nil && (!(*l.v >= 0) || math.IsInf(*l.v, 1)) {
+			return RuntimeConfig{}, fmt.Errorf("%s must be finite and >= 0", l.name)
+		}
+	}
+	if p.Wind != nil {
+		if err := p.Wind.Validate(); err != nil {
+			return RuntimeConfig{}, err
+		}
+		if speed := math.Hypot(p.Wind.Wx, p.Wind.Wy); speed > e.maxWind {
+			return RuntimeConfig{}, fmt.Errorf("%w: %.1f > %.1f m/s", ErrWindTooStrong, speed, e.maxWind)
+		}
+	}
+	var (
+		c    RuntimeConfig
+		verr error
+	)
+	err := e.call(ctx, func() {
+		limits := e.limits
+		if p.Limits != nil {
+			limits = p.Limits.Apply(limits)
+			if verr = limits.Validate(); verr != nil {
+				return
+			}
+		}
+		e.limits = limits
+		if p.PublishHz != nil {
+			// clamped at the tick rate, as in New
+			e.publishHz = math.Min(*p.PublishHz, e.tickHz)
+		}
+		if p.Wind != nil {
+			e.setEnvironment(env.ReplaceWind(e.environment, *p.Wind))
+		}
+		if p.CrosswindLimitMps != nil {
+			e.crosswindLimit = *p.CrosswindLimitMps
+		}
+		if p.TailwindLimitMps != nil {
+			e.tailwindLimit = *p.TailwindLimitMps
+		}
+		c = e.runtimeConfig()
+	})
+	if err != nil {
+		return RuntimeConfig{}, err
+	}
+	return c, verr
+}
@@ -0,0 +1,32 @@
+package sim
+
+import (
+	"context"
+
+	"flight-simulator2/internal/env"
+)
+
+// Scenario describes the effective environment as a scenario (see
+// env.LoadScenario): the configured chain, including any wind set since,
+// followed by the storm cells added at runtime. Microbursts are transient
+// and left out.
+func (e *Engine) Scenario(ctx context.Context) (env.Scenario, error) {
+	var (
+		s   env.Scenario
+		err error
+	)
+	callErr := e.call(ctx, func() {
+		c := &env.Chain{}
+		if e.environment != nil {
+			c.Effects = append(c.Effects, e.environment)
+		}
+		if e.weather.Len() > 0 {
+			c.Effects = append(c.Effects, e.weather)
+		}
+		s, err = env.DescribeScenario(c)
+	})
+	if callErr != nil {
+		return env.Scenario{}, callErr
+	}
+	return s, err
+}
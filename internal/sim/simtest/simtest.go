@@ -0,0 +1,107 @@
+// Package simtest drives a sim.Engine in step mode so guidance behavior can be
+// asserted deterministically, without real tickers or goroutines.
+package simtest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"flight-simulator2/internal/sim"
+)
+
+// DefaultDt is the step size used when a Harness or Case leaves Dt unset.
+// It matches the engine's default 20 Hz tick.
+const DefaultDt = 0.05
+
+// Epoch is the simulation start time used when a config leaves StartTime unset,
+// so timestamps in asserted states are reproducible.
+var Epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness wraps an engine that is advanced only by explicit steps.
+type Harness struct {
+	Engine *sim.Engine
+	Dt     float64
+
+	// Last is the state produced by the most recent step.
+	Last sim.AircraftState
+}
+
+// New builds a Harness around a fresh engine.
+func New(cfg sim.Config) (*Harness, error) {
+	if cfg.StartTime.IsZero() {
+		cfg.StartTime = Epoch
+	}
+	eng, err := sim.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Harness{Engine: eng, Dt: DefaultDt}, nil
+}
+
+// Submit queues a command; it is applied at the start of the next step.
+// Nothing drains the queue between steps, so a full queue fails at once
+// with sim.ErrOverloaded instead of blocking.
+func (h *Harness) Submit(cmd sim.Command) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return h.Engine.Submit(ctx, cmd)
+}
+
+// Steps advances the engine n times and returns the final state.
+func (h *Harness) Steps(n int) sim.AircraftState {
+	for i := 0; i < n; i++ {
+		h.Last = h.Engine.Step(h.Dt)
+	}
+	return h.Last
+}
+
+// StepUntil advances the engine until done reports true or max steps have
+// elapsed. It returns the last state, the number of steps taken and whether
+// the condition was met.
+func (h *Harness) StepUntil(done func(sim.AircraftState) bool, max int) (sim.AircraftState, int, bool) {
+	for i := 1; i <= max; i++ {
+		h.Last = h.Engine.Step(h.Dt)
+		if done(h.Last) {
+			return h.Last, i, true
+		}
+	}
+	return h.Last, max, false
+}
+
+// Case is one row of a table-driven guidance test.
+type Case struct {
+	Name     string
+	Config   sim.Config
+	Commands []sim.Command
+	Steps    int
+	Dt       float64
+
+	// Check inspects the final state; a non-nil error fails the case.
+	Check func(sim.AircraftState) error
+}
+
+// Run executes the case: it submits all commands, steps the engine and
+// applies Check to the final state.
+func (c Case) Run() (sim.AircraftState, error) {
+	h, err := New(c.Config)
+	if err != nil {
+		return sim.AircraftState{}, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	if c.Dt > 0 {
+		h.Dt = c.Dt
+	}
+	for _, cmd := range c.Commands {
+		if err := h.Submit(cmd); err != nil {
+			return sim.AircraftState{}, fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	st := h.Steps(c.Steps)
+	if c.Check == nil {
+		return st, nil
+	}
+	if err := c.Check(st); err != nil {
+		return st, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return st, nil
+}
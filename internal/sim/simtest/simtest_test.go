@@ -0,0 +1,110 @@
+package simtest_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestHarnessStepsFromEpoch(t *testing.T) {
+	h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := h.Steps(20)
+	if want := simtest.Epoch.Add(1e9); !st.TS.Equal(want) {
+		t.Fatalf("TS = %v after 20 steps of %gs, want %v", st.TS, h.Dt, want)
+	}
+	if math.Abs(st.Lat-47) > 1e-9 || math.Abs(st.Lon-8) > 1e-9 || math.Abs(st.Alt-1000) > 1e-6 {
+		t.Fatalf("idle aircraft moved to %g,%g,%g", st.Lat, st.Lon, st.Alt)
+	}
+}
+
+func TestHarnessStepUntil(t *testing.T) {
+	h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Submit(sim.GoToCommand{Lat: 47, Lon: 8, Alt: 1100}); err != nil {
+		t.Fatal(err)
+	}
+	st, n, ok := h.StepUntil(func(s sim.AircraftState) bool { return s.ActiveCommand == "" }, 2000)
+	if !ok {
+		t.Fatalf("goto still active after %d steps at alt %.1f", n, st.Alt)
+	}
+	if math.Abs(st.Alt-1100) > 10 {
+		t.Fatalf("arrived at alt %.1f, want 1100±10", st.Alt)
+	}
+	if _, _, ok := h.StepUntil(func(sim.AircraftState) bool { return false }, 3); ok {
+		t.Fatal("StepUntil reported a condition that never held")
+	}
+}
+
+func TestHarnessSubmitOverloaded(t *testing.T) {
+	h, err := simtest.New(sim.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last error
+	for i := 0; i < 1000 && last == nil; i++ {
+		last = h.Submit(sim.HoldCommand{})
+	}
+	if !errors.Is(last, sim.ErrOverloaded) {
+		t.Fatalf("Submit on a full queue = %v, want ErrOverloaded", last)
+	}
+}
+
+func TestCaseRun(t *testing.T) {
+	cases := []simtest.Case{
+		{
+			Name:     "climb",
+			Config:   sim.Config{OriginLat: 47, OriginLon: 8},
+			Commands: []sim.Command{sim.GoToCommand{Lat: 47, Lon: 8, Alt: 1050}},
+			Steps:    400,
+			Check: func(st sim.AircraftState) error {
+				if math.Abs(st.Alt-1050) > 10 {
+					return fmt.Errorf("alt %.1f, want 1050±10", st.Alt)
+				}
+				return nil
+			},
+		},
+		{
+			Name:     "hold stays put",
+			Config:   sim.Config{OriginLat: 47, OriginLon: 8},
+			Commands: []sim.Command{sim.HoldCommand{}},
+			Steps:    10,
+			Dt:       0.5,
+			Check: func(st sim.AircraftState) error {
+				if st.ActiveCommand != string(sim.CmdHold) {
+					return fmt.Errorf("active %q, want hold", st.ActiveCommand)
+				}
+				if want := simtest.Epoch.Add(5e9); !st.TS.Equal(want) {
+					return fmt.Errorf("TS %v, want %v", st.TS, want)
+				}
+				return nil
+			},
+		},
+	}
+	for _, c := range cases {
+		if _, err := c.Run(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	bad := simtest.Case{Name: "bad tick", Config: sim.Config{TickHz: -1}}
+	if _, err := bad.Run(); err == nil {
+		t.Error("Case.Run accepted an invalid config")
+	}
+	failing := simtest.Case{
+		Name:  "failing check",
+		Steps: 1,
+		Check: func(sim.AircraftState) error { return errors.New("boom") },
+	}
+	if _, err := failing.Run(); err == nil || err.Error() != "failing check: boom" {
+		t.Errorf("Case.Run = %v, want the check error prefixed with the case name", err)
+	}
+}
@@ -0,0 +1,133 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// Snapshot is a serializable checkpoint of the actor-owned simulation state.
+// Position is stored geographically so a snapshot can be restored into an
+// engine configured with a different origin.
+type Snapshot struct {
+	SimTime time.Time `json:"simTime"`
+
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	Alt float64 `json:"alt"`
+
+	Vx float64 `json:"vx"`
+	Vy float64 `json:"vy"`
+	Vz float64 `json:"vz"`
+
+	Active     *CommandEnvelope `json:"active,omitempty"`
+	Trajectory []Waypoint       `json:"trajectory,omitempty"`
+	TrajIdx    int              `json:"trajIdx"`
+	TrajLoop   bool             `json:"trajLoop,omitempty"`
+
+	LastWarnings []Warning `json:"lastWarnings,omitempty"`
+	// LastWarning is the joined summary; it is informational and ignored
+	// by Restore.
+	LastWarning string `json:"lastWarning,omitempty"`
+
+	DistanceFlownM float64 `json:"distanceFlownM,omitempty"`
+	FlightTimeS    float64 `json:"flightTimeS,omitempty"`
+}
+
+// Validate checks that the snapshot describes a state the engine can resume.
+func (s Snapshot) Validate() error {
+	for _, f := range []float64{s.Lat, s.Lon, s.Alt, s.Vx, s.Vy, s.Vz, s.DistanceFlownM, s.FlightTimeS} {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("snapshot contains a non-finite value")
+		}
+	}
+	if s.Lat < -90 || s.Lat > 90 || s.Lon < -180 || s.Lon > 180 {
+		return fmt.Errorf("snapshot position out of range")
+	}
+	if s.TrajIdx < 0 {
+		return fmt.Errorf("snapshot trajIdx must be >= 0")
+	}
+	if s.Active == nil || s.Active.Command == nil {
+		return nil
+	}
+	if s.Active.Command.Type() == CmdTrajectory {
+		if len(s.Trajectory) == 0 {
+			return fmt.Errorf("snapshot has an active trajectory but no waypoints")
+		}
+		if s.TrajIdx >= len(s.Trajectory) {
+			return fmt.Errorf("snapshot trajIdx %d out of range for %d waypoints", s.TrajIdx, len(s.Trajectory))
+		}
+	}
+	return nil
+}
+
+// Snapshot captures the current simulation state from inside the actor.
+func (e *Engine) Snapshot(ctx context.Context) (Snapshot, error) {
+	if e.replay != nil {
+		return Snapshot{}, ErrReplay
+	}
+	var snap Snapshot
+	err := e.call(ctx, func() { snap = e.snapshot() })
+	return snap, err
+}
+
+// Restore replaces the simulation state with snap. A trajectory resumes at
+// the saved waypoint index. While Run is active the loop keeps its own clock;
+// in step mode the saved SimTime becomes the current simulation time.
+func (e *Engine) Restore(ctx context.Context, snap Snapshot) error {
+	if e.replay != nil {
+		return ErrReplay
+	}
+	if err := snap.Validate(); err != nil {
+		return err
+	}
+	return e.call(ctx, func() { e.restore(snap) })
+}
+
+func (e *Engine) snapshot() Snapshot {
+	lat, lon, alt := e.geo.LocalToGeo(e.pos)
+	snap := Snapshot{
+		SimTime: e.now,
+		Lat:     lat, Lon: lon, Alt: alt,
+		Vx: e.vel.X, Vy: e.vel.Y, Vz: e.vel.Z,
+		Trajectory:   append([]Waypoint(nil), e.traj...),
+		TrajIdx:      e.trajIdx,
+		TrajLoop:     e.trajLoop,
+		LastWarnings: append([]Warning(nil), e.lastWarnings...),
+		LastWarning:  env.Summary(e.lastWarnings),
+
+		DistanceFlownM: e.odoM,
+		FlightTimeS:    e.odoTimeS,
+	}
+	if e.active != nil {
+		snap.Active = &CommandEnvelope{Command: e.active, Source: e.activeSource}
+	}
+	return snap
+}
+
+func (e *Engine) restore(snap Snapshot) {
+	if !e.running {
+		e.now = snap.SimTime
+	}
+	e.pos = e.geo.GeoToLocal(snap.Lat, snap.Lon, snap.Alt)
+	e.vel = vector.Vec3{X: snap.Vx, Y: snap.Vy, Z: snap.Vz}
+	e.gvel = e.vel
+	e.att.reset(e.vel)
+
+	e.active, e.activeSource = nil, ""
+	if snap.Active != nil {
+		e.active, e.activeSource = snap.Active.Command, snap.Active.Source
+	}
+	e.traj = append([]Waypoint(nil), snap.Trajectory...)
+	e.trajIdx = snap.TrajIdx
+	e.trajLoop = snap.TrajLoop
+	e.startActive(e.takenAt())
+	e.activeRec = nil // the log has no record of a restored command
+	e.lastWarnings = append([]Warning(nil), snap.LastWarnings...)
+	e.odoM = snap.DistanceFlownM
+	e.odoTimeS = snap.FlightTimeS
+}
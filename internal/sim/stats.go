@@ -0,0 +1,108 @@
+package sim
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"flight-simulator2/internal/env"
+)
+
+// Stats is a snapshot of the engine's internal counters.
+type Stats struct {
+	Ticks uint64 `json:"ticks"`
+
+	// Simulated time between ticks.
+	LastTickDtS float64 `json:"lastTickDtS"`
+	MeanTickDtS float64 `json:"meanTickDtS"`
+	// Overruns counts ticks that arrived more than 1.5 tick intervals after
+	// the previous one (Run only).
+	Overruns uint64 `json:"overruns"`
+
+	// Wall-clock time spent computing a tick.
+	LastTickWorkS float64 `json:"lastTickWorkS"`
+	MaxTickWorkS  float64 `json:"maxTickWorkS"`
+
+	Published       uint64 `json:"published"`
+	FramesDropped   uint64 `json:"framesDropped"` // over all subscribers, including departed ones
+	CommandsDropped uint64 `json:"commandsDropped"`
+
+	Subscribers      []SubscriberStats `json:"subscribers"`
+	EventSubscribers int               `json:"eventSubscribers"`
+
+	// TerrainCache counts the terrain provider's cache lookups, when it
+	// has one (see env.TerrainCache).
+	TerrainCache *env.TerrainCacheStats `json:"terrainCache,omitempty"`
+}
+
+// SubscriberStats describes one state subscriber.
+type SubscriberStats struct {
+	ID        uint64  `json:"id"`
+	MaxHz     float64 `json:"maxHz,omitempty"`
+	Delivered uint64  `json:"delivered"`
+	Dropped   uint64  `json:"dropped"` // frames lost because the channel was full
+}
+
+// engineStats holds the actor-owned counters behind Stats.
+type engineStats struct {
+	ticks         uint64
+	lastDt        float64
+	sumDt         float64
+	overruns      uint64
+	lastWork      time.Duration
+	maxWork       time.Duration
+	published     uint64
+	framesDropped uint64
+	nextSubID     uint64
+}
+
+func (s *engineStats) tick(dt float64, work time.Duration) {
+	s.ticks++
+	s.lastDt = dt
+	s.sumDt += dt
+	s.lastWork = work
+	if work > s.maxWork {
+		s.maxWork = work
+	}
+}
+
+// Stats returns the engine counters. It is served by the actor, so the
+// numbers are consistent with each other.
+func (e *Engine) Stats(ctx context.Context) (Stats, error) {
+	var st Stats
+	err := e.call(ctx, func() { st = e.stats() })
+	return st, err
+}
+
+func (e *Engine) stats() Stats {
+	s := e.stat
+	st := Stats{
+		Ticks:            s.ticks,
+		LastTickDtS:      s.lastDt,
+		Overruns:         s.overruns,
+		LastTickWorkS:    s.lastWork.Seconds(),
+		MaxTickWorkS:     s.maxWork.Seconds(),
+		Published:        s.published,
+		FramesDropped:    s.framesDropped,
+		CommandsDropped:  e.DroppedCommands(),
+		Subscribers:      []SubscriberStats{},
+		EventSubscribers: len(e.eventSubs),
+	}
+	if t, ok := env.FindTerrain(e.environment); ok {
+		if cs, ok := t.CacheStats(); ok {
+			st.TerrainCache = &cs
+		}
+	}
+	if s.ticks > 0 {
+		st.MeanTickDtS = s.sumDt / float64(s.ticks)
+	}
+	for _, sub := range e.subs {
+		ss := SubscriberStats{ID: sub.id, Delivered: sub.delivered, Dropped: sub.dropped}
+		if sub.interval > 0 {
+			ss.MaxHz = float64(time.Second) / float64(sub.interval)
+		}
+		st.Subscribers = append(st.Subscribers, ss)
+	}
+	sort.Slice(st.Subscribers, func(i, j int) bool { return st.Subscribers[i].ID < st.Subscribers[j].ID })
+	return st
+}
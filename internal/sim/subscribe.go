@@ -0,0 +1,121 @@
+package sim
+
+import (
+	"context"
+	"time"
+)
+
+// SubscribeOption configures a state subscription.
+type SubscribeOption func(*stateSub)
+
+// WithMaxRate limits a subscription to hz frames per second. The actor
+// decimates the stream for that subscriber only; frames carrying warnings
+// are always delivered. Zero or negative means no limit.
+func WithMaxRate(hz float64) SubscribeOption {
+	return func(s *stateSub) {
+		if hz > 0 {
+			s.interval = time.Duration(float64(time.Second) / hz)
+		}
+	}
+}
+
+// MaxResumeStates bounds how many missed states SubscribeAfter replays; a
+// longer gap is not resumed.
+const MaxResumeStates = 2048
+
+type resumeReply struct {
+	ch      chan AircraftState
+	resumed bool
+}
+
+// SubscribeAfter is Subscribe for a client that has seen the published
+// states up to seq, such as a stream reconnecting with Last-Event-ID. When
+// the states after seq are all still in the history (see
+// Config.HistorySize) and there are no more than MaxResumeStates of them,
+// the channel starts with them, in order and with no gap to the live
+// states that follow, and resumed is true. Otherwise the subscription
+// starts with the current state as Subscribe's does.
+//
+// Unlike Subscribe it waits for the actor to answer, so an engine driven by
+// Step must be stepped from another goroutine meanwhile.
+func (e *Engine) SubscribeAfter(ctx context.Context, seq uint64, opts ...SubscribeOption) (ch <-chan AircraftState, unsub func(), resumed bool) {
+	sub := &stateSub{ctx: ctx}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	req := subscribeReq{sub: sub, resume: &seq, resumed: make(chan resumeReply, 1)}
+	closed := func() (<-chan AircraftState, func(), bool) {
+		c := make(chan AircraftState)
+		close(c)
+		return c, func() {}, false
+	}
+	select {
+	case e.subscribeCh <- req:
+	case <-ctx.Done():
+		return closed()
+	}
+	var rep resumeReply
+	select {
+	case rep = <-req.resumed:
+	case <-ctx.Done():
+		// the actor has the request; drop the subscription once it answers
+		go func() {
+			rep := <-req.resumed
+			select {
+			case e.unsubCh <- rep.ch:
+			default:
+			}
+		}()
+		return closed()
+	}
+	unsub = func() {
+		select {
+		case e.unsubCh <- rep.ch:
+		default:
+		}
+	}
+	return rep.ch, unsub, rep.resumed
+}
+
+// handleResume registers a SubscribeAfter subscription, queueing the
+// missed states ahead of the live ones.
+func (e *Engine) handleResume(req subscribeReq) {
+	backlog, ok := e.history.after(*req.resume)
+	if !ok && *req.resume == e.seq {
+		// nothing missed, though the history does not say so
+		backlog, ok = nil, true
+	}
+	ok = ok && len(backlog) <= MaxResumeStates
+	if !ok {
+		backlog = []AircraftState{e.current()}
+	}
+	ch := make(chan AircraftState, len(backlog)+32)
+	for _, st := range backlog {
+		if !ok || req.sub.wants(st) {
+			ch <- st
+			req.sub.last = st.TS
+			req.sub.delivered++
+		}
+	}
+	e.subs[ch] = req.sub
+	req.resumed <- resumeReply{ch: ch, resumed: ok}
+}
+
+// stateSub is the actor-owned record of one state subscriber.
+type stateSub struct {
+	ctx      context.Context // the subscription ends with it
+	id       uint64
+	interval time.Duration // minimum spacing of frames; 0 = every frame
+	last     time.Time     // TS of the last delivered frame
+
+	delivered uint64
+	dropped   uint64
+}
+
+// wants reports whether st should go to this subscriber.
+func (s *stateSub) wants(st AircraftState) bool {
+	if s.interval == 0 || len(st.Warnings) > 0 || s.last.IsZero() {
+		return true
+	}
+	return st.TS.Sub(s.last) >= s.interval
+}
@@ -0,0 +1,58 @@
+package sim_test
+
+import (
+	"context"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestSubscribeMaxRate(t *testing.T) {
+	// five seconds at the default 20 Hz: 100 published states, plus the
+	// current one every subscription starts with
+	for _, c := range []struct {
+		name    string
+		hz      float64
+		traffic bool
+		want    int
+	}{
+		{name: "no limit", hz: 0, want: 101},
+		{name: "above the tick rate", hz: 50, want: 101},
+		{name: "5 Hz", hz: 5, want: 26},
+		{name: "1 Hz", hz: 1, want: 6},
+		{name: "warnings pass the limit", hz: 1, traffic: true, want: 101},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := sim.Config{OriginLat: 47, OriginLon: 8}
+			if c.traffic {
+				// parked next to the ownship: a traffic advisory on every frame
+				cfg.Traffic = []sim.TrafficTarget{{ID: "x", Waypoints: []sim.Waypoint{offset(100, 0, 1000), offset(100, 0, 1000)}}}
+			}
+			h, err := simtest.New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ch, unsub := h.Engine.Subscribe(ctx, sim.WithMaxRate(c.hz))
+			defer unsub()
+
+			got := 0
+			for i := 0; i < 100; i++ {
+				h.Steps(1)
+				for drained := false; !drained; {
+					select {
+					case <-ch:
+						got++
+					default:
+						drained = true
+					}
+				}
+			}
+			if got != c.want {
+				t.Errorf("received %d states, want %d", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"fmt"
+
+	"flight-simulator2/internal/env"
+)
+
+// Terrain look-ahead warning codes, from the earliest to the most urgent.
+const (
+	WarnTerrainCaution = "terrain-caution"
+	WarnTerrainWarning = "terrain-warning"
+	WarnTerrainPullUp  = "terrain-pull-up"
+)
+
+// Time to impact, in seconds, below which the look-ahead escalates to
+// WarnTerrainWarning and WarnTerrainPullUp. Any predicted impact within
+// Config.TerrainLookaheadS raises at least WarnTerrainCaution.
+const (
+	terrainWarningS = 15.0
+	terrainPullUpS  = 5.0
+)
+
+// terrainSampleS is the spacing of the look-ahead samples in seconds.
+const terrainSampleS = 0.5
+
+// timeToImpact projects the ground velocity of the last tick forward up to
+// horizonS seconds and returns the first time the path is below the
+// environment's safety floor (see env.MinAltitude), sampled every half
+// second. ok is false when the path stays clear or there is no floor.
+func (e *Engine) timeToImpact(horizonS float64) (seconds float64, ok bool) {
+	if e.environment == nil {
+		return 0, false
+	}
+	for t := terrainSampleS; t <= horizonS+1e-9; t += terrainSampleS {
+		p := e.pos.Add(e.gvel.Mul(t))
+		floor, found := env.MinAltitude(e.environment, p)
+		if !found {
+			return 0, false
+		}
+		if p.Z < floor {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// checkTerrainAhead raises the graded terrain look-ahead warning for the
+// predicted time to impact, if any.
+func (e *Engine) checkTerrainAhead() []Warning {
+	if e.terrainLookahead <= 0 {
+		return nil
+	}
+	t, ok := e.timeToImpact(e.terrainLookahead)
+	if !ok {
+		return nil
+	}
+	w := Warning{
+		Code:     WarnTerrainCaution,
+		Severity: env.SeverityCaution,
+		Message:  fmt.Sprintf("terrain ahead, impact in %.1f s", t),
+	}
+	switch {
+	case t <= terrainPullUpS:
+		w.Code, w.Severity = WarnTerrainPullUp, env.SeverityWarning
+		w.Message = fmt.Sprintf("pull up, terrain impact in %.1f s", t)
+	case t <= terrainWarningS:
+		w.Code, w.Severity = WarnTerrainWarning, env.SeverityWarning
+	}
+	return []Warning{w}
+}
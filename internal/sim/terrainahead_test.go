@@ -0,0 +1,114 @@
+package sim_test
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+// ridge rises from flat ground 3 km north of the origin at 1 in 2.
+type ridge struct{}
+
+func (ridge) GroundAltitude(pos vector.Vec3) float64 { return max(pos.Y-3000, 0) / 2 }
+
+// impactIn reads the time to impact from a look-ahead warning.
+func impactIn(t *testing.T, msg string) float64 {
+	t.Helper()
+	var s float64
+	for _, format := range []string{"terrain ahead, impact in %f s", "pull up, terrain impact in %f s"} {
+		if _, err := fmt.Sscanf(msg, format, &s); err == nil {
+			return s
+		}
+	}
+	t.Fatalf("no time to impact in %q", msg)
+	return 0
+}
+
+// flyAtRidge flies level at 1000 m toward the ridge, whose 50 m safety
+// floor reaches 1000 m 4.9 km north, for up to n steps or until stop
+// returns true, and returns every state.
+func flyAtRidge(t *testing.T, lookahead float64, n int, stop func(sim.AircraftState) bool) []sim.AircraftState {
+	t.Helper()
+	h, err := simtest.New(sim.Config{
+		OriginLat: 47, OriginLon: 8,
+		Environment:       env.Terrain{SafetyMarginM: 50, Provider: ridge{}},
+		TerrainLookaheadS: lookahead,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Submit(sim.GoToCommand{Lat: 47.2, Lon: 8, Alt: 1000, Speed: 80}); err != nil {
+		t.Fatal(err)
+	}
+	var states []sim.AircraftState
+	for range n {
+		st := h.Steps(1)
+		states = append(states, st)
+		if stop(st) {
+			break
+		}
+	}
+	return states
+}
+
+func TestTerrainAheadWarningSequence(t *testing.T) {
+	levels := map[string]float64{
+		sim.WarnTerrainCaution: 30,
+		sim.WarnTerrainWarning: 15,
+		sim.WarnTerrainPullUp:  5,
+	}
+	pullUp := func(st sim.AircraftState) bool {
+		return slices.ContainsFunc(st.Warnings, func(w sim.Warning) bool { return w.Code == sim.WarnTerrainPullUp })
+	}
+	geo := sim.GeoRef{OriginLat: 47, OriginLon: 8}
+	var seq []string
+	for _, st := range flyAtRidge(t, 30, 2000, pullUp) {
+		for _, w := range st.Warnings {
+			limit, ok := levels[w.Code]
+			if !ok {
+				continue
+			}
+			tti := impactIn(t, w.Message)
+			// the path is level, so impact is where it meets the floor
+			north := geo.GeoToLocal(st.Lat, st.Lon, st.Alt).Y
+			if want := (4900 - north) / st.GVy; tti < want-0.05 || tti > want+0.55 {
+				t.Errorf("%s: %.1f s to impact %.0f m north at %.1f m/s, want %.2f rounded up to a sample", w.Code, tti, north, st.GVy, want)
+			}
+			if len(seq) > 0 && seq[len(seq)-1] == w.Code {
+				continue
+			}
+			seq = append(seq, w.Code)
+			// each level starts at its threshold, give or take a sample
+			if tti > limit || tti < limit-1 {
+				t.Errorf("%s first at %.1f s to impact, want about %g", w.Code, tti, limit)
+			}
+		}
+	}
+	want := []string{sim.WarnTerrainCaution, sim.WarnTerrainWarning, sim.WarnTerrainPullUp}
+	if !slices.Equal(seq, want) {
+		t.Errorf("warnings %v, want %v", seq, want)
+	}
+}
+
+func TestTerrainAheadOff(t *testing.T) {
+	// without the look-ahead nothing warns until the floor itself
+	for _, st := range flyAtRidge(t, 0, 1000, func(sim.AircraftState) bool { return false }) {
+		for _, w := range st.Warnings {
+			switch w.Code {
+			case sim.WarnTerrainCaution, sim.WarnTerrainWarning, sim.WarnTerrainPullUp:
+				t.Fatalf("%s at %.4f°N with the look-ahead off", w.Code, st.Lat)
+			}
+		}
+	}
+	for _, s := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if _, err := sim.New(sim.Config{TerrainLookaheadS: s}); err == nil {
+			t.Errorf("look-ahead of %g s accepted", s)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package sim
+
+import (
+	"fmt"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnTerrainFollowLimit is the code of the caution raised when holding the
+// commanded height above ground would take a faster climb than the limit.
+const WarnTerrainFollowLimit = "terrain-follow-limit"
+
+// terrainFollowLeadS is how far ahead, in seconds at the commanded speed,
+// terrain following looks for rising ground; terrainFollowSampleS is the
+// spacing of its samples.
+const (
+	terrainFollowLeadS   = 5.0
+	terrainFollowSampleS = 1.0
+)
+
+// terrainFollow is the terrain following state of the last guidance step.
+type terrainFollow struct {
+	on       bool
+	aglM     float64 // the commanded height above ground
+	needRate float64 // climb rate needed to keep it over the terrain ahead
+}
+
+// groundAt is the terrain height at p, 0 without terrain.
+func (e *Engine) groundAt(p vector.Vec3) float64 {
+	if e.environment == nil {
+		return 0
+	}
+	ground, _ := env.GroundAltitude(e.environment, p)
+	return ground
+}
+
+// followTerrain returns the altitude that keeps agl metres above the
+// ground from here to terrainFollowLeadS ahead on the way to target, so
+// that the climb starts before a ridge rather than on it. It records the
+// climb rate that needs for the terrain-follow-limit check.
+func (e *Engine) followTerrain(target vector.Vec3, speed, agl float64) float64 {
+	horiz := target.Sub(e.pos).Horizontal()
+	dist := horiz.Length()
+	dir := horiz.Normalize()
+
+	alt := e.groundAt(e.pos) + agl
+	need := 0.0
+	for t := terrainFollowSampleS; t <= terrainFollowLeadS; t += terrainFollowSampleS {
+		d := min(speed*t, dist)
+		z := e.groundAt(e.pos.Add(dir.Mul(d))) + agl
+		alt = max(alt, z)
+		// within the altitude tolerance is close enough
+		need = max(need, (z-e.limits.AltTolM-e.pos.Z)/t)
+		if d == dist {
+			break
+		}
+	}
+	e.follow = terrainFollow{on: true, aglM: agl, needRate: need}
+	return alt
+}
+
+// checkTerrainFollow raises WarnTerrainFollowLimit when the terrain ahead
+// rises faster than the aircraft may climb.
+func (e *Engine) checkTerrainFollow() []Warning {
+	if !e.follow.on || e.follow.needRate <= e.limits.MaxClimbRate {
+		return nil
+	}
+	return []Warning{{
+		Code:     WarnTerrainFollowLimit,
+		Severity: env.SeverityCaution,
+		Message: fmt.Sprintf("cannot hold %.0f m AGL: terrain ahead needs %.1f m/s climb, limit %.1f m/s",
+			e.follow.aglM, e.follow.needRate, e.limits.MaxClimbRate),
+	}}
+}
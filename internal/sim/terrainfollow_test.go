@@ -0,0 +1,96 @@
+package sim_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestTerrainFollow(t *testing.T) {
+	// east across sine terrain at 80 m/s, 150 m above it
+	for _, c := range []struct {
+		name    string
+		terrain env.SyntheticTerrain
+		band    float64 // AGL tolerance once settled; 0 = not held
+		limited bool    // raises WarnTerrainFollowLimit
+	}{
+		{"gentle", env.SyntheticTerrain{AmplitudeM: 50, ScaleM: 2000}, 15, false},
+		{"rolling", env.SyntheticTerrain{AmplitudeM: 100, ScaleM: 1500, RidgeAmplitudeM: 20, RidgeScaleM: 800}, 30, false},
+		{"too steep to hold", env.SyntheticTerrain{AmplitudeM: 300, ScaleM: 300}, 0, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h, err := simtest.New(sim.Config{
+				OriginLat: 47, OriginLon: 8, InitialAlt: 150,
+				Environment: env.Terrain{SafetyMarginM: 10, Provider: c.terrain},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Submit(sim.GoToCommand{Lat: 47, Lon: 8.2, Speed: 80, AGL: 150}); err != nil {
+				t.Fatal(err)
+			}
+			var limited bool
+			var prevVz, prevAGL float64
+			minAGL, maxAGL := math.Inf(1), math.Inf(-1)
+			for i := range 3000 {
+				st := h.Steps(1)
+				if st.AGLM == nil || st.TargetAGLM == nil || *st.TargetAGLM != 150 {
+					t.Fatalf("step %d: AGL %v, target %v", i, st.AGLM, st.TargetAGLM)
+				}
+				// only Terrain's recovery below the 10 m margin climbs faster,
+				// and guidance then eases back to the limit
+				if st.Vz > sim.DefaultLimits().MaxClimbRate+1e-9 && prevAGL >= 10 && st.Vz >= prevVz {
+					t.Fatalf("step %d: climbing at %.2f m/s, above the limit", i, st.Vz)
+				}
+				prevVz, prevAGL = st.Vz, *st.AGLM
+				limited = limited || slices.ContainsFunc(st.Warnings, func(w sim.Warning) bool {
+					return w.Code == sim.WarnTerrainFollowLimit
+				})
+				if i >= 200 {
+					minAGL, maxAGL = min(minAGL, *st.AGLM), max(maxAGL, *st.AGLM)
+				}
+			}
+			if limited != c.limited {
+				t.Errorf("terrain-follow-limit raised: %v, want %v", limited, c.limited)
+			}
+			if c.band > 0 && (minAGL < 150-c.band || maxAGL > 150+c.band) {
+				t.Errorf("AGL between %.1f and %.1f m, want 150 ± %g", minAGL, maxAGL, c.band)
+			}
+		})
+	}
+}
+
+func TestTerrainFollowStateFields(t *testing.T) {
+	// without terrain there is no AGL to publish, and a plain goto holds none
+	for _, c := range []struct {
+		name       string
+		env        env.Environment
+		agl        float64
+		wantAGL    bool
+		wantTarget bool
+	}{
+		{"no terrain", env.Calm(), 0, false, false},
+		{"plain goto", env.Terrain{Provider: env.FlatTerrain(200)}, 0, true, false},
+		{"following", env.Terrain{Provider: env.FlatTerrain(200)}, 300, true, true},
+	} {
+		h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8, Environment: c.env})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Submit(sim.GoToCommand{Lat: 47, Lon: 8.2, Alt: 1000, Speed: 80, AGL: c.agl}); err != nil {
+			t.Fatal(err)
+		}
+		st := h.Steps(10)
+		if (st.AGLM != nil) != c.wantAGL || (st.TargetAGLM != nil) != c.wantTarget {
+			t.Errorf("%s: AGL %v, target %v", c.name, st.AGLM, st.TargetAGLM)
+			continue
+		}
+		if st.AGLM != nil && math.Abs(*st.AGLM-(st.Alt-200)) > 1e-9 {
+			t.Errorf("%s: AGL %.3f at %.3f m over 200 m ground", c.name, *st.AGLM, st.Alt)
+		}
+	}
+}
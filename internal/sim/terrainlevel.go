@@ -0,0 +1,82 @@
+package sim
+
+import "flight-simulator2/internal/env"
+
+// DefaultTerrainHysteresisTicks is how many ticks a new terrain proximity
+// level must persist before it is published when
+// Config.TerrainHysteresisTicks is zero.
+const DefaultTerrainHysteresisTicks = 5
+
+// terrainLevel grades the terrain proximity warnings of env.Terrain and the
+// engine's floor clip, from clear to a violation.
+type terrainLevel int
+
+const (
+	terrainClear terrainLevel = iota
+	terrainCaution
+	terrainWarning
+	terrainViolation
+)
+
+// terrainLevelOf returns the level a warning code stands for, or
+// terrainClear for other codes.
+func terrainLevelOf(code string) terrainLevel {
+	switch code {
+	case env.WarnTerrainProximity:
+		return terrainCaution
+	case env.WarnTerrainFloor:
+		return terrainWarning
+	case env.WarnTerrainContact:
+		return terrainViolation
+	}
+	return terrainClear
+}
+
+// terrainDebounce holds the published terrain level and the one waiting to
+// replace it.
+type terrainDebounce struct {
+	published terrainLevel
+	shown     Warning // the latest warning at the published level
+	pending   terrainLevel
+	count     int // ticks pending has been seen in a row
+}
+
+// debounceTerrain replaces the terrain proximity warnings of a tick with
+// the published level's, so that skimming a threshold does not flip the
+// state every tick. A new level is published once it has been the tick's
+// highest for ticks ticks in a row; a violation, when the altitude was
+// clipped, is published at once.
+func (d *terrainDebounce) debounceTerrain(ws []Warning, ticks int) []Warning {
+	raw, rawW := terrainClear, Warning{}
+	out := make([]Warning, 0, len(ws))
+	for _, w := range ws {
+		if lvl := terrainLevelOf(w.Code); lvl != terrainClear {
+			if lvl > raw {
+				raw, rawW = lvl, w
+			}
+			continue
+		}
+		out = append(out, w)
+	}
+
+	switch {
+	case raw == d.published:
+		d.count = 0
+	case raw == terrainViolation:
+		d.published, d.count = raw, 0
+	case raw == d.pending:
+		d.count++
+	default:
+		d.pending, d.count = raw, 1
+	}
+	if raw != d.published && d.count >= ticks {
+		d.published, d.count = raw, 0
+	}
+	if raw == d.published {
+		d.shown = rawW
+	}
+	if d.published == terrainClear {
+		return out
+	}
+	return append(out, d.shown)
+}
@@ -0,0 +1,117 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestDebounceTerrain(t *testing.T) {
+	// over flat ground with a 50 m margin: caution below 100 m, warning
+	// below 50 m, violation below the surface
+	terrain := env.Terrain{SafetyMarginM: 50, Provider: env.FlatTerrain(0)}
+	alternate := func(a, b float64, n int) []float64 {
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = a
+			if i%2 == 1 {
+				out[i] = b
+			}
+		}
+		return out
+	}
+	repeat := func(alt float64, n int) []float64 { return alternate(alt, alt, n) }
+	for _, c := range []struct {
+		name  string
+		ticks int
+		alts  []float64
+		want  string // published level per tick: . clear, c caution, w warning, v violation
+	}{
+		{"clear", 3, repeat(500, 5), "....."},
+		{"skimming the caution threshold", 3, alternate(99, 101, 12), "............"},
+		{"settling into caution", 3, repeat(99, 5), "..ccc"},
+		{"skimming the floor in caution", 3, append(repeat(99, 3), alternate(49, 51, 10)...), "..ccccccccccc"},
+		{"settling below the floor", 3, append(repeat(99, 3), repeat(45, 4)...), "..cccww"},
+		{"straight to the floor", 3, repeat(40, 4), "..ww"},
+		{"contact at once", 3, append(repeat(99, 3), -1, 40, 40, 40), "..cvvvw"},
+		{"leaving", 3, append(repeat(99, 3), repeat(200, 4)...), "..ccc.."},
+		{"skimming out of caution", 3, append(repeat(99, 3), alternate(101, 99, 8)...), "..ccccccccc"},
+		{"one tick", 1, []float64{500, 99, 101, 49, 99, -1, 500}, ".c.wcv."},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var d terrainDebounce
+			var got strings.Builder
+			for _, alt := range c.alts {
+				res, err := terrain.Apply(env.Context{Dt: 0.05, Pos: vector.Vec3{Z: alt}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				other := Warning{Code: "other", Severity: env.SeverityCaution}
+				ws := d.debounceTerrain(append(res.Warnings, other), c.ticks)
+				level := "."
+				for _, w := range ws {
+					switch w.Code {
+					case env.WarnTerrainProximity:
+						level = "c"
+					case env.WarnTerrainFloor:
+						level = "w"
+					case env.WarnTerrainContact:
+						level = "v"
+					case "other":
+					default:
+						t.Fatalf("unexpected warning %s", w.Code)
+					}
+				}
+				if ws[0] != other {
+					t.Fatalf("other warnings not passed through first: %+v", ws)
+				}
+				got.WriteString(level)
+			}
+			if got.String() != c.want {
+				t.Errorf("published %q, want %q", got.String(), c.want)
+			}
+		})
+	}
+}
+
+func TestTerrainHysteresisConfig(t *testing.T) {
+	// hovering 80 m over flat ground with a 50 m margin is a caution,
+	// published once it has lasted the configured ticks
+	for _, c := range []struct {
+		ticks, want int
+	}{
+		{0, DefaultTerrainHysteresisTicks},
+		{1, 1},
+		{8, 8},
+	} {
+		e, err := New(Config{
+			InitialAlt:             1000,
+			Environment:            env.Terrain{SafetyMarginM: 50, Provider: env.FlatTerrain(920)},
+			TerrainHysteresisTicks: c.ticks,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := 0
+		for i := 1; i <= 20 && first == 0; i++ {
+			for _, w := range e.Step(0.05).Warnings {
+				if w.Code == env.WarnTerrainProximity {
+					first = i
+				}
+			}
+		}
+		if first != c.want {
+			t.Errorf("%d ticks: caution published on tick %d, want %d", c.ticks, first, c.want)
+		}
+		// a reset starts over
+		e.reset(nil)
+		if e.terrainDeb != (terrainDebounce{}) {
+			t.Errorf("%d ticks: reset kept %+v", c.ticks, e.terrainDeb)
+		}
+	}
+	if _, err := New(Config{TerrainHysteresisTicks: -1}); err == nil {
+		t.Errorf("negative hysteresis accepted")
+	}
+}
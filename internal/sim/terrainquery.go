@@ -0,0 +1,297 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// ErrNoTerrain is returned by the terrain queries when the environment has
+// no terrain.
+var ErrNoTerrain = errors.New("the environment has no terrain")
+
+// MaxTerrainGridSide caps the rows and columns of a TerrainGrid.
+const MaxTerrainGridSide = 256
+
+// TerrainPoint is the terrain at one point as the engine sees it. Outside
+// the terrain data (e.g. past the edge of an elevation model) Covered is
+// false and the elevations are null rather than the provider's fallback.
+// Water is set where the surface is water (see env.WaterMask), and SlopeDeg
+// is the ground's steepest slope (see env.Terrain.SlopeDeg).
+type TerrainPoint struct {
+	Lat           float64  `json:"lat"`
+	Lon           float64  `json:"lon"`
+	Covered       bool     `json:"covered"`
+	Water         bool     `json:"water"`
+	ElevationM    *float64 `json:"elevationM"`
+	SlopeDeg      *float64 `json:"slopeDeg"`
+	SafetyMarginM float64  `json:"safetyMarginM"`
+	MinSafeAltM   *float64 `json:"minSafeAltM"`
+}
+
+// TerrainGridQuery is a bounding box sampled Rows by Cols times, corners
+// included.
+type TerrainGridQuery struct {
+	MinLat float64 `json:"minLat"`
+	MinLon float64 `json:"minLon"`
+	MaxLat float64 `json:"maxLat"`
+	MaxLon float64 `json:"maxLon"`
+	Rows   int     `json:"rows"`
+	Cols   int     `json:"cols"`
+}
+
+// Validate checks the box is in range and not inverted, and the grid has
+// between 2 and MaxTerrainGridSide samples a side.
+func (q TerrainGridQuery) Validate() error {
+	for _, v := range []float64{q.MinLat, q.MaxLat} {
+		if !(math.Abs(v) <= 90) {
+			return fmt.Errorf("minLat and maxLat must be between -90 and 90")
+		}
+	}
+	for _, v := range []float64{q.MinLon, q.MaxLon} {
+		if !(math.Abs(v) <= 180) {
+			return fmt.Errorf("minLon and maxLon must be between -180 and 180")
+		}
+	}
+	if q.MinLat > q.MaxLat || q.MinLon > q.MaxLon {
+		return fmt.Errorf("minLat and minLon must not exceed maxLat and maxLon")
+	}
+	if q.Rows < 2 || q.Cols < 2 || q.Rows > MaxTerrainGridSide || q.Cols > MaxTerrainGridSide {
+		return fmt.Errorf("rows and cols must be between 2 and %d", MaxTerrainGridSide)
+	}
+	return nil
+}
+
+// TerrainGrid holds the elevations of a TerrainGridQuery, row by row from
+// the north (MaxLat) and west to east within a row; null where the terrain
+// has no data.
+type TerrainGrid struct {
+	TerrainGridQuery
+	ElevationM [][]*float64 `json:"elevationM"`
+	Uncovered  int          `json:"uncovered"`
+}
+
+// TerrainParams reports the parameters of the environment's terrain, such
+// as the synthetic generator's or the elevation model's file.
+func (e *Engine) TerrainParams(ctx context.Context) (env.TerrainParams, error) {
+	var (
+		tp  env.TerrainParams
+		err error
+	)
+	callErr := e.call(ctx, func() {
+		t, ok := env.FindTerrain(e.environment)
+		if !ok {
+			err = ErrNoTerrain
+			return
+		}
+		tp, err = env.DescribeTerrain(t)
+	})
+	if callErr != nil {
+		return env.TerrainParams{}, callErr
+	}
+	return tp, err
+}
+
+// terrainAt samples the configured environment's terrain at lat, lon.
+func (e *Engine) terrainAt(lat, lon float64) TerrainPoint {
+	pos := e.geo.GeoToLocal(lat, lon, 0)
+	p := TerrainPoint{
+		Lat: lat, Lon: lon,
+		Covered: env.GroundCovered(e.environment, pos),
+		Water:   env.WaterAt(e.environment, pos),
+	}
+	ground, _ := env.GroundAltitude(e.environment, pos)
+	floor, ok := env.MinAltitude(e.environment, pos)
+	if !ok {
+		floor = ground
+	}
+	p.SafetyMarginM = floor - ground
+	if p.Covered {
+		p.ElevationM, p.MinSafeAltM = &ground, &floor
+		if slope, ok := env.SlopeAt(e.environment, pos); ok {
+			p.SlopeDeg = &slope
+		}
+	}
+	return p
+}
+
+// hasTerrain reports whether the configured environment knows the ground.
+func (e *Engine) hasTerrain() bool {
+	if e.environment == nil {
+		return false
+	}
+	_, ok := env.GroundAltitude(e.environment, e.pos)
+	return ok
+}
+
+// TerrainAt reports the terrain at lat, lon from the environment's own
+// provider, so that it matches what the aircraft flies over.
+func (e *Engine) TerrainAt(ctx context.Context, lat, lon float64) (TerrainPoint, error) {
+	var (
+		p   TerrainPoint
+		err error
+	)
+	callErr := e.call(ctx, func() {
+		if !e.hasTerrain() {
+			err = ErrNoTerrain
+			return
+		}
+		p = e.terrainAt(lat, lon)
+	})
+	if callErr != nil {
+		return TerrainPoint{}, callErr
+	}
+	return p, err
+}
+
+// TerrainGrid samples the terrain over q's box (see TerrainAt).
+func (e *Engine) TerrainGrid(ctx context.Context, q TerrainGridQuery) (TerrainGrid, error) {
+	if err := q.Validate(); err != nil {
+		return TerrainGrid{}, err
+	}
+	var (
+		g   = TerrainGrid{TerrainGridQuery: q}
+		err error
+	)
+	callErr := e.call(ctx, func() {
+		if !e.hasTerrain() {
+			err = ErrNoTerrain
+			return
+		}
+		dLat := (q.MaxLat - q.MinLat) / float64(q.Rows-1)
+		dLon := (q.MaxLon - q.MinLon) / float64(q.Cols-1)
+		g.ElevationM = make([][]*float64, q.Rows)
+		for i := range g.ElevationM {
+			row := make([]*float64, q.Cols)
+			for j := range row {
+				p := e.terrainAt(q.MaxLat-float64(i)*dLat, q.MinLon+float64(j)*dLon)
+				row[j] = p.ElevationM
+				if !p.Covered {
+					g.Uncovered++
+				}
+			}
+			g.ElevationM[i] = row
+		}
+	})
+	if callErr != nil {
+		return TerrainGrid{}, callErr
+	}
+	return g, err
+}
+
+// MaxProfileSamples caps the samples of a TerrainProfile.
+const MaxProfileSamples = 10000
+
+// ErrTooManySamples is returned by TerrainProfile for a route that needs
+// more than MaxProfileSamples samples.
+var ErrTooManySamples = errors.New("too many samples")
+
+// PathPoint is a vertex of a route.
+type PathPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// ProfileQuery is a route sampled every IntervalM metres along each leg;
+// every vertex is sampled too.
+type ProfileQuery struct {
+	Points    []PathPoint `json:"points"`
+	IntervalM float64     `json:"intervalM"`
+}
+
+// Validate checks the route has at least two points in range and a
+// positive, finite interval.
+func (q ProfileQuery) Validate() error {
+	if len(q.Points) < 2 {
+		return fmt.Errorf("points must have at least 2 entries")
+	}
+	for i, p := range q.Points {
+		if !(math.Abs(p.Lat) <= 90) || !(math.Abs(p.Lon) <= 180) {
+			return fmt.Errorf("points[%d]: lat must be within ±90 and lon within ±180", i)
+		}
+	}
+	if !(q.IntervalM > 0) || math.IsInf(q.IntervalM, 1) {
+		return fmt.Errorf("intervalM must be finite and > 0")
+	}
+	return nil
+}
+
+// ProfileSample is the terrain at one sample of a route, DistanceM along it.
+type ProfileSample struct {
+	DistanceM float64 `json:"distanceM"`
+	// Leg is the index of the route leg, from Points[Leg] to Points[Leg+1].
+	Leg int `json:"leg"`
+	TerrainPoint
+}
+
+// TerrainProfile samples the terrain under q's route (see TerrainAt).
+// Routes needing more than MaxProfileSamples samples are rejected.
+func (e *Engine) TerrainProfile(ctx context.Context, q ProfileQuery) ([]ProfileSample, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	var (
+		samples []ProfileSample
+		err     error
+	)
+	callErr := e.call(ctx, func() {
+		if !e.hasTerrain() {
+			err = ErrNoTerrain
+			return
+		}
+		path := make([]vector.Vec3, len(q.Points))
+		for i, p := range q.Points {
+			path[i] = e.geo.GeoToLocal(p.Lat, p.Lon, 0)
+		}
+		if n := pathSamples(path, q.IntervalM); n > MaxProfileSamples {
+			err = fmt.Errorf("%w: the route needs %d at %g m, the limit is %d; use a longer intervalM", ErrTooManySamples, n, q.IntervalM, MaxProfileSamples)
+			return
+		}
+		walkPath(path, q.IntervalM, func(leg int, dist float64, pos vector.Vec3) {
+			lat, lon, _ := e.geo.LocalToGeo(pos)
+			samples = append(samples, ProfileSample{DistanceM: dist, Leg: leg, TerrainPoint: e.terrainAt(lat, lon)})
+		})
+	})
+	if callErr != nil {
+		return nil, callErr
+	}
+	return samples, err
+}
+
+// pathSamples is the number of samples walkPath makes.
+func pathSamples(path []vector.Vec3, interval float64) int {
+	n := 1
+	for i := 1; i < len(path); i++ {
+		n += int(math.Ceil(path[i].Sub(path[i-1]).Horizontal().Length() / interval))
+	}
+	return n
+}
+
+// walkPath calls fn at the first point of path and then every interval
+// metres along each leg, restarting at each vertex, with the leg index and
+// the horizontal distance from the start. The last point is always
+// visited; a zero-length leg adds no sample.
+func walkPath(path []vector.Vec3, interval float64, fn func(leg int, dist float64, pos vector.Vec3)) {
+	if len(path) == 0 {
+		return
+	}
+	fn(0, 0, path[0])
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		d := path[i].Sub(path[i-1])
+		length := d.Horizontal().Length()
+		if length == 0 {
+			continue
+		}
+		n := int(math.Ceil(length / interval))
+		for k := 1; k <= n; k++ {
+			s := min(float64(k)*interval, length)
+			fn(i-1, total+s, path[i-1].Lerp(path[i], s/length))
+		}
+		total += length
+	}
+}
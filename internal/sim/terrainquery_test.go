@@ -0,0 +1,40 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/env"
+)
+
+// metersPerDegLon47 is a degree of longitude at 47°N, equirectangular.
+var metersPerDegLon47 = metersPerDegLat * math.Cos(47*math.Pi/180)
+
+func TestTerrainAtSlope(t *testing.T) {
+	// a 200 m wave, 1000 m per radian: 1 in 5 at the origin, level on its crest
+	terrain := env.Terrain{SafetyMarginM: 30, Provider: env.SyntheticTerrain{AmplitudeM: 200, ScaleM: 1000}}
+	for _, c := range []struct {
+		name     string
+		env      env.Environment
+		lat, lon float64
+		slope    float64
+		ok       bool
+	}{
+		{"over the wave", terrain, 47, 8, math.Atan(0.2) * 180 / math.Pi, true},
+		{"on its crest", terrain, 47, 8 + 1000*math.Pi/2/metersPerDegLon47, 0, true},
+		{"flat", env.Terrain{Provider: env.FlatTerrain(400)}, 47.01, 8.01, 0, true},
+		{"no terrain", env.Calm(), 47, 8, 0, false},
+	} {
+		e, err := New(Config{OriginLat: 47, OriginLon: 8, Projection: ProjectionEquirectangular, Environment: c.env})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := e.terrainAt(c.lat, c.lon)
+		switch {
+		case (p.SlopeDeg != nil) != c.ok:
+			t.Errorf("%s: slope %v, want one: %v", c.name, p.SlopeDeg, c.ok)
+		case c.ok && math.Abs(*p.SlopeDeg-c.slope) > 0.01:
+			t.Errorf("%s: slope %.4f°, want %.4f°", c.name, *p.SlopeDeg, c.slope)
+		}
+	}
+}
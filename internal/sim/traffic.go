@@ -0,0 +1,179 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WarnTraffic is the code of the warning raised while traffic is inside the
+// advisory thresholds. Its message lists each intruder's bearing, range and
+// relative altitude.
+const WarnTraffic = "traffic"
+
+// Advisory thresholds used when Config leaves them zero.
+const (
+	DefaultTrafficHorizM = 1000.0
+	DefaultTrafficVertM  = 150.0
+)
+
+// TrafficTarget is a scripted aircraft that flies its waypoints in a loop at
+// a constant speed, starting at the first one. It takes no commands.
+type TrafficTarget struct {
+	ID        string     `json:"id"`
+	Waypoints []Waypoint `json:"waypoints"`
+	Speed     float64    `json:"speed,omitempty"` // m/s; waypoint speeds override it per leg
+}
+
+// Validate checks the target has an ID, at least two waypoints and sane values.
+func (t TrafficTarget) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("traffic target needs an id")
+	}
+	if len(t.Waypoints) < 2 {
+		return fmt.Errorf("traffic %s: needs at least 2 waypoints", t.ID)
+	}
+	for i, wp := range t.Waypoints {
+		if wp.Lat < -90 || wp.Lat > 90 || wp.Lon < -180 || wp.Lon > 180 {
+			return fmt.Errorf("traffic %s: waypoints[%d] out of range", t.ID, i)
+		}
+		if wp.Speed < 0 || math.IsNaN(wp.Speed) {
+			return fmt.Errorf("traffic %s: waypoints[%d] speed must be >= 0", t.ID, i)
+		}
+	}
+	if t.Speed < 0 || math.IsNaN(t.Speed) || math.IsInf(t.Speed, 0) {
+		return fmt.Errorf("traffic %s: speed must be >= 0", t.ID)
+	}
+	return nil
+}
+
+// TrafficState is one traffic target as published alongside the ownship
+// state, with its geometry relative to the ownship.
+type TrafficState struct {
+	ID  string  `json:"id"`
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	Alt float64 `json:"alt"`
+
+	Vx float64 `json:"vx"`
+	Vy float64 `json:"vy"`
+	Vz float64 `json:"vz"`
+
+	HeadingDeg float64 `json:"headingDeg"`
+
+	// Relative to the ownship.
+	RangeM     float64 `json:"rangeM"`     // horizontal
+	BearingDeg float64 `json:"bearingDeg"` // true bearing from the ownship
+	RelAltM    float64 `json:"relAltM"`    // positive above the ownship
+	ClosureMps float64 `json:"closureMps"` // positive while the range shrinks
+
+	// Advisory is set while the target is inside both thresholds.
+	Advisory bool `json:"advisory,omitempty"`
+}
+
+// intruder is the actor-owned state of a traffic target.
+type intruder struct {
+	cfg   TrafficTarget
+	route []vector.Vec3
+	idx   int // waypoint being flown to
+	pos   vector.Vec3
+	vel   vector.Vec3
+}
+
+func newIntruders(targets []TrafficTarget, geo GeoRef) []*intruder {
+	var out []*intruder
+	for _, t := range targets {
+		in := &intruder{cfg: t}
+		for _, wp := range t.Waypoints {
+			in.route = append(in.route, geo.GeoToLocal(wp.Lat, wp.Lon, wp.Alt))
+		}
+		in.reset()
+		out = append(out, in)
+	}
+	return out
+}
+
+func (in *intruder) reset() {
+	in.pos = in.route[0]
+	in.idx = 1
+	in.vel = vector.Vec3{}
+}
+
+// move flies the target dt seconds along its looped route.
+func (in *intruder) move(dt, defaultSpeed float64) {
+	start := in.pos
+	left := dt
+	// bounded so a degenerate route cannot spin forever
+	for i := 0; i < 2*len(in.route) && left > 0; i++ {
+		speed := in.cfg.Waypoints[in.idx].Speed
+		if speed <= 0 {
+			speed = in.cfg.Speed
+		}
+		if speed <= 0 {
+			speed = defaultSpeed
+		}
+		d := in.route[in.idx].Sub(in.pos)
+		dist := d.Length()
+		if dist > speed*left {
+			in.pos = in.pos.Lerp(in.route[in.idx], speed*left/dist)
+			break
+		}
+		in.pos = in.route[in.idx]
+		left -= dist / speed
+		in.idx = (in.idx + 1) % len(in.route)
+	}
+	in.vel = in.pos.Sub(start).Mul(1 / dt)
+}
+
+// updateTraffic moves every target, computes its geometry relative to the
+// ownship and returns the published states and the traffic warning.
+func (e *Engine) updateTraffic(dt float64) ([]TrafficState, []Warning) {
+	if len(e.traffic) == 0 {
+		return nil, nil
+	}
+	var (
+		out      []TrafficState
+		advisory []string
+	)
+	for _, in := range e.traffic {
+		in.move(dt, e.limits.DefaultSpeed)
+
+		rel := in.pos.Sub(e.pos)
+		relVel := in.vel.Sub(e.gvel)
+		rng := rel.Horizontal().Length()
+		closure := 0.0
+		if rng > 1e-9 {
+			closure = -(rel.X*relVel.X + rel.Y*relVel.Y) / rng
+		}
+
+		lat, lon, alt := e.geo.LocalToGeo(in.pos)
+		ts := TrafficState{
+			ID: in.cfg.ID, Lat: lat, Lon: lon, Alt: alt,
+			Vx: in.vel.X, Vy: in.vel.Y, Vz: in.vel.Z,
+			HeadingDeg: HeadingDegFromVec(in.vel),
+			RangeM:     rng,
+			BearingDeg: HeadingDegFromVec(rel),
+			RelAltM:    rel.Z,
+			ClosureMps: closure,
+			Advisory:   rng <= e.trafficHorizM && math.Abs(rel.Z) <= e.trafficVertM,
+		}
+		if ts.Advisory {
+			advisory = append(advisory, fmt.Sprintf("%s bearing %03.0f° range %.0f m rel alt %+.0f m",
+				ts.ID, ts.BearingDeg, ts.RangeM, ts.RelAltM))
+		}
+		out = append(out, ts)
+	}
+	if len(advisory) == 0 {
+		return out, nil
+	}
+	sort.Strings(advisory)
+	return out, []Warning{{
+		Code:     WarnTraffic,
+		Severity: env.SeverityCaution,
+		Message:  strings.Join(advisory, ", "),
+	}}
+}
@@ -0,0 +1,117 @@
+package sim_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+// offset returns the waypoint east and north metres from 47°N 8°E.
+func offset(east, north, alt float64) sim.Waypoint {
+	return sim.Waypoint{
+		Lat: 47 + north/111_200,
+		Lon: 8 + east/(111_200*math.Cos(47*math.Pi/180)),
+		Alt: alt,
+	}
+}
+
+func TestTrafficAdvisory(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		target   sim.TrafficTarget
+		horizM   float64
+		advisory bool
+		bearing  float64
+	}{
+		{
+			name:     "close east, same level",
+			target:   sim.TrafficTarget{ID: "a", Waypoints: []sim.Waypoint{offset(600, 0, 1000), offset(600, 10000, 1000)}, Speed: 1},
+			advisory: true, bearing: 90,
+		},
+		{
+			name:    "close but far above",
+			target:  sim.TrafficTarget{ID: "b", Waypoints: []sim.Waypoint{offset(0, 500, 1300), offset(0, 10000, 1300)}, Speed: 1},
+			bearing: 0,
+		},
+		{
+			name:    "beyond the default range",
+			target:  sim.TrafficTarget{ID: "c", Waypoints: []sim.Waypoint{offset(-1500, 0, 1000), offset(-1500, 10000, 1000)}, Speed: 1},
+			bearing: 270,
+		},
+		{
+			name:     "within a wider range",
+			target:   sim.TrafficTarget{ID: "d", Waypoints: []sim.Waypoint{offset(0, -1500, 1000), offset(0, -10000, 1000)}, Speed: 1},
+			horizM:   2000,
+			advisory: true, bearing: 180,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h, err := simtest.New(sim.Config{
+				OriginLat: 47, OriginLon: 8,
+				Traffic:       []sim.TrafficTarget{c.target},
+				TrafficHorizM: c.horizM,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			st := h.Steps(1)
+			if len(st.Traffic) != 1 {
+				t.Fatalf("%d traffic states, want 1", len(st.Traffic))
+			}
+			tr := st.Traffic[0]
+			if tr.ID != c.target.ID || tr.Advisory != c.advisory {
+				t.Errorf("traffic %+v, want advisory %v", tr, c.advisory)
+			}
+			if d := math.Abs(math.Remainder(tr.BearingDeg-c.bearing, 360)); d > 0.5 {
+				t.Errorf("bearing %.1f, want %g", tr.BearingDeg, c.bearing)
+			}
+			var warned bool
+			for _, w := range st.Warnings {
+				if w.Code == sim.WarnTraffic {
+					warned = strings.HasPrefix(w.Message, c.target.ID+" bearing")
+				}
+			}
+			if warned != c.advisory {
+				t.Errorf("traffic warning %v, want %v (warnings %v)", warned, c.advisory, st.Warnings)
+			}
+		})
+	}
+}
+
+func TestTrafficFliesItsRoute(t *testing.T) {
+	target := sim.TrafficTarget{ID: "loop", Waypoints: []sim.Waypoint{offset(0, 2000, 1000), offset(0, 3000, 1000)}, Speed: 50}
+	h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8, Traffic: []sim.TrafficTarget{target}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 30 s at 50 m/s: from 2000 m north to the second waypoint and back to 2500 m
+	st := h.Steps(600).Traffic[0]
+	if math.Abs(st.RangeM-2500) > 5 || math.Abs(st.Vy+50) > 1e-6 {
+		t.Errorf("after 30 s at range %.1f m, vy %.1f; want 2500 m heading south at 50 m/s", st.RangeM, st.Vy)
+	}
+	if math.Abs(st.ClosureMps-50) > 1e-6 {
+		t.Errorf("closure %.2f m/s, want 50", st.ClosureMps)
+	}
+}
+
+func TestTrafficValidation(t *testing.T) {
+	wps := []sim.Waypoint{offset(0, 0, 1000), offset(0, 100, 1000)}
+	for _, c := range []struct {
+		name    string
+		targets []sim.TrafficTarget
+		err     string
+	}{
+		{"missing id", []sim.TrafficTarget{{Waypoints: wps}}, "needs an id"},
+		{"one waypoint", []sim.TrafficTarget{{ID: "a", Waypoints: wps[:1]}}, "at least 2 waypoints"},
+		{"negative speed", []sim.TrafficTarget{{ID: "a", Waypoints: wps, Speed: -1}}, "speed must be >= 0"},
+		{"duplicate id", []sim.TrafficTarget{{ID: "a", Waypoints: wps}, {ID: "a", Waypoints: wps}}, "duplicate traffic id"},
+	} {
+		_, err := sim.New(sim.Config{OriginLat: 47, OriginLon: 8, Traffic: c.targets})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: New = %v, want an error containing %q", c.name, err, c.err)
+		}
+	}
+}
@@ -2,22 +2,108 @@ package sim
 
 import (
 	"time"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
 )
 
+// Warning is a coded condition raised by the engine or an environment effect.
+type Warning = env.Warning
+
 type AircraftState struct {
 	Lat float64 `json:"lat"`
 	Lon float64 `json:"lon"`
 	Alt float64 `json:"alt"` // meters
+	// AltRef is the datum of Alt: AltRefMSL as published, or the one a
+	// client asked for (see Engine.InDatum).
+	AltRef AltRef `json:"altRef"`
+	// UTM is the position in its own UTM zone, set only when a client asks
+	// for it and the position is inside the grid.
+	UTM *UTM `json:"utm,omitempty"`
 
 	// "Air" velocity (commanded / controlled)
 	Vx float64 `json:"vx"`
 	Vy float64 `json:"vy"`
 	Vz float64 `json:"vz"`
 
-	HeadingDeg float64   `json:"headingDeg"`
-	TS         time.Time `json:"ts"`
+	// Ground velocity: actual displacement over the last tick, including
+	// wind drift and terrain corrections.
+	GVx float64 `json:"gvx"`
+	GVy float64 `json:"gvy"`
+	GVz float64 `json:"gvz"`
+
+	GroundSpeedMps   float64 `json:"groundSpeedMps"`   // horizontal ground speed
+	VerticalSpeedMps float64 `json:"verticalSpeedMps"` // ground-referenced, positive up
+	TrackDeg         float64 `json:"trackDeg"`         // course over ground, 0=north, 90=east
+
+	// Wind components relative to the ground track (the air heading below
+	// 1 m/s ground speed), from the estimated wind with Config.EstimateWind.
+	HeadwindMps  float64 `json:"headwindMps"`  // positive against the track, negative for a tailwind
+	CrosswindMps float64 `json:"crosswindMps"` // positive from the right of the track
+
+	// Odometer: horizontal ground track length and simulated time since the
+	// engine started or the odometer was last reset.
+	DistanceFlownM float64 `json:"distanceFlownM"`
+	FlightTimeS    float64 `json:"flightTimeS"`
+
+	// Height above the terrain (only with terrain in the environment), and
+	// the height being held while a command follows the terrain.
+	AGLM       *float64 `json:"aglM,omitempty"`
+	TargetAGLM *float64 `json:"targetAglM,omitempty"`
+
+	HeadingDeg    float64 `json:"headingDeg"`    // from air velocity, true
+	HeadingMagDeg float64 `json:"headingMagDeg"` // HeadingDeg less the declination (Config.Declination)
+
+	// Approximate attitude derived from the motion, smoothed
+	// (see Config.AttitudeTimeConstS). Positive roll is right wing down.
+	RollDeg  float64 `json:"rollDeg"`
+	PitchDeg float64 `json:"pitchDeg"`
+	YawDeg   float64 `json:"yawDeg"`
+	// Quaternion is the same attitude as a rotation from the body axes
+	// (forward, right, down) to north-east-down, free of the gimbal lock
+	// at ±90° pitch; interpolate it rather than the angles.
+	Quaternion vector.Quat `json:"quaternion"`
+
+	TS time.Time `json:"ts"`
+	// Seq numbers the published states, one up per state, from 1. A state
+	// that was not published (GetState, the first frame of a subscription)
+	// carries the Seq of the last one that was.
+	Seq uint64 `json:"seq"`
+	// ExtrapolatedS is how far GetState dead-reckoned the position past the
+	// last tick (see Config.ExtrapolateState); never more than one tick.
+	ExtrapolatedS float64 `json:"extrapolatedS,omitempty"`
+
+	// DensityAltitudeM is the ISA altitude with the current air density
+	// (only with Config.Atmosphere enabled).
+	DensityAltitudeM float64 `json:"densityAltitudeM,omitempty"`
+
+	// Wind estimated from the aircraft's own motion, m/s east/north, with
+	// a confidence from 0 to 1 (only with Config.EstimateWind).
+	EstimatedWindX          *float64 `json:"estimatedWindX,omitempty"`
+	EstimatedWindY          *float64 `json:"estimatedWindY,omitempty"`
+	EstimatedWindConfidence *float64 `json:"estimatedWindConfidence,omitempty"`
+
+	// Energy model (omitted when no battery is configured)
+	BatteryPct *float64 `json:"batteryPct,omitempty"`
+	EnduranceS *float64 `json:"enduranceS,omitempty"` // at the current draw
+
+	// Distance to the geofence boundary (positive inside), reported only
+	// within the fence margin or outside.
+	FenceDistanceM *float64 `json:"fenceDistanceM,omitempty"`
+
+	// Scripted traffic with its geometry relative to this aircraft.
+	Traffic []TrafficState `json:"traffic,omitempty"`
 
 	ActiveCommand string `json:"activeCommand,omitempty"`
 	TargetIndex   int    `json:"targetIndex,omitempty"`
-	Warning       string `json:"warning,omitempty"`
+	// Mission names the stored mission the active trajectory was flown
+	// from (TrajectoryCommand.Mission).
+	Mission string `json:"mission,omitempty"`
+	// Ditched is set once the aircraft has come down on water; it then
+	// stays put until the simulation is reset.
+	Ditched bool `json:"ditched,omitempty"`
+	// Warnings lists every condition active this tick. Warning is the same
+	// list joined into one line, kept for older clients.
+	Warnings []Warning `json:"warnings,omitempty"`
+	Warning  string    `json:"warning,omitempty"`
 }
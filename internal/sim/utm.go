@@ -0,0 +1,147 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// UTM is a position in the Universal Transverse Mercator grid: the zone
+// (1–60), metres east of the zone's false origin and metres north of the
+// equator, or of 10 000 km south of it when South is set.
+type UTM struct {
+	Zone     int     `json:"zone"`
+	Easting  float64 `json:"easting"`
+	Northing float64 `json:"northing"`
+	South    bool    `json:"south,omitempty"`
+}
+
+// UTM grid parameters.
+const (
+	utmK0             = 0.9996
+	utmFalseEasting   = 500_000.0
+	utmFalseNorthingS = 10_000_000.0
+	utmMinLat         = -80.0
+	utmMaxLat         = 84.0
+)
+
+// Krüger series in the third flattening, to n³ (well under a millimetre
+// within a zone).
+var (
+	utmN     = wgs84F / (2 - wgs84F)
+	utmA     = wgs84A / (1 + utmN) * (1 + utmN*utmN/4 + utmN*utmN*utmN*utmN/64)
+	utmAlpha = [3]float64{
+		utmN/2 - 2*utmN*utmN/3 + 5*utmN*utmN*utmN/16,
+		13*utmN*utmN/48 - 3*utmN*utmN*utmN/5,
+		61 * utmN * utmN * utmN / 240,
+	}
+	utmBeta = [3]float64{
+		utmN/2 - 2*utmN*utmN/3 + 37*utmN*utmN*utmN/96,
+		utmN*utmN/48 + utmN*utmN*utmN/15,
+		17 * utmN * utmN * utmN / 480,
+	}
+	utmDelta = [3]float64{
+		2*utmN - 2*utmN*utmN/3 - 2*utmN*utmN*utmN,
+		7*utmN*utmN/3 - 8*utmN*utmN*utmN/5,
+		56 * utmN * utmN * utmN / 15,
+	}
+)
+
+// UTMZone is the zone holding lat, lon, with the Norway and Svalbard
+// exceptions.
+func UTMZone(lat, lon float64) int {
+	lon = wrapLon(lon)
+	switch {
+	case lat >= 56 && lat < 64 && lon >= 3 && lon < 12:
+		return 32
+	case lat >= 72 && lat < 84 && lon >= 0 && lon < 42:
+		// 31X, 33X, 35X and 37X are widened over the even zones
+		switch {
+		case lon < 9:
+			return 31
+		case lon < 21:
+			return 33
+		case lon < 33:
+			return 35
+		default:
+			return 37
+		}
+	}
+	return int(math.Floor((lon+180)/6)) + 1
+}
+
+// LatLonToUTM converts lat, lon to UTM in its own zone (UTMZone).
+func LatLonToUTM(lat, lon float64) (UTM, error) {
+	return LatLonToUTMZone(lat, lon, UTMZone(lat, lon))
+}
+
+// LatLonToUTMZone converts lat, lon to UTM in the given zone, which may be
+// a neighbour of its own, as when a survey near a zone boundary keeps to
+// one zone. Latitudes outside [-80, 84] are rejected.
+func LatLonToUTMZone(lat, lon float64, zone int) (UTM, error) {
+	if !(lat >= utmMinLat && lat <= utmMaxLat) {
+		return UTM{}, fmt.Errorf("latitude %g is outside the UTM grid (%g to %g)", lat, utmMinLat, utmMaxLat)
+	}
+	if zone < 1 || zone > 60 {
+		return UTM{}, fmt.Errorf("utm zone %d must be between 1 and 60", zone)
+	}
+	phi := lat * math.Pi / 180
+	lambda := wrapLon(lon-utmCentralMeridian(zone)) * math.Pi / 180
+
+	c := 2 * math.Sqrt(utmN) / (1 + utmN)
+	t := math.Sinh(math.Atanh(math.Sin(phi)) - c*math.Atanh(c*math.Sin(phi)))
+	xi := math.Atan2(t, math.Cos(lambda))
+	eta := math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+
+	e, n := eta, xi
+	for j, a := range utmAlpha {
+		k := 2 * float64(j+1)
+		e += a * math.Cos(k*xi) * math.Sinh(k*eta)
+		n += a * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	u := UTM{
+		Zone:     zone,
+		Easting:  utmFalseEasting + utmK0*utmA*e,
+		Northing: utmK0 * utmA * n,
+		South:    lat < 0,
+	}
+	if u.South {
+		u.Northing += utmFalseNorthingS
+	}
+	return u, nil
+}
+
+// LatLon converts u back to latitude and longitude, the longitude in
+// [-180, 180).
+func (u UTM) LatLon() (lat, lon float64, err error) {
+	if u.Zone < 1 || u.Zone > 60 {
+		return 0, 0, fmt.Errorf("utm zone %d must be between 1 and 60", u.Zone)
+	}
+	if math.IsNaN(u.Easting) || math.IsInf(u.Easting, 0) || math.IsNaN(u.Northing) || math.IsInf(u.Northing, 0) {
+		return 0, 0, fmt.Errorf("utm easting and northing must be finite")
+	}
+	northing := u.Northing
+	if u.South {
+		northing -= utmFalseNorthingS
+	}
+	xi := northing / (utmK0 * utmA)
+	eta := (u.Easting - utmFalseEasting) / (utmK0 * utmA)
+
+	xi1, eta1 := xi, eta
+	for j, b := range utmBeta {
+		k := 2 * float64(j+1)
+		xi1 -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta1 -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xi1) / math.Cosh(eta1))
+	phi := chi
+	for j, d := range utmDelta {
+		phi += d * math.Sin(2*float64(j+1)*chi)
+	}
+	lambda := math.Atan2(math.Sinh(eta1), math.Cos(xi1))
+	return phi * 180 / math.Pi, wrapLon(utmCentralMeridian(u.Zone) + lambda*180/math.Pi), nil
+}
+
+// utmCentralMeridian is the longitude of zone's central meridian.
+func utmCentralMeridian(zone int) float64 {
+	return float64(zone)*6 - 183
+}
@@ -0,0 +1,68 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"flight-simulator2/internal/env"
+)
+
+// ErrNoWeatherCell is returned when removing a storm cell that does not exist.
+var ErrNoWeatherCell = errors.New("no weather cell with this id")
+
+// AddWeatherCell places a storm cell centered at c.Lat/c.Lon. It starts
+// drifting from there on the next tick.
+func (e *Engine) AddWeatherCell(ctx context.Context, c env.WeatherCell) error {
+	if e.replay != nil {
+		return ErrReplay
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	var aerr error
+	err := e.call(ctx, func() {
+		aerr = e.weather.Add(c, e.geo.GeoToLocal(c.Lat, c.Lon, 0))
+	})
+	if err != nil {
+		return err
+	}
+	return aerr
+}
+
+// RemoveWeatherCell deletes a storm cell; an empty id removes them all.
+func (e *Engine) RemoveWeatherCell(ctx context.Context, id string) error {
+	var found bool
+	err := e.call(ctx, func() {
+		if id == "" {
+			e.weather.Clear()
+			found = true
+			return
+		}
+		found = e.weather.Remove(id)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %q", ErrNoWeatherCell, id)
+	}
+	return nil
+}
+
+// WeatherCells lists the storm cells with Lat/Lon set to their current,
+// drifted centers.
+func (e *Engine) WeatherCells(ctx context.Context) ([]env.WeatherCell, error) {
+	var out []env.WeatherCell
+	err := e.call(ctx, func() {
+		cells, centers := e.weather.Cells()
+		for i, c := range cells {
+			c.Lat, c.Lon, _ = e.geo.LocalToGeo(centers[i])
+			out = append(out, c)
+		}
+	})
+	if out == nil {
+		out = []env.WeatherCell{}
+	}
+	return out, err
+}
@@ -0,0 +1,69 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// WindReport is the wind the environment produces at the aircraft.
+type WindReport struct {
+	Alt          float64             `json:"alt"`
+	Wx           float64             `json:"wx"` // m/s east
+	Wy           float64             `json:"wy"` // m/s north
+	Wz           float64             `json:"wz"` // m/s up (thermals, microbursts)
+	SpeedMps     float64             `json:"speedMps"`
+	DirectionDeg float64             `json:"directionDeg"` // blowing toward, as in env.FromSpeedAndDir
+	Sources      []env.WindComponent `json:"sources"`
+}
+
+// Wind reports the wind at the aircraft's true position, summed over the
+// environment's wind effects.
+func (e *Engine) Wind(ctx context.Context) (WindReport, error) {
+	var r WindReport
+	err := e.call(ctx, func() { r = e.windAt(e.pos) })
+	return r, err
+}
+
+// windAt sums the wind of every effect at pos.
+func (e *Engine) windAt(pos vector.Vec3) WindReport {
+	w, parts := env.WindAt(e.effects(), pos)
+	r := WindReport{
+		Alt: pos.Z, Wx: w.X, Wy: w.Y, Wz: w.Z,
+		SpeedMps:     w.Horizontal().Length(),
+		DirectionDeg: HeadingDegFromVec(w),
+		Sources:      []env.WindComponent{},
+	}
+	if parts != nil {
+		r.Sources = parts
+	}
+	return r
+}
+
+// DefaultMaxWindMps is used when Config.MaxWindMps is zero.
+const DefaultMaxWindMps = 50.0
+
+// ErrWindTooStrong is returned by SetWind for speeds above Config.MaxWindMps.
+var ErrWindTooStrong = errors.New("wind speed exceeds the configured maximum")
+
+// SetWind replaces the environment's mean wind with a constant w (see
+// env.ReplaceWind). The swap happens between ticks, so no step sees a mix
+// of old and new components.
+func (e *Engine) SetWind(ctx context.Context, w env.Wind) error {
+	if e.replay != nil {
+		return ErrReplay
+	}
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	if speed := math.Hypot(w.Wx, w.Wy); speed > e.maxWind {
+		return fmt.Errorf("%w: %.1f > %.1f m/s", ErrWindTooStrong, speed, e.maxWind)
+	}
+	return e.call(ctx, func() {
+		e.setEnvironment(env.ReplaceWind(e.environment, w))
+	})
+}
@@ -0,0 +1,50 @@
+package sim_test
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/env"
+	"flight-simulator2/internal/sim"
+	"flight-simulator2/internal/sim/simtest"
+)
+
+func TestWindMovesGroundNotAir(t *testing.T) {
+	// a goto due north at 50 m/s, steady after 10 s
+	for _, c := range []struct {
+		name   string
+		wind   env.Wind
+		ground float64 // ground speed; 0 when the aircraft crabs
+	}{
+		{"calm", env.Calm(), 50},
+		{"headwind", env.Wind{Wy: -15}, 35},
+		{"tailwind", env.Wind{Wy: 15}, 65},
+		{"crosswind", env.Wind{Wx: 20}, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h, err := simtest.New(sim.Config{OriginLat: 47, OriginLon: 8, Environment: c.wind})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Submit(sim.GoToCommand{Lat: 47.2, Lon: 8, Alt: 1000, Speed: 50}); err != nil {
+				t.Fatal(err)
+			}
+			st := h.Steps(200)
+			// air velocity is what guidance commands, whatever the wind
+			if math.Hypot(st.Vx, st.Vy) < 49.9 || math.Hypot(st.Vx, st.Vy) > 50.1 {
+				t.Errorf("airspeed %.2f, want 50", math.Hypot(st.Vx, st.Vy))
+			}
+			if math.Abs(st.GVx-(st.Vx+c.wind.Wx)) > 1e-6 || math.Abs(st.GVy-(st.Vy+c.wind.Wy)) > 1e-6 {
+				t.Errorf("ground velocity (%.3f, %.3f), want air (%.3f, %.3f) + wind (%g, %g)",
+					st.GVx, st.GVy, st.Vx, st.Vy, c.wind.Wx, c.wind.Wy)
+			}
+			if math.Abs(st.GroundSpeedMps-math.Hypot(st.GVx, st.GVy)) > 1e-9 || c.ground > 0 && math.Abs(st.GroundSpeedMps-c.ground) > 0.1 {
+				t.Errorf("ground speed %.2f, want %.2f", st.GroundSpeedMps, c.ground)
+			}
+			wantTrack := math.Mod(math.Atan2(st.GVx, st.GVy)*180/math.Pi+360, 360)
+			if math.Abs(math.Remainder(st.TrackDeg-wantTrack, 360)) > 1e-6 {
+				t.Errorf("track %.2f, want %.2f", st.TrackDeg, wantTrack)
+			}
+		})
+	}
+}
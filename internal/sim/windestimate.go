@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"math"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+// DefaultWindEstimateTauS is used when Config.WindEstimateTauS is zero.
+const DefaultWindEstimateTauS = 2.0
+
+// WindEstimator estimates the horizontal wind the way an autopilot would
+// without access to the environment: each tick the wind is the ground
+// velocity (displacement over dt) less the air velocity, low-pass filtered
+// with time constant TauS (default DefaultWindEstimateTauS).
+//
+// The zero value is ready to use.
+type WindEstimator struct {
+	TauS float64
+
+	est     vector.Vec3
+	resVar  float64 // filtered squared residual, (m/s)²
+	elapsed float64
+}
+
+// Update feeds one tick: the horizontal ground displacement over dt seconds
+// and the mean air velocity during it. Ticks with dt <= 0 are ignored.
+func (w *WindEstimator) Update(ground, air vector.Vec3, dt float64) {
+	if dt <= 0 {
+		return
+	}
+	tau := w.TauS
+	if tau <= 0 {
+		tau = DefaultWindEstimateTauS
+	}
+	sample := vector.Vec3{X: ground.X/dt - air.X, Y: ground.Y/dt - air.Y}
+	if w.elapsed == 0 {
+		// the first sample is the best guess there is
+		w.est, w.elapsed = sample, dt
+		return
+	}
+	a := 1 - math.Exp(-dt/tau)
+	res := sample.Sub(w.est)
+	w.est = w.est.Add(res.Mul(a))
+	w.resVar += a * (res.X*res.X + res.Y*res.Y - w.resVar)
+	w.elapsed += dt
+}
+
+// Estimate returns the wind estimate (m/s east/north) and a confidence from
+// 0 to 1. Confidence grows as the filter settles (over about 3·TauS) and
+// drops while the samples scatter, e.g. in gusts or manoeuvres.
+func (w *WindEstimator) Estimate() (wind vector.Vec3, confidence float64) {
+	tau := w.TauS
+	if tau <= 0 {
+		tau = DefaultWindEstimateTauS
+	}
+	settled := 1 - math.Exp(-w.elapsed/tau)
+	return w.est, settled / (1 + math.Sqrt(w.resVar))
+}
+
+// Reset forgets the estimate.
+func (w *WindEstimator) Reset() {
+	*w = WindEstimator{TauS: w.TauS}
+}
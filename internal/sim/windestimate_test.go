@@ -0,0 +1,66 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"flight-simulator2/internal/geometry/vector"
+)
+
+func TestWindEstimator(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		tau     float64
+		wind    vector.Vec3
+		air     vector.Vec3
+		seconds float64
+		minConf float64
+	}{
+		{"calm", 0, vector.Vec3{}, vector.Vec3{Y: 50}, 10, 0.9},
+		{"headwind", 0, vector.Vec3{Y: -12}, vector.Vec3{Y: 50}, 10, 0.9},
+		{"crosswind while turning", 1, vector.Vec3{X: 8, Y: 3}, vector.Vec3{X: 30, Y: 30}, 5, 0.9},
+		{"slow filter, short run", 20, vector.Vec3{X: 5}, vector.Vec3{Y: 40}, 2, 0},
+	} {
+		var w WindEstimator
+		w.TauS = c.tau
+		const dt = 0.05
+		for s := 0.0; s < c.seconds; s += dt {
+			ground := c.air.Add(c.wind).Mul(dt)
+			w.Update(ground, c.air, dt)
+		}
+		est, conf := w.Estimate()
+		if est.Sub(c.wind).Length() > 1e-9 {
+			t.Errorf("%s: estimate %v, want %v", c.name, est, c.wind)
+		}
+		if conf < c.minConf || conf > 1 {
+			t.Errorf("%s: confidence %.3f, want at least %g", c.name, conf, c.minConf)
+		}
+	}
+}
+
+func TestWindEstimatorSettles(t *testing.T) {
+	// a wind shift is followed over the time constant, with a dip in
+	// confidence while the samples disagree with the estimate
+	w := WindEstimator{TauS: 2}
+	air := vector.Vec3{Y: 50}
+	feed := func(wind vector.Vec3, seconds float64) {
+		for s := 0.0; s < seconds; s += 0.05 {
+			w.Update(air.Add(wind).Mul(0.05), air, 0.05)
+		}
+	}
+	feed(vector.Vec3{}, 20)
+	_, before := w.Estimate()
+	feed(vector.Vec3{X: 10}, 2)
+	est, during := w.Estimate()
+	if want := 10 * (1 - math.Exp(-1)); math.Abs(est.X-want) > 0.2 {
+		t.Errorf("one time constant after a 10 m/s shift the estimate is %.2f, want %.2f", est.X, want)
+	}
+	if during >= before {
+		t.Errorf("confidence %.3f during the shift, %.3f before", during, before)
+	}
+	w.Update(vector.Vec3{}, air, 0) // ignored
+	w.Reset()
+	if est, conf := w.Estimate(); est != (vector.Vec3{}) || conf != 0 || w.TauS != 2 {
+		t.Errorf("after Reset: %v, %g, tau %g", est, conf, w.TauS)
+	}
+}